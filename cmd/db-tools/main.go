@@ -21,6 +21,8 @@ func main() {
 		runDBInfo()
 	case "query":
 		runDBQuery()
+	case "migrate":
+		runMigrate(os.Args[2:])
 	default:
 		fmt.Printf("Bilinmeyen komut: %s\n", command)
 		printUsage()
@@ -33,6 +35,7 @@ func printUsage() {
 	fmt.Println("\nKomutlar:")
 	fmt.Println("  info    Veritabanı yapısı hakkında bilgi gösterir")
 	fmt.Println("  query   SQL sorgusu çalıştırır (örn: \"SELECT * FROM users\")")
+	fmt.Println("  migrate Migration'ları yönetir (status, up, down N, redo)")
 }
 
 func runDBInfo() {
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/database/migrations"
+)
+
+// runMigrate runs the migrate subcommand against the database the
+// environment uses, or the one given with -db
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbType := flags.String("db", string(database.DefaultDatabaseType()), "veritabanı türü (sqlite3 veya mysql)")
+	flags.Usage = printMigrateUsage
+	flags.Parse(args)
+
+	action := flags.Arg(0)
+	if action == "" {
+		action = "status"
+	}
+
+	steps := 1
+	if action == "down" && flags.NArg() > 1 {
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil || n < 1 {
+			fmt.Println("Hata: Geri alınacak migration sayısı pozitif bir tam sayı olmalı")
+			os.Exit(1)
+		}
+		steps = n
+	}
+
+	manager := database.NewDatabaseManager()
+	if err := manager.Connect(database.DatabaseType(*dbType)); err != nil {
+		fmt.Printf("Hata: Veritabanına bağlanılamadı: %v\n", err)
+		os.Exit(1)
+	}
+	defer manager.Close()
+
+	runner := database.NewMigrationRunner(manager.GetDB(), manager.GetType())
+	var err error
+	switch action {
+	case "status":
+		err = printMigrationStatus(runner)
+	case "up":
+		var applied int
+		applied, err = runner.Up()
+		if err == nil {
+			fmt.Printf("%d migration uygulandı\n", applied)
+		}
+	case "down":
+		var reverted int
+		reverted, err = runner.Down(steps)
+		if err == nil {
+			fmt.Printf("%d migration geri alındı\n", reverted)
+		}
+	case "redo":
+		var redone *migrations.Migration
+		redone, err = runner.Redo()
+		if err == nil && redone == nil {
+			fmt.Println("Uygulanmış migration yok")
+		} else if err == nil {
+			fmt.Printf("%s yeniden uygulandı\n", redone)
+		}
+	default:
+		fmt.Printf("Bilinmeyen migrate komutu: %s\n", action)
+		printMigrateUsage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("Hata: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printMigrationStatus prints every migration and whether it is applied
+func printMigrationStatus(runner *database.MigrationRunner) error {
+	statuses, err := runner.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	fmt.Printf("%-8s %-40s %-12s %s\n", "SÜRÜM", "AD", "DURUM", "UYGULANMA")
+	for _, status := range statuses {
+		state := "bekliyor"
+		switch {
+		case status.Missing:
+			state = "tanımsız"
+		case status.Modified:
+			state = "değişmiş"
+		case status.Applied:
+			state = "uygulandı"
+		default:
+			pending++
+		}
+
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%03d      %-40s %-12s %s\n", status.Version, status.Name, state, appliedAt)
+	}
+	fmt.Printf("\n%d bekleyen migration\n", pending)
+	return nil
+}
+
+func printMigrateUsage() {
+	fmt.Println("Kullanım: db-tools migrate [-db sqlite3|mysql] [status|up|down N|redo]")
+	fmt.Println("\nKomutlar:")
+	fmt.Println("  status  Migration'ların durumunu gösterir (varsayılan)")
+	fmt.Println("  up      Bekleyen tüm migration'ları uygular")
+	fmt.Println("  down N  Son N migration'ı geri alır (varsayılan 1)")
+	fmt.Println("  redo    Son migration'ı geri alıp yeniden uygular")
+}
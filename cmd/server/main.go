@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -12,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"kolajAi/internal/api"
 	"kolajAi/internal/database"
 	"kolajAi/internal/handlers"
 	"kolajAi/internal/models"
@@ -28,6 +31,12 @@ import (
 	"kolajAi/internal/middleware"
 	"kolajAi/internal/router"
 	"kolajAi/internal/config"
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/credentials"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/integrations/registry"
+	"kolajAi/internal/jobs"
+	"kolajAi/internal/reporting"
 
 )
 
@@ -93,10 +102,17 @@ func main() {
 		MainLogger.Printf("Konfigürasyon yüklenemedi, varsayılan değerler kullanılıyor: %v", err)
 		cfg = config.GetDefaultConfig()
 	}
+	if err := cfg.Validate(); err != nil {
+		MainLogger.Fatalf("Konfigürasyon geçersiz: %v", err)
+	}
 
 	// Initialize database manager (SQLite for dev, MySQL for prod)
 	MainLogger.Println("Database manager başlatılıyor...")
-	if err := database.InitGlobalDB(); err != nil {
+	dbConfig := database.NewManagerConfig(cfg.Database)
+	if len(dbConfig.Replicas) > 0 {
+		MainLogger.Printf("%d okuma replikası yapılandırıldı", len(dbConfig.Replicas))
+	}
+	if err := database.InitGlobalDBWithConfig(dbConfig); err != nil {
 		MainLogger.Fatalf("Database initialization failed: %v", err)
 	}
 	defer database.GlobalDBManager.Close()
@@ -127,87 +143,6 @@ func main() {
 	}()
 	MainLogger.Println("✅ Database seeding completed successfully - ANA SERVER")
 	
-	// TEMPORARY: Start simple HTTP server for testing
-	MainLogger.Println("🚀 Starting simple test server on :8081")
-	
-	// Database connection already available from above
-	
-	// Basic routes for testing
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`
-<!DOCTYPE html>
-<html>
-<head><title>KolajAI Test Server</title></head>
-<body>
-	<h1>KolajAI Server is running!</h1>
-	<h2>Available Test Endpoints:</h2>
-	<ul>
-		<li><a href="/health">Health Check</a></li>
-		<li><a href="/users">Users (Database Test)</a></li>
-		<li><a href="/products">Products (Database Test)</a></li>
-		<li><a href="/login">Login Form</a></li>
-		<li><a href="/register">Register Form</a></li>
-		<li><a href="/admin">Admin Dashboard</a></li>
-	</ul>
-</body>
-</html>`))
-	})
-	
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy","database":"connected","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
-	})
-	
-	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
-		db := database.GetGlobalDB()
-		rows, err := db.Query("SELECT id, name, email FROM users LIMIT 10")
-		if err != nil {
-			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-		
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte("<h1>Users</h1><ul>"))
-		for rows.Next() {
-			var id int
-			var name, email string
-			rows.Scan(&id, &name, &email)
-			w.Write([]byte(fmt.Sprintf("<li>%d: %s (%s)</li>", id, name, email)))
-		}
-		w.Write([]byte("</ul><a href='/'>Back</a>"))
-	})
-	
-	http.HandleFunc("/products", func(w http.ResponseWriter, r *http.Request) {
-		db := database.GetGlobalDB()
-		rows, err := db.Query("SELECT id, name, price FROM products LIMIT 10")
-		if err != nil {
-			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-		
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte("<h1>Products</h1><ul>"))
-		for rows.Next() {
-			var id int
-			var name string
-			var price float64
-			rows.Scan(&id, &name, &price)
-			w.Write([]byte(fmt.Sprintf("<li>%d: %s - %.2f TL</li>", id, name, price)))
-		}
-		w.Write([]byte("</ul><a href='/'>Back</a>"))
-	})
-	
-	MainLogger.Println("🌐 Server is ready at http://localhost:8081")
-	if err := http.ListenAndServe(":8081", nil); err != nil {
-		MainLogger.Fatalf("Server failed: %v", err)
-	}
-	
-	return // TEMPORARY: Exit here for testing
-	
 	// Get database connection for services
 	MainLogger.Println("Database connection alınıyor...")
 	
@@ -229,6 +164,29 @@ func main() {
 	defer cacheManager.Close()
 	MainLogger.Println("✅ Cache Manager başlatıldı")
 
+	// Ürün, kategori ve rapor okumaları için entity cache
+	var entityCache *cache.EntityCache
+	if entities := cfg.Cache.Entities; entities.Enabled {
+		err := cacheManager.AddStore("entities", cache.StoreConfig{
+			Type:    cache.StoreType(entities.Store),
+			MaxSize: entities.MaxSize,
+			TTL:     entities.DefaultTTL,
+			Enabled: true,
+		})
+		if err == nil {
+			entityCache, err = cache.NewEntityCache(cacheManager, cache.EntityCacheConfig{
+				TTLs:       entities.TTL,
+				DefaultTTL: entities.DefaultTTL,
+				Logger:     MainLogger,
+			})
+		}
+		if err != nil {
+			MainLogger.Printf("Entity cache başlatılamadı: %v", err)
+		} else {
+			MainLogger.Println("✅ Entity cache başlatıldı")
+		}
+	}
+
 	// Security Manager
 	MainLogger.Println("Güvenlik sistemi başlatılıyor...")
 	MainLogger.Printf("EncryptionKey: %s", cfg.Security.EncryptionKey)
@@ -256,6 +214,8 @@ func main() {
 		PasswordRequireSymbol: true,
 		SessionTimeout:       24 * time.Hour,
 		CSRFTokenLength:      32,
+		// Bildirimler imzalarıyla, bankanın 3-D Secure dönüşü ödeme altyapısına sorularak doğrulanır; CSRF belirteci taşımazlar
+		CSRFExemptPaths:      []string{"/webhooks/", "/payment/3ds/callback"},
 		EnableIPWhitelist:    false,
 		EnableIPBlacklist:    true,
 		EnableRateLimit:      true,
@@ -279,6 +239,8 @@ func main() {
 		MaxAge:     int(24 * time.Hour / time.Second),
 		Domain:     cfg.Server.Domain,
 		Path:       "/",
+		// Süresi dolan oturumlar iş zamanlayıcısı tarafından temizlenir
+		CleanupInterval: -1,
 	})
 	if err != nil {
 		MainLogger.Fatalf("Session sistemi başlatılamadı: %v", err)
@@ -317,15 +279,18 @@ func main() {
 		},
 	})
 
-	// Reporting Manager - commented out for simplification
+	// Reporting Manager
 	MainLogger.Println("Raporlama sistemi başlatılıyor...")
-	// reportManager := reporting.NewReportManager(db)
+	reportManager := reporting.NewReportManager(db)
+	reportManager.SetReplicas(database.GlobalDBManager.Replicas)
 
 	// Test Manager - Commented out as it's not needed in production
 	// MainLogger.Println("Test sistemi başlatılıyor...")
 
 	// Repository oluştur
 	mysqlRepo := database.NewMySQLRepository(db)
+	// Salt okunur sorgular okuma replikalarına yönlendirilir
+	mysqlRepo.SetReplicas(database.GlobalDBManager.Replicas)
 	repo := database.NewRepositoryWrapper(mysqlRepo)
 
 	// Servisleri oluştur
@@ -335,10 +300,29 @@ func main() {
 	// emailService := email.NewService() // Email service'i initialize et - temporarily disabled
 	var emailService *email.Service = nil
 	authService := services.NewAuthService(userRepo, emailService)
+
+	// E-posta ve bildirim servisleri: zamanlanmış raporlar, bildirimler ve sipariş durumu bildirimleri bunlarla gönderilir
+	mailService := services.NewEmailService(repository.NewBaseRepository(repo), db, services.EmailConfig{
+		Provider:  "smtp",
+		SMTPHost:  cfg.Email.SMTPHost,
+		SMTPPort:  cfg.Email.SMTPPort,
+		Username:  cfg.Email.SMTPUser,
+		Password:  cfg.Email.SMTPPassword,
+		FromEmail: cfg.Email.FromEmail,
+		FromName:  cfg.Email.FromName,
+	})
+	notificationService := services.NewNotificationService(repository.NewBaseRepository(repo), db, mailService)
 	vendorService := services.NewVendorService(repo)
+	// Liste sayfalarının cursor'ları JWT anahtarından türetilen ayrı bir anahtarla imzalanır; böylece tüm instance'larda ve yeniden başlatmalardan sonra geçerli kalır
+	cursorCodec := database.NewCursorCodec([]byte(cfg.Security.JWTSecret))
 	productService := services.NewProductService(repo)
+	productService.SetCache(entityCache)
+	productService.SetCursorCodec(cursorCodec)
 	orderService := services.NewOrderService(repo)
+	orderService.SetCache(entityCache)
+	orderService.SetCursorCodec(cursorCodec)
 	auctionService := services.NewAuctionService(repo)
+	auctionService.SetCursorCodec(cursorCodec)
 	aiService := services.NewAIService(repo, productService, orderService)
 	aiAnalyticsService := services.NewAIAnalyticsService(repo, productService, orderService)
 	aiVisionService := services.NewAIVisionService(repo, productService)
@@ -347,7 +331,148 @@ func main() {
 	// Yeni gelişmiş AI ve marketplace servisleri
 	aiAdvancedService := services.NewAIAdvancedService(repo, productService, orderService)
 	marketplaceService := services.NewMarketplaceIntegrationsService()
+	
+	// Entegrasyon kayıt defteri: sağlayıcılar şifreli kimlik bilgileriyle oluşturulur.
+	// Kimlik bilgileri şifreleme anahtarının SHA-256 özetiyle şifrelenir.
+	credentialStore := credentials.NewDatabaseStore(repo, "integration_credentials")
+	credentialKey := sha256.Sum256([]byte(cfg.Security.EncryptionKey))
+	if credentialManager, err := credentials.NewManager(credentialKey[:], credentialStore); err != nil {
+		MainLogger.Printf("Entegrasyon kimlik bilgisi yöneticisi oluşturulamadı: %v", err)
+	} else {
+		marketplaceService.SetRegistry(registry.NewIntegrationRegistry(credentialManager))
+	}
+
+	// Pazaryeri katalogları: kategori ağaçları ve özellik şemaları önbelleğe alınır, ürünler gönderilmeden önce eşleştirmelerle tamamlanıp doğrulanır
+	marketplaceCatalogService, err := services.NewMarketplaceCatalogService(repo, services.MarketplaceCatalogConfig{
+		Integrations: marketplaceService,
+		AIService:    aiService,
+		Logger:       MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Pazaryeri katalog servisi oluşturulamadı: %v", err)
+	}
 	paymentService := services.NewPaymentService(repo)
+	// Ödeme bildirimleri siparişlere checkout servisi üzerinden uygulanır; servis aşağıda oluşturulur
+	var checkoutService *services.CheckoutService
+
+	// Kart ödemeleri yapılandırılan ödeme altyapısından geçer; altyapı yapılandırılmamışsa kart ödemeleri reddedilir
+	var cardGateway payment.Gateway
+	switch {
+	case cfg.Payment.Iyzico.Enabled && cfg.Payment.Iyzico.APIKey != "" && cfg.Payment.Iyzico.APISecret != "":
+		iyzico := payment.NewIyzicoProvider()
+		err := iyzico.Initialize(context.Background(), integrations.Credentials{
+			APIKey:    cfg.Payment.Iyzico.APIKey,
+			APISecret: cfg.Payment.Iyzico.APISecret,
+		}, map[string]interface{}{"environment": cfg.Payment.Iyzico.Environment})
+		if err != nil {
+			MainLogger.Fatalf("iyzico ödeme altyapısı başlatılamadı: %v", err)
+		}
+		cardGateway = iyzico
+		MainLogger.Printf("Kart ödemeleri iyzico üzerinden alınacak (%s)", cfg.Payment.Iyzico.Environment)
+	case cfg.Payment.Sandbox:
+		cardGateway = payment.NewSandboxProvider(payment.SandboxConfig{
+			WebhookHandler: func(payload *payment.WebhookPayload) {
+				if checkoutService == nil {
+					MainLogger.Printf("Sandbox ödeme bildirimi %s checkout servisi olmadığı için işlenemedi", payload.ID)
+					return
+				}
+				if err := checkoutService.HandlePaymentWebhook(payload); err != nil {
+					MainLogger.Printf("Sandbox ödeme bildirimi işlenemedi: %v", err)
+				}
+			},
+			Logger: MainLogger,
+		})
+		MainLogger.Println("UYARI: Kart ödemeleri sandbox ödeme altyapısından geçiyor, gerçek ödeme alınmayacak")
+	case cfg.Payment.Iyzico.Enabled:
+		MainLogger.Println("iyzico API anahtarları eksik, kart ödemeleri kabul edilmeyecek")
+	default:
+		MainLogger.Println("Ödeme altyapısı yapılandırılmadı, kart ödemeleri kabul edilmeyecek")
+	}
+	var reconciliationService *services.PaymentReconciliationService
+	if cardGateway != nil {
+		paymentService.SetGateway(cardGateway)
+
+		// Ödeme mutabakatı: ödeme altyapısının işlem listesi her gece ödeme ve sipariş kayıtlarıyla karşılaştırılır, farklar rapora yazılır
+		if lister, ok := cardGateway.(payment.TransactionLister); ok {
+			reconciliationService, err = services.NewPaymentReconciliationService(repo, services.PaymentReconciliationConfig{
+				Gateway:       lister,
+				ReportManager: reportManager,
+				Logger:        MainLogger,
+			})
+			if err != nil {
+				MainLogger.Printf("Ödeme mutabakatı servisi oluşturulamadı: %v", err)
+			}
+		}
+	}
+
+	// Satıcı cari hesabı: onaylanan siparişlerin satış ve komisyonu, iptal ve iadelerin ters kaydı durum değişikliğiyle aynı işlemde yazılır
+	vendorLedgerService, err := services.NewVendorLedgerService(repo, "TRY", MainLogger)
+	if err != nil {
+		MainLogger.Fatalf("Satıcı cari hesap servisi oluşturulamadı: %v", err)
+	}
+
+	// Sipariş durum makinesi: tüm durum değişiklikleri buradan geçer, iptal ve iadelerde ödeme iadesi kalıcı olarak kuyruğa alınır
+	orderStateMachine, err := services.NewOrderStateMachine(repo, services.OrderStateMachineConfig{
+		PaymentService:      paymentService,
+		NotificationService: notificationService,
+		Ledger:              vendorLedgerService,
+		Cache:               entityCache,
+		Logger:              MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Sipariş durum makinesi oluşturulamadı: %v", err)
+	}
+	orderService.SetStateMachine(orderStateMachine)
+
+	// Çok kanallı stok senkronizasyonu: stok değişiklikleri toplanıp tahsis kurallarına göre pazaryerlerine gönderilir
+	inventorySyncService, err := services.NewInventorySyncService(repo, services.InventorySyncConfig{
+		Integrations: marketplaceService,
+		StateMachine: orderStateMachine,
+		Cache:        entityCache,
+		Logger:       MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Stok senkronizasyon servisi oluşturulamadı: %v", err)
+	}
+	defer inventorySyncService.Stop()
+
+	// Pazaryeri fiyatlandırma: kanal fiyatları fiyat kuralları ve kampanyalarla hesaplanıp değişenler gönderilir
+	repricingService, err := services.NewRepricingService(repo, services.RepricingConfig{
+		Integrations: marketplaceService,
+		Analytics:    aiAnalyticsService,
+		Logger:       MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Pazaryeri fiyatlandırma servisi oluşturulamadı: %v", err)
+	}
+
+	// Pazaryeri siparişleri yerel siparişlere aktarılır, yerel durum değişiklikleri pazaryerine geri gönderilir
+	orderImportService, err := services.NewMarketplaceOrderImportService(repo, services.MarketplaceOrderImportConfig{
+		Integrations: marketplaceService,
+		StateMachine: orderStateMachine,
+		Inventory:    inventorySyncService,
+		Cache:        entityCache,
+		Logger:       MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Pazaryeri sipariş aktarım servisi oluşturulamadı: %v", err)
+	}
+
+	// Checkout: sepet siparişe dönüştürülürken stok aynı veritabanı işleminde rezerve edilir
+	var paymentCallbackURL string
+	if cfg.Server.Domain != "" {
+		paymentCallbackURL = "https://" + cfg.Server.Domain + "/payment/3ds/callback"
+	}
+	checkoutService, err = services.NewCheckoutService(repo, orderService, paymentService, services.CheckoutConfig{
+		StateMachine:       orderStateMachine,
+		Inventory:          inventorySyncService,
+		PaymentCallbackURL: paymentCallbackURL,
+		Cache:              entityCache,
+		Logger:             MainLogger,
+	})
+	if err != nil {
+		MainLogger.Printf("Checkout servisi başlatılamadı: %v", err)
+	}
 	
 	// AI Integration Manager
 	MainLogger.Println("AI Integration Manager başlatılıyor...")
@@ -355,7 +480,22 @@ func main() {
 	
 	// Integration Webhook Service
 	MainLogger.Println("Integration Webhook Service başlatılıyor...")
-	webhookService := services.NewIntegrationWebhookService(marketplaceService, aiIntegrationManager)
+	webhookService, err := services.NewIntegrationWebhookService(repo, services.IntegrationWebhookConfig{
+		Integrations: marketplaceService,
+		Orders:       orderImportService,
+		Inventory:    inventorySyncService,
+	})
+	if err != nil {
+		MainLogger.Printf("Integration Webhook Service başlatılamadı: %v", err)
+	}
+	
+	// Kalıcı iş kuyruğu: işler veritabanında tutulur, kiralama süresi dolan işler yeniden başlatmadan sonra tekrar alınır.
+	// Kuyruk sahipliği sorguları replika gecikmesinden etkilenmemesi için birincil veritabanına gider.
+	MainLogger.Println("İş kuyruğu başlatılıyor...")
+	primaryRepo := database.NewRepositoryWrapper(database.NewMySQLRepository(db))
+	jobQueue := jobs.NewPersistentQueue(primaryRepo, jobs.PersistentQueueConfig{Logger: MainLogger})
+	jobManager := jobs.NewJobManager(jobs.JobManagerConfig{Queue: jobQueue, Logger: MainLogger})
+	defer jobManager.Shutdown()
 	
 	// Integration Analytics Service
 	MainLogger.Println("Integration Analytics Service başlatılıyor...")
@@ -363,8 +503,7 @@ func main() {
 
 	// Asset Manager'ı başlat
 	MainLogger.Println("Asset Manager başlatılıyor...")
-	// assetManager := utils.NewAssetManager("dist/manifest.json")
-	var assetManager *utils.AssetManager // temporary nil for debugging
+	assetManager := utils.NewAssetManager("dist/manifest.json")
 	MainLogger.Println("✅ Asset Manager başlatıldı")
 
 	// Şablonları yükle
@@ -406,6 +545,38 @@ func main() {
 		"formatDate": func(t time.Time) string {
 			return t.Format("02.01.2006 15:04")
 		},
+		"formatTime": func(t time.Time) string {
+			return t.Format("15:04")
+		},
+		"date": func(t time.Time) string {
+			return t.Format("02.01.2006")
+		},
+		"timeAgo": func(v interface{}) string {
+			var t time.Time
+			switch value := v.(type) {
+			case time.Time:
+				t = value
+			case *time.Time:
+				if value == nil {
+					return "-"
+				}
+				t = *value
+			default:
+				return "-"
+			}
+			elapsed := time.Since(t)
+			switch {
+			case elapsed < time.Minute:
+				return "az önce"
+			case elapsed < time.Hour:
+				return fmt.Sprintf("%d dakika önce", int(elapsed.Minutes()))
+			case elapsed < 24*time.Hour:
+				return fmt.Sprintf("%d saat önce", int(elapsed.Hours()))
+			case elapsed < 30*24*time.Hour:
+				return fmt.Sprintf("%d gün önce", int(elapsed.Hours()/24))
+			}
+			return t.Format("02.01.2006")
+		},
 		"seq": func(n int) []int {
 			result := make([]int, n)
 			for i := 0; i < n; i++ {
@@ -779,6 +950,13 @@ func main() {
 
 	// Admin handler'ı oluştur
 	adminHandler := handlers.NewAdminHandler(h, repo)
+	marketplaceSyncService, err := services.NewMarketplaceSyncService(repo, services.MarketplaceSyncConfig{Integrations: marketplaceService})
+	if err != nil {
+		MainLogger.Printf("Pazaryeri senkronizasyon servisi başlatılamadı: %v", err)
+	} else {
+		adminHandler.MarketplaceSync = marketplaceSyncService
+	}
+	adminHandler.Webhooks = webhookService
 
 	// Seller handler'ı oluştur
 	sellerHandler := handlers.NewSellerHandler(h, vendorService, productService, orderService)
@@ -788,9 +966,46 @@ func main() {
 	inventoryHandler := handlers.NewInventoryHandler(h, inventoryService, productService)
 
 	// Notification handler'ı oluştur
-	notificationService := services.NewNotificationService(nil, nil, nil)
 	notificationHandler := handlers.NewNotificationHandler(h, notificationService)
 
+	// Açık artırma motoru: teklifler ve bitişler buradan geçer, teklif olayları WebSocket ile yayınlanır ve kazananlara bildirim gönderilir
+	webSocketService := services.NewWebSocketService()
+	webSocketService.Start()
+	auctionEngine, err := services.NewAuctionEngine(repo, services.AuctionEngineConfig{
+		WebSocketService:    webSocketService,
+		NotificationService: notificationService,
+		Cache:               entityCache,
+		Logger:              MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Açık artırma motoru oluşturulamadı: %v", err)
+	}
+	auctionService.SetEngine(auctionEngine)
+
+	// Toptan satış: fiyat listeleri, teklifler ve kredi limitiyle açık hesap siparişleri
+	wholesaleService, err := services.NewWholesaleService(repo, services.WholesaleConfig{
+		Cache:  entityCache,
+		Logger: MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("Toptan satış servisi oluşturulamadı: %v", err)
+	}
+	wholesaleHandler := handlers.NewWholesaleHandler(h, wholesaleService)
+
+	// İadeler (RMA): müşteri talep eder, satıcı onaylar ve teslim alır, iade ödemesi durum makinesi üzerinden yapılır
+	returnService, err := services.NewReturnService(repo, paymentService, services.ReturnServiceConfig{
+		Ledger:              vendorLedgerService,
+		Inventory:           inventorySyncService,
+		Cache:               entityCache,
+		StateMachine:        orderStateMachine,
+		NotificationService: notificationService,
+		Logger:              MainLogger,
+	})
+	if err != nil {
+		MainLogger.Fatalf("İade servisi oluşturulamadı: %v", err)
+	}
+	returnHandler := handlers.NewReturnHandler(h, returnService, vendorService)
+
 	// Security handler'ı oluştur
 	securityHandler := handlers.NewSecurityHandler(h)
 
@@ -812,7 +1027,45 @@ func main() {
 	// Yeni gelişmiş handler'lar
 	aiAdvancedHandler := handlers.NewAIAdvancedHandler(h, aiAdvancedService)
 	marketplaceHandler := handlers.NewMarketplaceHandler(h, marketplaceService)
-	paymentHandler := handlers.NewPaymentHandler(h, paymentService, orderService)
+	marketplaceCatalogHandler := handlers.NewMarketplaceCatalogHandler(h, marketplaceCatalogService, vendorService, productService)
+	inventorySyncHandler := handlers.NewInventorySyncHandler(h, inventorySyncService)
+	marketplacePricingHandler := handlers.NewMarketplacePricingHandler(h, repricingService)
+	paymentHandler := handlers.NewPaymentHandler(h, paymentService, orderService, checkoutService)
+
+	// İş zamanlayıcısı: tekrarlayan işler cron ifadeleriyle kalıcı kuyruğa eklenir, lider kilidi sayesinde yalnızca bir instance tetikler
+	MainLogger.Println("İş zamanlayıcısı başlatılıyor...")
+	scheduler := jobs.NewScheduler(primaryRepo, jobManager, jobs.SchedulerConfig{Logger: MainLogger})
+	scheduledJobs := services.ScheduledJobsConfig{
+		AuctionService:      auctionService,
+		NotificationService: notificationService,
+		ReportManager:       reportManager,
+		EmailService:        mailService,
+		SessionManager:      sessionManager,
+		CheckoutService:     checkoutService,
+		OrderStateMachine:   orderStateMachine,
+		VendorLedger:        vendorLedgerService,
+		MarketplaceSync:     marketplaceSyncService,
+		Webhooks:            webhookService,
+		WholesaleService:    wholesaleService,
+		Reconciliation:      reconciliationService,
+		MarketplaceOrders:   orderImportService,
+		InventorySync:       inventorySyncService,
+		Repricing:           repricingService,
+		MarketplaceCatalog:  marketplaceCatalogService,
+		Logger:              MainLogger,
+	}
+	if err := services.RegisterScheduledJobs(jobManager, scheduler, scheduledJobs); err != nil {
+		MainLogger.Printf("Zamanlanmış işler kaydedilemedi: %v", err)
+	}
+	// İşçiler, alınan her işin handler'ı kayıtlı olsun diye kayıttan sonra başlar
+	jobManager.Start()
+	apiMiddleware := api.NewAPIMiddleware(securityManager, sessionManager, errorManager, cacheManager, &api.APIConfig{
+		Version:        "v1",
+		RequestTimeout: 30 * time.Second,
+		MaxRequestSize: 1 << 20,
+	})
+	schedulerMux := http.NewServeMux()
+	api.NewSchedulerHandlers(apiMiddleware, scheduler).RegisterRoutes(schedulerMux)
 
 	// Middleware stack oluştur
 	middlewareStack := middleware.NewMiddlewareStack(
@@ -1006,14 +1259,48 @@ func main() {
 	appRouter.HandleFunc("/api/marketplace/create-shipment", marketplaceHandler.CreateShipment)
 	appRouter.HandleFunc("/api/marketplace/generate-invoice", marketplaceHandler.GenerateInvoice)
 	appRouter.HandleFunc("/api/marketplace/update-inventory", marketplaceHandler.UpdateInventory)
+
+	// Pazaryeri kategori ve özellik eşleştirme rotaları (satıcı)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/categories", marketplaceCatalogHandler.SearchCategories)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/categories/{category}/attributes", marketplaceCatalogHandler.GetCategoryAttributes)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/category-mappings", marketplaceCatalogHandler.CategoryMappings)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/category-mappings/{id}", marketplaceCatalogHandler.DeleteCategoryMapping)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/attribute-mappings", marketplaceCatalogHandler.AttributeMappings)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/attribute-mappings/{id}", marketplaceCatalogHandler.DeleteAttributeMapping)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/suggestions", marketplaceCatalogHandler.SuggestCategories)
+	appRouter.HandleFunc("/api/seller/marketplace/catalog/{integration}/products/{id}/validate", marketplaceCatalogHandler.ValidateProduct)
 	
+	// Toptan satış rotaları
+	appRouter.HandleFunc("/api/wholesale/register", wholesaleHandler.RegisterCustomer)
+	appRouter.HandleFunc("/api/wholesale/credit", wholesaleHandler.GetCreditStatus)
+	appRouter.HandleFunc("/api/wholesale/orders", wholesaleHandler.Orders)
+	appRouter.HandleFunc("/api/wholesale/quotes", wholesaleHandler.RequestQuote)
+	appRouter.HandleFunc("/api/wholesale/quotes/{id}/accept", wholesaleHandler.AcceptQuote)
+
+	// Sipariş ve iade API'leri
+	appRouter.HandleFunc("/api/orders", ecommerceHandler.GetMyOrders)
+	appRouter.HandleFunc("/api/orders/{id}", ecommerceHandler.GetMyOrder)
+	appRouter.HandleFunc("/api/returns", returnHandler.Returns)
+	appRouter.HandleFunc("/api/returns/{id}", returnHandler.GetReturn)
+	appRouter.HandleFunc("/api/returns/{id}/cancel", returnHandler.CancelReturn)
+	appRouter.HandleFunc("/api/returns/{id}/ship", returnHandler.ShipReturn)
+	appRouter.HandleFunc("/api/seller/returns", returnHandler.SellerReturns)
+	appRouter.HandleFunc("/api/seller/returns/{id}/approve", returnHandler.ApproveReturn)
+	appRouter.HandleFunc("/api/seller/returns/{id}/reject", returnHandler.RejectReturn)
+	appRouter.HandleFunc("/api/seller/returns/{id}/receive", returnHandler.ReceiveReturn)
+
 	// Integration webhook endpoints
-	appRouter.HandleFunc("/webhooks/integration", webhookService.HandleWebhook)
+	if webhookService != nil {
+		appRouter.HandleFunc("/webhooks/integration", webhookService.HandleWebhook)
+	}
 	
 	// Payment endpoints
 	appRouter.HandleFunc("/payment/checkout", paymentHandler.PaymentPage)
 	appRouter.HandleFunc("/payment/success", paymentHandler.PaymentSuccess)
 	appRouter.HandleFunc("/payment/failure", paymentHandler.PaymentFailure)
+	// Bankanın 3-D Secure sonucu ve ödeme altyapısının durum bildirimleri siparişlere uygulanır
+	appRouter.HandleFunc("/payment/3ds/callback", paymentHandler.Complete3DSecure)
+	appRouter.HandleFunc("/webhooks/payment", paymentHandler.PaymentWebhook)
 	
 	// Payment API endpoints
 	appRouter.HandleFunc("/api/payment/intent", paymentHandler.CreatePaymentIntent)
@@ -1237,6 +1524,7 @@ func main() {
 	appRouter.Handle("/admin/vendors", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.AdminVendors)))
 	appRouter.Handle("/admin/system-health", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.AdminSystemHealth)))
 	appRouter.Handle("/admin/seo", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.AdminSEO)))
+	appRouter.Handle("/admin/marketplace-sync", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.AdminMarketplaceSync)))
 
 	// Admin API rotaları - Admin middleware ile korumalı
 	appRouter.Handle("/api/admin/users/stats", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIGetUserStats)))
@@ -1251,6 +1539,33 @@ func main() {
 	appRouter.Handle("/api/admin/system/health", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APISystemHealthCheck)))
 	appRouter.Handle("/api/admin/seo/sitemap", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIGenerateSitemap)))
 	appRouter.Handle("/api/admin/seo/analyze", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIAnalyzeSEO)))
+	appRouter.Handle("/api/admin/marketplace/sync-runs/{id}/retry", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIRetryMarketplaceSyncRun)))
+	appRouter.Handle("/api/admin/marketplace/catalog/refresh", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplaceCatalogHandler.APIRefreshCatalogs)))
+	appRouter.Handle("/api/admin/marketplace/catalog/{integration}/category-mappings", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplaceCatalogHandler.APICategoryMappings)))
+	appRouter.Handle("/api/admin/marketplace/catalog/{integration}/category-mappings/{id}", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplaceCatalogHandler.APIDeleteCategoryMapping)))
+	appRouter.Handle("/api/admin/marketplace/catalog/{integration}/attribute-mappings", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplaceCatalogHandler.APIAttributeMappings)))
+	appRouter.Handle("/api/admin/marketplace/catalog/{integration}/attribute-mappings/{id}", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplaceCatalogHandler.APIDeleteAttributeMapping)))
+	appRouter.Handle("/api/admin/marketplace/price-rules", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIPriceRules)))
+	appRouter.Handle("/api/admin/marketplace/price-rules/{id}", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIPriceRule)))
+	appRouter.Handle("/api/admin/marketplace/price-promotions", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIPricePromotions)))
+	appRouter.Handle("/api/admin/marketplace/price-promotions/{id}", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIDeletePricePromotion)))
+	appRouter.Handle("/api/admin/marketplace/pricing/{integration}/preview", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIPreviewPrices)))
+	appRouter.Handle("/api/admin/marketplace/pricing/{integration}/reprice", middlewareStack.AdminMiddleware(http.HandlerFunc(marketplacePricingHandler.APIReprice)))
+	appRouter.Handle("/api/admin/webhooks/events", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIListWebhookEvents)))
+	appRouter.Handle("/api/admin/webhooks/events/{id}/replay", middlewareStack.AdminMiddleware(http.HandlerFunc(adminHandler.APIReplayWebhookEvent)))
+	appRouter.Handle("/api/admin/wholesale/customers/{id}/approve", middlewareStack.AdminMiddleware(http.HandlerFunc(wholesaleHandler.APIApproveCustomer)))
+	appRouter.Handle("/api/admin/wholesale/orders/{id}/payment", middlewareStack.AdminMiddleware(http.HandlerFunc(wholesaleHandler.APIRecordPayment)))
+	appRouter.Handle("/api/admin/wholesale/orders/{id}/cancel", middlewareStack.AdminMiddleware(http.HandlerFunc(wholesaleHandler.APICancelOrder)))
+	appRouter.Handle("/api/admin/returns/{id}/refund", middlewareStack.AdminMiddleware(http.HandlerFunc(returnHandler.APIRefundReturn)))
+	appRouter.Handle("/api/v1/admin/schedules", middlewareStack.AdminMiddleware(schedulerMux))
+	appRouter.Handle("/api/v1/admin/schedules/", middlewareStack.AdminMiddleware(schedulerMux))
+	appRouter.Handle("/api/admin/database/pools", middlewareStack.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"pools":   database.GlobalDBManager.PoolStats(),
+		})
+	})))
 
 	// Seller rotaları - Authentication middleware ile korumalı
 	appRouter.HandleFunc("/seller/dashboard", sellerHandler.Dashboard)
@@ -1274,6 +1589,11 @@ func main() {
 	appRouter.Handle("/api/inventory/alerts", middlewareStack.AdminMiddleware(http.HandlerFunc(inventoryHandler.APIGetAlerts)))
 	appRouter.Handle("/api/inventory/dismiss-alert", middlewareStack.AdminMiddleware(http.HandlerFunc(inventoryHandler.APIDismissAlert)))
 
+	// Pazaryeri stok dağıtım kuralları
+	appRouter.Handle("/api/admin/inventory/allocation-rules", middlewareStack.AdminMiddleware(http.HandlerFunc(inventorySyncHandler.APIAllocationRules)))
+	appRouter.Handle("/api/admin/inventory/allocation-rules/{id}", middlewareStack.AdminMiddleware(http.HandlerFunc(inventorySyncHandler.APIAllocationRule)))
+	appRouter.Handle("/api/admin/inventory/products/{id}/channels", middlewareStack.AdminMiddleware(http.HandlerFunc(inventorySyncHandler.APIProductChannels)))
+
 	// Notification rotaları - Admin middleware ile korumalı
 	appRouter.Handle("/notifications/dashboard", middlewareStack.AdminMiddleware(http.HandlerFunc(notificationHandler.Dashboard)))
 	appRouter.Handle("/notifications/templates", middlewareStack.AdminMiddleware(http.HandlerFunc(notificationHandler.Templates)))
@@ -1341,9 +1661,15 @@ func main() {
 	addr := fmt.Sprintf(":%d", cfg.Server.Port)
 	MainLogger.Printf("Enterprise sunucu başlatılıyor: %s", addr)
 
+	// Her istek kendi okuma oturumunu alır; istek bağlamına bağlanan
+	// repository'ler yazdıktan sonra birincil veritabanından okur
+	rootHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appRouter.ServeHTTP(w, r.WithContext(database.WithReadSession(r.Context())))
+	})
+
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      appRouter,
+		Handler:      rootHandler,
 		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
 		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
@@ -1360,12 +1686,11 @@ func main() {
 		},
 	}
 
-	// Graceful shutdown için background process'ler başlat
-	go func() {
-		// Background processes would be started here in a real implementation
-		// For now, just log that they would be running
-		MainLogger.Println("Background processes started (session cleanup, notifications, cache cleanup, error cleanup)")
-	}()
+	// Arka plan işleri: açık artırma kapanışları, oturum temizliği, webhook tekrarları ve
+	// pazaryeri senkronizasyonu zamanlayıcı üzerinden iş kuyruğunda çalışır
+	scheduler.Start()
+	defer scheduler.Stop()
+	MainLogger.Println("İş zamanlayıcısı başlatıldı")
 
 	MainLogger.Printf("KolajAI Enterprise uygulaması başlatıldı. %s adresinde dinleniyor...", addr)
 	MainLogger.Printf("Tüm gelişmiş sistemler aktif: Session, Cache, Security, SEO, Notifications, Reporting, Testing, Error Management")
@@ -14,9 +14,7 @@ require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/hashicorp/vault/api v1.20.0
 	github.com/mattn/go-sqlite3 v1.14.30
-	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/pquerna/otp v1.5.0
-	github.com/sony/gobreaker v0.5.0
 	golang.org/x/crypto v0.39.0
 	gopkg.in/yaml.v2 v2.4.0
 )
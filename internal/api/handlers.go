@@ -124,6 +124,13 @@ func (h *APIHandlers) handleProducts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *APIHandlers) getProducts(w http.ResponseWriter, r *http.Request) {
+	// Offset pages are kept for clients that ask for a page number; everyone
+	// else pages with cursors
+	if r.URL.Query().Get("page") == "" {
+		h.getProductsPage(w, r, productFilters(r))
+		return
+	}
+
 	// Parse query parameters
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
@@ -135,10 +142,6 @@ func (h *APIHandlers) getProducts(w http.ResponseWriter, r *http.Request) {
 		limit = services.DefaultProductLimit
 	}
 
-	category := r.URL.Query().Get("category")
-	vendor := r.URL.Query().Get("vendor")
-	minPrice, _ := strconv.ParseFloat(r.URL.Query().Get("min_price"), 64)
-	maxPrice, _ := strconv.ParseFloat(r.URL.Query().Get("max_price"), 64)
 	sortBy := r.URL.Query().Get("sort_by")
 	if sortBy == "" {
 		sortBy = "created_at"
@@ -149,21 +152,7 @@ func (h *APIHandlers) getProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	offset := (page - 1) * limit
-
-	// Build filters
-	filters := map[string]interface{}{}
-	if category != "" {
-		filters["category_id"] = category
-	}
-	if vendor != "" {
-		filters["vendor_id"] = vendor
-	}
-	if minPrice > 0 {
-		filters["min_price"] = minPrice
-	}
-	if maxPrice > 0 {
-		filters["max_price"] = maxPrice
-	}
+	filters := productFilters(r)
 
 	// Get products
 	products, err := h.productService.GetProductsWithFilters(filters, sortBy, sortOrder, limit, offset)
@@ -191,6 +180,41 @@ func (h *APIHandlers) getProducts(w http.ResponseWriter, r *http.Request) {
 	h.middleware.SendSuccessResponse(w, r, products, meta)
 }
 
+// getProductsPage sends a cursor page of the products matching filters
+func (h *APIHandlers) getProductsPage(w http.ResponseWriter, r *http.Request, filters map[string]interface{}) {
+	params := cursorParams(r)
+	if params.Limit > services.MaxProductLimit {
+		params.Limit = services.MaxProductLimit
+	}
+
+	products, result, err := h.productService.GetProductsPage(filters, params)
+	if err != nil {
+		h.sendPageError(w, r, err, "Failed to fetch products")
+		return
+	}
+
+	h.middleware.SendSuccessResponse(w, r, products, cursorMeta(w, r, params, result))
+}
+
+// productFilters reads product list filters from the query
+func productFilters(r *http.Request) map[string]interface{} {
+	query := r.URL.Query()
+	filters := map[string]interface{}{}
+	if category := query.Get("category"); category != "" {
+		filters["category"] = category
+	}
+	if vendorID, err := strconv.ParseInt(query.Get("vendor"), 10, 64); err == nil && vendorID > 0 {
+		filters["vendor_id"] = vendorID
+	}
+	if minPrice, _ := strconv.ParseFloat(query.Get("min_price"), 64); minPrice > 0 {
+		filters["min_price"] = minPrice
+	}
+	if maxPrice, _ := strconv.ParseFloat(query.Get("max_price"), 64); maxPrice > 0 {
+		filters["max_price"] = maxPrice
+	}
+	return filters
+}
+
 func (h *APIHandlers) createProduct(w http.ResponseWriter, r *http.Request) {
 	// Check if user is authenticated and authorized
 	userID := h.getUserIDFromContext(r)
@@ -521,7 +545,29 @@ func (h *APIHandlers) handleAddToCart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *APIHandlers) handleOrders(w http.ResponseWriter, r *http.Request) {
-	// Orders implementation
+	switch r.Method {
+	case http.MethodGet:
+		h.getOrders(w, r)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+	}
+}
+
+// getOrders sends a cursor page of all orders, newest first, to admins
+func (h *APIHandlers) getOrders(w http.ResponseWriter, r *http.Request) {
+	if !h.middleware.IsAdmin(r) {
+		h.sendError(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	params := cursorParams(r)
+	orders, result, err := h.orderService.GetOrdersPage(params)
+	if err != nil {
+		h.sendPageError(w, r, err, "Failed to fetch orders")
+		return
+	}
+
+	h.middleware.SendSuccessResponse(w, r, orders, cursorMeta(w, r, params, result))
 }
 
 func (h *APIHandlers) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -1031,30 +1077,15 @@ func (h *APIHandlers) handleVendorProducts(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// getVendorProducts returns products for a specific vendor
+// getVendorProducts returns a cursor page of a vendor's products
 func (h *APIHandlers) getVendorProducts(w http.ResponseWriter, r *http.Request) {
-	// Get vendor products implementation
-	products := []map[string]interface{}{
-		{
-			"id":        1,
-			"name":      "Vendor Product 1",
-			"price":     99.99,
-			"vendor_id": 1,
-			"category":  "Electronics",
-		},
-		{
-			"id":        2,
-			"name":      "Vendor Product 2",
-			"price":     149.99,
-			"vendor_id": 1,
-			"category":  "Clothing",
-		},
+	vendorID, err := strconv.ParseInt(r.URL.Query().Get("vendor_id"), 10, 64)
+	if err != nil || vendorID <= 0 {
+		h.sendError(w, r, http.StatusBadRequest, "INVALID_VENDOR_ID", "Invalid vendor ID")
+		return
 	}
-	
-	h.sendResponse(w, map[string]interface{}{
-		"products": products,
-		"total":    len(products),
-	})
+
+	h.getProductsPage(w, r, map[string]interface{}{"vendor_id": vendorID})
 }
 
 // handleVendorOrders handles vendor orders API requests
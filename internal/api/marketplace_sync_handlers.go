@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kolajAi/internal/services"
+)
+
+// MarketplaceSyncHandlers provides admin endpoints for marketplace sync runs
+type MarketplaceSyncHandlers struct {
+	middleware *APIMiddleware
+	syncRuns   *services.MarketplaceSyncService
+}
+
+// NewMarketplaceSyncHandlers creates new marketplace sync handlers
+func NewMarketplaceSyncHandlers(middleware *APIMiddleware, syncRuns *services.MarketplaceSyncService) *MarketplaceSyncHandlers {
+	return &MarketplaceSyncHandlers{
+		middleware: middleware,
+		syncRuns:   syncRuns,
+	}
+}
+
+// RegisterRoutes registers marketplace sync admin routes
+func (h *MarketplaceSyncHandlers) RegisterRoutes(mux *http.ServeMux) {
+	apiV1 := "/api/v1"
+
+	mux.HandleFunc(apiV1+"/admin/marketplace/sync-runs", h.middleware.APIHandler(h.handleRuns))
+	mux.HandleFunc(apiV1+"/admin/marketplace/sync-runs/", h.middleware.APIHandler(h.handleRunAction))
+	mux.HandleFunc(apiV1+"/admin/marketplace/products/", h.middleware.APIHandler(h.handleProductSyncStatus))
+}
+
+// handleRuns lists sync runs, filtered by ?integration= and ?status=
+func (h *MarketplaceSyncHandlers) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+	if !h.middleware.IsAdmin(r) {
+		h.middleware.sendErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	runs, total, err := h.syncRuns.ListRuns(r.URL.Query().Get("integration"), r.URL.Query().Get("status"), perPage, (page-1)*perPage)
+	if err != nil {
+		h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SYNC_RUN_LIST_ERROR", "Failed to list sync runs")
+		return
+	}
+
+	h.middleware.SendSuccessResponse(w, r, runs, &APIMeta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	})
+}
+
+// handleRunAction handles /admin/marketplace/sync-runs/{id} with the run's
+// items, filtered by ?status=, and /admin/marketplace/sync-runs/{id}/retry
+func (h *MarketplaceSyncHandlers) handleRunAction(w http.ResponseWriter, r *http.Request) {
+	if !h.middleware.IsAdmin(r) {
+		h.middleware.sendErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/marketplace/sync-runs/"), "/")
+	parts := strings.Split(path, "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 {
+		h.middleware.sendErrorResponse(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid sync run ID")
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != "GET" {
+			h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+			return
+		}
+		run, err := h.syncRuns.GetRun(id)
+		if err != nil {
+			h.sendRunError(w, r, err)
+			return
+		}
+		items, err := h.syncRuns.GetRunItems(id, r.URL.Query().Get("status"))
+		if err != nil {
+			h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SYNC_RUN_ERROR", "Failed to get sync run items")
+			return
+		}
+		h.middleware.SendSuccessResponse(w, r, map[string]interface{}{
+			"run":   run,
+			"items": items,
+		}, nil)
+		return
+	}
+
+	if len(parts) != 2 || parts[1] != "retry" {
+		h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "NOT_FOUND", "Unknown sync run action")
+		return
+	}
+	if r.Method != "POST" {
+		h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+
+	run, err := h.syncRuns.Retry(id)
+	if err != nil {
+		h.sendRunError(w, r, err)
+		return
+	}
+	h.middleware.SendSuccessResponse(w, r, run, nil)
+}
+
+// handleProductSyncStatus handles /admin/marketplace/products/{id}/sync-status
+// with the latest outcome of the product on each marketplace
+func (h *MarketplaceSyncHandlers) handleProductSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+	if !h.middleware.IsAdmin(r) {
+		h.middleware.sendErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/marketplace/products/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "sync-status" {
+		h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "NOT_FOUND", "Not found")
+		return
+	}
+	productID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || productID <= 0 {
+		h.middleware.sendErrorResponse(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid product ID")
+		return
+	}
+
+	items, err := h.syncRuns.GetProductSyncStatus(productID)
+	if err != nil {
+		h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SYNC_STATUS_ERROR", "Failed to get product sync status")
+		return
+	}
+	h.middleware.SendSuccessResponse(w, r, items, &APIMeta{Total: len(items)})
+}
+
+// sendRunError maps sync run errors to responses
+func (h *MarketplaceSyncHandlers) sendRunError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrSyncRunNotFound):
+		h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "SYNC_RUN_NOT_FOUND", "Sync run not found")
+	case errors.Is(err, services.ErrNothingToRetry):
+		h.middleware.sendErrorResponse(w, r, http.StatusConflict, "NOTHING_TO_RETRY", err.Error())
+	default:
+		h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SYNC_RUN_ERROR", "Failed to process sync run")
+	}
+}
@@ -67,6 +67,13 @@ type APIMeta struct {
 	PerPage    int `json:"per_page,omitempty"`
 	Total      int `json:"total,omitempty"`
 	TotalPages int `json:"total_pages,omitempty"`
+	
+	// Cursor pagination; Next and Prev are the URLs of the pages around this one
+	HasMore    bool   `json:"has_more,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
 }
 
 // NewAPIMiddleware creates new API middleware
@@ -387,6 +394,26 @@ func (m *APIMiddleware) SendSuccessResponse(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
+// IsAdmin reports whether the request belongs to a session with admin permission
+func (m *APIMiddleware) IsAdmin(r *http.Request) bool {
+	if m.SessionManager == nil {
+		return false
+	}
+	
+	sessionData, err := m.SessionManager.GetSession(r)
+	if err != nil || sessionData == nil || !sessionData.IsActive {
+		return false
+	}
+	
+	for _, permission := range sessionData.Permissions {
+		if permission == "admin" || permission == "*" {
+			return true
+		}
+	}
+	
+	return false
+}
+
 // isOriginAllowed checks if origin is allowed
 func (m *APIMiddleware) isOriginAllowed(origin string) bool {
 	for _, allowed := range m.Config.AllowedOrigins {
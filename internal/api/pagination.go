@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kolajAi/internal/database"
+)
+
+// cursorParams reads cursor pagination parameters from the cursor, limit,
+// sort_by and sort_order query parameters
+func cursorParams(r *http.Request) database.CursorPaginationParams {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	params := database.CursorPaginationParams{
+		Cursor:  query.Get("cursor"),
+		Limit:   limit,
+		OrderBy: query.Get("sort_by"),
+		Order:   query.Get("sort_order"),
+	}
+	params.Validate()
+	return params
+}
+
+// cursorMeta returns the meta of a cursor page with the URLs of the pages
+// around it, which are also sent in a Link header
+func cursorMeta(w http.ResponseWriter, r *http.Request, params database.CursorPaginationParams, result database.CursorPaginationResult) *APIMeta {
+	meta := &APIMeta{
+		PerPage:    params.Limit,
+		HasMore:    result.HasMore,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	}
+	if result.NextCursor != "" {
+		meta.Next = pageURL(r, result.NextCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, meta.Next))
+	}
+	if result.PrevCursor != "" {
+		meta.Prev = pageURL(r, result.PrevCursor)
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="prev"`, meta.Prev))
+	}
+	return meta
+}
+
+// pageURL returns the request's URL with its cursor replaced
+func pageURL(r *http.Request, cursor string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("cursor", cursor)
+	query.Del("page")
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// sendPageError answers a failed page read. Cursors that were tampered with
+// or issued for another list are the client's error.
+func (h *APIHandlers) sendPageError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, database.ErrInvalidCursor) {
+		h.sendError(w, r, http.StatusBadRequest, "INVALID_CURSOR", "Invalid cursor")
+		return
+	}
+	h.sendError(w, r, http.StatusInternalServerError, "DATABASE_ERROR", message)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"kolajAi/internal/jobs"
+)
+
+// SchedulerHandlers provides admin endpoints for recurring job schedules
+type SchedulerHandlers struct {
+	middleware *APIMiddleware
+	scheduler  *jobs.Scheduler
+}
+
+// NewSchedulerHandlers creates new scheduler handlers
+func NewSchedulerHandlers(middleware *APIMiddleware, scheduler *jobs.Scheduler) *SchedulerHandlers {
+	return &SchedulerHandlers{
+		middleware: middleware,
+		scheduler:  scheduler,
+	}
+}
+
+// RegisterRoutes registers scheduler admin routes
+func (h *SchedulerHandlers) RegisterRoutes(mux *http.ServeMux) {
+	apiV1 := "/api/v1"
+
+	mux.HandleFunc(apiV1+"/admin/schedules", h.middleware.APIHandler(h.handleSchedules))
+	mux.HandleFunc(apiV1+"/admin/schedules/", h.middleware.APIHandler(h.handleScheduleAction))
+}
+
+// handleSchedules lists all schedules
+func (h *SchedulerHandlers) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+	if !h.middleware.IsAdmin(r) {
+		h.middleware.sendErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	schedules, err := h.scheduler.List()
+	if err != nil {
+		h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SCHEDULE_LIST_ERROR", "Failed to list schedules")
+		return
+	}
+
+	h.middleware.SendSuccessResponse(w, r, schedules, &APIMeta{Total: len(schedules)})
+}
+
+// handleScheduleAction handles /admin/schedules/{id}/{run|enable|disable}
+func (h *SchedulerHandlers) handleScheduleAction(w http.ResponseWriter, r *http.Request) {
+	if !h.middleware.IsAdmin(r) {
+		h.middleware.sendErrorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "Admin access required")
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/schedules/"), "/")
+	parts := strings.Split(path, "/")
+	if parts[0] == "" {
+		h.middleware.sendErrorResponse(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid schedule ID")
+		return
+	}
+	id := parts[0]
+
+	if len(parts) == 1 {
+		if r.Method != "GET" {
+			h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+			return
+		}
+		schedule, err := h.scheduler.Get(id)
+		if err != nil {
+			h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "Schedule not found")
+			return
+		}
+		h.middleware.SendSuccessResponse(w, r, schedule, nil)
+		return
+	}
+
+	if r.Method != "POST" {
+		h.middleware.sendErrorResponse(w, r, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed")
+		return
+	}
+
+	switch parts[1] {
+	case "run":
+		job, err := h.scheduler.RunNow(id)
+		if err != nil {
+			h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SCHEDULE_RUN_ERROR", err.Error())
+			return
+		}
+		h.middleware.SendSuccessResponse(w, r, job, nil)
+	case "enable", "disable":
+		if err := h.scheduler.SetEnabled(id, parts[1] == "enable"); err != nil {
+			h.middleware.sendErrorResponse(w, r, http.StatusInternalServerError, "SCHEDULE_UPDATE_ERROR", err.Error())
+			return
+		}
+		schedule, err := h.scheduler.Get(id)
+		if err != nil {
+			h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "Schedule not found")
+			return
+		}
+		h.middleware.SendSuccessResponse(w, r, schedule, nil)
+	default:
+		h.middleware.sendErrorResponse(w, r, http.StatusNotFound, "NOT_FOUND", "Unknown schedule action")
+	}
+}
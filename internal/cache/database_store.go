@@ -0,0 +1,369 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// evictionBatch is how many items a database store reads at a time while
+// evicting
+const evictionBatch = 100
+
+// DatabaseStore is a CacheStore keeping items in the cache_items table, so
+// they survive restarts and are shared by every instance using the
+// database. Several stores share the table; each one's keys are prefixed
+// with its name.
+type DatabaseStore struct {
+	db      *sql.DB
+	name    string
+	maxSize int64
+	policy  EvictionPolicy
+
+	mu    sync.Mutex
+	stats StoreStats
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDatabaseStore creates a store named name in db's cache_items table,
+// which the cache_tables migration creates. MaxSize limits the bytes of values it holds,
+// 0 meaning no limit. The random eviction policy evicts in FIFO order.
+func NewDatabaseStore(db *sql.DB, name string, config StoreConfig) *DatabaseStore {
+	policy := config.EvictionPolicy
+	if policy == "" {
+		policy = EvictionLRU
+	}
+
+	s := &DatabaseStore{
+		db:      db,
+		name:    name,
+		maxSize: config.MaxSize,
+		policy:  policy,
+		stop:    make(chan struct{}),
+	}
+	go s.cleanup(durationSetting(config.Settings, "cleanup_interval", defaultCleanupInterval))
+	return s
+}
+
+func (s *DatabaseStore) storeKey(key string) string {
+	return s.name + ":" + key
+}
+
+// Get returns the value of key, or ErrCacheMiss
+func (s *DatabaseStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	var expiresAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		"SELECT value, expires_at FROM cache_items WHERE cache_key = ?",
+		s.storeKey(key)).Scan(&value, &expiresAt)
+	if err == sql.ErrNoRows || (err == nil && expiresAt.Valid && time.Now().After(expiresAt.Time)) {
+		s.record(func(stats *StoreStats) { stats.Misses++ })
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache item: %w", err)
+	}
+
+	// Only eviction reads the access columns, so they are left alone when
+	// nothing is ever evicted by them
+	if s.maxSize > 0 && (s.policy == EvictionLRU || s.policy == EvictionLFU) {
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE cache_items SET accessed_at = ?, access_count = access_count + 1 WHERE cache_key = ?",
+			time.Now().UTC(), s.storeKey(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update cache item access: %w", err)
+		}
+	}
+
+	s.record(func(stats *StoreStats) {
+		stats.Hits++
+		stats.LastAccess = time.Now()
+	})
+	return value, nil
+}
+
+// Set stores value under key for ttl, or without expiry when ttl is 0
+func (s *DatabaseStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores value under key like Set, tagging it with tags
+func (s *DatabaseStore) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	if s.maxSize > 0 && int64(len(value)) > s.maxSize {
+		return ErrValueTooLarge
+	}
+
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache item tags: %w", err)
+	}
+	now := time.Now().UTC()
+	var expiresAt interface{}
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start cache transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM cache_items WHERE cache_key = ?", s.storeKey(key)); err != nil {
+		return fmt.Errorf("failed to replace cache item: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO cache_items
+		(cache_key, store_name, value, ttl_seconds, created_at, accessed_at, access_count, size_bytes, tags, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?)`,
+		s.storeKey(key), s.name, value, int(ttl.Seconds()), now, now, len(value), string(tagsJSON), expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to set cache item: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cache item: %w", err)
+	}
+	s.record(func(stats *StoreStats) { stats.Sets++ })
+
+	if s.maxSize > 0 {
+		return s.evict(ctx)
+	}
+	return nil
+}
+
+// evict removes expired items and then, while the store holds more than
+// MaxSize bytes, items in the order of its eviction policy
+func (s *DatabaseStore) evict(ctx context.Context) error {
+	if err := s.deleteExpired(ctx); err != nil {
+		return err
+	}
+
+	var size int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(size_bytes), 0) FROM cache_items WHERE store_name = ?", s.name).Scan(&size)
+	if err != nil {
+		return fmt.Errorf("failed to get cache size: %w", err)
+	}
+
+	var order string
+	switch s.policy {
+	case EvictionLFU:
+		order = "access_count, accessed_at"
+	case EvictionFIFO, EvictionRandom:
+		order = "created_at"
+	case EvictionTTL:
+		order = "expires_at IS NULL, expires_at, created_at"
+	default:
+		order = "accessed_at"
+	}
+
+	for size > s.maxSize {
+		rows, err := s.db.QueryContext(ctx,
+			"SELECT cache_key, size_bytes FROM cache_items WHERE store_name = ? ORDER BY "+order+" LIMIT ?",
+			s.name, evictionBatch)
+		if err != nil {
+			return fmt.Errorf("failed to get cache items to evict: %w", err)
+		}
+		type victim struct {
+			key  string
+			size int64
+		}
+		var victims []victim
+		for rows.Next() {
+			var v victim
+			if err := rows.Scan(&v.key, &v.size); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan cache item: %w", err)
+			}
+			victims = append(victims, v)
+		}
+		rows.Close()
+		if len(victims) == 0 {
+			return nil
+		}
+
+		for _, v := range victims {
+			if size <= s.maxSize {
+				break
+			}
+			if _, err := s.db.ExecContext(ctx, "DELETE FROM cache_items WHERE cache_key = ?", v.key); err != nil {
+				return fmt.Errorf("failed to evict cache item: %w", err)
+			}
+			size -= v.size
+			s.record(func(stats *StoreStats) { stats.Evictions++ })
+		}
+	}
+	return nil
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (s *DatabaseStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM cache_items WHERE cache_key = ?", s.storeKey(key)); err != nil {
+		return fmt.Errorf("failed to delete cache item: %w", err)
+	}
+	s.record(func(stats *StoreStats) { stats.Deletes++ })
+	return nil
+}
+
+// Exists reports whether key is in the store and has not expired
+func (s *DatabaseStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.TTL(ctx, key)
+	if err == ErrCacheMiss {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Clear removes every item of the store
+func (s *DatabaseStore) Clear(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM cache_items WHERE store_name = ?", s.name); err != nil {
+		return fmt.Errorf("failed to clear cache store: %w", err)
+	}
+	return nil
+}
+
+// Keys returns the keys matching pattern, in which * matches any run of
+// characters and ? any one character. An empty pattern matches every key.
+func (s *DatabaseStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	return s.keys(ctx, "cache_key LIKE ? ESCAPE '!'", likePattern(s.storeKey(""))+globToLike(pattern))
+}
+
+// KeysByTags returns the keys tagged with any of tags
+func (s *DatabaseStore) KeysByTags(ctx context.Context, tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	conditions := make([]string, len(tags))
+	args := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		encoded, _ := json.Marshal(tag)
+		conditions[i] = "tags LIKE ? ESCAPE '!'"
+		args[i] = "%" + likePattern(string(encoded)) + "%"
+	}
+	return s.keys(ctx, "("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// keys returns the unexpired keys of the store's items matching where
+func (s *DatabaseStore) keys(ctx context.Context, where string, args ...interface{}) ([]string, error) {
+	args = append([]interface{}{s.name, time.Now().UTC()}, args...)
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT cache_key FROM cache_items WHERE store_name = ? AND (expires_at IS NULL OR expires_at > ?) AND "+where,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache keys: %w", err)
+	}
+	defer rows.Close()
+
+	prefix := s.storeKey("")
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan cache key: %w", err)
+		}
+		keys = append(keys, strings.TrimPrefix(key, prefix))
+	}
+	return keys, rows.Err()
+}
+
+// TTL returns how long key has left, or 0 when it does not expire
+func (s *DatabaseStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var expiresAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		"SELECT expires_at FROM cache_items WHERE cache_key = ?", s.storeKey(key)).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrCacheMiss
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cache item TTL: %w", err)
+	}
+	if !expiresAt.Valid {
+		return 0, nil
+	}
+	ttl := time.Until(expiresAt.Time)
+	if ttl <= 0 {
+		return 0, ErrCacheMiss
+	}
+	return ttl, nil
+}
+
+// Size returns the number of unexpired items in the store
+func (s *DatabaseStore) Size(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM cache_items WHERE store_name = ? AND (expires_at IS NULL OR expires_at > ?)",
+		s.name, time.Now().UTC()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cache size: %w", err)
+	}
+	return count, nil
+}
+
+// Stats returns the store's statistics. Counters are this process's; item
+// count and memory usage are the table's.
+func (s *DatabaseStore) Stats(ctx context.Context) (*StoreStats, error) {
+	var count, size int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(size_bytes), 0) FROM cache_items WHERE store_name = ?",
+		s.name).Scan(&count, &size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+
+	s.mu.Lock()
+	stats := s.stats
+	s.mu.Unlock()
+	stats.ItemCount = count
+	stats.MemoryUsage = size
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return &stats, nil
+}
+
+// Close stops the store's cleanup of expired items. The database is the
+// caller's to close.
+func (s *DatabaseStore) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *DatabaseStore) record(update func(stats *StoreStats)) {
+	s.mu.Lock()
+	update(&s.stats)
+	s.mu.Unlock()
+}
+
+func (s *DatabaseStore) deleteExpired(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx,
+		"DELETE FROM cache_items WHERE store_name = ? AND expires_at IS NOT NULL AND expires_at <= ?",
+		s.name, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete expired cache items: %w", err)
+	}
+	return nil
+}
+
+// cleanup removes expired items every interval until the store is closed
+func (s *DatabaseStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.deleteExpired(context.Background())
+		}
+	}
+}
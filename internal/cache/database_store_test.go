@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"kolajAi/internal/testutil"
+)
+
+// newTestManager returns a manager on a migrated in-memory SQLite database
+func newTestManager(t *testing.T, config CacheConfig) *CacheManager {
+	t.Helper()
+	cm := NewCacheManager(testutil.NewDB(t), config)
+	t.Cleanup(func() { cm.Close() })
+	return cm
+}
+
+func TestDatabaseStore(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{})
+	store := NewDatabaseStore(cm.db, "pages", StoreConfig{MaxSize: 30, EvictionPolicy: EvictionLRU})
+	other := NewDatabaseStore(cm.db, "api", StoreConfig{})
+	defer store.Close()
+	defer other.Close()
+
+	if _, err := store.Get(ctx, "k0"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("got %v, want ErrCacheMiss", err)
+	}
+
+	fillStore(t, store, 3, noTTL)
+	other.Set(ctx, "k0", []byte("other"), 0)
+	if value, err := store.Get(ctx, "k0"); err != nil || string(value) != "12345678" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	// 8 byte values and a 30 byte limit leave room for three; k1 is least
+	// recently used
+	time.Sleep(5 * time.Millisecond)
+	store.Get(ctx, "k2")
+	if err := store.Set(ctx, "k3", []byte("12345678"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Exists(ctx, "k1"); ok {
+		t.Error("k1 was not evicted")
+	}
+	keys, _ := store.Keys(ctx, "k*")
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[k0 k2 k3]" {
+		t.Errorf("got keys %v", keys)
+	}
+	if value, err := other.Get(ctx, "k0"); err != nil || string(value) != "other" {
+		t.Errorf("other store's k0: got %q, %v", value, err)
+	}
+
+	store.SetWithTags(ctx, "k2", []byte("tagged"), time.Hour, []string{"products", "vendor_1"})
+	store.SetWithTags(ctx, "k3", []byte("tagged"), 0, []string{"vendor_10"})
+	if keys, _ := store.KeysByTags(ctx, []string{"vendor_1"}); fmt.Sprint(keys) != "[k2]" {
+		t.Errorf("got tagged keys %v", keys)
+	}
+	if ttl, err := store.TTL(ctx, "k2"); err != nil || ttl <= 59*time.Minute {
+		t.Errorf("got TTL %s, %v", ttl, err)
+	}
+
+	store.Set(ctx, "short", []byte("x"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("got %v for an expired key", err)
+	}
+
+	store.Clear(ctx)
+	if size, _ := store.Size(ctx); size != 0 {
+		t.Errorf("got %d items after clearing", size)
+	}
+	if size, _ := other.Size(ctx); size != 1 {
+		t.Errorf("clearing one store left %d items in another", size)
+	}
+}
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{})
+	l1 := NewMemoryStore(StoreConfig{})
+	l2 := NewDatabaseStore(cm.db, "tiered", StoreConfig{})
+	store := NewTieredStore(l1, l2, time.Minute)
+	defer store.Close()
+
+	if err := store.SetWithTags(ctx, "k", []byte("v"), time.Hour, []string{"t"}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := l1.TTL(ctx, "k"); ttl > time.Minute {
+		t.Errorf("L1 keeps the item for %s, want at most a minute", ttl)
+	}
+
+	// Another instance's L1 fills itself from L2
+	l1.Delete(ctx, "k")
+	if value, err := store.Get(ctx, "k"); err != nil || string(value) != "v" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+	if ok, _ := l1.Exists(ctx, "k"); !ok {
+		t.Error("L1 was not filled from L2")
+	}
+
+	if keys, _ := store.KeysByTags(ctx, []string{"t"}); fmt.Sprint(keys) != "[k]" {
+		t.Errorf("got tagged keys %v", keys)
+	}
+	store.Delete(ctx, "k")
+	if _, err := store.Get(ctx, "k"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("got %v after delete", err)
+	}
+
+	stats, err := store.Stats(ctx)
+	if err != nil || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("got %+v, %v", stats, err)
+	}
+}
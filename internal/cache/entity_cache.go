@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultEntityStore is the manager store entity caches use unless
+// configured otherwise
+const defaultEntityStore = "entities"
+
+// EntityCacheConfig configures an entity cache
+type EntityCacheConfig struct {
+	// Store is the manager store entries are kept in. It defaults to
+	// "entities" and must be registered with the manager.
+	Store string
+	// TTLs holds the TTL of each entity. Entities without one use
+	// DefaultTTL, or the store's TTL when that is 0 too.
+	TTLs       map[string]time.Duration
+	DefaultTTL time.Duration
+	Logger     *log.Logger
+}
+
+// EntityCache caches what services read from the database. Entries are
+// tagged with the rows and tables they were read from, and write paths
+// invalidate the tags of what they change, so an entry lives until its data
+// changes or its entity's TTL runs out. A nil *EntityCache caches nothing.
+type EntityCache struct {
+	manager *CacheManager
+	config  EntityCacheConfig
+	logger  *log.Logger
+
+	// generation counts invalidations. A load that overlapped one does not
+	// store its result, which may have been read before the write.
+	generation atomic.Int64
+}
+
+// NewEntityCache creates an entity cache on a store of manager
+func NewEntityCache(manager *CacheManager, config EntityCacheConfig) (*EntityCache, error) {
+	if config.Store == "" {
+		config.Store = defaultEntityStore
+	}
+	if _, exists := manager.getStore(config.Store); !exists {
+		return nil, fmt.Errorf("store %s not found", config.Store)
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &EntityCache{manager: manager, config: config, logger: logger}, nil
+}
+
+// TableTag is the tag of entries read from any row of a table, such as
+// lists and reports
+func TableTag(table string) string {
+	return "table:" + table
+}
+
+// RowTag is the tag of entries read from one row of a table
+func RowTag(table string, id int64) string {
+	return fmt.Sprintf("%s:%d", table, id)
+}
+
+// Key builds the key of an entity entry
+func (c *EntityCache) Key(entity, id string, params map[string]string) string {
+	if c == nil {
+		return ""
+	}
+	return c.manager.BuildKey(CacheKey{Namespace: "entity", Type: entity, ID: id, Params: params})
+}
+
+// TTL returns how long entries of an entity are kept
+func (c *EntityCache) TTL(entity string) time.Duration {
+	if ttl, ok := c.config.TTLs[entity]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.config.DefaultTTL
+}
+
+// Stats returns the manager's statistics of the cache's store, with its
+// hit ratio
+func (c *EntityCache) Stats() *StoreStats {
+	if stats, ok := c.manager.GetStats().StoreStats[c.config.Store]; ok {
+		return stats
+	}
+	return &StoreStats{}
+}
+
+// Invalidate drops the entries tagged with any of tags. Write paths call it
+// once their change is committed; failures are logged and the entries
+// expire with their TTL.
+func (c *EntityCache) Invalidate(tags ...string) {
+	if c == nil || len(tags) == 0 {
+		return
+	}
+	c.generation.Add(1)
+	if err := c.manager.InvalidateByTags(context.Background(), c.config.Store, tags); err != nil {
+		c.logger.Printf("Failed to invalidate cache tags %s: %v", strings.Join(tags, ","), err)
+	}
+}
+
+// Load returns the cached value of an entity entry, or calls load and
+// caches its result with tags for the entity's TTL. Concurrent loads of a
+// key share one call, and errors are not cached. A nil cache always loads.
+func Load[T any](c *EntityCache, entity, key string, tags []string, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+	ctx := context.Background()
+
+	var value T
+	if data, err := c.manager.Get(ctx, c.config.Store, key); err == nil {
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+	}
+
+	data, _, err := c.manager.flights.do(c.config.Store+"\x00"+key, func() ([]byte, error) {
+		generation := c.generation.Load()
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", entity, err)
+		}
+		if c.generation.Load() == generation {
+			if err := c.manager.SetWithTags(ctx, c.config.Store, key, data, c.TTL(entity), tags); err != nil {
+				c.logger.Printf("Failed to cache %s: %v", key, err)
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	// Every caller decodes its own copy, so callers can change what they get
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode %s: %w", entity, err)
+	}
+	return value, nil
+}
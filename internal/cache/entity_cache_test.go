@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cachedProduct struct {
+	ID    int     `json:"id"`
+	Price float64 `json:"price"`
+}
+
+func newTestEntityCache(t *testing.T) (*EntityCache, *CacheManager) {
+	t.Helper()
+	cm := newTestManager(t, CacheConfig{DefaultTTL: time.Hour})
+	if _, err := NewEntityCache(cm, EntityCacheConfig{}); err == nil {
+		t.Fatal("entity cache was created without its store")
+	}
+	if err := cm.AddStore("entities", StoreConfig{Type: StoreTypeMemory}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewEntityCache(cm, EntityCacheConfig{
+		TTLs: map[string]time.Duration{"product": time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, cm
+}
+
+func TestEntityCacheLoad(t *testing.T) {
+	c, cm := newTestEntityCache(t)
+
+	var loads int32
+	price := 100.0
+	load := func(id int) func() (*cachedProduct, error) {
+		return func() (*cachedProduct, error) {
+			atomic.AddInt32(&loads, 1)
+			return &cachedProduct{ID: id, Price: price}, nil
+		}
+	}
+	get := func(id int) *cachedProduct {
+		t.Helper()
+		key := c.Key("product", strconv.Itoa(id), nil)
+		product, err := Load(c, "product", key, []string{RowTag("products", int64(id))}, load(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return product
+	}
+
+	get(1)
+	get(2)
+	if product := get(1); product.Price != 100 || atomic.LoadInt32(&loads) != 2 {
+		t.Fatalf("got %+v after %d loads", product, loads)
+	}
+	// Callers get copies
+	get(1).Price = 1
+	if product := get(1); product.Price != 100 {
+		t.Errorf("a caller changed the cached product: %+v", product)
+	}
+
+	store, _ := cm.getStore("entities")
+	if ttl, _ := store.TTL(context.Background(), c.Key("product", "1", nil)); ttl > time.Minute {
+		t.Errorf("product kept for %s, want its 1m TTL", ttl)
+	}
+
+	price = 80
+	c.Invalidate(RowTag("products", 1))
+	if product := get(1); product.Price != 80 {
+		t.Errorf("got %+v after invalidation", product)
+	}
+	if product := get(2); product.Price != 100 || atomic.LoadInt32(&loads) != 3 {
+		t.Errorf("invalidating product 1 reloaded product 2: %+v", product)
+	}
+
+	if stats := c.Stats(); stats.Hits != 4 || stats.Misses != 3 || stats.HitRatio != 4.0/7 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestEntityCacheLoadErrors(t *testing.T) {
+	c, _ := newTestEntityCache(t)
+
+	if _, err := Load(c, "product", "k", nil, func() (int, error) {
+		return 0, errors.New("boom")
+	}); err == nil {
+		t.Fatal("load error was not returned")
+	}
+	if value, err := Load(c, "product", "k", nil, func() (int, error) { return 7, nil }); err != nil || value != 7 {
+		t.Errorf("got %d, %v after a failed load", value, err)
+	}
+
+	// A load that overlaps an invalidation may have read the old data, so
+	// its result is not kept
+	if _, err := Load(c, "product", "raced", []string{"t"}, func() (int, error) {
+		c.Invalidate("t")
+		return 1, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := Load(c, "product", "raced", nil, func() (int, error) { return 2, nil }); value != 2 {
+		t.Errorf("got %d, want the result of a new load", value)
+	}
+
+	var nilCache *EntityCache
+	nilCache.Invalidate("t")
+	if value, err := Load(nilCache, "product", nilCache.Key("product", "1", nil), nil, func() (int, error) { return 3, nil }); err != nil || value != 3 {
+		t.Errorf("nil cache: got %d, %v", value, err)
+	}
+}
@@ -1,10 +1,17 @@
 package cache
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"sync"
@@ -19,6 +26,7 @@ type CacheManager struct {
 	mu        sync.RWMutex
 	db        *sql.DB
 	metrics   *MetricsCollector
+	flights   flightGroup
 }
 
 // CacheConfig holds cache configuration
@@ -100,6 +108,8 @@ const (
 	StoreTypeDatabase   StoreType = "database"
 	StoreTypeFile       StoreType = "file"
 	StoreTypeDistributed StoreType = "distributed"
+	// StoreTypeTiered is an in-memory L1 in front of a database L2
+	StoreTypeTiered StoreType = "tiered"
 )
 
 // EvictionPolicy represents cache eviction policies
@@ -245,7 +255,6 @@ func NewCacheManager(db *sql.DB, config CacheConfig) *CacheManager {
 		metrics: NewMetricsCollector(config.Monitoring),
 	}
 
-	cm.createCacheTables()
 	cm.initializeStores()
 	cm.startMonitoring()
 
@@ -276,73 +285,6 @@ func NewMetricsCollector(config MonitoringConfig) *MetricsCollector {
 	return mc
 }
 
-// createCacheTables creates necessary tables for cache management
-func (cm *CacheManager) createCacheTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS cache_items (
-			cache_key VARCHAR(512) PRIMARY KEY,
-			store_name VARCHAR(100) NOT NULL,
-			value LONGBLOB,
-			ttl_seconds INT DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			accessed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			access_count BIGINT DEFAULT 0,
-			size_bytes BIGINT DEFAULT 0,
-			compressed BOOLEAN DEFAULT FALSE,
-			encrypted BOOLEAN DEFAULT FALSE,
-			tags TEXT,
-			metadata TEXT,
-			expires_at DATETIME,
-			INDEX idx_store_name (store_name),
-			INDEX idx_expires_at (expires_at),
-			INDEX idx_accessed_at (accessed_at)
-		)`,
-		`CREATE TABLE IF NOT EXISTS cache_stats (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			store_name VARCHAR(100) NOT NULL,
-			operation VARCHAR(20) NOT NULL,
-			hits BIGINT DEFAULT 0,
-			misses BIGINT DEFAULT 0,
-			sets BIGINT DEFAULT 0,
-			deletes BIGINT DEFAULT 0,
-			evictions BIGINT DEFAULT 0,
-			memory_usage BIGINT DEFAULT 0,
-			avg_response_time_ms INT DEFAULT 0,
-			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-			INDEX idx_store_name (store_name),
-			INDEX idx_timestamp (timestamp)
-		)`,
-		`CREATE TABLE IF NOT EXISTS cache_events (
-			id VARCHAR(128) PRIMARY KEY,
-			operation VARCHAR(20) NOT NULL,
-			store_name VARCHAR(100) NOT NULL,
-			cache_key VARCHAR(512),
-			size_bytes BIGINT DEFAULT 0,
-			ttl_seconds INT DEFAULT 0,
-			success BOOLEAN DEFAULT TRUE,
-			duration_ms INT DEFAULT 0,
-			error_message TEXT,
-			client_ip VARCHAR(45),
-			user_agent TEXT,
-			user_id VARCHAR(128),
-			session_id VARCHAR(128),
-			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-			INDEX idx_operation (operation),
-			INDEX idx_store_name (store_name),
-			INDEX idx_timestamp (timestamp),
-			INDEX idx_success (success)
-		)`,
-	}
-
-	for _, query := range queries {
-		if _, err := cm.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to create cache table: %w", err)
-		}
-	}
-
-	return nil
-}
-
 // Get retrieves a value from cache
 func (cm *CacheManager) Get(ctx context.Context, storeName, key string) ([]byte, error) {
 	start := time.Now()
@@ -353,6 +295,9 @@ func (cm *CacheManager) Get(ctx context.Context, storeName, key string) ([]byte,
 	}
 
 	value, err := store.Get(ctx, key)
+	if err == nil {
+		value, err = cm.decodeValue(value)
+	}
 	duration := time.Since(start)
 
 	// Record metrics
@@ -379,8 +324,19 @@ func (cm *CacheManager) Get(ctx context.Context, storeName, key string) ([]byte,
 	return value, err
 }
 
-// Set stores a value in cache
+// Set stores a value in cache. A ttl of 0 uses the store's TTL, or the
+// manager's DefaultTTL when the store has none.
 func (cm *CacheManager) Set(ctx context.Context, storeName, key string, value []byte, ttl time.Duration) error {
+	return cm.set(ctx, storeName, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value in cache like Set, tagging it so that
+// InvalidateByTags can remove it. The store must be a TaggedStore.
+func (cm *CacheManager) SetWithTags(ctx context.Context, storeName, key string, value []byte, ttl time.Duration, tags []string) error {
+	return cm.set(ctx, storeName, key, value, ttl, tags)
+}
+
+func (cm *CacheManager) set(ctx context.Context, storeName, key string, value []byte, ttl time.Duration, tags []string) error {
 	start := time.Now()
 	
 	store, exists := cm.getStore(storeName)
@@ -388,23 +344,27 @@ func (cm *CacheManager) Set(ctx context.Context, storeName, key string, value []
 		return fmt.Errorf("store %s not found", storeName)
 	}
 
-	// Apply compression if enabled
-	if cm.config.CompressionEnabled {
-		compressed, err := cm.compress(value)
-		if err == nil && len(compressed) < len(value) {
-			value = compressed
-		}
+	if ttl <= 0 {
+		ttl = cm.config.Stores[storeName].TTL
+	}
+	if ttl <= 0 {
+		ttl = cm.config.DefaultTTL
 	}
 
-	// Apply encryption if enabled
-	if cm.config.EncryptionEnabled {
-		encrypted, err := cm.encrypt(value)
-		if err == nil {
-			value = encrypted
-		}
+	value, err := cm.encodeValue(value)
+	if err != nil {
+		return err
 	}
 
-	err := store.Set(ctx, key, value, ttl)
+	if len(tags) > 0 {
+		tagged, ok := store.(TaggedStore)
+		if !ok {
+			return fmt.Errorf("store %s: %w", storeName, ErrTagsNotSupported)
+		}
+		err = tagged.SetWithTags(ctx, key, value, ttl, tags)
+	} else {
+		err = store.Set(ctx, key, value, ttl)
+	}
 	duration := time.Since(start)
 
 	// Record metrics
@@ -465,7 +425,8 @@ func (cm *CacheManager) Delete(ctx context.Context, storeName, key string) error
 	return err
 }
 
-// GetOrSet retrieves a value or sets it if not found
+// GetOrSet retrieves a value or sets it if not found. Concurrent calls
+// missing the same key wait for a single call of generator.
 func (cm *CacheManager) GetOrSet(ctx context.Context, storeName, key string, ttl time.Duration, generator func() ([]byte, error)) ([]byte, error) {
 	// Try to get from cache first
 	value, err := cm.Get(ctx, storeName, key)
@@ -473,19 +434,26 @@ func (cm *CacheManager) GetOrSet(ctx context.Context, storeName, key string, ttl
 		return value, nil
 	}
 
-	// Generate new value
-	value, err = generator()
-	if err != nil {
-		return nil, err
-	}
+	value, _, err = cm.flights.do(storeName+"\x00"+key, func() ([]byte, error) {
+		// The call this one waited behind may just have set the key
+		if value, err := cm.Get(ctx, storeName, key); err == nil {
+			return value, nil
+		}
 
-	// Set in cache
-	if setErr := cm.Set(ctx, storeName, key, value, ttl); setErr != nil {
-		// Log error but return the generated value
-		cm.logError(fmt.Sprintf("Failed to set cache key %s: %v", key, setErr))
-	}
+		// Generate new value
+		value, err := generator()
+		if err != nil {
+			return nil, err
+		}
 
-	return value, nil
+		// Set in cache
+		if setErr := cm.Set(ctx, storeName, key, value, ttl); setErr != nil {
+			// Log error but return the generated value
+			cm.logError(fmt.Sprintf("Failed to set cache key %s: %v", key, setErr))
+		}
+		return value, nil
+	})
+	return value, err
 }
 
 // GetMulti retrieves multiple values from cache
@@ -523,7 +491,7 @@ func (cm *CacheManager) DeleteMulti(ctx context.Context, storeName string, keys
 	return nil
 }
 
-// InvalidateByTags invalidates cache items by tags
+// InvalidateByTags invalidates cache items tagged with any of tags
 func (cm *CacheManager) InvalidateByTags(ctx context.Context, storeName string, tags []string) error {
 	_, exists := cm.getStore(storeName)
 	if !exists {
@@ -571,18 +539,37 @@ func (cm *CacheManager) Flush(ctx context.Context, storeName string) error {
 	return err
 }
 
-// GetStats returns cache statistics
+// GetStats returns a snapshot of the cache statistics with the hit ratio
+// of the manager and of each store
 func (cm *CacheManager) GetStats() *CacheStats {
 	cm.stats.mu.RLock()
 	defer cm.stats.mu.RUnlock()
-	
-	// Calculate hit ratio
-	totalRequests := cm.stats.TotalHits + cm.stats.TotalMisses
-	if totalRequests > 0 {
-		cm.stats.HitRatio = float64(cm.stats.TotalHits) / float64(totalRequests)
+
+	stats := &CacheStats{
+		TotalHits:        cm.stats.TotalHits,
+		TotalMisses:      cm.stats.TotalMisses,
+		TotalSets:        cm.stats.TotalSets,
+		TotalDeletes:     cm.stats.TotalDeletes,
+		TotalEvictions:   cm.stats.TotalEvictions,
+		TotalMemoryUsage: cm.stats.TotalMemoryUsage,
+		HitRatio:         hitRatio(cm.stats.TotalHits, cm.stats.TotalMisses),
+		StoreStats:       make(map[string]*StoreStats, len(cm.stats.StoreStats)),
+		LastReset:        cm.stats.LastReset,
 	}
-	
-	return cm.stats
+	for name, storeStats := range cm.stats.StoreStats {
+		snapshot := *storeStats
+		snapshot.HitRatio = hitRatio(snapshot.Hits, snapshot.Misses)
+		stats.StoreStats[name] = &snapshot
+	}
+	return stats
+}
+
+// hitRatio returns the share of lookups that were hits
+func hitRatio(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
 }
 
 // GetStoreStats returns statistics for a specific store
@@ -682,19 +669,121 @@ func (cm *CacheManager) generateEventID() string {
 	return fmt.Sprintf("cache_event_%d", time.Now().UnixNano())
 }
 
+// Stored values start with a byte of flags saying how the rest is encoded
+const (
+	valueCompressed byte = 1 << iota
+	valueEncrypted
+)
+
+// encodeValue compresses and encrypts a value as configured. Compression is
+// skipped when it does not make the value smaller.
+func (cm *CacheManager) encodeValue(value []byte) ([]byte, error) {
+	var flags byte
+	if cm.config.CompressionEnabled {
+		compressed, err := cm.compress(value)
+		if err == nil && len(compressed) < len(value) {
+			value = compressed
+			flags |= valueCompressed
+		}
+	}
+	if cm.config.EncryptionEnabled {
+		encrypted, err := cm.encrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt cache value: %w", err)
+		}
+		value = encrypted
+		flags |= valueEncrypted
+	}
+	return append([]byte{flags}, value...), nil
+}
+
+// decodeValue reverses encodeValue
+func (cm *CacheManager) decodeValue(stored []byte) ([]byte, error) {
+	if len(stored) == 0 {
+		return nil, errors.New("cache value has no header")
+	}
+	flags, value := stored[0], stored[1:]
+	var err error
+	if flags&valueEncrypted != 0 {
+		if value, err = cm.decrypt(value); err != nil {
+			return nil, fmt.Errorf("failed to decrypt cache value: %w", err)
+		}
+	}
+	if flags&valueCompressed != 0 {
+		if value, err = cm.decompress(value); err != nil {
+			return nil, fmt.Errorf("failed to decompress cache value: %w", err)
+		}
+	}
+	return value, nil
+}
+
 func (cm *CacheManager) compress(data []byte) ([]byte, error) {
-	// Implementation would use compression library (gzip, lz4, etc.)
-	return data, nil
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (cm *CacheManager) decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// aead returns the AES-GCM cipher keyed with the SHA-256 of EncryptionKey
+func (cm *CacheManager) aead() (cipher.AEAD, error) {
+	if cm.config.EncryptionKey == "" {
+		return nil, errors.New("encryption key is not set")
+	}
+	key := sha256.Sum256([]byte(cm.config.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
 
 func (cm *CacheManager) encrypt(data []byte) ([]byte, error) {
-	// Implementation would use encryption library (AES, etc.)
-	return data, nil
+	gcm, err := cm.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func (cm *CacheManager) decrypt(data []byte) ([]byte, error) {
+	gcm, err := cm.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
 }
 
 func (cm *CacheManager) getKeysByTags(ctx context.Context, storeName string, tags []string) ([]string, error) {
-	// Implementation would query database for keys with matching tags
-	return []string{}, nil
+	store, exists := cm.getStore(storeName)
+	if !exists {
+		return nil, fmt.Errorf("store %s not found", storeName)
+	}
+	tagged, ok := store.(TaggedStore)
+	if !ok {
+		return nil, fmt.Errorf("store %s: %w", storeName, ErrTagsNotSupported)
+	}
+	return tagged.KeysByTags(ctx, tags)
 }
 
 func (cm *CacheManager) logError(message string) {
@@ -703,7 +792,29 @@ func (cm *CacheManager) logError(message string) {
 }
 
 func (cm *CacheManager) initializeStores() {
-	// Implementation would initialize configured stores
+	for name, config := range cm.config.Stores {
+		if !config.Enabled {
+			continue
+		}
+		store, err := cm.newStore(name, config)
+		if err != nil {
+			cm.logError(fmt.Sprintf("Failed to create store %s: %v", name, err))
+			continue
+		}
+		cm.stores[name] = store
+	}
+}
+
+// RegisterStore adds a store, replacing and closing any store of the same name
+func (cm *CacheManager) RegisterStore(name string, store CacheStore) {
+	cm.mu.Lock()
+	old := cm.stores[name]
+	cm.stores[name] = store
+	cm.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
 }
 
 func (cm *CacheManager) startMonitoring() {
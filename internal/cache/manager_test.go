@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheManagerStores(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{
+		DefaultTTL: time.Hour,
+		Stores: map[string]StoreConfig{
+			"default":  {Type: StoreTypeMemory, Enabled: true},
+			"shared":   {Type: StoreTypeDatabase, Enabled: true, TTL: time.Minute},
+			"tiered":   {Type: StoreTypeTiered, Enabled: true},
+			"disabled": {Type: StoreTypeMemory},
+			"redis":    {Type: StoreTypeRedis, Enabled: true},
+		},
+	})
+
+	for _, name := range []string{"default", "shared", "tiered"} {
+		if err := cm.Set(ctx, name, "k", []byte("v"), 0); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if value, err := cm.Get(ctx, name, "k"); err != nil || string(value) != "v" {
+			t.Errorf("%s: got %q, %v", name, value, err)
+		}
+	}
+	for _, name := range []string{"disabled", "redis"} {
+		if _, exists := cm.getStore(name); exists {
+			t.Errorf("store %s was created", name)
+		}
+	}
+
+	// A ttl of 0 uses the store's TTL, then the manager's
+	store, _ := cm.getStore("shared")
+	if ttl, _ := store.TTL(ctx, "k"); ttl > time.Minute {
+		t.Errorf("shared store kept k for %s, want its 1m TTL", ttl)
+	}
+	store, _ = cm.getStore("default")
+	if ttl, _ := store.TTL(ctx, "k"); ttl < 59*time.Minute {
+		t.Errorf("default store kept k for %s, want the 1h default", ttl)
+	}
+}
+
+func TestCacheManagerEncoding(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{
+		CompressionEnabled: true,
+		EncryptionEnabled:  true,
+		EncryptionKey:      "test-key",
+	})
+	store := NewMemoryStore(StoreConfig{})
+	cm.RegisterStore("default", store)
+
+	value := bytes.Repeat([]byte("kolajai "), 100)
+	if err := cm.Set(ctx, "default", "k", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := store.Get(ctx, "k")
+	if stored[0] != valueCompressed|valueEncrypted || bytes.Contains(stored, []byte("kolajai")) {
+		t.Errorf("stored value is not compressed and encrypted: %q", stored[:20])
+	}
+	if got, err := cm.Get(ctx, "default", "k"); err != nil || !bytes.Equal(got, value) {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	cm.config.EncryptionKey = ""
+	if err := cm.Set(ctx, "default", "k", value, time.Minute); err == nil {
+		t.Error("value was stored without encryption")
+	}
+}
+
+func TestCacheManagerInvalidateByTags(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{DefaultTTL: time.Hour})
+	cm.RegisterStore("default", NewMemoryStore(StoreConfig{}))
+
+	cm.SetWithTags(ctx, "default", "product:1", []byte("a"), 0, []string{"product:1", "products"})
+	cm.SetWithTags(ctx, "default", "product:2", []byte("b"), 0, []string{"product:2", "products"})
+	cm.Set(ctx, "default", "home", []byte("c"), 0)
+
+	if err := cm.InvalidateByTags(ctx, "default", []string{"products"}); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"product:1": false, "product:2": false, "home": true} {
+		if _, err := cm.Get(ctx, "default", key); (err == nil) != want {
+			t.Errorf("%s: got %v", key, err)
+		}
+	}
+
+	cm.RegisterStore("plain", plainStore{NewMemoryStore(StoreConfig{})})
+	if err := cm.SetWithTags(ctx, "plain", "k", []byte("v"), 0, []string{"t"}); !errors.Is(err, ErrTagsNotSupported) {
+		t.Errorf("got %v, want ErrTagsNotSupported", err)
+	}
+}
+
+// plainStore hides a store's tag support
+type plainStore struct {
+	CacheStore
+}
+
+func TestCacheManagerGetOrSetSingleflight(t *testing.T) {
+	ctx := context.Background()
+	cm := newTestManager(t, CacheConfig{DefaultTTL: time.Hour})
+	cm.RegisterStore("default", NewMemoryStore(StoreConfig{}))
+
+	var calls int32
+	release := make(chan struct{})
+	generator := func() ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte("generated"), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cm.GetOrSet(ctx, "default", "hot", 0, generator)
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("generator ran %d times, want once", calls)
+	}
+	for i, result := range results {
+		if string(result) != "generated" {
+			t.Errorf("caller %d got %q", i, result)
+		}
+	}
+
+	if _, err := cm.GetOrSet(ctx, "default", "failing", 0, func() ([]byte, error) {
+		return nil, errors.New("boom")
+	}); err == nil {
+		t.Error("generator error was not returned")
+	}
+	if _, err := cm.Get(ctx, "default", "failing"); err == nil {
+		t.Error("failed generation was cached")
+	}
+}
@@ -0,0 +1,410 @@
+package cache
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCacheMiss is returned for keys that are not in a store or have expired
+	ErrCacheMiss = errors.New("cache: key not found")
+	// ErrValueTooLarge is returned for values larger than a store can hold
+	ErrValueTooLarge = errors.New("cache: value is larger than the store")
+	// ErrTagsNotSupported is returned when tagging items in a store that
+	// cannot look keys up by tag
+	ErrTagsNotSupported = errors.New("cache: store does not support tags")
+)
+
+const (
+	// defaultShards is the number of shards of a memory store whose config
+	// does not set one
+	defaultShards = 16
+	// defaultCleanupInterval is how often stores remove expired items unless
+	// their config's cleanup_interval setting says otherwise
+	defaultCleanupInterval = time.Minute
+)
+
+// TaggedStore is implemented by stores that can tag items and find keys by
+// tag, which CacheManager.InvalidateByTags needs
+type TaggedStore interface {
+	SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// KeysByTags returns the keys tagged with any of tags
+	KeysByTags(ctx context.Context, tags []string) ([]string, error)
+}
+
+// MemoryStore is an in-memory CacheStore. Keys are spread over shards, each
+// with its own lock and an equal part of MaxSize; a shard that is full
+// evicts items in the order of the eviction policy.
+type MemoryStore struct {
+	shards    []*memoryShard
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// memoryShard holds part of a memory store's items. order keeps them sorted
+// by the eviction policy, so the next item to evict is always order[0].
+type memoryShard struct {
+	mu      sync.Mutex
+	items   map[string]*memoryItem
+	order   memoryOrder
+	tags    map[string]map[string]struct{}
+	size    int64
+	maxSize int64
+	// clock orders accesses and insertions without reading the time
+	clock uint64
+	stats StoreStats
+}
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+	created   uint64
+	accessed  uint64
+	hits      int64
+	index     int
+}
+
+func (item *memoryItem) size() int64 {
+	return int64(len(item.key) + len(item.value))
+}
+
+func (item *memoryItem) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && now.After(item.expiresAt)
+}
+
+// NewMemoryStore creates an in-memory store. MaxSize limits the bytes of
+// keys and values it holds, 0 meaning no limit; EvictionPolicy defaults to
+// LRU.
+func NewMemoryStore(config StoreConfig) *MemoryStore {
+	shards := config.Shards
+	if shards <= 0 {
+		shards = defaultShards
+	}
+	policy := config.EvictionPolicy
+	if policy == "" {
+		policy = EvictionLRU
+	}
+
+	s := &MemoryStore{
+		shards: make([]*memoryShard, shards),
+		stop:   make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{
+			items:   make(map[string]*memoryItem),
+			order:   memoryOrder{policy: policy},
+			tags:    make(map[string]map[string]struct{}),
+			maxSize: config.MaxSize / int64(shards),
+		}
+	}
+
+	go s.cleanup(durationSetting(config.Settings, "cleanup_interval", defaultCleanupInterval))
+	return s
+}
+
+func (s *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Get returns the value of key, or ErrCacheMiss
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, ok := shard.items[key]
+	if !ok || item.expired(time.Now()) {
+		if ok {
+			shard.remove(item)
+		}
+		shard.stats.Misses++
+		return nil, ErrCacheMiss
+	}
+
+	shard.clock++
+	item.accessed = shard.clock
+	item.hits++
+	heap.Fix(&shard.order, item.index)
+	shard.stats.Hits++
+	shard.stats.LastAccess = time.Now()
+	return item.value, nil
+}
+
+// Set stores value under key for ttl, or without expiry when ttl is 0
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores value under key like Set, tagging it with tags
+func (s *MemoryStore) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	item := &memoryItem{key: key, value: value, tags: tags}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if shard.maxSize > 0 && item.size() > shard.maxSize {
+		return ErrValueTooLarge
+	}
+	if old, ok := shard.items[key]; ok {
+		shard.remove(old)
+	}
+	for shard.maxSize > 0 && shard.size+item.size() > shard.maxSize {
+		shard.remove(shard.victim())
+		shard.stats.Evictions++
+	}
+
+	shard.clock++
+	item.created = shard.clock
+	item.accessed = shard.clock
+	shard.items[key] = item
+	heap.Push(&shard.order, item)
+	shard.size += item.size()
+	for _, tag := range tags {
+		if shard.tags[tag] == nil {
+			shard.tags[tag] = make(map[string]struct{})
+		}
+		shard.tags[tag][key] = struct{}{}
+	}
+	shard.stats.Sets++
+	return nil
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if item, ok := shard.items[key]; ok {
+		shard.remove(item)
+		shard.stats.Deletes++
+	}
+	return nil
+}
+
+// Exists reports whether key is in the store and has not expired
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, ok := shard.items[key]
+	return ok && !item.expired(time.Now()), nil
+}
+
+// Clear removes every item
+func (s *MemoryStore) Clear(ctx context.Context) error {
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		shard.items = make(map[string]*memoryItem)
+		shard.order.items = nil
+		shard.tags = make(map[string]map[string]struct{})
+		shard.size = 0
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+// Keys returns the keys matching pattern, in which * matches any run of
+// characters and ? any one character. An empty pattern matches every key.
+func (s *MemoryStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	match := keyMatcher(pattern)
+	now := time.Now()
+
+	var keys []string
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		for key, item := range shard.items {
+			if !item.expired(now) && match(key) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return keys, nil
+}
+
+// KeysByTags returns the keys tagged with any of tags
+func (s *MemoryStore) KeysByTags(ctx context.Context, tags []string) ([]string, error) {
+	var keys []string
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		seen := make(map[string]bool)
+		for _, tag := range tags {
+			for key := range shard.tags[tag] {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return keys, nil
+}
+
+// TTL returns how long key has left, or 0 when it does not expire
+func (s *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	item, ok := shard.items[key]
+	if !ok || item.expired(time.Now()) {
+		return 0, ErrCacheMiss
+	}
+	if item.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(item.expiresAt), nil
+}
+
+// Size returns the number of items in the store
+func (s *MemoryStore) Size(ctx context.Context) (int64, error) {
+	var count int64
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		count += int64(len(shard.items))
+		shard.mu.Unlock()
+	}
+	return count, nil
+}
+
+// Stats returns the store's statistics summed over its shards
+func (s *MemoryStore) Stats(ctx context.Context) (*StoreStats, error) {
+	stats := &StoreStats{}
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		stats.Hits += shard.stats.Hits
+		stats.Misses += shard.stats.Misses
+		stats.Sets += shard.stats.Sets
+		stats.Deletes += shard.stats.Deletes
+		stats.Evictions += shard.stats.Evictions
+		stats.MemoryUsage += shard.size
+		stats.ItemCount += int64(len(shard.items))
+		if shard.stats.LastAccess.After(stats.LastAccess) {
+			stats.LastAccess = shard.stats.LastAccess
+		}
+		shard.mu.Unlock()
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats, nil
+}
+
+// Close stops the store's cleanup of expired items
+func (s *MemoryStore) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	return nil
+}
+
+// cleanup removes expired items every interval until the store is closed
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			for _, shard := range s.shards {
+				shard.mu.Lock()
+				for _, item := range shard.items {
+					if item.expired(now) {
+						shard.remove(item)
+					}
+				}
+				shard.mu.Unlock()
+			}
+		}
+	}
+}
+
+// victim returns the item to evict next. Under the random policy that is
+// whichever item map iteration yields first.
+func (shard *memoryShard) victim() *memoryItem {
+	if shard.order.policy == EvictionRandom {
+		for _, item := range shard.items {
+			return item
+		}
+	}
+	return shard.order.items[0]
+}
+
+func (shard *memoryShard) remove(item *memoryItem) {
+	heap.Remove(&shard.order, item.index)
+	delete(shard.items, item.key)
+	shard.size -= item.size()
+	for _, tag := range item.tags {
+		delete(shard.tags[tag], item.key)
+		if len(shard.tags[tag]) == 0 {
+			delete(shard.tags, tag)
+		}
+	}
+}
+
+// memoryOrder is a heap of a shard's items with the next one to evict first
+type memoryOrder struct {
+	policy EvictionPolicy
+	items  []*memoryItem
+}
+
+func (o memoryOrder) Len() int { return len(o.items) }
+
+func (o memoryOrder) Less(i, j int) bool {
+	a, b := o.items[i], o.items[j]
+	switch o.policy {
+	case EvictionLFU:
+		if a.hits != b.hits {
+			return a.hits < b.hits
+		}
+		return a.accessed < b.accessed
+	case EvictionFIFO, EvictionRandom:
+		return a.created < b.created
+	case EvictionTTL:
+		// Items expiring soonest go first and those that never expire last
+		if a.expiresAt.IsZero() != b.expiresAt.IsZero() {
+			return b.expiresAt.IsZero()
+		}
+		if !a.expiresAt.Equal(b.expiresAt) {
+			return a.expiresAt.Before(b.expiresAt)
+		}
+		return a.created < b.created
+	default:
+		return a.accessed < b.accessed
+	}
+}
+
+func (o memoryOrder) Swap(i, j int) {
+	o.items[i], o.items[j] = o.items[j], o.items[i]
+	o.items[i].index = i
+	o.items[j].index = j
+}
+
+func (o *memoryOrder) Push(x interface{}) {
+	item := x.(*memoryItem)
+	item.index = len(o.items)
+	o.items = append(o.items, item)
+}
+
+func (o *memoryOrder) Pop() interface{} {
+	last := o.items[len(o.items)-1]
+	o.items[len(o.items)-1] = nil
+	o.items = o.items[:len(o.items)-1]
+	return last
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fillStore sets keys k0..k(n-1), each taking 10 bytes with its key
+func fillStore(t *testing.T, store CacheStore, n int, ttl func(i int) time.Duration) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := store.Set(context.Background(), fmt.Sprintf("k%d", i), []byte("12345678"), ttl(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func noTTL(int) time.Duration { return 0 }
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		policy EvictionPolicy
+		ttl    func(i int) time.Duration
+		// touch is read before k3 is added, which evicts one key
+		touch []string
+		want  string
+	}{
+		{policy: EvictionLRU, ttl: noTTL, touch: []string{"k0"}, want: "k1"},
+		{policy: EvictionLFU, ttl: noTTL, touch: []string{"k0", "k0", "k1", "k2"}, want: "k1"},
+		{policy: EvictionFIFO, ttl: noTTL, touch: []string{"k0"}, want: "k0"},
+		{policy: EvictionTTL, ttl: func(i int) time.Duration { return time.Duration(3-i) * time.Hour }, want: "k2"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			store := NewMemoryStore(StoreConfig{MaxSize: 30, Shards: 1, EvictionPolicy: tt.policy})
+			defer store.Close()
+
+			fillStore(t, store, 3, tt.ttl)
+			for _, key := range tt.touch {
+				if _, err := store.Get(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.Set(ctx, "k3", []byte("12345678"), tt.ttl(3)); err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, _ := store.Exists(ctx, tt.want); ok {
+				t.Errorf("%s was not evicted", tt.want)
+			}
+			if size, _ := store.Size(ctx); size != 3 {
+				t.Errorf("got %d items, want 3", size)
+			}
+			if stats, _ := store.Stats(ctx); stats.Evictions != 1 || stats.MemoryUsage != 30 {
+				t.Errorf("got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(StoreConfig{MaxSize: 1024})
+	defer store.Close()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("got %v, want ErrCacheMiss", err)
+	}
+	if err := store.Set(ctx, "big", make([]byte, 1024), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("got %v, want ErrValueTooLarge", err)
+	}
+
+	store.Set(ctx, "product:1", []byte("a"), 20*time.Millisecond)
+	store.SetWithTags(ctx, "product:2", []byte("b"), time.Hour, []string{"products", "vendor:7"})
+	store.SetWithTags(ctx, "order:1", []byte("c"), 0, []string{"vendor:7"})
+
+	if ttl, err := store.TTL(ctx, "product:2"); err != nil || ttl <= 59*time.Minute {
+		t.Errorf("got TTL %s, %v", ttl, err)
+	}
+	if ttl, err := store.TTL(ctx, "order:1"); err != nil || ttl != 0 {
+		t.Errorf("got TTL %s, %v for a key without expiry", ttl, err)
+	}
+
+	keys, _ := store.Keys(ctx, "product:*")
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[product:1 product:2]" {
+		t.Errorf("got keys %v", keys)
+	}
+	keys, _ = store.KeysByTags(ctx, []string{"vendor:7"})
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[order:1 product:2]" {
+		t.Errorf("got tagged keys %v", keys)
+	}
+
+	// Replacing an item drops its old tags
+	store.Set(ctx, "order:1", []byte("d"), 0)
+	if keys, _ := store.KeysByTags(ctx, []string{"vendor:7"}); len(keys) != 1 {
+		t.Errorf("got tagged keys %v after replacing order:1", keys)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := store.Get(ctx, "product:1"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("got %v for an expired key", err)
+	}
+
+	store.Clear(ctx)
+	if size, _ := store.Size(ctx); size != 0 {
+		t.Errorf("got %d items after clearing", size)
+	}
+}
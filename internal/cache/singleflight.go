@@ -0,0 +1,46 @@
+package cache
+
+import "sync"
+
+// flightGroup runs one call per key at a time; callers asking for a key
+// whose call is in flight wait for it and share its result. GetOrSet uses it
+// so that an expired hot key is generated once rather than by every request
+// that misses it.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// do runs fn for key unless a call for key is already in flight, in which
+// case it waits for that call's result. shared reports whether the result
+// came from another caller's call.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) (value []byte, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, true, call.err
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.value, call.err = fn()
+	return call.value, false, call.err
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultL1MaxSize is the size of a tiered store's first tier unless its
+// config's l1_max_size setting says otherwise
+const defaultL1MaxSize = 64 * 1024 * 1024
+
+// newStore creates the store a config describes. Stores without an eviction
+// policy get the manager's.
+func (cm *CacheManager) newStore(name string, config StoreConfig) (CacheStore, error) {
+	if config.EvictionPolicy == "" {
+		config.EvictionPolicy = cm.config.EvictionPolicy
+	}
+
+	switch config.Type {
+	case StoreTypeMemory, "":
+		return NewMemoryStore(config), nil
+	case StoreTypeDatabase:
+		if cm.db == nil {
+			return nil, fmt.Errorf("store %s needs a database", name)
+		}
+		return NewDatabaseStore(cm.db, name, config), nil
+	case StoreTypeTiered:
+		if cm.db == nil {
+			return nil, fmt.Errorf("store %s needs a database", name)
+		}
+		l1 := NewMemoryStore(StoreConfig{
+			MaxSize:        int64Setting(config.Settings, "l1_max_size", defaultL1MaxSize),
+			EvictionPolicy: config.EvictionPolicy,
+			Shards:         config.Shards,
+			Settings:       config.Settings,
+		})
+		l2 := NewDatabaseStore(cm.db, name, config)
+		return NewTieredStore(l1, l2, durationSetting(config.Settings, "l1_ttl", defaultL1TTL)), nil
+	default:
+		return nil, fmt.Errorf("store type %s is not supported", config.Type)
+	}
+}
+
+// AddStore creates the store a config describes and registers it under
+// name
+func (cm *CacheManager) AddStore(name string, config StoreConfig) error {
+	store, err := cm.newStore(name, config)
+	if err != nil {
+		return err
+	}
+	cm.RegisterStore(name, store)
+	return nil
+}
+
+// durationSetting reads a duration from store settings, given as a
+// time.Duration, a string like "30s" or a number of seconds
+func durationSetting(settings map[string]interface{}, key string, def time.Duration) time.Duration {
+	switch v := settings[key].(type) {
+	case time.Duration:
+		if v > 0 {
+			return v
+		}
+	case string:
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	case int:
+		if v > 0 {
+			return time.Duration(v) * time.Second
+		}
+	case float64:
+		if v > 0 {
+			return time.Duration(v * float64(time.Second))
+		}
+	}
+	return def
+}
+
+// int64Setting reads a number from store settings
+func int64Setting(settings map[string]interface{}, key string, def int64) int64 {
+	switch v := settings[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return def
+}
+
+// keyMatcher returns a function matching keys against a pattern in which *
+// matches any run of characters and ? any one character
+func keyMatcher(pattern string) func(string) bool {
+	if pattern == "" || pattern == "*" {
+		return func(string) bool { return true }
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	re := regexp.MustCompile("^" + expr + "$")
+	return re.MatchString
+}
+
+// likePattern escapes s for a LIKE pattern using ! as the escape character
+func likePattern(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// globToLike turns a keyMatcher pattern into a LIKE pattern using ! as the
+// escape character
+func globToLike(pattern string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(likePattern(pattern))
+}
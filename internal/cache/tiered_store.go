@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// defaultL1TTL is how long a tiered store keeps items in its first tier
+// unless its config's l1_ttl setting says otherwise
+const defaultL1TTL = time.Minute
+
+// TieredStore is a two-tier CacheStore: a small, fast L1 in front of a
+// larger, shared L2 that is the store of record. Items are written to both
+// and read from L1 first. L1 keeps items for at most l1TTL, which bounds how
+// long another instance's writes to L2 can go unseen.
+type TieredStore struct {
+	l1    CacheStore
+	l2    CacheStore
+	l1TTL time.Duration
+}
+
+// NewTieredStore creates a tiered store of l1 and l2
+func NewTieredStore(l1, l2 CacheStore, l1TTL time.Duration) *TieredStore {
+	if l1TTL <= 0 {
+		l1TTL = defaultL1TTL
+	}
+	return &TieredStore{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+// Get returns the value of key from L1, or from L2 and copies it into L1
+func (s *TieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := s.l1.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	value, err := s.l2.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := s.l2.TTL(ctx, key)
+	if err == nil {
+		s.l1.Set(ctx, key, value, s.l1Expiry(ttl))
+	}
+	return value, nil
+}
+
+// Set stores value in both tiers
+func (s *TieredStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return s.setL1(ctx, key, value, ttl)
+}
+
+// SetWithTags stores value in both tiers, tagging it in L2, which KeysByTags
+// reads
+func (s *TieredStore) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	tagged, ok := s.l2.(TaggedStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+	if err := tagged.SetWithTags(ctx, key, value, ttl, tags); err != nil {
+		return err
+	}
+	return s.setL1(ctx, key, value, ttl)
+}
+
+// setL1 copies an item written to L2 into L1. A value too large for L1 is
+// only kept in L2, but an older copy must not be left behind in L1.
+func (s *TieredStore) setL1(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.l1.Set(ctx, key, value, s.l1Expiry(ttl)); err != nil {
+		return s.l1.Delete(ctx, key)
+	}
+	return nil
+}
+
+// l1Expiry returns how long L1 keeps an item that L2 keeps for ttl
+func (s *TieredStore) l1Expiry(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < s.l1TTL {
+		return ttl
+	}
+	return s.l1TTL
+}
+
+// Delete removes key from both tiers
+func (s *TieredStore) Delete(ctx context.Context, key string) error {
+	if err := s.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.l1.Delete(ctx, key)
+}
+
+// Exists reports whether key is in either tier
+func (s *TieredStore) Exists(ctx context.Context, key string) (bool, error) {
+	if ok, err := s.l1.Exists(ctx, key); err == nil && ok {
+		return true, nil
+	}
+	return s.l2.Exists(ctx, key)
+}
+
+// Clear removes every item from both tiers
+func (s *TieredStore) Clear(ctx context.Context) error {
+	if err := s.l2.Clear(ctx); err != nil {
+		return err
+	}
+	return s.l1.Clear(ctx)
+}
+
+// Keys returns L2's keys matching pattern
+func (s *TieredStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return s.l2.Keys(ctx, pattern)
+}
+
+// KeysByTags returns L2's keys tagged with any of tags
+func (s *TieredStore) KeysByTags(ctx context.Context, tags []string) ([]string, error) {
+	tagged, ok := s.l2.(TaggedStore)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+	return tagged.KeysByTags(ctx, tags)
+}
+
+// TTL returns how long key has left in L2
+func (s *TieredStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return s.l2.TTL(ctx, key)
+}
+
+// Size returns the number of items in L2
+func (s *TieredStore) Size(ctx context.Context) (int64, error) {
+	return s.l2.Size(ctx)
+}
+
+// Stats returns the store's statistics. Hits are those of either tier and
+// misses those of L2, since an L1 miss falls through to it; item count is
+// L2's and memory usage that of both.
+func (s *TieredStore) Stats(ctx context.Context) (*StoreStats, error) {
+	l1, err := s.l1.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l2, err := s.l2.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &StoreStats{
+		Hits:        l1.Hits + l2.Hits,
+		Misses:      l2.Misses,
+		Sets:        l2.Sets,
+		Deletes:     l2.Deletes,
+		Evictions:   l1.Evictions + l2.Evictions,
+		MemoryUsage: l1.MemoryUsage + l2.MemoryUsage,
+		ItemCount:   l2.ItemCount,
+		LastAccess:  l1.LastAccess,
+	}
+	if l2.LastAccess.After(stats.LastAccess) {
+		stats.LastAccess = l2.LastAccess
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats, nil
+}
+
+// Close closes both tiers
+func (s *TieredStore) Close() error {
+	err := s.l1.Close()
+	if err2 := s.l2.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
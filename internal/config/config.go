@@ -24,6 +24,7 @@ type Config struct {
 	Email       EmailConfig   `yaml:"email"`
 	SEO         SEOConfig     `yaml:"seo"`
 	Logging     LoggingConfig `yaml:"logging"`
+	Payment     PaymentConfig `yaml:"payment"`
 }
 
 // ServerConfig holds server configuration
@@ -48,6 +49,23 @@ type DatabaseConfig struct {
 	MaxOpenConns    int    `yaml:"max_open_conns"`
 	MaxIdleConns    int    `yaml:"max_idle_conns"`
 	ConnMaxLifetime int    `yaml:"conn_max_lifetime"`
+	// Replicas are MySQL read replicas of the database that read-only queries
+	// are spread over
+	Replicas                   []DatabaseReplicaConfig `yaml:"replicas"`
+	ReplicaHealthCheckInterval time.Duration           `yaml:"replica_health_check_interval"`
+	ReplicaFailureThreshold    int                     `yaml:"replica_failure_threshold"`
+}
+
+// DatabaseReplicaConfig holds the configuration of a read replica. User,
+// password and pool sizes default to the primary database's.
+type DatabaseReplicaConfig struct {
+	Name         string `yaml:"name"`
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns"`
 }
 
 // SecurityConfig holds security configuration
@@ -71,6 +89,18 @@ type CacheConfig struct {
 	DB             int           `yaml:"db"`
 	DefaultTTL     time.Duration `yaml:"default_ttl"`
 	MaxMemoryUsage int64         `yaml:"max_memory_usage"`
+	Entities       EntityCacheConfig `yaml:"entities"`
+}
+
+// EntityCacheConfig holds the configuration of the cache of service reads
+type EntityCacheConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Store   string `yaml:"store"` // memory, database or tiered
+	MaxSize int64  `yaml:"max_size"`
+	// TTL holds the TTL of each cached entity: product, featured_products,
+	// categories and report. Entities without one use DefaultTTL.
+	DefaultTTL time.Duration            `yaml:"default_ttl"`
+	TTL        map[string]time.Duration `yaml:"ttl"`
 }
 
 // EmailConfig holds email configuration
@@ -107,6 +137,24 @@ type LoggingConfig struct {
 	Compress   bool   `yaml:"compress"`
 }
 
+// PaymentConfig holds the card payment gateway configuration. Card
+// payments go through iyzico when it is enabled, through the local sandbox
+// when Sandbox is set, and are refused otherwise.
+type PaymentConfig struct {
+	Iyzico IyzicoConfig `yaml:"iyzico"`
+	// Sandbox routes card payments through the in-memory sandbox gateway.
+	// It is for development and tests only and never takes real money.
+	Sandbox bool `yaml:"sandbox"`
+}
+
+// IyzicoConfig holds the iyzico credentials
+type IyzicoConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	APIKey      string `yaml:"api_key"`
+	APISecret   string `yaml:"api_secret"`
+	Environment string `yaml:"environment"` // sandbox or production
+}
+
 // NotificationConfig represents notification configuration
 type NotificationConfig struct {
 	EnableEmail bool                        `yaml:"enable_email"`
@@ -187,6 +235,9 @@ func GetDefaultConfig() *Config {
 			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
 			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
 			ConnMaxLifetime: getEnvAsInt("DB_CONN_MAX_LIFETIME", 5),
+			Replicas:        parseReplicaHosts(getEnv("DB_READ_REPLICAS", "")),
+			ReplicaHealthCheckInterval: 10 * time.Second,
+			ReplicaFailureThreshold:    3,
 		},
 		Security: SecurityConfig{
 			EncryptionKey:    getEnv("ENCRYPTION_KEY", "supersecretkey32byteslongforencryption"),
@@ -206,6 +257,18 @@ func GetDefaultConfig() *Config {
 			DB:             getEnvAsInt("CACHE_DB", 0),
 			DefaultTTL:     time.Duration(getEnvAsInt("CACHE_DEFAULT_TTL", 1800)) * time.Second,
 			MaxMemoryUsage: int64(getEnvAsInt("CACHE_MAX_MEMORY", 1073741824)), // 1GB
+			Entities: EntityCacheConfig{
+				Enabled:    getEnvAsBool("CACHE_ENTITIES_ENABLED", true),
+				Store:      getEnv("CACHE_ENTITIES_STORE", "memory"),
+				MaxSize:    64 * 1024 * 1024,
+				DefaultTTL: 10 * time.Minute,
+				TTL: map[string]time.Duration{
+					"product":           10 * time.Minute,
+					"featured_products": 5 * time.Minute,
+					"categories":        time.Hour,
+					"report":            15 * time.Minute,
+				},
+			},
 		},
 		Email: EmailConfig{
 			SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
@@ -235,6 +298,15 @@ func GetDefaultConfig() *Config {
 			MaxAge:     getEnvAsInt("LOG_MAX_AGE", 28),
 			Compress:   getEnvAsBool("LOG_COMPRESS", true),
 		},
+		Payment: PaymentConfig{
+			Iyzico: IyzicoConfig{
+				Enabled:     getEnv("IYZICO_API_KEY", "") != "",
+				APIKey:      getEnv("IYZICO_API_KEY", ""),
+				APISecret:   getEnv("IYZICO_API_SECRET", ""),
+				Environment: getEnv("IYZICO_ENVIRONMENT", "sandbox"),
+			},
+			Sandbox: getEnvAsBool("PAYMENT_SANDBOX", false),
+		},
 	}
 	
 	// Set global config
@@ -248,6 +320,29 @@ func overrideWithEnv(config *Config) {
 	if env := getEnv("APP_ENV", ""); env != "" {
 		config.Environment = env
 	}
+	if replicas := getEnv("DB_READ_REPLICAS", ""); replicas != "" {
+		config.Database.Replicas = parseReplicaHosts(replicas)
+	}
+}
+
+// parseReplicaHosts parses a comma separated list of host[:port] read
+// replicas
+func parseReplicaHosts(value string) []DatabaseReplicaConfig {
+	var replicas []DatabaseReplicaConfig
+	for _, host := range strings.Split(value, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		replica := DatabaseReplicaConfig{Host: host}
+		if name, port, found := strings.Cut(host, ":"); found {
+			if p, err := strconv.Atoi(port); err == nil {
+				replica.Host, replica.Port = name, p
+			}
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas
 }
 
 // Helper functions for environment variables
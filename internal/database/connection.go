@@ -158,22 +158,3 @@ func createDatabaseIfNotExists(config *Config) error {
 
 	return nil
 }
-
-// SetupDatabase initializes database tables
-func SetupDatabase(db *sql.DB) error {
-	// Migrasyon yöneticisi oluştur
-	migrator, err := NewMigrator(db)
-	if err != nil {
-		return fmt.Errorf("failed to create migrator: %w", err)
-	}
-
-	// Core migrasyonları uygula
-	err = migrator.ApplyCoreMigrations()
-	if err != nil {
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	// Database initialization completed
-
-	return nil
-}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursor tokens the codec did not issue, or
+// issued for another list or ordering
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a keyset ordered list: the sort key value and ID
+// of the row a page starts after. Backward cursors page towards the start of
+// the list.
+type Cursor struct {
+	Value    interface{}
+	ID       int64
+	Backward bool
+}
+
+// Keyset orders a list by a sort key and then by ID, which keeps the order
+// stable when sort key values repeat. Pages are read with a condition on
+// (sort key, id) instead of an OFFSET, so reading page 500 costs as much as
+// reading page 1 when the table has an index on (sort key, id). The sort key
+// must be a NOT NULL column.
+type Keyset struct {
+	// Scope names the list, such as "products", so a cursor issued for one
+	// list is rejected by another
+	Scope  string
+	Column string
+	Order  SortDirection
+}
+
+// String identifies the list and ordering cursors are issued for
+func (k Keyset) String() string {
+	return fmt.Sprintf("%s:%s:%s", k.Scope, k.Column, k.Order)
+}
+
+// Validate checks that the keyset can be written into a query
+func (k Keyset) Validate() error {
+	if k.Column == "" || !validateTableName(k.Column) {
+		return fmt.Errorf("invalid sort column: %s", k.Column)
+	}
+	if k.Order != Ascending && k.Order != Descending {
+		return fmt.Errorf("invalid sort order: %s", k.Order)
+	}
+	return nil
+}
+
+// Condition returns the SQL condition selecting the rows after cursor, and
+// its args. It is empty for a nil cursor.
+func (k Keyset) Condition(cursor *Cursor) (string, []interface{}) {
+	if cursor == nil {
+		return "", nil
+	}
+	operator := ">"
+	if (k.Order == Descending) != cursor.Backward {
+		operator = "<"
+	}
+	condition := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", k.Column, operator)
+	return condition, []interface{}{cursor.Value, cursor.Value, cursor.ID}
+}
+
+// OrderBy returns the ORDER BY clause the page after cursor is read in.
+// Backward pages are read in reverse and put back in keyset order by
+// Paginate.
+func (k Keyset) OrderBy(cursor *Cursor) string {
+	order := k.Order
+	if cursor != nil && cursor.Backward {
+		if order == Descending {
+			order = Ascending
+		} else {
+			order = Descending
+		}
+	}
+	return fmt.Sprintf("%s %s, id %s", k.Column, order, order)
+}
+
+// Apply adds the condition and ordering of the page after cursor to qb
+func (k Keyset) Apply(qb *QueryBuilder, cursor *Cursor) *QueryBuilder {
+	if condition, args := k.Condition(cursor); condition != "" {
+		qb.WhereRaw(condition, args...)
+	}
+	qb.orderBy = append(qb.orderBy, k.OrderBy(cursor))
+	return qb
+}
+
+// CursorCodec encodes cursors into opaque tokens signed with HMAC-SHA256, so
+// clients can neither forge positions nor reuse a cursor with another list
+// or ordering
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a codec signing with a key derived from secret, so
+// a secret shared with other uses, such as the JWT secret, never signs
+// cursors itself. An empty secret gets a random key, whose tokens stop
+// working when the process restarts.
+func NewCursorCodec(secret []byte) *CursorCodec {
+	if len(secret) == 0 {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(fmt.Sprintf("failed to generate cursor key: %v", err))
+		}
+		return &CursorCodec{key: key}
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("cursor"))
+	return &CursorCodec{key: mac.Sum(nil)}
+}
+
+// cursorToken is the signed payload of a token
+type cursorToken struct {
+	Keyset   string `json:"k"`
+	Kind     string `json:"t"`
+	Value    string `json:"v"`
+	ID       int64  `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Encode returns the token of cursor for keyset
+func (c *CursorCodec) Encode(keyset Keyset, cursor Cursor) (string, error) {
+	token := cursorToken{Keyset: keyset.String(), ID: cursor.ID, Backward: cursor.Backward}
+	switch value := cursor.Value.(type) {
+	case time.Time:
+		token.Kind, token.Value = "time", value.Format(time.RFC3339Nano)
+	case string:
+		token.Kind, token.Value = "string", value
+	case int:
+		token.Kind, token.Value = "int", strconv.FormatInt(int64(value), 10)
+	case int64:
+		token.Kind, token.Value = "int", strconv.FormatInt(value, 10)
+	case float64:
+		token.Kind, token.Value = "float", strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		return "", fmt.Errorf("unsupported cursor value type %T", cursor.Value)
+	}
+
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode returns the cursor of a token issued for keyset
+func (c *CursorCodec) Decode(keyset Keyset, encoded string) (*Cursor, error) {
+	encoding := base64.RawURLEncoding
+	payloadPart, signaturePart, found := strings.Cut(encoded, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := encoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := encoding.DecodeString(signaturePart)
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var token cursorToken
+	if err := json.Unmarshal(payload, &token); err != nil || token.Keyset != keyset.String() {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{ID: token.ID, Backward: token.Backward}
+	switch token.Kind {
+	case "time":
+		cursor.Value, err = time.Parse(time.RFC3339Nano, token.Value)
+	case "string":
+		cursor.Value = token.Value
+	case "int":
+		cursor.Value, err = strconv.ParseInt(token.Value, 10, 64)
+	case "float":
+		cursor.Value, err = strconv.ParseFloat(token.Value, 64)
+	default:
+		err = ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Paginate reads the page of params from a keyset ordered list. It decodes
+// the cursor, reads up to limit+1 rows with fetch to learn whether more
+// follow, and returns the page in keyset order with the cursors of the pages
+// before and after it. key returns the sort key value and ID of an item.
+func Paginate[T any](codec *CursorCodec, keyset Keyset, params CursorPaginationParams, fetch func(cursor *Cursor, limit int) ([]T, error), key func(T) (interface{}, int64)) ([]T, CursorPaginationResult, error) {
+	params.Validate()
+	var result CursorPaginationResult
+
+	var cursor *Cursor
+	if params.Cursor != "" {
+		decoded, err := codec.Decode(keyset, params.Cursor)
+		if err != nil {
+			return nil, result, err
+		}
+		cursor = decoded
+	}
+
+	items, err := fetch(cursor, params.Limit+1)
+	if err != nil {
+		return nil, result, err
+	}
+	more := len(items) > params.Limit
+	if more {
+		items = items[:params.Limit]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	result.Count = len(items)
+	if len(items) == 0 {
+		return items, result, nil
+	}
+
+	// A forward page has a next page when more rows follow it and a previous
+	// one when it was reached with a cursor; a backward page the other way
+	// round
+	hasNext, hasPrev := more, cursor != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		value, id := key(items[len(items)-1])
+		if result.NextCursor, err = codec.Encode(keyset, Cursor{Value: value, ID: id}); err != nil {
+			return nil, result, err
+		}
+	}
+	if hasPrev {
+		value, id := key(items[0])
+		if result.PrevCursor, err = codec.Encode(keyset, Cursor{Value: value, ID: id, Backward: true}); err != nil {
+			return nil, result, err
+		}
+	}
+	result.HasMore = hasNext
+	return items, result, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type keysetItem struct {
+	ID       int64   `db:"id"`
+	Price    float64 `db:"price"`
+	VendorID int64   `db:"vendor_id"`
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	keyset := Keyset{Scope: "orders", Column: "created_at", Order: Descending}
+
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC)
+	for _, value := range []interface{}{createdAt, "Çanta", int64(42), 19.99} {
+		token, err := codec.Encode(keyset, Cursor{Value: value, ID: 7, Backward: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursor, err := codec.Decode(keyset, token)
+		if err != nil {
+			t.Fatalf("%v: %v", value, err)
+		}
+		if fmt.Sprint(cursor.Value) != fmt.Sprint(value) || cursor.ID != 7 || !cursor.Backward {
+			t.Errorf("got %+v, want %v", cursor, value)
+		}
+	}
+
+	token, _ := codec.Encode(keyset, Cursor{Value: int64(1), ID: 1})
+	for name, bad := range map[string]string{
+		"tampered":    token[:len(token)-2] + "xx",
+		"unsigned":    token[:len(token)-44],
+		"other key":   mustEncode(t, NewCursorCodec([]byte("other")), keyset),
+		"raw secret":  mustEncode(t, &CursorCodec{key: []byte("secret")}, keyset),
+		"other order": mustEncode(t, codec, Keyset{Scope: "orders", Column: "created_at", Order: Ascending}),
+		"garbage":     "not-a-cursor",
+	} {
+		if _, err := codec.Decode(keyset, bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func mustEncode(t *testing.T, codec *CursorCodec, keyset Keyset) string {
+	t.Helper()
+	token, err := codec.Encode(keyset, Cursor{Value: int64(1), ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestPaginateKeyset(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, price REAL NOT NULL, vendor_id INTEGER NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	// Prices repeat, so pages must break ties on id
+	vendors := []int64{1, 1, 1, 2, 1, 1, 2, 2}
+	for i, price := range []float64{5, 3, 5, 1, 3, 5, 2, 4} {
+		if _, err := db.Exec("INSERT INTO items (id, price, vendor_id) VALUES (?, ?, ?)", i+1, price, vendors[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := NewMySQLRepository(db)
+	codec := NewCursorCodec(nil)
+	keyset := Keyset{Scope: "items", Column: "price", Order: Descending}
+
+	page := func(cursor string, conditions map[string]interface{}) ([]int64, CursorPaginationResult) {
+		t.Helper()
+		items, result, err := Paginate(codec, keyset, CursorPaginationParams{Cursor: cursor, Limit: 3},
+			func(cursor *Cursor, limit int) ([]keysetItem, error) {
+				var items []keysetItem
+				err := repo.FindAllKeyset("items", &items, conditions, keyset, cursor, limit)
+				return items, err
+			},
+			func(item keysetItem) (interface{}, int64) { return item.Price, item.ID })
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int64, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		return ids, result
+	}
+
+	// price DESC, id DESC: 6 3 1 | 8 5 2 | 7 4
+	first, result := page("", nil)
+	if fmt.Sprint(first) != "[6 3 1]" || result.PrevCursor != "" || !result.HasMore {
+		t.Fatalf("first page: got %v, %+v", first, result)
+	}
+	second, result := page(result.NextCursor, nil)
+	if fmt.Sprint(second) != "[8 5 2]" || result.PrevCursor == "" {
+		t.Fatalf("second page: got %v, %+v", second, result)
+	}
+	last, result := page(result.NextCursor, nil)
+	if fmt.Sprint(last) != "[7 4]" || result.NextCursor != "" || result.HasMore {
+		t.Fatalf("last page: got %v, %+v", last, result)
+	}
+
+	back, result := page(result.PrevCursor, nil)
+	if fmt.Sprint(back) != "[8 5 2]" || result.NextCursor == "" || result.PrevCursor == "" {
+		t.Fatalf("back from the last page: got %v, %+v", back, result)
+	}
+	back, result = page(result.PrevCursor, nil)
+	if fmt.Sprint(back) != "[6 3 1]" || result.PrevCursor != "" || result.NextCursor == "" {
+		t.Fatalf("back to the first page: got %v, %+v", back, result)
+	}
+
+	// Filters apply on every page
+	filtered, result := page("", map[string]interface{}{"vendor_id": 2})
+	if fmt.Sprint(filtered) != "[8 7 4]" || result.HasMore {
+		t.Errorf("filtered page: got %v, %+v", filtered, result)
+	}
+
+	token, _ := codec.Encode(keyset, Cursor{Value: 3.0, ID: 5})
+	if _, _, err := Paginate(codec, Keyset{Scope: "other", Column: "price", Order: Descending},
+		CursorPaginationParams{Cursor: token}, nil, func(keysetItem) (interface{}, int64) { return nil, 0 }); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("got %v for another list's cursor", err)
+	}
+}
@@ -3,9 +3,11 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"kolajAi/internal/config"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/mattn/go-sqlite3"
@@ -19,12 +21,102 @@ const (
 	SQLite DatabaseType = "sqlite3"
 )
 
+// PoolConfig sets the connection pool of a database. Zero fields keep the
+// defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// defaultMySQLPool is the pool of MySQL databases without a configured one
+var defaultMySQLPool = PoolConfig{MaxOpenConns: 25, MaxIdleConns: 10}
+
+// withDefaults returns p with the fields it leaves zero taken from defaults
+func (p PoolConfig) withDefaults(defaults PoolConfig) PoolConfig {
+	if p.MaxOpenConns <= 0 {
+		p.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if p.MaxIdleConns <= 0 {
+		p.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if p.ConnMaxLifetime <= 0 {
+		p.ConnMaxLifetime = defaults.ConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime <= 0 {
+		p.ConnMaxIdleTime = defaults.ConnMaxIdleTime
+	}
+	return p
+}
+
+// apply sets db's pool
+func (p PoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// ReplicaConfig is a MySQL read replica of the primary database. User,
+// Password and Pool default to the primary's.
+type ReplicaConfig struct {
+	// Name labels the replica in logs and metrics; it defaults to host:port
+	Name     string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Pool     PoolConfig
+}
+
+// ManagerConfig configures the databases a manager opens
+type ManagerConfig struct {
+	// Pool is the primary's connection pool. SQLite always uses a single
+	// connection.
+	Pool     PoolConfig
+	Replicas []ReplicaConfig
+	// Health configures the replicas' health checks
+	Health ReplicaSetConfig
+}
+
+// NewManagerConfig returns the manager config of the application's database
+// config
+func NewManagerConfig(c config.DatabaseConfig) ManagerConfig {
+	managerConfig := ManagerConfig{
+		Pool: PoolConfig{
+			MaxOpenConns:    c.MaxOpenConns,
+			MaxIdleConns:    c.MaxIdleConns,
+			ConnMaxLifetime: time.Duration(c.ConnMaxLifetime) * time.Minute,
+		},
+		Health: ReplicaSetConfig{
+			HealthCheckInterval: c.ReplicaHealthCheckInterval,
+			FailureThreshold:    c.ReplicaFailureThreshold,
+		},
+	}
+	for _, replica := range c.Replicas {
+		managerConfig.Replicas = append(managerConfig.Replicas, ReplicaConfig{
+			Name:     replica.Name,
+			Host:     replica.Host,
+			Port:     replica.Port,
+			User:     replica.User,
+			Password: replica.Password,
+			Pool:     PoolConfig{MaxOpenConns: replica.MaxOpenConns, MaxIdleConns: replica.MaxIdleConns},
+		})
+	}
+	return managerConfig
+}
+
 // DatabaseManager manages database connections
 type DatabaseManager struct {
 	DB       *sql.DB
 	DBType   DatabaseType
 	ConnStr  string
 	IsActive bool
+	Config   ManagerConfig
+	// Replicas routes reads between DB and its read replicas. Without
+	// replicas every read goes to DB.
+	Replicas *ReplicaSet
 }
 
 // NewDatabaseManager creates a new database manager
@@ -32,16 +124,30 @@ func NewDatabaseManager() *DatabaseManager {
 	return &DatabaseManager{}
 }
 
-// InitializeDatabase initializes the database based on environment
-func (dm *DatabaseManager) InitializeDatabase() error {
-	// Check if we're in development mode (use SQLite) or production (use MySQL)
+// NewDatabaseManagerWithConfig creates a database manager that opens its
+// databases with config
+func NewDatabaseManagerWithConfig(config ManagerConfig) *DatabaseManager {
+	return &DatabaseManager{Config: config}
+}
+
+// DefaultDatabaseType returns the type of database the environment uses,
+// SQLite in development and MySQL in production
+func DefaultDatabaseType() DatabaseType {
 	env := os.Getenv("APP_ENV")
 	if env == "" {
 		env = os.Getenv("GIN_MODE")
 	}
 
-	// Development: Use SQLite
 	if env == "development" || env == "" {
+		return SQLite
+	}
+	return MySQL
+}
+
+// InitializeDatabase initializes the database based on environment
+func (dm *DatabaseManager) InitializeDatabase() error {
+	// Development: Use SQLite
+	if DefaultDatabaseType() == SQLite {
 		return dm.initSQLite()
 	}
 
@@ -83,6 +189,10 @@ func (dm *DatabaseManager) initSQLite() error {
 	dm.DBType = SQLite
 	dm.ConnStr = connStr
 	dm.IsActive = true
+	dm.Replicas = NewReplicaSet(db, dm.Config.Health)
+	if len(dm.Config.Replicas) > 0 {
+		log.Printf("Read replicas are only supported with MySQL, ignoring %d replicas", len(dm.Config.Replicas))
+	}
 
 	log.Printf("✅ SQLite database initialized: %s", dbPath)
 	return nil
@@ -124,8 +234,7 @@ func (dm *DatabaseManager) initMySQL() error {
 	}
 
 	// Configure MySQL
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(10)
+	dm.Config.Pool.withDefaults(defaultMySQLPool).apply(db)
 
 	dm.DB = db
 	dm.DBType = MySQL
@@ -133,11 +242,63 @@ func (dm *DatabaseManager) initMySQL() error {
 	dm.IsActive = true
 
 	log.Printf("✅ MySQL database initialized: %s@%s:%s/%s", user, host, port, dbname)
+
+	dm.Replicas = NewReplicaSet(db, dm.Config.Health)
+	for _, replica := range dm.Config.Replicas {
+		dm.addMySQLReplica(replica, user, password, dbname)
+	}
+	dm.Replicas.Start()
 	return nil
 }
 
-// Close closes the database connection
+// addMySQLReplica opens a read replica of the primary database. A replica
+// that cannot be reached yet is added anyway and serves reads once its
+// health check succeeds.
+func (dm *DatabaseManager) addMySQLReplica(replica ReplicaConfig, user, password, dbname string) {
+	if replica.Port == 0 {
+		replica.Port = 3306
+	}
+	if replica.User == "" {
+		replica.User, replica.Password = user, password
+	}
+	if replica.Name == "" {
+		replica.Name = fmt.Sprintf("%s:%d", replica.Host, replica.Port)
+	}
+
+	connStr := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		replica.User, replica.Password, replica.Host, replica.Port, dbname)
+	db, err := sql.Open("mysql", connStr)
+	if err != nil {
+		log.Printf("Failed to open read replica %s: %v", replica.Name, err)
+		return
+	}
+	replica.Pool.withDefaults(dm.Config.Pool).withDefaults(defaultMySQLPool).apply(db)
+
+	dm.Replicas.AddReplica(replica.Name, db)
+	log.Printf("✅ MySQL read replica added: %s", replica.Name)
+}
+
+// Connect connects to the given type of database. Unlike InitializeDatabase
+// it never falls back to SQLite, for tools that must not act on the wrong
+// database.
+func (dm *DatabaseManager) Connect(dbType DatabaseType) error {
+	switch dbType {
+	case SQLite:
+		return dm.initSQLite()
+	case MySQL:
+		return dm.initMySQL()
+	default:
+		return fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
+
+// Close closes the database connection and its read replicas
 func (dm *DatabaseManager) Close() error {
+	if dm.Replicas != nil {
+		if err := dm.Replicas.Close(); err != nil {
+			log.Printf("Failed to close read replicas: %v", err)
+		}
+	}
 	if dm.DB != nil {
 		dm.IsActive = false
 		return dm.DB.Close()
@@ -150,6 +311,18 @@ func (dm *DatabaseManager) GetDB() *sql.DB {
 	return dm.DB
 }
 
+// PoolStats returns the connection pool metrics of the primary database and
+// its read replicas
+func (dm *DatabaseManager) PoolStats() []PoolStats {
+	if dm.Replicas == nil {
+		if dm.DB == nil {
+			return nil
+		}
+		return NewReplicaSet(dm.DB, ReplicaSetConfig{}).Stats()
+	}
+	return dm.Replicas.Stats()
+}
+
 // GetType returns the database type
 func (dm *DatabaseManager) GetType() DatabaseType {
 	return dm.DBType
@@ -175,7 +348,13 @@ var GlobalDBManager *DatabaseManager
 
 // InitGlobalDB initializes the global database manager
 func InitGlobalDB() error {
-	GlobalDBManager = NewDatabaseManager()
+	return InitGlobalDBWithConfig(ManagerConfig{})
+}
+
+// InitGlobalDBWithConfig initializes the global database manager with
+// config's pools and read replicas
+func InitGlobalDBWithConfig(config ManagerConfig) error {
+	GlobalDBManager = NewDatabaseManagerWithConfig(config)
 	return GlobalDBManager.InitializeDatabase()
 }
 
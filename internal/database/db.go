@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -42,6 +43,10 @@ type Repository interface {
 // MySQLRepository represents a MySQL database repository
 type MySQLRepository struct {
 	db *sql.DB
+	// replicas, when set, serves reads; db is its primary and takes writes
+	replicas *ReplicaSet
+	// ctx holds the read session of a repository bound with WithContext
+	ctx context.Context
 }
 
 // NewMySQLRepository creates a new MySQL repository
@@ -49,6 +54,46 @@ func NewMySQLRepository(db *sql.DB) *MySQLRepository {
 	return &MySQLRepository{db: db}
 }
 
+// SetReplicas routes the reads of copies bound to a read session through
+// rs; see WithContext. Other reads, writes, transactions and raw queries
+// that may write stay on the primary.
+func (r *MySQLRepository) SetReplicas(rs *ReplicaSet) {
+	r.replicas = rs
+}
+
+// WithContext returns a copy of the repository bound to ctx. Once a copy
+// writes, reads of every copy bound to the same read session go to the
+// primary; see WithReadSession.
+func (r *MySQLRepository) WithContext(ctx context.Context) *MySQLRepository {
+	bound := *r
+	bound.ctx = ctx
+	return &bound
+}
+
+// reader returns the database reads go to
+func (r *MySQLRepository) reader() *sql.DB {
+	if r.replicas == nil {
+		return r.db
+	}
+	return r.replicas.Reader(r.ctx)
+}
+
+// queryDB returns the database a raw query goes to
+func (r *MySQLRepository) queryDB(query string) *sql.DB {
+	if isReadOnlyQuery(query) {
+		return r.reader()
+	}
+	r.wrote()
+	return r.db
+}
+
+// wrote records a write in the repository's read session
+func (r *MySQLRepository) wrote() {
+	if r.replicas != nil {
+		r.replicas.Wrote(r.ctx)
+	}
+}
+
 // SetConnectionPool sets the connection pool parameters
 func (r *MySQLRepository) SetConnectionPool(maxOpen, maxIdle int, maxLifetime time.Duration) {
 	r.db.SetMaxOpenConns(maxOpen)
@@ -88,6 +133,7 @@ func (r *MySQLRepository) Create(table string, fields []string, values []interfa
 	}
 
 	query, args := qb.BuildInsert(data)
+	r.wrote()
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return 0, &DatabaseError{
@@ -153,6 +199,7 @@ func (r *MySQLRepository) Update(table string, id interface{}, data interface{})
 	}
 
 	query, args := qb.Where("id", Equal, id).BuildUpdate(dataMap)
+	r.wrote()
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return &DatabaseError{
@@ -185,6 +232,7 @@ func (r *MySQLRepository) Delete(table string, id interface{}) error {
 	}
 
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", table)
+	r.wrote()
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return &DatabaseError{
@@ -233,6 +281,7 @@ func (r *MySQLRepository) SoftDelete(table string, id interface{}) error {
 	}
 
 	query := fmt.Sprintf("UPDATE %s SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", table)
+	r.wrote()
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return &DatabaseError{
@@ -282,7 +331,7 @@ func (r *MySQLRepository) FindByID(table string, id interface{}, result interfac
 
 	qb := NewQueryBuilder(table)
 	query, args := qb.FindByID(id)
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return &DatabaseError{
 			Code:    "PREPARE_ERROR",
@@ -328,7 +377,7 @@ func (r *MySQLRepository) FindAll(table string, result interface{}, conditions m
 	}
 	
 	query, args := qb.Build()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return fmt.Errorf("error preparing statement: %v", err)
 	}
@@ -343,6 +392,35 @@ func (r *MySQLRepository) FindAll(table string, result interface{}, conditions m
 	return r.scanRows(rows, result)
 }
 
+// FindAllKeyset finds up to limit records after cursor in keyset order. A
+// nil cursor starts at the beginning of the list.
+func (r *MySQLRepository) FindAllKeyset(table string, result interface{}, conditions map[string]interface{}, keyset Keyset, cursor *Cursor, limit int) error {
+	if !validateTableName(table) {
+		return fmt.Errorf("invalid table name: %s", table)
+	}
+	if err := keyset.Validate(); err != nil {
+		return err
+	}
+
+	qb := NewQueryBuilder(table)
+	if conditions != nil {
+		qb.Filter(conditions)
+	}
+	keyset.Apply(qb, cursor)
+	if limit > 0 {
+		qb.Limit(limit)
+	}
+
+	query, args := qb.Build()
+	rows, err := r.reader().Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("error executing query: %v", err)
+	}
+	defer rows.Close()
+
+	return r.scanRows(rows, result)
+}
+
 // scanRows scans multiple rows into a slice
 func (r *MySQLRepository) scanRows(rows *sql.Rows, result interface{}) error {
 	// Get result value and type
@@ -442,7 +520,7 @@ func (r *MySQLRepository) FindOne(table string, dest interface{}, conditions map
 	qb := NewQueryBuilder(table)
 	qb.Filter(conditions)
 	query, args := qb.Limit(1).Build()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return &DatabaseError{
 			Code:    "PREPARE_ERROR",
@@ -555,7 +633,7 @@ func (r *MySQLRepository) Count(table string, conditions map[string]interface{})
 	qb := NewQueryBuilder(table)
 	qb.Filter(conditions)
 	query, args := qb.BuildCount()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return 0, fmt.Errorf("error preparing statement: %v", err)
 	}
@@ -579,7 +657,7 @@ func (r *MySQLRepository) Search(table string, fields []string, term string, lim
 	qb := NewQueryBuilder(table)
 	qb.Search(fields, term)
 	query, args := qb.Limit(limit).Offset(offset).Build()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return fmt.Errorf("error preparing statement: %v", err)
 	}
@@ -602,7 +680,7 @@ func (r *MySQLRepository) FindByDateRange(table, dateField string, start, end ti
 
 	qb := NewQueryBuilder(table)
 	query, args := qb.WhereDateBetween(dateField, start, end).Limit(limit).Offset(offset).Build()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return fmt.Errorf("error preparing statement: %v", err)
 	}
@@ -619,6 +697,7 @@ func (r *MySQLRepository) FindByDateRange(table, dateField string, start, end ti
 
 // Transaction executes a function within a transaction
 func (r *MySQLRepository) Transaction(fn func(*sql.Tx) error) error {
+	r.wrote()
 	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("error beginning transaction: %v", err)
@@ -718,7 +797,7 @@ func (r *MySQLRepository) Exists(table string, conditions map[string]interface{}
 	qb := NewQueryBuilder(table)
 	qb.Filter(conditions)
 	query, args := qb.BuildCount()
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.reader().Prepare(query)
 	if err != nil {
 		return false, &DatabaseError{
 			Code:    "PREPARE_ERROR",
@@ -805,6 +884,7 @@ func getFieldsAndValues(data interface{}) ([]string, []interface{}) {
 
 // Begin starts a database transaction
 func (r *MySQLRepository) Begin() (Transaction, error) {
+	r.wrote()
 	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, err
@@ -814,6 +894,7 @@ func (r *MySQLRepository) Begin() (Transaction, error) {
 
 // Exec executes a query without returning any rows
 func (r *MySQLRepository) Exec(query string, args ...interface{}) (Result, error) {
+	r.wrote()
 	result, err := r.db.Exec(query, args...)
 	if err != nil {
 		return nil, err
@@ -823,7 +904,7 @@ func (r *MySQLRepository) Exec(query string, args ...interface{}) (Result, error
 
 // Query executes a query that returns rows
 func (r *MySQLRepository) Query(query string, args ...interface{}) (Rows, error) {
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.queryDB(query).Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -832,7 +913,7 @@ func (r *MySQLRepository) Query(query string, args ...interface{}) (Rows, error)
 
 // QueryRow executes a query that is expected to return at most one row
 func (r *MySQLRepository) QueryRow(query string, args ...interface{}) Row {
-	row := r.db.QueryRow(query, args...)
+	row := r.queryDB(query).QueryRow(query, args...)
 	return &rowWrapper{row}
 }
 
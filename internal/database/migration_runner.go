@@ -1,201 +1,439 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"sort"
+	"time"
 
 	"kolajAi/internal/database/migrations"
 )
 
-// MigrationRunner handles database migrations
+var (
+	// ErrMigrationModified is returned when an applied migration's SQL no
+	// longer matches the checksum recorded when it was applied
+	ErrMigrationModified = errors.New("applied migration has been modified")
+	// ErrUnknownMigration is returned when rolling back a migration the
+	// application no longer defines
+	ErrUnknownMigration = errors.New("applied migration is not defined")
+	// ErrMigrationLocked is returned when another process holds the migration
+	// lock for longer than the runner's LockTimeout
+	ErrMigrationLocked = errors.New("migrations are locked by another process")
+)
+
+const (
+	// migrationLockName names the MySQL advisory lock taken while migrating
+	migrationLockName = "kolajai_schema_migrations"
+	// staleMigrationLock is how old a SQLite lock row must be before it is
+	// taken to belong to a process that died without releasing it
+	staleMigrationLock = 10 * time.Minute
+)
+
+// MigrationStatus is a migration's state in the database
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the migration's SQL changed after it was applied
+	Modified bool `json:"modified"`
+	// Missing is set when the migration was applied but the application no
+	// longer defines it, as after deploying an older release
+	Missing bool `json:"missing"`
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// MigrationRunner applies and rolls back versioned migrations, taking a lock
+// so that only one process migrates a database at a time
 type MigrationRunner struct {
-	db     *sql.DB
-	dbType DatabaseType
+	db         *sql.DB
+	dbType     DatabaseType
+	migrations []migrations.Migration
+	// LockTimeout is how long to wait for another process to finish
+	// migrating, 1 minute by default
+	LockTimeout time.Duration
 }
 
 // NewMigrationRunner creates a new migration runner
 func NewMigrationRunner(db *sql.DB, dbType DatabaseType) *MigrationRunner {
 	return &MigrationRunner{
-		db:     db,
-		dbType: dbType,
+		db:          db,
+		dbType:      dbType,
+		migrations:  migrations.All(),
+		LockTimeout: time.Minute,
 	}
 }
 
-// RunMigrations runs migrations based on database type
+// RunMigrations applies all pending migrations
 func (mr *MigrationRunner) RunMigrations() error {
 	log.Printf("🔄 Starting migrations for %s database", mr.dbType)
 
-	switch mr.dbType {
-	case SQLite:
-		return migrations.RunSQLiteMigrations(mr.db)
-	case MySQL:
-		return mr.runMySQLMigrations()
-	default:
+	applied, err := mr.Up()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Migrations completed, %d applied", applied)
+	return nil
+}
+
+// Status returns every migration the application defines, followed by any
+// applied ones it does not
+func (mr *MigrationRunner) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := mr.withConn(false, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mr.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range mr.migrations {
+			status := MigrationStatus{Version: m.Version, Name: m.Name}
+			if row, ok := applied[m.Version]; ok {
+				appliedAt := row.appliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = row.checksum != m.Checksum(mr.dialect())
+				delete(applied, m.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, version := range sortedVersions(applied) {
+			row := applied[version]
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Name:      row.name,
+				Applied:   true,
+				AppliedAt: &row.appliedAt,
+				Missing:   true,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Up applies all pending migrations in version order and returns how many
+// it applied. It refuses to run when an applied migration has been modified.
+func (mr *MigrationRunner) Up() (int, error) {
+	if err := migrations.Validate(mr.migrations); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := mr.withConn(true, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mr.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var pending []migrations.Migration
+		for _, m := range mr.migrations {
+			row, ok := applied[m.Version]
+			if !ok {
+				pending = append(pending, m)
+				continue
+			}
+			if row.checksum != m.Checksum(mr.dialect()) {
+				return fmt.Errorf("%w: %s", ErrMigrationModified, m)
+			}
+			delete(applied, m.Version)
+		}
+		for _, version := range sortedVersions(applied) {
+			log.Printf("Migration %03d_%s is applied but not defined by this release", version, applied[version].name)
+		}
+
+		for _, m := range pending {
+			if err := mr.apply(ctx, conn, m); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the last n applied migrations, newest first, and returns
+// how many it rolled back
+func (mr *MigrationRunner) Down(n int) (int, error) {
+	count := 0
+	err := mr.withConn(true, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mr.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := sortedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && count < n; i-- {
+			m, ok := mr.find(versions[i])
+			if !ok {
+				return fmt.Errorf("%w: %03d_%s", ErrUnknownMigration, versions[i], applied[versions[i]].name)
+			}
+			if err := mr.revert(ctx, conn, m); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Redo rolls back the last applied migration and applies it again, which
+// also records its current checksum
+func (mr *MigrationRunner) Redo() (*migrations.Migration, error) {
+	var redone *migrations.Migration
+	err := mr.withConn(true, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := mr.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := sortedVersions(applied)
+		if len(versions) == 0 {
+			return nil
+		}
+		last := versions[len(versions)-1]
+		m, ok := mr.find(last)
+		if !ok {
+			return fmt.Errorf("%w: %03d_%s", ErrUnknownMigration, last, applied[last].name)
+		}
+		if err := mr.revert(ctx, conn, m); err != nil {
+			return err
+		}
+		if err := mr.apply(ctx, conn, m); err != nil {
+			return err
+		}
+		redone = &m
+		return nil
+	})
+	return redone, err
+}
+
+// apply runs a migration's up statements and records it. MySQL commits DDL
+// statements implicitly, so a migration that fails there may be left
+// partially applied and has to be cleaned up by hand before retrying.
+func (mr *MigrationRunner) apply(ctx context.Context, conn *sql.Conn, m migrations.Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %s: %w", m, err)
+	}
+	defer tx.Rollback()
+
+	for i, statement := range m.Up.Statements(mr.dialect()) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to apply migration %s, statement %d: %w", m, i+1, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		m.Version, m.Name, m.Checksum(mr.dialect()), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m, err)
+	}
+
+	log.Printf("Applied migration %s", m)
+	return nil
+}
+
+// revert runs a migration's down statements and removes its record
+func (mr *MigrationRunner) revert(ctx context.Context, conn *sql.Conn, m migrations.Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start rolling back migration %s: %w", m, err)
+	}
+	defer tx.Rollback()
+
+	for i, statement := range m.Down.Statements(mr.dialect()) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to roll back migration %s, statement %d: %w", m, i+1, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+		return fmt.Errorf("failed to remove migration record %s: %w", m, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %s: %w", m, err)
+	}
+
+	log.Printf("Rolled back migration %s", m)
+	return nil
+}
+
+// applied returns the recorded migrations by version
+func (mr *MigrationRunner) applied(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
+
+// find returns the migration with version
+func (mr *MigrationRunner) find(version int) (migrations.Migration, bool) {
+	for _, m := range mr.migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return migrations.Migration{}, false
+}
+
+func (mr *MigrationRunner) dialect() migrations.Dialect {
+	return migrations.Dialect(mr.dbType)
+}
+
+// withConn runs fn on a single connection, after creating the migration
+// tables and, when lock is set, taking the migration lock. Using one
+// connection keeps MySQL's session-scoped lock held and works with SQLite's
+// single connection pool.
+func (mr *MigrationRunner) withConn(lock bool, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	if mr.dbType != SQLite && mr.dbType != MySQL {
 		return fmt.Errorf("unsupported database type: %s", mr.dbType)
 	}
+
+	ctx := context.Background()
+	conn, err := mr.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := mr.createTables(ctx, conn); err != nil {
+		return err
+	}
+	if lock {
+		unlock, err := mr.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	return fn(ctx, conn)
 }
 
-// runMySQLMigrations runs MySQL specific migrations
-func (mr *MigrationRunner) runMySQLMigrations() error {
-	log.Println("🔄 Running MySQL migrations...")
-
-	// MySQL migration files are in the migrations directory
-	// For now, we'll use a simple approach
-	mysqlMigrations := []string{
-		// Users table
-		`CREATE TABLE IF NOT EXISTS users (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			password VARCHAR(255) NOT NULL,
-			phone VARCHAR(20),
-			is_active BOOLEAN DEFAULT TRUE,
-			is_admin BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			INDEX idx_users_email (email),
-			INDEX idx_users_active (is_active)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-
-		// Categories table
-		`CREATE TABLE IF NOT EXISTS categories (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			name VARCHAR(200) NOT NULL,
-			slug VARCHAR(250) UNIQUE NOT NULL,
-			description TEXT,
-			parent_id INT NULL,
-			image VARCHAR(500),
-			is_active BOOLEAN DEFAULT TRUE,
-			is_visible BOOLEAN DEFAULT TRUE,
-			is_featured BOOLEAN DEFAULT FALSE,
-			sort_order INT DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE CASCADE,
-			INDEX idx_categories_parent (parent_id),
-			INDEX idx_categories_active (is_active),
-			INDEX idx_categories_slug (slug),
-			INDEX idx_categories_sort (sort_order)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-
-		// Vendors table
-		`CREATE TABLE IF NOT EXISTS vendors (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			user_id INT NOT NULL,
-			company_name VARCHAR(255) NOT NULL,
-			business_id VARCHAR(50) UNIQUE,
-			phone VARCHAR(20),
-			address TEXT,
-			city VARCHAR(100),
-			country VARCHAR(100),
-			status ENUM('pending', 'approved', 'rejected', 'suspended') DEFAULT 'pending',
-			commission_rate DECIMAL(5,2) DEFAULT 10.00,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-			INDEX idx_vendors_user (user_id),
-			INDEX idx_vendors_status (status)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-
-		// Products table
-		`CREATE TABLE IF NOT EXISTS products (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			vendor_id INT NOT NULL,
-			category_id INT NOT NULL,
-			name VARCHAR(255) NOT NULL,
-			description TEXT,
-			short_desc VARCHAR(500),
-			sku VARCHAR(100) UNIQUE NOT NULL,
-			price DECIMAL(10,2) NOT NULL DEFAULT 0.00,
-			compare_price DECIMAL(10,2) DEFAULT 0.00,
-			cost_price DECIMAL(10,2) DEFAULT 0.00,
-			wholesale_price DECIMAL(10,2) DEFAULT 0.00,
-			min_wholesale_qty INT DEFAULT 1,
-			stock INT NOT NULL DEFAULT 0,
-			min_stock INT DEFAULT 0,
-			weight DECIMAL(8,2) DEFAULT 0.00,
-			dimensions VARCHAR(100),
-			status ENUM('draft', 'active', 'inactive', 'out_of_stock') DEFAULT 'draft',
-			is_digital BOOLEAN DEFAULT FALSE,
-			is_featured BOOLEAN DEFAULT FALSE,
-			allow_reviews BOOLEAN DEFAULT TRUE,
-			meta_title VARCHAR(255),
-			meta_desc VARCHAR(500),
-			tags VARCHAR(1000),
-			view_count INT DEFAULT 0,
-			sales_count INT DEFAULT 0,
-			rating DECIMAL(3,2) DEFAULT 0.00,
-			review_count INT DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
-			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
-			INDEX idx_products_vendor (vendor_id),
-			INDEX idx_products_category (category_id),
-			INDEX idx_products_status (status),
-			INDEX idx_products_featured (is_featured),
-			INDEX idx_products_sku (sku),
-			INDEX idx_products_rating (rating),
-			FULLTEXT idx_products_search (name, description, tags)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-
-		// Product images table
-		`CREATE TABLE IF NOT EXISTS product_images (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			product_id INT NOT NULL,
-			image_url VARCHAR(500) NOT NULL,
-			alt_text VARCHAR(255),
-			sort_order INT DEFAULT 0,
-			is_primary BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
-			INDEX idx_product_images_product (product_id),
-			INDEX idx_product_images_primary (is_primary)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-
-		// Auctions table
-		`CREATE TABLE IF NOT EXISTS auctions (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			product_id INT NULL,
-			vendor_id INT NOT NULL,
-			title VARCHAR(255) NOT NULL,
-			description TEXT,
-			starting_price DECIMAL(10,2) NOT NULL,
-			reserve_price DECIMAL(10,2) DEFAULT 0.00,
-			current_bid DECIMAL(10,2) DEFAULT 0.00,
-			bid_increment DECIMAL(10,2) DEFAULT 1.00,
-			total_bids INT DEFAULT 0,
-			start_time TIMESTAMP NOT NULL,
-			end_time TIMESTAMP NOT NULL,
-			status ENUM('draft', 'active', 'ended', 'cancelled') DEFAULT 'draft',
-			winner_id INT NULL,
-			view_count INT DEFAULT 0,
-			is_reserve_met BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
-			FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
-			FOREIGN KEY (winner_id) REFERENCES users(id) ON DELETE SET NULL,
-			INDEX idx_auctions_vendor (vendor_id),
-			INDEX idx_auctions_status (status),
-			INDEX idx_auctions_end_time (end_time),
-			INDEX idx_auctions_product (product_id)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
-	}
-
-	for i, migration := range mysqlMigrations {
-		log.Printf("Running MySQL migration %d/%d", i+1, len(mysqlMigrations))
-		
-		if _, err := mr.db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to run MySQL migration %d: %w", i+1, err)
-		}
-	}
-
-	log.Println("✅ All MySQL migrations completed successfully")
+// createTables creates the table recording applied migrations and, on
+// SQLite, the table holding the migration lock
+func (mr *MigrationRunner) createTables(ctx context.Context, conn *sql.Conn) error {
+	queries := []string{`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`}
+	if mr.dbType == SQLite {
+		queries = append(queries, `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY,
+			owner VARCHAR(255) NOT NULL,
+			locked_at DATETIME NOT NULL
+		)`)
+	} else {
+		queries[0] += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci"
+	}
+
+	for _, query := range queries {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create migrations table: %w", err)
+		}
+	}
 	return nil
 }
 
+// lock takes the migration lock and returns the function releasing it.
+// MySQL has advisory locks, which it releases by itself when the connection
+// drops. SQLite has none, so a row in schema_migrations_lock stands in for
+// one and is taken over once it is stale.
+func (mr *MigrationRunner) lock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	timeout := mr.LockTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+
+	if mr.dbType == MySQL {
+		var acquired sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, int(timeout.Seconds())).Scan(&acquired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if acquired.Int64 != 1 {
+			return nil, ErrMigrationLocked
+		}
+		return func() {
+			conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationLockName)
+		}, nil
+	}
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+	deadline := time.Now().Add(timeout)
+	for {
+		result, err := conn.ExecContext(ctx,
+			"INSERT OR IGNORE INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, ?, ?)",
+			owner, time.Now().UTC())
+		if err != nil {
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			return func() {
+				conn.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?", owner)
+			}, nil
+		}
+
+		var holder string
+		var lockedAt time.Time
+		err = conn.QueryRowContext(ctx, "SELECT owner, locked_at FROM schema_migrations_lock WHERE id = 1").Scan(&holder, &lockedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to check migration lock: %w", err)
+		}
+		if err == nil && time.Since(lockedAt) > staleMigrationLock {
+			log.Printf("Taking over stale migration lock held by %s since %s", holder, lockedAt)
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?", holder); err != nil {
+				return nil, fmt.Errorf("failed to remove stale migration lock: %w", err)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrMigrationLocked
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// sortedVersions returns the versions of applied in ascending order
+func sortedVersions(applied map[int]appliedMigration) []int {
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
 // RunMigrationsForGlobalDB runs migrations for the global database
 func RunMigrationsForGlobalDB() error {
 	if GlobalDBManager == nil {
@@ -204,4 +442,4 @@ func RunMigrationsForGlobalDB() error {
 
 	runner := NewMigrationRunner(GlobalDBManager.GetDB(), GlobalDBManager.GetType())
 	return runner.RunMigrations()
-}
\ No newline at end of file
+}
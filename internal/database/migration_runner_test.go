@@ -0,0 +1,215 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database/migrations"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count == 1
+}
+
+func TestMigrationsAreValid(t *testing.T) {
+	if err := migrations.Validate(migrations.All()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCoreSchemaUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+	runner := NewMigrationRunner(db, SQLite)
+
+	if err := runner.RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"users", "products", "orders", "order_items", "sessions"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+
+	// Applying again is a no-op
+	if applied, err := runner.Up(); err != nil || applied != 0 {
+		t.Fatalf("got %d, %v on a migrated database", applied, err)
+	}
+
+	if reverted, err := runner.Down(len(migrations.All())); err != nil || reverted != len(migrations.All()) {
+		t.Fatalf("got %d, %v", reverted, err)
+	}
+	if tableExists(t, db, "users") {
+		t.Error("users table was not dropped")
+	}
+}
+
+// TestServiceTablesAreMigrated checks that an empty database gets the tables
+// services query directly, including those of the schema files that
+// predate versioned migrations
+func TestServiceTablesAreMigrated(t *testing.T) {
+	db := openTestDB(t)
+	runner := NewMigrationRunner(db, SQLite)
+	all := runner.migrations
+
+	// A user signs up before the AI credit tables are added
+	runner.migrations = all[:1]
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO users (name, email, password) VALUES ('a', 'a@kolaj.ai', 'x')"); err != nil {
+		t.Fatal(err)
+	}
+	runner.migrations = all
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{
+		"user_profiles", "email_logs",
+		"carts", "cart_items", "order_addresses", "product_attributes", "product_reviews", "auction_watchers", "auction_images",
+		"ai_image_analysis", "user_image_categories", "user_image_tags", "user_image_collections",
+		"customer_service_requests", "ai_credits", "chat_sessions", "chat_messages",
+		"user_sessions", "page_views", "customer_segments", "cart_abandonment",
+		"integration_configs", "webhook_events",
+	} {
+		if !tableExists(t, db, table) {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+
+	// and gets the starting credits
+	var credits int
+	if err := db.QueryRow("SELECT credits FROM ai_credits WHERE user_id = 1").Scan(&credits); err != nil || credits != 100 {
+		t.Errorf("got %d credits, %v; want 100", credits, err)
+	}
+}
+
+func TestMigrationRunner(t *testing.T) {
+	db := openTestDB(t)
+	runner := NewMigrationRunner(db, SQLite)
+	runner.migrations = []migrations.Migration{
+		{
+			Version: 1,
+			Name:    "create_notes",
+			Up:      migrations.Both(`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)`),
+			Down:    migrations.Both(`DROP TABLE notes`),
+		},
+		{
+			Version: 2,
+			Name:    "add_notes_title",
+			Up:      migrations.Both(`ALTER TABLE notes ADD COLUMN title TEXT`),
+			Down:    migrations.Both(`ALTER TABLE notes DROP COLUMN title`),
+		},
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("got %+v before migrating", statuses)
+	}
+
+	if applied, err := runner.Up(); err != nil || applied != 2 {
+		t.Fatalf("got %d, %v", applied, err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (body, title) VALUES ('a', 'b')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if reverted, err := runner.Down(1); err != nil || reverted != 1 {
+		t.Fatalf("got %d, %v", reverted, err)
+	}
+	statuses, _ = runner.Status()
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("got %+v after rolling back one", statuses)
+	}
+
+	// A migration failing part way is rolled back with its record
+	runner.migrations[1].Up = migrations.Both(`ALTER TABLE notes ADD COLUMN title TEXT`, `INSERT INTO missing VALUES (1)`)
+	if _, err := runner.Up(); err == nil {
+		t.Fatal("failing migration was applied")
+	}
+	if _, err := db.Exec("SELECT title FROM notes"); err == nil {
+		t.Error("failed migration left its column behind")
+	}
+	runner.migrations[1].Up = migrations.Both(`ALTER TABLE notes ADD COLUMN title TEXT`)
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Editing an applied migration is caught until it is redone
+	runner.migrations[1].Up = migrations.Both(`ALTER TABLE notes ADD COLUMN title VARCHAR(100)`)
+	if _, err := runner.Up(); !errors.Is(err, ErrMigrationModified) {
+		t.Fatalf("got %v, want ErrMigrationModified", err)
+	}
+	statuses, _ = runner.Status()
+	if !statuses[1].Modified {
+		t.Errorf("got %+v, want the second migration modified", statuses[1])
+	}
+	redone, err := runner.Redo()
+	if err != nil || redone == nil || redone.Version != 2 {
+		t.Fatalf("got %v, %v", redone, err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("after redo: %v", err)
+	}
+
+	// Applied migrations the release no longer defines are reported and
+	// cannot be rolled back
+	runner.migrations = runner.migrations[:1]
+	statuses, _ = runner.Status()
+	if len(statuses) != 2 || !statuses[1].Missing {
+		t.Fatalf("got %+v, want the second migration missing", statuses)
+	}
+	if _, err := runner.Down(1); !errors.Is(err, ErrUnknownMigration) {
+		t.Fatalf("got %v, want ErrUnknownMigration", err)
+	}
+}
+
+func TestMigrationLock(t *testing.T) {
+	db := openTestDB(t)
+	runner := NewMigrationRunner(db, SQLite)
+	runner.LockTimeout = 300 * time.Millisecond
+	if _, err := runner.Status(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'other', ?)", time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("got %v, want ErrMigrationLocked", err)
+	}
+
+	// A lock left behind by a process that died is taken over
+	if _, err := db.Exec("UPDATE schema_migrations_lock SET locked_at = ?", time.Now().UTC().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+	var locks int
+	db.QueryRow("SELECT COUNT(*) FROM schema_migrations_lock").Scan(&locks)
+	if locks != 0 {
+		t.Errorf("lock was not released")
+	}
+}
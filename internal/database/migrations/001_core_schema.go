@@ -0,0 +1,396 @@
+package migrations
+
+// coreSchema is the schema both dialects were created with before migrations
+// were versioned. Its tables already exist on older databases, so every
+// statement is written to be a no-op there.
+var coreSchema = Migration{
+	Version: 1,
+	Name:    "core_schema",
+	Up: Step{
+		SQLite: []string{
+			// Users table
+			`CREATE TABLE IF NOT EXISTS users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name VARCHAR(255) NOT NULL,
+				email VARCHAR(255) UNIQUE NOT NULL,
+				password VARCHAR(255) NOT NULL,
+				phone VARCHAR(20),
+				is_active BOOLEAN DEFAULT 1,
+				is_admin BOOLEAN DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Categories table
+			`CREATE TABLE IF NOT EXISTS categories (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name VARCHAR(200) NOT NULL,
+				slug VARCHAR(250) UNIQUE NOT NULL,
+				description TEXT,
+				parent_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+				image VARCHAR(500),
+				is_active BOOLEAN DEFAULT 1,
+				is_visible BOOLEAN DEFAULT 1,
+				is_featured BOOLEAN DEFAULT 0,
+				sort_order INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Vendors table
+			`CREATE TABLE IF NOT EXISTS vendors (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				company_name VARCHAR(255) NOT NULL,
+				business_id VARCHAR(50) UNIQUE,
+				phone VARCHAR(20),
+				address TEXT,
+				city VARCHAR(100),
+				country VARCHAR(100),
+				status VARCHAR(20) DEFAULT 'pending',
+				commission_rate DECIMAL(5,2) DEFAULT 10.00,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Products table
+			`CREATE TABLE IF NOT EXISTS products (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+				category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				short_desc VARCHAR(500),
+				sku VARCHAR(100) UNIQUE NOT NULL,
+				price DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				compare_price DECIMAL(10,2) DEFAULT 0.00,
+				cost_price DECIMAL(10,2) DEFAULT 0.00,
+				wholesale_price DECIMAL(10,2) DEFAULT 0.00,
+				min_wholesale_qty INTEGER DEFAULT 1,
+				stock INTEGER NOT NULL DEFAULT 0,
+				min_stock INTEGER DEFAULT 0,
+				weight DECIMAL(8,2) DEFAULT 0.00,
+				dimensions VARCHAR(100),
+				status VARCHAR(20) DEFAULT 'draft',
+				is_digital BOOLEAN DEFAULT 0,
+				is_featured BOOLEAN DEFAULT 0,
+				allow_reviews BOOLEAN DEFAULT 1,
+				meta_title VARCHAR(255),
+				meta_desc VARCHAR(500),
+				tags VARCHAR(1000),
+				view_count INTEGER DEFAULT 0,
+				sales_count INTEGER DEFAULT 0,
+				rating DECIMAL(3,2) DEFAULT 0.00,
+				review_count INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Product images table
+			`CREATE TABLE IF NOT EXISTS product_images (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+				image_url VARCHAR(500) NOT NULL,
+				alt_text VARCHAR(255),
+				sort_order INTEGER DEFAULT 0,
+				is_primary BOOLEAN DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Auctions table
+			`CREATE TABLE IF NOT EXISTS auctions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				product_id INTEGER REFERENCES products(id) ON DELETE CASCADE,
+				vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+				title VARCHAR(255) NOT NULL,
+				description TEXT,
+				starting_price DECIMAL(10,2) NOT NULL,
+				reserve_price DECIMAL(10,2) DEFAULT 0.00,
+				current_bid DECIMAL(10,2) DEFAULT 0.00,
+				bid_increment DECIMAL(10,2) DEFAULT 1.00,
+				total_bids INTEGER DEFAULT 0,
+				start_time DATETIME NOT NULL,
+				end_time DATETIME NOT NULL,
+				status VARCHAR(20) DEFAULT 'draft',
+				winner_id INTEGER REFERENCES users(id),
+				view_count INTEGER DEFAULT 0,
+				is_reserve_met BOOLEAN DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Orders table
+			`CREATE TABLE IF NOT EXISTS orders (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				order_number VARCHAR(50) UNIQUE NOT NULL,
+				status VARCHAR(20) DEFAULT 'pending',
+				subtotal DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				tax_amount DECIMAL(10,2) DEFAULT 0.00,
+				shipping_amount DECIMAL(10,2) DEFAULT 0.00,
+				discount_amount DECIMAL(10,2) DEFAULT 0.00,
+				total_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				currency VARCHAR(3) DEFAULT 'TRY',
+				payment_status VARCHAR(20) DEFAULT 'pending',
+				payment_method VARCHAR(50),
+				shipping_address TEXT,
+				billing_address TEXT,
+				notes TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Order items table
+			`CREATE TABLE IF NOT EXISTS order_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+				product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+				vendor_id INTEGER NOT NULL REFERENCES vendors(id) ON DELETE CASCADE,
+				product_name VARCHAR(255) NOT NULL,
+				product_sku VARCHAR(100) NOT NULL,
+				quantity INTEGER NOT NULL DEFAULT 1,
+				unit_price DECIMAL(10,2) NOT NULL,
+				total_price DECIMAL(10,2) NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Sessions table
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id VARCHAR(255) PRIMARY KEY,
+				user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+				data TEXT,
+				expires_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+
+			// Create indexes for better performance
+			`CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_categories_active ON categories(is_active)`,
+			`CREATE INDEX IF NOT EXISTS idx_categories_slug ON categories(slug)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_vendor ON products(vendor_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_category ON products(category_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_status ON products(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_featured ON products(is_featured)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_sku ON products(sku)`,
+			`CREATE INDEX IF NOT EXISTS idx_product_images_product ON product_images(product_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_auctions_vendor ON auctions(vendor_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_auctions_status ON auctions(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_auctions_end_time ON auctions(end_time)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_user ON orders(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_order_items_product ON order_items(product_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at)`,
+		},
+		MySQL: []string{
+			// Users table
+			`CREATE TABLE IF NOT EXISTS users (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				email VARCHAR(255) UNIQUE NOT NULL,
+				password VARCHAR(255) NOT NULL,
+				phone VARCHAR(20),
+				is_active BOOLEAN DEFAULT TRUE,
+				is_admin BOOLEAN DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_users_email (email),
+				INDEX idx_users_active (is_active)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Categories table
+			`CREATE TABLE IF NOT EXISTS categories (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(200) NOT NULL,
+				slug VARCHAR(250) UNIQUE NOT NULL,
+				description TEXT,
+				parent_id INT NULL,
+				image VARCHAR(500),
+				is_active BOOLEAN DEFAULT TRUE,
+				is_visible BOOLEAN DEFAULT TRUE,
+				is_featured BOOLEAN DEFAULT FALSE,
+				sort_order INT DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE CASCADE,
+				INDEX idx_categories_parent (parent_id),
+				INDEX idx_categories_active (is_active),
+				INDEX idx_categories_slug (slug),
+				INDEX idx_categories_sort (sort_order)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Vendors table
+			`CREATE TABLE IF NOT EXISTS vendors (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				company_name VARCHAR(255) NOT NULL,
+				business_id VARCHAR(50) UNIQUE,
+				phone VARCHAR(20),
+				address TEXT,
+				city VARCHAR(100),
+				country VARCHAR(100),
+				status ENUM('pending', 'approved', 'rejected', 'suspended') DEFAULT 'pending',
+				commission_rate DECIMAL(5,2) DEFAULT 10.00,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+				INDEX idx_vendors_user (user_id),
+				INDEX idx_vendors_status (status)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Products table
+			`CREATE TABLE IF NOT EXISTS products (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				vendor_id INT NOT NULL,
+				category_id INT NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				short_desc VARCHAR(500),
+				sku VARCHAR(100) UNIQUE NOT NULL,
+				price DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				compare_price DECIMAL(10,2) DEFAULT 0.00,
+				cost_price DECIMAL(10,2) DEFAULT 0.00,
+				wholesale_price DECIMAL(10,2) DEFAULT 0.00,
+				min_wholesale_qty INT DEFAULT 1,
+				stock INT NOT NULL DEFAULT 0,
+				min_stock INT DEFAULT 0,
+				weight DECIMAL(8,2) DEFAULT 0.00,
+				dimensions VARCHAR(100),
+				status ENUM('draft', 'active', 'inactive', 'out_of_stock') DEFAULT 'draft',
+				is_digital BOOLEAN DEFAULT FALSE,
+				is_featured BOOLEAN DEFAULT FALSE,
+				allow_reviews BOOLEAN DEFAULT TRUE,
+				meta_title VARCHAR(255),
+				meta_desc VARCHAR(500),
+				tags VARCHAR(1000),
+				view_count INT DEFAULT 0,
+				sales_count INT DEFAULT 0,
+				rating DECIMAL(3,2) DEFAULT 0.00,
+				review_count INT DEFAULT 0,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
+				FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
+				INDEX idx_products_vendor (vendor_id),
+				INDEX idx_products_category (category_id),
+				INDEX idx_products_status (status),
+				INDEX idx_products_featured (is_featured),
+				INDEX idx_products_sku (sku),
+				INDEX idx_products_rating (rating),
+				FULLTEXT idx_products_search (name, description, tags)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Product images table
+			`CREATE TABLE IF NOT EXISTS product_images (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				product_id INT NOT NULL,
+				image_url VARCHAR(500) NOT NULL,
+				alt_text VARCHAR(255),
+				sort_order INT DEFAULT 0,
+				is_primary BOOLEAN DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+				INDEX idx_product_images_product (product_id),
+				INDEX idx_product_images_primary (is_primary)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Auctions table
+			`CREATE TABLE IF NOT EXISTS auctions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				product_id INT NULL,
+				vendor_id INT NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				description TEXT,
+				starting_price DECIMAL(10,2) NOT NULL,
+				reserve_price DECIMAL(10,2) DEFAULT 0.00,
+				current_bid DECIMAL(10,2) DEFAULT 0.00,
+				bid_increment DECIMAL(10,2) DEFAULT 1.00,
+				total_bids INT DEFAULT 0,
+				start_time TIMESTAMP NOT NULL,
+				end_time TIMESTAMP NOT NULL,
+				status ENUM('draft', 'active', 'ended', 'cancelled') DEFAULT 'draft',
+				winner_id INT NULL,
+				view_count INT DEFAULT 0,
+				is_reserve_met BOOLEAN DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+				FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
+				FOREIGN KEY (winner_id) REFERENCES users(id) ON DELETE SET NULL,
+				INDEX idx_auctions_vendor (vendor_id),
+				INDEX idx_auctions_status (status),
+				INDEX idx_auctions_end_time (end_time),
+				INDEX idx_auctions_product (product_id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Orders table
+			`CREATE TABLE IF NOT EXISTS orders (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				order_number VARCHAR(50) UNIQUE NOT NULL,
+				status VARCHAR(20) DEFAULT 'pending',
+				subtotal DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				tax_amount DECIMAL(10,2) DEFAULT 0.00,
+				shipping_amount DECIMAL(10,2) DEFAULT 0.00,
+				discount_amount DECIMAL(10,2) DEFAULT 0.00,
+				total_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+				currency VARCHAR(3) DEFAULT 'TRY',
+				payment_status VARCHAR(20) DEFAULT 'pending',
+				payment_method VARCHAR(50),
+				shipping_address TEXT,
+				billing_address TEXT,
+				notes TEXT,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+				INDEX idx_orders_user (user_id),
+				INDEX idx_orders_status (status)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Order items table
+			`CREATE TABLE IF NOT EXISTS order_items (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				order_id INT NOT NULL,
+				product_id INT NOT NULL,
+				vendor_id INT NOT NULL,
+				product_name VARCHAR(255) NOT NULL,
+				product_sku VARCHAR(100) NOT NULL,
+				quantity INT NOT NULL DEFAULT 1,
+				unit_price DECIMAL(10,2) NOT NULL,
+				total_price DECIMAL(10,2) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+				FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+				FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
+				INDEX idx_order_items_order (order_id),
+				INDEX idx_order_items_product (product_id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+
+			// Sessions table
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id VARCHAR(255) PRIMARY KEY,
+				user_id INT NULL,
+				data TEXT,
+				expires_at TIMESTAMP NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+				INDEX idx_sessions_user (user_id),
+				INDEX idx_sessions_expires (expires_at)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+		},
+	},
+	Down: Both(
+		`DROP TABLE IF EXISTS sessions`,
+		`DROP TABLE IF EXISTS order_items`,
+		`DROP TABLE IF EXISTS orders`,
+		`DROP TABLE IF EXISTS auctions`,
+		`DROP TABLE IF EXISTS product_images`,
+		`DROP TABLE IF EXISTS products`,
+		`DROP TABLE IF EXISTS vendors`,
+		`DROP TABLE IF EXISTS categories`,
+		`DROP TABLE IF EXISTS users`,
+	),
+}
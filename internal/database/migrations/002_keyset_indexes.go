@@ -0,0 +1,36 @@
+package migrations
+
+// keysetIndexes indexes the (sort key, id) pairs list pages are read by, so
+// a page after a cursor is an index range scan however deep it is
+var keysetIndexes = Migration{
+	Version: 2,
+	Name:    "keyset_indexes",
+	Up: Step{
+		SQLite: []string{
+			`CREATE INDEX IF NOT EXISTS idx_products_created_id ON products(created_at, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_vendor_created_id ON products(vendor_id, created_at, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_created_id ON orders(created_at, id)`,
+		},
+		MySQL: []string{
+			`CREATE INDEX idx_products_created_id ON products(created_at, id)`,
+			`CREATE INDEX idx_products_price_id ON products(price, id)`,
+			`CREATE INDEX idx_products_vendor_created_id ON products(vendor_id, created_at, id)`,
+			`CREATE INDEX idx_orders_created_id ON orders(created_at, id)`,
+		},
+	},
+	Down: Step{
+		SQLite: []string{
+			`DROP INDEX IF EXISTS idx_orders_created_id`,
+			`DROP INDEX IF EXISTS idx_products_vendor_created_id`,
+			`DROP INDEX IF EXISTS idx_products_price_id`,
+			`DROP INDEX IF EXISTS idx_products_created_id`,
+		},
+		MySQL: []string{
+			`DROP INDEX idx_orders_created_id ON orders`,
+			`DROP INDEX idx_products_vendor_created_id ON products`,
+			`DROP INDEX idx_products_price_id ON products`,
+			`DROP INDEX idx_products_created_id ON products`,
+		},
+	},
+}
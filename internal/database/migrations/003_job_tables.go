@@ -0,0 +1,75 @@
+package migrations
+
+// jobTables holds the persistent job queue with its dead letters, the cron
+// schedules and the leader locks of scheduled work
+var jobTables = Migration{
+	Version: 3,
+	Name:    "job_tables",
+	Up: Portable(
+		`CREATE TABLE job_queue (
+			id VARCHAR(64) PRIMARY KEY,
+			job_type VARCHAR(100) NOT NULL,
+			priority INT NOT NULL DEFAULT 1,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			payload TEXT,
+			result TEXT,
+			error TEXT,
+			retry_count INT NOT NULL DEFAULT 0,
+			max_retries INT NOT NULL DEFAULT 3,
+			lease_owner VARCHAR(255),
+			lease_token VARCHAR(64),
+			lease_expires_at DATETIME NULL,
+			available_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			started_at DATETIME NULL,
+			completed_at DATETIME NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_job_queue_claim ON job_queue (status, available_at, priority)`,
+		`CREATE INDEX idx_job_queue_lease ON job_queue (status, lease_expires_at)`,
+
+		`CREATE TABLE job_dead_letters (
+			id VARCHAR(64) PRIMARY KEY,
+			job_type VARCHAR(100) NOT NULL,
+			priority INT NOT NULL DEFAULT 1,
+			payload TEXT,
+			error TEXT,
+			retry_count INT NOT NULL DEFAULT 0,
+			max_retries INT NOT NULL DEFAULT 3,
+			created_at DATETIME NOT NULL,
+			failed_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_job_dead_letters_type ON job_dead_letters (job_type)`,
+
+		`CREATE TABLE job_schedules (
+			id VARCHAR(100) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			cron_expr VARCHAR(100) NOT NULL,
+			timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+			job_type VARCHAR(100) NOT NULL,
+			payload TEXT,
+			priority INT NOT NULL DEFAULT 1,
+			max_retries INT NOT NULL DEFAULT 3,
+			enabled BOOLEAN NOT NULL DEFAULT 1,
+			run_count BIGINT NOT NULL DEFAULT 0,
+			last_run_at DATETIME NULL,
+			next_run_at DATETIME NULL,
+			last_job_id VARCHAR(64),
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE job_leader_locks (
+			name VARCHAR(100) PRIMARY KEY,
+			owner VARCHAR(255) NOT NULL,
+			expires_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS job_leader_locks`,
+		`DROP TABLE IF EXISTS job_schedules`,
+		`DROP TABLE IF EXISTS job_dead_letters`,
+		`DROP TABLE IF EXISTS job_queue`,
+	),
+}
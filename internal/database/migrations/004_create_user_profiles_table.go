@@ -1,17 +0,0 @@
-package migrations
-
-// CreateUserProfilesTable migration
-var CreateUserProfilesTable = `
-CREATE TABLE IF NOT EXISTS user_profiles (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	user_id INTEGER NOT NULL UNIQUE,
-	bio TEXT,
-	avatar VARCHAR(255),
-	company VARCHAR(100),
-	website VARCHAR(255),
-	location VARCHAR(100),
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-`
@@ -0,0 +1,164 @@
+package migrations
+
+// orderWorkflowTables ties orders to vendors, parent orders and sales
+// channels, and adds checkout's stock reservations, the order status
+// history, returns with their shipments and the vendor payouts ledger
+var orderWorkflowTables = Migration{
+	Version: 4,
+	Name:    "order_workflow_tables",
+	Up: Portable(
+		`ALTER TABLE orders ADD COLUMN vendor_id BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN parent_order_id BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN source_channel VARCHAR(50) NOT NULL DEFAULT 'web'`,
+		`CREATE INDEX idx_orders_parent ON orders (parent_order_id)`,
+
+		`CREATE TABLE stock_reservations (
+			id VARCHAR(36) PRIMARY KEY,
+			order_id BIGINT NOT NULL,
+			product_id BIGINT NOT NULL,
+			quantity INT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'reserved',
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_stock_reservations_order ON stock_reservations (order_id)`,
+		`CREATE INDEX idx_stock_reservations_expiry ON stock_reservations (status, expires_at)`,
+
+		`CREATE TABLE order_status_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id BIGINT NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			previous_status VARCHAR(20) NOT NULL,
+			comment TEXT,
+			changed_by BIGINT NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_order_status_history_order ON order_status_history (order_id)`,
+
+		`CREATE TABLE return_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			rma_number VARCHAR(50) NOT NULL UNIQUE,
+			order_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			vendor_id BIGINT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'requested',
+			reason VARCHAR(50) NOT NULL,
+			customer_note TEXT,
+			vendor_note TEXT,
+			refund_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			refund_reference VARCHAR(100),
+			shipment_id BIGINT NOT NULL DEFAULT 0,
+			tracking_number VARCHAR(100),
+			approved_at DATETIME NULL,
+			received_at DATETIME NULL,
+			refunded_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_return_requests_order ON return_requests (order_id)`,
+		`CREATE INDEX idx_return_requests_vendor ON return_requests (vendor_id, status)`,
+		`CREATE INDEX idx_return_requests_user ON return_requests (user_id)`,
+
+		`CREATE TABLE return_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			return_id BIGINT NOT NULL,
+			order_item_id BIGINT NOT NULL,
+			product_id BIGINT NOT NULL,
+			quantity INT NOT NULL,
+			unit_price DECIMAL(15,2) NOT NULL,
+			refund_amount DECIMAL(15,2) NOT NULL,
+			reason VARCHAR(50) NOT NULL,
+			item_condition VARCHAR(20),
+			restocked BOOLEAN NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX idx_return_items_return ON return_items (return_id)`,
+
+		`CREATE TABLE shipments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id BIGINT NOT NULL,
+			customer_id BIGINT NOT NULL,
+			shipping_method_id BIGINT NOT NULL DEFAULT 0,
+			from_address TEXT,
+			to_address TEXT,
+			tracking_number VARCHAR(100),
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			package_count INT NOT NULL DEFAULT 1,
+			shipping_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+			total_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			special_instructions TEXT,
+			shipped_at DATETIME NULL,
+			delivered_at DATETIME NULL,
+			cancelled_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_shipments_order ON shipments (order_id)`,
+
+		`CREATE TABLE vendor_ledger_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id VARCHAR(36) NOT NULL,
+			vendor_id BIGINT NOT NULL,
+			order_id BIGINT NOT NULL DEFAULT 0,
+			payout_id BIGINT NOT NULL DEFAULT 0,
+			account VARCHAR(30) NOT NULL,
+			entry_type VARCHAR(30) NOT NULL,
+			debit DECIMAL(15,2) NOT NULL DEFAULT 0,
+			credit DECIMAL(15,2) NOT NULL DEFAULT 0,
+			description VARCHAR(255),
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_vendor_ledger_vendor ON vendor_ledger_entries (vendor_id, payout_id, created_at)`,
+		`CREATE INDEX idx_vendor_ledger_order ON vendor_ledger_entries (order_id)`,
+		`CREATE INDEX idx_vendor_ledger_transaction ON vendor_ledger_entries (transaction_id)`,
+
+		`CREATE TABLE vendor_payouts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			vendor_id BIGINT NOT NULL,
+			period_start DATETIME NOT NULL,
+			period_end DATETIME NOT NULL,
+			gross_sales DECIMAL(15,2) NOT NULL DEFAULT 0,
+			commission DECIMAL(15,2) NOT NULL DEFAULT 0,
+			refunds DECIMAL(15,2) NOT NULL DEFAULT 0,
+			commission_reversal DECIMAL(15,2) NOT NULL DEFAULT 0,
+			shipping_costs DECIMAL(15,2) NOT NULL DEFAULT 0,
+			net_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			reference VARCHAR(100),
+			paid_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_vendor_payouts_vendor ON vendor_payouts (vendor_id, status)`,
+	),
+	Down: Step{
+		SQLite: []string{
+			`DROP TABLE IF EXISTS vendor_payouts`,
+			`DROP TABLE IF EXISTS vendor_ledger_entries`,
+			`DROP TABLE IF EXISTS shipments`,
+			`DROP TABLE IF EXISTS return_items`,
+			`DROP TABLE IF EXISTS return_requests`,
+			`DROP TABLE IF EXISTS order_status_history`,
+			`DROP TABLE IF EXISTS stock_reservations`,
+			`DROP INDEX IF EXISTS idx_orders_parent`,
+			`ALTER TABLE orders DROP COLUMN source_channel`,
+			`ALTER TABLE orders DROP COLUMN parent_order_id`,
+			`ALTER TABLE orders DROP COLUMN vendor_id`,
+		},
+		MySQL: []string{
+			`DROP TABLE IF EXISTS vendor_payouts`,
+			`DROP TABLE IF EXISTS vendor_ledger_entries`,
+			`DROP TABLE IF EXISTS shipments`,
+			`DROP TABLE IF EXISTS return_items`,
+			`DROP TABLE IF EXISTS return_requests`,
+			`DROP TABLE IF EXISTS order_status_history`,
+			`DROP TABLE IF EXISTS stock_reservations`,
+			`DROP INDEX idx_orders_parent ON orders`,
+			`ALTER TABLE orders DROP COLUMN source_channel`,
+			`ALTER TABLE orders DROP COLUMN parent_order_id`,
+			`ALTER TABLE orders DROP COLUMN vendor_id`,
+		},
+	},
+}
@@ -0,0 +1,122 @@
+package migrations
+
+// wholesaleTables holds wholesale customers with their credit terms, tiered
+// price lists, negotiated quotes and wholesale orders
+var wholesaleTables = Migration{
+	Version: 5,
+	Name:    "wholesale_tables",
+	Up: Portable(
+		`CREATE TABLE wholesale_customers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id BIGINT NOT NULL,
+			company_name VARCHAR(255) NOT NULL,
+			tax_id VARCHAR(50),
+			business_type VARCHAR(100),
+			yearly_volume DECIMAL(15,2) NOT NULL DEFAULT 0,
+			credit_limit DECIMAL(15,2) NOT NULL DEFAULT 0,
+			payment_terms INT NOT NULL DEFAULT 30,
+			discount_tier VARCHAR(20) NOT NULL DEFAULT 'bronze',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			approved_by BIGINT NULL,
+			approved_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_wholesale_customers_user ON wholesale_customers (user_id)`,
+
+		`CREATE TABLE wholesale_prices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id BIGINT NOT NULL,
+			min_qty INT NOT NULL,
+			max_qty INT NULL,
+			price DECIMAL(15,2) NOT NULL DEFAULT 0,
+			discount DECIMAL(5,2) NOT NULL DEFAULT 0,
+			tier VARCHAR(20) NOT NULL DEFAULT 'all',
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_wholesale_prices_product ON wholesale_prices (product_id, is_active)`,
+
+		`CREATE TABLE wholesale_quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id BIGINT NOT NULL,
+			vendor_id BIGINT NOT NULL,
+			quote_number VARCHAR(50) NOT NULL UNIQUE,
+			status VARCHAR(20) NOT NULL DEFAULT 'draft',
+			valid_until DATETIME NOT NULL,
+			sub_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+			discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			total_amount DECIMAL(15,2) NOT NULL,
+			revision INT NOT NULL DEFAULT 0,
+			order_id BIGINT NOT NULL DEFAULT 0,
+			notes TEXT,
+			vendor_notes TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_wholesale_quotes_customer ON wholesale_quotes (customer_id)`,
+		`CREATE INDEX idx_wholesale_quotes_vendor ON wholesale_quotes (vendor_id, status)`,
+
+		`CREATE TABLE wholesale_quote_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			quote_id BIGINT NOT NULL,
+			product_id BIGINT NOT NULL,
+			product_name VARCHAR(255),
+			product_sku VARCHAR(100),
+			quantity INT NOT NULL,
+			list_price DECIMAL(15,2) NOT NULL DEFAULT 0,
+			target_price DECIMAL(15,2) NOT NULL DEFAULT 0,
+			unit_price DECIMAL(15,2) NOT NULL,
+			discount_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+			total_price DECIMAL(15,2) NOT NULL
+		)`,
+		`CREATE INDEX idx_wholesale_quote_items_quote ON wholesale_quote_items (quote_id)`,
+
+		`CREATE TABLE wholesale_orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id BIGINT NOT NULL,
+			vendor_id BIGINT NOT NULL DEFAULT 0,
+			quote_id BIGINT NOT NULL DEFAULT 0,
+			order_number VARCHAR(50) NOT NULL UNIQUE,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			payment_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			payment_terms INT NOT NULL DEFAULT 30,
+			due_date DATETIME NULL,
+			sub_total DECIMAL(15,2) NOT NULL,
+			discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			shipping_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+			total_amount DECIMAL(15,2) NOT NULL,
+			paid_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			notes TEXT,
+			internal_notes TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_wholesale_orders_customer ON wholesale_orders (customer_id, payment_status)`,
+
+		`CREATE TABLE wholesale_order_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id BIGINT NOT NULL,
+			product_id BIGINT NOT NULL,
+			product_name VARCHAR(255),
+			product_sku VARCHAR(100),
+			quantity INT NOT NULL,
+			unit_price DECIMAL(15,2) NOT NULL,
+			discount_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+			total_price DECIMAL(15,2) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending'
+		)`,
+		`CREATE INDEX idx_wholesale_order_items_order ON wholesale_order_items (order_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS wholesale_order_items`,
+		`DROP TABLE IF EXISTS wholesale_orders`,
+		`DROP TABLE IF EXISTS wholesale_quote_items`,
+		`DROP TABLE IF EXISTS wholesale_quotes`,
+		`DROP TABLE IF EXISTS wholesale_prices`,
+		`DROP TABLE IF EXISTS wholesale_customers`,
+	),
+}
@@ -1,123 +0,0 @@
-package migrations
-
-const CreateProductsTable = `
-CREATE TABLE IF NOT EXISTS categories (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name VARCHAR(255) NOT NULL,
-    description TEXT,
-    parent_id INTEGER NULL,
-    image VARCHAR(255),
-    is_active BOOLEAN DEFAULT TRUE,
-    sort_order INTEGER DEFAULT 0,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_category_parent ON categories(parent_id);
-CREATE INDEX IF NOT EXISTS idx_category_active ON categories(is_active);
-
-CREATE TABLE IF NOT EXISTS products (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    vendor_id INTEGER NOT NULL,
-    category_id INTEGER NOT NULL,
-    name VARCHAR(255) NOT NULL,
-    description TEXT,
-    short_desc VARCHAR(500),
-    sku VARCHAR(100) UNIQUE NOT NULL,
-    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
-    compare_price DECIMAL(10,2) DEFAULT 0.00 CHECK (compare_price >= 0),
-    cost_price DECIMAL(10,2) DEFAULT 0.00 CHECK (cost_price >= 0),
-    wholesale_price DECIMAL(10,2) DEFAULT 0.00 CHECK (wholesale_price >= 0),
-    min_wholesale_qty INTEGER DEFAULT 1 CHECK (min_wholesale_qty >= 1),
-    stock INTEGER DEFAULT 0 CHECK (stock >= 0),
-    min_stock INTEGER DEFAULT 0 CHECK (min_stock >= 0),
-    weight DECIMAL(8,2) DEFAULT 0.00 CHECK (weight >= 0),
-    dimensions VARCHAR(100),
-    status VARCHAR(20) DEFAULT 'draft' CHECK (status IN ('draft', 'active', 'inactive', 'out_of_stock')),
-    is_digital BOOLEAN DEFAULT FALSE,
-    is_featured BOOLEAN DEFAULT FALSE,
-    allow_reviews BOOLEAN DEFAULT TRUE,
-    meta_title VARCHAR(255),
-    meta_desc VARCHAR(500),
-    tags TEXT,
-    view_count INTEGER DEFAULT 0 CHECK (view_count >= 0),
-    sales_count INTEGER DEFAULT 0 CHECK (sales_count >= 0),
-    rating DECIMAL(3,2) DEFAULT 0.00 CHECK (rating >= 0 AND rating <= 5),
-    review_count INTEGER DEFAULT 0 CHECK (review_count >= 0),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE CASCADE,
-    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT
-);
-
-CREATE INDEX IF NOT EXISTS idx_product_vendor ON products(vendor_id);
-CREATE INDEX IF NOT EXISTS idx_product_category ON products(category_id);
-CREATE INDEX IF NOT EXISTS idx_product_status ON products(status);
-CREATE INDEX IF NOT EXISTS idx_product_featured ON products(is_featured);
-CREATE INDEX IF NOT EXISTS idx_product_sku ON products(sku);
-CREATE INDEX IF NOT EXISTS idx_product_price ON products(price);
-CREATE INDEX IF NOT EXISTS idx_product_rating ON products(rating);
-CREATE INDEX IF NOT EXISTS idx_product_created_at ON products(created_at);
-CREATE INDEX IF NOT EXISTS idx_product_name ON products(name);
-
-CREATE TABLE IF NOT EXISTS product_images (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    product_id INTEGER NOT NULL,
-    image_url VARCHAR(500) NOT NULL,
-    alt_text VARCHAR(255),
-    sort_order INTEGER DEFAULT 0,
-    is_primary BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_product_image_product ON product_images(product_id);
-CREATE INDEX IF NOT EXISTS idx_product_image_primary ON product_images(is_primary);
-
-CREATE TABLE IF NOT EXISTS product_variants (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    product_id INTEGER NOT NULL,
-    name VARCHAR(100) NOT NULL,
-    value VARCHAR(100) NOT NULL,
-    price DECIMAL(10,2) DEFAULT 0.00,
-    stock INTEGER DEFAULT 0,
-    sku VARCHAR(100),
-    is_active BOOLEAN DEFAULT TRUE,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_product_variant_product ON product_variants(product_id);
-CREATE INDEX IF NOT EXISTS idx_product_variant_active ON product_variants(is_active);
-
-CREATE TABLE IF NOT EXISTS product_attributes (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    product_id INTEGER NOT NULL,
-    name VARCHAR(100) NOT NULL,
-    value VARCHAR(255) NOT NULL,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_product_attr_product ON product_attributes(product_id);
-
-CREATE TABLE IF NOT EXISTS product_reviews (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    product_id INTEGER NOT NULL,
-    user_id INTEGER NOT NULL,
-    order_id INTEGER,
-    rating INTEGER NOT NULL CHECK (rating >= 1 AND rating <= 5),
-    title VARCHAR(255),
-    comment TEXT,
-    images TEXT,
-    is_verified BOOLEAN DEFAULT FALSE,
-    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_product_review_product ON product_reviews(product_id);
-CREATE INDEX IF NOT EXISTS idx_product_review_user ON product_reviews(user_id);
-CREATE INDEX IF NOT EXISTS idx_product_review_status ON product_reviews(status);
-`
@@ -0,0 +1,67 @@
+package migrations
+
+// paymentTables records gateway payments and the reconciliation runs that
+// compare them with the gateways' transaction lists
+var paymentTables = Migration{
+	Version: 6,
+	Name:    "payment_tables",
+	Up: Portable(
+		`CREATE TABLE payments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id VARCHAR(100) NOT NULL,
+			order_id BIGINT NOT NULL DEFAULT 0,
+			customer_id BIGINT NOT NULL DEFAULT 0,
+			provider VARCHAR(30) NOT NULL DEFAULT '',
+			method VARCHAR(30) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			amount DECIMAL(15,2) NOT NULL,
+			refunded_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			error_code VARCHAR(100),
+			error_message VARCHAR(500),
+			payment_url VARCHAR(500),
+			webhook_received BOOLEAN NOT NULL DEFAULT 0,
+			webhook_at DATETIME NULL,
+			completed_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_payments_transaction ON payments (transaction_id)`,
+		`CREATE INDEX idx_payments_order ON payments (order_id)`,
+		`CREATE INDEX idx_payments_provider_created ON payments (provider, created_at)`,
+
+		`CREATE TABLE payment_reconciliations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			gateway VARCHAR(30) NOT NULL,
+			period_start DATETIME NOT NULL,
+			period_end DATETIME NOT NULL,
+			gateway_count INT NOT NULL DEFAULT 0,
+			local_count INT NOT NULL DEFAULT 0,
+			matched_count INT NOT NULL DEFAULT 0,
+			discrepancy_count INT NOT NULL DEFAULT 0,
+			gateway_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+			local_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE payment_reconciliation_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reconciliation_id BIGINT NOT NULL,
+			issue VARCHAR(30) NOT NULL,
+			transaction_id VARCHAR(100) NOT NULL DEFAULT '',
+			order_id BIGINT NOT NULL DEFAULT 0,
+			gateway_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			local_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT '',
+			details VARCHAR(500),
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_payment_reconciliation_items_run ON payment_reconciliation_items (reconciliation_id, issue)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS payment_reconciliation_items`,
+		`DROP TABLE IF EXISTS payment_reconciliations`,
+		`DROP TABLE IF EXISTS payments`,
+	),
+}
@@ -1,114 +0,0 @@
-package migrations
-
-const CreateOrdersTable = `
-CREATE TABLE IF NOT EXISTS orders (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    user_id INTEGER NOT NULL,
-    order_number VARCHAR(50) UNIQUE NOT NULL,
-    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'processing', 'shipped', 'delivered', 'cancelled', 'refunded')),
-    payment_status VARCHAR(20) DEFAULT 'pending' CHECK (payment_status IN ('pending', 'paid', 'failed', 'refunded')),
-    payment_method VARCHAR(50),
-    shipping_method VARCHAR(50),
-    sub_total REAL NOT NULL,
-    tax_amount REAL DEFAULT 0.00,
-    shipping_cost REAL DEFAULT 0.00,
-    discount_amount REAL DEFAULT 0.00,
-    total_amount REAL NOT NULL,
-    currency VARCHAR(3) DEFAULT 'TRY',
-    notes TEXT,
-    tracking_number VARCHAR(100),
-    shipped_at TIMESTAMP NULL,
-    delivered_at TIMESTAMP NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_order_user ON orders(user_id);
-CREATE INDEX IF NOT EXISTS idx_order_status ON orders(status);
-CREATE INDEX IF NOT EXISTS idx_order_payment_status ON orders(payment_status);
-CREATE INDEX IF NOT EXISTS idx_order_number ON orders(order_number);
-
-CREATE TABLE IF NOT EXISTS order_items (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    order_id INTEGER NOT NULL,
-    product_id INTEGER NOT NULL,
-    vendor_id INTEGER NOT NULL,
-    product_name VARCHAR(255) NOT NULL,
-    product_sku VARCHAR(100) NOT NULL,
-    variant_info VARCHAR(255),
-    quantity INTEGER NOT NULL,
-    unit_price DECIMAL(10,2) NOT NULL,
-    total_price DECIMAL(10,2) NOT NULL,
-    commission DECIMAL(10,2) DEFAULT 0.00,
-    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'shipped', 'delivered', 'cancelled')),
-    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT,
-    FOREIGN KEY (vendor_id) REFERENCES vendors(id) ON DELETE RESTRICT
-);
-
-CREATE INDEX IF NOT EXISTS idx_order_item_order ON order_items(order_id);
-CREATE INDEX IF NOT EXISTS idx_order_item_product ON order_items(product_id);
-CREATE INDEX IF NOT EXISTS idx_order_item_vendor ON order_items(vendor_id);
-
-CREATE TABLE IF NOT EXISTS order_addresses (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    order_id INTEGER NOT NULL,
-    type VARCHAR(20) NOT NULL CHECK (type IN ('shipping', 'billing')),
-    first_name VARCHAR(100) NOT NULL,
-    last_name VARCHAR(100) NOT NULL,
-    company VARCHAR(255),
-    address1 VARCHAR(255) NOT NULL,
-    address2 VARCHAR(255),
-    city VARCHAR(100) NOT NULL,
-    state VARCHAR(100),
-    postal_code VARCHAR(20),
-    country VARCHAR(100) NOT NULL,
-    phone VARCHAR(20),
-    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_order_address_order ON order_addresses(order_id);
-
-CREATE TABLE IF NOT EXISTS carts (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    user_id INTEGER,
-    session_id VARCHAR(255),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_cart_user ON carts(user_id);
-CREATE INDEX IF NOT EXISTS idx_cart_session ON carts(session_id);
-
-CREATE TABLE IF NOT EXISTS cart_items (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    cart_id INTEGER NOT NULL,
-    product_id INTEGER NOT NULL,
-    variant_id INTEGER,
-    quantity INTEGER NOT NULL,
-    price DECIMAL(10,2) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (cart_id) REFERENCES carts(id) ON DELETE CASCADE,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
-    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
-);
-
-CREATE INDEX IF NOT EXISTS idx_cart_item_cart ON cart_items(cart_id);
-CREATE INDEX IF NOT EXISTS idx_cart_item_product ON cart_items(product_id);
-
-CREATE TABLE IF NOT EXISTS wishlists (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    user_id INTEGER NOT NULL,
-    product_id INTEGER NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
-    UNIQUE (user_id, product_id)
-);
-
-CREATE INDEX IF NOT EXISTS idx_wishlist_user ON wishlists(user_id);
-CREATE INDEX IF NOT EXISTS idx_wishlist_product ON wishlists(product_id);
-`
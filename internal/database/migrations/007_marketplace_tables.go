@@ -0,0 +1,241 @@
+package migrations
+
+// marketplaceTables holds the marketplace integrations' state: imported
+// orders with their polling cursors, channel stock allocation, price rules,
+// cached category trees with their mappings and catalog sync runs
+var marketplaceTables = Migration{
+	Version: 7,
+	Name:    "marketplace_tables",
+	Up: Portable(
+		`CREATE TABLE marketplace_order_cursors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			last_order_at DATETIME NULL,
+			last_run_at DATETIME NULL,
+			last_error TEXT,
+			imported_count INT NOT NULL DEFAULT 0,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_order_cursors_integration ON marketplace_order_cursors (integration_id)`,
+
+		`CREATE TABLE marketplace_orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			marketplace_order_id VARCHAR(100) NOT NULL,
+			order_number VARCHAR(100) NOT NULL DEFAULT '',
+			order_id BIGINT NOT NULL,
+			marketplace_status VARCHAR(20) NOT NULL,
+			synced_status VARCHAR(20) NOT NULL,
+			last_error TEXT,
+			imported_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_orders_external ON marketplace_orders (integration_id, marketplace_order_id)`,
+		`CREATE INDEX idx_marketplace_orders_order ON marketplace_orders (order_id)`,
+
+		`CREATE TABLE inventory_allocation_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			product_id BIGINT NOT NULL DEFAULT 0,
+			buffer_stock INT NOT NULL DEFAULT 0,
+			percentage DECIMAL(5,2) NOT NULL DEFAULT 100,
+			max_quantity INT NOT NULL DEFAULT 0,
+			priority INT NOT NULL DEFAULT 0,
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_inventory_allocation_rules_scope ON inventory_allocation_rules (integration_id, product_id)`,
+
+		`CREATE TABLE inventory_channel_stock (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			product_id BIGINT NOT NULL,
+			sku VARCHAR(100) NOT NULL,
+			quantity INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			synced_at DATETIME NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_inventory_channel_stock_product ON inventory_channel_stock (integration_id, product_id)`,
+		`CREATE INDEX idx_inventory_channel_stock_updated ON inventory_channel_stock (product_id, updated_at)`,
+
+		`CREATE TABLE inventory_sync_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id BIGINT NOT NULL,
+			reason VARCHAR(30) NOT NULL,
+			version INT NOT NULL DEFAULT 1,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			queued_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_inventory_sync_queue_product ON inventory_sync_queue (product_id)`,
+
+		`CREATE TABLE marketplace_price_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			vendor_id BIGINT NOT NULL DEFAULT 0,
+			category_id BIGINT NOT NULL DEFAULT 0,
+			product_id BIGINT NOT NULL DEFAULT 0,
+			markup_percent DECIMAL(7,2) NOT NULL DEFAULT 0,
+			markup_fixed DECIMAL(10,2) NOT NULL DEFAULT 0,
+			commission_percent DECIMAL(5,2) NOT NULL DEFAULT 0,
+			fixed_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+			min_margin_percent DECIMAL(7,2) NOT NULL DEFAULT 0,
+			rounding VARCHAR(10) NOT NULL DEFAULT '',
+			use_recommended_price BOOLEAN NOT NULL DEFAULT 0,
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_price_rules_scope ON marketplace_price_rules (integration_id, vendor_id, category_id, product_id)`,
+
+		`CREATE TABLE marketplace_price_promotions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL DEFAULT '',
+			product_id BIGINT NOT NULL,
+			price DECIMAL(10,2) NOT NULL DEFAULT 0,
+			discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0,
+			starts_at DATETIME NOT NULL,
+			ends_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_marketplace_price_promotions_product ON marketplace_price_promotions (product_id, ends_at)`,
+
+		`CREATE TABLE marketplace_channel_prices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			product_id BIGINT NOT NULL,
+			sku VARCHAR(100) NOT NULL,
+			price DECIMAL(10,2) NOT NULL DEFAULT 0,
+			list_price DECIMAL(10,2) NOT NULL DEFAULT 0,
+			last_error TEXT,
+			synced_at DATETIME NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_channel_prices_product ON marketplace_channel_prices (integration_id, product_id)`,
+
+		`CREATE TABLE marketplace_category_tree (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			external_id VARCHAR(100) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			parent_id VARCHAR(100) NOT NULL DEFAULT '',
+			path VARCHAR(1000) NOT NULL DEFAULT '',
+			level INT NOT NULL DEFAULT 0,
+			is_leaf BOOLEAN NOT NULL DEFAULT 1,
+			synced_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_category_tree_external ON marketplace_category_tree (integration_id, external_id)`,
+
+		`CREATE TABLE marketplace_category_attributes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			category_id VARCHAR(100) NOT NULL,
+			attribute_id VARCHAR(100) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			required BOOLEAN NOT NULL DEFAULT 0,
+			allow_custom BOOLEAN NOT NULL DEFAULT 0,
+			varianter BOOLEAN NOT NULL DEFAULT 0,
+			attribute_values TEXT,
+			synced_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_marketplace_category_attributes_category ON marketplace_category_attributes (integration_id, category_id)`,
+
+		`CREATE TABLE marketplace_brands (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			external_id VARCHAR(100) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			synced_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_brands_external ON marketplace_brands (integration_id, external_id)`,
+
+		`CREATE TABLE marketplace_categories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			category_id BIGINT NOT NULL,
+			vendor_id BIGINT NOT NULL DEFAULT 0,
+			marketplace_name VARCHAR(100) NOT NULL,
+			external_category_id VARCHAR(255) NOT NULL,
+			external_category_name VARCHAR(500) NOT NULL DEFAULT '',
+			external_path VARCHAR(1000) NOT NULL DEFAULT '',
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			commission_rate DECIMAL(5,4) NOT NULL DEFAULT 0,
+			last_sync_at DATETIME NULL,
+			sync_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			sync_error TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_categories_scope ON marketplace_categories (marketplace_name, vendor_id, category_id)`,
+
+		`CREATE TABLE marketplace_attribute_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			vendor_id BIGINT NOT NULL DEFAULT 0,
+			external_category_id VARCHAR(100) NOT NULL,
+			external_attribute_id VARCHAR(100) NOT NULL,
+			attribute_name VARCHAR(100) NOT NULL DEFAULT '',
+			value VARCHAR(255) NOT NULL DEFAULT '',
+			external_value_id VARCHAR(100) NOT NULL DEFAULT '',
+			default_value VARCHAR(255) NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_attribute_mappings_scope ON marketplace_attribute_mappings
+			(integration_id, vendor_id, external_category_id, external_attribute_id, value)`,
+
+		`CREATE TABLE marketplace_sync_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			trigger_type VARCHAR(20) NOT NULL,
+			retry_of BIGINT NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL,
+			total INT NOT NULL DEFAULT 0,
+			accepted INT NOT NULL DEFAULT 0,
+			rejected INT NOT NULL DEFAULT 0,
+			pending INT NOT NULL DEFAULT 0,
+			failed INT NOT NULL DEFAULT 0,
+			error_message TEXT,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_marketplace_sync_runs_integration ON marketplace_sync_runs (integration_id, started_at)`,
+
+		`CREATE TABLE marketplace_sync_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id BIGINT NOT NULL,
+			integration_id VARCHAR(50) NOT NULL,
+			product_id BIGINT NOT NULL DEFAULT 0,
+			sku VARCHAR(100) NOT NULL DEFAULT '',
+			barcode VARCHAR(100) NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL,
+			message TEXT,
+			batch_id VARCHAR(100) NOT NULL DEFAULT '',
+			listing TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_marketplace_sync_items_run ON marketplace_sync_items (run_id, status)`,
+		`CREATE INDEX idx_marketplace_sync_items_batch ON marketplace_sync_items (status, integration_id, batch_id)`,
+		`CREATE INDEX idx_marketplace_sync_items_product ON marketplace_sync_items (product_id, integration_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS marketplace_sync_items`,
+		`DROP TABLE IF EXISTS marketplace_sync_runs`,
+		`DROP TABLE IF EXISTS marketplace_attribute_mappings`,
+		`DROP TABLE IF EXISTS marketplace_categories`,
+		`DROP TABLE IF EXISTS marketplace_brands`,
+		`DROP TABLE IF EXISTS marketplace_category_attributes`,
+		`DROP TABLE IF EXISTS marketplace_category_tree`,
+		`DROP TABLE IF EXISTS marketplace_channel_prices`,
+		`DROP TABLE IF EXISTS marketplace_price_promotions`,
+		`DROP TABLE IF EXISTS marketplace_price_rules`,
+		`DROP TABLE IF EXISTS inventory_sync_queue`,
+		`DROP TABLE IF EXISTS inventory_channel_stock`,
+		`DROP TABLE IF EXISTS inventory_allocation_rules`,
+		`DROP TABLE IF EXISTS marketplace_orders`,
+		`DROP TABLE IF EXISTS marketplace_order_cursors`,
+	),
+}
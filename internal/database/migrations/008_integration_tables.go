@@ -0,0 +1,36 @@
+package migrations
+
+// integrationTables holds the inbox of received integration webhooks and the
+// encrypted integration credentials
+var integrationTables = Migration{
+	Version: 8,
+	Name:    "integration_tables",
+	Up: Portable(
+		`CREATE TABLE webhook_inbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			event_id VARCHAR(191) NOT NULL,
+			event_type VARCHAR(100) NOT NULL DEFAULT '',
+			payload TEXT NOT NULL,
+			headers TEXT,
+			status VARCHAR(20) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at DATETIME NOT NULL,
+			received_at DATETIME NOT NULL,
+			processed_at DATETIME NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_webhook_inbox_event ON webhook_inbox (integration_id, event_id)`,
+		`CREATE INDEX idx_webhook_inbox_due ON webhook_inbox (status, next_attempt_at)`,
+
+		`CREATE TABLE integration_credentials (
+			integration_id VARCHAR(100) NOT NULL PRIMARY KEY,
+			encrypted_data TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS integration_credentials`,
+		`DROP TABLE IF EXISTS webhook_inbox`,
+	),
+}
@@ -0,0 +1,125 @@
+package migrations
+
+// cacheTables holds the database cache store and the cache statistics.
+// Databases that predate migrations got these tables from the cache manager
+// itself, so they are only created when missing.
+var cacheTables = Migration{
+	Version: 9,
+	Name:    "cache_tables",
+	Up: Step{
+		SQLite: []string{
+			`CREATE TABLE IF NOT EXISTS cache_items (
+				cache_key VARCHAR(512) PRIMARY KEY,
+				store_name VARCHAR(100) NOT NULL,
+				value BLOB,
+				ttl_seconds INT DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				accessed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				access_count BIGINT DEFAULT 0,
+				size_bytes BIGINT DEFAULT 0,
+				compressed BOOLEAN DEFAULT FALSE,
+				encrypted BOOLEAN DEFAULT FALSE,
+				tags TEXT,
+				metadata TEXT,
+				expires_at DATETIME
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_items_store_name ON cache_items (store_name)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_items_expires_at ON cache_items (expires_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_items_accessed_at ON cache_items (accessed_at)`,
+			`CREATE TABLE IF NOT EXISTS cache_stats (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				store_name VARCHAR(100) NOT NULL,
+				operation VARCHAR(20) NOT NULL,
+				hits BIGINT DEFAULT 0,
+				misses BIGINT DEFAULT 0,
+				sets BIGINT DEFAULT 0,
+				deletes BIGINT DEFAULT 0,
+				evictions BIGINT DEFAULT 0,
+				memory_usage BIGINT DEFAULT 0,
+				avg_response_time_ms INT DEFAULT 0,
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_stats_store_name ON cache_stats (store_name)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_stats_timestamp ON cache_stats (timestamp)`,
+			`CREATE TABLE IF NOT EXISTS cache_events (
+				id VARCHAR(128) PRIMARY KEY,
+				operation VARCHAR(20) NOT NULL,
+				store_name VARCHAR(100) NOT NULL,
+				cache_key VARCHAR(512),
+				size_bytes BIGINT DEFAULT 0,
+				ttl_seconds INT DEFAULT 0,
+				success BOOLEAN DEFAULT TRUE,
+				duration_ms INT DEFAULT 0,
+				error_message TEXT,
+				client_ip VARCHAR(45),
+				user_agent TEXT,
+				user_id VARCHAR(128),
+				session_id VARCHAR(128),
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_events_operation ON cache_events (operation)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_events_store_name ON cache_events (store_name)`,
+			`CREATE INDEX IF NOT EXISTS idx_cache_events_timestamp ON cache_events (timestamp)`,
+		},
+		MySQL: []string{
+			`CREATE TABLE IF NOT EXISTS cache_items (
+				cache_key VARCHAR(512) PRIMARY KEY,
+				store_name VARCHAR(100) NOT NULL,
+				value LONGBLOB,
+				ttl_seconds INT DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				accessed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				access_count BIGINT DEFAULT 0,
+				size_bytes BIGINT DEFAULT 0,
+				compressed BOOLEAN DEFAULT FALSE,
+				encrypted BOOLEAN DEFAULT FALSE,
+				tags TEXT,
+				metadata TEXT,
+				expires_at DATETIME,
+				INDEX idx_store_name (store_name),
+				INDEX idx_expires_at (expires_at),
+				INDEX idx_accessed_at (accessed_at)
+			)`,
+			`CREATE TABLE IF NOT EXISTS cache_stats (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				store_name VARCHAR(100) NOT NULL,
+				operation VARCHAR(20) NOT NULL,
+				hits BIGINT DEFAULT 0,
+				misses BIGINT DEFAULT 0,
+				sets BIGINT DEFAULT 0,
+				deletes BIGINT DEFAULT 0,
+				evictions BIGINT DEFAULT 0,
+				memory_usage BIGINT DEFAULT 0,
+				avg_response_time_ms INT DEFAULT 0,
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_store_name (store_name),
+				INDEX idx_timestamp (timestamp)
+			)`,
+			`CREATE TABLE IF NOT EXISTS cache_events (
+				id VARCHAR(128) PRIMARY KEY,
+				operation VARCHAR(20) NOT NULL,
+				store_name VARCHAR(100) NOT NULL,
+				cache_key VARCHAR(512),
+				size_bytes BIGINT DEFAULT 0,
+				ttl_seconds INT DEFAULT 0,
+				success BOOLEAN DEFAULT TRUE,
+				duration_ms INT DEFAULT 0,
+				error_message TEXT,
+				client_ip VARCHAR(45),
+				user_agent TEXT,
+				user_id VARCHAR(128),
+				session_id VARCHAR(128),
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_operation (operation),
+				INDEX idx_store_name (store_name),
+				INDEX idx_timestamp (timestamp),
+				INDEX idx_success (success)
+			)`,
+		},
+	},
+	Down: Both(
+		`DROP TABLE IF EXISTS cache_events`,
+		`DROP TABLE IF EXISTS cache_stats`,
+		`DROP TABLE IF EXISTS cache_items`,
+	),
+}
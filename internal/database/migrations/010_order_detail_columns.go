@@ -0,0 +1,81 @@
+package migrations
+
+// orderDetailColumns adds the address, coupon, payment reference and
+// fulfilment columns of models.Order to orders, and the commission and
+// status of each line to order_items
+var orderDetailColumns = Migration{
+	Version: 10,
+	Name:    "order_detail_columns",
+	Up: Both(
+		`ALTER TABLE orders ADD COLUMN shipping_city VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN shipping_state VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN shipping_zip VARCHAR(20)`,
+		`ALTER TABLE orders ADD COLUMN shipping_country VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN shipping_phone VARCHAR(20)`,
+		`ALTER TABLE orders ADD COLUMN billing_city VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN billing_state VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN billing_zip VARCHAR(20)`,
+		`ALTER TABLE orders ADD COLUMN billing_country VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN billing_phone VARCHAR(20)`,
+		`ALTER TABLE orders ADD COLUMN coupon_code VARCHAR(50)`,
+		`ALTER TABLE orders ADD COLUMN internal_notes TEXT`,
+		`ALTER TABLE orders ADD COLUMN reference_id VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN tracking_number VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN carrier_name VARCHAR(100)`,
+		`ALTER TABLE orders ADD COLUMN shipped_at DATETIME NULL`,
+		`ALTER TABLE orders ADD COLUMN delivered_at DATETIME NULL`,
+		`ALTER TABLE orders ADD COLUMN cancelled_at DATETIME NULL`,
+		`CREATE INDEX idx_orders_reference ON orders (reference_id)`,
+
+		`ALTER TABLE order_items ADD COLUMN commission DECIMAL(10,2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE order_items ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'pending'`,
+	),
+	Down: Step{
+		SQLite: []string{
+			`ALTER TABLE order_items DROP COLUMN status`,
+			`ALTER TABLE order_items DROP COLUMN commission`,
+			`DROP INDEX IF EXISTS idx_orders_reference`,
+			`ALTER TABLE orders DROP COLUMN cancelled_at`,
+			`ALTER TABLE orders DROP COLUMN delivered_at`,
+			`ALTER TABLE orders DROP COLUMN shipped_at`,
+			`ALTER TABLE orders DROP COLUMN carrier_name`,
+			`ALTER TABLE orders DROP COLUMN tracking_number`,
+			`ALTER TABLE orders DROP COLUMN reference_id`,
+			`ALTER TABLE orders DROP COLUMN internal_notes`,
+			`ALTER TABLE orders DROP COLUMN coupon_code`,
+			`ALTER TABLE orders DROP COLUMN billing_phone`,
+			`ALTER TABLE orders DROP COLUMN billing_country`,
+			`ALTER TABLE orders DROP COLUMN billing_zip`,
+			`ALTER TABLE orders DROP COLUMN billing_state`,
+			`ALTER TABLE orders DROP COLUMN billing_city`,
+			`ALTER TABLE orders DROP COLUMN shipping_phone`,
+			`ALTER TABLE orders DROP COLUMN shipping_country`,
+			`ALTER TABLE orders DROP COLUMN shipping_zip`,
+			`ALTER TABLE orders DROP COLUMN shipping_state`,
+			`ALTER TABLE orders DROP COLUMN shipping_city`,
+		},
+		MySQL: []string{
+			`ALTER TABLE order_items DROP COLUMN status`,
+			`ALTER TABLE order_items DROP COLUMN commission`,
+			`DROP INDEX idx_orders_reference ON orders`,
+			`ALTER TABLE orders DROP COLUMN cancelled_at`,
+			`ALTER TABLE orders DROP COLUMN delivered_at`,
+			`ALTER TABLE orders DROP COLUMN shipped_at`,
+			`ALTER TABLE orders DROP COLUMN carrier_name`,
+			`ALTER TABLE orders DROP COLUMN tracking_number`,
+			`ALTER TABLE orders DROP COLUMN reference_id`,
+			`ALTER TABLE orders DROP COLUMN internal_notes`,
+			`ALTER TABLE orders DROP COLUMN coupon_code`,
+			`ALTER TABLE orders DROP COLUMN billing_phone`,
+			`ALTER TABLE orders DROP COLUMN billing_country`,
+			`ALTER TABLE orders DROP COLUMN billing_zip`,
+			`ALTER TABLE orders DROP COLUMN billing_state`,
+			`ALTER TABLE orders DROP COLUMN billing_city`,
+			`ALTER TABLE orders DROP COLUMN shipping_phone`,
+			`ALTER TABLE orders DROP COLUMN shipping_country`,
+			`ALTER TABLE orders DROP COLUMN shipping_zip`,
+			`ALTER TABLE orders DROP COLUMN shipping_state`,
+			`ALTER TABLE orders DROP COLUMN shipping_city`,
+		},
+	},
+}
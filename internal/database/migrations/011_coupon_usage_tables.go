@@ -0,0 +1,36 @@
+package migrations
+
+// couponUsageTables records which orders redeemed a coupon, with running
+// totals per coupon and per customer that checkout enforces the usage
+// limits against. The totals of a coupon are kept under customer_id 0.
+var couponUsageTables = Migration{
+	Version: 11,
+	Name:    "coupon_usage_tables",
+	Up: Portable(
+		`CREATE TABLE coupon_usage_counts (
+			coupon_code VARCHAR(50) NOT NULL,
+			customer_id BIGINT NOT NULL DEFAULT 0,
+			used_count INT NOT NULL DEFAULT 0,
+			PRIMARY KEY (coupon_code, customer_id)
+		)`,
+
+		`CREATE TABLE coupon_usages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			coupon_code VARCHAR(50) NOT NULL,
+			customer_id BIGINT NOT NULL,
+			order_id BIGINT NOT NULL,
+			discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			order_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			status VARCHAR(20) NOT NULL DEFAULT 'used',
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_coupon_usages_order ON coupon_usages (order_id)`,
+		`CREATE INDEX idx_coupon_usages_coupon ON coupon_usages (coupon_code, customer_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS coupon_usages`,
+		`DROP TABLE IF EXISTS coupon_usage_counts`,
+	),
+}
@@ -0,0 +1,34 @@
+package migrations
+
+// orderRefunds records how many units each order line took from stock
+// outside of a reservation, and holds the payment refunds of cancelled and
+// refunded orders until the gateway has accepted them
+var orderRefunds = Migration{
+	Version: 12,
+	Name:    "order_refunds",
+	Up: Portable(
+		`ALTER TABLE order_items ADD COLUMN stock_taken INT NOT NULL DEFAULT 0`,
+
+		`CREATE TABLE order_refunds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id BIGINT NOT NULL,
+			transaction_id VARCHAR(100) NOT NULL,
+			amount DECIMAL(10,2) NOT NULL,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			reason TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			completed_at DATETIME NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_order_refunds_order ON order_refunds (order_id)`,
+		`CREATE INDEX idx_order_refunds_due ON order_refunds (status, next_attempt_at)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS order_refunds`,
+		`ALTER TABLE order_items DROP COLUMN stock_taken`,
+	),
+}
@@ -0,0 +1,14 @@
+package migrations
+
+// vendorTotalSales adds the running sales figure the vendor ledger keeps
+// in step with its sale and refund postings
+var vendorTotalSales = Migration{
+	Version: 13,
+	Name:    "vendor_total_sales",
+	Up: Both(
+		`ALTER TABLE vendors ADD COLUMN total_sales DECIMAL(15,2) NOT NULL DEFAULT 0`,
+	),
+	Down: Both(
+		`ALTER TABLE vendors DROP COLUMN total_sales`,
+	),
+}
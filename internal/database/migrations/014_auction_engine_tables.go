@@ -0,0 +1,34 @@
+package migrations
+
+// auctionEngineTables adds the buy-it-now and soft close columns the
+// auction engine reads, and the bid history it writes
+var auctionEngineTables = Migration{
+	Version: 14,
+	Name:    "auction_engine_tables",
+	Up: Portable(
+		`ALTER TABLE auctions ADD COLUMN buy_now_price DECIMAL(10,2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE auctions ADD COLUMN auto_extend BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE auctions ADD COLUMN extend_minutes INT NOT NULL DEFAULT 0`,
+
+		`CREATE TABLE auction_bids (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			auction_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			amount DECIMAL(10,2) NOT NULL,
+			max_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+			is_winning BOOLEAN NOT NULL DEFAULT FALSE,
+			is_proxy BOOLEAN NOT NULL DEFAULT FALSE,
+			ip_address VARCHAR(45),
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_auction_bids_auction ON auction_bids (auction_id, is_winning)`,
+		`CREATE INDEX idx_auction_bids_amount_id ON auction_bids (auction_id, amount, id)`,
+		`CREATE INDEX idx_auction_bids_user ON auction_bids (user_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS auction_bids`,
+		`ALTER TABLE auctions DROP COLUMN extend_minutes`,
+		`ALTER TABLE auctions DROP COLUMN auto_extend`,
+		`ALTER TABLE auctions DROP COLUMN buy_now_price`,
+	),
+}
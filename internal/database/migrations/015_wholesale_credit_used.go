@@ -0,0 +1,19 @@
+package migrations
+
+// wholesaleCreditUsed keeps the amount each wholesale customer owes on its
+// unpaid orders next to its credit limit, so an order can be checked and
+// booked against the limit with one conditional update
+var wholesaleCreditUsed = Migration{
+	Version: 15,
+	Name:    "wholesale_credit_used",
+	Up: Both(
+		`ALTER TABLE wholesale_customers ADD COLUMN credit_used DECIMAL(15,2) NOT NULL DEFAULT 0`,
+		`UPDATE wholesale_customers SET credit_used = (
+			SELECT COALESCE(SUM(o.total_amount - o.paid_amount), 0) FROM wholesale_orders o
+			WHERE o.customer_id = wholesale_customers.id AND o.status <> 'cancelled' AND o.payment_status <> 'paid'
+		)`,
+	),
+	Down: Both(
+		`ALTER TABLE wholesale_customers DROP COLUMN credit_used`,
+	),
+}
@@ -0,0 +1,81 @@
+package migrations
+
+// reportTables holds the report configurations, their execution log and
+// the user behavior cache of the report manager. Databases that predate
+// migrations got these tables from the report manager itself, so they are
+// only created when missing.
+var reportTables = Migration{
+	Version: 16,
+	Name:    "report_tables",
+	Up: Step{
+		SQLite: []string{
+			`CREATE TABLE IF NOT EXISTS report_configs (
+				id VARCHAR(128) PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				category VARCHAR(100),
+				config_json TEXT NOT NULL,
+				created_by BIGINT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_report_configs_category ON report_configs (category)`,
+			`CREATE INDEX IF NOT EXISTS idx_report_configs_created_by ON report_configs (created_by)`,
+			`CREATE TABLE IF NOT EXISTS report_executions (
+				id VARCHAR(128) PRIMARY KEY,
+				report_id VARCHAR(128),
+				executed_by BIGINT,
+				execution_time_ms INT,
+				row_count INT,
+				filters_json TEXT,
+				executed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_report_executions_report_id ON report_executions (report_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_report_executions_executed_by ON report_executions (executed_by)`,
+			`CREATE INDEX IF NOT EXISTS idx_report_executions_executed_at ON report_executions (executed_at)`,
+			`CREATE TABLE IF NOT EXISTS user_behavior_cache (
+				user_id BIGINT PRIMARY KEY,
+				behavior_data TEXT NOT NULL,
+				last_updated DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_user_behavior_cache_last_updated ON user_behavior_cache (last_updated)`,
+		},
+		MySQL: []string{
+			`CREATE TABLE IF NOT EXISTS report_configs (
+				id VARCHAR(128) PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				category VARCHAR(100),
+				config_json TEXT NOT NULL,
+				created_by BIGINT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_category (category),
+				INDEX idx_created_by (created_by)
+			)`,
+			`CREATE TABLE IF NOT EXISTS report_executions (
+				id VARCHAR(128) PRIMARY KEY,
+				report_id VARCHAR(128),
+				executed_by BIGINT,
+				execution_time_ms INT,
+				row_count INT,
+				filters_json TEXT,
+				executed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_report_id (report_id),
+				INDEX idx_executed_by (executed_by),
+				INDEX idx_executed_at (executed_at)
+			)`,
+			`CREATE TABLE IF NOT EXISTS user_behavior_cache (
+				user_id BIGINT PRIMARY KEY,
+				behavior_data TEXT NOT NULL,
+				last_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_last_updated (last_updated)
+			)`,
+		},
+	},
+	Down: Both(
+		`DROP TABLE IF EXISTS user_behavior_cache`,
+		`DROP TABLE IF EXISTS report_executions`,
+		`DROP TABLE IF EXISTS report_configs`,
+	),
+}
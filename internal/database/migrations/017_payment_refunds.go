@@ -0,0 +1,24 @@
+package migrations
+
+// paymentRefunds records every refund a gateway accepted for a payment
+// with the time it was made, so reconciliation can compare the refunds of
+// a period instead of the payment's lifetime refunded amount
+var paymentRefunds = Migration{
+	Version: 17,
+	Name:    "payment_refunds",
+	Up: Portable(
+		`CREATE TABLE payment_refunds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id VARCHAR(100) NOT NULL,
+			refund_id VARCHAR(100) NOT NULL,
+			amount DECIMAL(15,2) NOT NULL,
+			currency VARCHAR(3) NOT NULL DEFAULT 'TRY',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_payment_refunds_transaction ON payment_refunds (transaction_id)`,
+		`CREATE INDEX idx_payment_refunds_created ON payment_refunds (created_at)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS payment_refunds`,
+	),
+}
@@ -0,0 +1,44 @@
+package migrations
+
+// notificationTables holds the notifications sent to users through each
+// channel and the channels users have chosen per notification type
+var notificationTables = Migration{
+	Version: 18,
+	Name:    "notification_tables",
+	Up: Portable(
+		`CREATE TABLE notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipient_id BIGINT NOT NULL,
+			recipient_type VARCHAR(20) NOT NULL DEFAULT 'user',
+			type VARCHAR(30) NOT NULL,
+			channel VARCHAR(20) NOT NULL,
+			title VARCHAR(200) NOT NULL DEFAULT '',
+			message TEXT,
+			priority VARCHAR(20) NOT NULL DEFAULT 'normal',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			error TEXT,
+			scheduled_at DATETIME NULL,
+			sent_at DATETIME NULL,
+			delivered_at DATETIME NULL,
+			read_at DATETIME NULL,
+			expires_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_notifications_recipient ON notifications (recipient_id, created_at)`,
+		`CREATE INDEX idx_notifications_scheduled ON notifications (status, scheduled_at)`,
+
+		`CREATE TABLE user_notification_preferences (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id BIGINT NOT NULL,
+			notification_type VARCHAR(30) NOT NULL,
+			channel VARCHAR(20) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT 1
+		)`,
+		`CREATE UNIQUE INDEX idx_user_notification_preferences ON user_notification_preferences (user_id, notification_type, channel)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS user_notification_preferences`,
+		`DROP TABLE IF EXISTS notifications`,
+	),
+}
@@ -0,0 +1,46 @@
+package migrations
+
+// userProfilesAndEmailLogs restores the user profile table and the log of
+// sent emails, which the email service pages through newest first
+var userProfilesAndEmailLogs = Migration{
+	Version: 19,
+	Name:    "user_profiles_and_email_logs",
+	Up: Portable(
+		`CREATE TABLE user_profiles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL UNIQUE,
+			bio TEXT,
+			avatar VARCHAR(255),
+			company VARCHAR(100),
+			website VARCHAR(255),
+			location VARCHAR(100),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE email_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id VARCHAR(255) NOT NULL,
+			to_email VARCHAR(255) NOT NULL,
+			from_email VARCHAR(255) NOT NULL,
+			subject VARCHAR(500) NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL,
+			provider VARCHAR(50) NOT NULL DEFAULT '',
+			template_id VARCHAR(100) NOT NULL DEFAULT '',
+			variables TEXT,
+			error TEXT,
+			sent_at DATETIME NOT NULL,
+			delivered_at DATETIME NULL,
+			opened_at DATETIME NULL,
+			clicked_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX idx_email_logs_message ON email_logs (message_id)`,
+		`CREATE INDEX idx_email_logs_created_id ON email_logs (created_at, id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS email_logs`,
+		`DROP TABLE IF EXISTS user_profiles`,
+	),
+}
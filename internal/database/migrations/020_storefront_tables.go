@@ -0,0 +1,157 @@
+package migrations
+
+// storefrontTables restores the cart, review, product detail and auction
+// side tables of the schema that predates versioned migrations, which the
+// core schema left out
+var storefrontTables = Migration{
+	Version: 20,
+	Name:    "storefront_tables",
+	Up: Portable(
+		`CREATE TABLE vendor_documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			vendor_id INTEGER NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			file_path VARCHAR(500) NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			uploaded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_vendor_doc_vendor ON vendor_documents (vendor_id)`,
+		`CREATE INDEX idx_vendor_doc_status ON vendor_documents (status)`,
+
+		`CREATE TABLE product_variants (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			value VARCHAR(100) NOT NULL,
+			price DECIMAL(10,2) DEFAULT 0.00,
+			stock INTEGER DEFAULT 0,
+			sku VARCHAR(100),
+			is_active BOOLEAN DEFAULT 1
+		)`,
+		`CREATE INDEX idx_product_variant_product ON product_variants (product_id)`,
+		`CREATE INDEX idx_product_variant_active ON product_variants (is_active)`,
+
+		`CREATE TABLE product_attributes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			value VARCHAR(255) NOT NULL
+		)`,
+		`CREATE INDEX idx_product_attr_product ON product_attributes (product_id)`,
+
+		`CREATE TABLE product_reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			product_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			order_id INTEGER,
+			rating INTEGER NOT NULL,
+			title VARCHAR(255),
+			comment TEXT,
+			images TEXT,
+			is_verified BOOLEAN DEFAULT 0,
+			status VARCHAR(20) DEFAULT 'pending',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_product_review_product ON product_reviews (product_id)`,
+		`CREATE INDEX idx_product_review_user ON product_reviews (user_id)`,
+		`CREATE INDEX idx_product_review_status ON product_reviews (status)`,
+
+		`CREATE TABLE order_addresses (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			order_id INTEGER NOT NULL,
+			type VARCHAR(20) NOT NULL,
+			first_name VARCHAR(100) NOT NULL,
+			last_name VARCHAR(100) NOT NULL,
+			company VARCHAR(255),
+			address1 VARCHAR(255) NOT NULL,
+			address2 VARCHAR(255),
+			city VARCHAR(100) NOT NULL,
+			state VARCHAR(100),
+			postal_code VARCHAR(20),
+			country VARCHAR(100) NOT NULL,
+			phone VARCHAR(20)
+		)`,
+		`CREATE INDEX idx_order_address_order ON order_addresses (order_id)`,
+
+		`CREATE TABLE carts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			session_id VARCHAR(255),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_cart_user ON carts (user_id)`,
+		`CREATE INDEX idx_cart_session ON carts (session_id)`,
+
+		`CREATE TABLE cart_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cart_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			variant_id INTEGER,
+			quantity INTEGER NOT NULL,
+			price DECIMAL(10,2) NOT NULL,
+			total DECIMAL(10,2) NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_cart_item_cart ON cart_items (cart_id)`,
+		`CREATE INDEX idx_cart_item_product ON cart_items (product_id)`,
+
+		`CREATE TABLE wishlists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, product_id)
+		)`,
+		`CREATE INDEX idx_wishlist_product ON wishlists (product_id)`,
+
+		`CREATE TABLE auction_watchers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			auction_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (auction_id, user_id)
+		)`,
+		`CREATE INDEX idx_auction_watcher_user ON auction_watchers (user_id)`,
+
+		`CREATE TABLE auction_images (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			auction_id INTEGER NOT NULL,
+			image_url VARCHAR(500) NOT NULL,
+			alt_text VARCHAR(255),
+			sort_order INTEGER DEFAULT 0,
+			is_primary BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_auction_image_auction ON auction_images (auction_id)`,
+
+		`CREATE TABLE auction_questions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			auction_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			question TEXT NOT NULL,
+			answer TEXT,
+			is_public BOOLEAN DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			answered_at DATETIME NULL
+		)`,
+		`CREATE INDEX idx_auction_question_auction ON auction_questions (auction_id)`,
+		`CREATE INDEX idx_auction_question_user ON auction_questions (user_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS auction_questions`,
+		`DROP TABLE IF EXISTS auction_images`,
+		`DROP TABLE IF EXISTS auction_watchers`,
+		`DROP TABLE IF EXISTS wishlists`,
+		`DROP TABLE IF EXISTS cart_items`,
+		`DROP TABLE IF EXISTS carts`,
+		`DROP TABLE IF EXISTS order_addresses`,
+		`DROP TABLE IF EXISTS product_reviews`,
+		`DROP TABLE IF EXISTS product_attributes`,
+		`DROP TABLE IF EXISTS product_variants`,
+		`DROP TABLE IF EXISTS vendor_documents`,
+	),
+}
@@ -0,0 +1,134 @@
+package migrations
+
+// aiVisionTables holds the image analyses of the AI vision service, the
+// categories, tags and collections users file their images under, and the
+// processing logs, model metrics and prediction feedback kept alongside
+var aiVisionTables = Migration{
+	Version: 21,
+	Name:    "ai_vision_tables",
+	Up: Portable(
+		`CREATE TABLE ai_image_analysis (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			image_id VARCHAR(255) NOT NULL UNIQUE,
+			user_id INTEGER NOT NULL,
+			original_filename VARCHAR(500) NOT NULL,
+			stored_filename VARCHAR(500) NOT NULL,
+			file_size INTEGER NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			format VARCHAR(50) NOT NULL,
+			hash VARCHAR(64) NOT NULL,
+			detected_objects TEXT,
+			category_predictions TEXT,
+			color_analysis TEXT,
+			quality_score REAL NOT NULL DEFAULT 0,
+			tags TEXT,
+			metadata TEXT,
+			processing_time_ms INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_image_user_hash ON ai_image_analysis (user_id, hash)`,
+		`CREATE INDEX idx_ai_image_created ON ai_image_analysis (created_at)`,
+		`CREATE INDEX idx_ai_image_quality ON ai_image_analysis (quality_score)`,
+		`CREATE INDEX idx_ai_image_format ON ai_image_analysis (format)`,
+
+		`CREATE TABLE user_image_categories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			image_id VARCHAR(255) NOT NULL,
+			category_id INTEGER NOT NULL,
+			confidence REAL NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, image_id, category_id)
+		)`,
+		`CREATE INDEX idx_user_image_cat_image ON user_image_categories (image_id)`,
+		`CREATE INDEX idx_user_image_cat_category ON user_image_categories (category_id)`,
+
+		`CREATE TABLE user_image_tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			image_id VARCHAR(255) NOT NULL,
+			tag VARCHAR(100) NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, image_id, tag)
+		)`,
+		`CREATE INDEX idx_user_image_tag_image ON user_image_tags (image_id)`,
+		`CREATE INDEX idx_user_image_tag_tag ON user_image_tags (tag)`,
+
+		`CREATE TABLE user_image_collections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			collection_id VARCHAR(255) NOT NULL UNIQUE,
+			name VARCHAR(200) NOT NULL,
+			description TEXT,
+			image_ids TEXT,
+			is_public BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_user_collections_user ON user_image_collections (user_id)`,
+		`CREATE INDEX idx_user_collections_public ON user_image_collections (is_public)`,
+
+		`CREATE TABLE ai_processing_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			image_id VARCHAR(255),
+			operation VARCHAR(100) NOT NULL,
+			status VARCHAR(50) NOT NULL,
+			processing_time_ms INTEGER,
+			error_message TEXT,
+			metadata TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_logs_user ON ai_processing_logs (user_id)`,
+		`CREATE INDEX idx_ai_logs_operation ON ai_processing_logs (operation, status)`,
+		`CREATE INDEX idx_ai_logs_created ON ai_processing_logs (created_at)`,
+
+		`CREATE TABLE ai_model_metrics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model_type VARCHAR(100) NOT NULL,
+			model_version VARCHAR(50) NOT NULL,
+			accuracy_score REAL,
+			precision_score REAL,
+			recall_score REAL,
+			f1_score REAL,
+			total_predictions INTEGER DEFAULT 0,
+			correct_predictions INTEGER DEFAULT 0,
+			last_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+			metadata TEXT,
+			UNIQUE (model_type, model_version)
+		)`,
+		`INSERT INTO ai_model_metrics (model_type, model_version, accuracy_score, precision_score, recall_score, f1_score, metadata) VALUES
+			('object_detection', 'v1.0', 0.75, 0.72, 0.78, 0.75, '{"algorithm": "heuristic_based", "training_data": "internal"}'),
+			('category_prediction', 'v1.0', 0.68, 0.65, 0.71, 0.68, '{"algorithm": "rule_based", "categories": "marketplace_specific"}'),
+			('color_analysis', 'v1.0', 0.85, 0.83, 0.87, 0.85, '{"algorithm": "rgb_clustering", "color_space": "rgb"}'),
+			('quality_assessment', 'v1.0', 0.72, 0.70, 0.74, 0.72, '{"algorithm": "composite_scoring", "factors": ["resolution", "contrast", "brightness", "saturation"]}')`,
+
+		`CREATE TABLE ai_prediction_feedback (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			image_id VARCHAR(255) NOT NULL,
+			prediction_type VARCHAR(100) NOT NULL,
+			predicted_value VARCHAR(200) NOT NULL,
+			actual_value VARCHAR(200),
+			is_correct BOOLEAN,
+			confidence_score REAL,
+			user_rating INTEGER,
+			feedback_text TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_feedback_user ON ai_prediction_feedback (user_id)`,
+		`CREATE INDEX idx_ai_feedback_image ON ai_prediction_feedback (image_id)`,
+		`CREATE INDEX idx_ai_feedback_type ON ai_prediction_feedback (prediction_type, is_correct)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS ai_prediction_feedback`,
+		`DROP TABLE IF EXISTS ai_model_metrics`,
+		`DROP TABLE IF EXISTS ai_processing_logs`,
+		`DROP TABLE IF EXISTS user_image_collections`,
+		`DROP TABLE IF EXISTS user_image_tags`,
+		`DROP TABLE IF EXISTS user_image_categories`,
+		`DROP TABLE IF EXISTS ai_image_analysis`,
+	),
+}
@@ -0,0 +1,150 @@
+package migrations
+
+// enterpriseAITables holds the customer service requests the enterprise AI
+// service triages, its moderation results, business insights and automated
+// tasks, and the configuration, metrics and training data of the AI services
+var enterpriseAITables = Migration{
+	Version: 22,
+	Name:    "enterprise_ai_tables",
+	Up: Portable(
+		`CREATE TABLE customer_service_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			subject VARCHAR(500) NOT NULL,
+			description TEXT NOT NULL,
+			priority VARCHAR(20) NOT NULL DEFAULT 'medium',
+			status VARCHAR(20) NOT NULL DEFAULT 'open',
+			category VARCHAR(50),
+			tags TEXT,
+			attachments TEXT,
+			ai_analysis TEXT,
+			assigned_to INTEGER,
+			resolution TEXT,
+			satisfaction_score REAL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME NULL
+		)`,
+		`CREATE INDEX idx_customer_service_user ON customer_service_requests (user_id)`,
+		`CREATE INDEX idx_customer_service_status ON customer_service_requests (status, priority)`,
+		`CREATE INDEX idx_customer_service_category ON customer_service_requests (category)`,
+		`CREATE INDEX idx_customer_service_assigned ON customer_service_requests (assigned_to)`,
+		`CREATE INDEX idx_customer_service_created ON customer_service_requests (created_at)`,
+
+		`CREATE TABLE content_moderation_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			content_id VARCHAR(255) NOT NULL,
+			content_type VARCHAR(50) NOT NULL,
+			user_id INTEGER,
+			is_appropriate BOOLEAN NOT NULL DEFAULT 1,
+			confidence_score REAL NOT NULL DEFAULT 0,
+			violations TEXT,
+			recommendations TEXT,
+			action_required VARCHAR(20) NOT NULL DEFAULT 'none',
+			action_taken VARCHAR(20) DEFAULT 'none',
+			reviewed_by INTEGER,
+			processed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			reviewed_at DATETIME NULL
+		)`,
+		`CREATE INDEX idx_content_moderation_content ON content_moderation_results (content_id, content_type)`,
+		`CREATE INDEX idx_content_moderation_user ON content_moderation_results (user_id)`,
+		`CREATE INDEX idx_content_moderation_action ON content_moderation_results (action_required)`,
+		`CREATE INDEX idx_content_moderation_processed ON content_moderation_results (processed_at)`,
+
+		`CREATE TABLE business_insights (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type VARCHAR(50) NOT NULL,
+			title VARCHAR(500) NOT NULL,
+			description TEXT NOT NULL,
+			impact VARCHAR(20) NOT NULL,
+			confidence REAL NOT NULL DEFAULT 0,
+			data TEXT,
+			action_items TEXT,
+			category VARCHAR(50) NOT NULL,
+			status VARCHAR(20) DEFAULT 'active',
+			acknowledged_by INTEGER,
+			acknowledged_at DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NULL
+		)`,
+		`CREATE INDEX idx_business_insights_type ON business_insights (type, impact)`,
+		`CREATE INDEX idx_business_insights_category ON business_insights (category)`,
+		`CREATE INDEX idx_business_insights_status ON business_insights (status, expires_at)`,
+		`CREATE INDEX idx_business_insights_created ON business_insights (created_at)`,
+
+		`CREATE TABLE automated_tasks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type VARCHAR(100) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			progress REAL DEFAULT 0,
+			results TEXT,
+			error_message TEXT,
+			scheduled_at DATETIME NOT NULL,
+			started_at DATETIME NULL,
+			completed_at DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_automated_tasks_type ON automated_tasks (type)`,
+		`CREATE INDEX idx_automated_tasks_status ON automated_tasks (status, scheduled_at)`,
+
+		`CREATE TABLE ai_performance_metrics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			service_type VARCHAR(50) NOT NULL,
+			operation VARCHAR(100) NOT NULL,
+			processing_time_ms INTEGER NOT NULL,
+			accuracy_score REAL,
+			confidence_score REAL,
+			success BOOLEAN NOT NULL DEFAULT 1,
+			error_type VARCHAR(100),
+			user_id INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_performance_service ON ai_performance_metrics (service_type, operation)`,
+		`CREATE INDEX idx_ai_performance_user ON ai_performance_metrics (user_id)`,
+		`CREATE INDEX idx_ai_performance_created ON ai_performance_metrics (created_at)`,
+
+		`CREATE TABLE ai_configuration (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			service_name VARCHAR(100) NOT NULL UNIQUE,
+			configuration TEXT NOT NULL,
+			is_enabled BOOLEAN DEFAULT 1,
+			version VARCHAR(20) DEFAULT '1.0',
+			last_updated DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_by INTEGER
+		)`,
+		`INSERT INTO ai_configuration (service_name, configuration, is_enabled, version) VALUES
+			('vision_service', '{"max_file_size": 10485760, "allowed_types": ["image/jpeg", "image/png", "image/gif", "image/webp"], "quality_threshold": 0.5}', 1, '1.0'),
+			('enterprise_service', '{"sentiment_threshold": 0.5, "urgency_threshold": 0.7, "auto_assignment": true, "moderation_enabled": true}', 1, '1.0'),
+			('content_moderation', '{"spam_threshold": 0.8, "inappropriate_threshold": 0.9, "offensive_threshold": 0.85, "auto_action": false}', 1, '1.0'),
+			('business_insights', '{"update_frequency": "daily", "confidence_threshold": 0.7, "max_insights": 50, "retention_days": 30}', 1, '1.0')`,
+
+		`CREATE TABLE ai_training_data (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			data_type VARCHAR(50) NOT NULL,
+			input_data TEXT NOT NULL,
+			expected_output TEXT NOT NULL,
+			actual_output TEXT,
+			confidence_score REAL,
+			is_correct BOOLEAN,
+			user_feedback TEXT,
+			source VARCHAR(50) DEFAULT 'user_feedback',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			reviewed_at DATETIME NULL,
+			reviewed_by INTEGER
+		)`,
+		`CREATE INDEX idx_ai_training_type ON ai_training_data (data_type, is_correct)`,
+		`CREATE INDEX idx_ai_training_source ON ai_training_data (source)`,
+		`CREATE INDEX idx_ai_training_created ON ai_training_data (created_at)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS ai_training_data`,
+		`DROP TABLE IF EXISTS ai_configuration`,
+		`DROP TABLE IF EXISTS ai_performance_metrics`,
+		`DROP TABLE IF EXISTS automated_tasks`,
+		`DROP TABLE IF EXISTS business_insights`,
+		`DROP TABLE IF EXISTS content_moderation_results`,
+		`DROP TABLE IF EXISTS customer_service_requests`,
+	),
+}
@@ -0,0 +1,104 @@
+package migrations
+
+// aiCreditTables holds the AI credit balances of users and what they spent
+// them on, the content and templates they generated, and the marketplace
+// integration settings and sync logs of the AI tools. Existing users start
+// with 100 credits.
+var aiCreditTables = Migration{
+	Version: 23,
+	Name:    "ai_credit_tables",
+	Up: Portable(
+		`CREATE TABLE ai_credits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL UNIQUE,
+			credits INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO ai_credits (user_id, credits) SELECT id, 100 FROM users`,
+
+		`CREATE TABLE ai_credit_transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			amount INTEGER NOT NULL,
+			description TEXT,
+			reference_type VARCHAR(50),
+			reference_id INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_credit_transactions_user_id ON ai_credit_transactions (user_id)`,
+
+		`CREATE TABLE ai_generated_content (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			model VARCHAR(100) NOT NULL,
+			prompt TEXT,
+			content TEXT,
+			metadata TEXT,
+			credits INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_generated_content_user_id ON ai_generated_content (user_id)`,
+
+		`CREATE TABLE ai_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			design TEXT,
+			thumbnail VARCHAR(500),
+			is_public BOOLEAN DEFAULT 0,
+			usage_count INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_templates_user_id ON ai_templates (user_id)`,
+
+		`CREATE TABLE ai_chat_sessions (
+			id VARCHAR(100) PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			context VARCHAR(100),
+			messages TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ai_chat_sessions_user_id ON ai_chat_sessions (user_id)`,
+
+		`CREATE TABLE marketplace_integration_configs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			credentials TEXT,
+			settings TEXT,
+			is_active BOOLEAN DEFAULT 1,
+			last_sync DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_marketplace_configs_user_id ON marketplace_integration_configs (user_id)`,
+
+		`CREATE TABLE marketplace_sync_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			sync_type VARCHAR(50) NOT NULL,
+			status VARCHAR(50) NOT NULL,
+			details TEXT,
+			error_message TEXT,
+			items_count INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_marketplace_logs_user_id ON marketplace_sync_logs (user_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS marketplace_sync_logs`,
+		`DROP TABLE IF EXISTS marketplace_integration_configs`,
+		`DROP TABLE IF EXISTS ai_chat_sessions`,
+		`DROP TABLE IF EXISTS ai_templates`,
+		`DROP TABLE IF EXISTS ai_generated_content`,
+		`DROP TABLE IF EXISTS ai_credit_transactions`,
+		`DROP TABLE IF EXISTS ai_credits`,
+	),
+}
@@ -0,0 +1,270 @@
+package migrations
+
+// chatAnalyticsTables holds the AI chat sessions and their messages, and the
+// visitor sessions, page views, events and the other tracking tables the
+// analytics services read. updated_at columns are set by the services.
+var chatAnalyticsTables = Migration{
+	Version: 24,
+	Name:    "chat_analytics_tables",
+	Up: Portable(
+		`CREATE TABLE chat_sessions (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			title VARCHAR(500) NOT NULL,
+			context VARCHAR(50) DEFAULT 'general',
+			status VARCHAR(20) DEFAULT 'active',
+			metadata TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_message DATETIME NULL
+		)`,
+		`CREATE INDEX idx_chat_sessions_user_id ON chat_sessions (user_id, updated_at)`,
+		`CREATE INDEX idx_chat_sessions_status ON chat_sessions (status, updated_at)`,
+		`CREATE INDEX idx_chat_sessions_created_at ON chat_sessions (created_at)`,
+
+		`CREATE TABLE chat_messages (
+			id VARCHAR(255) PRIMARY KEY,
+			session_id VARCHAR(255) NOT NULL,
+			user_id INTEGER NOT NULL,
+			role VARCHAR(20) NOT NULL,
+			content TEXT NOT NULL,
+			message_type VARCHAR(50) DEFAULT 'text',
+			metadata TEXT,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			tokens INTEGER DEFAULT 0
+		)`,
+		`CREATE INDEX idx_chat_messages_session_id ON chat_messages (session_id, timestamp)`,
+		`CREATE INDEX idx_chat_messages_user_id ON chat_messages (user_id)`,
+		`CREATE INDEX idx_chat_messages_timestamp ON chat_messages (timestamp, role)`,
+
+		`CREATE TABLE user_sessions (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id INTEGER,
+			session_token VARCHAR(255) UNIQUE,
+			ip_address VARCHAR(45),
+			user_agent TEXT,
+			device_type VARCHAR(50),
+			browser VARCHAR(100),
+			os VARCHAR(100),
+			country VARCHAR(100),
+			city VARCHAR(100),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_activity DATETIME DEFAULT CURRENT_TIMESTAMP,
+			is_active BOOLEAN DEFAULT 1
+		)`,
+		`CREATE INDEX idx_user_sessions_user_id ON user_sessions (user_id)`,
+		`CREATE INDEX idx_user_sessions_last_activity ON user_sessions (last_activity)`,
+		`CREATE INDEX idx_user_sessions_created_at ON user_sessions (created_at)`,
+
+		`CREATE TABLE page_views (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id VARCHAR(255),
+			user_id INTEGER,
+			path VARCHAR(500) NOT NULL,
+			title VARCHAR(500),
+			referrer VARCHAR(500),
+			query_params TEXT,
+			duration INTEGER DEFAULT 0,
+			bounce BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_page_views_session_id ON page_views (session_id)`,
+		`CREATE INDEX idx_page_views_user_id ON page_views (user_id)`,
+		`CREATE INDEX idx_page_views_created_at ON page_views (created_at)`,
+		`CREATE INDEX idx_page_views_path ON page_views (path)`,
+
+		`CREATE TABLE events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id VARCHAR(255),
+			user_id INTEGER,
+			event_type VARCHAR(100) NOT NULL,
+			event_name VARCHAR(200) NOT NULL,
+			event_data TEXT,
+			page_path VARCHAR(500),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_events_session_id ON events (session_id)`,
+		`CREATE INDEX idx_events_user_id ON events (user_id)`,
+		`CREATE INDEX idx_events_event_type ON events (event_type, created_at)`,
+		`CREATE INDEX idx_events_created_at ON events (created_at)`,
+
+		`CREATE TABLE business_metrics_cache (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			metric_type VARCHAR(100) NOT NULL,
+			period_type VARCHAR(50) NOT NULL,
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL,
+			data TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			UNIQUE (metric_type, period_type, start_date, end_date)
+		)`,
+		`CREATE INDEX idx_business_metrics_cache_expires_at ON business_metrics_cache (expires_at)`,
+
+		`CREATE TABLE customer_segments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL UNIQUE,
+			segment_name VARCHAR(100) NOT NULL,
+			segment_score DECIMAL(5,2) DEFAULT 0,
+			rfm_recency INTEGER DEFAULT 0,
+			rfm_frequency INTEGER DEFAULT 0,
+			rfm_monetary DECIMAL(10,2) DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_customer_segments_segment_name ON customer_segments (segment_name)`,
+		`CREATE INDEX idx_customer_segments_updated_at ON customer_segments (updated_at)`,
+
+		`CREATE TABLE ab_experiments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(200) NOT NULL,
+			description TEXT,
+			status VARCHAR(20) DEFAULT 'draft',
+			traffic_allocation DECIMAL(3,2) DEFAULT 0.5,
+			control_variant TEXT NOT NULL,
+			test_variants TEXT NOT NULL,
+			success_metrics TEXT,
+			start_date DATETIME NULL,
+			end_date DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE TABLE ab_assignments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			experiment_id INTEGER NOT NULL,
+			user_id INTEGER,
+			session_id VARCHAR(255),
+			variant_name VARCHAR(100) NOT NULL,
+			assigned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (experiment_id, user_id),
+			UNIQUE (experiment_id, session_id)
+		)`,
+
+		`CREATE TABLE ab_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			experiment_id INTEGER NOT NULL,
+			variant_name VARCHAR(100) NOT NULL,
+			metric_name VARCHAR(100) NOT NULL,
+			metric_value DECIMAL(15,4) NOT NULL,
+			user_count INTEGER DEFAULT 0,
+			conversion_count INTEGER DEFAULT 0,
+			recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_ab_results_experiment ON ab_results (experiment_id, variant_name)`,
+
+		`CREATE TABLE product_views (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			session_id VARCHAR(255),
+			product_id INTEGER NOT NULL,
+			view_duration INTEGER DEFAULT 0,
+			source VARCHAR(100),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_product_views_user_id ON product_views (user_id)`,
+		`CREATE INDEX idx_product_views_product_id ON product_views (product_id)`,
+		`CREATE INDEX idx_product_views_session_id ON product_views (session_id)`,
+		`CREATE INDEX idx_product_views_created_at ON product_views (created_at)`,
+
+		`CREATE TABLE search_queries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			session_id VARCHAR(255),
+			query VARCHAR(500) NOT NULL,
+			results_count INTEGER DEFAULT 0,
+			clicked_result_position INTEGER,
+			clicked_product_id INTEGER,
+			no_results BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_search_queries_user_id ON search_queries (user_id)`,
+		`CREATE INDEX idx_search_queries_query ON search_queries (query)`,
+		`CREATE INDEX idx_search_queries_created_at ON search_queries (created_at, no_results)`,
+
+		`CREATE TABLE cart_abandonment (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			session_id VARCHAR(255),
+			cart_data TEXT NOT NULL,
+			cart_value DECIMAL(10,2) DEFAULT 0,
+			abandonment_stage VARCHAR(100),
+			recovery_email_sent BOOLEAN DEFAULT 0,
+			recovered BOOLEAN DEFAULT 0,
+			recovered_at DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_cart_abandonment_user_id ON cart_abandonment (user_id)`,
+		`CREATE INDEX idx_cart_abandonment_created_at ON cart_abandonment (created_at, recovered)`,
+
+		`CREATE TABLE email_campaigns (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(200) NOT NULL,
+			subject VARCHAR(500) NOT NULL,
+			template_id VARCHAR(100),
+			segment_criteria TEXT,
+			status VARCHAR(20) DEFAULT 'draft',
+			scheduled_at DATETIME NULL,
+			sent_at DATETIME NULL,
+			total_recipients INTEGER DEFAULT 0,
+			delivered_count INTEGER DEFAULT 0,
+			opened_count INTEGER DEFAULT 0,
+			clicked_count INTEGER DEFAULT 0,
+			unsubscribed_count INTEGER DEFAULT 0,
+			bounced_count INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_email_campaigns_status ON email_campaigns (status, scheduled_at)`,
+
+		`CREATE TABLE email_campaign_recipients (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			campaign_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			sent_at DATETIME NULL,
+			delivered_at DATETIME NULL,
+			opened_at DATETIME NULL,
+			clicked_at DATETIME NULL,
+			unsubscribed_at DATETIME NULL,
+			bounce_reason TEXT
+		)`,
+		`CREATE INDEX idx_email_campaign_recipients_campaign_id ON email_campaign_recipients (campaign_id, status)`,
+		`CREATE INDEX idx_email_campaign_recipients_user_id ON email_campaign_recipients (user_id)`,
+
+		`CREATE TABLE analytics_aggregations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			metric_name VARCHAR(100) NOT NULL,
+			dimension VARCHAR(100),
+			dimension_value VARCHAR(255),
+			metric_value DECIMAL(15,4) NOT NULL,
+			count_value INTEGER DEFAULT 0,
+			aggregation_date DATE NOT NULL,
+			aggregation_hour INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (metric_name, dimension, dimension_value, aggregation_date, aggregation_hour)
+		)`,
+		`CREATE INDEX idx_analytics_aggregations_date ON analytics_aggregations (aggregation_date)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS analytics_aggregations`,
+		`DROP TABLE IF EXISTS email_campaign_recipients`,
+		`DROP TABLE IF EXISTS email_campaigns`,
+		`DROP TABLE IF EXISTS cart_abandonment`,
+		`DROP TABLE IF EXISTS search_queries`,
+		`DROP TABLE IF EXISTS product_views`,
+		`DROP TABLE IF EXISTS ab_results`,
+		`DROP TABLE IF EXISTS ab_assignments`,
+		`DROP TABLE IF EXISTS ab_experiments`,
+		`DROP TABLE IF EXISTS customer_segments`,
+		`DROP TABLE IF EXISTS business_metrics_cache`,
+		`DROP TABLE IF EXISTS events`,
+		`DROP TABLE IF EXISTS page_views`,
+		`DROP TABLE IF EXISTS user_sessions`,
+		`DROP TABLE IF EXISTS chat_messages`,
+		`DROP TABLE IF EXISTS chat_sessions`,
+	),
+}
@@ -0,0 +1,147 @@
+package migrations
+
+// integrationSupportTables holds integration settings, audit logs, rate
+// limits, health checks, user mappings and queued jobs, the gateway
+// transactions of payment integrations and the webhook events they received.
+// Credentials are in integration_credentials; the integration analytics
+// service creates its own metrics tables.
+var integrationSupportTables = Migration{
+	Version: 25,
+	Name:    "integration_support_tables",
+	Up: Portable(
+		`CREATE TABLE integration_configs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(100) UNIQUE NOT NULL,
+			integration_type VARCHAR(50) NOT NULL,
+			provider VARCHAR(100) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			status VARCHAR(20) DEFAULT 'inactive',
+			config TEXT,
+			metadata TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_integration_configs_type ON integration_configs (integration_type)`,
+		`CREATE INDEX idx_integration_configs_provider ON integration_configs (provider)`,
+		`CREATE INDEX idx_integration_configs_status ON integration_configs (status)`,
+
+		`CREATE TABLE integration_audit_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(100) NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			user_id INTEGER,
+			ip_address VARCHAR(45),
+			user_agent TEXT,
+			request_data TEXT,
+			response_data TEXT,
+			error_message TEXT,
+			status_code INTEGER,
+			duration_ms INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_integration_audit ON integration_audit_logs (integration_id, created_at)`,
+		`CREATE INDEX idx_integration_audit_action ON integration_audit_logs (action)`,
+		`CREATE INDEX idx_integration_audit_user ON integration_audit_logs (user_id)`,
+
+		`CREATE TABLE webhook_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id VARCHAR(100) UNIQUE NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			event_type VARCHAR(50) NOT NULL,
+			payload TEXT NOT NULL,
+			headers TEXT,
+			signature VARCHAR(500),
+			status VARCHAR(20) DEFAULT 'pending',
+			processed_at DATETIME NULL,
+			retry_count INTEGER DEFAULT 0,
+			error_message TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_webhook_events_integration ON webhook_events (integration_id)`,
+		`CREATE INDEX idx_webhook_events_status ON webhook_events (status)`,
+		`CREATE INDEX idx_webhook_events_created ON webhook_events (created_at)`,
+
+		`CREATE TABLE integration_rate_limits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(100) NOT NULL,
+			endpoint VARCHAR(255),
+			requests_per_minute INTEGER,
+			requests_remaining INTEGER,
+			resets_at DATETIME NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (integration_id, endpoint)
+		)`,
+
+		`CREATE TABLE integration_health_checks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(100) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			response_time_ms INTEGER,
+			error_message TEXT,
+			checked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_integration_health ON integration_health_checks (integration_id, checked_at)`,
+
+		`CREATE TABLE payment_transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			transaction_id VARCHAR(100) UNIQUE NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			order_id INTEGER,
+			payment_method VARCHAR(50),
+			amount DECIMAL(10,2) NOT NULL,
+			currency VARCHAR(3) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			gateway_response TEXT,
+			metadata TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_payment_transactions_order ON payment_transactions (order_id)`,
+		`CREATE INDEX idx_payment_transactions_status ON payment_transactions (status)`,
+		`CREATE INDEX idx_payment_transactions_created ON payment_transactions (created_at)`,
+
+		`CREATE TABLE integration_user_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			external_user_id VARCHAR(255),
+			access_token TEXT,
+			refresh_token TEXT,
+			token_expires_at DATETIME NULL,
+			metadata TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, integration_id)
+		)`,
+		`CREATE INDEX idx_integration_user_mappings_external ON integration_user_mappings (external_user_id)`,
+
+		`CREATE TABLE integration_queue_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			job_id VARCHAR(100) UNIQUE NOT NULL,
+			integration_id VARCHAR(100) NOT NULL,
+			job_type VARCHAR(50) NOT NULL,
+			payload TEXT NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			priority INTEGER DEFAULT 0,
+			retry_count INTEGER DEFAULT 0,
+			max_retries INTEGER DEFAULT 3,
+			error_message TEXT,
+			scheduled_at DATETIME NULL,
+			started_at DATETIME NULL,
+			completed_at DATETIME NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_integration_queue_status ON integration_queue_jobs (status, priority, scheduled_at)`,
+		`CREATE INDEX idx_integration_queue_integration ON integration_queue_jobs (integration_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS integration_queue_jobs`,
+		`DROP TABLE IF EXISTS integration_user_mappings`,
+		`DROP TABLE IF EXISTS payment_transactions`,
+		`DROP TABLE IF EXISTS integration_health_checks`,
+		`DROP TABLE IF EXISTS integration_rate_limits`,
+		`DROP TABLE IF EXISTS webhook_events`,
+		`DROP TABLE IF EXISTS integration_audit_logs`,
+		`DROP TABLE IF EXISTS integration_configs`,
+	),
+}
@@ -0,0 +1,51 @@
+package migrations
+
+// performanceIndexes adds the lookup indexes of the old performance index
+// set that the core and keyset schemas lack. The MySQL core schema already
+// indexes vendors and auctions by owner, status and product.
+var performanceIndexes = Migration{
+	Version: 26,
+	Name:    "performance_indexes",
+	Up: Step{
+		SQLite: []string{
+			`CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_stock ON products(stock)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_category_price ON products(category_id, price)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_user_status ON orders(user_id, status)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_total_amount ON orders(total_amount)`,
+			`CREATE INDEX IF NOT EXISTS idx_vendors_created_at ON vendors(created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_vendors_user ON vendors(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_vendors_status ON vendors(status)`,
+			`CREATE INDEX IF NOT EXISTS idx_auctions_product ON auctions(product_id)`,
+		},
+		MySQL: []string{
+			`CREATE INDEX idx_users_created_at ON users(created_at)`,
+			`CREATE INDEX idx_products_stock ON products(stock)`,
+			`CREATE INDEX idx_products_category_price ON products(category_id, price)`,
+			`CREATE INDEX idx_orders_user_status ON orders(user_id, status)`,
+			`CREATE INDEX idx_orders_total_amount ON orders(total_amount)`,
+			`CREATE INDEX idx_vendors_created_at ON vendors(created_at)`,
+		},
+	},
+	Down: Step{
+		SQLite: []string{
+			`DROP INDEX IF EXISTS idx_auctions_product`,
+			`DROP INDEX IF EXISTS idx_vendors_status`,
+			`DROP INDEX IF EXISTS idx_vendors_user`,
+			`DROP INDEX IF EXISTS idx_vendors_created_at`,
+			`DROP INDEX IF EXISTS idx_orders_total_amount`,
+			`DROP INDEX IF EXISTS idx_orders_user_status`,
+			`DROP INDEX IF EXISTS idx_products_category_price`,
+			`DROP INDEX IF EXISTS idx_products_stock`,
+			`DROP INDEX IF EXISTS idx_users_created_at`,
+		},
+		MySQL: []string{
+			`DROP INDEX idx_vendors_created_at ON vendors`,
+			`DROP INDEX idx_orders_total_amount ON orders`,
+			`DROP INDEX idx_orders_user_status ON orders`,
+			`DROP INDEX idx_products_category_price ON products`,
+			`DROP INDEX idx_products_stock ON products`,
+			`DROP INDEX idx_users_created_at ON users`,
+		},
+	},
+}
@@ -0,0 +1,26 @@
+package migrations
+
+// marketplaceOrderFailures parks the marketplace orders an import could not
+// process, so the import cursor can move past them while they are retried
+// on their own
+var marketplaceOrderFailures = Migration{
+	Version: 27,
+	Name:    "marketplace_order_failures",
+	Up: Portable(
+		`CREATE TABLE marketplace_order_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			integration_id VARCHAR(50) NOT NULL,
+			marketplace_order_id VARCHAR(100) NOT NULL,
+			order_date DATETIME NOT NULL,
+			payload TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 1,
+			last_error TEXT,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_marketplace_order_failures_external ON marketplace_order_failures (integration_id, marketplace_order_id)`,
+	),
+	Down: Both(
+		`DROP TABLE IF EXISTS marketplace_order_failures`,
+	),
+}
@@ -1,131 +1,146 @@
 package migrations
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
-	"time"
 )
 
-// MigrationService handles database migrations
-type MigrationService struct {
-	db     *sql.DB
-	dbName string
-}
-
-// NewMigrationService creates a new migration service
-func NewMigrationService(db *sql.DB, dbName string) *MigrationService {
-	return &MigrationService{
-		db:     db,
-		dbName: dbName,
-	}
-}
+// Dialect is the SQL dialect a migration's statements are written for. The
+// values match database.DatabaseType.
+type Dialect string
 
-// EnsureMigrationTable creates the migrations table if it doesn't exist
-func (m *MigrationService) EnsureMigrationTable() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS schema_migrations (
-		version VARCHAR(255) NOT NULL,
-		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (version)
-	);`
+const (
+	SQLite Dialect = "sqlite3"
+	MySQL  Dialect = "mysql"
+)
 
-	_, err := m.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating migration table: %w", err)
-	}
+// Dialects are the dialects every migration must support
+var Dialects = []Dialect{SQLite, MySQL}
 
-	return nil
+// Step is one direction of a migration, with its statements for each dialect.
+// Statements run one at a time, so each holds a single SQL statement.
+type Step struct {
+	SQLite []string
+	MySQL  []string
 }
 
-// GetAppliedMigrations returns a list of already applied migrations
-func (m *MigrationService) GetAppliedMigrations() (map[string]bool, error) {
-	applied := make(map[string]bool)
-
-	rows, err := m.db.Query("SELECT version FROM schema_migrations ORDER BY version")
-	if err != nil {
-		return nil, fmt.Errorf("error querying migrations: %w", err)
-	}
-	defer rows.Close()
+// Both returns a step whose statements are the same on every dialect
+func Both(statements ...string) Step {
+	return Step{SQLite: statements, MySQL: statements}
+}
 
-	for rows.Next() {
-		var version string
-		if err := rows.Scan(&version); err != nil {
-			return nil, fmt.Errorf("error scanning migration version: %w", err)
+// mysqlTableOptions are the storage options of MySQL tables
+const mysqlTableOptions = " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci"
+
+// Portable returns a step whose statements are written once, in SQLite
+// syntax, for every dialect. On MySQL, INTEGER PRIMARY KEY AUTOINCREMENT id
+// columns become AUTO_INCREMENT ones and created tables get the storage
+// options of the core schema.
+func Portable(statements ...string) Step {
+	step := Step{SQLite: statements}
+	for _, statement := range statements {
+		statement = strings.ReplaceAll(statement, "INTEGER PRIMARY KEY AUTOINCREMENT", "INT AUTO_INCREMENT PRIMARY KEY")
+		if strings.HasPrefix(strings.TrimSpace(statement), "CREATE TABLE") {
+			statement += mysqlTableOptions
 		}
-		applied[version] = true
+		step.MySQL = append(step.MySQL, statement)
 	}
-
-	return applied, nil
+	return step
 }
 
-// CreateMigration creates a new migration file with the given name
-func (m *MigrationService) CreateMigration(name string) (string, error) {
-	timestamp := time.Now().Format("20060102150405")
-	safeName := strings.ReplaceAll(strings.ToLower(name), " ", "_")
-
-	// Create both up and down migrations
-	upFileName := fmt.Sprintf("%s_%s.up.sql", timestamp, safeName)
-	downFileName := fmt.Sprintf("%s_%s.down.sql", timestamp, safeName)
-
-	// Note: In an actual implementation, this would create the files
-	// For this example, we'll just return the filenames
-	return fmt.Sprintf("Created migration files: %s and %s", upFileName, downFileName), nil
+// Statements returns the step's statements for dialect
+func (s Step) Statements(dialect Dialect) []string {
+	switch dialect {
+	case SQLite:
+		return s.SQLite
+	case MySQL:
+		return s.MySQL
+	default:
+		return nil
+	}
 }
 
-// RunMigrations runs all pending migrations
-func (m *MigrationService) RunMigrations() error {
-	// Ensure migration table exists
-	if err := m.EnsureMigrationTable(); err != nil {
-		return err
-	}
+// Migration is a versioned schema change. Down undoes Up.
+type Migration struct {
+	Version int
+	Name    string
+	Up      Step
+	Down    Step
+}
 
-	// Get applied migrations
-	applied, err := m.GetAppliedMigrations()
-	if err != nil {
-		return err
+// Checksum identifies the migration's SQL for dialect. It is recorded when
+// the migration is applied so that later edits to it can be detected.
+func (m Migration) Checksum(dialect Dialect) string {
+	h := sha256.New()
+	for _, step := range []Step{m.Up, m.Down} {
+		for _, statement := range step.Statements(dialect) {
+			h.Write([]byte(strings.TrimSpace(statement)))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
 	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	// Define all migrations in order
-	migrations := []struct {
-		version string
-		sql     string
-	}{
-		{"001_create_users_table", CreateUsersTable},
-		{"002_create_sessions_table", CreateSessionsTable},
-		{"003_create_email_log_table", CreateEmailLogTable},
-		{"004_create_user_profiles_table", CreateUserProfilesTable},
-		{"005_create_vendors_table", CreateVendorsTable},
-		{"006_create_products_table", CreateProductsTable},
-		{"007_create_orders_table", CreateOrdersTable},
-		{"008_create_auctions_table", CreateAuctionsTable},
-		{"009_create_wholesale_table", CreateWholesaleTable},
-		{"015_create_ai_vision_tables", CreateAIVisionTables},
-		{"016_create_enterprise_ai_tables", CreateEnterpriseAITables},
-		{"017_create_ai_advanced_tables", CreateAIAdvancedTables},
-	{"018_create_chat_analytics_tables", CreateChatAnalyticsTables},
-	}
+// String returns the migration's version and name, as in 001_core_schema
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
 
-	// Run pending migrations
-	for _, migration := range migrations {
-		if !applied[migration.version] {
-			fmt.Printf("Running migration: %s\n", migration.version)
+// all are the application's migrations in version order. New migrations are
+// added at the end with the next version and never edited once released.
+var all = []Migration{
+	coreSchema,
+	keysetIndexes,
+	jobTables,
+	orderWorkflowTables,
+	wholesaleTables,
+	paymentTables,
+	marketplaceTables,
+	integrationTables,
+	cacheTables,
+	orderDetailColumns,
+	couponUsageTables,
+	orderRefunds,
+	vendorTotalSales,
+	auctionEngineTables,
+	wholesaleCreditUsed,
+	reportTables,
+	paymentRefunds,
+	notificationTables,
+	userProfilesAndEmailLogs,
+	storefrontTables,
+	aiVisionTables,
+	enterpriseAITables,
+	aiCreditTables,
+	chatAnalyticsTables,
+	integrationSupportTables,
+	performanceIndexes,
+	marketplaceOrderFailures,
+}
 
-			// Execute migration
-			_, err := m.db.Exec(migration.sql)
-			if err != nil {
-				return fmt.Errorf("error running migration %s: %w", migration.version, err)
-			}
+// All returns the application's migrations in version order
+func All() []Migration {
+	return append([]Migration(nil), all...)
+}
 
-			// Record migration as applied
-			_, err = m.db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migration.version)
-			if err != nil {
-				return fmt.Errorf("error recording migration %s: %w", migration.version, err)
+// Validate checks that versions increase and that every migration has up and
+// down statements for every dialect
+func Validate(list []Migration) error {
+	for i, m := range list {
+		if m.Version <= 0 || m.Name == "" {
+			return fmt.Errorf("migration %d needs a positive version and a name", i)
+		}
+		if i > 0 && m.Version <= list[i-1].Version {
+			return fmt.Errorf("migration %s does not follow %s", m, list[i-1])
+		}
+		for _, dialect := range Dialects {
+			if len(m.Up.Statements(dialect)) == 0 || len(m.Down.Statements(dialect)) == 0 {
+				return fmt.Errorf("migration %s has no %s statements", m, dialect)
 			}
-
-			fmt.Printf("Migration %s completed successfully\n", migration.version)
 		}
 	}
-
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
+	"strings"
 )
 
 // PaginationParams holds pagination parameters
@@ -186,28 +187,16 @@ type CursorPaginationResult struct {
 	Count      int    `json:"count"`
 }
 
-// BuildCursorQuery builds a cursor-based pagination query
-func BuildCursorQuery(baseQuery string, params CursorPaginationParams, cursorField string) string {
-	if params.Limit <= 0 {
-		params.Limit = 20
+// Validate normalizes cursor pagination parameters
+func (p *CursorPaginationParams) Validate() {
+	if p.Limit < 1 {
+		p.Limit = 20
 	}
-	if params.Limit > 100 {
-		params.Limit = 100
+	if p.Limit > 100 {
+		p.Limit = 100 // Max page size to prevent abuse
 	}
-	
-	query := baseQuery
-	if params.Cursor != "" {
-		operator := ">"
-		if params.Order == "DESC" {
-			operator = "<"
-		}
-		query = fmt.Sprintf("%s WHERE %s %s '%s'", baseQuery, cursorField, operator, params.Cursor)
+	p.Order = strings.ToUpper(p.Order)
+	if p.Order != "ASC" && p.Order != "DESC" {
+		p.Order = "DESC"
 	}
-	
-	return fmt.Sprintf("%s ORDER BY %s %s LIMIT %d",
-		query,
-		params.OrderBy,
-		params.Order,
-		params.Limit+1, // Fetch one extra to determine if there are more
-	)
-}
\ No newline at end of file
+}
@@ -59,6 +59,8 @@ type Condition struct {
 	Operator Operator
 	Value    interface{}
 	Or       bool // If true, condition will be joined with OR instead of AND
+	Raw      bool // If true, Field is an SQL expression taking Args
+	Args     []interface{}
 }
 
 // Subquery represents a subquery in the query
@@ -166,6 +168,18 @@ func (qb *QueryBuilder) OrWhere(field string, operator Operator, value interface
 	return qb
 }
 
+// WhereRaw adds a parenthesized SQL expression with ? placeholders for args.
+// The expression is written into the query as is and must not contain user
+// input.
+func (qb *QueryBuilder) WhereRaw(expression string, args ...interface{}) *QueryBuilder {
+	qb.conditions = append(qb.conditions, Condition{
+		Field: expression,
+		Raw:   true,
+		Args:  args,
+	})
+	return qb
+}
+
 // WhereIn adds a WHERE IN condition
 func (qb *QueryBuilder) WhereIn(field string, values []interface{}) *QueryBuilder {
 	return qb.Where(field, In, values)
@@ -386,7 +400,10 @@ func (qb *QueryBuilder) Build() (string, []interface{}) {
 			}
 
 			// Operatör tipine göre koşul oluştur
-			if condition.Operator == IsNull || condition.Operator == IsNotNull {
+			if condition.Raw {
+				whereClause = append(whereClause, "("+condition.Field+")")
+				args = append(args, condition.Args...)
+			} else if condition.Operator == IsNull || condition.Operator == IsNotNull {
 				whereClause = append(whereClause, fmt.Sprintf("%s %s", condition.Field, condition.Operator))
 			} else {
 				whereClause = append(whereClause, fmt.Sprintf("%s %s ?", condition.Field, condition.Operator))
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaSetConfig configures the health checks of a replica set
+type ReplicaSetConfig struct {
+	// HealthCheckInterval is how often replicas are pinged. It defaults to
+	// 10 seconds.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds each ping. It defaults to 2 seconds.
+	HealthCheckTimeout time.Duration
+	// FailureThreshold is the number of failed pings in a row after which a
+	// replica stops serving reads until a ping succeeds. It defaults to 3.
+	FailureThreshold int
+	Logger           *log.Logger
+}
+
+// ReplicaSet routes reads to the read replicas of a primary. Reads go round
+// robin to the replicas that pass their health checks, and to the primary
+// when none does. Writes always go to the primary.
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	config   ReplicaSetConfig
+	logger   *log.Logger
+
+	next         atomic.Uint64
+	primaryReads atomic.Int64
+	stop         chan struct{}
+	stopOnce     sync.Once
+	wg           sync.WaitGroup
+}
+
+// replica is a read replica and its health
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+	reads   atomic.Int64
+
+	mu        sync.Mutex
+	failures  int
+	lastError string
+}
+
+// PoolStats are the connection pool metrics of a database of a replica set
+type PoolStats struct {
+	Name    string `json:"name"`
+	Role    string `json:"role"` // primary or replica
+	Healthy bool   `json:"healthy"`
+	// Reads counts the reads routed to the database
+	Reads        int64         `json:"reads"`
+	MaxOpen      int           `json:"max_open_connections"`
+	Open         int           `json:"open_connections"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+}
+
+// NewReplicaSet creates a replica set of primary without replicas
+func NewReplicaSet(primary *sql.DB, config ReplicaSetConfig) *ReplicaSet {
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 10 * time.Second
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = 2 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &ReplicaSet{primary: primary, config: config, logger: logger, stop: make(chan struct{})}
+}
+
+// AddReplica adds a read replica. It serves reads from its first successful
+// health check; replicas are added before Start.
+func (rs *ReplicaSet) AddReplica(name string, db *sql.DB) {
+	rs.replicas = append(rs.replicas, &replica{name: name, db: db})
+}
+
+// Start checks the replicas' health, then keeps checking it in the
+// background until Close
+func (rs *ReplicaSet) Start() {
+	rs.CheckHealth()
+	if len(rs.replicas) == 0 {
+		return
+	}
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		ticker := time.NewTicker(rs.config.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rs.CheckHealth()
+			case <-rs.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the health checks and closes the replicas. The primary is
+// left to its owner.
+func (rs *ReplicaSet) Close() error {
+	rs.stopOnce.Do(func() { close(rs.stop) })
+	rs.wg.Wait()
+	var firstErr error
+	for _, r := range rs.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Primary returns the primary database
+func (rs *ReplicaSet) Primary() *sql.DB {
+	return rs.primary
+}
+
+// Reader returns the database a read in ctx goes to: the next healthy
+// replica while ctx's read session has not written, otherwise the primary.
+// Reads without a read session go to the primary, as their caller may have
+// just written through another handle; ctx may be nil.
+func (rs *ReplicaSet) Reader(ctx context.Context) *sql.DB {
+	if ctx != nil {
+		if session, ok := ctx.Value(readSessionKey{}).(*readSession); ok && !session.wrote.Load() {
+			return rs.Replica()
+		}
+	}
+	rs.primaryReads.Add(1)
+	return rs.primary
+}
+
+// Replica returns the next healthy replica, or the primary without one. It
+// is for reads that tolerate replication lag, such as reports.
+func (rs *ReplicaSet) Replica() *sql.DB {
+	if n := len(rs.replicas); n > 0 {
+		start := rs.next.Add(1)
+		for i := 0; i < n; i++ {
+			r := rs.replicas[(start+uint64(i))%uint64(n)]
+			if r.healthy.Load() {
+				r.reads.Add(1)
+				return r.db
+			}
+		}
+	}
+	rs.primaryReads.Add(1)
+	return rs.primary
+}
+
+// Wrote records a write in ctx's read session, whose reads then go to the
+// primary. ctx may be nil or have no session.
+func (rs *ReplicaSet) Wrote(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	if session, ok := ctx.Value(readSessionKey{}).(*readSession); ok {
+		session.wrote.Store(true)
+	}
+}
+
+// CheckHealth pings every replica. A replica that fails FailureThreshold
+// pings in a row stops serving reads, and serves them again once a ping
+// succeeds.
+func (rs *ReplicaSet) CheckHealth() {
+	var wg sync.WaitGroup
+	for _, r := range rs.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), rs.config.HealthCheckTimeout)
+			defer cancel()
+			rs.recordHealth(r, r.db.PingContext(ctx))
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (rs *ReplicaSet) recordHealth(r *replica, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.failures = 0
+		r.lastError = ""
+		if !r.healthy.Swap(true) {
+			rs.logger.Printf("Read replica %s is serving reads", r.name)
+		}
+		return
+	}
+	r.failures++
+	r.lastError = err.Error()
+	if r.failures >= rs.config.FailureThreshold && r.healthy.Swap(false) {
+		rs.logger.Printf("Read replica %s failed %d health checks, failing over: %v", r.name, r.failures, err)
+	}
+}
+
+// Stats returns the pool metrics of the primary and each replica
+func (rs *ReplicaSet) Stats() []PoolStats {
+	stats := []PoolStats{poolStats("primary", "primary", rs.primary.Stats())}
+	stats[0].Healthy = true
+	stats[0].Reads = rs.primaryReads.Load()
+	for _, r := range rs.replicas {
+		s := poolStats(r.name, "replica", r.db.Stats())
+		s.Healthy = r.healthy.Load()
+		s.Reads = r.reads.Load()
+		r.mu.Lock()
+		s.LastError = r.lastError
+		r.mu.Unlock()
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func poolStats(name, role string, s sql.DBStats) PoolStats {
+	return PoolStats{
+		Name:         name,
+		Role:         role,
+		MaxOpen:      s.MaxOpenConnections,
+		Open:         s.OpenConnections,
+		InUse:        s.InUse,
+		Idle:         s.Idle,
+		WaitCount:    s.WaitCount,
+		WaitDuration: s.WaitDuration,
+	}
+}
+
+// readSession records whether a request has written
+type readSession struct {
+	wrote atomic.Bool
+}
+
+type readSessionKey struct{}
+
+// WithReadSession returns a context with a new read session, which opts
+// the reads of repositories bound to it into the replicas. Reads in the
+// session go to replicas until something is written in it, then to the
+// primary, so a request that binds its repositories to the context reads
+// its own writes.
+func WithReadSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readSessionKey{}, &readSession{})
+}
+
+// isReadOnlyQuery reports whether a raw query can run on a replica: a
+// SELECT that does not lock rows
+func isReadOnlyQuery(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(query, "SELECT") {
+		return false
+	}
+	return !strings.Contains(query, "FOR UPDATE") && !strings.Contains(query, "LOCK IN SHARE MODE") && !strings.Contains(query, "FOR SHARE")
+}
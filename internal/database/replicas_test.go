@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"testing"
+)
+
+// openSourceDB opens an in-memory database whose source table holds its name,
+// so a read shows which database served it
+func openSourceDB(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"-"+name+"?mode=memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE source (name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO source (name) VALUES (?)", name); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func readSource(t *testing.T, repo *MySQLRepository) string {
+	t.Helper()
+	var name string
+	if err := repo.QueryRow("SELECT name FROM source").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestReplicaSetRouting(t *testing.T) {
+	primary := openSourceDB(t, "primary")
+	first, second := openSourceDB(t, "first"), openSourceDB(t, "second")
+	rs := NewReplicaSet(primary, ReplicaSetConfig{FailureThreshold: 2, Logger: log.New(io.Discard, "", 0)})
+	rs.AddReplica("first", first)
+	rs.AddReplica("second", second)
+	repo := NewMySQLRepository(primary)
+	repo.SetReplicas(rs)
+	// session returns repo bound to a new read session
+	session := func() *MySQLRepository {
+		return repo.WithContext(WithReadSession(context.Background()))
+	}
+
+	// Replicas serve reads from their first successful health check
+	if got := readSource(t, session()); got != "primary" {
+		t.Fatalf("read before health checks went to %s", got)
+	}
+	rs.CheckHealth()
+	reads := map[string]int{}
+	for i := 0; i < 4; i++ {
+		reads[readSource(t, session())]++
+	}
+	if reads["first"] != 2 || reads["second"] != 2 {
+		t.Fatalf("reads were not spread over the replicas: %v", reads)
+	}
+
+	// Reads outside a read session go to the primary, so a caller reads
+	// what it has just written
+	if _, err := repo.Exec("INSERT INTO source (name) VALUES ('written')"); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := repo.QueryRow("SELECT COUNT(*) FROM source").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("unbound read did not see the write: count %d, %v", count, err)
+	}
+	if got := readSource(t, repo.WithContext(context.Background())); got != "primary" {
+		t.Fatalf("read without a read session went to %s", got)
+	}
+
+	// A request reads its own writes once it has written
+	ctx := WithReadSession(context.Background())
+	bound := repo.WithContext(ctx)
+	if got := readSource(t, bound); got == "primary" {
+		t.Fatal("read session read from the primary before writing")
+	}
+	if _, err := repo.WithContext(ctx).Exec("UPDATE source SET name = name"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if got := readSource(t, bound); got != "primary" {
+			t.Fatalf("read after writing in the session went to %s", got)
+		}
+	}
+	if got := readSource(t, session()); got == "primary" {
+		t.Fatal("another session read from the primary")
+	}
+
+	// Wrapped repositories bound to the request follow its session too
+	var name string
+	if err := BindContext(NewRepositoryWrapper(repo), ctx).QueryRow("SELECT name FROM source").Scan(&name); err != nil || name != "primary" {
+		t.Fatalf("bound wrapper read from %s (err %v) after the session wrote", name, err)
+	}
+
+	// A replica fails over after FailureThreshold failed checks in a row
+	second.Close()
+	rs.CheckHealth()
+	if !rs.Stats()[2].Healthy {
+		t.Fatal("replica failed over after a single failed check")
+	}
+	rs.CheckHealth()
+	for i := 0; i < 3; i++ {
+		if got := readSource(t, session()); got != "first" {
+			t.Fatalf("read went to %s after the second replica failed", got)
+		}
+	}
+	first.Close()
+	rs.CheckHealth()
+	rs.CheckHealth()
+	if got := readSource(t, session()); got != "primary" {
+		t.Fatalf("read went to %s without healthy replicas", got)
+	}
+
+	stats := rs.Stats()
+	if len(stats) != 3 || stats[0].Role != "primary" || stats[1].Name != "first" || stats[2].Name != "second" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats[1].Healthy || stats[2].Healthy || stats[2].LastError == "" {
+		t.Errorf("failed replicas reported healthy: %+v", stats[1:])
+	}
+	if stats[0].Reads != 8 || stats[1].Reads+stats[2].Reads != 9 {
+		t.Errorf("reads: got %d on the primary and %d on the replicas, want 8 and 9", stats[0].Reads, stats[1].Reads+stats[2].Reads)
+	}
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SELECT * FROM products":                             true,
+		"  select id from orders where id = ?":               true,
+		"SELECT stock FROM products WHERE id = ? FOR UPDATE": false,
+		"SELECT * FROM orders LOCK IN SHARE MODE":            false,
+		"UPDATE products SET stock = stock - 1":              false,
+		"INSERT INTO orders (id) SELECT id FROM carts":       false,
+		"WITH t AS (DELETE FROM carts) SELECT 1":             false,
+	} {
+		if got := isReadOnlyQuery(query); got != want {
+			t.Errorf("isReadOnlyQuery(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
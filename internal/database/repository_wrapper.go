@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"reflect"
 	"time"
@@ -16,6 +17,21 @@ func NewRepositoryWrapper(repo *MySQLRepository) *RepositoryWrapper {
 	return &RepositoryWrapper{MySQLRepository: repo}
 }
 
+// WithContext returns a copy of the wrapper bound to ctx, whose reads follow
+// ctx's read session
+func (r *RepositoryWrapper) WithContext(ctx context.Context) *RepositoryWrapper {
+	return &RepositoryWrapper{MySQLRepository: r.MySQLRepository.WithContext(ctx)}
+}
+
+// BindContext returns repo bound to ctx when it supports read sessions, so
+// its reads follow ctx's session. Other repositories are returned as they are.
+func BindContext(repo SimpleRepository, ctx context.Context) SimpleRepository {
+	if wrapper, ok := repo.(*RepositoryWrapper); ok {
+		return wrapper.WithContext(ctx)
+	}
+	return repo
+}
+
 // CreateStruct creates a record from a struct
 func (r *RepositoryWrapper) CreateStruct(table string, data interface{}) (int64, error) {
 	fields, values := r.structToFieldsAndValues(data)
@@ -57,6 +73,7 @@ func (r *RepositoryWrapper) structToFieldsAndValues(data interface{}) ([]string,
 
 // Exec executes a query without returning any rows
 func (r *RepositoryWrapper) Exec(query string, args ...interface{}) (Result, error) {
+	r.wrote()
 	result, err := r.MySQLRepository.db.Exec(query, args...)
 	if err != nil {
 		return nil, err
@@ -66,7 +83,7 @@ func (r *RepositoryWrapper) Exec(query string, args ...interface{}) (Result, err
 
 // Query executes a query that returns rows
 func (r *RepositoryWrapper) Query(query string, args ...interface{}) (Rows, error) {
-	rows, err := r.MySQLRepository.db.Query(query, args...)
+	rows, err := r.queryDB(query).Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,12 +92,13 @@ func (r *RepositoryWrapper) Query(query string, args ...interface{}) (Rows, erro
 
 // QueryRow executes a query that returns at most one row
 func (r *RepositoryWrapper) QueryRow(query string, args ...interface{}) Row {
-	row := r.MySQLRepository.db.QueryRow(query, args...)
+	row := r.queryDB(query).QueryRow(query, args...)
 	return &rowWrapper{row: row}
 }
 
 // Begin starts a transaction
 func (r *RepositoryWrapper) Begin() (Transaction, error) {
+	r.wrote()
 	tx, err := r.MySQLRepository.db.Begin()
 	if err != nil {
 		return nil, err
@@ -117,6 +135,7 @@ type SimpleRepository interface {
 	Delete(table string, id interface{}) error
 	FindByID(table string, id interface{}, result interface{}) error
 	FindAll(table string, result interface{}, conditions map[string]interface{}, orderBy string, limit, offset int) error
+	FindAllKeyset(table string, result interface{}, conditions map[string]interface{}, keyset Keyset, cursor *Cursor, limit int) error
 	FindOne(table string, result interface{}, conditions map[string]interface{}) error
 	Count(table string, conditions map[string]interface{}) (int64, error)
 	Search(table string, fields []string, term string, limit, offset int, result interface{}) error
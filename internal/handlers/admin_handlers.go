@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,12 +14,17 @@ import (
 	"kolajAi/internal/models"
 	"kolajAi/internal/database"
 	"kolajAi/internal/middleware"
+	"kolajAi/internal/services"
 )
 
 // AdminHandler handles admin-related requests
 type AdminHandler struct {
 	*Handler
 	AdminRepo *repository.AdminRepository
+	// MarketplaceSync, when set, enables the marketplace sync runs page
+	MarketplaceSync *services.MarketplaceSyncService
+	// Webhooks, when set, enables the webhook inbox endpoints
+	Webhooks *services.IntegrationWebhookService
 }
 
 // NewAdminHandler creates a new admin handler
@@ -311,6 +317,202 @@ func (h *AdminHandler) AdminVendors(w http.ResponseWriter, r *http.Request) {
 	h.RenderTemplate(w, r, "admin/vendors", data)
 }
 
+// AdminMarketplaceSync handles the marketplace sync runs page. ?run= shows
+// the listings of a run and ?product= the latest outcome of a product on
+// each marketplace.
+func (h *AdminHandler) AdminMarketplaceSync(w http.ResponseWriter, r *http.Request) {
+	if h.MarketplaceSync == nil {
+		http.Error(w, "Marketplace sync runs are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	data := map[string]interface{}{
+		"Title":       "Pazaryeri Senkronizasyonları",
+		"Integration": query.Get("integration"),
+		"Status":      query.Get("status"),
+	}
+
+	if runID, _ := strconv.ParseInt(query.Get("run"), 10, 64); runID > 0 {
+		run, err := h.MarketplaceSync.GetRun(runID)
+		if err != nil {
+			http.Error(w, "Senkronizasyon bulunamadı", http.StatusNotFound)
+			return
+		}
+		items, err := h.MarketplaceSync.GetRunItems(runID, query.Get("item_status"))
+		if err != nil {
+			Logger.Printf("Error getting sync run items: %v", err)
+			items = []models.MarketplaceSyncItem{}
+		}
+		data["Run"] = run
+		data["Items"] = items
+		data["ItemStatus"] = query.Get("item_status")
+	}
+
+	if productID, _ := strconv.ParseInt(query.Get("product"), 10, 64); productID > 0 {
+		items, err := h.MarketplaceSync.GetProductSyncStatus(productID)
+		if err != nil {
+			Logger.Printf("Error getting product sync status: %v", err)
+			items = []models.MarketplaceSyncItem{}
+		}
+		data["ProductID"] = productID
+		data["ProductItems"] = items
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage := 20
+	runs, total, err := h.MarketplaceSync.ListRuns(query.Get("integration"), query.Get("status"), perPage, (page-1)*perPage)
+	if err != nil {
+		Logger.Printf("Error listing sync runs: %v", err)
+		runs = []models.MarketplaceSyncRun{}
+	}
+	data["Runs"] = runs
+	data["TotalCount"] = total
+	data["CurrentPage"] = page
+	data["TotalPages"] = (total + perPage - 1) / perPage
+
+	h.RenderTemplate(w, r, "admin/marketplace-sync", data)
+}
+
+// APIRetryMarketplaceSyncRun sends the rejected and failed listings of a sync
+// run again
+func (h *AdminHandler) APIRetryMarketplaceSyncRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.MarketplaceSync == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Pazaryeri senkronizasyonları etkin değil",
+		})
+		return
+	}
+
+	runID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Geçersiz senkronizasyon ID",
+		})
+		return
+	}
+
+	run, err := h.MarketplaceSync.Retry(runID)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Senkronizasyon tekrarlanırken hata oluştu"
+		switch {
+		case errors.Is(err, services.ErrSyncRunNotFound):
+			status, message = http.StatusNotFound, "Senkronizasyon bulunamadı"
+		case errors.Is(err, services.ErrNothingToRetry):
+			status, message = http.StatusConflict, "Tekrarlanacak başarısız ürün yok"
+		default:
+			log.Printf("Error retrying sync run %d: %v", runID, err)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": message,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Senkronizasyon %d tekrarlandı", runID),
+		"run":     run,
+	})
+}
+
+// APIListWebhookEvents lists the webhook inbox, newest first. ?integration=
+// and ?status= filter the events.
+func (h *AdminHandler) APIListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.Webhooks == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Webhook kutusu etkin değil",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage := 20
+	events, total, err := h.Webhooks.ListEvents(query.Get("integration"), query.Get("status"), perPage, (page-1)*perPage)
+	if err != nil {
+		log.Printf("Error listing webhook events: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Webhook olayları alınırken hata oluştu",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"events":      events,
+		"total":       total,
+		"page":        page,
+		"total_pages": (total + perPage - 1) / perPage,
+	})
+}
+
+// APIReplayWebhookEvent queues a webhook event to be applied again
+func (h *AdminHandler) APIReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.Webhooks == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Webhook kutusu etkin değil",
+		})
+		return
+	}
+
+	eventID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Geçersiz webhook olayı ID",
+		})
+		return
+	}
+
+	event, err := h.Webhooks.Replay(eventID)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Webhook olayı tekrar oynatılırken hata oluştu"
+		switch {
+		case errors.Is(err, services.ErrWebhookEventNotFound):
+			status, message = http.StatusNotFound, "Webhook olayı bulunamadı"
+		case errors.Is(err, services.ErrWebhookEventPending):
+			status, message = http.StatusConflict, "Webhook olayı şu anda işleniyor"
+		default:
+			log.Printf("Error replaying webhook event %d: %v", eventID, err)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": message,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Webhook olayı %d tekrar kuyruğa alındı", eventID),
+		"event":   event,
+	})
+}
+
 // AdminSystemHealth handles admin system health page
 func (h *AdminHandler) AdminSystemHealth(w http.ResponseWriter, r *http.Request) {
 	// Get real system health data
@@ -9,7 +9,7 @@ import (
 	"time"
 	
 	"golang.org/x/crypto/bcrypt"
-	"github.com/kolajai/internal/models"
+	"kolajAi/internal/models"
 )
 
 var (
@@ -7,7 +7,7 @@ import (
 	"os"
 	"time"
 	
-	"github.com/kolajai/internal/models"
+	"kolajAi/internal/models"
 )
 
 var (
@@ -118,22 +118,22 @@ func (h *Handler) GetRecentNotifications(userID int64, limit int) []models.Notif
 	// Demo data for now
 	return []models.Notification{
 		{
-			ID:        1,
-			UserID:    userID,
-			Title:     "Hoş Geldiniz",
-			Message:   "KolajAI platformuna hoş geldiniz! Başlamak için profil bilgilerinizi tamamlayın.",
-			Type:      "info",
-			IsRead:    false,
-			CreatedAt: time.Now().Add(-1 * time.Hour),
+			ID:            1,
+			RecipientID:   uint(userID),
+			RecipientType: models.RecipientTypeUser,
+			Title:         "Hoş Geldiniz",
+			Message:       "KolajAI platformuna hoş geldiniz! Başlamak için profil bilgilerinizi tamamlayın.",
+			Type:          "info",
+			CreatedAt:     time.Now().Add(-1 * time.Hour),
 		},
 		{
-			ID:        2,
-			UserID:    userID,
-			Title:     "Güvenlik Bildirimi",
-			Message:   "Hesabınıza yeni bir cihazdan giriş yapıldı.",
-			Type:      "warning",
-			IsRead:    false,
-			CreatedAt: time.Now().Add(-2 * time.Hour),
+			ID:            2,
+			RecipientID:   uint(userID),
+			RecipientType: models.RecipientTypeUser,
+			Title:         "Güvenlik Bildirimi",
+			Message:       "Hesabınıza yeni bir cihazdan giriş yapıldı.",
+			Type:          "warning",
+			CreatedAt:     time.Now().Add(-2 * time.Hour),
 		},
 	}
 }
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -51,7 +53,7 @@ func (h *EcommerceHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Get products from service
-	products, err := h.productService.GetProducts(category, search, page, limit)
+	products, err := h.productService.WithContext(r.Context()).GetProducts(category, search, page, limit)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get products: %v", err), http.StatusInternalServerError)
 		return
@@ -78,7 +80,7 @@ func (h *EcommerceHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Get product from service
-	product, err := h.productService.GetProductByID(id)
+	product, err := h.productService.WithContext(r.Context()).GetProductByID(id)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get product: %v", err), http.StatusInternalServerError)
 		return
@@ -115,7 +117,7 @@ func (h *EcommerceHandler) SearchProducts(w http.ResponseWriter, r *http.Request
 	}
 	
 	// Search products using the service
-	products, err := h.productService.GetProducts("", query, page, limit)
+	products, err := h.productService.WithContext(r.Context()).GetProducts("", query, page, limit)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to search products: %v", err), http.StatusInternalServerError)
 		return
@@ -135,7 +137,7 @@ func (h *EcommerceHandler) SearchProducts(w http.ResponseWriter, r *http.Request
 // GetCategories handles category listing
 func (h *EcommerceHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
 	// Get categories from service
-	categories, err := h.productService.GetAllCategories()
+	categories, err := h.productService.WithContext(r.Context()).GetAllCategories()
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get categories: %v", err), http.StatusInternalServerError)
 		return
@@ -149,6 +151,50 @@ func (h *EcommerceHandler) GetCategories(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// GetMyOrders lists the signed-in customer's orders. Multi-vendor orders
+// are listed once; GetMyOrder shows their per-vendor sub-orders.
+func (h *EcommerceHandler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	limit, offset := listPage(r)
+	orders, err := h.orderService.WithContext(r.Context()).GetOrdersByUser(int(userID), limit, offset)
+	if err != nil {
+		log.Printf("Failed to get orders of user %d: %v", userID, err)
+		writeAPIJSON(w, http.StatusInternalServerError, false, "Siparişler alınamadı", nil)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"orders": orders})
+}
+
+// GetMyOrder returns an order of the signed-in customer with its items and,
+// for a multi-vendor order, the sub-order of each vendor
+func (h *EcommerceHandler) GetMyOrder(w http.ResponseWriter, r *http.Request) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz sipariş ID", nil)
+		return
+	}
+	order, err := h.orderService.WithContext(r.Context()).GetCustomerOrder(int(userID), orderID)
+	if errors.Is(err, services.ErrOrderNotFound) {
+		writeAPIJSON(w, http.StatusNotFound, false, "Sipariş bulunamadı", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get order %d: %v", orderID, err)
+		writeAPIJSON(w, http.StatusInternalServerError, false, "Sipariş alınamadı", nil)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"order": order})
+}
+
 // HealthCheck provides a simple health check endpoint
 func (h *EcommerceHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
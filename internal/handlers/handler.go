@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
@@ -485,3 +486,18 @@ func (h *Handler) IsAdminUser(r *http.Request) bool {
 	
 	return false
 }
+
+// writeAPIJSON writes a success/message response of the JSON API with extra
+// fields
+func writeAPIJSON(w http.ResponseWriter, status int, success bool, message string, fields map[string]interface{}) {
+	response := map[string]interface{}{"success": success}
+	if message != "" {
+		response["message"] = message
+	}
+	for k, v := range fields {
+		response[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+)
+
+// InventorySyncHandler handles the allocation rules that decide how much of
+// a product's stock each marketplace is offered, and shows the result per
+// channel. All of its endpoints are admin only.
+type InventorySyncHandler struct {
+	*Handler
+	inventorySync *services.InventorySyncService
+}
+
+// NewInventorySyncHandler creates a new inventory sync handler
+func NewInventorySyncHandler(h *Handler, inventorySync *services.InventorySyncService) *InventorySyncHandler {
+	return &InventorySyncHandler{
+		Handler:       h,
+		inventorySync: inventorySync,
+	}
+}
+
+// APIAllocationRules lists the allocation rules, of the marketplace in the
+// integration query parameter if given, on GET and creates one on POST
+func (h *InventorySyncHandler) APIAllocationRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := h.inventorySync.GetRules(r.URL.Query().Get("integration"))
+		if err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralları alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"rules": rules})
+	case http.MethodPost:
+		var rule models.InventoryAllocationRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		rule.ID = 0
+		if err := h.inventorySync.SaveRule(&rule); err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralı oluşturulamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusCreated, true, "Stok dağıtım kuralı oluşturuldu", map[string]interface{}{"rule": &rule})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// APIAllocationRule returns an allocation rule on GET, updates it on PUT and
+// removes it on DELETE. An update changes only the fields in the body; the
+// marketplace and product of a rule are fixed.
+func (h *InventorySyncHandler) APIAllocationRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz kural ID", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rule, err := h.inventorySync.GetRule(id)
+		if err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralı alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"rule": rule})
+	case http.MethodPut:
+		rule, err := h.inventorySync.GetRule(id)
+		if err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralı güncellenemedi")
+			return
+		}
+		integrationID, productID := rule.IntegrationID, rule.ProductID
+		if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		rule.ID, rule.IntegrationID, rule.ProductID = id, integrationID, productID
+		if err := h.inventorySync.SaveRule(rule); err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralı güncellenemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Stok dağıtım kuralı güncellendi", map[string]interface{}{"rule": rule})
+	case http.MethodDelete:
+		if err := h.inventorySync.DeleteRule(id); err != nil {
+			h.writeError(w, err, "Stok dağıtım kuralı silinemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Stok dağıtım kuralı silindi", nil)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// APIProductChannels returns the quantity each marketplace is offered of a
+// product under the current rules, with the quantities last pushed
+func (h *InventorySyncHandler) APIProductChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	productID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || productID <= 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz ürün ID", nil)
+		return
+	}
+	allocation, err := h.inventorySync.Allocate(productID)
+	if err != nil {
+		h.writeError(w, err, "Kanal stokları alınamadı")
+		return
+	}
+	channels, err := h.inventorySync.GetChannelStock(productID)
+	if err != nil {
+		h.writeError(w, err, "Kanal stokları alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{
+		"allocation": allocation,
+		"channels":   channels,
+	})
+}
+
+// writeError maps inventory sync errors to a status and a message
+func (h *InventorySyncHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	status, message := http.StatusInternalServerError, fallback
+	switch {
+	case errors.Is(err, services.ErrAllocationRuleNotFound):
+		status, message = http.StatusNotFound, "Stok dağıtım kuralı bulunamadı"
+	case errors.Is(err, services.ErrProductNotFound):
+		status, message = http.StatusNotFound, "Ürün bulunamadı"
+	case errors.Is(err, services.ErrInvalidAllocationRule):
+		status, message = http.StatusBadRequest, fallback+": "+err.Error()
+	default:
+		log.Printf("Inventory sync request failed: %v", err)
+	}
+	writeAPIJSON(w, status, false, message, nil)
+}
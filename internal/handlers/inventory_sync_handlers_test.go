@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+	"kolajAi/internal/testutil"
+)
+
+// newTestInventorySyncMux serves the allocation rule endpoints on an
+// in-memory database. The admin middleware is left out; main.go wraps the
+// routes in it.
+func newTestInventorySyncMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	inventorySync, err := services.NewInventorySyncService(testutil.NewRepo(t), services.InventorySyncConfig{
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			return nil, errors.New("no marketplaces in tests")
+		},
+		IntegrationIDs: []string{"trendyol", "n11"},
+		Debounce:       time.Hour,
+		Logger:         testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(inventorySync.Stop)
+
+	h := NewInventorySyncHandler(&Handler{}, inventorySync)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/inventory/allocation-rules", h.APIAllocationRules)
+	mux.HandleFunc("/api/admin/inventory/allocation-rules/{id}", h.APIAllocationRule)
+	mux.HandleFunc("/api/admin/inventory/products/{id}/channels", h.APIProductChannels)
+	return mux
+}
+
+type ruleResponse struct {
+	Success bool                             `json:"success"`
+	Message string                           `json:"message"`
+	Rule    models.InventoryAllocationRule   `json:"rule"`
+	Rules   []models.InventoryAllocationRule `json:"rules"`
+}
+
+func serveRule(t *testing.T, mux *http.ServeMux, method, path, body string) (int, ruleResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var response ruleResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: %v (body %q)", method, path, err, rec.Body.String())
+	}
+	return rec.Code, response
+}
+
+func TestAllocationRuleEndpoints(t *testing.T) {
+	mux := newTestInventorySyncMux(t)
+
+	code, created := serveRule(t, mux, http.MethodPost, "/api/admin/inventory/allocation-rules",
+		`{"integration_id":"n11","buffer_stock":2,"percentage":50,"priority":1,"is_active":true}`)
+	if code != http.StatusCreated || created.Rule.ID == 0 {
+		t.Fatalf("create = %d %+v", code, created)
+	}
+	if code, _ := serveRule(t, mux, http.MethodPost, "/api/admin/inventory/allocation-rules",
+		`{"integration_id":"trendyol","percentage":100,"is_active":true}`); code != http.StatusCreated {
+		t.Fatalf("second create = %d", code)
+	}
+
+	code, listed := serveRule(t, mux, http.MethodGet, "/api/admin/inventory/allocation-rules?integration=n11", "")
+	if code != http.StatusOK || len(listed.Rules) != 1 || listed.Rules[0].ID != created.Rule.ID {
+		t.Fatalf("list = %d %+v", code, listed.Rules)
+	}
+	if _, all := serveRule(t, mux, http.MethodGet, "/api/admin/inventory/allocation-rules", ""); len(all.Rules) != 2 {
+		t.Fatalf("list without a filter = %+v", all.Rules)
+	}
+
+	// An update changes the fields in the body only and cannot move the
+	// rule to another marketplace
+	path := "/api/admin/inventory/allocation-rules/" + strconv.FormatInt(created.Rule.ID, 10)
+	code, updated := serveRule(t, mux, http.MethodPut, path, `{"integration_id":"trendyol","max_quantity":7}`)
+	if code != http.StatusOK {
+		t.Fatalf("update = %d %+v", code, updated)
+	}
+	code, got := serveRule(t, mux, http.MethodGet, path, "")
+	if code != http.StatusOK || got.Rule.IntegrationID != "n11" || got.Rule.MaxQuantity != 7 ||
+		got.Rule.BufferStock != 2 || got.Rule.Percentage != 50 || !got.Rule.IsActive {
+		t.Fatalf("get after update = %d %+v", code, got.Rule)
+	}
+
+	if code, _ := serveRule(t, mux, http.MethodDelete, path, ""); code != http.StatusOK {
+		t.Fatalf("delete = %d", code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if code, response := serveRule(t, mux, method, path, `{}`); code != http.StatusNotFound || response.Success {
+			t.Fatalf("%s of a deleted rule = %d %+v", method, code, response)
+		}
+	}
+}
+
+func TestAllocationRuleEndpointsRejectInvalidRules(t *testing.T) {
+	mux := newTestInventorySyncMux(t)
+
+	tests := []struct {
+		name, method, path, body string
+	}{
+		{"no marketplace", http.MethodPost, "/api/admin/inventory/allocation-rules", `{"percentage":50}`},
+		{"percentage above 100", http.MethodPost, "/api/admin/inventory/allocation-rules", `{"integration_id":"n11","percentage":150}`},
+		{"malformed body", http.MethodPost, "/api/admin/inventory/allocation-rules", `{`},
+		{"malformed ID", http.MethodGet, "/api/admin/inventory/allocation-rules/abc", ""},
+		{"malformed product ID", http.MethodGet, "/api/admin/inventory/products/abc/channels", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, response := serveRule(t, mux, tt.method, tt.path, tt.body); code != http.StatusBadRequest || response.Success {
+				t.Fatalf("got %d %+v, want 400", code, response)
+			}
+		})
+	}
+
+	// A marketplace has one rule per product
+	body := `{"integration_id":"n11","percentage":50}`
+	if code, _ := serveRule(t, mux, http.MethodPost, "/api/admin/inventory/allocation-rules", body); code != http.StatusCreated {
+		t.Fatalf("create = %d", code)
+	}
+	if code, _ := serveRule(t, mux, http.MethodPost, "/api/admin/inventory/allocation-rules", body); code != http.StatusBadRequest {
+		t.Fatalf("duplicate create = %d, want 400", code)
+	}
+
+	if code, _ := serveRule(t, mux, http.MethodGet, "/api/admin/inventory/products/999/channels", ""); code != http.StatusNotFound {
+		t.Fatalf("channels of a missing product = %d, want 404", code)
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+)
+
+// MarketplaceCatalogHandler handles the marketplace category trees and
+// attribute schemas, and the mappings of catalog categories and product
+// attributes to them. Sellers keep their own mappings; admins keep the
+// platform's, which apply to every vendor without one.
+type MarketplaceCatalogHandler struct {
+	*Handler
+	catalogService *services.MarketplaceCatalogService
+	vendorService  *services.VendorService
+	productService *services.ProductService
+}
+
+// NewMarketplaceCatalogHandler creates a new marketplace catalog handler
+func NewMarketplaceCatalogHandler(h *Handler, catalogService *services.MarketplaceCatalogService, vendorService *services.VendorService, productService *services.ProductService) *MarketplaceCatalogHandler {
+	return &MarketplaceCatalogHandler{
+		Handler:        h,
+		catalogService: catalogService,
+		vendorService:  vendorService,
+		productService: productService,
+	}
+}
+
+// SearchCategories returns the leaf categories of a marketplace whose path
+// contains the q query parameter
+func (h *MarketplaceCatalogHandler) SearchCategories(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.currentVendorID(w, r); !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	categories, err := h.catalogService.SearchCategories(r.PathValue("integration"), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		h.writeError(w, err, "Pazaryeri kategorileri alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"categories": categories})
+}
+
+// GetCategoryAttributes returns the attribute schema of a marketplace
+// category
+func (h *MarketplaceCatalogHandler) GetCategoryAttributes(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.currentVendorID(w, r); !ok {
+		return
+	}
+	attributes, err := h.catalogService.GetCategoryAttributes(r.PathValue("integration"), r.PathValue("category"))
+	if err != nil {
+		h.writeError(w, err, "Kategori özellikleri alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"attributes": attributes})
+}
+
+// CategoryMappings lists the signed-in seller's category mappings with the
+// platform's on GET and maps a catalog category on POST
+func (h *MarketplaceCatalogHandler) CategoryMappings(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	h.categoryMappings(w, r, vendorID)
+}
+
+// APICategoryMappings lists and creates the platform's category mappings
+// (admin)
+func (h *MarketplaceCatalogHandler) APICategoryMappings(w http.ResponseWriter, r *http.Request) {
+	h.categoryMappings(w, r, 0)
+}
+
+func (h *MarketplaceCatalogHandler) categoryMappings(w http.ResponseWriter, r *http.Request, vendorID int64) {
+	integrationID := r.PathValue("integration")
+	switch r.Method {
+	case http.MethodGet:
+		mappings, err := h.catalogService.GetCategoryMappings(integrationID, vendorID)
+		if err != nil {
+			h.writeError(w, err, "Kategori eşleştirmeleri alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"mappings": mappings})
+	case http.MethodPost:
+		var mapping models.MarketplaceCategory
+		if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		mapping.MarketplaceName = integrationID
+		mapping.VendorID = uint(vendorID)
+		if err := h.catalogService.MapCategory(&mapping); err != nil {
+			h.writeError(w, err, "Kategori eşleştirilemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Kategori eşleştirildi", map[string]interface{}{"mapping": &mapping})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DeleteCategoryMapping removes a category mapping of the signed-in seller
+func (h *MarketplaceCatalogHandler) DeleteCategoryMapping(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	h.deleteCategoryMapping(w, r, vendorID)
+}
+
+// APIDeleteCategoryMapping removes a platform category mapping (admin)
+func (h *MarketplaceCatalogHandler) APIDeleteCategoryMapping(w http.ResponseWriter, r *http.Request) {
+	h.deleteCategoryMapping(w, r, 0)
+}
+
+func (h *MarketplaceCatalogHandler) deleteCategoryMapping(w http.ResponseWriter, r *http.Request, vendorID int64) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz eşleştirme ID", nil)
+		return
+	}
+	if err := h.catalogService.DeleteCategoryMapping(uint(id), vendorID); err != nil {
+		h.writeError(w, err, "Kategori eşleştirmesi silinemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Kategori eşleştirmesi silindi", nil)
+}
+
+// AttributeMappings lists the signed-in seller's attribute mappings of the
+// marketplace category in the category query parameter on GET and saves
+// one on POST
+func (h *MarketplaceCatalogHandler) AttributeMappings(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	h.attributeMappings(w, r, vendorID)
+}
+
+// APIAttributeMappings lists and saves the platform's attribute mappings
+// (admin)
+func (h *MarketplaceCatalogHandler) APIAttributeMappings(w http.ResponseWriter, r *http.Request) {
+	h.attributeMappings(w, r, 0)
+}
+
+func (h *MarketplaceCatalogHandler) attributeMappings(w http.ResponseWriter, r *http.Request, vendorID int64) {
+	integrationID := r.PathValue("integration")
+	switch r.Method {
+	case http.MethodGet:
+		categoryID := r.URL.Query().Get("category")
+		if categoryID == "" {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Pazaryeri kategorisi gerekli", nil)
+			return
+		}
+		mappings, err := h.catalogService.GetAttributeMappings(integrationID, vendorID, categoryID)
+		if err != nil {
+			h.writeError(w, err, "Özellik eşleştirmeleri alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"mappings": mappings})
+	case http.MethodPost:
+		var mapping models.MarketplaceAttributeMapping
+		if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		mapping.IntegrationID = integrationID
+		mapping.VendorID = vendorID
+		if err := h.catalogService.SaveAttributeMapping(&mapping); err != nil {
+			h.writeError(w, err, "Özellik eşleştirilemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Özellik eşleştirildi", map[string]interface{}{"mapping": &mapping})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DeleteAttributeMapping removes an attribute mapping of the signed-in
+// seller
+func (h *MarketplaceCatalogHandler) DeleteAttributeMapping(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	h.deleteAttributeMapping(w, r, vendorID)
+}
+
+// APIDeleteAttributeMapping removes a platform attribute mapping (admin)
+func (h *MarketplaceCatalogHandler) APIDeleteAttributeMapping(w http.ResponseWriter, r *http.Request) {
+	h.deleteAttributeMapping(w, r, 0)
+}
+
+func (h *MarketplaceCatalogHandler) deleteAttributeMapping(w http.ResponseWriter, r *http.Request, vendorID int64) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz eşleştirme ID", nil)
+		return
+	}
+	if err := h.catalogService.DeleteAttributeMapping(id, vendorID); err != nil {
+		h.writeError(w, err, "Özellik eşleştirmesi silinemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Özellik eşleştirmesi silindi", nil)
+}
+
+// SuggestCategories proposes marketplace categories for the product in the
+// product_id query parameter
+func (h *MarketplaceCatalogHandler) SuggestCategories(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	productID, ok := h.vendorProduct(w, r, r.URL.Query().Get("product_id"), vendorID)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	suggestions, err := h.catalogService.SuggestCategories(r.PathValue("integration"), productID, limit)
+	if err != nil {
+		h.writeError(w, err, "Kategori önerileri alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"suggestions": suggestions})
+}
+
+// ValidateProduct checks a product of the signed-in seller against the
+// schema of the marketplace category it maps to, before it is exported.
+// Every missing or unmapped field is listed in errors.
+func (h *MarketplaceCatalogHandler) ValidateProduct(w http.ResponseWriter, r *http.Request) {
+	vendorID, ok := h.currentVendorID(w, r)
+	if !ok {
+		return
+	}
+	productID, ok := h.vendorProduct(w, r, r.PathValue("id"), vendorID)
+	if !ok {
+		return
+	}
+	err := h.catalogService.ValidateProduct(r.PathValue("integration"), productID)
+	var invalid *marketplace.ValidationError
+	if errors.As(err, &invalid) {
+		writeAPIJSON(w, http.StatusOK, true, "Ürün pazaryerine gönderilmeye hazır değil", map[string]interface{}{
+			"valid":  false,
+			"errors": invalid.Errors,
+		})
+		return
+	}
+	if err != nil {
+		h.writeError(w, err, "Ürün doğrulanamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Ürün pazaryerine gönderilmeye hazır", map[string]interface{}{"valid": true})
+}
+
+// APIRefreshCatalogs downloads the category trees and brands of every
+// configured marketplace (admin)
+func (h *MarketplaceCatalogHandler) APIRefreshCatalogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	refreshed, err := h.catalogService.RefreshAll()
+	if err != nil && refreshed == 0 {
+		h.writeError(w, err, "Pazaryeri katalogları güncellenemedi")
+		return
+	}
+	fields := map[string]interface{}{"refreshed": refreshed}
+	if err != nil {
+		fields["errors"] = err.Error()
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Pazaryeri katalogları güncellendi", fields)
+}
+
+// currentVendorID returns the vendor ID of the signed-in seller. It writes
+// the response and returns false if there is none.
+func (h *MarketplaceCatalogHandler) currentVendorID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return 0, false
+	}
+	vendor, err := h.vendorService.GetVendorByUserID(int(userID))
+	if err != nil {
+		writeAPIJSON(w, http.StatusForbidden, false, "Satıcı hesabı bulunamadı", nil)
+		return 0, false
+	}
+	return int64(vendor.ID), true
+}
+
+// vendorProduct parses a product ID and checks that the product belongs to
+// the vendor. It writes the response and returns false otherwise.
+func (h *MarketplaceCatalogHandler) vendorProduct(w http.ResponseWriter, r *http.Request, value string, vendorID int64) (int64, bool) {
+	productID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || productID <= 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz ürün ID", nil)
+		return 0, false
+	}
+	product, err := h.productService.WithContext(r.Context()).GetProductByID(int(productID))
+	if err != nil || int64(product.VendorID) != vendorID {
+		writeAPIJSON(w, http.StatusNotFound, false, "Ürün bulunamadı", nil)
+		return 0, false
+	}
+	return productID, true
+}
+
+// writeError maps catalog errors to a status and a message
+func (h *MarketplaceCatalogHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	status, message := http.StatusInternalServerError, fallback
+	switch {
+	case errors.Is(err, services.ErrCategoryMappingNotFound), errors.Is(err, services.ErrAttributeMappingNotFound):
+		status, message = http.StatusNotFound, "Eşleştirme bulunamadı"
+	case errors.Is(err, services.ErrMarketplaceCategoryNotFound):
+		status, message = http.StatusNotFound, "Pazaryeri kategorisi bulunamadı"
+	case errors.Is(err, services.ErrProductNotFound):
+		status, message = http.StatusNotFound, "Ürün bulunamadı"
+	case errors.Is(err, services.ErrInvalidCatalogMapping):
+		status, message = http.StatusBadRequest, fallback+": "+err.Error()
+	case errors.Is(err, services.ErrNoCategorySuggestions):
+		status, message = http.StatusServiceUnavailable, "Kategori önerileri şu anda kullanılamıyor"
+	default:
+		log.Printf("Marketplace catalog request failed: %v", err)
+	}
+	writeAPIJSON(w, status, false, message, nil)
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
+	"kolajAi/internal/integrations/marketplace"
 	"kolajAi/internal/models"
 	"kolajAi/internal/services"
 )
@@ -155,9 +157,9 @@ func (h *MarketplaceHandler) SyncProducts(w http.ResponseWriter, r *http.Request
 
 	// Parse request body
 	var req struct {
-		IntegrationID string        `json:"integration_id"`
-		ProductIDs    []int         `json:"product_ids"`
-		Products      []interface{} `json:"products,omitempty"`
+		IntegrationID string                `json:"integration_id"`
+		ProductIDs    []int                 `json:"product_ids"`
+		Products      []marketplace.Listing `json:"products,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -165,18 +167,31 @@ func (h *MarketplaceHandler) SyncProducts(w http.ResponseWriter, r *http.Request
 	}
 
 	// Sync products
-	if err := h.marketplaceService.SyncProducts(req.IntegrationID, req.Products); err != nil {
+	run, err := h.marketplaceService.SyncProductsRun(req.IntegrationID, req.Products)
+	if err != nil {
+		// Listings the marketplace cannot accept are the caller's to fix
+		var validationErr *marketplace.ValidationError
+		if errors.As(err, &validationErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(validationErr)
+			return
+		}
 		Logger.Printf("Error syncing products: %v", err)
 		http.Error(w, "Failed to sync products", http.StatusInternalServerError)
 		return
 	}
 
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	// Return response with the per-product outcome when the sync was recorded
+	response := map[string]interface{}{
 		"success": true,
 		"message": "Products synced successfully",
-	})
+	}
+	if run != nil {
+		response["run"] = run
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetMarketplaceOrders retrieves orders from a marketplace
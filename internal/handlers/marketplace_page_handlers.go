@@ -32,14 +32,14 @@ func NewMarketplacePageHandler(h *Handler, productService *services.ProductServi
 // Index handles marketplace home page
 func (h *MarketplacePageHandler) Index(w http.ResponseWriter, r *http.Request) {
 	// Get categories from database
-	categories, err := h.productService.GetAllCategories()
+	categories, err := h.productService.WithContext(r.Context()).GetAllCategories()
 	if err != nil {
 		log.Printf("Error loading categories: %v", err)
 		categories = []models.Category{} // Empty slice on error
 	}
 
 	// Get featured products
-	featuredProducts, err := h.productService.GetFeaturedProducts(8, 0)
+	featuredProducts, err := h.productService.WithContext(r.Context()).GetFeaturedProducts(8, 0)
 	if err != nil {
 		log.Printf("Error loading featured products: %v", err)
 		featuredProducts = []models.Product{} // Empty slice on error
@@ -79,14 +79,14 @@ func (h *MarketplacePageHandler) Products(w http.ResponseWriter, r *http.Request
 	}
 	
 	// Get products from database
-	products, err := h.productService.GetProducts(category, search, page, limit)
+	products, err := h.productService.WithContext(r.Context()).GetProducts(category, search, page, limit)
 	if err != nil {
 		log.Printf("Error loading products: %v", err)
 		products = []models.Product{} // Empty slice on error
 	}
 	
 	// Get categories for filter
-	categories, err := h.productService.GetAllCategories()
+	categories, err := h.productService.WithContext(r.Context()).GetAllCategories()
 	if err != nil {
 		log.Printf("Error loading categories: %v", err)
 		categories = []models.Category{} // Empty slice on error
@@ -116,14 +116,14 @@ func (h *MarketplacePageHandler) ProductDetail(w http.ResponseWriter, r *http.Re
 	}
 	
 	// Get product from database
-	product, err := h.productService.GetProductByID(id)
+	product, err := h.productService.WithContext(r.Context()).GetProductByID(id)
 	if err != nil {
 		h.HandleError(w, r, err, "Ürün bulunamadı")
 		return
 	}
 	
 	// Get related products
-	relatedProducts, err := h.productService.GetProductsByCategory(product.CategoryID, 4, 0)
+	relatedProducts, err := h.productService.WithContext(r.Context()).GetProductsByCategory(product.CategoryID, 4, 0)
 	if err != nil {
 		log.Printf("Error loading related products: %v", err)
 		relatedProducts = []models.Product{} // Empty slice on error
@@ -194,7 +194,7 @@ func (h *MarketplacePageHandler) AuctionDetail(w http.ResponseWriter, r *http.Re
 // Categories handles marketplace categories page
 func (h *MarketplacePageHandler) Categories(w http.ResponseWriter, r *http.Request) {
 	// Get all categories
-	categories, err := h.productService.GetAllCategories()
+	categories, err := h.productService.WithContext(r.Context()).GetAllCategories()
 	if err != nil {
 		log.Printf("Error loading categories: %v", err)
 		categories = []models.Category{} // Empty slice on error
@@ -226,7 +226,7 @@ func (h *MarketplacePageHandler) Search(w http.ResponseWriter, r *http.Request)
 	if query != "" {
 		// Search products
 		var err error
-		products, err = h.productService.GetProducts("", query, page, limit)
+		products, err = h.productService.WithContext(r.Context()).GetProducts("", query, page, limit)
 		if err != nil {
 			log.Printf("Error searching products: %v", err)
 			products = []models.Product{} // Empty slice on error
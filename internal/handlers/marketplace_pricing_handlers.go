@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+)
+
+// MarketplacePricingHandler handles the price rules and promotions that
+// set the price each marketplace is offered a product at, and previews and
+// runs repricing. All of its endpoints are admin only.
+type MarketplacePricingHandler struct {
+	*Handler
+	repricing *services.RepricingService
+}
+
+// NewMarketplacePricingHandler creates a new marketplace pricing handler
+func NewMarketplacePricingHandler(h *Handler, repricing *services.RepricingService) *MarketplacePricingHandler {
+	return &MarketplacePricingHandler{
+		Handler:   h,
+		repricing: repricing,
+	}
+}
+
+// APIPriceRules lists the price rules, of the marketplace in the
+// integration query parameter if given, on GET and saves one on POST. A
+// rule for the scope of an existing one replaces it.
+func (h *MarketplacePricingHandler) APIPriceRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := h.repricing.GetRules(r.URL.Query().Get("integration"))
+		if err != nil {
+			h.writeError(w, err, "Fiyat kuralları alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"rules": rules})
+	case http.MethodPost:
+		var rule models.MarketplacePriceRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		if err := h.repricing.SaveRule(&rule); err != nil {
+			h.writeError(w, err, "Fiyat kuralı kaydedilemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Fiyat kuralı kaydedildi", map[string]interface{}{"rule": &rule})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// APIPriceRule returns a price rule on GET, updates it on PUT and removes it
+// on DELETE. An update changes only the fields in the body; the marketplace,
+// vendor, category and product a rule applies to are fixed.
+func (h *MarketplacePricingHandler) APIPriceRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz kural ID", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rule, err := h.repricing.GetRule(id)
+		if err != nil {
+			h.writeError(w, err, "Fiyat kuralı alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"rule": rule})
+	case http.MethodPut:
+		rule, err := h.repricing.GetRule(id)
+		if err != nil {
+			h.writeError(w, err, "Fiyat kuralı güncellenemedi")
+			return
+		}
+		scope := *rule
+		if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		rule.ID, rule.IntegrationID, rule.VendorID = id, scope.IntegrationID, scope.VendorID
+		rule.CategoryID, rule.ProductID = scope.CategoryID, scope.ProductID
+		if err := h.repricing.SaveRule(rule); err != nil {
+			h.writeError(w, err, "Fiyat kuralı güncellenemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Fiyat kuralı güncellendi", map[string]interface{}{"rule": rule})
+	case http.MethodDelete:
+		if err := h.repricing.DeleteRule(id); err != nil {
+			h.writeError(w, err, "Fiyat kuralı silinemedi")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "Fiyat kuralı silindi", nil)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// APIPricePromotions lists the promotions of the product in the product_id
+// query parameter that have not ended on GET and schedules one on POST
+func (h *MarketplacePricingHandler) APIPricePromotions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		productID, err := strconv.ParseInt(r.URL.Query().Get("product_id"), 10, 64)
+		if err != nil || productID <= 0 {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz ürün ID", nil)
+			return
+		}
+		promotions, err := h.repricing.GetPromotions(productID)
+		if err != nil {
+			h.writeError(w, err, "Fiyat kampanyaları alınamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"promotions": promotions})
+	case http.MethodPost:
+		var promotion models.MarketplacePricePromotion
+		if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+			return
+		}
+		if err := h.repricing.SavePromotion(&promotion); err != nil {
+			h.writeError(w, err, "Fiyat kampanyası oluşturulamadı")
+			return
+		}
+		writeAPIJSON(w, http.StatusCreated, true, "Fiyat kampanyası oluşturuldu", map[string]interface{}{"promotion": &promotion})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// APIDeletePricePromotion removes a promotion. The regular price returns on
+// the next repricing run.
+func (h *MarketplacePricingHandler) APIDeletePricePromotion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz kampanya ID", nil)
+		return
+	}
+	if err := h.repricing.DeletePromotion(id); err != nil {
+		h.writeError(w, err, "Fiyat kampanyası silinemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Fiyat kampanyası silindi", nil)
+}
+
+// APIPreviewPrices returns the prices of a marketplace that would change,
+// without sending anything. The products query parameter limits it to a
+// comma separated list of product IDs.
+func (h *MarketplacePricingHandler) APIPreviewPrices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	productIDs, ok := parseProductIDs(w, r.URL.Query().Get("products"))
+	if !ok {
+		return
+	}
+	result, err := h.repricing.Preview(r.PathValue("integration"), productIDs...)
+	if err != nil {
+		h.writeError(w, err, "Fiyat önizlemesi oluşturulamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"result": result})
+}
+
+// APIReprice pushes the prices of a marketplace that changed. The products
+// query parameter limits it to a comma separated list of product IDs.
+func (h *MarketplacePricingHandler) APIReprice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	productIDs, ok := parseProductIDs(w, r.URL.Query().Get("products"))
+	if !ok {
+		return
+	}
+	result, err := h.repricing.Reprice(r.PathValue("integration"), productIDs...)
+	if result == nil {
+		h.writeError(w, err, "Fiyatlar gönderilemedi")
+		return
+	}
+	fields := map[string]interface{}{"result": result}
+	if err != nil {
+		fields["errors"] = err.Error()
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Fiyatlar güncellendi", fields)
+}
+
+// parseProductIDs parses a comma separated list of product IDs. It writes
+// the response and returns false if one is invalid.
+func parseProductIDs(w http.ResponseWriter, value string) ([]int64, bool) {
+	var ids []int64
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || id <= 0 {
+			writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz ürün ID", nil)
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// writeError maps repricing errors to a status and a message
+func (h *MarketplacePricingHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	status, message := http.StatusInternalServerError, fallback
+	switch {
+	case errors.Is(err, services.ErrPriceRuleNotFound):
+		status, message = http.StatusNotFound, "Fiyat kuralı bulunamadı"
+	case errors.Is(err, services.ErrPricePromotionNotFound):
+		status, message = http.StatusNotFound, "Fiyat kampanyası bulunamadı"
+	case errors.Is(err, services.ErrProductNotFound):
+		status, message = http.StatusNotFound, "Ürün bulunamadı"
+	case errors.Is(err, services.ErrInvalidPriceRule), errors.Is(err, services.ErrInvalidPricePromotion):
+		status, message = http.StatusBadRequest, fallback+": "+err.Error()
+	default:
+		log.Printf("Marketplace pricing request failed: %v", err)
+	}
+	writeAPIJSON(w, status, false, message, nil)
+}
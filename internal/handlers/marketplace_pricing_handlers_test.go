@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+	"kolajAi/internal/testutil"
+)
+
+// newTestPricingMux serves the pricing endpoints on an in-memory database
+// with one product priced at 100. No marketplace can be reached, so a
+// preview that tried to send prices would fail.
+func newTestPricingMux(t *testing.T) (*http.ServeMux, int64) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	repricing, err := services.NewRepricingService(repo, services.RepricingConfig{
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			return nil, errors.New("no marketplaces in tests")
+		},
+		IntegrationIDs: []string{"trendyol"},
+		Logger:         testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewMarketplacePricingHandler(&Handler{}, repricing)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/marketplace/price-rules", h.APIPriceRules)
+	mux.HandleFunc("/api/admin/marketplace/price-rules/{id}", h.APIPriceRule)
+	mux.HandleFunc("/api/admin/marketplace/price-promotions", h.APIPricePromotions)
+	mux.HandleFunc("/api/admin/marketplace/price-promotions/{id}", h.APIDeletePricePromotion)
+	mux.HandleFunc("/api/admin/marketplace/pricing/{integration}/preview", h.APIPreviewPrices)
+	mux.HandleFunc("/api/admin/marketplace/pricing/{integration}/reprice", h.APIReprice)
+	return mux, seedPricedProduct(t, repo)
+}
+
+func seedPricedProduct(t *testing.T, repo database.SimpleRepository) int64 {
+	t.Helper()
+	exec := func(query string, args ...interface{}) int64 {
+		result, err := repo.Exec(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	userID := exec(`INSERT INTO users (name, email, password) VALUES ('Satıcı', 'seller@example.com', 'x')`)
+	vendorID := exec(`INSERT INTO vendors (user_id, company_name, status, commission_rate) VALUES (?, 'Satıcı', 'active', 10)`, userID)
+	categoryID := exec(`INSERT INTO categories (name, slug) VALUES ('Genel', 'genel')`)
+	return exec(`INSERT INTO products (vendor_id, category_id, name, sku, price, stock, status) VALUES (?, ?, 'Ürün', 'SKU-1', 100, 5, ?)`,
+		vendorID, categoryID, services.ProductStatusActive)
+}
+
+type pricingResponse struct {
+	Success    bool                               `json:"success"`
+	Message    string                             `json:"message"`
+	Rule       models.MarketplacePriceRule        `json:"rule"`
+	Rules      []models.MarketplacePriceRule      `json:"rules"`
+	Promotion  models.MarketplacePricePromotion   `json:"promotion"`
+	Promotions []models.MarketplacePricePromotion `json:"promotions"`
+	Result     services.RepricingResult           `json:"result"`
+}
+
+func servePricing(t *testing.T, mux *http.ServeMux, method, path, body string) (int, pricingResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var response pricingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: %v (body %q)", method, path, err, rec.Body.String())
+	}
+	return rec.Code, response
+}
+
+func TestPriceRuleEndpoints(t *testing.T) {
+	mux, _ := newTestPricingMux(t)
+
+	code, created := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/price-rules",
+		`{"integration_id":"trendyol","markup_percent":20,"rounding":"whole","is_active":true}`)
+	if code != http.StatusOK || created.Rule.ID == 0 {
+		t.Fatalf("create = %d %+v", code, created)
+	}
+	code, listed := servePricing(t, mux, http.MethodGet, "/api/admin/marketplace/price-rules?integration=trendyol", "")
+	if code != http.StatusOK || len(listed.Rules) != 1 || listed.Rules[0].ID != created.Rule.ID {
+		t.Fatalf("list = %d %+v", code, listed.Rules)
+	}
+
+	// An update changes the fields in the body only and keeps the scope of
+	// the rule
+	path := "/api/admin/marketplace/price-rules/" + strconv.FormatInt(created.Rule.ID, 10)
+	if code, updated := servePricing(t, mux, http.MethodPut, path, `{"integration_id":"n11","commission_percent":10}`); code != http.StatusOK {
+		t.Fatalf("update = %d %+v", code, updated)
+	}
+	code, got := servePricing(t, mux, http.MethodGet, path, "")
+	if code != http.StatusOK || got.Rule.IntegrationID != "trendyol" || got.Rule.CommissionPercent != 10 ||
+		got.Rule.MarkupPercent != 20 || got.Rule.Rounding != models.PriceRoundingWhole {
+		t.Fatalf("get after update = %d %+v", code, got.Rule)
+	}
+	if code, _ := servePricing(t, mux, http.MethodPut, path, `{"commission_percent":100}`); code != http.StatusBadRequest {
+		t.Fatalf("invalid update = %d, want 400", code)
+	}
+
+	if code, _ := servePricing(t, mux, http.MethodDelete, path, ""); code != http.StatusOK {
+		t.Fatalf("delete = %d", code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if code, response := servePricing(t, mux, method, path, `{}`); code != http.StatusNotFound || response.Success {
+			t.Fatalf("%s of a deleted rule = %d %+v", method, code, response)
+		}
+	}
+	if code, _ := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/price-rules", `{"integration_id":"trendyol","rounding":"95"}`); code != http.StatusBadRequest {
+		t.Fatalf("create with an unknown rounding = %d, want 400", code)
+	}
+}
+
+func TestPricePromotionEndpoints(t *testing.T) {
+	mux, productID := newTestPricingMux(t)
+	now := time.Now().UTC()
+	body, _ := json.Marshal(models.MarketplacePricePromotion{ProductID: productID, DiscountPercent: 10,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+
+	code, created := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/price-promotions", string(body))
+	if code != http.StatusCreated || created.Promotion.ID == 0 {
+		t.Fatalf("create = %d %+v", code, created)
+	}
+	listPath := "/api/admin/marketplace/price-promotions?product_id=" + strconv.FormatInt(productID, 10)
+	if code, listed := servePricing(t, mux, http.MethodGet, listPath, ""); code != http.StatusOK || len(listed.Promotions) != 1 {
+		t.Fatalf("list = %d %+v", code, listed.Promotions)
+	}
+	if code, _ := servePricing(t, mux, http.MethodGet, "/api/admin/marketplace/price-promotions", ""); code != http.StatusBadRequest {
+		t.Fatalf("list without a product = %d, want 400", code)
+	}
+
+	ended, _ := json.Marshal(models.MarketplacePricePromotion{ProductID: productID, Price: 80,
+		StartsAt: now, EndsAt: now.Add(-time.Hour)})
+	if code, _ := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/price-promotions", string(ended)); code != http.StatusBadRequest {
+		t.Fatalf("create ending before it starts = %d, want 400", code)
+	}
+
+	path := "/api/admin/marketplace/price-promotions/" + strconv.FormatInt(created.Promotion.ID, 10)
+	if code, _ := servePricing(t, mux, http.MethodDelete, path, ""); code != http.StatusOK {
+		t.Fatalf("delete = %d", code)
+	}
+	if code, _ := servePricing(t, mux, http.MethodDelete, path, ""); code != http.StatusNotFound {
+		t.Fatalf("second delete = %d, want 404", code)
+	}
+}
+
+func TestPreviewPricesEndpoint(t *testing.T) {
+	mux, productID := newTestPricingMux(t)
+	if code, _ := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/price-rules",
+		`{"integration_id":"trendyol","markup_percent":20,"is_active":true}`); code != http.StatusOK {
+		t.Fatalf("create rule = %d", code)
+	}
+
+	// The preview computes the new price without reaching the marketplace
+	path := "/api/admin/marketplace/pricing/trendyol/preview?products=" + strconv.FormatInt(productID, 10)
+	code, preview := servePricing(t, mux, http.MethodGet, path, "")
+	if code != http.StatusOK || !preview.Result.DryRun || len(preview.Result.Changes) != 1 ||
+		preview.Result.Changes[0].Price != 120 || preview.Result.Pushed != 0 {
+		t.Fatalf("preview = %d %+v", code, preview.Result)
+	}
+
+	if code, _ := servePricing(t, mux, http.MethodGet, "/api/admin/marketplace/pricing/trendyol/preview?products=1,x", ""); code != http.StatusBadRequest {
+		t.Fatalf("preview with a malformed product = %d, want 400", code)
+	}
+
+	// Repricing does try to send, and reports the marketplace's error
+	code, repriced := servePricing(t, mux, http.MethodPost, "/api/admin/marketplace/pricing/trendyol/reprice", "")
+	if code != http.StatusOK || repriced.Result.DryRun || repriced.Result.Pushed != 0 || len(repriced.Result.Changes) != 1 {
+		t.Fatalf("reprice = %d %+v", code, repriced.Result)
+	}
+}
@@ -2,26 +2,32 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"kolajAi/internal/integrations/payment"
 	"kolajAi/internal/services"
 )
 
 // PaymentHandler handles payment-related requests
 type PaymentHandler struct {
 	*Handler
-	paymentService *services.PaymentService
-	orderService   *services.OrderService
+	paymentService  *services.PaymentService
+	orderService    *services.OrderService
+	checkoutService *services.CheckoutService
 }
 
-// NewPaymentHandler creates a new payment handler
-func NewPaymentHandler(h *Handler, paymentService *services.PaymentService, orderService *services.OrderService) *PaymentHandler {
+// NewPaymentHandler creates a new payment handler. The checkout service
+// applies 3-D Secure results and gateway webhooks to the orders.
+func NewPaymentHandler(h *Handler, paymentService *services.PaymentService, orderService *services.OrderService, checkoutService *services.CheckoutService) *PaymentHandler {
 	return &PaymentHandler{
-		Handler:        h,
-		paymentService: paymentService,
-		orderService:   orderService,
+		Handler:         h,
+		paymentService:  paymentService,
+		orderService:    orderService,
+		checkoutService: checkoutService,
 	}
 }
 
@@ -251,7 +257,7 @@ func (h *PaymentHandler) PaymentPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get real order details from database
-	order, err := h.orderService.GetOrderByID(int(orderID))
+	order, err := h.orderService.WithContext(r.Context()).GetOrderByID(int(orderID))
 	if err != nil {
 		Logger.Printf("Error getting order details: %v", err)
 		http.Error(w, "Order not found", http.StatusNotFound)
@@ -265,4 +271,88 @@ func (h *PaymentHandler) PaymentPage(w http.ResponseWriter, r *http.Request) {
 	data["PaymentMethods"] = h.paymentService.GetSupportedPaymentMethods()
 
 	h.RenderTemplate(w, r, "payment/checkout", data)
-}
\ No newline at end of file
+}
+
+// Complete3DSecure handles the 3-D Secure result the bank posts back for an
+// order's card payment, confirms or releases the order and sends the
+// customer to the outcome page
+func (h *PaymentHandler) Complete3DSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.checkoutService == nil {
+		http.Error(w, "Checkout is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	orderID, err := strconv.ParseInt(r.URL.Query().Get("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	verificationData := make(map[string]string, len(r.PostForm))
+	for key := range r.PostForm {
+		verificationData[key] = r.PostForm.Get(key)
+	}
+
+	response, err := h.checkoutService.Complete3DSecure(orderID, verificationData)
+	if errors.Is(err, services.ErrOrderNotPending) {
+		http.Error(w, "Order is not awaiting payment", http.StatusConflict)
+		return
+	}
+	if err != nil || response.Status == services.PaymentStatusFailed {
+		query := url.Values{"order_id": {strconv.FormatInt(orderID, 10)}}
+		if response != nil {
+			query.Set("transaction_id", response.TransactionID)
+			query.Set("reason", response.FailureReason)
+		}
+		if err != nil {
+			Logger.Printf("Error completing 3-D Secure payment of order %d: %v", orderID, err)
+			query.Set("reason", "Payment could not be completed")
+		}
+		http.Redirect(w, r, "/payment/failure?"+query.Encode(), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/payment/success?transaction_id="+url.QueryEscape(response.TransactionID), http.StatusSeeOther)
+}
+
+// PaymentWebhook applies a payment status change reported by the payment
+// gateway to the payment and its order. Webhooks that fail verification are
+// rejected; other failures are answered with an error so the gateway sends
+// them again.
+func (h *PaymentHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.checkoutService == nil {
+		http.Error(w, "Checkout is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload payment.WebhookPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.checkoutService.HandlePaymentWebhook(&payload); err != nil {
+		if errors.Is(err, services.ErrInvalidWebhook) {
+			http.Error(w, "Invalid webhook", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrGatewayNotConfigured) {
+			http.Error(w, "No payment gateway is configured", http.StatusServiceUnavailable)
+			return
+		}
+		Logger.Printf("Error handling payment webhook %s: %v", payload.ID, err)
+		http.Error(w, "Webhook could not be processed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
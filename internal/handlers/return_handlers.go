@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+)
+
+// ReturnHandler handles return (RMA) requests of customers, sellers and
+// admins
+type ReturnHandler struct {
+	*Handler
+	returnService *services.ReturnService
+	vendorService *services.VendorService
+}
+
+// NewReturnHandler creates a new return handler
+func NewReturnHandler(h *Handler, returnService *services.ReturnService, vendorService *services.VendorService) *ReturnHandler {
+	return &ReturnHandler{
+		Handler:       h,
+		returnService: returnService,
+		vendorService: vendorService,
+	}
+}
+
+// Returns lists the signed-in customer's returns on GET and requests a
+// return on POST
+func (h *ReturnHandler) Returns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetReturns(w, r)
+	case http.MethodPost:
+		h.RequestReturn(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetReturns lists the signed-in customer's returns, newest first
+func (h *ReturnHandler) GetReturns(w http.ResponseWriter, r *http.Request) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	limit, offset := listPage(r)
+	returns, err := h.returnService.GetUserReturns(userID, limit, offset)
+	if err != nil {
+		h.writeError(w, err, "İade talepleri alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"returns": returns})
+}
+
+// RequestReturn requests the return of items of a delivered order. The
+// order may be a multi-vendor order; the return is booked against the
+// sub-order of the vendor whose items are returned.
+func (h *ReturnHandler) RequestReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+
+	var request services.CreateReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.OrderID == 0 {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	request.UserID = userID
+	ret, err := h.returnService.RequestReturn(&request)
+	if err != nil {
+		h.writeError(w, err, "İade talebi oluşturulamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, true, "İade talebiniz alındı", map[string]interface{}{"return": ret})
+}
+
+// GetReturn returns a return with its items to its customer or vendor
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	ret, err := h.returnService.GetReturn(returnID)
+	if err != nil {
+		h.writeError(w, err, "İade talebi alınamadı")
+		return
+	}
+	if ret.UserID != userID {
+		vendor, err := h.vendorService.GetVendorByUserID(int(userID))
+		if err != nil || int64(vendor.ID) != ret.VendorID {
+			h.writeError(w, services.ErrReturnNotFound, "")
+			return
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"return": ret})
+}
+
+// CancelReturn withdraws a return the customer has not shipped yet
+func (h *ReturnHandler) CancelReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := h.returnService.CancelReturn(returnID, userID); err != nil {
+		h.writeError(w, err, "İade talebi iptal edilemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "İade talebiniz iptal edildi", nil)
+}
+
+// ShipReturn records the tracking number the customer sent the goods with
+func (h *ReturnHandler) ShipReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		TrackingNumber string `json:"tracking_number"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	if err := h.returnService.ShipReturn(returnID, userID, request.TrackingNumber); err != nil {
+		h.writeError(w, err, "Kargo bilgisi kaydedilemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "Kargo bilgisi kaydedildi", nil)
+}
+
+// SellerReturns lists the returns of the signed-in seller, optionally
+// filtered by status
+func (h *ReturnHandler) SellerReturns(w http.ResponseWriter, r *http.Request) {
+	vendor, ok := h.currentVendor(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := listPage(r)
+	returns, err := h.returnService.GetVendorReturns(int64(vendor.ID), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		h.writeError(w, err, "İade talepleri alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "", map[string]interface{}{"returns": returns})
+}
+
+// ApproveReturn accepts a return and creates its return shipment (seller)
+func (h *ReturnHandler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	vendor, ok := h.currentVendor(w, r)
+	if !ok {
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Note string `json:"note"`
+	}
+	json.NewDecoder(r.Body).Decode(&request)
+	ret, err := h.returnService.ApproveReturn(returnID, int64(vendor.ID), request.Note)
+	if err != nil {
+		h.writeError(w, err, "İade talebi onaylanamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "İade talebi onaylandı", map[string]interface{}{"return": ret})
+}
+
+// RejectReturn turns a return down with a reason (seller)
+func (h *ReturnHandler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	vendor, ok := h.currentVendor(w, r)
+	if !ok {
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	ret, err := h.returnService.RejectReturn(returnID, int64(vendor.ID), request.Note)
+	if err != nil {
+		h.writeError(w, err, "İade talebi reddedilemedi")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "İade talebi reddedildi", map[string]interface{}{"return": ret})
+}
+
+// ReceiveReturn records the condition of each returned item (seller)
+func (h *ReturnHandler) ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	vendor, ok := h.currentVendor(w, r)
+	if !ok {
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		Items []services.ReceivedItem `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	ret, err := h.returnService.ReceiveReturn(returnID, int64(vendor.ID), request.Items)
+	if err != nil {
+		h.writeError(w, err, "İade teslim alınamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "İade teslim alındı", map[string]interface{}{"return": ret})
+}
+
+// APIRefundReturn refunds a received return (admin)
+func (h *ReturnHandler) APIRefundReturn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	returnID, ok := returnIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	ret, err := h.returnService.RefundReturn(returnID)
+	if err != nil {
+		h.writeError(w, err, "İade ödemesi yapılamadı")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, true, "İade ödemesi yapıldı", map[string]interface{}{"return": ret})
+}
+
+// currentVendor returns the vendor of the signed-in seller. It writes the
+// response and returns false if there is none.
+func (h *ReturnHandler) currentVendor(w http.ResponseWriter, r *http.Request) (*models.Vendor, bool) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeAPIJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return nil, false
+	}
+	vendor, err := h.vendorService.GetVendorByUserID(int(userID))
+	if err != nil {
+		writeAPIJSON(w, http.StatusForbidden, false, "Satıcı hesabı bulunamadı", nil)
+		return nil, false
+	}
+	return vendor, true
+}
+
+// writeError maps return errors to a status and a message
+func (h *ReturnHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	status, message := http.StatusInternalServerError, fallback
+	switch {
+	case errors.Is(err, services.ErrReturnNotFound):
+		status, message = http.StatusNotFound, "İade talebi bulunamadı"
+	case errors.Is(err, services.ErrReturnForbidden):
+		status, message = http.StatusForbidden, "Bu iade talebi üzerinde işlem yapamazsınız"
+	case errors.Is(err, services.ErrOrderNotReturnable):
+		status, message = http.StatusConflict, "Bu sipariş iade edilemez"
+	case errors.Is(err, services.ErrReturnWindowClosed):
+		status, message = http.StatusConflict, "İade süresi dolmuş"
+	case errors.Is(err, services.ErrReturnQuantityExceeded):
+		status, message = http.StatusConflict, "İade adedi iade edilebilir adedi aşıyor"
+	case errors.Is(err, services.ErrInvalidReturnStatus):
+		status, message = http.StatusConflict, "İade talebi bu işlem için uygun durumda değil"
+	case errors.Is(err, services.ErrNoPaymentReference):
+		status, message = http.StatusConflict, "Siparişin iade edilecek ödemesi bulunamadı"
+	case errors.Is(err, services.ErrReturnConditionRequired), errors.Is(err, services.ErrInvalidReturn):
+		status, message = http.StatusBadRequest, fallback+": "+err.Error()
+	default:
+		log.Printf("Return request failed: %v", err)
+	}
+	writeAPIJSON(w, status, false, message, nil)
+}
+
+// returnIDFromPath parses the return ID of the request path. It writes the
+// response and returns false if it is invalid.
+func returnIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	returnID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadRequest, false, "Geçersiz iade ID", nil)
+		return 0, false
+	}
+	return returnID, true
+}
+
+// listPage reads the limit and offset of a list request
+func listPage(r *http.Request) (int, int) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -85,7 +85,7 @@ func (h *SellerHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get recent orders from database
-	recentOrders, err := h.OrderService.GetOrdersByVendor(vendor.ID, 5, 0)
+	recentOrders, err := h.OrderService.WithContext(r.Context()).GetOrdersByVendor(vendor.ID, 5, 0)
 	if err != nil {
 		log.Printf("Error getting recent orders: %v", err)
 		recentOrders = []models.Order{} // Empty slice on error
@@ -125,7 +125,7 @@ func (h *SellerHandler) Products(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get vendor products from database
-	products, err := h.ProductService.GetProductsByVendor(vendor.ID, 50, 0)
+	products, err := h.ProductService.WithContext(r.Context()).GetProductsByVendor(vendor.ID, 50, 0)
 	if err != nil {
 		log.Printf("Error getting vendor products: %v", err)
 		products = []models.Product{} // Empty slice on error
@@ -164,7 +164,7 @@ func (h *SellerHandler) Orders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get vendor orders from database
-	orders, err := h.OrderService.GetOrdersByVendor(vendor.ID, 50, 0)
+	orders, err := h.OrderService.WithContext(r.Context()).GetOrdersByVendor(vendor.ID, 50, 0)
 	if err != nil {
 		log.Printf("Error getting vendor orders: %v", err)
 		orders = []models.Order{} // Empty slice on error
@@ -202,7 +202,7 @@ func (h *SellerHandler) APIGetProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get products from database
-	products, err := h.ProductService.GetProductsByVendor(vendor.ID, 50, 0)
+	products, err := h.ProductService.WithContext(r.Context()).GetProductsByVendor(vendor.ID, 50, 0)
 	if err != nil {
 		log.Printf("Error getting vendor products: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -273,7 +273,7 @@ func (h *SellerHandler) APIGetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get orders from database
-	orders, err := h.OrderService.GetOrdersByVendor(vendor.ID, 50, 0)
+	orders, err := h.OrderService.WithContext(r.Context()).GetOrdersByVendor(vendor.ID, 50, 0)
 	if err != nil {
 		log.Printf("Error getting vendor orders: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
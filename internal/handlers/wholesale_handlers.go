@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"kolajAi/internal/models"
+	"kolajAi/internal/services"
+)
+
+// WholesaleHandler handles wholesale (B2B) requests
+type WholesaleHandler struct {
+	*Handler
+	wholesaleService *services.WholesaleService
+}
+
+// NewWholesaleHandler creates a new wholesale handler
+func NewWholesaleHandler(h *Handler, wholesaleService *services.WholesaleService) *WholesaleHandler {
+	return &WholesaleHandler{
+		Handler:          h,
+		wholesaleService: wholesaleService,
+	}
+}
+
+// RegisterCustomer applies for a wholesale account for the signed-in user
+func (h *WholesaleHandler) RegisterCustomer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeWholesaleJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return
+	}
+
+	var customer models.WholesaleCustomer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	customer.UserID = int(userID)
+	if err := h.wholesaleService.RegisterCustomer(&customer); err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Toptan satış başvurusu oluşturulamadı: "+err.Error(), nil)
+		return
+	}
+	writeWholesaleJSON(w, http.StatusCreated, true, "Toptan satış başvurunuz alındı", map[string]interface{}{"customer": &customer})
+}
+
+// GetCreditStatus returns the signed-in customer's credit line
+func (h *WholesaleHandler) GetCreditStatus(w http.ResponseWriter, r *http.Request) {
+	customer, ok := h.currentCustomer(w, r)
+	if !ok {
+		return
+	}
+	credit, err := h.wholesaleService.GetCreditStatus(customer.ID)
+	if err != nil {
+		h.writeError(w, err, "Kredi durumu alınamadı")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusOK, true, "", map[string]interface{}{"credit": credit})
+}
+
+// Orders lists the signed-in customer's wholesale orders on GET and places
+// an order on POST
+func (h *WholesaleHandler) Orders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetOrders(w, r)
+	case http.MethodPost:
+		h.CreateOrder(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GetOrders lists the signed-in customer's wholesale orders
+func (h *WholesaleHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	customer, ok := h.currentCustomer(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	orders, err := h.wholesaleService.GetCustomerOrders(customer.ID, limit, offset)
+	if err != nil {
+		h.writeError(w, err, "Siparişler alınamadı")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusOK, true, "", map[string]interface{}{"orders": orders})
+}
+
+// CreateOrder places a wholesale order at the customer's price list prices
+func (h *WholesaleHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	customer, ok := h.currentCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Items []services.WholesaleLine `json:"items"`
+		Notes string                   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Items) == 0 {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	order, err := h.wholesaleService.CreateOrder(&services.WholesaleOrderRequest{
+		CustomerID: customer.ID,
+		Items:      request.Items,
+		Notes:      request.Notes,
+	})
+	if err != nil {
+		h.writeError(w, err, "Sipariş oluşturulamadı")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusCreated, true, "Siparişiniz oluşturuldu", map[string]interface{}{"order": order})
+}
+
+// RequestQuote asks a vendor for a quote
+func (h *WholesaleHandler) RequestQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	customer, ok := h.currentCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Items []services.WholesaleLine `json:"items"`
+		Notes string                   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Items) == 0 {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	quote, err := h.wholesaleService.RequestQuote(&services.QuoteRequest{
+		CustomerID: customer.ID,
+		Items:      request.Items,
+		Notes:      request.Notes,
+	})
+	if err != nil {
+		h.writeError(w, err, "Teklif talebi oluşturulamadı")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusCreated, true, "Teklif talebiniz iletildi", map[string]interface{}{"quote": quote})
+}
+
+// AcceptQuote accepts a vendor's offer and turns it into an order
+func (h *WholesaleHandler) AcceptQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	customer, ok := h.currentCustomer(w, r)
+	if !ok {
+		return
+	}
+	quoteID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz teklif ID", nil)
+		return
+	}
+	order, err := h.wholesaleService.AcceptQuote(customer.ID, quoteID)
+	if err != nil {
+		h.writeError(w, err, "Teklif kabul edilemedi")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusCreated, true, "Teklif kabul edildi, siparişiniz oluşturuldu", map[string]interface{}{"order": order})
+}
+
+// APIApproveCustomer approves a wholesale customer with its customer group,
+// credit limit and payment terms (admin)
+func (h *WholesaleHandler) APIApproveCustomer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	customerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz müşteri ID", nil)
+		return
+	}
+	var request struct {
+		Tier         string  `json:"tier"`
+		CreditLimit  float64 `json:"credit_limit"`
+		PaymentTerms int     `json:"payment_terms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz istek", nil)
+		return
+	}
+	adminID := int(h.GetUserIDFromSession(r))
+	if err := h.wholesaleService.ApproveCustomer(customerID, adminID, request.Tier, request.CreditLimit, request.PaymentTerms); err != nil {
+		h.writeError(w, err, "Müşteri onaylanamadı")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusOK, true, "Toptan satış müşterisi onaylandı", nil)
+}
+
+// APIRecordPayment records a payment against a wholesale order (admin)
+func (h *WholesaleHandler) APIRecordPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz sipariş ID", nil)
+		return
+	}
+	var request struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Amount <= 0 {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz ödeme tutarı", nil)
+		return
+	}
+	order, err := h.wholesaleService.RecordPayment(orderID, request.Amount)
+	if err != nil {
+		h.writeError(w, err, "Ödeme kaydedilemedi")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusOK, true, "Ödeme kaydedildi", map[string]interface{}{"order": order})
+}
+
+// APICancelOrder cancels an unpaid wholesale order (admin)
+func (h *WholesaleHandler) APICancelOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	orderID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeWholesaleJSON(w, http.StatusBadRequest, false, "Geçersiz sipariş ID", nil)
+		return
+	}
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&request)
+	if err := h.wholesaleService.CancelOrder(orderID, request.Reason); err != nil {
+		h.writeError(w, err, "Sipariş iptal edilemedi")
+		return
+	}
+	writeWholesaleJSON(w, http.StatusOK, true, "Sipariş iptal edildi", nil)
+}
+
+// currentCustomer returns the wholesale customer of the signed-in user. It
+// writes the response and returns false if there is none.
+func (h *WholesaleHandler) currentCustomer(w http.ResponseWriter, r *http.Request) (*models.WholesaleCustomer, bool) {
+	userID := h.GetUserIDFromSession(r)
+	if userID == 0 {
+		writeWholesaleJSON(w, http.StatusUnauthorized, false, "Oturum açmanız gerekiyor", nil)
+		return nil, false
+	}
+	customer, err := h.wholesaleService.GetCustomerByUser(int(userID))
+	if err != nil {
+		h.writeError(w, err, "Toptan satış hesabı alınamadı")
+		return nil, false
+	}
+	return customer, true
+}
+
+// writeError maps wholesale errors to a status and a message
+func (h *WholesaleHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	status, message := http.StatusInternalServerError, fallback
+	switch {
+	case errors.Is(err, services.ErrWholesaleCustomerNotFound):
+		status, message = http.StatusNotFound, "Toptan satış hesabı bulunamadı"
+	case errors.Is(err, services.ErrWholesaleOrderNotFound):
+		status, message = http.StatusNotFound, "Sipariş bulunamadı"
+	case errors.Is(err, services.ErrQuoteNotFound):
+		status, message = http.StatusNotFound, "Teklif bulunamadı"
+	case errors.Is(err, services.ErrWholesaleNotApproved):
+		status, message = http.StatusForbidden, "Toptan satış hesabınız onaylı değil"
+	case errors.Is(err, services.ErrQuoteForbidden):
+		status, message = http.StatusForbidden, "Bu teklif üzerinde işlem yapamazsınız"
+	case errors.Is(err, services.ErrCreditLimitExceeded):
+		status, message = http.StatusConflict, "Sipariş tutarı kullanılabilir kredi limitinizi aşıyor"
+	case errors.Is(err, services.ErrPaymentOverdue):
+		status, message = http.StatusConflict, "Vadesi geçmiş ödemeleriniz bulunuyor"
+	case errors.Is(err, services.ErrInsufficientStock):
+		status, message = http.StatusConflict, "Yetersiz stok"
+	case errors.Is(err, services.ErrQuoteNotOpen), errors.Is(err, services.ErrQuoteExpired):
+		status, message = http.StatusConflict, "Teklif artık geçerli değil"
+	case errors.Is(err, services.ErrBelowWholesaleMinimum), errors.Is(err, services.ErrMixedVendors):
+		status, message = http.StatusBadRequest, fallback+": "+err.Error()
+	default:
+		log.Printf("Wholesale request failed: %v", err)
+	}
+	writeWholesaleJSON(w, status, false, message, nil)
+}
+
+// writeWholesaleJSON writes a success/message response with extra fields
+func writeWholesaleJSON(w http.ResponseWriter, status int, success bool, message string, fields map[string]interface{}) {
+	response := map[string]interface{}{"success": success}
+	if message != "" {
+		response["message"] = message
+	}
+	for k, v := range fields {
+		response[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
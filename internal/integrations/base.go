@@ -2,6 +2,7 @@ package integrations
 
 import (
 	"context"
+	"net/http"
 	"time"
 	"kolajAi/internal/security"
 )
@@ -171,6 +172,20 @@ type IntegrationProvider interface {
 	Close() error
 }
 
+// HTTPClientSetter is implemented by providers whose HTTP client can be
+// replaced, e.g. with a recording or replaying transport in tests. The
+// client must be set before Initialize, which may already call the API.
+type HTTPClientSetter interface {
+	SetHTTPClient(client *http.Client)
+}
+
+// RateLimiterSetter is implemented by providers whose rate limiter can be
+// replaced. Providers share DefaultRateLimiter unless tests give them a
+// fresh one.
+type RateLimiterSetter interface {
+	SetRateLimiter(limiter *RateLimiter)
+}
+
 // WebhookHandler handles incoming webhooks from integrations
 type WebhookHandler interface {
 	// ValidateWebhook validates the webhook signature/authenticity
@@ -232,6 +247,9 @@ type IntegrationRequest struct {
 	Body      interface{}            `json:"body"`
 	Timeout   time.Duration          `json:"timeout"`
 	Retries   int                    `json:"retries"`
+	// Class is the endpoint class whose rate limit bucket the request
+	// draws from; it defaults to EndpointDefault
+	Class     string                 `json:"class,omitempty"`
 }
 
 // IntegrationResponse represents a response from an integration
@@ -4,6 +4,7 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"io"
 	"sync"
 	"time"
+	"kolajAi/internal/database"
 	"kolajAi/internal/integrations"
 )
 
@@ -31,6 +33,21 @@ type Store interface {
 	List() ([]string, error)
 }
 
+// storedCredentials is the stored form of integrations.Credentials, whose
+// fields are kept out of JSON so they are never exposed
+type storedCredentials struct {
+	APIKey          string            `json:"api_key,omitempty"`
+	APISecret       string            `json:"api_secret,omitempty"`
+	AccessToken     string            `json:"access_token,omitempty"`
+	RefreshToken    string            `json:"refresh_token,omitempty"`
+	ClientID        string            `json:"client_id,omitempty"`
+	ClientSecret    string            `json:"client_secret,omitempty"`
+	AccessKeyID     string            `json:"access_key_id,omitempty"`
+	SecretAccessKey string            `json:"secret_access_key,omitempty"`
+	SellerID        string            `json:"seller_id,omitempty"`
+	Extra           map[string]string `json:"extra,omitempty"`
+}
+
 // cachedCredential holds a credential with expiry time
 type cachedCredential struct {
 	credential integrations.Credentials
@@ -74,10 +91,11 @@ func (m *Manager) GetCredentials(integrationID string) (*integrations.Credential
 	}
 	
 	// Unmarshal
-	var creds integrations.Credentials
-	if err := json.Unmarshal(decryptedData, &creds); err != nil {
+	var stored storedCredentials
+	if err := json.Unmarshal(decryptedData, &stored); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal credentials: %w", err)
 	}
+	creds := integrations.Credentials(stored)
 	
 	// Update cache
 	m.cacheMutex.Lock()
@@ -98,7 +116,7 @@ func (m *Manager) SetCredentials(integrationID string, creds *integrations.Crede
 	}
 	
 	// Marshal
-	data, err := json.Marshal(creds)
+	data, err := json.Marshal(storedCredentials(*creds))
 	if err != nil {
 		return fmt.Errorf("failed to marshal credentials: %w", err)
 	}
@@ -235,6 +253,8 @@ func (m *Manager) validateCredentials(creds *integrations.Credentials) error {
 	hasCredential := false
 	if creds.APIKey != "" || creds.APISecret != "" || 
 	   creds.AccessToken != "" || creds.RefreshToken != "" ||
+	   creds.ClientID != "" || creds.ClientSecret != "" ||
+	   creds.AccessKeyID != "" || creds.SecretAccessKey != "" ||
 	   len(creds.Extra) > 0 {
 		hasCredential = true
 	}
@@ -248,52 +268,74 @@ func (m *Manager) validateCredentials(creds *integrations.Credentials) error {
 
 // DatabaseStore implements Store interface using database
 type DatabaseStore struct {
+	repo      database.SimpleRepository
 	tableName string
-	// In real implementation, this would have a database connection
 }
 
-// NewDatabaseStore creates a new database-backed credential store
-func NewDatabaseStore(tableName string) *DatabaseStore {
+// NewDatabaseStore creates a new database-backed credential store. The
+// table has the integration_id, encrypted_data and updated_at columns of
+// integration_credentials.
+func NewDatabaseStore(repo database.SimpleRepository, tableName string) *DatabaseStore {
 	return &DatabaseStore{
+		repo:      repo,
 		tableName: tableName,
 	}
 }
 
 // Get retrieves encrypted credentials from database
 func (s *DatabaseStore) Get(integrationID string) ([]byte, error) {
-	// In real implementation:
-	// query := "SELECT encrypted_data FROM " + s.tableName + " WHERE integration_id = ?"
-	// return result
-	
-	// For now, return a placeholder
-	return nil, errors.New("not implemented")
+	var encoded string
+	err := s.repo.QueryRow("SELECT encrypted_data FROM "+s.tableName+" WHERE integration_id = ?", integrationID).Scan(&encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("credentials not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
 }
 
 // Set stores encrypted credentials in database
 func (s *DatabaseStore) Set(integrationID string, data []byte) error {
-	// In real implementation:
-	// query := "INSERT INTO " + s.tableName + " (integration_id, encrypted_data, updated_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE encrypted_data = ?, updated_at = ?"
-	// execute query
-	
-	return errors.New("not implemented")
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM "+s.tableName+" WHERE integration_id = ?", integrationID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO "+s.tableName+" (integration_id, encrypted_data, updated_at) VALUES (?, ?, ?)",
+		integrationID, base64.StdEncoding.EncodeToString(data), time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Delete removes credentials from database
 func (s *DatabaseStore) Delete(integrationID string) error {
-	// In real implementation:
-	// query := "DELETE FROM " + s.tableName + " WHERE integration_id = ?"
-	// execute query
-	
-	return errors.New("not implemented")
+	_, err := s.repo.Exec("DELETE FROM "+s.tableName+" WHERE integration_id = ?", integrationID)
+	return err
 }
 
 // List returns all integration IDs from database
 func (s *DatabaseStore) List() ([]string, error) {
-	// In real implementation:
-	// query := "SELECT integration_id FROM " + s.tableName
-	// return results
-	
-	return nil, errors.New("not implemented")
+	rows, err := s.repo.Query("SELECT integration_id FROM " + s.tableName + " ORDER BY integration_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 // MemoryStore implements Store interface using in-memory storage (for testing)
@@ -0,0 +1,52 @@
+package credentials
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"kolajAi/internal/integrations"
+)
+
+func TestCredentialsRoundTrip(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore()
+	manager, err := NewManager(key, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds := &integrations.Credentials{
+		ClientID:        "client",
+		ClientSecret:    "secret",
+		RefreshToken:    "refresh",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "aws-secret",
+		SellerID:        "seller",
+		Extra:           map[string]string{"supplier_id": "42"},
+	}
+	if err := manager.SetCredentials("amazon_tr", creds); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read back from the store, not the cache
+	manager.ClearCache()
+	got, err := manager.GetCredentials("amazon_tr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, creds) {
+		t.Errorf("got %+v, want %+v", got, creds)
+	}
+
+	stored, err := store.Get("amazon_tr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, []byte("aws-secret")) {
+		t.Error("credentials stored in plain text")
+	}
+}
@@ -0,0 +1,492 @@
+// Package httpreplay records the HTTP traffic of integration providers to
+// fixture files and replays it, so provider contracts can be tested offline.
+//
+// A Recorder is an http.RoundTripper. In replay mode it answers requests
+// from a fixture and never touches the network; in record mode it sends
+// them to the live API and writes what it saw to the fixture on Save.
+// Secrets are redacted from requests and responses before anything is
+// written, and the same redaction is applied to live requests before they
+// are matched, so fixtures never contain credentials.
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a recorder replays or records
+type Mode string
+
+// Recorder modes
+const (
+	ModeReplay Mode = "replay"
+	ModeRecord Mode = "record"
+)
+
+// Redacted replaces secret values in fixtures
+const Redacted = "REDACTED"
+
+// Any in a fixture's JSON request body matches any value, for fields such
+// as timestamps that differ between runs
+const Any = "<any>"
+
+// ErrNoInteraction is returned for requests a fixture has no answer for
+var ErrNoInteraction = errors.New("httpreplay: no recorded interaction")
+
+// DefaultRedactedHeaders are the request headers that carry credentials
+var DefaultRedactedHeaders = []string{"Authorization", "X-Amz-Access-Token"}
+
+// DefaultRedactedFields are the JSON and form fields that carry credentials
+// or card data
+var DefaultRedactedFields = []string{
+	"appKey", "appSecret", "apiKey", "secretKey",
+	"client_id", "client_secret", "refresh_token", "access_token",
+	"cardNumber", "cvc",
+}
+
+// Fixture is the recorded traffic of one test scenario
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response it got
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. JSON bodies are kept as JSON to keep
+// fixtures readable; other bodies as text.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	JSON    json.RawMessage   `json:"json,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	JSON    json.RawMessage   `json:"json,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Config holds recorder settings
+type Config struct {
+	Mode Mode
+	// Transport sends requests in record mode. It defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// RedactHeaders and RedactFields default to DefaultRedactedHeaders and
+	// DefaultRedactedFields
+	RedactHeaders []string
+	RedactFields  []string
+}
+
+// Recorder records or replays the HTTP traffic of a fixture file
+type Recorder struct {
+	path      string
+	config    Config
+	mu        sync.Mutex
+	fixture   Fixture
+	used      []bool
+	requests  []*http.Request
+	unmatched []string
+}
+
+// New creates a recorder for a fixture file. In replay mode the fixture is
+// loaded and must exist.
+func New(path string, config Config) (*Recorder, error) {
+	if config.Mode == "" {
+		config.Mode = ModeReplay
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = DefaultRedactedHeaders
+	}
+	if config.RedactFields == nil {
+		config.RedactFields = DefaultRedactedFields
+	}
+
+	r := &Recorder{path: path, config: config}
+	if config.Mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.fixture.Interactions))
+	}
+	return r, nil
+}
+
+// ModeFromEnv returns ModeRecord when HTTPREPLAY is set to record, so
+// fixtures can be refreshed against the live APIs without code changes
+func ModeFromEnv() Mode {
+	if os.Getenv("HTTPREPLAY") == string(ModeRecord) {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Client returns an HTTP client that sends its requests through the
+// recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays a request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.mu.Unlock()
+
+	recorded := r.recordRequest(req, body)
+	if r.config.Mode == ModeRecord {
+		return r.record(req, body, recorded)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.fixture.Interactions {
+		if r.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return interaction.Response.toHTTP(req), nil
+	}
+	r.unmatched = append(r.unmatched, req.Method+" "+req.URL.String())
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, req.Method, req.URL.String())
+}
+
+// record sends a request to the live API and keeps the interaction
+func (r *Recorder) record(req *http.Request, body []byte, recorded Request) (*http.Response, error) {
+	live := req.Clone(req.Context())
+	live.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.config.Transport.RoundTrip(live)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := Response{Status: resp.StatusCode, Headers: make(map[string]string)}
+	for name := range resp.Header {
+		// The body is stored re-encoded, so its recorded length is stale
+		if name == "Content-Length" {
+			continue
+		}
+		response.Headers[name] = resp.Header.Get(name)
+	}
+	response.JSON, response.Body = splitBody(responseBody)
+	if len(response.JSON) > 0 {
+		response.JSON = r.redactRaw(response.JSON)
+	}
+
+	r.mu.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the fixture file. It does
+// nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.config.Mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.fixture); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data.Bytes(), 0o644)
+}
+
+// Requests returns the requests sent through the recorder, as sent, so
+// tests can inspect signatures and headers
+func (r *Recorder) Requests() []*http.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*http.Request(nil), r.requests...)
+}
+
+// Unused returns the recorded interactions that no request matched
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.fixture.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Unmatched returns the requests the fixture had no interaction for
+func (r *Recorder) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
+
+// readBody reads a request body and puts it back for the caller
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// recordRequest converts a request to its redacted fixture form
+func (r *Recorder) recordRequest(req *http.Request, body []byte) Request {
+	recorded := Request{Method: req.Method, URL: req.URL.String(), Headers: make(map[string]string)}
+	for name := range req.Header {
+		value := req.Header.Get(name)
+		if r.redactedHeader(name) {
+			value = Redacted
+		}
+		recorded.Headers[name] = value
+	}
+
+	if len(body) == 0 {
+		return recorded
+	}
+	if json.Valid(body) {
+		recorded.JSON = r.redactRaw(body)
+		return recorded
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for _, field := range r.config.RedactFields {
+				if _, ok := form[field]; ok {
+					form.Set(field, Redacted)
+				}
+			}
+			recorded.Body = form.Encode()
+			return recorded
+		}
+	}
+	recorded.Body = string(body)
+	return recorded
+}
+
+func (r *Recorder) redactedHeader(name string) bool {
+	for _, header := range r.config.RedactHeaders {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactRaw redacts an encoded JSON value
+func (r *Recorder) redactRaw(raw []byte) json.RawMessage {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return raw
+	}
+	redacted, err := json.Marshal(r.redactJSON(value))
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
+
+// redactJSON replaces the values of redacted fields at any depth
+func (r *Recorder) redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			redacted := false
+			for _, name := range r.config.RedactFields {
+				if key == name {
+					redacted = true
+					break
+				}
+			}
+			if redacted {
+				v[key] = Redacted
+			} else {
+				v[key] = r.redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactJSON(v[i])
+		}
+	}
+	return value
+}
+
+// matches reports whether a live request, in its redacted form, is the
+// recorded one. Query parameters may be in any order; JSON bodies are
+// compared by value. Headers are not compared: signatures differ between
+// runs and are verified by the tests themselves.
+func matches(recorded, live Request) bool {
+	if recorded.Method != live.Method {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	liveURL, err := url.Parse(live.URL)
+	if err != nil {
+		return false
+	}
+	if recordedURL.Host != liveURL.Host || recordedURL.Path != liveURL.Path {
+		return false
+	}
+	if !matchValues(recordedURL.Query(), liveURL.Query()) {
+		return false
+	}
+
+	if len(recorded.JSON) > 0 {
+		var want, got interface{}
+		if json.Unmarshal(recorded.JSON, &want) != nil || json.Unmarshal(live.JSON, &got) != nil {
+			return false
+		}
+		return matchJSON(want, got)
+	}
+	if recorded.Body != "" {
+		if form, err := url.ParseQuery(recorded.Body); err == nil && len(form) > 0 {
+			liveForm, err := url.ParseQuery(live.Body)
+			return err == nil && matchValues(form, liveForm)
+		}
+		return recorded.Body == live.Body
+	}
+	return true
+}
+
+func matchValues(want, got url.Values) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for key, values := range want {
+		if len(values) == 1 && values[0] == Any {
+			if _, ok := got[key]; !ok {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(values, got[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchJSON compares decoded JSON values, treating Any as a wildcard
+func matchJSON(want, got interface{}) bool {
+	if s, ok := want.(string); ok && s == Any {
+		return true
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		for key, value := range w {
+			field, ok := g[key]
+			if !ok || !matchJSON(value, field) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(w) != len(g) {
+			return false
+		}
+		for i := range w {
+			if !matchJSON(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// splitBody keeps a JSON body as JSON and anything else as text
+func splitBody(body []byte) (json.RawMessage, string) {
+	if len(body) == 0 {
+		return nil, ""
+	}
+	if json.Valid(body) {
+		var compact bytes.Buffer
+		if json.Compact(&compact, body) == nil {
+			return compact.Bytes(), ""
+		}
+	}
+	return nil, string(body)
+}
+
+// toHTTP builds the response replayed for a request
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if len(r.JSON) > 0 {
+		body = r.JSON
+	}
+	header := make(http.Header)
+	for name, value := range r.Headers {
+		header.Set(name, value)
+	}
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,107 @@
+package httpreplay
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Remaining", "99")
+		io.WriteString(w, `{"access_token":"live-token","items":[{"id":1}]}`)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	recorder, err := New(path, Config{Mode: ModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"auth":{"appKey":"live-key"},"page":1}`
+	req, _ := http.NewRequest("POST", server.URL+"/items?b=2&a=1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer live-secret")
+	resp, err := recorder.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(recorded), "live-token") {
+		t.Errorf("record mode altered the live response: %s", recorded)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture, _ := os.ReadFile(path)
+	for _, secret := range []string{"live-key", "live-secret", "live-token"} {
+		if strings.Contains(string(fixture), secret) {
+			t.Errorf("fixture contains %q", secret)
+		}
+	}
+
+	replayer, err := New(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Query order, credentials and headers do not affect matching
+	req, _ = http.NewRequest("POST", server.URL+"/items?a=1&b=2", strings.NewReader(`{"page":1,"auth":{"appKey":"other-key"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = replayer.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		Items       []struct {
+			ID int `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(replayed, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "99" || len(payload.Items) != 1 || payload.AccessToken != Redacted {
+		t.Errorf("replayed response: %s %v", replayed, resp.Header)
+	}
+	if len(replayer.Unused()) != 0 {
+		t.Errorf("unused interactions: %v", replayer.Unused())
+	}
+
+	// Each interaction answers once
+	req, _ = http.NewRequest("POST", server.URL+"/items?a=1&b=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := replayer.Client().Do(req); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("second request: got %v", err)
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 {
+		t.Errorf("unmatched: got %v", unmatched)
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	recorded := Request{Method: "GET", URL: "https://api.example.com/orders?page=0&startDate=" + Any, JSON: []byte(`{"id":7,"sentAt":"` + Any + `"}`)}
+	live := Request{Method: "GET", URL: "https://api.example.com/orders?startDate=1788220800000&page=0", JSON: []byte(`{"sentAt":"2026-09-01T00:00:00Z","id":7}`)}
+	if !matches(recorded, live) {
+		t.Error("wildcards did not match")
+	}
+	live.JSON = []byte(`{"sentAt":"2026-09-01T00:00:00Z","id":8}`)
+	if matches(recorded, live) {
+		t.Error("different body matched")
+	}
+	live.URL = "https://api.example.com/orders?page=0"
+	live.JSON = []byte(`{"sentAt":"x","id":7}`)
+	if matches(recorded, live) {
+		t.Error("missing wildcard parameter matched")
+	}
+}
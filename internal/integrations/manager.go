@@ -3,33 +3,50 @@ package integrations
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
-	"github.com/sony/gobreaker"
 )
 
-// Manager manages all integrations
+// RequestExecutor is implemented by providers that can send generic
+// requests through the Manager, e.g. to endpoints their typed interface
+// does not cover
+type RequestExecutor interface {
+	ExecuteRequest(ctx context.Context, request IntegrationRequest) (*IntegrationResponse, error)
+}
+
+// Manager routes generic requests, webhooks and health checks to the
+// providers of registered integrations. Providers are created by the
+// integration registry; the Manager adds the circuit breaker, retries,
+// rate limiting, logging, metrics and caching around their calls.
 type Manager struct {
 	integrations    map[string]*Integration
 	providers       map[string]IntegrationProvider
 	webhookHandlers map[string]WebhookHandler
-	circuitBreakers map[string]*gobreaker.CircuitBreaker
+	circuitBreakers map[string]*CircuitBreaker
+	limiter         *RateLimiter
 	logger          IntegrationLogger
 	metrics         IntegrationMetrics
 	cache           IntegrationCache
 	eventBus        IntegrationEventBus
 	mu              sync.RWMutex
 	config          *ManagerConfig
+	stop            chan struct{}
+	stopOnce        sync.Once
 }
 
 // ManagerConfig holds configuration for the integration manager
 type ManagerConfig struct {
-	EnableCircuitBreaker bool
-	EnableCaching        bool
-	EnableMetrics        bool
-	DefaultTimeout       time.Duration
-	HealthCheckInterval  time.Duration
+	EnableCircuitBreaker  bool
+	EnableCaching         bool
+	EnableMetrics         bool
+	DefaultTimeout        time.Duration
+	HealthCheckInterval   time.Duration
 	MaxConcurrentRequests int
+	// RateLimiter throttles requests per integration and endpoint class.
+	// It defaults to DefaultRateLimiter, which the providers' own requests
+	// draw from too.
+	RateLimiter *RateLimiter
 }
 
 // NewManager creates a new integration manager
@@ -44,13 +61,19 @@ func NewManager(config *ManagerConfig) *Manager {
 			MaxConcurrentRequests: 100,
 		}
 	}
+	limiter := config.RateLimiter
+	if limiter == nil {
+		limiter = DefaultRateLimiter
+	}
 
 	return &Manager{
 		integrations:    make(map[string]*Integration),
 		providers:       make(map[string]IntegrationProvider),
 		webhookHandlers: make(map[string]WebhookHandler),
-		circuitBreakers: make(map[string]*gobreaker.CircuitBreaker),
+		circuitBreakers: make(map[string]*CircuitBreaker),
+		limiter:         limiter,
 		config:          config,
+		stop:            make(chan struct{}),
 	}
 }
 
@@ -93,33 +116,26 @@ func (m *Manager) RegisterIntegration(integration *Integration, provider Integra
 
 	// Set up circuit breaker if enabled
 	if m.config.EnableCircuitBreaker {
-		settings := gobreaker.Settings{
-			Name:        integration.ID,
-			MaxRequests: uint32(DefaultCircuitBreakerConfig.HalfOpenMaxCalls),
-			Interval:    DefaultCircuitBreakerConfig.Timeout,
-			Timeout:     DefaultCircuitBreakerConfig.Timeout,
-			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-				return counts.Requests >= uint32(DefaultCircuitBreakerConfig.FailureThreshold) && failureRatio >= 0.6
-			},
-			OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+		m.circuitBreakers[integration.ID] = NewCircuitBreaker(CircuitBreakerConfigNew{
+			Name:          integration.ID,
+			MaxFailures:   DefaultCircuitBreakerConfig.FailureThreshold,
+			ResetTimeout:  DefaultCircuitBreakerConfig.Timeout,
+			HalfOpenCalls: DefaultCircuitBreakerConfig.HalfOpenMaxCalls,
+			OnStateChange: func(name string, from, to CircuitBreakerState) {
 				if m.logger != nil {
 					m.logger.LogError(name, fmt.Errorf("circuit breaker state changed from %s to %s", from, to))
 				}
-				if m.eventBus != nil {
-					m.eventBus.Publish(IntegrationEvent{
-						Type:          "circuit_breaker_state_change",
-						IntegrationID: name,
-						Timestamp:     time.Now(),
-						Data: map[string]interface{}{
-							"from": from.String(),
-							"to":   to.String(),
-						},
-					})
-				}
+				m.publish(IntegrationEvent{
+					Type:          "circuit_breaker_state_change",
+					IntegrationID: name,
+					Timestamp:     time.Now(),
+					Data: map[string]interface{}{
+						"from": from.String(),
+						"to":   to.String(),
+					},
+				})
 			},
-		}
-		m.circuitBreakers[integration.ID] = gobreaker.NewCircuitBreaker(settings)
+		})
 	}
 
 	// Store integration and provider
@@ -127,22 +143,21 @@ func (m *Manager) RegisterIntegration(integration *Integration, provider Integra
 	m.providers[integration.ID] = provider
 
 	// Start health check routine
-	go m.startHealthCheckRoutine(integration.ID)
-
-	// Publish integration registered event
-	if m.eventBus != nil {
-		m.eventBus.Publish(IntegrationEvent{
-			Type:          "integration_registered",
-			IntegrationID: integration.ID,
-			Timestamp:     time.Now(),
-			Data: map[string]interface{}{
-				"name":     integration.Name,
-				"type":     integration.Type,
-				"provider": integration.Provider,
-			},
-		})
+	if m.config.HealthCheckInterval > 0 {
+		go m.startHealthCheckRoutine(integration.ID)
 	}
 
+	m.publish(IntegrationEvent{
+		Type:          "integration_registered",
+		IntegrationID: integration.ID,
+		Timestamp:     time.Now(),
+		Data: map[string]interface{}{
+			"name":     integration.Name,
+			"type":     integration.Type,
+			"provider": integration.Provider,
+		},
+	})
+
 	return nil
 }
 
@@ -200,21 +215,29 @@ func (m *Manager) GetAllIntegrations() []*Integration {
 	return result
 }
 
-// ExecuteRequest executes a request through an integration with circuit breaker and retry logic
+// ExecuteRequest sends a request through an integration's provider, which
+// must implement RequestExecutor. Each attempt waits for the rate limiter
+// bucket of the request's endpoint class, and the response's rate limit
+// headers and Retry-After adapt the bucket. Retryable errors are retried
+// with backoff behind the integration's circuit breaker.
 func (m *Manager) ExecuteRequest(ctx context.Context, integrationID string, request IntegrationRequest) (*IntegrationResponse, error) {
 	integration, err := m.GetIntegration(integrationID)
 	if err != nil {
 		return nil, err
 	}
 
+	m.mu.RLock()
 	provider, exists := m.providers[integrationID]
+	cb := m.circuitBreakers[integrationID]
+	m.mu.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("provider not found for integration %s", integrationID)
 	}
 
-	// Check cache if enabled
-	if m.config.EnableCaching && m.cache != nil {
-		cacheKey := fmt.Sprintf("%s:%s:%s", integrationID, request.Method, request.Endpoint)
+	// Only reads are cached
+	cacheable := m.config.EnableCaching && m.cache != nil && (request.Method == "" || request.Method == http.MethodGet)
+	cacheKey := fmt.Sprintf("%s:%s:%s", integrationID, request.Method, request.Endpoint)
+	if cacheable {
 		if cached, found := m.cache.Get(cacheKey); found {
 			if response, ok := cached.(*IntegrationResponse); ok {
 				return response, nil
@@ -222,23 +245,14 @@ func (m *Manager) ExecuteRequest(ctx context.Context, integrationID string, requ
 		}
 	}
 
-	// Execute with circuit breaker if enabled
 	var response *IntegrationResponse
 	var execErr error
-
-	if m.config.EnableCircuitBreaker {
-		cb, exists := m.circuitBreakers[integrationID]
-		if !exists {
-			return nil, fmt.Errorf("circuit breaker not found for integration %s", integrationID)
-		}
-
+	if cb != nil {
 		result, err := cb.Execute(func() (interface{}, error) {
 			return m.executeWithRetry(ctx, integration, provider, request)
 		})
-
-		if err != nil {
-			execErr = err
-		} else if result != nil {
+		execErr = err
+		if result != nil {
 			response = result.(*IntegrationResponse)
 		}
 	} else {
@@ -272,11 +286,8 @@ func (m *Manager) ExecuteRequest(ctx context.Context, integrationID string, requ
 		}
 	}
 
-	// Cache successful responses if enabled
-	if execErr == nil && response != nil && m.config.EnableCaching && m.cache != nil {
-		cacheKey := fmt.Sprintf("%s:%s:%s", integrationID, request.Method, request.Endpoint)
-		cacheTTL := 5 * time.Minute // Default cache TTL
-		m.cache.Set(cacheKey, response, cacheTTL)
+	if execErr == nil && response != nil && cacheable {
+		m.cache.Set(cacheKey, response, 5*time.Minute)
 	}
 
 	return response, execErr
@@ -284,7 +295,9 @@ func (m *Manager) ExecuteRequest(ctx context.Context, integrationID string, requ
 
 // ProcessWebhook processes an incoming webhook
 func (m *Manager) ProcessWebhook(ctx context.Context, integrationID string, event WebhookEvent) error {
+	m.mu.RLock()
 	handler, exists := m.webhookHandlers[integrationID]
+	m.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("webhook handler not found for integration %s", integrationID)
 	}
@@ -309,24 +322,42 @@ func (m *Manager) ProcessWebhook(ctx context.Context, integrationID string, even
 		m.metrics.RecordWebhook(integrationID, event.Type, true)
 	}
 
-	// Publish webhook processed event
-	if m.eventBus != nil {
-		m.eventBus.Publish(IntegrationEvent{
-			Type:          "webhook_processed",
-			IntegrationID: integrationID,
-			Timestamp:     time.Now(),
-			Data: map[string]interface{}{
-				"webhook_type": event.Type,
-				"webhook_id":   event.ID,
-			},
-		})
-	}
+	m.publish(IntegrationEvent{
+		Type:          "webhook_processed",
+		IntegrationID: integrationID,
+		Timestamp:     time.Now(),
+		Data: map[string]interface{}{
+			"webhook_type": event.Type,
+			"webhook_id":   event.ID,
+		},
+	})
 
 	return nil
 }
 
-// executeWithRetry executes a request with retry logic
+// executeWithRetry sends a request, retrying retryable errors with
+// exponential backoff. Every attempt waits for the rate limiter first.
 func (m *Manager) executeWithRetry(ctx context.Context, integration *Integration, provider IntegrationProvider, request IntegrationRequest) (*IntegrationResponse, error) {
+	executor, ok := provider.(RequestExecutor)
+	if !ok {
+		return nil, &IntegrationError{
+			Code:      "NOT_SUPPORTED",
+			Message:   fmt.Sprintf("provider of %s does not execute generic requests", integration.ID),
+			Provider:  integration.Provider,
+			Timestamp: time.Now(),
+		}
+	}
+
+	class := request.Class
+	if class == "" {
+		class = EndpointDefault
+	}
+	if request.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.Timeout)
+		defer cancel()
+	}
+
 	retryPolicy := DefaultRetryPolicy
 	if request.Retries > 0 {
 		retryPolicy.MaxAttempts = request.Retries
@@ -341,20 +372,20 @@ func (m *Manager) executeWithRetry(ctx context.Context, integration *Integration
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			case <-time.After(delay):
-				// Continue with retry
 			}
 		}
 
-		// Execute the actual request (this would be implemented by specific providers)
-		response := &IntegrationResponse{
-			ID:        request.ID,
-			Duration:  time.Since(time.Now()),
+		if err := m.limiter.Wait(ctx, integration.ID, class, provider.GetRateLimit()); err != nil {
+			return nil, err
 		}
 
-		// For now, just do a health check as example
-		err := provider.HealthCheck(ctx)
+		start := time.Now()
+		response, err := executor.ExecuteRequest(ctx, request)
+		if response != nil {
+			response.Duration = time.Since(start)
+			m.limiter.Observe(integration.ID, class, observedResponse(response), provider.GetRateLimit())
+		}
 		if err == nil {
-			response.StatusCode = 200
 			return response, nil
 		}
 
@@ -376,6 +407,16 @@ func (m *Manager) executeWithRetry(ctx context.Context, integration *Integration
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
+// observedResponse converts a response for the rate limiter, which reads
+// its status code and Retry-After header
+func observedResponse(response *IntegrationResponse) *http.Response {
+	header := make(http.Header, len(response.Headers))
+	for key, value := range response.Headers {
+		header.Set(key, value)
+	}
+	return &http.Response{StatusCode: response.StatusCode, Header: header}
+}
+
 // startHealthCheckRoutine starts a routine to periodically check integration health
 func (m *Manager) startHealthCheckRoutine(integrationID string) {
 	ticker := time.NewTicker(m.config.HealthCheckInterval)
@@ -385,6 +426,8 @@ func (m *Manager) startHealthCheckRoutine(integrationID string) {
 		select {
 		case <-ticker.C:
 			m.performHealthCheck(integrationID)
+		case <-m.stop:
+			return
 		}
 	}
 }
@@ -394,13 +437,15 @@ func (m *Manager) performHealthCheck(integrationID string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	m.mu.RLock()
 	provider, exists := m.providers[integrationID]
+	m.mu.RUnlock()
 	if !exists {
 		return
 	}
 
 	err := provider.HealthCheck(ctx)
-	
+
 	m.mu.Lock()
 	integration, exists := m.integrations[integrationID]
 	if !exists {
@@ -417,20 +462,18 @@ func (m *Manager) performHealthCheck(integrationID string) {
 		integration.Metadata.SuccessCount++
 	}
 	integration.Metadata.LastHealthCheck = time.Now()
+	status := integration.Status
 	m.mu.Unlock()
 
-	// Publish health check event
-	if m.eventBus != nil {
-		m.eventBus.Publish(IntegrationEvent{
-			Type:          "health_check_completed",
-			IntegrationID: integrationID,
-			Timestamp:     time.Now(),
-			Data: map[string]interface{}{
-				"status": integration.Status,
-				"error":  err != nil,
-			},
-		})
-	}
+	m.publish(IntegrationEvent{
+		Type:          "health_check_completed",
+		IntegrationID: integrationID,
+		Timestamp:     time.Now(),
+		Data: map[string]interface{}{
+			"status": status,
+			"error":  err != nil,
+		},
+	})
 }
 
 // UpdateIntegrationConfig updates the configuration of an integration
@@ -512,17 +555,28 @@ func (m *Manager) EnableIntegration(integrationID string) error {
 	return nil
 }
 
-// GetIntegrationMetrics returns metrics for a specific integration
+// GetIntegrationMetrics returns metrics for a specific integration,
+// including the state of its rate limiter buckets. Collected metrics are
+// only included when metrics are enabled.
 func (m *Manager) GetIntegrationMetrics(integrationID string) (map[string]interface{}, error) {
-	if !m.config.EnableMetrics || m.metrics == nil {
-		return nil, fmt.Errorf("metrics not enabled")
+	if _, err := m.GetIntegration(integrationID); err != nil {
+		return nil, err
 	}
 
-	return m.metrics.GetMetrics(integrationID), nil
+	metrics := make(map[string]interface{})
+	if m.config.EnableMetrics && m.metrics != nil {
+		for key, value := range m.metrics.GetMetrics(integrationID) {
+			metrics[key] = value
+		}
+	}
+	metrics["rate_limiter"] = m.limiter.State(integrationID)
+	return metrics, nil
 }
 
-// Close closes all integrations and cleans up resources
+// Close stops the health checks and closes all providers
 func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -535,4 +589,11 @@ func (m *Manager) Close() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// publish publishes an event on the event bus, if one is set
+func (m *Manager) publish(event IntegrationEvent) {
+	if m.eventBus != nil {
+		m.eventBus.Publish(event)
+	}
+}
@@ -0,0 +1,100 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stubProvider implements IntegrationProvider only, so it cannot execute
+// generic requests
+type stubProvider struct{}
+
+func (stubProvider) Initialize(ctx context.Context, credentials Credentials, config map[string]interface{}) error {
+	return nil
+}
+
+func (stubProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (stubProvider) GetCapabilities() []string { return nil }
+
+func (stubProvider) GetRateLimit() RateLimitInfo {
+	return RateLimitInfo{RequestsPerSecond: 100, BurstSize: 10}
+}
+
+func (stubProvider) Close() error { return nil }
+
+// fakeProvider answers generic requests with its responses in order
+type fakeProvider struct {
+	stubProvider
+	responses []*IntegrationResponse
+	requests  int
+}
+
+func (p *fakeProvider) ExecuteRequest(ctx context.Context, request IntegrationRequest) (*IntegrationResponse, error) {
+	response := p.responses[p.requests]
+	p.requests++
+	if response.StatusCode == http.StatusTooManyRequests {
+		return response, &IntegrationError{Code: "RATE_LIMIT", Message: "too many requests", StatusCode: response.StatusCode}
+	}
+	return response, nil
+}
+
+func newTestManager(t *testing.T, provider IntegrationProvider) *Manager {
+	t.Helper()
+	m := NewManager(&ManagerConfig{EnableCircuitBreaker: true, DefaultTimeout: time.Second, RateLimiter: NewRateLimiter()})
+	t.Cleanup(func() { m.Close() })
+	if err := m.RegisterIntegration(&Integration{ID: "n11", Provider: "n11"}, provider); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestExecuteRequestHonorsRetryAfter(t *testing.T) {
+	provider := &fakeProvider{responses: []*IntegrationResponse{
+		{StatusCode: http.StatusOK},
+		{StatusCode: http.StatusTooManyRequests, Headers: map[string]string{"Retry-After": "30"}},
+	}}
+	m := newTestManager(t, provider)
+	ctx := context.Background()
+	request := IntegrationRequest{Method: http.MethodPost, Endpoint: "/products", Class: EndpointProducts, Retries: 1}
+
+	if response, err := m.ExecuteRequest(ctx, "n11", request); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("got %+v (err %v)", response, err)
+	}
+	if _, err := m.ExecuteRequest(ctx, "n11", request); err == nil {
+		t.Fatal("a 429 response succeeded")
+	}
+
+	// The bucket is held back for the Retry-After; requests wait on it
+	// until their context is done instead of reaching the provider
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := m.ExecuteRequest(waitCtx, "n11", request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context's error", err)
+	}
+	if provider.requests != 2 {
+		t.Fatalf("provider got %d requests, want 2", provider.requests)
+	}
+
+	metrics, err := m.GetIntegrationMetrics("n11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, ok := metrics["rate_limiter"].([]RateLimiterState)
+	if !ok || len(state) != 1 || state[0].Class != EndpointProducts || state[0].BlockedUntil.IsZero() {
+		t.Fatalf("rate limiter metrics = %+v", metrics["rate_limiter"])
+	}
+}
+
+func TestExecuteRequestNeedsExecutor(t *testing.T) {
+	m := newTestManager(t, stubProvider{})
+
+	_, err := m.ExecuteRequest(context.Background(), "n11", IntegrationRequest{Method: http.MethodGet, Endpoint: "/orders"})
+	var integrationErr *IntegrationError
+	if !errors.As(err, &integrationErr) || integrationErr.Code != "NOT_SUPPORTED" {
+		t.Fatalf("got %v, want NOT_SUPPORTED", err)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,6 +31,9 @@ type AmazonProvider struct {
 	accessToken   string
 	tokenExpiry   time.Time
 	rateLimit     integrations.RateLimitInfo
+	limiter       *integrations.RateLimiter
+	// now is the signing clock; it defaults to time.Now
+	now func() time.Time
 }
 
 // AmazonProduct represents an Amazon product structure
@@ -89,7 +94,7 @@ type AmazonMoney struct {
 
 // AmazonAPIResponse represents Amazon SP-API response structure
 type AmazonAPIResponse struct {
-	Payload interface{} `json:"payload"`
+	Payload json.RawMessage `json:"payload"`
 	Errors  []struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
@@ -115,9 +120,20 @@ func NewAmazonProvider() *AmazonProvider {
 			RequestsPerSecond: 2, // Amazon has strict rate limits
 			BurstSize:        5,
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *AmazonProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *AmazonProvider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize initializes the Amazon provider
 func (p *AmazonProvider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	p.credentials = credentials
@@ -158,9 +174,18 @@ func (p *AmazonProvider) GetType() string {
 	return "marketplace"
 }
 
-// IsHealthy checks if the provider is healthy
-func (p *AmazonProvider) IsHealthy(ctx context.Context) (bool, error) {
-	return p.testConnection(ctx) == nil, nil
+// HealthCheck verifies the Amazon integration is working
+func (p *AmazonProvider) HealthCheck(ctx context.Context) error {
+	if err := p.testConnection(ctx); err != nil {
+		return &integrations.IntegrationError{
+			Code:      "HEALTH_CHECK_FAILED",
+			Message:   "Failed to connect to Amazon API",
+			Provider:  "amazon",
+			Retryable: true,
+			Timestamp: time.Now(),
+		}
+	}
+	return nil
 }
 
 // GetMetrics returns provider metrics
@@ -169,6 +194,18 @@ func (p *AmazonProvider) GetMetrics() map[string]interface{} {
 		"rate_limit_remaining": p.rateLimit.RequestsPerSecond,
 		"last_request_time":   time.Now().Unix(),
 		"token_expires_at":    p.tokenExpiry.Unix(),
+		"rate_limiter":        p.limiter.State("amazon"),
+	}
+}
+
+// GetCapabilities returns the capabilities of this integration
+func (p *AmazonProvider) GetCapabilities() []string {
+	return []string{
+		"product_sync",
+		"order_sync",
+		"inventory_sync",
+		"price_sync",
+		"category_mapping",
 	}
 }
 
@@ -177,184 +214,289 @@ func (p *AmazonProvider) GetRateLimit() integrations.RateLimitInfo {
 	return p.rateLimit
 }
 
-// SyncProducts syncs products to Amazon
-func (p *AmazonProvider) SyncProducts(ctx context.Context, products []interface{}) error {
-	for _, product := range products {
-		productMap, ok := product.(map[string]interface{})
-		if !ok {
+// Close cleans up any resources
+func (p *AmazonProvider) Close() error {
+	return nil
+}
+
+// SyncProducts syncs listings to Amazon. Nothing is sent if any listing
+// cannot be mapped.
+func (p *AmazonProvider) SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error) {
+	amazonProducts := make([]AmazonProduct, 0, len(listings))
+	mappings := make([]*mapping, 0, len(listings))
+	for i := range listings {
+		amazonProduct, m := p.mapListing(&listings[i])
+		amazonProducts = append(amazonProducts, amazonProduct)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("amazon", mappings); err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{}
+	for i, amazonProduct := range amazonProducts {
+		err := p.putListingItem(ctx, amazonProduct)
+		if err == nil {
+			report.add(amazonProduct.SKU, listings[i].Barcode, ListingAccepted, "", "")
 			continue
 		}
-		
-		amazonProduct := p.convertToAmazonProduct(productMap)
-		if err := p.putListingItem(ctx, amazonProduct); err != nil {
-			return fmt.Errorf("failed to sync product %s: %v", amazonProduct.SKU, err)
+		reason, rejected := rejection(err)
+		if !rejected {
+			return report, fmt.Errorf("failed to sync product %s: %w", amazonProduct.SKU, err)
 		}
+		report.add(amazonProduct.SKU, listings[i].Barcode, ListingRejected, reason, "")
 	}
-	
-	return nil
+
+	return report, nil
+}
+
+// GetBatchStatus is not supported: Amazon answers every listing as it is
+// sent
+func (p *AmazonProvider) GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error) {
+	return nil, ErrBatchStatusNotSupported
 }
 
-// UpdateStockAndPrice updates stock and price information
-func (p *AmazonProvider) UpdateStockAndPrice(ctx context.Context, updates []interface{}) error {
+// UpdateStockAndPrice updates stock and price information. Amazon keys
+// listings by seller SKU.
+func (p *AmazonProvider) UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error {
+	mappings := make([]*mapping, 0, len(updates))
+	for i := range updates {
+		m := newMapping("amazon", EntityStockPrice, updates[i].SKU)
+		m.stockPrice(&updates[i])
+		m.require("sku", updates[i].SKU)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("amazon", mappings); err != nil {
+		return err
+	}
+
 	for _, update := range updates {
-		updateMap, ok := update.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		sku, _ := updateMap["sku"].(string)
-		quantity, _ := updateMap["quantity"].(int)
-		price, _ := updateMap["price"].(float64)
-		
-		// Update inventory
-		if err := p.updateInventory(ctx, sku, quantity); err != nil {
-			return fmt.Errorf("failed to update inventory for %s: %v", sku, err)
+		if update.Stock != nil {
+			if err := p.updateInventory(ctx, update.SKU, *update.Stock); err != nil {
+				return fmt.Errorf("failed to update inventory for %s: %v", update.SKU, err)
+			}
 		}
-		
-		// Update price
-		if err := p.updatePrice(ctx, sku, price); err != nil {
-			return fmt.Errorf("failed to update price for %s: %v", sku, err)
+
+		if update.Price != nil {
+			if err := p.updatePrice(ctx, update.SKU, *update.Price); err != nil {
+				return fmt.Errorf("failed to update price for %s: %v", update.SKU, err)
+			}
 		}
 	}
-	
+
 	return nil
 }
 
-// GetProducts retrieves products from Amazon
-func (p *AmazonProvider) GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/listings/2021-08-01/items"
-	
+// GetProducts retrieves listings from Amazon
+func (p *AmazonProvider) GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error) {
+	endpoint := fmt.Sprintf("/listings/2021-08-01/items/%s", p.credentials.SellerID)
+
 	queryParams := url.Values{}
 	queryParams.Set("marketplaceIds", p.marketplaceID)
-	
-	if pageSize, ok := params["limit"].(int); ok {
-		queryParams.Set("pageSize", fmt.Sprintf("%d", pageSize))
+	queryParams.Set("includedData", "summaries,offers,fulfillmentAvailability")
+
+	if query.PageSize > 0 {
+		queryParams.Set("pageSize", strconv.Itoa(query.PageSize))
 	}
-	
-	if nextToken, ok := params["next_token"].(string); ok && nextToken != "" {
-		queryParams.Set("nextToken", nextToken)
+
+	if query.PageToken != "" {
+		queryParams.Set("pageToken", query.PageToken)
 	}
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint+"?"+queryParams.Encode(), nil)
-	if err != nil {
-		return nil, err
+
+	var payload struct {
+		Items      []amazonListingItem `json:"items"`
+		Pagination struct {
+			NextToken string `json:"nextToken"`
+		} `json:"pagination"`
 	}
-	
-	var apiResponse AmazonAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+	if err := p.get(ctx, endpoint+"?"+queryParams.Encode(), &payload); err != nil {
 		return nil, err
 	}
-	
-	if len(apiResponse.Errors) > 0 {
-		return nil, fmt.Errorf("Amazon API error: %s", apiResponse.Errors[0].Message)
+
+	page := &ListingPage{
+		Listings:      make([]Listing, 0, len(payload.Items)),
+		NextPageToken: payload.Pagination.NextToken,
 	}
-	
-	// Convert response to standard format
-	products := make([]interface{}, 0)
-	if payload, ok := apiResponse.Payload.(map[string]interface{}); ok {
-		if items, ok := payload["items"].([]interface{}); ok {
-			products = items
-		}
+	for _, item := range payload.Items {
+		page.Listings = append(page.Listings, item.toListing())
 	}
-	
-	return products, nil
+	return page, nil
 }
 
 // GetOrders retrieves orders from Amazon
-func (p *AmazonProvider) GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
+func (p *AmazonProvider) GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error) {
 	endpoint := "/orders/v0/orders"
-	
+
 	queryParams := url.Values{}
 	queryParams.Set("MarketplaceIds", p.marketplaceID)
-	
+
 	// Set date range
-	if createdAfter, ok := params["created_after"].(time.Time); ok {
-		queryParams.Set("CreatedAfter", createdAfter.Format(time.RFC3339))
+	if !query.Since.IsZero() {
+		queryParams.Set("CreatedAfter", query.Since.Format(time.RFC3339))
 	} else {
 		// Default to last 30 days
 		queryParams.Set("CreatedAfter", time.Now().AddDate(0, 0, -30).Format(time.RFC3339))
 	}
-	
-	if createdBefore, ok := params["created_before"].(time.Time); ok {
-		queryParams.Set("CreatedBefore", createdBefore.Format(time.RFC3339))
+
+	if !query.Until.IsZero() {
+		queryParams.Set("CreatedBefore", query.Until.Format(time.RFC3339))
 	}
-	
-	if nextToken, ok := params["next_token"].(string); ok && nextToken != "" {
-		queryParams.Set("NextToken", nextToken)
+
+	if query.Status != "" {
+		statuses, ok := amazonStatusFilters[query.Status]
+		if !ok {
+			return nil, orderStatusError("amazon", "", query.Status)
+		}
+		queryParams.Set("OrderStatuses", statuses)
 	}
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint+"?"+queryParams.Encode(), nil)
-	if err != nil {
-		return nil, err
+
+	if query.PageSize > 0 {
+		queryParams.Set("MaxResultsPerPage", strconv.Itoa(query.PageSize))
 	}
-	
-	var apiResponse AmazonAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+
+	if query.PageToken != "" {
+		queryParams.Set("NextToken", query.PageToken)
+	}
+
+	var payload struct {
+		Orders    []AmazonOrder `json:"Orders"`
+		NextToken string        `json:"NextToken"`
+	}
+	if err := p.get(ctx, endpoint+"?"+queryParams.Encode(), &payload); err != nil {
 		return nil, err
 	}
-	
-	if len(apiResponse.Errors) > 0 {
-		return nil, fmt.Errorf("Amazon API error: %s", apiResponse.Errors[0].Message)
+
+	page := &OrderPage{
+		Orders:        make([]Order, 0, len(payload.Orders)),
+		NextPageToken: payload.NextToken,
 	}
-	
-	// Convert response to standard format
-	orders := make([]interface{}, 0)
-	if payload, ok := apiResponse.Payload.(map[string]interface{}); ok {
-		if ordersList, ok := payload["Orders"].([]interface{}); ok {
-			orders = ordersList
-		}
+	for _, order := range payload.Orders {
+		page.Orders = append(page.Orders, order.toOrder())
 	}
-	
-	return orders, nil
+	return page, nil
 }
 
 // UpdateOrderStatus updates order status
-func (p *AmazonProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error {
-	// Amazon uses different endpoints for different order updates
-	// This is a simplified implementation for shipment confirmation
-	
-	if status == "shipped" {
-		return p.confirmShipment(ctx, orderID, params)
+func (p *AmazonProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error {
+	// Amazon uses different endpoints for different order updates;
+	// sellers can only confirm shipments through the orders API
+	if status != OrderStatusShipped {
+		return orderStatusError("amazon", orderID, status)
 	}
-	
-	return fmt.Errorf("unsupported order status update: %s", status)
+
+	m := newMapping("amazon", EntityShipment, orderID)
+	m.shipment(status, shipment)
+	if err := validationError("amazon", []*mapping{m}); err != nil {
+		return err
+	}
+
+	return p.confirmShipment(ctx, orderID, shipment)
 }
 
 // GetCategories retrieves categories from Amazon
-func (p *AmazonProvider) GetCategories(ctx context.Context) ([]interface{}, error) {
+func (p *AmazonProvider) GetCategories(ctx context.Context) ([]Category, error) {
 	endpoint := "/catalog/2022-04-01/items"
-	
+
 	queryParams := url.Values{}
 	queryParams.Set("marketplaceIds", p.marketplaceID)
 	queryParams.Set("includedData", "browseNodeInfo")
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint+"?"+queryParams.Encode(), nil)
+
+	if err := p.get(ctx, endpoint+"?"+queryParams.Encode(), nil); err != nil {
+		return nil, err
+	}
+
+	// Amazon doesn't have a direct categories endpoint
+	// Categories are retrieved through browse nodes in catalog items
+	return []Category{}, nil
+}
+
+// GetCategoryAttributes retrieves the listing attributes of an Amazon
+// product type, which is what categories are on Amazon. The product type
+// definition links to a JSON schema holding the attributes.
+func (p *AmazonProvider) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	queryParams := url.Values{}
+	queryParams.Set("marketplaceIds", p.marketplaceID)
+	queryParams.Set("requirements", "LISTING")
+	queryParams.Set("locale", "tr_TR")
+	endpoint := "/definitions/2020-09-01/productTypes/" + url.PathEscape(categoryID) + "?" + queryParams.Encode()
+
+	var definition struct {
+		Schema struct {
+			Link struct {
+				Resource string `json:"resource"`
+			} `json:"link"`
+		} `json:"schema"`
+	}
+	if err := p.get(ctx, endpoint, &definition); err != nil {
+		return nil, err
+	}
+	if definition.Schema.Link.Resource == "" {
+		return nil, fmt.Errorf("Amazon product type %s has no schema", categoryID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", definition.Schema.Link.Resource, nil)
 	if err != nil {
 		return nil, err
 	}
-	
-	var apiResponse AmazonAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
 		return nil, err
 	}
-	
-	if len(apiResponse.Errors) > 0 {
-		return nil, fmt.Errorf("Amazon API error: %s", apiResponse.Errors[0].Message)
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("Amazon product type schema returned status %d", resp.StatusCode)
 	}
-	
-	// Convert response to standard format
-	categories := make([]interface{}, 0)
-	// Amazon doesn't have a direct categories endpoint
-	// Categories are retrieved through browse nodes in catalog items
-	
-	return categories, nil
+
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Title string `json:"title"`
+			Items struct {
+				Properties struct {
+					Value struct {
+						Enum      []string `json:"enum"`
+						EnumNames []string `json:"enumNames"`
+					} `json:"value"`
+				} `json:"properties"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		return nil, fmt.Errorf("failed to parse Amazon product type schema: %w", err)
+	}
+
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	attributes := make([]CategoryAttribute, 0, len(schema.Properties))
+	for name, property := range schema.Properties {
+		attribute := CategoryAttribute{
+			ID:       name,
+			Name:     property.Title,
+			Required: required[name],
+		}
+		enum := property.Items.Properties.Value
+		for i, value := range enum.Enum {
+			valueName := value
+			if i < len(enum.EnumNames) {
+				valueName = enum.EnumNames[i]
+			}
+			attribute.Values = append(attribute.Values, AttributeValue{ID: value, Name: valueName})
+		}
+		attribute.AllowCustom = len(attribute.Values) == 0
+		attributes = append(attributes, attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].ID < attributes[j].ID })
+
+	return attributes, nil
 }
 
 // GetBrands retrieves brands from Amazon
-func (p *AmazonProvider) GetBrands(ctx context.Context) ([]interface{}, error) {
+func (p *AmazonProvider) GetBrands(ctx context.Context) ([]Brand, error) {
 	// Amazon doesn't have a separate brands endpoint
 	// Brands are part of product attributes
-	return []interface{}{}, nil
+	return []Brand{}, nil
 }
 
 // setBaseURL sets the base URL based on region
@@ -423,6 +565,13 @@ func (p *AmazonProvider) testConnection(ctx context.Context) error {
 
 // makeRequest makes HTTP request to Amazon SP-API
 func (p *AmazonProvider) makeRequest(ctx context.Context, method, endpoint string, data interface{}) ([]byte, error) {
+	// Wait for the rate limiter first, the signature and token must not
+	// go stale while the request is queued
+	class := endpointClass(endpoint)
+	if err := p.limiter.Wait(ctx, "amazon", class, p.rateLimit); err != nil {
+		return nil, err
+	}
+	
 	// Check if token needs refresh
 	if time.Now().After(p.tokenExpiry.Add(-5 * time.Minute)) {
 		if err := p.refreshAccessToken(ctx); err != nil {
@@ -451,7 +600,7 @@ func (p *AmazonProvider) makeRequest(ctx context.Context, method, endpoint strin
 	req.Header.Set("x-amz-access-token", p.accessToken)
 	
 	// Add AWS Signature Version 4
-	if err := p.signRequest(req); err != nil {
+	if err := p.signRequest(req, requestBody); err != nil {
 		return nil, fmt.Errorf("failed to sign request: %v", err)
 	}
 	
@@ -461,6 +610,9 @@ func (p *AmazonProvider) makeRequest(ctx context.Context, method, endpoint strin
 	}
 	defer resp.Body.Close()
 	
+	p.updateRateLimit(resp.Header)
+	p.limiter.Observe("amazon", class, resp, p.rateLimit)
+	
 	responseBody := make([]byte, 0)
 	buf := make([]byte, 1024)
 	for {
@@ -474,138 +626,165 @@ func (p *AmazonProvider) makeRequest(ctx context.Context, method, endpoint strin
 	}
 	
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("Amazon API error: %d - %s", resp.StatusCode, string(responseBody))
+		return nil, apiError("amazon", resp.StatusCode, fmt.Sprintf("Amazon API error: %d - %s", resp.StatusCode, string(responseBody)))
 	}
 	
 	return responseBody, nil
 }
 
-// signRequest signs the request with AWS Signature Version 4
-func (p *AmazonProvider) signRequest(req *http.Request) error {
-	// This is a simplified AWS signature implementation
-	// In production, you should use the official AWS SDK
-	
-	now := time.Now().UTC()
-	req.Header.Set("x-amz-date", now.Format("20060102T150405Z"))
-	
-	// Create canonical request
-	canonicalRequest := p.createCanonicalRequest(req)
-	
-	// Create string to sign
-	stringToSign := p.createStringToSign(now, canonicalRequest)
-	
-	// Calculate signature
-	signature := p.calculateSignature(now, stringToSign)
-	
-	// Add authorization header
-	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
-		p.credentials.AccessKeyID,
-		p.getCredentialScope(now),
-		p.getSignedHeaders(req),
-		signature)
-	
-	req.Header.Set("Authorization", authHeader)
-	
+// signRequest signs the request with AWS Signature Version 4 for the
+// execute-api service of the provider's region
+func (p *AmazonProvider) signRequest(req *http.Request, body []byte) error {
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	signV4(req, body, p.credentials.AccessKeyID, p.credentials.SecretAccessKey, p.region, "execute-api", now().UTC())
 	return nil
 }
 
-// createCanonicalRequest creates canonical request for AWS signature
-func (p *AmazonProvider) createCanonicalRequest(req *http.Request) string {
-	// This is a simplified implementation
-	// Full implementation would handle all AWS signature requirements
-	
-	method := req.Method
-	uri := req.URL.Path
-	if uri == "" {
-		uri = "/"
-	}
-	
-	query := req.URL.RawQuery
-	headers := p.getCanonicalHeaders(req)
-	signedHeaders := p.getSignedHeaders(req)
-	
-	// Calculate payload hash
-	payloadHash := "UNSIGNED-PAYLOAD" // Simplified
-	
-	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s",
-		method, uri, query, headers, signedHeaders, payloadHash)
-}
+// signV4 adds the x-amz-date and Authorization headers of AWS Signature
+// Version 4 to a request. Every header of the request and its host are
+// signed.
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Del("Authorization")
 
-// createStringToSign creates string to sign for AWS signature
-func (p *AmazonProvider) createStringToSign(now time.Time, canonicalRequest string) string {
-	algorithm := "AWS4-HMAC-SHA256"
-	timestamp := now.Format("20060102T150405Z")
-	credentialScope := p.getCredentialScope(now)
-	
-	hasher := sha256.New()
-	hasher.Write([]byte(canonicalRequest))
-	hashedCanonicalRequest := hex.EncodeToString(hasher.Sum(nil))
-	
-	return fmt.Sprintf("%s\n%s\n%s\n%s",
-		algorithm, timestamp, credentialScope, hashedCanonicalRequest)
+	canonicalHeaders, signedHeaders := v4CanonicalHeaders(req)
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		v4CanonicalURI(req.URL),
+		v4CanonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", now.Format("20060102"), region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := v4SigningKey(secretAccessKey, now.Format("20060102"), region, service)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
 }
 
-// calculateSignature calculates AWS signature
-func (p *AmazonProvider) calculateSignature(now time.Time, stringToSign string) string {
-	// This is a simplified signature calculation
-	// Full implementation would follow AWS signature process exactly
-	
-	dateKey := p.hmacSHA256([]byte("AWS4"+p.credentials.SecretAccessKey), now.Format("20060102"))
-	regionKey := p.hmacSHA256(dateKey, p.region)
-	serviceKey := p.hmacSHA256(regionKey, "execute-api")
-	signingKey := p.hmacSHA256(serviceKey, "aws4_request")
-	
-	signature := p.hmacSHA256(signingKey, stringToSign)
-	return hex.EncodeToString(signature)
+// v4SigningKey derives the signing key of a day, region and service
+func v4SigningKey(secretAccessKey, date, region, service string) []byte {
+	dateKey := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	regionKey := hmacSHA256(dateKey, region)
+	serviceKey := hmacSHA256(regionKey, service)
+	return hmacSHA256(serviceKey, "aws4_request")
 }
 
 // hmacSHA256 calculates HMAC-SHA256
-func (p *AmazonProvider) hmacSHA256(key []byte, data string) []byte {
+func hmacSHA256(key []byte, data string) []byte {
 	h := hmac.New(sha256.New, key)
 	h.Write([]byte(data))
 	return h.Sum(nil)
 }
 
-// getCredentialScope returns credential scope for AWS signature
-func (p *AmazonProvider) getCredentialScope(now time.Time) string {
-	return fmt.Sprintf("%s/%s/execute-api/aws4_request",
-		now.Format("20060102"), p.region)
+// v4CanonicalURI encodes each segment of the already escaped path again,
+// as AWS requires for every service but S3
+func v4CanonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = v4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// v4CanonicalQuery sorts the query parameters by name and value and
+// escapes them the AWS way
+func v4CanonicalQuery(u *url.URL) string {
+	query := u.Query()
+	params := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, v4Escape(name)+"="+v4Escape(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
 }
 
-// getCanonicalHeaders returns canonical headers for AWS signature
-func (p *AmazonProvider) getCanonicalHeaders(req *http.Request) string {
-	headers := make(map[string]string)
+// v4CanonicalHeaders returns the canonical headers, host included, and the
+// list of signed header names
+func v4CanonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.Host}
+	if req.Host == "" {
+		headers["host"] = req.URL.Host
+	}
 	for name, values := range req.Header {
-		headers[strings.ToLower(name)] = strings.Join(values, ",")
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
 	}
-	
-	var keys []string
-	for k := range headers {
-		keys = append(keys, k)
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
 	}
-	sort.Strings(keys)
-	
-	var canonical []string
-	for _, k := range keys {
-		canonical = append(canonical, fmt.Sprintf("%s:%s", k, headers[k]))
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
 	}
-	
-	return strings.Join(canonical, "\n") + "\n"
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// v4Escape percent-encodes everything but the unreserved characters
+func v4Escape(s string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
 }
 
-// getSignedHeaders returns signed headers for AWS signature
-func (p *AmazonProvider) getSignedHeaders(req *http.Request) string {
-	var headers []string
-	for name := range req.Header {
-		headers = append(headers, strings.ToLower(name))
+// updateRateLimit reads the request rate SP-API grants the operation,
+// in requests per second
+func (p *AmazonProvider) updateRateLimit(headers http.Header) {
+	limit := headers.Get("X-Amzn-Ratelimit-Limit")
+	if limit == "" {
+		return
+	}
+	if rate, err := strconv.ParseFloat(limit, 64); err == nil && rate > 0 {
+		p.rateLimit.RequestsPerSecond = int(rate)
+		p.rateLimit.RequestsPerMinute = int(math.Round(rate * 60))
 	}
-	sort.Strings(headers)
-	return strings.Join(headers, ";")
+}
+
+// listingItemEndpoint returns the listings items API endpoint of a seller
+// SKU in the provider's marketplace
+func (p *AmazonProvider) listingItemEndpoint(sku string) string {
+	return fmt.Sprintf("/listings/2021-08-01/items/%s/%s?marketplaceIds=%s",
+		url.PathEscape(p.credentials.SellerID), url.PathEscape(sku), url.QueryEscape(p.marketplaceID))
 }
 
 // putListingItem creates or updates a listing item
 func (p *AmazonProvider) putListingItem(ctx context.Context, product AmazonProduct) error {
-	endpoint := fmt.Sprintf("/listings/2021-08-01/items/%s/%s", p.credentials.SellerID, product.SKU)
+	endpoint := p.listingItemEndpoint(product.SKU)
 	
 	requestData := map[string]interface{}{
 		"productType": product.ProductType,
@@ -613,17 +792,35 @@ func (p *AmazonProvider) putListingItem(ctx context.Context, product AmazonProdu
 		"requirements": product.Requirements,
 	}
 	
-	_, err := p.makeRequest(ctx, "PUT", endpoint, requestData)
+	response, err := p.makeRequest(ctx, "PUT", endpoint, requestData)
 	if err != nil {
 		return err
 	}
 	
-	return nil
+	// Amazon answers invalid listings with status INVALID and the issues
+	var submission struct {
+		Status string `json:"status"`
+		Issues []struct {
+			Code     string `json:"code"`
+			Message  string `json:"message"`
+			Severity string `json:"severity"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal(response, &submission); err != nil || submission.Status != "INVALID" {
+		return nil
+	}
+	messages := make([]string, 0, len(submission.Issues))
+	for _, issue := range submission.Issues {
+		if issue.Severity == "ERROR" {
+			messages = append(messages, issue.Code+": "+issue.Message)
+		}
+	}
+	return apiError("amazon", 0, strings.Join(messages, "; "))
 }
 
 // updateInventory updates product inventory
 func (p *AmazonProvider) updateInventory(ctx context.Context, sku string, quantity int) error {
-	endpoint := fmt.Sprintf("/listings/2021-08-01/items/%s/%s", p.credentials.SellerID, sku)
+	endpoint := p.listingItemEndpoint(sku)
 	
 	requestData := map[string]interface{}{
 		"productType": "PRODUCT", // This should be determined dynamically
@@ -647,7 +844,7 @@ func (p *AmazonProvider) updateInventory(ctx context.Context, sku string, quanti
 
 // updatePrice updates product price
 func (p *AmazonProvider) updatePrice(ctx context.Context, sku string, price float64) error {
-	endpoint := fmt.Sprintf("/listings/2021-08-01/items/%s/%s", p.credentials.SellerID, sku)
+	endpoint := p.listingItemEndpoint(sku)
 	
 	requestData := map[string]interface{}{
 		"productType": "PRODUCT", // This should be determined dynamically
@@ -678,42 +875,135 @@ func (p *AmazonProvider) updatePrice(ctx context.Context, sku string, price floa
 }
 
 // confirmShipment confirms order shipment
-func (p *AmazonProvider) confirmShipment(ctx context.Context, orderID string, params map[string]interface{}) error {
+func (p *AmazonProvider) confirmShipment(ctx context.Context, orderID string, shipment *Shipment) error {
 	endpoint := fmt.Sprintf("/orders/v0/orders/%s/shipment", orderID)
-	
+
+	packageDetail := map[string]interface{}{
+		"packageReferenceId": fmt.Sprintf("package-%s", orderID),
+		"carrierCode":        shipment.CarrierCode,
+		"carrierName":        shipment.Carrier,
+		"trackingNumber":     shipment.TrackingNumber,
+	}
+	if !shipment.ShippedAt.IsZero() {
+		packageDetail["shipDate"] = shipment.ShippedAt.UTC().Format(time.RFC3339)
+	}
+
 	requestData := map[string]interface{}{
-		"packageDetail": map[string]interface{}{
-			"packageReferenceId": fmt.Sprintf("package-%s", orderID),
-			"carrierCode":       params["carrier_code"],
-			"trackingNumber":    params["tracking_number"],
-		},
+		"marketplaceId": p.marketplaceID,
+		"packageDetail": packageDetail,
 	}
-	
+
 	_, err := p.makeRequest(ctx, "POST", endpoint, requestData)
 	return err
 }
 
-// convertToAmazonProduct converts generic product to Amazon product format
-func (p *AmazonProvider) convertToAmazonProduct(product map[string]interface{}) AmazonProduct {
+// get requests an SP-API endpoint and decodes the payload of the response
+// into payload, which may be nil
+func (p *AmazonProvider) get(ctx context.Context, endpoint string, payload interface{}) error {
+	response, err := p.makeRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse AmazonAPIResponse
+	if err := json.Unmarshal(response, &apiResponse); err != nil {
+		return err
+	}
+
+	if len(apiResponse.Errors) > 0 {
+		return fmt.Errorf("Amazon API error: %s", apiResponse.Errors[0].Message)
+	}
+
+	// The listings API answers without a payload envelope
+	body := apiResponse.Payload
+	if len(body) == 0 {
+		body = response
+	}
+	if payload == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return fmt.Errorf("failed to parse Amazon response: %w", err)
+	}
+	return nil
+}
+
+// amazonOrderStatuses maps Amazon order statuses to canonical ones
+var amazonOrderStatuses = map[string]string{
+	"PendingAvailability": OrderStatusPending,
+	"Pending":             OrderStatusPending,
+	"Unshipped":           OrderStatusConfirmed,
+	"PartiallyShipped":    OrderStatusProcessing,
+	"Shipped":             OrderStatusShipped,
+	"InvoiceUnconfirmed":  OrderStatusShipped,
+	"Canceled":            OrderStatusCancelled,
+	"Unfulfillable":       OrderStatusCancelled,
+}
+
+// amazonStatusFilters maps canonical statuses to the Amazon statuses orders
+// are filtered by
+var amazonStatusFilters = map[string]string{
+	OrderStatusPending:    "PendingAvailability,Pending",
+	OrderStatusConfirmed:  "Unshipped",
+	OrderStatusProcessing: "PartiallyShipped",
+	OrderStatusShipped:    "Shipped,InvoiceUnconfirmed",
+	OrderStatusCancelled:  "Canceled,Unfulfillable",
+}
+
+// amazonListingItem is a listing as returned by the listings items API
+type amazonListingItem struct {
+	SKU       string `json:"sku"`
+	Summaries []struct {
+		ASIN        string   `json:"asin"`
+		ProductType string   `json:"productType"`
+		ItemName    string   `json:"itemName"`
+		Status      []string `json:"status"`
+		MainImage   struct {
+			Link string `json:"link"`
+		} `json:"mainImage"`
+	} `json:"summaries"`
+	Offers []struct {
+		Price AmazonMoney `json:"price"`
+	} `json:"offers"`
+	FulfillmentAvailability []struct {
+		Quantity int `json:"quantity"`
+	} `json:"fulfillmentAvailability"`
+}
+
+// mapListing maps a listing to an Amazon listing item, recording every
+// field Amazon would reject. The category ID is the Amazon product type.
+func (p *AmazonProvider) mapListing(listing *Listing) (AmazonProduct, *mapping) {
+	m := newMapping("amazon", EntityListing, listing.SKU)
+	m.listing(listing, "TRY")
+	m.maxLength("title", listing.Title, 200)
+	m.require("brand", listing.Brand)
+	m.require("description", listing.Description)
+	m.images(listing.Images, 1, 9)
+
+	productType := listing.CategoryID
+	if productType == "" {
+		productType = "PRODUCT"
+	}
+
 	amazonProduct := AmazonProduct{
-		SKU:         getString(product, "sku"),
-		ProductType: "PRODUCT", // This should be determined based on category
+		SKU:          listing.SKU,
+		ProductType:  productType,
 		Requirements: "LISTING",
 		Attributes: map[string]interface{}{
 			"item_name": []map[string]interface{}{
 				{
-					"value": getString(product, "title"),
+					"value":        listing.Title,
 					"language_tag": "tr_TR",
 				},
 			},
 			"brand": []map[string]interface{}{
 				{
-					"value": getString(product, "brand"),
+					"value": listing.Brand,
 				},
 			},
-			"description": []map[string]interface{}{
+			"product_description": []map[string]interface{}{
 				{
-					"value": getString(product, "description"),
+					"value":        listing.Description,
 					"language_tag": "tr_TR",
 				},
 			},
@@ -724,7 +1014,7 @@ func (p *AmazonProvider) convertToAmazonProduct(product map[string]interface{})
 						{
 							"schedule": []map[string]interface{}{
 								{
-									"value_with_tax": getFloat64(product, "price"),
+									"value_with_tax": listing.Price,
 								},
 							},
 						},
@@ -734,29 +1024,134 @@ func (p *AmazonProvider) convertToAmazonProduct(product map[string]interface{})
 			"fulfillment_availability": []map[string]interface{}{
 				{
 					"fulfillment_channel_code": "DEFAULT",
-					"quantity": getInt(product, "quantity"),
+					"quantity":                 listing.Stock,
 				},
 			},
 		},
 	}
-	
-	// Add images if available
-	if images, ok := product["images"].([]interface{}); ok && len(images) > 0 {
-		imageValues := make([]map[string]interface{}, 0)
-		for _, img := range images {
-			if imgStr, ok := img.(string); ok {
-				imageValues = append(imageValues, map[string]interface{}{
-					"value": imgStr,
-				})
-			}
+
+	if listing.Barcode != "" {
+		amazonProduct.Attributes["externally_assigned_product_identifier"] = []map[string]interface{}{
+			{
+				"type":  "ean",
+				"value": listing.Barcode,
+			},
+		}
+	}
+
+	if len(listing.Images) > 0 {
+		imageValues := make([]map[string]interface{}, 0, len(listing.Images))
+		for _, image := range listing.Images {
+			imageValues = append(imageValues, map[string]interface{}{
+				"media_location": image,
+			})
+		}
+		amazonProduct.Attributes["main_product_image_locator"] = imageValues[:1]
+		if len(imageValues) > 1 {
+			amazonProduct.Attributes["other_product_image_locator_1"] = imageValues[1:]
+		}
+	}
+
+	// Amazon keys attributes by their schema name
+	for i, attribute := range listing.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		name := attribute.ID
+		if name == "" {
+			name = attribute.Name
 		}
-		if len(imageValues) > 0 {
-			amazonProduct.Attributes["main_product_image_locator"] = imageValues[:1]
-			if len(imageValues) > 1 {
-				amazonProduct.Attributes["other_product_image_locator"] = imageValues[1:]
+		m.require(field+".name", name)
+		m.require(field+".value", attribute.Value)
+		amazonProduct.Attributes[name] = []map[string]interface{}{
+			{
+				"value": attribute.Value,
+			},
+		}
+	}
+
+	return amazonProduct, m
+}
+
+// toListing maps an Amazon listing item back to a listing
+func (item amazonListingItem) toListing() Listing {
+	listing := Listing{
+		SKU:      item.SKU,
+		Currency: defaultCurrency,
+		Status:   ProductStatusInactive,
+	}
+	if len(item.Summaries) > 0 {
+		summary := item.Summaries[0]
+		listing.ExternalID = summary.ASIN
+		listing.Title = summary.ItemName
+		listing.CategoryID = summary.ProductType
+		if summary.MainImage.Link != "" {
+			listing.Images = []string{summary.MainImage.Link}
+		}
+		for _, status := range summary.Status {
+			if status == "BUYABLE" {
+				listing.Status = ProductStatusActive
 			}
 		}
 	}
-	
-	return amazonProduct
-}
\ No newline at end of file
+	if len(item.Offers) > 0 {
+		listing.Price = item.Offers[0].Price.value()
+		listing.Currency = listingCurrency(item.Offers[0].Price.CurrencyCode)
+	}
+	for _, availability := range item.FulfillmentAvailability {
+		listing.Stock += availability.Quantity
+	}
+	return listing
+}
+
+// toOrder maps an Amazon order to a canonical order
+func (order AmazonOrder) toOrder() Order {
+	result := Order{
+		ID:                order.AmazonOrderID,
+		OrderNumber:       order.AmazonOrderID,
+		Status:            canonicalOrderStatus(amazonOrderStatuses, order.OrderStatus),
+		MarketplaceStatus: order.OrderStatus,
+		CustomerName:      order.BuyerName,
+		CustomerEmail:     order.BuyerEmail,
+		TotalAmount:       order.OrderTotal.value(),
+		Currency:          listingCurrency(order.OrderTotal.CurrencyCode),
+		PaymentMethod:     order.PaymentMethod,
+		PaymentStatus:     PaymentStatusPaid,
+		ShippingMethod:    order.ShipServiceLevel,
+		OrderDate:         order.PurchaseDate,
+	}
+	if order.OrderStatus == "Pending" {
+		result.PaymentStatus = PaymentStatusPending
+	}
+
+	for _, line := range order.OrderItems {
+		// Amazon item prices are for the whole line
+		total := line.ItemPrice.value()
+		price := total
+		if line.QuantityOrdered > 0 {
+			price = total / float64(line.QuantityOrdered)
+		}
+		discount := line.PromotionDiscount.value()
+		result.Items = append(result.Items, OrderItem{
+			ID:             line.OrderItemID,
+			ProductID:      line.ASIN,
+			SKU:            line.SellerSKU,
+			Name:           line.Title,
+			Quantity:       line.QuantityOrdered,
+			Price:          price,
+			TotalPrice:     total,
+			TaxAmount:      line.ItemTax.value(),
+			DiscountAmount: discount,
+		})
+		result.Subtotal += total
+		result.TaxAmount += line.ItemTax.value()
+		result.ShippingAmount += line.ShippingPrice.value()
+		result.DiscountAmount += discount + line.ShippingDiscount.value()
+	}
+
+	return result
+}
+
+// value parses the decimal amount of a money value
+func (m AmazonMoney) value() float64 {
+	amount, _ := strconv.ParseFloat(m.Amount, 64)
+	return amount
+}
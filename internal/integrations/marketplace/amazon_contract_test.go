@@ -0,0 +1,170 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+var amazonTestCredentials = integrations.Credentials{
+	ClientID:        testValue("AMAZON_CLIENT_ID", "amzn1.application-oa2-client.test"),
+	ClientSecret:    testValue("AMAZON_CLIENT_SECRET", "test-client-secret"),
+	RefreshToken:    testValue("AMAZON_REFRESH_TOKEN", "Atzr|test-refresh-token"),
+	AccessKeyID:     testValue("AMAZON_ACCESS_KEY_ID", "AKIDEXAMPLE"),
+	SecretAccessKey: testValue("AMAZON_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"),
+	SellerID:        testValue("AMAZON_SELLER_ID", "A2TESTSELLER01"),
+}
+
+// amazonTestTime is the signing clock of replays. Recordings sign with the
+// real clock, which SP-API checks.
+var amazonTestTime = time.Date(2026, 9, 5, 12, 0, 0, 0, time.UTC)
+
+func newAmazonContract(t *testing.T, fixture string) (*AmazonProvider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewAmazonProvider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	if httpreplay.ModeFromEnv() == httpreplay.ModeReplay {
+		p.now = func() time.Time { return amazonTestTime }
+	}
+	if err := p.Initialize(context.Background(), amazonTestCredentials, map[string]interface{}{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+// amazonListing is the test listing with an Amazon product type and
+// attribute names
+func amazonListing(sku, barcode string) Listing {
+	listing := testListing(sku, barcode)
+	listing.CategoryID = "SHIRT"
+	listing.Attributes = []Attribute{{ID: "color", Name: "Renk", Value: "Kırmızı"}}
+	return listing
+}
+
+// TestAmazonSignatureV4 checks the signer against the GET example of the
+// AWS Signature Version 4 documentation
+func TestAmazonSignatureV4(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signV4(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization:\n got %s\nwant %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date: got %s", got)
+	}
+}
+
+func TestAmazonOrdersContract(t *testing.T) {
+	p, recorder := newAmazonContract(t, "amazon_orders")
+
+	query := OrderQuery{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), PageSize: 2}
+	first, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken == "" {
+		t.Fatalf("first page: got %d orders, next %q", len(first.Orders), first.NextPageToken)
+	}
+	order := first.Orders[0]
+	if order.ID != "405-1234567-1234567" || order.Status != OrderStatusConfirmed || order.TotalAmount != 299.8 {
+		t.Errorf("order: got %s %s total %.2f", order.ID, order.Status, order.TotalAmount)
+	}
+	if pending := first.Orders[1]; pending.PaymentStatus != PaymentStatusPending {
+		t.Errorf("pending order: got payment status %s", pending.PaymentStatus)
+	}
+
+	query.PageToken = first.NextPageToken
+	last, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders next token: %v", err)
+	}
+	if len(last.Orders) != 1 || last.NextPageToken != "" {
+		t.Fatalf("last page: got %d orders, next %q", len(last.Orders), last.NextPageToken)
+	}
+
+	// The token exchange goes to LWA unsigned; SP-API requests carry the
+	// access token and are signed for execute-api in the region
+	requests := recorder.Requests()
+	if requests[0].URL.Host != "api.amazon.com" || requests[0].Header.Get("Authorization") != "" {
+		t.Errorf("token request: %s with Authorization %q", requests[0].URL, requests[0].Header.Get("Authorization"))
+	}
+	signedAt := time.Now().UTC()
+	if p.now != nil {
+		signedAt = p.now()
+	}
+	scope := fmt.Sprintf("Credential=%s/%s/eu-west-1/execute-api/aws4_request", amazonTestCredentials.AccessKeyID, signedAt.Format("20060102"))
+	for _, request := range requests[1:] {
+		authorization := request.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 ") || !strings.Contains(authorization, scope) {
+			t.Errorf("%s: Authorization %q", request.URL.Path, authorization)
+		}
+		if !strings.Contains(authorization, "SignedHeaders=content-type;host;user-agent;x-amz-access-token;x-amz-date,") {
+			t.Errorf("%s: signed headers in %q", request.URL.Path, authorization)
+		}
+		if request.Header.Get("X-Amz-Access-Token") == "" {
+			t.Errorf("%s: no access token", request.URL.Path)
+		}
+	}
+
+	// The orders API grants 0.0167 requests per second
+	if rateLimit := p.GetRateLimit(); rateLimit.RequestsPerMinute != 1 || rateLimit.RequestsPerSecond != 0 {
+		t.Errorf("rate limit: got %d/s, %d/min", rateLimit.RequestsPerSecond, rateLimit.RequestsPerMinute)
+	}
+}
+
+func TestAmazonSyncContract(t *testing.T) {
+	p, _ := newAmazonContract(t, "amazon_sync")
+
+	// Amazon answers invalid listings with a 200 and their issues
+	report, err := p.SyncProducts(context.Background(), []Listing{amazonListing("TS-RED-M", "8680000000011"), amazonListing("TS-RED-L", "8680000000028")})
+	if err != nil {
+		t.Fatalf("SyncProducts: %v", err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results", len(report.Results))
+	}
+	if accepted := report.Results[0]; accepted.Status != ListingAccepted {
+		t.Errorf("first listing: got %+v", accepted)
+	}
+	rejected := report.Results[1]
+	if rejected.Status != ListingRejected || rejected.Message != "90220: 'item_type_keyword' is required but not supplied." {
+		t.Errorf("second listing: got %+v", rejected)
+	}
+}
+
+func TestAmazonErrorContract(t *testing.T) {
+	p, _ := newAmazonContract(t, "amazon_errors")
+	ctx := context.Background()
+	listings := []Listing{amazonListing("TS-RED-M", "8680000000011")}
+
+	// 400 rejects the listing
+	report, err := p.SyncProducts(ctx, listings)
+	if err != nil {
+		t.Fatalf("SyncProducts 400: %v", err)
+	}
+	if result := report.Results[0]; result.Status != ListingRejected || !strings.Contains(result.Message, "InvalidInput") {
+		t.Errorf("400: got %+v", result)
+	}
+
+	// 403 is an authorization failure, not a rejection of the listing
+	_, err = p.SyncProducts(ctx, listings)
+	var marketplaceErr *MarketplaceError
+	if !errors.As(err, &marketplaceErr) || marketplaceErr.StatusCode != 403 || marketplaceErr.Retryable {
+		t.Fatalf("403: got %v", err)
+	}
+}
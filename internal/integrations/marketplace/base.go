@@ -2,33 +2,86 @@ package marketplace
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"kolajAi/internal/integrations"
 )
 
-// MarketplaceProvider defines the interface for marketplace integrations
+// MarketplaceProvider defines the interface for marketplace integrations.
+// Every provider speaks the canonical Listing, Order, StockPriceUpdate and
+// Shipment types and maps them to its own API, rejecting values the
+// marketplace cannot accept with a *ValidationError before calling it.
 type MarketplaceProvider interface {
 	// Base integration methods
-	Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error
+	integrations.IntegrationProvider
 	GetName() string
 	GetType() string
-	IsHealthy(ctx context.Context) (bool, error)
 	GetMetrics() map[string]interface{}
-	GetRateLimit() integrations.RateLimitInfo
-	
+
 	// Product operations
-	SyncProducts(ctx context.Context, products []interface{}) error
-	UpdateStockAndPrice(ctx context.Context, updates []interface{}) error
-	GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error)
-	
+	// SyncProducts creates or updates listings and reports the outcome of
+	// each. Listings the marketplace processes asynchronously stay pending
+	// until GetBatchStatus reports them. An error means the sync stopped;
+	// the report holds the listings sent before it.
+	SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error)
+	// GetBatchStatus returns the progress of a batch of pending listings
+	GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error)
+	UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error
+	GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error)
+
 	// Order operations
-	GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error)
-	UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error
-	
+	GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error)
+	// UpdateOrderStatus moves a marketplace order to one of the canonical
+	// OrderStatus values. Shipped orders need a shipment.
+	UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error
+
 	// Category operations
-	GetCategories(ctx context.Context) ([]interface{}, error)
-	GetBrands(ctx context.Context) ([]interface{}, error)
+	GetCategories(ctx context.Context) ([]Category, error)
+	// GetCategoryAttributes returns the attributes listings of a category
+	// take, with the fixed values of those that have them
+	GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error)
+	GetBrands(ctx context.Context) ([]Brand, error)
+}
+
+// NewProvider creates the provider of a marketplace integration by its ID
+func NewProvider(integrationID string) (MarketplaceProvider, error) {
+	switch integrationID {
+	case "trendyol":
+		return NewTrendyolProvider(), nil
+	case "hepsiburada":
+		return NewHepsiburadaProvider(), nil
+	case "n11":
+		return NewN11Provider(), nil
+	case "amazon", "amazon_tr":
+		return NewAmazonProvider(), nil
+	case "ciceksepeti":
+		return NewCicekSepetiProvider(), nil
+	default:
+		return nil, fmt.Errorf("no marketplace provider for integration %q", integrationID)
+	}
+}
+
+// endpointClass returns the rate limiter class of an API endpoint, so that
+// e.g. a bulk product sync does not hold order imports back
+func endpointClass(endpoint string) string {
+	path := strings.ToLower(endpoint)
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	switch {
+	case strings.Contains(path, "order"):
+		return integrations.EndpointOrders
+	case strings.Contains(path, "categor"), strings.Contains(path, "brand"):
+		return integrations.EndpointDefault
+	case strings.Contains(path, "stock"), strings.Contains(path, "price"), strings.Contains(path, "inventory"):
+		return integrations.EndpointInventory
+	case strings.Contains(path, "product"), strings.Contains(path, "listing"), strings.Contains(path, "item"):
+		return integrations.EndpointProducts
+	default:
+		return integrations.EndpointDefault
+	}
 }
 
 // MarketplaceProviderConfig holds configuration for marketplace providers
@@ -47,12 +100,12 @@ type MarketplaceProviderConfig struct {
 
 // MarketplaceError represents marketplace-specific errors
 type MarketplaceError struct {
-	Code       string    `json:"code"`
-	Message    string    `json:"message"`
-	Provider   string    `json:"provider"`
-	Retryable  bool      `json:"retryable"`
-	Timestamp  time.Time `json:"timestamp"`
-	StatusCode int       `json:"status_code,omitempty"`
+	Code       string                 `json:"code"`
+	Message    string                 `json:"message"`
+	Provider   string                 `json:"provider"`
+	Retryable  bool                   `json:"retryable"`
+	Timestamp  time.Time              `json:"timestamp"`
+	StatusCode int                    `json:"status_code,omitempty"`
 	Details    map[string]interface{} `json:"details,omitempty"`
 }
 
@@ -60,26 +113,41 @@ func (e *MarketplaceError) Error() string {
 	return e.Message
 }
 
-// Product represents a generic marketplace product
-type Product struct {
-	ID               string            `json:"id"`
-	SKU              string            `json:"sku"`
-	Barcode          string            `json:"barcode"`
-	Title            string            `json:"title"`
-	Description      string            `json:"description"`
-	Brand            string            `json:"brand"`
-	Category         string            `json:"category"`
-	Price            float64           `json:"price"`
-	ListPrice        float64           `json:"list_price"`
-	Currency         string            `json:"currency"`
-	Stock            int               `json:"stock"`
-	Images           []string          `json:"images"`
-	Attributes       map[string]string `json:"attributes"`
-	Weight           float64           `json:"weight"`
-	Dimensions       Dimensions        `json:"dimensions"`
-	Status           string            `json:"status"`
-	CreatedAt        time.Time         `json:"created_at"`
-	UpdatedAt        time.Time         `json:"updated_at"`
+// Listing is a product as it is offered on a marketplace. ListingFromProduct
+// fills it from a catalog product; the marketplace-specific category, brand
+// and attribute IDs come from the integration's mappings.
+type Listing struct {
+	ProductID   int     `json:"product_id"`
+	ExternalID  string  `json:"external_id,omitempty"` // set on listings read back from the marketplace
+	SKU         string  `json:"sku"`
+	Barcode     string  `json:"barcode"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Brand       string  `json:"brand"`
+	BrandID     string  `json:"brand_id,omitempty"`
+	Category    string  `json:"category"`
+	CategoryID  string  `json:"category_id,omitempty"`
+	Price       float64 `json:"price"`
+	ListPrice   float64 `json:"list_price"`
+	Currency    string  `json:"currency"`
+	Stock       int     `json:"stock"`
+	// VatRate is the VAT percentage. 0 uses the marketplace default.
+	VatRate      int         `json:"vat_rate,omitempty"`
+	DispatchDays int         `json:"dispatch_days,omitempty"`
+	Images       []string    `json:"images"`
+	Attributes   []Attribute `json:"attributes"`
+	Weight       float64     `json:"weight"`
+	Dimensions   Dimensions  `json:"dimensions"`
+	Status       string      `json:"status"`
+}
+
+// Attribute is a category attribute of a listing. Marketplaces that key
+// attributes by ID need ID and, for fixed values, ValueID.
+type Attribute struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	ValueID string `json:"value_id,omitempty"`
 }
 
 // Dimensions represents product dimensions
@@ -90,61 +158,113 @@ type Dimensions struct {
 	Unit   string  `json:"unit"` // cm, in, etc.
 }
 
+// ListingQuery pages through the listings of a marketplace account
+type ListingQuery struct {
+	PageToken string `json:"page_token,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+}
+
+// ListingPage is a page of listings. NextPageToken is empty on the last page.
+type ListingPage struct {
+	Listings      []Listing `json:"listings"`
+	NextPageToken string    `json:"next_page_token,omitempty"`
+}
+
+// StockPriceUpdate changes the stock and/or price of a listing. Nil fields
+// are left unchanged.
+type StockPriceUpdate struct {
+	SKU     string   `json:"sku"`
+	Barcode string   `json:"barcode"`
+	Stock   *int     `json:"stock,omitempty"`
+	Price   *float64 `json:"price,omitempty"`
+	// ListPrice is the crossed-out price shown next to Price. 0 keeps it
+	// equal to Price.
+	ListPrice float64 `json:"list_price,omitempty"`
+}
+
 // Order represents a generic marketplace order
 type Order struct {
-	ID               string        `json:"id"`
-	OrderNumber      string        `json:"order_number"`
-	Status           string        `json:"status"`
-	CustomerID       string        `json:"customer_id"`
-	CustomerName     string        `json:"customer_name"`
-	CustomerEmail    string        `json:"customer_email"`
-	CustomerPhone    string        `json:"customer_phone"`
-	BillingAddress   Address       `json:"billing_address"`
-	ShippingAddress  Address       `json:"shipping_address"`
-	Items            []OrderItem   `json:"items"`
-	Subtotal         float64       `json:"subtotal"`
-	TaxAmount        float64       `json:"tax_amount"`
-	ShippingAmount   float64       `json:"shipping_amount"`
-	DiscountAmount   float64       `json:"discount_amount"`
-	TotalAmount      float64       `json:"total_amount"`
-	Currency         string        `json:"currency"`
-	PaymentMethod    string        `json:"payment_method"`
-	PaymentStatus    string        `json:"payment_status"`
-	ShippingMethod   string        `json:"shipping_method"`
-	TrackingNumber   string        `json:"tracking_number"`
-	Notes            string        `json:"notes"`
-	OrderDate        time.Time     `json:"order_date"`
-	ShippedDate      *time.Time    `json:"shipped_date,omitempty"`
-	DeliveredDate    *time.Time    `json:"delivered_date,omitempty"`
+	ID                string      `json:"id"`
+	OrderNumber       string      `json:"order_number"`
+	Status            string      `json:"status"`
+	MarketplaceStatus string      `json:"marketplace_status"`
+	CustomerID        string      `json:"customer_id"`
+	CustomerName      string      `json:"customer_name"`
+	CustomerEmail     string      `json:"customer_email"`
+	CustomerPhone     string      `json:"customer_phone"`
+	BillingAddress    Address     `json:"billing_address"`
+	ShippingAddress   Address     `json:"shipping_address"`
+	Items             []OrderItem `json:"items"`
+	Subtotal          float64     `json:"subtotal"`
+	TaxAmount         float64     `json:"tax_amount"`
+	ShippingAmount    float64     `json:"shipping_amount"`
+	DiscountAmount    float64     `json:"discount_amount"`
+	TotalAmount       float64     `json:"total_amount"`
+	Currency          string      `json:"currency"`
+	PaymentMethod     string      `json:"payment_method"`
+	PaymentStatus     string      `json:"payment_status"`
+	ShippingMethod    string      `json:"shipping_method"`
+	TrackingNumber    string      `json:"tracking_number"`
+	Notes             string      `json:"notes"`
+	OrderDate         time.Time   `json:"order_date"`
+	ShippedDate       *time.Time  `json:"shipped_date,omitempty"`
+	DeliveredDate     *time.Time  `json:"delivered_date,omitempty"`
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
-	ID           string  `json:"id"`
-	ProductID    string  `json:"product_id"`
-	SKU          string  `json:"sku"`
-	Name         string  `json:"name"`
-	Quantity     int     `json:"quantity"`
-	Price        float64 `json:"price"`
-	TotalPrice   float64 `json:"total_price"`
-	TaxAmount    float64 `json:"tax_amount"`
-	DiscountAmount float64 `json:"discount_amount"`
-	Attributes   map[string]string `json:"attributes"`
+	ID             string            `json:"id"`
+	ProductID      string            `json:"product_id"`
+	SKU            string            `json:"sku"`
+	Barcode        string            `json:"barcode"`
+	Name           string            `json:"name"`
+	Quantity       int               `json:"quantity"`
+	Price          float64           `json:"price"`
+	TotalPrice     float64           `json:"total_price"`
+	TaxAmount      float64           `json:"tax_amount"`
+	DiscountAmount float64           `json:"discount_amount"`
+	Attributes     map[string]string `json:"attributes"`
 }
 
 // Address represents a billing or shipping address
 type Address struct {
-	ID          string `json:"id"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Company     string `json:"company"`
-	Address1    string `json:"address1"`
-	Address2    string `json:"address2"`
-	City        string `json:"city"`
-	State       string `json:"state"`
-	PostalCode  string `json:"postal_code"`
-	Country     string `json:"country"`
-	Phone       string `json:"phone"`
+	ID         string `json:"id"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Company    string `json:"company"`
+	Address1   string `json:"address1"`
+	Address2   string `json:"address2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone"`
+}
+
+// OrderQuery selects marketplace orders by creation date and status
+type OrderQuery struct {
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until,omitempty"`
+	Status    string    `json:"status,omitempty"` // canonical OrderStatus value
+	PageToken string    `json:"page_token,omitempty"`
+	PageSize  int       `json:"page_size,omitempty"`
+}
+
+// OrderPage is a page of orders. NextPageToken is empty on the last page.
+type OrderPage struct {
+	Orders        []Order `json:"orders"`
+	NextPageToken string  `json:"next_page_token,omitempty"`
+}
+
+// Shipment is the carrier and tracking information pushed to a marketplace
+// when an order ships
+type Shipment struct {
+	Carrier        string    `json:"carrier"`
+	CarrierCode    string    `json:"carrier_code"`
+	TrackingNumber string    `json:"tracking_number"`
+	TrackingURL    string    `json:"tracking_url,omitempty"`
+	InvoiceNumber  string    `json:"invoice_number,omitempty"`
+	ShippedAt      time.Time `json:"shipped_at"`
 }
 
 // Category represents a marketplace category
@@ -156,6 +276,23 @@ type Category struct {
 	Level    int    `json:"level"`
 }
 
+// CategoryAttribute is an attribute a marketplace category accepts.
+// Attributes with Values only take one of them unless AllowCustom is set.
+type CategoryAttribute struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Required    bool             `json:"required"`
+	AllowCustom bool             `json:"allow_custom"`
+	Varianter   bool             `json:"varianter,omitempty"`
+	Values      []AttributeValue `json:"values,omitempty"`
+}
+
+// AttributeValue is a fixed value of a category attribute
+type AttributeValue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 // Brand represents a marketplace brand
 type Brand struct {
 	ID   string `json:"id"`
@@ -163,15 +300,6 @@ type Brand struct {
 	Logo string `json:"logo,omitempty"`
 }
 
-// StockPriceUpdate represents a stock and price update
-type StockPriceUpdate struct {
-	SKU       string  `json:"sku"`
-	Barcode   string  `json:"barcode"`
-	Stock     int     `json:"stock"`
-	Price     float64 `json:"price"`
-	ListPrice float64 `json:"list_price"`
-}
-
 // WebhookEvent represents a webhook event
 type WebhookEvent struct {
 	Type      string                 `json:"type"`
@@ -207,4 +335,4 @@ const (
 	PaymentStatusFailed    = "failed"
 	PaymentStatusRefunded  = "refunded"
 	PaymentStatusCancelled = "cancelled"
-)
\ No newline at end of file
+)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"kolajAi/internal/integrations"
@@ -20,6 +21,7 @@ type CicekSepetiProvider struct {
 	baseURL     string
 	apiKey      string
 	rateLimit   integrations.RateLimitInfo
+	limiter     *integrations.RateLimiter
 }
 
 // CicekSepetiProduct represents a ÇiçekSepeti product structure
@@ -89,7 +91,7 @@ type CicekSepetiOrderItem struct {
 type CicekSepetiAPIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Data    json.RawMessage `json:"data"`
 	Error   struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
@@ -107,9 +109,20 @@ func NewCicekSepetiProvider() *CicekSepetiProvider {
 			RequestsPerSecond: 10,
 			BurstSize:        20,
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *CicekSepetiProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *CicekSepetiProvider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize initializes the ÇiçekSepeti provider
 func (p *CicekSepetiProvider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	p.credentials = credentials
@@ -134,9 +147,18 @@ func (p *CicekSepetiProvider) GetType() string {
 	return "marketplace"
 }
 
-// IsHealthy checks if the provider is healthy
-func (p *CicekSepetiProvider) IsHealthy(ctx context.Context) (bool, error) {
-	return p.testConnection(ctx) == nil, nil
+// HealthCheck verifies the ÇiçekSepeti integration is working
+func (p *CicekSepetiProvider) HealthCheck(ctx context.Context) error {
+	if err := p.testConnection(ctx); err != nil {
+		return &integrations.IntegrationError{
+			Code:      "HEALTH_CHECK_FAILED",
+			Message:   "Failed to connect to ÇiçekSepeti API",
+			Provider:  "ciceksepeti",
+			Retryable: true,
+			Timestamp: time.Now(),
+		}
+	}
+	return nil
 }
 
 // GetMetrics returns provider metrics
@@ -144,6 +166,18 @@ func (p *CicekSepetiProvider) GetMetrics() map[string]interface{} {
 	return map[string]interface{}{
 		"rate_limit_remaining": p.rateLimit.RequestsPerSecond,
 		"last_request_time":   time.Now().Unix(),
+		"rate_limiter":        p.limiter.State("ciceksepeti"),
+	}
+}
+
+// GetCapabilities returns the capabilities of this integration
+func (p *CicekSepetiProvider) GetCapabilities() []string {
+	return []string{
+		"product_sync",
+		"order_sync",
+		"inventory_sync",
+		"price_sync",
+		"category_mapping",
 	}
 }
 
@@ -152,204 +186,219 @@ func (p *CicekSepetiProvider) GetRateLimit() integrations.RateLimitInfo {
 	return p.rateLimit
 }
 
-// SyncProducts syncs products to ÇiçekSepeti
-func (p *CicekSepetiProvider) SyncProducts(ctx context.Context, products []interface{}) error {
-	for _, product := range products {
-		productMap, ok := product.(map[string]interface{})
-		if !ok {
+// Close cleans up any resources
+func (p *CicekSepetiProvider) Close() error {
+	return nil
+}
+
+// SyncProducts syncs listings to ÇiçekSepeti. Nothing is sent if any
+// listing cannot be mapped.
+func (p *CicekSepetiProvider) SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error) {
+	cicekSepetiProducts := make([]CicekSepetiProduct, 0, len(listings))
+	mappings := make([]*mapping, 0, len(listings))
+	for i := range listings {
+		cicekSepetiProduct, m := p.mapListing(&listings[i])
+		cicekSepetiProducts = append(cicekSepetiProducts, cicekSepetiProduct)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("ciceksepeti", mappings); err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{}
+	for i, cicekSepetiProduct := range cicekSepetiProducts {
+		err := p.createOrUpdateProduct(ctx, cicekSepetiProduct)
+		if err == nil {
+			report.add(cicekSepetiProduct.SKU, listings[i].Barcode, ListingAccepted, "", "")
 			continue
 		}
-		
-		cicekSepetiProduct := p.convertToCicekSepetiProduct(productMap)
-		if err := p.createOrUpdateProduct(ctx, cicekSepetiProduct); err != nil {
-			return fmt.Errorf("failed to sync product %s: %v", cicekSepetiProduct.SKU, err)
+		reason, rejected := rejection(err)
+		if !rejected {
+			return report, fmt.Errorf("failed to sync product %s: %w", cicekSepetiProduct.SKU, err)
 		}
+		report.add(cicekSepetiProduct.SKU, listings[i].Barcode, ListingRejected, reason, "")
 	}
-	
-	return nil
+
+	return report, nil
+}
+
+// GetBatchStatus is not supported: ÇiçekSepeti answers every listing as it is
+// sent
+func (p *CicekSepetiProvider) GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error) {
+	return nil, ErrBatchStatusNotSupported
 }
 
-// UpdateStockAndPrice updates stock and price information
-func (p *CicekSepetiProvider) UpdateStockAndPrice(ctx context.Context, updates []interface{}) error {
+// UpdateStockAndPrice updates stock and price information. ÇiçekSepeti
+// keys listings by SKU.
+func (p *CicekSepetiProvider) UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error {
+	mappings := make([]*mapping, 0, len(updates))
+	for i := range updates {
+		m := newMapping("ciceksepeti", EntityStockPrice, updates[i].SKU)
+		m.stockPrice(&updates[i])
+		m.require("sku", updates[i].SKU)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("ciceksepeti", mappings); err != nil {
+		return err
+	}
+
 	for _, update := range updates {
-		updateMap, ok := update.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		sku, _ := updateMap["sku"].(string)
-		quantity, _ := updateMap["quantity"].(int)
-		price, _ := updateMap["price"].(float64)
-		
-		if err := p.updateProductStock(ctx, sku, quantity); err != nil {
-			return fmt.Errorf("failed to update stock for %s: %v", sku, err)
+		if update.Stock != nil {
+			if err := p.updateProductStock(ctx, update.SKU, *update.Stock); err != nil {
+				return fmt.Errorf("failed to update stock for %s: %v", update.SKU, err)
+			}
 		}
-		
-		if err := p.updateProductPrice(ctx, sku, price); err != nil {
-			return fmt.Errorf("failed to update price for %s: %v", sku, err)
+
+		if update.Price != nil {
+			if err := p.updateProductPrice(ctx, update.SKU, *update.Price); err != nil {
+				return fmt.Errorf("failed to update price for %s: %v", update.SKU, err)
+			}
 		}
 	}
-	
+
 	return nil
 }
 
-// GetProducts retrieves products from ÇiçekSepeti
-func (p *CicekSepetiProvider) GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/products"
-	
+// GetProducts retrieves listings from ÇiçekSepeti
+func (p *CicekSepetiProvider) GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error) {
+	page, limit := cicekSepetiPage(query.PageToken, query.PageSize)
+
 	queryParams := url.Values{}
-	if page, ok := params["page"].(int); ok {
-		queryParams.Set("page", fmt.Sprintf("%d", page))
-	}
-	if limit, ok := params["limit"].(int); ok {
-		queryParams.Set("limit", fmt.Sprintf("%d", limit))
-	}
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint+"?"+queryParams.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
-	
-	var apiResponse CicekSepetiAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+	queryParams.Set("page", strconv.Itoa(page))
+	queryParams.Set("limit", strconv.Itoa(limit))
+
+	var products []CicekSepetiProduct
+	if err := p.call(ctx, "GET", "/products?"+queryParams.Encode(), nil, &products); err != nil {
 		return nil, err
 	}
-	
-	if !apiResponse.Success {
-		return nil, fmt.Errorf("ÇiçekSepeti API error: %s", apiResponse.Error.Message)
+
+	result := &ListingPage{Listings: make([]Listing, 0, len(products))}
+	for _, product := range products {
+		result.Listings = append(result.Listings, product.toListing())
 	}
-	
-	// Convert response to standard format
-	products := make([]interface{}, 0)
-	if data, ok := apiResponse.Data.([]interface{}); ok {
-		products = data
+	if len(products) == limit {
+		result.NextPageToken = strconv.Itoa(page + 1)
 	}
-	
-	return products, nil
+	return result, nil
 }
 
 // GetOrders retrieves orders from ÇiçekSepeti
-func (p *CicekSepetiProvider) GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/orders"
-	
+func (p *CicekSepetiProvider) GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error) {
+	page, limit := cicekSepetiPage(query.PageToken, query.PageSize)
+
 	queryParams := url.Values{}
-	if page, ok := params["page"].(int); ok {
-		queryParams.Set("page", fmt.Sprintf("%d", page))
+	queryParams.Set("page", strconv.Itoa(page))
+	queryParams.Set("limit", strconv.Itoa(limit))
+	if !query.Since.IsZero() {
+		queryParams.Set("startDate", query.Since.Format(time.RFC3339))
 	}
-	if limit, ok := params["limit"].(int); ok {
-		queryParams.Set("limit", fmt.Sprintf("%d", limit))
+	if !query.Until.IsZero() {
+		queryParams.Set("endDate", query.Until.Format(time.RFC3339))
 	}
-	if status, ok := params["status"].(string); ok {
+	if query.Status != "" {
+		status, ok := cicekSepetiStatusFilters[query.Status]
+		if !ok {
+			return nil, orderStatusError("ciceksepeti", "", query.Status)
+		}
 		queryParams.Set("status", status)
 	}
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint+"?"+queryParams.Encode(), nil)
-	if err != nil {
+
+	var orders []CicekSepetiOrder
+	if err := p.call(ctx, "GET", "/orders?"+queryParams.Encode(), nil, &orders); err != nil {
 		return nil, err
 	}
-	
-	var apiResponse CicekSepetiAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
-		return nil, err
+
+	result := &OrderPage{Orders: make([]Order, 0, len(orders))}
+	for _, order := range orders {
+		result.Orders = append(result.Orders, order.toOrder())
 	}
-	
-	if !apiResponse.Success {
-		return nil, fmt.Errorf("ÇiçekSepeti API error: %s", apiResponse.Error.Message)
+	if len(orders) == limit {
+		result.NextPageToken = strconv.Itoa(page + 1)
 	}
-	
-	// Convert response to standard format
-	orders := make([]interface{}, 0)
-	if data, ok := apiResponse.Data.([]interface{}); ok {
-		orders = data
-	}
-	
-	return orders, nil
+	return result, nil
 }
 
 // UpdateOrderStatus updates order status
-func (p *CicekSepetiProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error {
+func (p *CicekSepetiProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error {
+	cicekSepetiStatus, ok := cicekSepetiStatusUpdates[status]
+	if !ok {
+		return orderStatusError("ciceksepeti", orderID, status)
+	}
+	m := newMapping("ciceksepeti", EntityShipment, orderID)
+	m.shipment(status, shipment)
+	if err := validationError("ciceksepeti", []*mapping{m}); err != nil {
+		return err
+	}
+
 	endpoint := fmt.Sprintf("/orders/%s/status", orderID)
-	
+
 	requestData := map[string]interface{}{
-		"status": status,
+		"status": cicekSepetiStatus,
 	}
-	
+
 	// Add tracking info if provided
-	if trackingNo, ok := params["tracking_no"].(string); ok {
-		requestData["trackingNo"] = trackingNo
-	}
-	if carrierCode, ok := params["carrier_code"].(string); ok {
+	if shipment != nil {
+		requestData["trackingNo"] = shipment.TrackingNumber
+		carrierCode := shipment.CarrierCode
+		if carrierCode == "" {
+			carrierCode = shipment.Carrier
+		}
 		requestData["carrierCode"] = carrierCode
 	}
-	
-	response, err := p.makeRequest(ctx, "PUT", endpoint, requestData)
-	if err != nil {
-		return err
-	}
-	
-	var apiResponse CicekSepetiAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
-		return err
-	}
-	
-	if !apiResponse.Success {
-		return fmt.Errorf("ÇiçekSepeti API error: %s", apiResponse.Error.Message)
-	}
-	
-	return nil
+
+	return p.call(ctx, "PUT", endpoint, requestData, nil)
 }
 
 // GetCategories retrieves categories from ÇiçekSepeti
-func (p *CicekSepetiProvider) GetCategories(ctx context.Context) ([]interface{}, error) {
-	endpoint := "/categories"
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint, nil)
-	if err != nil {
+func (p *CicekSepetiProvider) GetCategories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	if err := p.call(ctx, "GET", "/categories", nil, &categories); err != nil {
 		return nil, err
 	}
-	
-	var apiResponse CicekSepetiAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+	return categories, nil
+}
+
+// GetCategoryAttributes retrieves the attributes of a ÇiçekSepeti category
+func (p *CicekSepetiProvider) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	var data struct {
+		CategoryAttributes []struct {
+			AttributeID     int    `json:"attributeId"`
+			AttributeName   string `json:"attributeName"`
+			Required        bool   `json:"required"`
+			Varianter       bool   `json:"varianter"`
+			AttributeValues []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"attributeValues"`
+		} `json:"categoryAttributes"`
+	}
+	if err := p.call(ctx, "GET", "/categories/"+url.PathEscape(categoryID)+"/attributes", nil, &data); err != nil {
 		return nil, err
 	}
-	
-	if !apiResponse.Success {
-		return nil, fmt.Errorf("ÇiçekSepeti API error: %s", apiResponse.Error.Message)
-	}
-	
-	// Convert response to standard format
-	categories := make([]interface{}, 0)
-	if data, ok := apiResponse.Data.([]interface{}); ok {
-		categories = data
+
+	attributes := make([]CategoryAttribute, 0, len(data.CategoryAttributes))
+	for _, a := range data.CategoryAttributes {
+		attribute := CategoryAttribute{
+			ID:          strconv.Itoa(a.AttributeID),
+			Name:        a.AttributeName,
+			Required:    a.Required,
+			AllowCustom: len(a.AttributeValues) == 0,
+			Varianter:   a.Varianter,
+		}
+		for _, value := range a.AttributeValues {
+			attribute.Values = append(attribute.Values, AttributeValue{ID: strconv.Itoa(value.ID), Name: value.Name})
+		}
+		attributes = append(attributes, attribute)
 	}
-	
-	return categories, nil
+	return attributes, nil
 }
 
 // GetBrands retrieves brands from ÇiçekSepeti
-func (p *CicekSepetiProvider) GetBrands(ctx context.Context) ([]interface{}, error) {
-	endpoint := "/brands"
-	
-	response, err := p.makeRequest(ctx, "GET", endpoint, nil)
-	if err != nil {
+func (p *CicekSepetiProvider) GetBrands(ctx context.Context) ([]Brand, error) {
+	var brands []Brand
+	if err := p.call(ctx, "GET", "/brands", nil, &brands); err != nil {
 		return nil, err
 	}
-	
-	var apiResponse CicekSepetiAPIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
-		return nil, err
-	}
-	
-	if !apiResponse.Success {
-		return nil, fmt.Errorf("ÇiçekSepeti API error: %s", apiResponse.Error.Message)
-	}
-	
-	// Convert response to standard format
-	brands := make([]interface{}, 0)
-	if data, ok := apiResponse.Data.([]interface{}); ok {
-		brands = data
-	}
-	
 	return brands, nil
 }
 
@@ -385,12 +434,19 @@ func (p *CicekSepetiProvider) makeRequest(ctx context.Context, method, endpoint
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("User-Agent", "KolajAI-CicekSepeti-Integration/1.0")
 	
+	class := endpointClass(endpoint)
+	if err := p.limiter.Wait(ctx, "ciceksepeti", class, p.rateLimit); err != nil {
+		return nil, err
+	}
+	
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
+	p.limiter.Observe("ciceksepeti", class, resp, p.rateLimit)
+	
 	responseBody := make([]byte, 0)
 	buf := make([]byte, 1024)
 	for {
@@ -404,7 +460,7 @@ func (p *CicekSepetiProvider) makeRequest(ctx context.Context, method, endpoint
 	}
 	
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("ÇiçekSepeti API error: %d - %s", resp.StatusCode, string(responseBody))
+		return nil, apiError("ciceksepeti", resp.StatusCode, fmt.Sprintf("ÇiçekSepeti API error: %d - %s", resp.StatusCode, string(responseBody)))
 	}
 	
 	return responseBody, nil
@@ -414,12 +470,7 @@ func (p *CicekSepetiProvider) makeRequest(ctx context.Context, method, endpoint
 func (p *CicekSepetiProvider) createOrUpdateProduct(ctx context.Context, product CicekSepetiProduct) error {
 	endpoint := "/products"
 	
-	_, err := p.makeRequest(ctx, "POST", endpoint, product)
-	if err != nil {
-		return err
-	}
-	
-	return nil
+	return p.call(ctx, "POST", endpoint, product, nil)
 }
 
 // updateProductStock updates product stock
@@ -446,35 +497,176 @@ func (p *CicekSepetiProvider) updateProductPrice(ctx context.Context, sku string
 	return err
 }
 
-// convertToCicekSepetiProduct converts generic product to ÇiçekSepeti product format
-func (p *CicekSepetiProvider) convertToCicekSepetiProduct(product map[string]interface{}) CicekSepetiProduct {
+// call makes a request to the ÇiçekSepeti API and decodes the data of a
+// successful response into data, which may be nil
+func (p *CicekSepetiProvider) call(ctx context.Context, method, endpoint string, requestData interface{}, data interface{}) error {
+	response, err := p.makeRequest(ctx, method, endpoint, requestData)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse CicekSepetiAPIResponse
+	if err := json.Unmarshal(response, &apiResponse); err != nil {
+		return err
+	}
+
+	if !apiResponse.Success {
+		return apiError("ciceksepeti", 0, fmt.Sprintf("ÇiçekSepeti API error: %s", apiResponse.Error.Message))
+	}
+
+	if data == nil || len(apiResponse.Data) == 0 || string(apiResponse.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(apiResponse.Data, data); err != nil {
+		return fmt.Errorf("failed to parse ÇiçekSepeti response: %w", err)
+	}
+	return nil
+}
+
+// cicekSepetiPage reads a page token and size, defaulting to the first
+// page of 50
+func cicekSepetiPage(pageToken string, pageSize int) (int, int) {
+	page, err := strconv.Atoi(pageToken)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	return page, pageSize
+}
+
+// cicekSepetiOrderStatuses maps ÇiçekSepeti order statuses to canonical ones
+var cicekSepetiOrderStatuses = map[string]string{
+	"New":       OrderStatusPending,
+	"Approved":  OrderStatusConfirmed,
+	"Preparing": OrderStatusProcessing,
+	"Shipped":   OrderStatusShipped,
+	"Delivered": OrderStatusDelivered,
+	"Cancelled": OrderStatusCancelled,
+	"Returned":  OrderStatusReturned,
+}
+
+// cicekSepetiStatusFilters maps canonical statuses to the ÇiçekSepeti
+// status orders are filtered by
+var cicekSepetiStatusFilters = map[string]string{
+	OrderStatusPending:    "New",
+	OrderStatusConfirmed:  "Approved",
+	OrderStatusProcessing: "Preparing",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusDelivered:  "Delivered",
+	OrderStatusCancelled:  "Cancelled",
+	OrderStatusReturned:   "Returned",
+}
+
+// cicekSepetiStatusUpdates maps the canonical statuses a seller can move
+// an order to onto ÇiçekSepeti's
+var cicekSepetiStatusUpdates = map[string]string{
+	OrderStatusConfirmed:  "Approved",
+	OrderStatusProcessing: "Preparing",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusCancelled:  "Cancelled",
+}
+
+// mapListing maps a listing to a ÇiçekSepeti product, recording every
+// field ÇiçekSepeti would reject
+func (p *CicekSepetiProvider) mapListing(listing *Listing) (CicekSepetiProduct, *mapping) {
+	m := newMapping("ciceksepeti", EntityListing, listing.SKU)
+	m.listing(listing, "TRY")
+	m.require("barcode", listing.Barcode)
+	m.images(listing.Images, 1, 0)
+
+	category := listing.CategoryID
+	if category == "" {
+		category = listing.Category
+	}
+	m.require("category", category)
+
 	cicekSepetiProduct := CicekSepetiProduct{
-		SKU:         getString(product, "sku"),
-		Name:        getString(product, "title"),
-		Description: getString(product, "description"),
-		Category:    getString(product, "category"),
-		Price:       getFloat64(product, "price"),
-		Stock:       getInt(product, "quantity"),
-		Brand:       getString(product, "brand"),
-		Barcode:     getString(product, "barcode"),
-		Attributes:  make(map[string]interface{}),
+		SKU:         listing.SKU,
+		Name:        listing.Title,
+		Description: listing.Description,
+		Category:    category,
+		Price:       listing.Price,
+		Stock:       listing.Stock,
+		Images:      listing.Images,
+		Brand:       listing.Brand,
+		Barcode:     listing.Barcode,
+		Attributes:  make(map[string]interface{}, len(listing.Attributes)),
 	}
-	
-	// Set images
-	if images, ok := product["images"].([]interface{}); ok {
-		imageList := make([]string, 0)
-		for _, img := range images {
-			if imgStr, ok := img.(string); ok {
-				imageList = append(imageList, imgStr)
-			}
-		}
-		cicekSepetiProduct.Images = imageList
+
+	for i, attribute := range listing.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		m.require(field+".name", attribute.Name)
+		m.require(field+".value", attribute.Value)
+		cicekSepetiProduct.Attributes[attribute.Name] = attribute.Value
 	}
-	
-	// Set attributes
-	if attributes, ok := product["attributes"].(map[string]interface{}); ok {
-		cicekSepetiProduct.Attributes = attributes
+
+	return cicekSepetiProduct, m
+}
+
+// toListing maps a ÇiçekSepeti product back to a listing
+func (product CicekSepetiProduct) toListing() Listing {
+	listing := Listing{
+		SKU:         product.SKU,
+		Barcode:     product.Barcode,
+		Title:       product.Name,
+		Description: product.Description,
+		Brand:       product.Brand,
+		CategoryID:  product.Category,
+		Price:       product.Price,
+		Currency:    defaultCurrency,
+		Stock:       product.Stock,
+		Images:      product.Images,
+		Status:      ProductStatusActive,
+	}
+	for name, value := range product.Attributes {
+		listing.Attributes = append(listing.Attributes, Attribute{Name: name, Value: fmt.Sprint(value)})
+	}
+	return listing
+}
+
+// toOrder maps a ÇiçekSepeti order to a canonical order
+func (order CicekSepetiOrder) toOrder() Order {
+	address := order.ShippingInfo.Address
+	result := Order{
+		ID:                order.OrderID,
+		OrderNumber:       order.OrderNumber,
+		Status:            canonicalOrderStatus(cicekSepetiOrderStatuses, order.Status),
+		MarketplaceStatus: order.Status,
+		CustomerID:        order.CustomerInfo.CustomerID,
+		CustomerName:      order.CustomerInfo.FirstName + " " + order.CustomerInfo.LastName,
+		CustomerEmail:     order.CustomerInfo.Email,
+		CustomerPhone:     order.CustomerInfo.Phone,
+		ShippingAddress: Address{
+			FirstName:  address.Name,
+			Address1:   address.AddressLine,
+			City:       address.City,
+			State:      address.District,
+			PostalCode: address.PostalCode,
+			Country:    address.Country,
+			Phone:      order.CustomerInfo.Phone,
+		},
+		TotalAmount:    order.TotalAmount,
+		Currency:       defaultCurrency,
+		PaymentMethod:  order.PaymentMethod,
+		PaymentStatus:  PaymentStatusPaid,
+		ShippingMethod: order.ShippingInfo.CarrierCode,
+		TrackingNumber: order.ShippingInfo.TrackingNo,
+		OrderDate:      order.OrderDate,
 	}
-	
-	return cicekSepetiProduct
-}
\ No newline at end of file
+
+	for _, line := range order.OrderItems {
+		result.Items = append(result.Items, OrderItem{
+			ProductID:  line.ProductID,
+			SKU:        line.SKU,
+			Name:       line.ProductName,
+			Quantity:   line.Quantity,
+			Price:      line.UnitPrice,
+			TotalPrice: line.TotalPrice,
+		})
+		result.Subtotal += line.TotalPrice
+	}
+
+	return result
+}
@@ -0,0 +1,108 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+var cicekSepetiTestCredentials = integrations.Credentials{
+	APIKey: testValue("CICEKSEPETI_API_KEY", "test-api-key"),
+}
+
+func newCicekSepetiContract(t *testing.T, fixture string) (*CicekSepetiProvider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewCicekSepetiProvider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	if err := p.Initialize(context.Background(), cicekSepetiTestCredentials, map[string]interface{}{"environment": "sandbox"}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+func TestCicekSepetiOrdersContract(t *testing.T) {
+	p, recorder := newCicekSepetiContract(t, "ciceksepeti_orders")
+
+	query := OrderQuery{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), PageSize: 2}
+	first, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken != "2" {
+		t.Fatalf("first page: got %d orders, next %q", len(first.Orders), first.NextPageToken)
+	}
+	order := first.Orders[0]
+	if order.ID != "CS-4001" || order.Status != OrderStatusPending || order.CustomerName != "Ayşe Yılmaz" {
+		t.Errorf("order: got %s %s %q", order.ID, order.Status, order.CustomerName)
+	}
+
+	query.PageToken = first.NextPageToken
+	last, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders page 2: %v", err)
+	}
+	if len(last.Orders) != 1 || last.NextPageToken != "" {
+		t.Fatalf("last page: got %d orders, next %q", len(last.Orders), last.NextPageToken)
+	}
+
+	want := "Bearer " + cicekSepetiTestCredentials.APIKey
+	for _, request := range recorder.Requests() {
+		if got := request.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: Authorization %q", request.URL.Path, got)
+		}
+	}
+}
+
+func TestCicekSepetiSyncContract(t *testing.T) {
+	p, _ := newCicekSepetiContract(t, "ciceksepeti_sync")
+
+	// A refusal reported in the body of a 200 rejects only that listing
+	report, err := p.SyncProducts(context.Background(), []Listing{testListing("TS-RED-M", "8680000000011"), testListing("TS-RED-L", "8680000000028")})
+	if err != nil {
+		t.Fatalf("SyncProducts: %v", err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results", len(report.Results))
+	}
+	if accepted := report.Results[0]; accepted.Status != ListingAccepted {
+		t.Errorf("first listing: got %+v", accepted)
+	}
+	if rejected := report.Results[1]; rejected.Status != ListingRejected || !strings.Contains(rejected.Message, "barkod") {
+		t.Errorf("second listing: got %+v", rejected)
+	}
+}
+
+func TestCicekSepetiErrorContract(t *testing.T) {
+	p, _ := newCicekSepetiContract(t, "ciceksepeti_errors")
+	ctx := context.Background()
+	listings := []Listing{testListing("TS-RED-M", "8680000000011")}
+
+	// 422 rejects the listing
+	report, err := p.SyncProducts(ctx, listings)
+	if err != nil {
+		t.Fatalf("SyncProducts 422: %v", err)
+	}
+	if result := report.Results[0]; result.Status != ListingRejected || !strings.Contains(result.Message, "422") {
+		t.Errorf("422: got %+v", result)
+	}
+
+	// 500 stops the sync with a retryable error
+	_, err = p.SyncProducts(ctx, listings)
+	var marketplaceErr *MarketplaceError
+	if !errors.As(err, &marketplaceErr) || marketplaceErr.StatusCode != 500 || !marketplaceErr.Retryable {
+		t.Fatalf("500: got %v", err)
+	}
+
+	// success false on a read is an error, with the API's message
+	_, err = p.GetOrders(ctx, OrderQuery{PageSize: 50})
+	if err == nil || !strings.Contains(err.Error(), "Geçersiz tarih aralığı") {
+		t.Errorf("GetOrders: got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package marketplace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+// The contract tests run the providers against recorded marketplace
+// traffic in testdata. To refresh a fixture against the live API, run the
+// test with HTTPREPLAY=record and the credentials named by testValue set.
+
+// replay returns a recorder for the named fixture that fails the test on
+// requests the fixture does not answer and interactions left unused
+func replay(t *testing.T, name string) *httpreplay.Recorder {
+	t.Helper()
+	recorder, err := httpreplay.New(filepath.Join("testdata", name+".json"), httpreplay.Config{Mode: httpreplay.ModeFromEnv()})
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+		}
+		for _, request := range recorder.Unmatched() {
+			t.Errorf("unexpected request %s", request)
+		}
+		for _, interaction := range recorder.Unused() {
+			t.Errorf("request not sent: %s %s", interaction.Request.Method, interaction.Request.URL)
+		}
+	})
+	return recorder
+}
+
+// testValue returns the environment variable when recording and fallback
+// otherwise, so replays never depend on real credentials
+func testValue(env, fallback string) string {
+	if httpreplay.ModeFromEnv() == httpreplay.ModeRecord {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+	}
+	return fallback
+}
+
+// testListing is a listing that maps cleanly on every marketplace
+func testListing(sku, barcode string) Listing {
+	return Listing{
+		ProductID:    1,
+		SKU:          sku,
+		Barcode:      barcode,
+		Title:        "Pamuklu Kadın Tişört",
+		Description:  "Yüzde yüz pamuk, bisiklet yaka kadın tişört.",
+		Brand:        "KolajAI",
+		BrandID:      "1791",
+		Category:     "Tişört",
+		CategoryID:   "411",
+		Price:        149.9,
+		ListPrice:    179.9,
+		Currency:     "TRY",
+		Stock:        25,
+		VatRate:      20,
+		DispatchDays: 2,
+		Images:       []string{"https://cdn.kolajai.com/products/" + sku + "-1.jpg"},
+		Attributes:   []Attribute{{ID: "338", Name: "Renk", Value: "Kırmızı", ValueID: "6980"}},
+		Weight:       0.3,
+		Dimensions:   Dimensions{Length: 30, Width: 25, Height: 2, Unit: "cm"},
+	}
+}
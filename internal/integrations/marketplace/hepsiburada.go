@@ -8,7 +8,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +26,7 @@ type HepsiburadaProvider struct {
 	baseURL     string
 	merchantID  string
 	rateLimit   integrations.RateLimitInfo
+	limiter     *integrations.RateLimiter
 }
 
 // HepsiburadaProduct represents a Hepsiburada product structure
@@ -161,9 +164,20 @@ func NewHepsiburadaProvider() *HepsiburadaProvider {
 			RequestsRemaining: 100,
 			ResetsAt:          time.Now().Add(time.Minute),
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *HepsiburadaProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *HepsiburadaProvider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize sets up the Hepsiburada provider
 func (p *HepsiburadaProvider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	p.credentials = credentials
@@ -217,6 +231,29 @@ func (p *HepsiburadaProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// GetName returns the provider name
+func (p *HepsiburadaProvider) GetName() string {
+	return "Hepsiburada"
+}
+
+// GetType returns the provider type
+func (p *HepsiburadaProvider) GetType() string {
+	return "marketplace"
+}
+
+// GetMetrics returns provider metrics
+func (p *HepsiburadaProvider) GetMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"rate_limit_remaining":  p.rateLimit.RequestsRemaining,
+		"rate_limit_per_minute": p.rateLimit.RequestsPerMinute,
+		"rate_limit_resets_at":  p.rateLimit.ResetsAt.Unix(),
+		"provider_name":         "hepsiburada",
+		"base_url":              p.baseURL,
+		"merchant_id":           p.merchantID,
+		"rate_limiter":          p.limiter.State("hepsiburada"),
+	}
+}
+
 // GetCapabilities returns the capabilities of this integration
 func (p *HepsiburadaProvider) GetCapabilities() []string {
 	return []string{
@@ -242,125 +279,202 @@ func (p *HepsiburadaProvider) Close() error {
 	return nil
 }
 
-// SyncProducts syncs products to Hepsiburada
-func (p *HepsiburadaProvider) SyncProducts(ctx context.Context, products []interface{}) error {
-	hepsiburadaProducts := make([]HepsiburadaProduct, 0, len(products))
-	
-	for _, product := range products {
-		hepsiburadaProduct, err := p.convertToHepsiburadaProduct(product)
-		if err != nil {
-			continue // Skip invalid products
-		}
+// SyncProducts syncs listings to Hepsiburada. Nothing is sent if any
+// listing cannot be mapped.
+func (p *HepsiburadaProvider) SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error) {
+	hepsiburadaProducts := make([]HepsiburadaProduct, 0, len(listings))
+	mappings := make([]*mapping, 0, len(listings))
+	for i := range listings {
+		hepsiburadaProduct, m := p.mapListing(&listings[i])
 		hepsiburadaProducts = append(hepsiburadaProducts, hepsiburadaProduct)
+		mappings = append(mappings, m)
 	}
-	
+	if err := validationError("hepsiburada", mappings); err != nil {
+		return nil, err
+	}
+
 	// Send products in batches of 100 (Hepsiburada limit)
+	report := &SyncReport{}
 	batchSize := 100
 	for i := 0; i < len(hepsiburadaProducts); i += batchSize {
 		end := i + batchSize
 		if end > len(hepsiburadaProducts) {
 			end = len(hepsiburadaProducts)
 		}
-		
+
 		batch := hepsiburadaProducts[i:end]
-		err := p.sendProductBatch(ctx, batch)
+		trackingID, err := p.sendProductBatch(ctx, batch)
+		status, message := ListingPending, ""
 		if err != nil {
-			return err
+			reason, rejected := rejection(err)
+			if !rejected {
+				return report, err
+			}
+			status, message = ListingRejected, reason
+		} else if trackingID == "" {
+			status = ListingAccepted
+		}
+		for _, product := range batch {
+			report.add(product.MerchantSKU, product.Barcode, status, message, trackingID)
 		}
 	}
-	
-	return nil
+
+	return report, nil
 }
 
-// GetProducts retrieves products from Hepsiburada
-func (p *HepsiburadaProvider) GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/api/products/v1/products"
-	
-	// Add query parameters
-	queryParams := make([]string, 0)
-	if offset, ok := params["offset"].(int); ok {
-		queryParams = append(queryParams, "offset="+strconv.Itoa(offset))
+// GetBatchStatus returns the outcome of the listings of an import, by the
+// tracking ID Hepsiburada returned for it
+func (p *HepsiburadaProvider) GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error) {
+	endpoint := fmt.Sprintf("/api/products/v1/products/status/%s", url.PathEscape(batchID))
+
+	var response struct {
+		Status string `json:"status"`
+		Data   []struct {
+			MerchantSKU       string `json:"merchantSku"`
+			Barcode           string `json:"barcode"`
+			ImportStatus      string `json:"importStatus"`
+			ValidationResults []struct {
+				AttributeName string `json:"attributeName"`
+				Message       string `json:"message"`
+			} `json:"validationResults"`
+		} `json:"data"`
 	}
-	if limit, ok := params["limit"].(int); ok {
-		queryParams = append(queryParams, "limit="+strconv.Itoa(limit))
+	if err := p.makeRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return nil, err
 	}
-	
-	if len(queryParams) > 0 {
-		endpoint += "?" + strings.Join(queryParams, "&")
+
+	status := &BatchStatus{BatchID: batchID, Done: response.Status == "DONE" || response.Status == "COMPLETED"}
+	for _, item := range response.Data {
+		result := ListingResult{SKU: item.MerchantSKU, Barcode: item.Barcode, BatchID: batchID}
+		switch item.ImportStatus {
+		case "SUCCESS", "MATCHED", "CREATED":
+			result.Status = ListingAccepted
+		case "FAILED", "REJECTED":
+			result.Status = ListingRejected
+			reasons := make([]string, 0, len(item.ValidationResults))
+			for _, v := range item.ValidationResults {
+				if v.AttributeName != "" {
+					reasons = append(reasons, v.AttributeName+": "+v.Message)
+				} else {
+					reasons = append(reasons, v.Message)
+				}
+			}
+			result.Message = strings.Join(reasons, "; ")
+		default:
+			result.Status = ListingPending
+		}
+		status.Results = append(status.Results, result)
 	}
-	
+	return status, nil
+}
+
+// GetProducts retrieves listings from Hepsiburada
+func (p *HepsiburadaProvider) GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error) {
+	offset, _ := strconv.Atoi(query.PageToken)
+	limit := query.PageSize
+	if limit <= 0 {
+		limit = 50
+	}
+	endpoint := fmt.Sprintf("/api/products/v1/products?offset=%d&limit=%d", offset, limit)
+
 	var response struct {
-		Products []HepsiburadaProduct `json:"products"`
-		TotalCount int `json:"totalCount"`
+		Products   []HepsiburadaProduct `json:"products"`
+		TotalCount int                  `json:"totalCount"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	products := make([]interface{}, len(response.Products))
-	for i, product := range response.Products {
-		products[i] = product
+
+	result := &ListingPage{Listings: make([]Listing, 0, len(response.Products))}
+	for _, product := range response.Products {
+		result.Listings = append(result.Listings, product.toListing())
 	}
-	
-	return products, nil
+	if next := offset + len(response.Products); len(response.Products) > 0 && next < response.TotalCount {
+		result.NextPageToken = strconv.Itoa(next)
+	}
+	return result, nil
 }
 
 // GetOrders retrieves orders from Hepsiburada
-func (p *HepsiburadaProvider) GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/api/orders/v1/orders"
-	
-	// Add query parameters
-	queryParams := make([]string, 0)
-	if startDate, ok := params["start_date"].(string); ok {
-		queryParams = append(queryParams, "startDate="+startDate)
+func (p *HepsiburadaProvider) GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error) {
+	offset, _ := strconv.Atoi(query.PageToken)
+	limit := query.PageSize
+	if limit <= 0 {
+		limit = 50
 	}
-	if endDate, ok := params["end_date"].(string); ok {
-		queryParams = append(queryParams, "endDate="+endDate)
+
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	if !query.Since.IsZero() {
+		params.Set("startDate", query.Since.UTC().Format("2006-01-02 15:04"))
 	}
-	if status, ok := params["status"].(string); ok {
-		queryParams = append(queryParams, "status="+status)
+	if !query.Until.IsZero() {
+		params.Set("endDate", query.Until.UTC().Format("2006-01-02 15:04"))
 	}
-	
-	if len(queryParams) > 0 {
-		endpoint += "?" + strings.Join(queryParams, "&")
+	if query.Status != "" {
+		status, ok := hepsiburadaStatusFilters[query.Status]
+		if !ok {
+			return nil, orderStatusError("hepsiburada", "", query.Status)
+		}
+		params.Set("status", status)
 	}
-	
+	endpoint := "/api/orders/v1/orders?" + params.Encode()
+
 	var response struct {
-		Orders []HepsiburadaOrder `json:"orders"`
-		TotalCount int `json:"totalCount"`
+		Orders     []HepsiburadaOrder `json:"orders"`
+		TotalCount int                `json:"totalCount"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	orders := make([]interface{}, len(response.Orders))
-	for i, order := range response.Orders {
-		orders[i] = order
+
+	result := &OrderPage{Orders: make([]Order, 0, len(response.Orders))}
+	for _, order := range response.Orders {
+		result.Orders = append(result.Orders, order.toOrder())
 	}
-	
-	return orders, nil
+	if next := offset + len(response.Orders); len(response.Orders) > 0 && next < response.TotalCount {
+		result.NextPageToken = strconv.Itoa(next)
+	}
+	return result, nil
 }
 
-// UpdateStockAndPrice updates stock and price for products
-func (p *HepsiburadaProvider) UpdateStockAndPrice(ctx context.Context, updates []interface{}) error {
+// UpdateStockAndPrice updates stock and price for listings. Hepsiburada
+// keys listings by merchant SKU.
+func (p *HepsiburadaProvider) UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error {
 	stockItems := make([]HepsiburadaStockItem, 0)
 	priceItems := make([]HepsiburadaPriceItem, 0)
-	
-	for _, update := range updates {
-		stockItem, priceItem, err := p.convertToStockPriceItems(update)
-		if err != nil {
-			continue // Skip invalid items
+	mappings := make([]*mapping, 0, len(updates))
+
+	for i := range updates {
+		update := &updates[i]
+		m := newMapping("hepsiburada", EntityStockPrice, update.SKU)
+		m.stockPrice(update)
+		m.require("sku", update.SKU)
+		mappings = append(mappings, m)
+
+		if update.Stock != nil {
+			stockItems = append(stockItems, HepsiburadaStockItem{
+				MerchantSKU:    update.SKU,
+				AvailableStock: *update.Stock,
+			})
+		}
+		if update.Price != nil {
+			priceItems = append(priceItems, HepsiburadaPriceItem{
+				MerchantSKU: update.SKU,
+				Price:       *update.Price,
+				ListPrice:   listPrice(*update.Price, update.ListPrice),
+			})
 		}
-		stockItems = append(stockItems, stockItem)
-		priceItems = append(priceItems, priceItem)
 	}
-	
+	if err := validationError("hepsiburada", mappings); err != nil {
+		return err
+	}
+
 	// Update stock
 	if len(stockItems) > 0 {
 		stockRequest := HepsiburadaStockUpdate{Items: stockItems}
@@ -370,7 +484,7 @@ func (p *HepsiburadaProvider) UpdateStockAndPrice(ctx context.Context, updates [
 			return err
 		}
 	}
-	
+
 	// Update prices
 	if len(priceItems) > 0 {
 		priceRequest := HepsiburadaPriceUpdate{Items: priceItems}
@@ -380,72 +494,145 @@ func (p *HepsiburadaProvider) UpdateStockAndPrice(ctx context.Context, updates [
 			return err
 		}
 	}
-	
+
 	return nil
 }
 
 // UpdateOrderStatus updates order status
-func (p *HepsiburadaProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error {
+func (p *HepsiburadaProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error {
+	hepsiburadaStatus, ok := hepsiburadaStatusUpdates[status]
+	if !ok {
+		return orderStatusError("hepsiburada", orderID, status)
+	}
+	m := newMapping("hepsiburada", EntityShipment, orderID)
+	m.shipment(status, shipment)
+	if err := validationError("hepsiburada", []*mapping{m}); err != nil {
+		return err
+	}
+
 	endpoint := fmt.Sprintf("/api/orders/v1/orders/%s/status", orderID)
-	
+
 	request := map[string]interface{}{
-		"status": status,
-	}
-	
-	// Add additional parameters
-	if trackingNumber, ok := params["tracking_number"].(string); ok {
-		request["trackingNumber"] = trackingNumber
+		"status": hepsiburadaStatus,
 	}
-	if cargoCompany, ok := params["cargo_company"].(string); ok {
-		request["cargoCompany"] = cargoCompany
+	if shipment != nil {
+		request["trackingNumber"] = shipment.TrackingNumber
+		request["cargoCompany"] = shipment.Carrier
+		if shipment.Carrier == "" {
+			request["cargoCompany"] = shipment.CarrierCode
+		}
 	}
-	
+
 	var response map[string]interface{}
 	return p.makeRequest(ctx, "PUT", endpoint, request, &response)
 }
 
 // GetCategories retrieves categories from Hepsiburada
-func (p *HepsiburadaProvider) GetCategories(ctx context.Context) ([]interface{}, error) {
+func (p *HepsiburadaProvider) GetCategories(ctx context.Context) ([]Category, error) {
 	endpoint := "/api/categories/v1/categories"
-	
+
 	var response struct {
 		Categories []Category `json:"categories"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	categories := make([]interface{}, len(response.Categories))
-	for i, category := range response.Categories {
-		categories[i] = category
+
+	return response.Categories, nil
+}
+
+// hepsiburadaCategoryAttribute is an attribute of a Hepsiburada category
+type hepsiburadaCategoryAttribute struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Mandatory bool   `json:"mandatory"`
+	Type      string `json:"type"`
+}
+
+// GetCategoryAttributes retrieves the attributes of a Hepsiburada category.
+// Values are read for the enum attributes, the only ones that restrict
+// them.
+func (p *HepsiburadaProvider) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	endpoint := "/product/api/categories/" + url.PathEscape(categoryID) + "/attributes"
+
+	var response struct {
+		Data struct {
+			BaseAttributes    []hepsiburadaCategoryAttribute `json:"baseAttributes"`
+			Attributes        []hepsiburadaCategoryAttribute `json:"attributes"`
+			VariantAttributes []hepsiburadaCategoryAttribute `json:"variantAttributes"`
+		} `json:"data"`
 	}
-	
-	return categories, nil
+
+	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := [][]hepsiburadaCategoryAttribute{response.Data.BaseAttributes, response.Data.Attributes, response.Data.VariantAttributes}
+	attributes := make([]CategoryAttribute, 0)
+	for i, group := range groups {
+		for _, a := range group {
+			attribute := CategoryAttribute{
+				ID:          a.ID,
+				Name:        a.Name,
+				Required:    a.Mandatory,
+				AllowCustom: a.Type != "enum",
+				Varianter:   i == 2,
+			}
+			if a.Type == "enum" {
+				values, err := p.getAttributeValues(ctx, categoryID, a.ID)
+				if err != nil {
+					return nil, err
+				}
+				attribute.Values = values
+			}
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	return attributes, nil
+}
+
+// getAttributeValues retrieves the values of an enum attribute
+func (p *HepsiburadaProvider) getAttributeValues(ctx context.Context, categoryID, attributeID string) ([]AttributeValue, error) {
+	endpoint := fmt.Sprintf("/product/api/categories/%s/attribute/%s/values?page=0&size=1000",
+		url.PathEscape(categoryID), url.PathEscape(attributeID))
+
+	var response struct {
+		Data []struct {
+			ID    string `json:"id"`
+			Value string `json:"value"`
+		} `json:"data"`
+	}
+
+	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]AttributeValue, 0, len(response.Data))
+	for _, value := range response.Data {
+		values = append(values, AttributeValue{ID: value.ID, Name: value.Value})
+	}
+	return values, nil
 }
 
 // GetBrands retrieves brands from Hepsiburada
-func (p *HepsiburadaProvider) GetBrands(ctx context.Context) ([]interface{}, error) {
+func (p *HepsiburadaProvider) GetBrands(ctx context.Context) ([]Brand, error) {
 	endpoint := "/api/brands/v1/brands"
-	
+
 	var response struct {
 		Brands []Brand `json:"brands"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	brands := make([]interface{}, len(response.Brands))
-	for i, brand := range response.Brands {
-		brands[i] = brand
-	}
-	
-	return brands, nil
+
+	return response.Brands, nil
 }
 
 // ProcessWebhook processes incoming webhooks from Hepsiburada
@@ -509,6 +696,12 @@ func (p *HepsiburadaProvider) makeRequest(ctx context.Context, method, endpoint
 	auth := p.generateAuthHeader()
 	req.Header.Set("Authorization", auth)
 	
+	// Wait for the rate limiter
+	class := endpointClass(endpoint)
+	if err := p.limiter.Wait(ctx, "hepsiburada", class, p.rateLimit); err != nil {
+		return err
+	}
+	
 	// Execute request
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
@@ -524,14 +717,21 @@ func (p *HepsiburadaProvider) makeRequest(ctx context.Context, method, endpoint
 	
 	// Update rate limit info
 	p.updateRateLimit(resp.Header)
+	p.limiter.Observe("hepsiburada", class, resp, p.rateLimit)
 	
-	// Check for API errors
+	// Check for API errors. The body names the reason, which is what
+	// vendors need to fix a rejected listing.
 	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		message := fmt.Sprintf("Hepsiburada API returned status %d", resp.StatusCode)
+		if len(errorBody) > 0 {
+			message += ": " + strings.TrimSpace(string(errorBody))
+		}
 		return &integrations.IntegrationError{
 			Code:       "API_ERROR",
-			Message:    fmt.Sprintf("Hepsiburada API returned status %d", resp.StatusCode),
+			Message:    message,
 			Provider:   "hepsiburada",
-			Retryable:  resp.StatusCode >= 500,
+			Retryable:  resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
 			Timestamp:  time.Now(),
 			StatusCode: resp.StatusCode,
 		}
@@ -598,118 +798,215 @@ func (p *HepsiburadaProvider) validateWebhookSignature(payload []byte, signature
 
 // Helper methods for data conversion and event handling
 
-func (p *HepsiburadaProvider) convertToHepsiburadaProduct(product interface{}) (HepsiburadaProduct, error) {
-	productMap, ok := product.(map[string]interface{})
-	if !ok {
-		return HepsiburadaProduct{}, fmt.Errorf("invalid product format")
+// hepsiburadaOrderStatuses maps Hepsiburada order statuses to canonical ones
+var hepsiburadaOrderStatuses = map[string]string{
+	"Open":                OrderStatusPending,
+	"Unpacked":            OrderStatusConfirmed,
+	"Packaged":            OrderStatusProcessing,
+	"InTransit":           OrderStatusShipped,
+	"Shipped":             OrderStatusShipped,
+	"Delivered":           OrderStatusDelivered,
+	"CancelledByMerchant": OrderStatusCancelled,
+	"CancelledByCustomer": OrderStatusCancelled,
+	"CancelledBySap":      OrderStatusCancelled,
+	"Returned":            OrderStatusReturned,
+}
+
+// hepsiburadaStatusFilters maps canonical statuses to the Hepsiburada
+// status orders are filtered by
+var hepsiburadaStatusFilters = map[string]string{
+	OrderStatusPending:    "Open",
+	OrderStatusConfirmed:  "Unpacked",
+	OrderStatusProcessing: "Packaged",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusDelivered:  "Delivered",
+	OrderStatusCancelled:  "CancelledByMerchant",
+	OrderStatusReturned:   "Returned",
+}
+
+// hepsiburadaStatusUpdates maps the canonical statuses a merchant can move
+// an order to onto Hepsiburada's
+var hepsiburadaStatusUpdates = map[string]string{
+	OrderStatusConfirmed:  "Unpacked",
+	OrderStatusProcessing: "Packaged",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusDelivered:  "Delivered",
+	OrderStatusCancelled:  "CancelledByMerchant",
+}
+
+// mapListing maps a listing to a Hepsiburada product, recording every
+// field Hepsiburada would reject
+func (p *HepsiburadaProvider) mapListing(listing *Listing) (HepsiburadaProduct, *mapping) {
+	m := newMapping("hepsiburada", EntityListing, listing.SKU)
+	m.listing(listing, "TRY")
+	m.maxLength("sku", listing.SKU, 100)
+	m.maxLength("title", listing.Title, 200)
+	m.require("description", listing.Description)
+	m.require("category", listing.Category)
+	m.require("brand", listing.Brand)
+	m.images(listing.Images, 1, 10)
+	dispatchDays := listing.DispatchDays
+	if dispatchDays == 0 {
+		dispatchDays = 1
 	}
-	
-	hepsiburadaProduct := HepsiburadaProduct{
-		MerchantSKU:      getString(productMap, "sku"),
-		HepsiburadaSKU:   getString(productMap, "hepsiburada_sku"),
-		ProductName:      getString(productMap, "title"),
-		ProductNameEn:    getString(productMap, "title_en"),
-		Description:      getString(productMap, "description"),
-		DescriptionEn:    getString(productMap, "description_en"),
-		CategoryName:     getString(productMap, "category"),
-		BrandName:        getString(productMap, "brand"),
-		Barcode:          getString(productMap, "barcode"),
-		Price:            getFloat64(productMap, "price"),
-		ListPrice:        getFloat64(productMap, "list_price"),
-		CurrencyType:     "TRY",
-		AvailableStock:   getInt(productMap, "quantity"),
-		DispatchTime:     getIntWithDefault(productMap, "dispatch_time", 1),
-		CargoCompanyName: getStringWithDefault(productMap, "cargo_company", "Aras Kargo"),
-		Status:           getStringWithDefault(productMap, "status", "Active"),
+	if dispatchDays < 0 || dispatchDays > 30 {
+		m.fail("dispatch_days", "must be between 1 and 30")
 	}
-	
-	// Set list price if not provided
-	if hepsiburadaProduct.ListPrice == 0 && hepsiburadaProduct.Price > 0 {
-		hepsiburadaProduct.ListPrice = hepsiburadaProduct.Price * 1.2 // Add 20% margin
+
+	status := "Active"
+	if listing.Status != "" && listing.Status != ProductStatusActive {
+		status = "Passive"
 	}
-	
-	// Set images
-	if images, ok := productMap["images"].([]interface{}); ok {
-		hepsiburadaImages := make([]HepsiburadaImage, 0)
-		for _, img := range images {
-			if imgStr, ok := img.(string); ok {
-				hepsiburadaImages = append(hepsiburadaImages, HepsiburadaImage{URL: imgStr})
-			}
-		}
-		hepsiburadaProduct.Images = hepsiburadaImages
+
+	hepsiburadaProduct := HepsiburadaProduct{
+		MerchantSKU:      listing.SKU,
+		ProductName:      listing.Title,
+		Description:      listing.Description,
+		CategoryName:     listing.Category,
+		BrandName:        listing.Brand,
+		Barcode:          listing.Barcode,
+		Price:            listing.Price,
+		ListPrice:        listPrice(listing.Price, listing.ListPrice),
+		CurrencyType:     "TRY",
+		AvailableStock:   listing.Stock,
+		DispatchTime:     dispatchDays,
+		CargoCompanyName: "Aras Kargo",
+		Status:           status,
+		Dimensions: HepsiburadaDimensions{
+			Width:  listing.Dimensions.Width,
+			Height: listing.Dimensions.Height,
+			Length: listing.Dimensions.Length,
+			Weight: listing.Weight,
+		},
 	}
-	
-	// Set attributes
-	if attributes, ok := productMap["attributes"].(map[string]interface{}); ok {
-		hepsiburadaAttributes := make([]HepsiburadaAttribute, 0)
-		for key, value := range attributes {
-			if valueStr, ok := value.(string); ok {
-				hepsiburadaAttributes = append(hepsiburadaAttributes, HepsiburadaAttribute{
-					Name:  key,
-					Value: valueStr,
-				})
-			}
-		}
-		hepsiburadaProduct.Attributes = hepsiburadaAttributes
+
+	hepsiburadaProduct.Images = make([]HepsiburadaImage, 0, len(listing.Images))
+	for _, image := range listing.Images {
+		hepsiburadaProduct.Images = append(hepsiburadaProduct.Images, HepsiburadaImage{URL: image})
 	}
-	
-	// Set dimensions
-	if dimensions, ok := productMap["dimensions"].(map[string]interface{}); ok {
-		hepsiburadaProduct.Dimensions = HepsiburadaDimensions{
-			Width:  getFloat64(dimensions, "width"),
-			Height: getFloat64(dimensions, "height"),
-			Length: getFloat64(dimensions, "length"),
-			Weight: getFloat64(dimensions, "weight"),
-		}
+
+	// Hepsiburada takes attributes by name
+	hepsiburadaProduct.Attributes = make([]HepsiburadaAttribute, 0, len(listing.Attributes))
+	for i, attribute := range listing.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		m.require(field+".name", attribute.Name)
+		m.require(field+".value", attribute.Value)
+		hepsiburadaProduct.Attributes = append(hepsiburadaProduct.Attributes, HepsiburadaAttribute{
+			Name:  attribute.Name,
+			Value: attribute.Value,
+		})
 	}
-	
-	return hepsiburadaProduct, nil
-}
 
-// Helper functions moved to helpers.go
+	return hepsiburadaProduct, m
+}
 
-func (p *HepsiburadaProvider) convertToStockPriceItems(update interface{}) (HepsiburadaStockItem, HepsiburadaPriceItem, error) {
-	updateMap, ok := update.(map[string]interface{})
-	if !ok {
-		return HepsiburadaStockItem{}, HepsiburadaPriceItem{}, fmt.Errorf("invalid update format")
+// toListing maps a Hepsiburada product back to a listing
+func (product HepsiburadaProduct) toListing() Listing {
+	status := ProductStatusActive
+	if product.Status != "" && product.Status != "Active" {
+		status = ProductStatusInactive
 	}
-	
-	merchantSKU := getString(updateMap, "sku")
-	if merchantSKU == "" {
-		return HepsiburadaStockItem{}, HepsiburadaPriceItem{}, fmt.Errorf("merchant SKU is required")
+	listing := Listing{
+		ExternalID:   product.HepsiburadaSKU,
+		SKU:          product.MerchantSKU,
+		Barcode:      product.Barcode,
+		Title:        product.ProductName,
+		Description:  product.Description,
+		Brand:        product.BrandName,
+		Category:     product.CategoryName,
+		Price:        product.Price,
+		ListPrice:    product.ListPrice,
+		Currency:     product.CurrencyType,
+		Stock:        product.AvailableStock,
+		DispatchDays: product.DispatchTime,
+		Weight:       product.Dimensions.Weight,
+		Dimensions: Dimensions{
+			Length: product.Dimensions.Length,
+			Width:  product.Dimensions.Width,
+			Height: product.Dimensions.Height,
+			Unit:   "cm",
+		},
+		Status: status,
 	}
-	
-	stockItem := HepsiburadaStockItem{
-		MerchantSKU:    merchantSKU,
-		AvailableStock: getInt(updateMap, "quantity"),
+	for _, image := range product.Images {
+		listing.Images = append(listing.Images, image.URL)
 	}
-	
-	price := getFloat64(updateMap, "price")
-	listPrice := getFloat64(updateMap, "list_price")
-	
-	// If list price is not provided, use price + margin
-	if listPrice == 0 && price > 0 {
-		listPrice = price * 1.2 // Add 20% margin
+	for _, attribute := range product.Attributes {
+		listing.Attributes = append(listing.Attributes, Attribute{Name: attribute.Name, Value: attribute.Value})
 	}
-	
-	priceItem := HepsiburadaPriceItem{
-		MerchantSKU: merchantSKU,
-		Price:       price,
-		ListPrice:   listPrice,
+	return listing
+}
+
+// toOrder maps a Hepsiburada order to a canonical order
+func (order HepsiburadaOrder) toOrder() Order {
+	result := Order{
+		ID:                order.OrderNumber,
+		OrderNumber:       order.OrderNumber,
+		Status:            canonicalOrderStatus(hepsiburadaOrderStatuses, order.Status),
+		MarketplaceStatus: order.Status,
+		CustomerName:      order.CustomerName,
+		CustomerEmail:     order.CustomerEmail,
+		CustomerPhone:     order.CustomerPhone,
+		BillingAddress:    order.BillingAddress.toAddress(),
+		ShippingAddress:   order.ShippingAddress.toAddress(),
+		TaxAmount:         order.TaxAmount,
+		ShippingAmount:    order.ShippingAmount,
+		TotalAmount:       order.TotalAmount,
+		Currency:          listingCurrency(order.Currency),
+		PaymentMethod:     order.PaymentType,
+		PaymentStatus:     PaymentStatusPaid,
+		ShippingMethod:    order.CargoCompany,
+		TrackingNumber:    order.TrackingNumber,
+		OrderDate:         order.OrderDate,
 	}
-	
-	return stockItem, priceItem, nil
+
+	for _, line := range order.Items {
+		result.Items = append(result.Items, OrderItem{
+			ID:         line.LineItemId,
+			ProductID:  line.HepsiburadaSKU,
+			SKU:        line.MerchantSKU,
+			Name:       line.ProductName,
+			Quantity:   line.Quantity,
+			Price:      line.Price,
+			TotalPrice: line.TotalPrice,
+			TaxAmount:  line.VatAmount,
+		})
+		result.Subtotal += line.TotalPrice
+	}
+
+	return result
 }
 
-func (p *HepsiburadaProvider) sendProductBatch(ctx context.Context, products []HepsiburadaProduct) error {
+// toAddress maps a Hepsiburada address to a canonical address
+func (address HepsiburadaAddress) toAddress() Address {
+	return Address{
+		FirstName:  address.FirstName,
+		LastName:   address.LastName,
+		Address1:   address.Address,
+		City:       address.City,
+		State:      address.District,
+		PostalCode: address.PostalCode,
+		Country:    address.Country,
+		Phone:      address.Phone,
+	}
+}
+
+// sendProductBatch sends a batch of products and returns the tracking ID
+// Hepsiburada reports the import's progress under
+func (p *HepsiburadaProvider) sendProductBatch(ctx context.Context, products []HepsiburadaProduct) (string, error) {
 	endpoint := "/api/products/v1/products"
 	
 	request := map[string]interface{}{
 		"products": products,
 	}
 	
-	var response map[string]interface{}
-	return p.makeRequest(ctx, "POST", endpoint, request, &response)
+	var response struct {
+		TrackingID string `json:"trackingId"`
+	}
+	if err := p.makeRequest(ctx, "POST", endpoint, request, &response); err != nil {
+		return "", err
+	}
+	return response.TrackingID, nil
 }
 
 func (p *HepsiburadaProvider) handleOrderCreatedEvent(ctx context.Context, event WebhookEvent) error {
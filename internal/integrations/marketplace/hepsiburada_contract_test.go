@@ -0,0 +1,131 @@
+package marketplace
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+var hepsiburadaTestCredentials = integrations.Credentials{
+	APIKey:    testValue("HEPSIBURADA_USERNAME", "test-user"),
+	APISecret: testValue("HEPSIBURADA_PASSWORD", "test-password"),
+}
+
+func newHepsiburadaContract(t *testing.T, fixture string) (*HepsiburadaProvider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewHepsiburadaProvider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	if err := p.Initialize(context.Background(), hepsiburadaTestCredentials, map[string]interface{}{
+		"merchant_id": testValue("HEPSIBURADA_MERCHANT_ID", "a1b2c3d4-0000-4000-8000-000000000001"),
+	}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+func TestHepsiburadaOrdersContract(t *testing.T) {
+	p, recorder := newHepsiburadaContract(t, "hepsiburada_orders")
+
+	query := OrderQuery{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), PageSize: 2}
+	first, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken != "2" {
+		t.Fatalf("first page: got %d orders, next %q", len(first.Orders), first.NextPageToken)
+	}
+	order := first.Orders[0]
+	if order.ID != "HB-2001" || order.Status != OrderStatusPending || order.Subtotal != 299.8 {
+		t.Errorf("order: got %s %s subtotal %.2f", order.ID, order.Status, order.Subtotal)
+	}
+	if len(order.Items) != 1 || order.Items[0].SKU != "TS-RED-M" {
+		t.Errorf("order items: got %+v", order.Items)
+	}
+
+	query.PageToken = first.NextPageToken
+	last, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders offset 2: %v", err)
+	}
+	if len(last.Orders) != 1 || last.NextPageToken != "" {
+		t.Fatalf("last page: got %d orders, next %q", len(last.Orders), last.NextPageToken)
+	}
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(hepsiburadaTestCredentials.APIKey+":"+hepsiburadaTestCredentials.APISecret))
+	for _, request := range recorder.Requests() {
+		if got := request.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: Authorization %q", request.URL.Path, got)
+		}
+	}
+	// The rate limit comes from the last response's headers
+	rateLimit := p.GetRateLimit()
+	if rateLimit.RequestsPerMinute != 240 || rateLimit.RequestsRemaining != 238 {
+		t.Errorf("rate limit: got %d of %d remaining", rateLimit.RequestsRemaining, rateLimit.RequestsPerMinute)
+	}
+	if want := time.Unix(1788220860, 0); !rateLimit.ResetsAt.Equal(want) {
+		t.Errorf("rate limit reset: got %s, want %s", rateLimit.ResetsAt, want)
+	}
+}
+
+func TestHepsiburadaSyncContract(t *testing.T) {
+	p, _ := newHepsiburadaContract(t, "hepsiburada_sync")
+	ctx := context.Background()
+
+	report, err := p.SyncProducts(ctx, []Listing{testListing("TS-RED-M", "8680000000011"), testListing("TS-RED-L", "8680000000028")})
+	if err != nil {
+		t.Fatalf("SyncProducts: %v", err)
+	}
+	for _, result := range report.Results {
+		if result.Status != ListingPending || result.BatchID != "7f3e9c1a-52b4-4d8e-a6f0-91c2d3e4b5a6" {
+			t.Errorf("%s: got %s in batch %q", result.SKU, result.Status, result.BatchID)
+		}
+	}
+
+	status, err := p.GetBatchStatus(ctx, report.Results[0].BatchID)
+	if err != nil {
+		t.Fatalf("GetBatchStatus: %v", err)
+	}
+	if !status.Done || len(status.Results) != 2 {
+		t.Fatalf("batch: done %v with %d results", status.Done, len(status.Results))
+	}
+	if accepted := status.Results[0]; accepted.SKU != "TS-RED-M" || accepted.Status != ListingAccepted {
+		t.Errorf("first listing: got %+v", accepted)
+	}
+	rejected := status.Results[1]
+	if rejected.SKU != "TS-RED-L" || rejected.Status != ListingRejected || !strings.HasPrefix(rejected.Message, "Beden: ") {
+		t.Errorf("second listing: got %+v", rejected)
+	}
+}
+
+func TestHepsiburadaErrorContract(t *testing.T) {
+	p, _ := newHepsiburadaContract(t, "hepsiburada_errors")
+	ctx := context.Background()
+	listings := []Listing{testListing("TS-RED-M", "8680000000011")}
+
+	// 400: the import is refused, so its listings are rejected
+	report, err := p.SyncProducts(ctx, listings)
+	if err != nil {
+		t.Fatalf("SyncProducts 400: %v", err)
+	}
+	if result := report.Results[0]; result.Status != ListingRejected || !strings.Contains(result.Message, "categoryName") {
+		t.Errorf("400: got %+v", result)
+	}
+
+	// 401: bad credentials stop the sync instead of rejecting listings
+	report, err = p.SyncProducts(ctx, listings)
+	var integrationErr *integrations.IntegrationError
+	if !errors.As(err, &integrationErr) || integrationErr.StatusCode != 401 || integrationErr.Retryable {
+		t.Fatalf("401: got %v", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("401: got results %+v", report.Results)
+	}
+}
@@ -0,0 +1,457 @@
+package marketplace
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kolajAi/internal/models"
+)
+
+// Entities a mapping error can refer to
+const (
+	EntityListing     = "listing"
+	EntityStockPrice  = "stock_price"
+	EntityOrderStatus = "order_status"
+	EntityShipment    = "shipment"
+)
+
+// defaultCurrency is the currency of listings that do not set one
+const defaultCurrency = "TRY"
+
+// MappingError reports a canonical value that a marketplace cannot accept
+type MappingError struct {
+	Provider string `json:"provider"`
+	Entity   string `json:"entity"`
+	Key      string `json:"key"` // SKU, barcode or order ID
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+func (e *MappingError) Error() string {
+	return fmt.Sprintf("%s %s %q: %s %s", e.Provider, e.Entity, e.Key, e.Field, e.Message)
+}
+
+// ValidationError is returned, before anything is sent, when values of a
+// request cannot be mapped to the marketplace's API
+type ValidationError struct {
+	Provider string          `json:"provider"`
+	Errors   []*MappingError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s: %d fields cannot be mapped, first: %s", e.Provider, len(e.Errors), e.Errors[0].Error())
+}
+
+// mapping collects the mapping errors of a single value
+type mapping struct {
+	provider string
+	entity   string
+	key      string
+	errs     []*MappingError
+}
+
+func newMapping(provider, entity, key string) *mapping {
+	return &mapping{provider: provider, entity: entity, key: key}
+}
+
+// fail records an error on a field
+func (m *mapping) fail(field, format string, args ...interface{}) {
+	m.errs = append(m.errs, &MappingError{
+		Provider: m.provider,
+		Entity:   m.entity,
+		Key:      m.key,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// require fails if value is blank
+func (m *mapping) require(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		m.fail(field, "is required")
+	}
+}
+
+// maxLength fails if value has more than max characters
+func (m *mapping) maxLength(field, value string, max int) {
+	if n := utf8.RuneCountInString(value); n > max {
+		m.fail(field, "is %d characters, at most %d are allowed", n, max)
+	}
+}
+
+// numericID parses a marketplace ID that must be a positive integer
+func (m *mapping) numericID(field, value string) int {
+	if strings.TrimSpace(value) == "" {
+		m.fail(field, "is required")
+		return 0
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || id <= 0 {
+		m.fail(field, "must be a numeric ID, got %q", value)
+		return 0
+	}
+	return id
+}
+
+// images checks the number of images and that each is an absolute URL
+func (m *mapping) images(images []string, min, max int) {
+	if len(images) < min {
+		m.fail("images", "needs at least %d image(s)", min)
+	}
+	if max > 0 && len(images) > max {
+		m.fail("images", "has %d images, at most %d are allowed", len(images), max)
+	}
+	for i, image := range images {
+		u, err := url.Parse(image)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			m.fail(fmt.Sprintf("images[%d]", i), "must be an absolute http(s) URL, got %q", image)
+		}
+	}
+}
+
+// listing checks the fields every marketplace needs
+func (m *mapping) listing(l *Listing, currencies ...string) {
+	m.require("sku", l.SKU)
+	m.require("title", l.Title)
+	if l.Price <= 0 {
+		m.fail("price", "must be greater than zero")
+	}
+	if l.ListPrice != 0 && l.ListPrice < l.Price {
+		m.fail("list_price", "must not be lower than price")
+	}
+	if l.Stock < 0 {
+		m.fail("stock", "must not be negative")
+	}
+	m.currency(listingCurrency(l.Currency), currencies...)
+}
+
+// currency fails if currency is not one of the supported currencies
+func (m *mapping) currency(currency string, supported ...string) {
+	for _, c := range supported {
+		if strings.EqualFold(c, currency) {
+			return
+		}
+	}
+	m.fail("currency", "%s is not supported, use one of %s", currency, strings.Join(supported, ", "))
+}
+
+// stockPrice checks the fields every stock and price update needs
+func (m *mapping) stockPrice(u *StockPriceUpdate) {
+	if u.Stock == nil && u.Price == nil {
+		m.fail("stock", "or price must be set")
+	}
+	if u.Stock != nil && *u.Stock < 0 {
+		m.fail("stock", "must not be negative")
+	}
+	if u.Price != nil && *u.Price <= 0 {
+		m.fail("price", "must be greater than zero")
+	}
+	if u.Price != nil && u.ListPrice != 0 && u.ListPrice < *u.Price {
+		m.fail("list_price", "must not be lower than price")
+	}
+}
+
+// shipment checks that a shipped order comes with tracking information
+func (m *mapping) shipment(status string, shipment *Shipment) {
+	if status != OrderStatusShipped {
+		return
+	}
+	if shipment == nil {
+		m.fail("shipment", "is required for shipped orders")
+		return
+	}
+	m.require("shipment.tracking_number", shipment.TrackingNumber)
+	if shipment.Carrier == "" && shipment.CarrierCode == "" {
+		m.fail("shipment.carrier", "is required")
+	}
+}
+
+// validationError collects the errors of a batch into a *ValidationError,
+// or returns nil if every value mapped
+func validationError(provider string, mappings []*mapping) error {
+	var errs []*MappingError
+	for _, m := range mappings {
+		errs = append(errs, m.errs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Provider: provider, Errors: errs}
+}
+
+// orderStatusError is the mapping error for a canonical order status a
+// marketplace cannot be moved to
+func orderStatusError(provider, orderID, status string) error {
+	m := newMapping(provider, EntityOrderStatus, orderID)
+	m.fail("status", "%q cannot be set on this marketplace", status)
+	return validationError(provider, []*mapping{m})
+}
+
+// canonicalOrderStatus maps a marketplace order status through statuses,
+// falling back to pending for statuses the mapping does not know
+func canonicalOrderStatus(statuses map[string]string, status string) string {
+	if canonical, ok := statuses[status]; ok {
+		return canonical
+	}
+	return OrderStatusPending
+}
+
+// listingCurrency returns the currency of a listing, defaulting to TRY
+func listingCurrency(currency string) string {
+	if currency == "" {
+		return defaultCurrency
+	}
+	return strings.ToUpper(currency)
+}
+
+// listPrice returns the list price to send with a price, which marketplaces
+// require to be set and not lower than the price
+func listPrice(price, listPrice float64) float64 {
+	if listPrice < price {
+		return price
+	}
+	return listPrice
+}
+
+// vatRate returns the VAT rate of a listing, defaulting to Turkey's
+// standard rate
+func vatRate(rate int) int {
+	if rate == 0 {
+		return 20
+	}
+	return rate
+}
+
+// amazonListingAttributes are the product type attributes the Amazon
+// provider fills from the listing's own fields
+var amazonListingAttributes = map[string]bool{
+	"item_name":                              true,
+	"brand":                                  true,
+	"product_description":                    true,
+	"purchasable_offer":                      true,
+	"fulfillment_availability":               true,
+	"externally_assigned_product_identifier": true,
+}
+
+// listingFieldAttributes are, by integration, the category attributes
+// providers fill from the listing's own fields rather than its Attributes
+var listingFieldAttributes = map[string]map[string]bool{
+	"amazon":    amazonListingAttributes,
+	"amazon_tr": amazonListingAttributes,
+}
+
+// FilledFromListing reports whether the provider of an integration fills a
+// category attribute from the listing's title, brand, price and the like,
+// so it needs no attribute mapping
+func FilledFromListing(integrationID, attributeID string) bool {
+	return listingFieldAttributes[integrationID][attributeID]
+}
+
+// ListingFromProduct builds the canonical listing of a catalog product.
+// Barcode, brand, category and attributes are marketplace specific and are
+// left for the caller to fill in from its mappings.
+func ListingFromProduct(product *models.Product) Listing {
+	description := product.Description
+	if description == "" {
+		description = product.ShortDesc
+	}
+	images := product.Images
+	if len(images) == 0 && product.Image != "" {
+		images = []string{product.Image}
+	}
+	status := ProductStatusInactive
+	if product.Status == "active" && product.Stock > 0 {
+		status = ProductStatusActive
+	}
+
+	return Listing{
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		Title:       product.Name,
+		Description: description,
+		Price:       product.Price,
+		ListPrice:   listPrice(product.Price, product.ComparePrice),
+		Currency:    defaultCurrency,
+		Stock:       product.Stock,
+		Images:      images,
+		Weight:      product.Weight,
+		Dimensions:  parseDimensions(product.Dimensions),
+		Status:      status,
+	}
+}
+
+// StockPriceUpdateFromProduct builds an update setting a listing's stock
+// and price to the catalog product's
+func StockPriceUpdateFromProduct(product *models.Product, barcode string) StockPriceUpdate {
+	stock := product.Stock
+	price := product.Price
+	return StockPriceUpdate{
+		SKU:       product.SKU,
+		Barcode:   barcode,
+		Stock:     &stock,
+		Price:     &price,
+		ListPrice: listPrice(product.Price, product.ComparePrice),
+	}
+}
+
+// ShipmentFromOrder builds the shipment of a shipped order
+func ShipmentFromOrder(order *models.Order) *Shipment {
+	shippedAt := time.Now()
+	if order.ShippedAt != nil {
+		shippedAt = *order.ShippedAt
+	}
+	return &Shipment{
+		Carrier:        order.CarrierName,
+		TrackingNumber: order.TrackingNumber,
+		ShippedAt:      shippedAt,
+	}
+}
+
+// ModelOrderStatus maps a canonical order status to the local order status
+// it corresponds to
+func ModelOrderStatus(status string) string {
+	switch status {
+	case OrderStatusConfirmed:
+		return models.OrderStatusConfirmed
+	case OrderStatusProcessing:
+		return models.OrderStatusProcessing
+	case OrderStatusShipped:
+		return models.OrderStatusShipped
+	case OrderStatusDelivered:
+		return models.OrderStatusDelivered
+	case OrderStatusCancelled:
+		return models.OrderStatusCancelled
+	case OrderStatusReturned:
+		return models.OrderStatusRefunded
+	default:
+		return models.OrderStatusPending
+	}
+}
+
+// OrderStatusFromModel maps a local order status to the canonical status
+// pushed to marketplaces
+func OrderStatusFromModel(status string) string {
+	if status == models.OrderStatusRefunded {
+		return OrderStatusReturned
+	}
+	return status
+}
+
+// ToModel builds the local order of a marketplace order. The items carry
+// the SKU and price the marketplace sold them at; matching them to catalog
+// products is left to the caller. channel is the integration the order came
+// from and becomes its source channel.
+func (o *Order) ToModel(channel string) *models.Order {
+	paymentStatus := "paid"
+	if o.PaymentStatus == PaymentStatusPending || o.PaymentStatus == PaymentStatusFailed {
+		paymentStatus = o.PaymentStatus
+	}
+	orderNumber := o.OrderNumber
+	if orderNumber == "" {
+		orderNumber = o.ID
+	}
+
+	order := &models.Order{
+		OrderNumber:     strings.ToUpper(channel) + "-" + orderNumber,
+		Status:          ModelOrderStatus(o.Status),
+		PaymentStatus:   paymentStatus,
+		PaymentMethod:   o.PaymentMethod,
+		SubtotalAmount:  o.Subtotal,
+		TaxAmount:       o.TaxAmount,
+		ShippingAmount:  o.ShippingAmount,
+		DiscountAmount:  o.DiscountAmount,
+		TotalAmount:     o.TotalAmount,
+		Currency:        listingCurrency(o.Currency),
+		ShippingAddress: o.ShippingAddress.street(),
+		ShippingCity:    o.ShippingAddress.City,
+		ShippingState:   o.ShippingAddress.State,
+		ShippingZip:     o.ShippingAddress.PostalCode,
+		ShippingCountry: o.ShippingAddress.Country,
+		ShippingPhone:   o.ShippingAddress.Phone,
+		BillingAddress:  o.BillingAddress.street(),
+		BillingCity:     o.BillingAddress.City,
+		BillingState:    o.BillingAddress.State,
+		BillingZip:      o.BillingAddress.PostalCode,
+		BillingCountry:  o.BillingAddress.Country,
+		BillingPhone:    o.BillingAddress.Phone,
+		TrackingNumber:  o.TrackingNumber,
+		CarrierName:     o.ShippingMethod,
+		Notes:           o.Notes,
+		SourceChannel:   channel,
+		CreatedAt:       o.OrderDate,
+		UpdatedAt:       o.OrderDate,
+	}
+	if order.ShippingPhone == "" {
+		order.ShippingPhone = o.CustomerPhone
+	}
+	// Marketplace customers have no local account, so who ordered is kept
+	// with the notes
+	if o.CustomerName != "" || o.CustomerEmail != "" {
+		customer := strings.TrimSuffix(strings.TrimSpace(o.CustomerName+" <"+o.CustomerEmail+">"), " <>")
+		order.Notes = strings.TrimSpace("Customer: " + customer + "\n" + o.Notes)
+	}
+
+	subtotal := 0.0
+	for _, item := range o.Items {
+		total := item.TotalPrice
+		if total == 0 {
+			total = item.Price * float64(item.Quantity)
+		}
+		subtotal += total
+		order.Items = append(order.Items, models.OrderItem{
+			ProductName: item.Name,
+			ProductSKU:  item.SKU,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
+			TotalPrice:  total,
+			Status:      "pending",
+		})
+	}
+	if order.SubtotalAmount == 0 {
+		order.SubtotalAmount = subtotal
+	}
+	if order.TotalAmount == 0 {
+		order.TotalAmount = order.SubtotalAmount + order.TaxAmount + order.ShippingAmount - order.DiscountAmount
+	}
+
+	return order
+}
+
+// street joins the street lines of an address
+func (a Address) street() string {
+	return strings.TrimSpace(a.Address1 + " " + a.Address2)
+}
+
+// parseDimensions reads catalog dimensions written as "LxWxH" with an
+// optional unit, e.g. "30x20x10 cm"
+func parseDimensions(value string) Dimensions {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 {
+		return Dimensions{}
+	}
+	dimensions := Dimensions{Unit: "cm"}
+	if len(fields) > 1 {
+		dimensions.Unit = fields[1]
+	}
+	parts := strings.Split(fields[0], "x")
+	if len(parts) != 3 {
+		return Dimensions{}
+	}
+	values := make([]float64, 3)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return Dimensions{}
+		}
+		values[i] = v
+	}
+	dimensions.Length, dimensions.Width, dimensions.Height = values[0], values[1], values[2]
+	return dimensions
+}
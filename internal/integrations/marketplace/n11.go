@@ -21,6 +21,7 @@ type N11Provider struct {
 	apiKey      string
 	apiSecret   string
 	rateLimit   integrations.RateLimitInfo
+	limiter     *integrations.RateLimiter
 }
 
 // N11Product represents an N11 product structure
@@ -118,7 +119,7 @@ type N11APIResponse struct {
 		Status      string      `json:"status"`
 		ErrorCode   string      `json:"errorCode"`
 		ErrorMessage string     `json:"errorMessage"`
-		Data        interface{} `json:"data"`
+		Data        json.RawMessage `json:"data"`
 	} `json:"result"`
 }
 
@@ -133,9 +134,20 @@ func NewN11Provider() *N11Provider {
 			RequestsPerSecond: 5,
 			BurstSize:        10,
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *N11Provider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *N11Provider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize initializes the N11 provider
 func (p *N11Provider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	p.credentials = credentials
@@ -161,9 +173,18 @@ func (p *N11Provider) GetType() string {
 	return "marketplace"
 }
 
-// IsHealthy checks if the provider is healthy
-func (p *N11Provider) IsHealthy(ctx context.Context) (bool, error) {
-	return p.testConnection(ctx) == nil, nil
+// HealthCheck verifies the N11 integration is working
+func (p *N11Provider) HealthCheck(ctx context.Context) error {
+	if err := p.testConnection(ctx); err != nil {
+		return &integrations.IntegrationError{
+			Code:      "HEALTH_CHECK_FAILED",
+			Message:   "Failed to connect to N11 API",
+			Provider:  "n11",
+			Retryable: true,
+			Timestamp: time.Now(),
+		}
+	}
+	return nil
 }
 
 // GetMetrics returns provider metrics
@@ -171,6 +192,18 @@ func (p *N11Provider) GetMetrics() map[string]interface{} {
 	return map[string]interface{}{
 		"rate_limit_remaining": p.rateLimit.RequestsPerSecond,
 		"last_request_time":   time.Now().Unix(),
+		"rate_limiter":        p.limiter.State("n11"),
+	}
+}
+
+// GetCapabilities returns the capabilities of this integration
+func (p *N11Provider) GetCapabilities() []string {
+	return []string{
+		"product_sync",
+		"order_sync",
+		"inventory_sync",
+		"price_sync",
+		"category_mapping",
 	}
 }
 
@@ -179,193 +212,270 @@ func (p *N11Provider) GetRateLimit() integrations.RateLimitInfo {
 	return p.rateLimit
 }
 
-// SyncProducts syncs products to N11
-func (p *N11Provider) SyncProducts(ctx context.Context, products []interface{}) error {
-	for _, product := range products {
-		productMap, ok := product.(map[string]interface{})
-		if !ok {
+// Close cleans up any resources
+func (p *N11Provider) Close() error {
+	return nil
+}
+
+// SyncProducts syncs listings to N11. Nothing is sent if any listing
+// cannot be mapped.
+func (p *N11Provider) SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error) {
+	n11Products := make([]N11Product, 0, len(listings))
+	mappings := make([]*mapping, 0, len(listings))
+	for i := range listings {
+		n11Product, m := p.mapListing(&listings[i])
+		n11Products = append(n11Products, n11Product)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("n11", mappings); err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{}
+	for i, n11Product := range n11Products {
+		err := p.saveProduct(ctx, n11Product)
+		if err == nil {
+			report.add(n11Product.ProductSellerCode, listings[i].Barcode, ListingAccepted, "", "")
 			continue
 		}
-		
-		n11Product := p.convertToN11Product(productMap)
-		if err := p.saveProduct(ctx, n11Product); err != nil {
-			return fmt.Errorf("failed to sync product %s: %v", n11Product.ProductSellerCode, err)
+		reason, rejected := rejection(err)
+		if !rejected {
+			return report, fmt.Errorf("failed to sync product %s: %w", n11Product.ProductSellerCode, err)
 		}
+		report.add(n11Product.ProductSellerCode, listings[i].Barcode, ListingRejected, reason, "")
 	}
-	
-	return nil
+
+	return report, nil
 }
 
-// UpdateStockAndPrice updates stock and price information
-func (p *N11Provider) UpdateStockAndPrice(ctx context.Context, updates []interface{}) error {
+// GetBatchStatus is not supported: N11 answers every listing as it is
+// sent
+func (p *N11Provider) GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error) {
+	return nil, ErrBatchStatusNotSupported
+}
+
+// UpdateStockAndPrice updates stock and price information. N11 keys
+// listings by seller stock code.
+func (p *N11Provider) UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error {
+	mappings := make([]*mapping, 0, len(updates))
+	for i := range updates {
+		m := newMapping("n11", EntityStockPrice, updates[i].SKU)
+		m.stockPrice(&updates[i])
+		m.require("sku", updates[i].SKU)
+		mappings = append(mappings, m)
+	}
+	if err := validationError("n11", mappings); err != nil {
+		return err
+	}
+
 	for _, update := range updates {
-		updateMap, ok := update.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		sellerCode, _ := updateMap["seller_code"].(string)
-		quantity, _ := updateMap["quantity"].(int)
-		price, _ := updateMap["price"].(float64)
-		
-		if err := p.updateStock(ctx, sellerCode, quantity); err != nil {
-			return fmt.Errorf("failed to update stock for %s: %v", sellerCode, err)
+		if update.Stock != nil {
+			if err := p.updateStock(ctx, update.SKU, *update.Stock); err != nil {
+				return fmt.Errorf("failed to update stock for %s: %v", update.SKU, err)
+			}
 		}
-		
-		if err := p.updatePrice(ctx, sellerCode, price); err != nil {
-			return fmt.Errorf("failed to update price for %s: %v", sellerCode, err)
+
+		if update.Price != nil {
+			if err := p.updatePrice(ctx, update.SKU, *update.Price); err != nil {
+				return fmt.Errorf("failed to update price for %s: %v", update.SKU, err)
+			}
 		}
 	}
-	
+
 	return nil
 }
 
-// GetProducts retrieves products from N11
-func (p *N11Provider) GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/ProductService.do"
-	
+// GetProducts retrieves listings from N11
+func (p *N11Provider) GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error) {
+	page, _ := strconv.Atoi(query.PageToken)
+	size := query.PageSize
+	if size <= 0 {
+		size = 50
+	}
+
 	requestData := map[string]interface{}{
 		"auth": p.createAuth(),
 		"pagingData": map[string]interface{}{
-			"currentPage": params["page"],
-			"pageSize":    params["limit"],
+			"currentPage": page,
+			"pageSize":    size,
 		},
 	}
-	
-	response, err := p.makeRequest(ctx, "POST", endpoint, requestData)
-	if err != nil {
-		return nil, err
-	}
-	
-	var apiResponse N11APIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+
+	var products []N11Product
+	if err := p.call(ctx, "/ProductService.do", requestData, &products); err != nil {
 		return nil, err
 	}
-	
-	if apiResponse.Result.Status != "success" {
-		return nil, fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
+
+	result := &ListingPage{Listings: make([]Listing, 0, len(products))}
+	for _, product := range products {
+		result.Listings = append(result.Listings, product.toListing())
 	}
-	
-	// Convert response to standard format
-	products := make([]interface{}, 0)
-	if data, ok := apiResponse.Result.Data.([]interface{}); ok {
-		products = data
+	if len(products) == size {
+		result.NextPageToken = strconv.Itoa(page + 1)
 	}
-	
-	return products, nil
+	return result, nil
 }
 
 // GetOrders retrieves orders from N11
-func (p *N11Provider) GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := "/OrderService.do"
-	
+func (p *N11Provider) GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error) {
+	page, _ := strconv.Atoi(query.PageToken)
+	size := query.PageSize
+	if size <= 0 {
+		size = 50
+	}
+
+	searchData := map[string]interface{}{}
+	if !query.Since.IsZero() || !query.Until.IsZero() {
+		period := map[string]interface{}{}
+		if !query.Since.IsZero() {
+			period["startDate"] = query.Since.Format("02/01/2006")
+		}
+		if !query.Until.IsZero() {
+			period["endDate"] = query.Until.Format("02/01/2006")
+		}
+		searchData["period"] = period
+	}
+	if query.Status != "" {
+		status, ok := n11StatusFilters[query.Status]
+		if !ok {
+			return nil, orderStatusError("n11", "", query.Status)
+		}
+		searchData["status"] = status
+	}
+
 	requestData := map[string]interface{}{
-		"auth": p.createAuth(),
-		"searchData": params,
+		"auth":       p.createAuth(),
+		"searchData": searchData,
 		"pagingData": map[string]interface{}{
-			"currentPage": params["page"],
-			"pageSize":    params["limit"],
+			"currentPage": page,
+			"pageSize":    size,
 		},
 	}
-	
-	response, err := p.makeRequest(ctx, "POST", endpoint, requestData)
-	if err != nil {
-		return nil, err
-	}
-	
-	var apiResponse N11APIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+
+	var orders []N11Order
+	if err := p.call(ctx, "/OrderService.do", requestData, &orders); err != nil {
 		return nil, err
 	}
-	
-	if apiResponse.Result.Status != "success" {
-		return nil, fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
+
+	result := &OrderPage{Orders: make([]Order, 0, len(orders))}
+	for _, order := range orders {
+		result.Orders = append(result.Orders, order.toOrder())
 	}
-	
-	// Convert response to standard format
-	orders := make([]interface{}, 0)
-	if data, ok := apiResponse.Result.Data.([]interface{}); ok {
-		orders = data
+	if len(orders) == size {
+		result.NextPageToken = strconv.Itoa(page + 1)
 	}
-	
-	return orders, nil
+	return result, nil
 }
 
 // UpdateOrderStatus updates order status
-func (p *N11Provider) UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error {
-	endpoint := "/OrderService.do"
-	
+func (p *N11Provider) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error {
+	n11Status, ok := n11StatusUpdates[status]
+	if !ok {
+		return orderStatusError("n11", orderID, status)
+	}
+	m := newMapping("n11", EntityShipment, orderID)
+	m.shipment(status, shipment)
+	if err := validationError("n11", []*mapping{m}); err != nil {
+		return err
+	}
+
 	requestData := map[string]interface{}{
 		"auth": p.createAuth(),
 		"orderItemList": []map[string]interface{}{
 			{
-				"id": orderID,
-				"status": status,
+				"id":     orderID,
+				"status": n11Status,
 			},
 		},
 	}
-	
+
 	// Add tracking info if provided
-	if trackingNo, ok := params["tracking_no"].(string); ok {
+	if shipment != nil {
+		companyName := shipment.Carrier
+		if companyName == "" {
+			companyName = shipment.CarrierCode
+		}
 		requestData["shipmentInfo"] = map[string]interface{}{
-			"trackingNumber": trackingNo,
-			"companyName":   params["shipping_company"],
+			"trackingNumber": shipment.TrackingNumber,
+			"companyName":    companyName,
 		}
 	}
-	
-	response, err := p.makeRequest(ctx, "POST", endpoint, requestData)
-	if err != nil {
-		return err
-	}
-	
-	var apiResponse N11APIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
-		return err
-	}
-	
-	if apiResponse.Result.Status != "success" {
-		return fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
-	}
-	
-	return nil
+
+	return p.call(ctx, "/OrderService.do", requestData, nil)
 }
 
 // GetCategories retrieves categories from N11
-func (p *N11Provider) GetCategories(ctx context.Context) ([]interface{}, error) {
-	endpoint := "/CategoryService.do"
-	
+func (p *N11Provider) GetCategories(ctx context.Context) ([]Category, error) {
 	requestData := map[string]interface{}{
 		"auth": p.createAuth(),
 	}
-	
-	response, err := p.makeRequest(ctx, "POST", endpoint, requestData)
-	if err != nil {
-		return nil, err
+
+	var n11Categories []struct {
+		ID       json.Number `json:"id"`
+		Name     string      `json:"name"`
+		ParentID json.Number `json:"parentId"`
 	}
-	
-	var apiResponse N11APIResponse
-	if err := json.Unmarshal(response, &apiResponse); err != nil {
+	if err := p.call(ctx, "/CategoryService.do", requestData, &n11Categories); err != nil {
 		return nil, err
 	}
-	
-	if apiResponse.Result.Status != "success" {
-		return nil, fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
-	}
-	
-	// Convert response to standard format
-	categories := make([]interface{}, 0)
-	if data, ok := apiResponse.Result.Data.([]interface{}); ok {
-		categories = data
+
+	categories := make([]Category, 0, len(n11Categories))
+	for _, category := range n11Categories {
+		categories = append(categories, Category{
+			ID:       category.ID.String(),
+			Name:     category.Name,
+			ParentID: category.ParentID.String(),
+			Path:     category.Name,
+		})
 	}
-	
+
 	return categories, nil
 }
 
+// GetCategoryAttributes retrieves the attributes of an N11 category
+func (p *N11Provider) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	requestData := map[string]interface{}{
+		"auth":       p.createAuth(),
+		"categoryId": categoryID,
+	}
+
+	var n11Attributes []struct {
+		ID             json.Number `json:"id"`
+		Name           string      `json:"name"`
+		Mandatory      bool        `json:"mandatory"`
+		MultipleSelect bool        `json:"multipleSelect"`
+		CustomValue    bool        `json:"customValue"`
+		ValueList      []struct {
+			ID   json.Number `json:"id"`
+			Name string      `json:"name"`
+		} `json:"valueList"`
+	}
+	if err := p.call(ctx, "/CategoryService.do", requestData, &n11Attributes); err != nil {
+		return nil, err
+	}
+
+	attributes := make([]CategoryAttribute, 0, len(n11Attributes))
+	for _, a := range n11Attributes {
+		attribute := CategoryAttribute{
+			ID:          a.ID.String(),
+			Name:        a.Name,
+			Required:    a.Mandatory,
+			AllowCustom: a.CustomValue || len(a.ValueList) == 0,
+		}
+		for _, value := range a.ValueList {
+			attribute.Values = append(attribute.Values, AttributeValue{ID: value.ID.String(), Name: value.Name})
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
 // GetBrands retrieves brands from N11
-func (p *N11Provider) GetBrands(ctx context.Context) ([]interface{}, error) {
+func (p *N11Provider) GetBrands(ctx context.Context) ([]Brand, error) {
 	// N11 doesn't have a separate brands endpoint
 	// Brands are usually part of category attributes
-	return []interface{}{}, nil
+	return []Brand{}, nil
 }
 
 // testConnection tests the N11 API connection
@@ -417,12 +527,19 @@ func (p *N11Provider) makeRequest(ctx context.Context, method, endpoint string,
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "KolajAI-N11-Integration/1.0")
 	
+	class := endpointClass(endpoint)
+	if err := p.limiter.Wait(ctx, "n11", class, p.rateLimit); err != nil {
+		return nil, err
+	}
+	
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	
+	p.limiter.Observe("n11", class, resp, p.rateLimit)
+	
 	responseBody := make([]byte, 0)
 	buf := make([]byte, 1024)
 	for {
@@ -436,7 +553,7 @@ func (p *N11Provider) makeRequest(ctx context.Context, method, endpoint string,
 	}
 	
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("N11 API error: %d - %s", resp.StatusCode, string(responseBody))
+		return nil, apiError("n11", resp.StatusCode, fmt.Sprintf("N11 API error: %d - %s", resp.StatusCode, string(responseBody)))
 	}
 	
 	return responseBody, nil
@@ -462,7 +579,7 @@ func (p *N11Provider) saveProduct(ctx context.Context, product N11Product) error
 	}
 	
 	if apiResponse.Result.Status != "success" {
-		return fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
+		return apiError("n11", 0, fmt.Sprintf("N11 API error: %s", apiResponse.Result.ErrorMessage))
 	}
 	
 	return nil
@@ -530,58 +647,184 @@ func (p *N11Provider) updatePrice(ctx context.Context, sellerCode string, price
 	return nil
 }
 
-// convertToN11Product converts generic product to N11 product format
-func (p *N11Provider) convertToN11Product(product map[string]interface{}) N11Product {
+// call posts a request to an N11 service and decodes the data of a
+// successful result into data, which may be nil
+func (p *N11Provider) call(ctx context.Context, endpoint string, requestData map[string]interface{}, data interface{}) error {
+	response, err := p.makeRequest(ctx, "POST", endpoint, requestData)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse N11APIResponse
+	if err := json.Unmarshal(response, &apiResponse); err != nil {
+		return err
+	}
+
+	if apiResponse.Result.Status != "success" {
+		return fmt.Errorf("N11 API error: %s", apiResponse.Result.ErrorMessage)
+	}
+
+	if data == nil || len(apiResponse.Result.Data) == 0 || string(apiResponse.Result.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(apiResponse.Result.Data, data); err != nil {
+		return fmt.Errorf("failed to parse N11 response: %w", err)
+	}
+	return nil
+}
+
+// n11OrderStatuses maps N11 order statuses to canonical ones
+var n11OrderStatuses = map[string]string{
+	"New":       OrderStatusPending,
+	"Approved":  OrderStatusConfirmed,
+	"Rejected":  OrderStatusCancelled,
+	"Shipped":   OrderStatusShipped,
+	"Delivered": OrderStatusDelivered,
+	"Completed": OrderStatusDelivered,
+	"Claimed":   OrderStatusReturned,
+}
+
+// n11StatusFilters maps canonical statuses to the N11 status orders are
+// filtered by
+var n11StatusFilters = map[string]string{
+	OrderStatusPending:   "New",
+	OrderStatusConfirmed: "Approved",
+	OrderStatusShipped:   "Shipped",
+	OrderStatusDelivered: "Delivered",
+	OrderStatusCancelled: "Rejected",
+	OrderStatusReturned:  "Claimed",
+}
+
+// n11StatusUpdates maps the canonical statuses a seller can move an order
+// to onto N11's
+var n11StatusUpdates = map[string]string{
+	OrderStatusConfirmed:  "Approved",
+	OrderStatusProcessing: "Approved",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusCancelled:  "Rejected",
+}
+
+// mapListing maps a listing to an N11 product, recording every field N11
+// would reject
+func (p *N11Provider) mapListing(listing *Listing) (N11Product, *mapping) {
+	m := newMapping("n11", EntityListing, listing.SKU)
+	m.listing(listing, "TRY")
+	m.maxLength("title", listing.Title, 65)
+	m.require("description", listing.Description)
+	m.require("brand", listing.Brand)
+	m.numericID("category_id", listing.CategoryID)
+	m.images(listing.Images, 1, 8)
+
+	price := fmt.Sprintf("%.2f", listing.Price)
 	n11Product := N11Product{
-		ProductSellerCode: getString(product, "sku"),
-		Title:            getString(product, "title"),
-		Subtitle:         getString(product, "subtitle"),
-		Description:      getString(product, "description"),
-		Price:            fmt.Sprintf("%.2f", getFloat64(product, "price")),
-		CurrencyType:     "1", // TL
-		PreparingDay:     3,
+		ProductSellerCode:   listing.SKU,
+		Title:               listing.Title,
+		Description:         listing.Description,
+		Category:            N11Category{ID: listing.CategoryID},
+		Price:               price,
+		CurrencyType:        "1", // TL
+		PreparingDay:        3,
 		MaxPurchaseQuantity: 999,
 	}
-	
-	// Set category
-	if categoryID := getString(product, "category_id"); categoryID != "" {
-		n11Product.Category = N11Category{ID: categoryID}
+	if listing.DispatchDays > 0 {
+		n11Product.PreparingDay = listing.DispatchDays
 	}
-	
-	// Set images
-	if images, ok := product["images"].([]interface{}); ok {
-		n11Images := make([]N11Image, 0)
-		for i, img := range images {
-			if imgStr, ok := img.(string); ok {
-				n11Images = append(n11Images, N11Image{
-					URL:   imgStr,
-					Order: strconv.Itoa(i + 1),
-				})
-			}
-		}
-		n11Product.Images = N11Images{Image: n11Images}
+
+	images := make([]N11Image, 0, len(listing.Images))
+	for i, image := range listing.Images {
+		images = append(images, N11Image{URL: image, Order: strconv.Itoa(i + 1)})
+	}
+	n11Product.Images = N11Images{Image: images}
+
+	// N11 takes attributes by their Turkish names; the brand goes in "Marka"
+	attributes := []N11Attribute{{Name: "Marka", Value: listing.Brand}}
+	for i, attribute := range listing.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		m.require(field+".name", attribute.Name)
+		m.require(field+".value", attribute.Value)
+		attributes = append(attributes, N11Attribute{Name: attribute.Name, Value: attribute.Value})
 	}
-	
-	// Set stock items
-	quantity := getInt(product, "quantity")
 	n11Product.StockItems = N11StockItems{
 		StockItem: []N11StockItem{
 			{
 				Bundle:          "false",
-				SellerStockCode: getString(product, "sku"),
-				Quantity:        strconv.Itoa(quantity),
-				OptionPrice:     n11Product.Price,
-				Attributes: N11Attributes{
-					Attribute: []N11Attribute{
-						{Name: "Marka", Value: getString(product, "brand")},
-						{Name: "Renk", Value: getString(product, "color")},
-					},
-				},
+				GTIN:            listing.Barcode,
+				SellerStockCode: listing.SKU,
+				Quantity:        strconv.Itoa(listing.Stock),
+				OptionPrice:     price,
+				Attributes:      N11Attributes{Attribute: attributes},
 			},
 		},
 	}
-	
-	return n11Product
+
+	return n11Product, m
 }
 
-// Helper functions moved to helpers.go
\ No newline at end of file
+// toListing maps an N11 product back to a listing
+func (product N11Product) toListing() Listing {
+	price, _ := strconv.ParseFloat(product.Price, 64)
+	listing := Listing{
+		SKU:         product.ProductSellerCode,
+		Title:       product.Title,
+		Description: product.Description,
+		CategoryID:  product.Category.ID,
+		Price:       price,
+		Currency:    defaultCurrency,
+		Status:      ProductStatusActive,
+	}
+	for _, image := range product.Images.Image {
+		listing.Images = append(listing.Images, image.URL)
+	}
+	for _, item := range product.StockItems.StockItem {
+		stock, _ := strconv.Atoi(item.Quantity)
+		listing.Stock += stock
+		if listing.Barcode == "" {
+			listing.Barcode = item.GTIN
+		}
+		for _, attribute := range item.Attributes.Attribute {
+			if attribute.Name == "Marka" {
+				listing.Brand = attribute.Value
+				continue
+			}
+			listing.Attributes = append(listing.Attributes, Attribute{Name: attribute.Name, Value: attribute.Value})
+		}
+	}
+	return listing
+}
+
+// toOrder maps an N11 order to a canonical order
+func (order N11Order) toOrder() Order {
+	result := Order{
+		ID:                strconv.FormatInt(order.ID, 10),
+		OrderNumber:       order.OrderNumber,
+		Status:            canonicalOrderStatus(n11OrderStatuses, order.Status),
+		MarketplaceStatus: order.Status,
+		CustomerName:      order.BuyerName,
+		ShippingAddress:   Address{FirstName: order.Recipient, Country: "TR"},
+		Currency:          defaultCurrency,
+		PaymentStatus:     PaymentStatusPaid,
+		ShippingMethod:    order.ShippingInfo.CompanyName,
+		TrackingNumber:    order.ShippingInfo.TrackingNo,
+		OrderDate:         order.CreateDate,
+	}
+	if !order.ShippingInfo.ShippedDate.IsZero() {
+		shippedDate := order.ShippingInfo.ShippedDate
+		result.ShippedDate = &shippedDate
+	}
+
+	for _, line := range order.OrderItems {
+		total := line.Price * float64(line.Quantity)
+		result.Items = append(result.Items, OrderItem{
+			ProductID:  strconv.FormatInt(line.ProductID, 10),
+			SKU:        line.SellerCode,
+			Name:       line.ProductName,
+			Quantity:   line.Quantity,
+			Price:      line.Price,
+			TotalPrice: total,
+		})
+		result.Subtotal += total
+	}
+	result.TotalAmount = result.Subtotal
+
+	return result
+}
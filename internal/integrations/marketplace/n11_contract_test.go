@@ -0,0 +1,115 @@
+package marketplace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+var n11TestCredentials = integrations.Credentials{
+	APIKey:    testValue("N11_APP_KEY", "test-app-key"),
+	APISecret: testValue("N11_APP_SECRET", "test-app-secret"),
+}
+
+func newN11Contract(t *testing.T, fixture string) (*N11Provider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewN11Provider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	if err := p.Initialize(context.Background(), n11TestCredentials, map[string]interface{}{"environment": "sandbox"}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+func TestN11OrdersContract(t *testing.T) {
+	p, recorder := newN11Contract(t, "n11_orders")
+
+	query := OrderQuery{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), PageSize: 2}
+	first, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken != "1" {
+		t.Fatalf("first page: got %d orders, next %q", len(first.Orders), first.NextPageToken)
+	}
+	order := first.Orders[0]
+	if order.ID != "301000001" || order.OrderNumber != "N11-3001" || order.Status != OrderStatusPending || order.TotalAmount != 299.8 {
+		t.Errorf("order: got %s/%s %s total %.2f", order.ID, order.OrderNumber, order.Status, order.TotalAmount)
+	}
+
+	query.PageToken = first.NextPageToken
+	last, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders page 1: %v", err)
+	}
+	if len(last.Orders) != 1 || last.NextPageToken != "" {
+		t.Fatalf("last page: got %d orders, next %q", len(last.Orders), last.NextPageToken)
+	}
+	if shipped := last.Orders[0]; shipped.Status != OrderStatusShipped || shipped.ShippedDate == nil || shipped.TrackingNumber != "YK1234567890" {
+		t.Errorf("shipped order: got %+v", shipped)
+	}
+
+	// N11 takes the credentials in the body of every request
+	for _, request := range recorder.Requests() {
+		body, _ := io.ReadAll(request.Body)
+		var payload struct {
+			Auth struct {
+				AppKey    string `json:"appKey"`
+				AppSecret string `json:"appSecret"`
+			} `json:"auth"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("%s: %v", request.URL.Path, err)
+		}
+		if payload.Auth.AppKey != n11TestCredentials.APIKey || payload.Auth.AppSecret != n11TestCredentials.APISecret {
+			t.Errorf("%s: auth %+v", request.URL.Path, payload.Auth)
+		}
+	}
+}
+
+func TestN11SyncContract(t *testing.T) {
+	p, _ := newN11Contract(t, "n11_sync")
+
+	// N11 answers each listing as it is sent; a failed result rejects only
+	// that listing
+	report, err := p.SyncProducts(context.Background(), []Listing{testListing("TS-RED-M", "8680000000011"), testListing("TS-RED-L", "8680000000028")})
+	if err != nil {
+		t.Fatalf("SyncProducts: %v", err)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results", len(report.Results))
+	}
+	if accepted := report.Results[0]; accepted.Status != ListingAccepted {
+		t.Errorf("first listing: got %+v", accepted)
+	}
+	if rejected := report.Results[1]; rejected.Status != ListingRejected || !strings.Contains(rejected.Message, "GTIN") {
+		t.Errorf("second listing: got %+v", rejected)
+	}
+
+	if _, err := p.GetBatchStatus(context.Background(), "any"); !errors.Is(err, ErrBatchStatusNotSupported) {
+		t.Errorf("GetBatchStatus: got %v", err)
+	}
+}
+
+func TestN11ErrorContract(t *testing.T) {
+	p, _ := newN11Contract(t, "n11_errors")
+
+	// 503 stops the sync with a retryable error
+	report, err := p.SyncProducts(context.Background(), []Listing{testListing("TS-RED-M", "8680000000011")})
+	var marketplaceErr *MarketplaceError
+	if !errors.As(err, &marketplaceErr) || marketplaceErr.StatusCode != 503 || !marketplaceErr.Retryable {
+		t.Fatalf("503: got %v", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("503: got results %+v", report.Results)
+	}
+}
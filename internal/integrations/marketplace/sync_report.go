@@ -0,0 +1,87 @@
+package marketplace
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"kolajAi/internal/integrations"
+)
+
+// ErrBatchStatusNotSupported is returned by GetBatchStatus of marketplaces
+// that answer every listing synchronously
+var ErrBatchStatusNotSupported = errors.New("marketplace does not process listings in batches")
+
+// Listing sync outcomes
+const (
+	ListingAccepted = "accepted"
+	ListingRejected = "rejected"
+	ListingPending  = "pending"
+)
+
+// ListingResult is the outcome of one listing of a sync
+type ListingResult struct {
+	SKU     string `json:"sku"`
+	Barcode string `json:"barcode,omitempty"`
+	Status  string `json:"status"`
+	// Message is the marketplace's reason for a rejection
+	Message string `json:"message,omitempty"`
+	// BatchID identifies the batch a pending listing is processed in
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// SyncReport is the outcome of a sync, listing by listing. When the sync
+// stops on an error, listings that were not sent are left out.
+type SyncReport struct {
+	Results []ListingResult `json:"results"`
+}
+
+func (r *SyncReport) add(sku, barcode, status, message, batchID string) {
+	r.Results = append(r.Results, ListingResult{SKU: sku, Barcode: barcode, Status: status, Message: message, BatchID: batchID})
+}
+
+// BatchStatus is the progress of a batch of listings the marketplace
+// processes asynchronously
+type BatchStatus struct {
+	BatchID string          `json:"batch_id"`
+	Done    bool            `json:"done"`
+	Results []ListingResult `json:"results"`
+}
+
+// rejection returns the reason a marketplace rejected a request when
+// sending it again would be rejected the same way. Network errors, server
+// errors, throttling and authentication failures are not rejections: they
+// stop the sync so it can be retried.
+func rejection(err error) (string, bool) {
+	var marketplaceErr *MarketplaceError
+	if errors.As(err, &marketplaceErr) {
+		return marketplaceErr.Message, !marketplaceErr.Retryable && rejectedStatus(marketplaceErr.StatusCode)
+	}
+	var integrationErr *integrations.IntegrationError
+	if errors.As(err, &integrationErr) {
+		return integrationErr.Message, !integrationErr.Retryable && integrationErr.StatusCode > 0 && rejectedStatus(integrationErr.StatusCode)
+	}
+	return "", false
+}
+
+// rejectedStatus reports whether an HTTP status rejects the request itself.
+// 0 is used for rejections reported in the body of a successful response.
+func rejectedStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status == 0 || status >= 400 && status < 500
+}
+
+// apiError builds the error of a failed marketplace response
+func apiError(provider string, status int, message string) *MarketplaceError {
+	return &MarketplaceError{
+		Code:       "API_ERROR",
+		Message:    message,
+		Provider:   provider,
+		Retryable:  status >= 500 || status == http.StatusTooManyRequests,
+		Timestamp:  time.Now(),
+		StatusCode: status,
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +28,7 @@ type TrendyolProvider struct {
 	baseURL        string
 	supplierID     string
 	rateLimit      integrations.RateLimitInfo
+	limiter        *integrations.RateLimiter
 	retryManager   *retry.RetryManager
 	inputValidator *security.InputValidator
 	errorHandler   *errors.ErrorHandler
@@ -65,7 +69,7 @@ type TrendyolAttribute struct {
 // TrendyolOrder represents Trendyol order structure
 type TrendyolOrder struct {
 	OrderNumber    string                `json:"orderNumber"`
-	OrderDate      time.Time             `json:"orderDate"`
+	OrderDate      trendyolTime          `json:"orderDate"`
 	Status         string                `json:"status"`
 	CustomerID     int                   `json:"customerId"`
 	CustomerName   string                `json:"customerFirstName"`
@@ -78,6 +82,22 @@ type TrendyolOrder struct {
 	InvoiceAddress TrendyolAddress       `json:"invoiceAddress"`
 	ShippingAddress TrendyolAddress      `json:"shippingAddress"`
 	Lines          []TrendyolOrderLine   `json:"lines"`
+	TotalPrice     float64               `json:"totalPrice"`
+	CurrencyCode   string                `json:"currencyCode"`
+}
+
+// trendyolTime reads Trendyol timestamps, which are milliseconds since the
+// epoch
+type trendyolTime struct {
+	time.Time
+}
+
+func (t *trendyolTime) UnmarshalJSON(data []byte) error {
+	if ms, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		t.Time = time.UnixMilli(ms).UTC()
+		return nil
+	}
+	return t.Time.UnmarshalJSON(data)
 }
 
 // TrendyolOrderLine represents order line item
@@ -119,10 +139,10 @@ type TrendyolStockPriceUpdate struct {
 
 // TrendyolStockPriceItem represents individual stock/price item
 type TrendyolStockPriceItem struct {
-	Barcode   string  `json:"barcode"`
-	Quantity  int     `json:"quantity"`
-	SalePrice float64 `json:"salePrice"`
-	ListPrice float64 `json:"listPrice"`
+	Barcode   string   `json:"barcode"`
+	Quantity  *int     `json:"quantity,omitempty"`
+	SalePrice *float64 `json:"salePrice,omitempty"`
+	ListPrice *float64 `json:"listPrice,omitempty"`
 }
 
 // NewTrendyolProvider creates a new Trendyol marketplace provider
@@ -136,9 +156,20 @@ func NewTrendyolProvider() *TrendyolProvider {
 			RequestsRemaining: 60,
 			ResetsAt:          time.Now().Add(time.Minute),
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *TrendyolProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *TrendyolProvider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize sets up the Trendyol provider
 func (p *TrendyolProvider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	// For now, we'll store credentials temporarily
@@ -204,12 +235,6 @@ func (p *TrendyolProvider) GetType() string {
 	return "marketplace"
 }
 
-// IsHealthy checks if the provider is healthy
-func (p *TrendyolProvider) IsHealthy(ctx context.Context) (bool, error) {
-	err := p.HealthCheck(ctx)
-	return err == nil, err
-}
-
 // GetMetrics returns provider metrics
 func (p *TrendyolProvider) GetMetrics() map[string]interface{} {
 	return map[string]interface{}{
@@ -220,6 +245,7 @@ func (p *TrendyolProvider) GetMetrics() map[string]interface{} {
 		"provider_name": "trendyol",
 		"base_url": p.baseURL,
 		"supplier_id": p.supplierID,
+		"rate_limiter": p.limiter.State("trendyol"),
 	}
 }
 
@@ -247,197 +273,338 @@ func (p *TrendyolProvider) Close() error {
 	return nil
 }
 
-// SyncProducts syncs products to Trendyol
-func (p *TrendyolProvider) SyncProducts(ctx context.Context, products []interface{}) error {
-	trendyolProducts := make([]TrendyolProduct, 0, len(products))
-	
-	for _, product := range products {
-		trendyolProduct, err := p.convertToTrendyolProduct(product)
-		if err != nil {
-			continue // Skip invalid products
-		}
+// SyncProducts syncs listings to Trendyol. Nothing is sent if any listing
+// cannot be mapped. Trendyol processes listings in batches, so they are
+// pending until GetBatchStatus reports them; a batch Trendyol refuses
+// outright rejects all of its listings.
+func (p *TrendyolProvider) SyncProducts(ctx context.Context, listings []Listing) (*SyncReport, error) {
+	trendyolProducts := make([]TrendyolProduct, 0, len(listings))
+	mappings := make([]*mapping, 0, len(listings))
+	for i := range listings {
+		trendyolProduct, m := p.mapListing(&listings[i])
 		trendyolProducts = append(trendyolProducts, trendyolProduct)
+		mappings = append(mappings, m)
 	}
-	
+	if err := validationError("trendyol", mappings); err != nil {
+		return nil, err
+	}
+
 	// Send products in batches of 100 (Trendyol limit)
+	report := &SyncReport{}
 	batchSize := 100
 	for i := 0; i < len(trendyolProducts); i += batchSize {
 		end := i + batchSize
 		if end > len(trendyolProducts) {
 			end = len(trendyolProducts)
 		}
-		
+
 		batch := trendyolProducts[i:end]
-		err := p.sendProductBatch(ctx, batch)
+		batchID, err := p.sendProductBatch(ctx, batch)
+		status, message := ListingPending, ""
 		if err != nil {
-			return err
+			reason, rejected := rejection(err)
+			if !rejected {
+				return report, err
+			}
+			status, message = ListingRejected, reason
+		} else if batchID == "" {
+			status = ListingAccepted
+		}
+		for _, product := range batch {
+			report.add(product.StockCode, product.Barcode, status, message, batchID)
 		}
 	}
-	
-	return nil
+
+	return report, nil
 }
 
-// GetProducts retrieves products from Trendyol
-func (p *TrendyolProvider) GetProducts(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/products", p.supplierID)
-	
-	// Add query parameters
-	queryParams := make([]string, 0)
-	if page, ok := params["page"].(int); ok {
-		queryParams = append(queryParams, fmt.Sprintf("page=%d", page))
-	}
-	if size, ok := params["size"].(int); ok {
-		queryParams = append(queryParams, fmt.Sprintf("size=%d", size))
+// GetBatchStatus returns the outcome of the listings of a product batch
+func (p *TrendyolProvider) GetBatchStatus(ctx context.Context, batchID string) (*BatchStatus, error) {
+	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/products/batch-requests/%s", p.supplierID, url.PathEscape(batchID))
+
+	var response struct {
+		Status string `json:"status"`
+		Items  []struct {
+			RequestItem struct {
+				Product struct {
+					Barcode   string `json:"barcode"`
+					StockCode string `json:"stockCode"`
+				} `json:"product"`
+				Barcode string `json:"barcode"`
+			} `json:"requestItem"`
+			Status         string   `json:"status"`
+			FailureReasons []string `json:"failureReasons"`
+		} `json:"items"`
+	}
+	if err := p.makeRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+		return nil, err
 	}
-	if approved, ok := params["approved"].(bool); ok {
-		queryParams = append(queryParams, fmt.Sprintf("approved=%t", approved))
+
+	status := &BatchStatus{BatchID: batchID, Done: response.Status == "COMPLETED"}
+	for _, item := range response.Items {
+		result := ListingResult{
+			SKU:     item.RequestItem.Product.StockCode,
+			Barcode: item.RequestItem.Product.Barcode,
+			BatchID: batchID,
+		}
+		if result.Barcode == "" {
+			result.Barcode = item.RequestItem.Barcode
+		}
+		switch item.Status {
+		case "SUCCESS":
+			result.Status = ListingAccepted
+		case "FAILED":
+			result.Status = ListingRejected
+			result.Message = strings.Join(item.FailureReasons, "; ")
+		default:
+			result.Status = ListingPending
+		}
+		status.Results = append(status.Results, result)
 	}
-	
-	if len(queryParams) > 0 {
-		endpoint += "?" + strings.Join(queryParams, "&")
+	return status, nil
+}
+
+// GetProducts retrieves listings from Trendyol
+func (p *TrendyolProvider) GetProducts(ctx context.Context, query ListingQuery) (*ListingPage, error) {
+	page, _ := strconv.Atoi(query.PageToken)
+	size := query.PageSize
+	if size <= 0 {
+		size = 50
 	}
-	
+	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/products?page=%d&size=%d", p.supplierID, page, size)
+
 	var response struct {
-		Content []TrendyolProduct `json:"content"`
-		TotalElements int `json:"totalElements"`
-		TotalPages int `json:"totalPages"`
+		Content       []TrendyolProduct `json:"content"`
+		TotalElements int               `json:"totalElements"`
+		TotalPages    int               `json:"totalPages"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	products := make([]interface{}, len(response.Content))
-	for i, product := range response.Content {
-		products[i] = product
+
+	result := &ListingPage{Listings: make([]Listing, 0, len(response.Content))}
+	for _, product := range response.Content {
+		result.Listings = append(result.Listings, product.toListing())
 	}
-	
-	return products, nil
+	if page+1 < response.TotalPages {
+		result.NextPageToken = strconv.Itoa(page + 1)
+	}
+	return result, nil
 }
 
 // GetOrders retrieves orders from Trendyol
-func (p *TrendyolProvider) GetOrders(ctx context.Context, params map[string]interface{}) ([]interface{}, error) {
-	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/orders", p.supplierID)
-	
-	// Add query parameters
-	queryParams := make([]string, 0)
-	if startDate, ok := params["start_date"].(string); ok {
-		queryParams = append(queryParams, "startDate="+startDate)
-	}
-	if endDate, ok := params["end_date"].(string); ok {
-		queryParams = append(queryParams, "endDate="+endDate)
-	}
-	if status, ok := params["status"].(string); ok {
-		queryParams = append(queryParams, "status="+status)
-	}
-	
-	if len(queryParams) > 0 {
-		endpoint += "?" + strings.Join(queryParams, "&")
+func (p *TrendyolProvider) GetOrders(ctx context.Context, query OrderQuery) (*OrderPage, error) {
+	page, _ := strconv.Atoi(query.PageToken)
+	size := query.PageSize
+	if size <= 0 {
+		size = 50
+	}
+
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("size", strconv.Itoa(size))
+	params.Set("orderByField", "CreatedDate")
+	params.Set("orderByDirection", "ASC")
+	if !query.Since.IsZero() {
+		params.Set("startDate", strconv.FormatInt(query.Since.UnixMilli(), 10))
+	}
+	if !query.Until.IsZero() {
+		params.Set("endDate", strconv.FormatInt(query.Until.UnixMilli(), 10))
+	}
+	if query.Status != "" {
+		status, ok := trendyolStatusFilters[query.Status]
+		if !ok {
+			return nil, orderStatusError("trendyol", "", query.Status)
+		}
+		params.Set("status", status)
 	}
-	
+	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/orders?%s", p.supplierID, params.Encode())
+
 	var response struct {
-		Content []TrendyolOrder `json:"content"`
+		Content    []TrendyolOrder `json:"content"`
+		TotalPages int             `json:"totalPages"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	orders := make([]interface{}, len(response.Content))
-	for i, order := range response.Content {
-		orders[i] = order
+
+	result := &OrderPage{Orders: make([]Order, 0, len(response.Content))}
+	for _, order := range response.Content {
+		result.Orders = append(result.Orders, order.toOrder())
 	}
-	
-	return orders, nil
+	if page+1 < response.TotalPages {
+		result.NextPageToken = strconv.Itoa(page + 1)
+	}
+	return result, nil
 }
 
-// UpdateStockAndPrice updates stock and price for products
-func (p *TrendyolProvider) UpdateStockAndPrice(ctx context.Context, updates []interface{}) error {
+// UpdateStockAndPrice updates stock and price for listings. Trendyol keys
+// listings by barcode.
+func (p *TrendyolProvider) UpdateStockAndPrice(ctx context.Context, updates []StockPriceUpdate) error {
 	items := make([]TrendyolStockPriceItem, 0, len(updates))
-	
-	for _, update := range updates {
-		item, err := p.convertToStockPriceItem(update)
-		if err != nil {
-			continue // Skip invalid items
-		}
+	mappings := make([]*mapping, 0, len(updates))
+	for i := range updates {
+		item, m := p.mapStockPrice(&updates[i])
 		items = append(items, item)
+		mappings = append(mappings, m)
 	}
-	
-	request := TrendyolStockPriceUpdate{
-		Items: items,
+	if err := validationError("trendyol", mappings); err != nil {
+		return err
 	}
-	
+
 	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/products/price-and-inventory", p.supplierID)
-	
-	var response map[string]interface{}
-	return p.makeRequest(ctx, "POST", endpoint, request, &response)
+
+	// Trendyol accepts up to 1000 items per request
+	batchSize := 1000
+	for i := 0; i < len(items); i += batchSize {
+		end := i + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var response map[string]interface{}
+		if err := p.makeRequest(ctx, "POST", endpoint, TrendyolStockPriceUpdate{Items: items[i:end]}, &response); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // UpdateOrderStatus updates order status
-func (p *TrendyolProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, params map[string]interface{}) error {
+func (p *TrendyolProvider) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *Shipment) error {
+	trendyolStatus, ok := trendyolStatusUpdates[status]
+	if !ok {
+		return orderStatusError("trendyol", orderID, status)
+	}
+	m := newMapping("trendyol", EntityShipment, orderID)
+	m.shipment(status, shipment)
+	if err := validationError("trendyol", []*mapping{m}); err != nil {
+		return err
+	}
+
 	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/orders/%s/status", p.supplierID, orderID)
-	
+
 	request := map[string]interface{}{
-		"status": status,
-	}
-	
-	// Add additional parameters
-	if trackingNumber, ok := params["tracking_number"].(string); ok {
-		request["trackingNumber"] = trackingNumber
+		"status": trendyolStatus,
 	}
-	if invoiceNumber, ok := params["invoice_number"].(string); ok {
-		request["invoiceNumber"] = invoiceNumber
+	if shipment != nil {
+		if shipment.TrackingNumber != "" {
+			request["trackingNumber"] = shipment.TrackingNumber
+		}
+		if shipment.InvoiceNumber != "" {
+			request["invoiceNumber"] = shipment.InvoiceNumber
+		}
 	}
-	
+
 	var response map[string]interface{}
 	return p.makeRequest(ctx, "PUT", endpoint, request, &response)
 }
 
-// GetCategories retrieves categories from Trendyol
-func (p *TrendyolProvider) GetCategories(ctx context.Context) ([]interface{}, error) {
-	endpoint := "/sapigw/categories"
-	
+// GetCategories retrieves the category tree from Trendyol, flattened
+func (p *TrendyolProvider) GetCategories(ctx context.Context) ([]Category, error) {
+	endpoint := "/sapigw/product-categories"
+
 	var response struct {
-		Categories []Category `json:"categories"`
+		Categories []TrendyolCategory `json:"categories"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	categories := make([]interface{}, len(response.Categories))
-	for i, category := range response.Categories {
-		categories[i] = category
+
+	categories := make([]Category, 0)
+	var walk func(nodes []TrendyolCategory, parentID, path string, level int)
+	walk = func(nodes []TrendyolCategory, parentID, path string, level int) {
+		for _, node := range nodes {
+			category := Category{
+				ID:       strconv.Itoa(node.ID),
+				Name:     node.Name,
+				ParentID: parentID,
+				Path:     node.Name,
+				Level:    level,
+			}
+			if path != "" {
+				category.Path = path + " > " + node.Name
+			}
+			categories = append(categories, category)
+			walk(node.SubCategories, category.ID, category.Path, level+1)
+		}
 	}
-	
+	walk(response.Categories, "", "", 0)
+
 	return categories, nil
 }
 
+// GetCategoryAttributes retrieves the attributes of a Trendyol category
+func (p *TrendyolProvider) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	endpoint := "/sapigw/product-categories/" + url.PathEscape(categoryID) + "/attributes"
+
+	var response struct {
+		CategoryAttributes []struct {
+			AllowCustom bool `json:"allowCustom"`
+			Required    bool `json:"required"`
+			Varianter   bool `json:"varianter"`
+			Attribute   struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"attribute"`
+			AttributeValues []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"attributeValues"`
+		} `json:"categoryAttributes"`
+	}
+
+	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := make([]CategoryAttribute, 0, len(response.CategoryAttributes))
+	for _, a := range response.CategoryAttributes {
+		attribute := CategoryAttribute{
+			ID:          strconv.Itoa(a.Attribute.ID),
+			Name:        a.Attribute.Name,
+			Required:    a.Required,
+			AllowCustom: a.AllowCustom,
+			Varianter:   a.Varianter,
+		}
+		for _, value := range a.AttributeValues {
+			attribute.Values = append(attribute.Values, AttributeValue{ID: strconv.Itoa(value.ID), Name: value.Name})
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
 // GetBrands retrieves brands from Trendyol
-func (p *TrendyolProvider) GetBrands(ctx context.Context) ([]interface{}, error) {
+func (p *TrendyolProvider) GetBrands(ctx context.Context) ([]Brand, error) {
 	endpoint := "/sapigw/brands"
-	
+
 	var response struct {
-		Brands []Brand `json:"brands"`
+		Brands []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"brands"`
 	}
-	
+
 	err := p.makeRequest(ctx, "GET", endpoint, nil, &response)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert to generic interface
-	brands := make([]interface{}, len(response.Brands))
-	for i, brand := range response.Brands {
-		brands[i] = brand
+
+	brands := make([]Brand, 0, len(response.Brands))
+	for _, brand := range response.Brands {
+		brands = append(brands, Brand{ID: strconv.Itoa(brand.ID), Name: brand.Name})
 	}
-	
+
 	return brands, nil
 }
 
@@ -469,6 +636,12 @@ func (p *TrendyolProvider) makeRequest(ctx context.Context, method, endpoint str
 	auth := p.generateAuthHeader(method, endpoint, string(body))
 	req.Header.Set("Authorization", auth)
 	
+	// Wait for the rate limiter
+	class := endpointClass(endpoint)
+	if err := p.limiter.Wait(ctx, "trendyol", class, p.rateLimit); err != nil {
+		return err
+	}
+	
 	// Execute request
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
@@ -484,14 +657,21 @@ func (p *TrendyolProvider) makeRequest(ctx context.Context, method, endpoint str
 	
 	// Update rate limit info
 	p.updateRateLimit(resp.Header)
+	p.limiter.Observe("trendyol", class, resp, p.rateLimit)
 	
-	// Check for API errors
+	// Check for API errors. The body names the reason, which is what
+	// vendors need to fix a rejected listing.
 	if resp.StatusCode >= 400 {
+		errorBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		message := fmt.Sprintf("Trendyol API returned status %d", resp.StatusCode)
+		if len(errorBody) > 0 {
+			message += ": " + strings.TrimSpace(string(errorBody))
+		}
 		return &integrations.IntegrationError{
 			Code:       "API_ERROR",
-			Message:    fmt.Sprintf("Trendyol API returned status %d", resp.StatusCode),
+			Message:    message,
 			Provider:   "trendyol",
-			Retryable:  resp.StatusCode >= 500,
+			Retryable:  resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
 			Timestamp:  time.Now(),
 			StatusCode: resp.StatusCode,
 		}
@@ -551,120 +731,246 @@ func (p *TrendyolProvider) updateRateLimit(headers http.Header) {
 
 // Helper methods for data conversion
 
-func (p *TrendyolProvider) convertToTrendyolProduct(product interface{}) (TrendyolProduct, error) {
-	productMap, ok := product.(map[string]interface{})
-	if !ok {
-		return TrendyolProduct{}, fmt.Errorf("invalid product format")
+// trendyolOrderStatuses maps Trendyol order statuses to canonical ones
+var trendyolOrderStatuses = map[string]string{
+	"Created":           OrderStatusPending,
+	"Picking":           OrderStatusProcessing,
+	"Invoiced":          OrderStatusProcessing,
+	"Shipped":           OrderStatusShipped,
+	"AtCollectionPoint": OrderStatusShipped,
+	"UnDelivered":       OrderStatusShipped,
+	"Delivered":         OrderStatusDelivered,
+	"Cancelled":         OrderStatusCancelled,
+	"UnSupplied":        OrderStatusCancelled,
+	"Returned":          OrderStatusReturned,
+}
+
+// trendyolStatusFilters maps canonical statuses to the Trendyol status
+// orders are filtered by
+var trendyolStatusFilters = map[string]string{
+	OrderStatusPending:    "Created",
+	OrderStatusProcessing: "Picking",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusDelivered:  "Delivered",
+	OrderStatusCancelled:  "Cancelled",
+	OrderStatusReturned:   "Returned",
+}
+
+// trendyolStatusUpdates maps the canonical statuses a supplier can move an
+// order to onto Trendyol's
+var trendyolStatusUpdates = map[string]string{
+	OrderStatusConfirmed:  "Picking",
+	OrderStatusProcessing: "Picking",
+	OrderStatusShipped:    "Shipped",
+	OrderStatusDelivered:  "Delivered",
+	OrderStatusCancelled:  "UnSupplied",
+}
+
+// trendyolVatRates are the VAT rates Trendyol accepts
+var trendyolVatRates = map[int]bool{0: true, 1: true, 10: true, 20: true}
+
+// TrendyolCategory is a node of Trendyol's category tree
+type TrendyolCategory struct {
+	ID            int                `json:"id"`
+	Name          string             `json:"name"`
+	ParentID      int                `json:"parentId"`
+	SubCategories []TrendyolCategory `json:"subCategories"`
+}
+
+// mapListing maps a listing to a Trendyol product, recording every field
+// Trendyol would reject
+func (p *TrendyolProvider) mapListing(listing *Listing) (TrendyolProduct, *mapping) {
+	m := newMapping("trendyol", EntityListing, listing.SKU)
+	m.listing(listing, "TRY")
+	m.require("barcode", listing.Barcode)
+	m.maxLength("barcode", listing.Barcode, 40)
+	m.maxLength("title", listing.Title, 100)
+	m.require("description", listing.Description)
+	m.maxLength("description", listing.Description, 30000)
+	brandID := m.numericID("brand_id", listing.BrandID)
+	categoryID := m.numericID("category_id", listing.CategoryID)
+	m.images(listing.Images, 1, 8)
+	vat := vatRate(listing.VatRate)
+	if !trendyolVatRates[vat] {
+		m.fail("vat_rate", "%d is not accepted, use 0, 1, 10 or 20", vat)
 	}
-	
+
 	trendyolProduct := TrendyolProduct{
-		Barcode:          getString(productMap, "barcode"),
-		Title:            getString(productMap, "title"),
-		ProductMainID:    getString(productMap, "product_main_id"),
-		BrandID:          getInt(productMap, "brand_id"),
-		CategoryID:       getInt(productMap, "category_id"),
-		Quantity:         getInt(productMap, "quantity"),
-		StockCode:        getString(productMap, "sku"),
-		DimensionalWeight: getFloat64(productMap, "dimensional_weight"),
-		Description:      getString(productMap, "description"),
-		CurrencyType:     "TRY",
-		ListPrice:        getFloat64(productMap, "list_price"),
-		SalePrice:        getFloat64(productMap, "price"),
-		VatRate:          18, // Default VAT rate for Turkey
-		CargoCompanyID:   1,  // Default cargo company
-	}
-	
-	// Set images
-	if images, ok := productMap["images"].([]interface{}); ok {
-		trendyolImages := make([]TrendyolImage, 0)
-		for _, img := range images {
-			if imgStr, ok := img.(string); ok {
-				trendyolImages = append(trendyolImages, TrendyolImage{URL: imgStr})
-			}
+		Barcode:           listing.Barcode,
+		Title:             listing.Title,
+		ProductMainID:     listing.SKU,
+		BrandID:           brandID,
+		CategoryID:        categoryID,
+		Quantity:          listing.Stock,
+		StockCode:         listing.SKU,
+		DimensionalWeight: listing.Weight,
+		Description:       listing.Description,
+		CurrencyType:      "TRY",
+		ListPrice:         listPrice(listing.Price, listing.ListPrice),
+		SalePrice:         listing.Price,
+		VatRate:           vat,
+		CargoCompanyID:    1, // Default cargo company
+	}
+
+	trendyolProduct.Images = make([]TrendyolImage, 0, len(listing.Images))
+	for _, image := range listing.Images {
+		trendyolProduct.Images = append(trendyolProduct.Images, TrendyolImage{URL: image})
+	}
+
+	trendyolProduct.Attributes = make([]TrendyolAttribute, 0, len(listing.Attributes))
+	for i, attribute := range listing.Attributes {
+		field := fmt.Sprintf("attributes[%d]", i)
+		trendyolAttribute := TrendyolAttribute{AttributeID: m.numericID(field+".id", attribute.ID)}
+		if attribute.ValueID != "" {
+			trendyolAttribute.AttributeValueID = m.numericID(field+".value_id", attribute.ValueID)
+		} else {
+			m.require(field+".value", attribute.Value)
+			trendyolAttribute.CustomAttributeValue = attribute.Value
 		}
-		trendyolProduct.Images = trendyolImages
+		trendyolProduct.Attributes = append(trendyolProduct.Attributes, trendyolAttribute)
 	}
-	
-	// Set attributes
-	if attributes, ok := productMap["attributes"].(map[string]interface{}); ok {
-		trendyolAttributes := make([]TrendyolAttribute, 0)
-		for key, value := range attributes {
-			if valueStr, ok := value.(string); ok {
-				trendyolAttributes = append(trendyolAttributes, TrendyolAttribute{
-					AttributeID:          getAttributeID(key),
-					AttributeValueID:     getAttributeValueID(key, valueStr),
-					CustomAttributeValue: valueStr,
-				})
-			}
-		}
-		trendyolProduct.Attributes = trendyolAttributes
-	}
-	
-	return trendyolProduct, nil
+
+	return trendyolProduct, m
 }
 
-// Helper functions moved to helpers.go
+// mapStockPrice maps a stock and price update to a Trendyol item
+func (p *TrendyolProvider) mapStockPrice(update *StockPriceUpdate) (TrendyolStockPriceItem, *mapping) {
+	m := newMapping("trendyol", EntityStockPrice, update.Barcode)
+	m.stockPrice(update)
+	m.require("barcode", update.Barcode)
 
-func getAttributeID(attributeName string) int {
-	// This would typically be a lookup from your attribute mapping
-	// For now, return a default value
-	attributeMap := map[string]int{
-		"color":  1,
-		"size":   2,
-		"brand":  3,
-		"model":  4,
-	}
-	if id, ok := attributeMap[attributeName]; ok {
-		return id
+	item := TrendyolStockPriceItem{
+		Barcode:  update.Barcode,
+		Quantity: update.Stock,
+	}
+	if update.Price != nil {
+		salePrice := *update.Price
+		listPrice := listPrice(salePrice, update.ListPrice)
+		item.SalePrice = &salePrice
+		item.ListPrice = &listPrice
+	}
+
+	return item, m
+}
+
+// toListing maps a Trendyol product back to a listing
+func (product TrendyolProduct) toListing() Listing {
+	listing := Listing{
+		ExternalID:  product.Barcode,
+		SKU:         product.StockCode,
+		Barcode:     product.Barcode,
+		Title:       product.Title,
+		Description: product.Description,
+		BrandID:     strconv.Itoa(product.BrandID),
+		CategoryID:  strconv.Itoa(product.CategoryID),
+		Price:       product.SalePrice,
+		ListPrice:   product.ListPrice,
+		Currency:    product.CurrencyType,
+		Stock:       product.Quantity,
+		VatRate:     product.VatRate,
+		Weight:      product.DimensionalWeight,
+		Status:      ProductStatusActive,
+	}
+	for _, image := range product.Images {
+		listing.Images = append(listing.Images, image.URL)
+	}
+	for _, attribute := range product.Attributes {
+		listing.Attributes = append(listing.Attributes, Attribute{
+			ID:      strconv.Itoa(attribute.AttributeID),
+			ValueID: strconv.Itoa(attribute.AttributeValueID),
+			Value:   attribute.CustomAttributeValue,
+		})
+	}
+	return listing
+}
+
+// toOrder maps a Trendyol order to a canonical order
+func (order TrendyolOrder) toOrder() Order {
+	result := Order{
+		ID:                order.OrderNumber,
+		OrderNumber:       order.OrderNumber,
+		Status:            canonicalOrderStatus(trendyolOrderStatuses, order.Status),
+		MarketplaceStatus: order.Status,
+		CustomerID:        strconv.Itoa(order.CustomerID),
+		CustomerName:      strings.TrimSpace(order.CustomerName + " " + order.CustomerSurname),
+		CustomerEmail:     order.CustomerEmail,
+		BillingAddress:    order.InvoiceAddress.toAddress(),
+		ShippingAddress:   order.ShippingAddress.toAddress(),
+		Subtotal:          order.GrossAmount,
+		DiscountAmount:    order.TotalDiscount + order.TotalTyDiscount,
+		TotalAmount:       order.TotalPrice,
+		Currency:          listingCurrency(order.CurrencyCode),
+		PaymentStatus:     PaymentStatusPaid,
+		OrderDate:         order.OrderDate.Time,
+	}
+	if result.TotalAmount == 0 {
+		result.TotalAmount = order.GrossAmount - result.DiscountAmount
+	}
+
+	for _, line := range order.Lines {
+		discount := line.Discount + line.TyDiscount
+		item := OrderItem{
+			ID:             strconv.Itoa(line.LineID),
+			ProductID:      line.ProductCode,
+			SKU:            line.MerchantSKU,
+			Barcode:        line.Barcode,
+			Name:           line.ProductName,
+			Quantity:       line.Quantity,
+			Price:          line.Price,
+			TotalPrice:     line.Price*float64(line.Quantity) - discount,
+			TaxAmount:      line.VatAmount,
+			DiscountAmount: discount,
+			Attributes:     map[string]string{},
+		}
+		if line.ProductSize != "" {
+			item.Attributes["size"] = line.ProductSize
+		}
+		if line.ProductColor != "" {
+			item.Attributes["color"] = line.ProductColor
+		}
+		result.TaxAmount += line.VatAmount
+		result.Items = append(result.Items, item)
 	}
-	return 0
-}
 
-func getAttributeValueID(attributeName, value string) int {
-	// This would typically be a lookup from your attribute value mapping
-	// For now, return a default value
-	return 0
+	return result
 }
 
-func (p *TrendyolProvider) convertToStockPriceItem(update interface{}) (TrendyolStockPriceItem, error) {
-	updateMap, ok := update.(map[string]interface{})
-	if !ok {
-		return TrendyolStockPriceItem{}, fmt.Errorf("invalid update format")
-	}
-	
-	item := TrendyolStockPriceItem{
-		Barcode:   getString(updateMap, "barcode"),
-		Quantity:  getInt(updateMap, "quantity"),
-		SalePrice: getFloat64(updateMap, "price"),
-		ListPrice: getFloat64(updateMap, "list_price"),
-	}
-	
-	// If list price is not provided, use sale price + margin
-	if item.ListPrice == 0 && item.SalePrice > 0 {
-		item.ListPrice = item.SalePrice * 1.2 // Add 20% margin
+// toAddress maps a Trendyol address to a canonical address
+func (address TrendyolAddress) toAddress() Address {
+	return Address{
+		ID:         strconv.Itoa(address.ID),
+		FirstName:  address.FirstName,
+		LastName:   address.LastName,
+		Company:    address.Company,
+		Address1:   address.Address1,
+		Address2:   address.Address2,
+		City:       address.City,
+		State:      address.District,
+		PostalCode: address.PostalCode,
+		Country:    address.CountryCode,
+		Phone:      address.Phone,
 	}
-	
-	return item, nil
 }
 
-func (p *TrendyolProvider) sendProductBatch(ctx context.Context, products []TrendyolProduct) error {
+// sendProductBatch sends a batch of products and returns the ID Trendyol
+// tracks its processing under
+func (p *TrendyolProvider) sendProductBatch(ctx context.Context, products []TrendyolProduct) (string, error) {
 	endpoint := fmt.Sprintf("/sapigw/suppliers/%s/products", p.supplierID)
-	
+
 	request := map[string]interface{}{
 		"items": products,
 	}
-	
-	var response map[string]interface{}
-	return p.makeRequest(ctx, "POST", endpoint, request, &response)
-}
 
-// UpdateStock is a convenience method for updating stock of a single product
-func (p *TrendyolProvider) UpdateStock(ctx context.Context, stockCode string, quantity int) error {
-	updates := []interface{}{
-		map[string]interface{}{
-			"stockCode": stockCode,
-			"quantity":  quantity,
-		},
+	var response struct {
+		BatchRequestID string `json:"batchRequestId"`
+	}
+	if err := p.makeRequest(ctx, "POST", endpoint, request, &response); err != nil {
+		return "", err
 	}
-	return p.UpdateStockAndPrice(ctx, updates)
-}
\ No newline at end of file
+	return response.BatchRequestID, nil
+}
+
+// UpdateStock is a convenience method for updating stock of a single listing
+func (p *TrendyolProvider) UpdateStock(ctx context.Context, barcode string, quantity int) error {
+	return p.UpdateStockAndPrice(ctx, []StockPriceUpdate{{Barcode: barcode, Stock: &quantity}})
+}
@@ -0,0 +1,149 @@
+package marketplace
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+var trendyolTestCredentials = integrations.Credentials{
+	APIKey:    testValue("TRENDYOL_API_KEY", "test-key"),
+	APISecret: testValue("TRENDYOL_API_SECRET", "test-secret"),
+}
+
+func newTrendyolContract(t *testing.T, fixture string) (*TrendyolProvider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewTrendyolProvider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	if err := p.Initialize(context.Background(), trendyolTestCredentials, map[string]interface{}{
+		"supplier_id": testValue("TRENDYOL_SUPPLIER_ID", "107112"),
+	}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+func TestTrendyolOrdersContract(t *testing.T) {
+	p, recorder := newTrendyolContract(t, "trendyol_orders")
+
+	query := OrderQuery{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), PageSize: 2}
+	first, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(first.Orders) != 2 || first.NextPageToken != "1" {
+		t.Fatalf("first page: got %d orders, next %q", len(first.Orders), first.NextPageToken)
+	}
+	order := first.Orders[0]
+	if order.ID != "TY-1001" || order.Status != OrderStatusPending || order.MarketplaceStatus != "Created" {
+		t.Errorf("order: got %s %s/%s", order.ID, order.Status, order.MarketplaceStatus)
+	}
+	if want := time.Date(2026, 9, 2, 10, 15, 0, 0, time.UTC); !order.OrderDate.Equal(want) {
+		t.Errorf("order date: got %s, want %s", order.OrderDate, want)
+	}
+	if len(order.Items) != 1 || order.Items[0].SKU != "TS-RED-M" || order.Items[0].Quantity != 2 {
+		t.Errorf("order items: got %+v", order.Items)
+	}
+
+	query.PageToken = first.NextPageToken
+	last, err := p.GetOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetOrders page 1: %v", err)
+	}
+	if len(last.Orders) != 1 || last.NextPageToken != "" {
+		t.Fatalf("last page: got %d orders, next %q", len(last.Orders), last.NextPageToken)
+	}
+	if last.Orders[0].Status != OrderStatusShipped {
+		t.Errorf("shipped order: got status %s", last.Orders[0].Status)
+	}
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(trendyolTestCredentials.APIKey+":"+trendyolTestCredentials.APISecret))
+	for _, request := range recorder.Requests() {
+		if got := request.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: Authorization %q", request.URL.Path, got)
+		}
+	}
+	// Trendyol sends no rate limit headers; the budget is counted locally
+	if remaining := p.GetRateLimit().RequestsRemaining; remaining != 58 {
+		t.Errorf("requests remaining: got %d, want 58", remaining)
+	}
+}
+
+func TestTrendyolSyncContract(t *testing.T) {
+	p, _ := newTrendyolContract(t, "trendyol_sync")
+	ctx := context.Background()
+
+	report, err := p.SyncProducts(ctx, []Listing{testListing("TS-RED-M", "8680000000011"), testListing("TS-RED-L", "8680000000028")})
+	if err != nil {
+		t.Fatalf("SyncProducts: %v", err)
+	}
+	for _, result := range report.Results {
+		if result.Status != ListingPending || result.BatchID != "c5e1b2a4-3d6f-4e1a-9b7c-2f0d8e6a1c35" {
+			t.Errorf("%s: got %s in batch %q", result.SKU, result.Status, result.BatchID)
+		}
+	}
+
+	status, err := p.GetBatchStatus(ctx, report.Results[0].BatchID)
+	if err != nil {
+		t.Fatalf("GetBatchStatus: %v", err)
+	}
+	if !status.Done || len(status.Results) != 2 {
+		t.Fatalf("batch: done %v with %d results", status.Done, len(status.Results))
+	}
+	if accepted := status.Results[0]; accepted.SKU != "TS-RED-M" || accepted.Status != ListingAccepted {
+		t.Errorf("first listing: got %+v", accepted)
+	}
+	rejected := status.Results[1]
+	if rejected.SKU != "TS-RED-L" || rejected.Status != ListingRejected || !strings.Contains(rejected.Message, "barkod") {
+		t.Errorf("second listing: got %+v", rejected)
+	}
+}
+
+func TestTrendyolErrorContract(t *testing.T) {
+	p, _ := newTrendyolContract(t, "trendyol_errors")
+	limiter := integrations.NewRateLimiter()
+	p.SetRateLimiter(limiter)
+	ctx := context.Background()
+	listings := []Listing{testListing("TS-RED-M", "8680000000011")}
+
+	// 400: the batch itself is refused, so its listings are rejected
+	report, err := p.SyncProducts(ctx, listings)
+	if err != nil {
+		t.Fatalf("SyncProducts 400: %v", err)
+	}
+	if result := report.Results[0]; result.Status != ListingRejected || !strings.Contains(result.Message, "categoryId") {
+		t.Errorf("400: got %+v", result)
+	}
+
+	// 429 and 500 stop the sync with a retryable error
+	for _, status := range []int{429, 500} {
+		_, err := p.SyncProducts(ctx, listings)
+		var integrationErr *integrations.IntegrationError
+		if !errors.As(err, &integrationErr) {
+			t.Fatalf("%d: got %v", status, err)
+		}
+		if integrationErr.StatusCode != status || !integrationErr.Retryable {
+			t.Errorf("%d: got status %d, retryable %v", status, integrationErr.StatusCode, integrationErr.Retryable)
+		}
+		if status == 429 {
+			// Retry-After: 60 holds product requests back; reset the
+			// limiter so the next request is sent right away
+			state := p.GetMetrics()["rate_limiter"].([]integrations.RateLimiterState)
+			if len(state) != 1 || state[0].Class != integrations.EndpointProducts {
+				t.Fatalf("rate limiter: got %+v", state)
+			}
+			if wait := time.Until(state[0].BlockedUntil); wait < 55*time.Second || wait > 60*time.Second {
+				t.Errorf("rate limiter blocked for %s, want 60s", wait)
+			}
+			limiter.Reset("trendyol")
+		}
+	}
+}
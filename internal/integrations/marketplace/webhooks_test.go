@@ -0,0 +1,157 @@
+package marketplace
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	type wantOrder struct {
+		id     string
+		status string
+		skus   []string
+	}
+	tests := []struct {
+		name    string
+		parse   WebhookParser
+		headers http.Header
+		id      string
+		typ     string
+		order   *wantOrder
+		stock   map[string]int
+	}{
+		{name: "trendyol_package", parse: ParseTrendyolWebhook, id: "80145571-3218007-Shipped-1788260400000", typ: "order.Shipped", order: &wantOrder{"80145571", OrderStatusShipped, []string{"TS-RED-M"}}},
+		{name: "hepsiburada_order", parse: ParseHepsiburadaWebhook, id: "c0a8012e-6f1b-4c55-9a1e-2b3f1d0e7a11", typ: "order.created", order: &wantOrder{"HB-4401927", OrderStatusPending, []string{"TS-BLU-L"}}},
+		{name: "hepsiburada_listing", parse: ParseHepsiburadaWebhook, id: "5e7d2a90-1c3b-4f4e-8d6a-0b9c8e7f6a55", typ: "product.updated", stock: map[string]int{"TS-BLU-L": 7}},
+		{name: "n11_order", parse: ParseN11Webhook, typ: "ORDER_STATUS_CHANGED", order: &wantOrder{"208441", OrderStatusConfirmed, []string{"TS-RED-M"}}},
+		{name: "amazon_order_change", parse: ParseAmazonNotification, id: "0e999936-da2c-4f9c-9fc2-02b67bae5f27", typ: "ORDER_CHANGE", order: &wantOrder{"405-1234567-7654321", OrderStatusShipped, nil}},
+		{name: "amazon_inventory", parse: ParseAmazonNotification, id: "7d1c3a55-8b1e-4f0a-b6b2-6c1f2a9e4d10", typ: "FBA_INVENTORY_AVAILABILITY_CHANGES", stock: map[string]int{"TS-RED-M": 12}},
+		{
+			name:    "shopify_order",
+			parse:   ParseShopifyWebhook,
+			headers: http.Header{"X-Shopify-Topic": {"orders/updated"}, "X-Shopify-Webhook-Id": {"b54557e4-bdd9-4b37-8a5f-bf7d70bcd043"}},
+			id:      "b54557e4-bdd9-4b37-8a5f-bf7d70bcd043",
+			typ:     "orders/updated",
+			order:   &wantOrder{"5617001234567", OrderStatusShipped, []string{"TS-RED-M"}},
+		},
+		{
+			name:    "shopify_product",
+			parse:   ParseShopifyWebhook,
+			headers: http.Header{"X-Shopify-Topic": {"products/update"}, "X-Shopify-Webhook-Id": {"1f0a7c61-3c8e-4a55-a0a4-5d2d0c9a8b77"}},
+			id:      "1f0a7c61-3c8e-4a55-a0a4-5d2d0c9a8b77",
+			typ:     "products/update",
+			stock:   map[string]int{"TS-RED-M": 4, "TS-RED-L": 0},
+		},
+		{
+			name:    "woocommerce_order",
+			parse:   ParseWooCommerceWebhook,
+			headers: http.Header{"X-Wc-Webhook-Topic": {"order.updated"}},
+			id:      "order-727-processing-1788592590",
+			typ:     "order.updated",
+			order:   &wantOrder{"727", OrderStatusConfirmed, []string{"TS-BLU-L"}},
+		},
+		{
+			name:    "woocommerce_product",
+			parse:   ParseWooCommerceWebhook,
+			headers: http.Header{"X-Wc-Webhook-Topic": {"product.updated"}},
+			id:      "product-93-1788595200",
+			typ:     "product.updated",
+			stock:   map[string]int{"TS-BLU-L": 15},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", tt.name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := tt.headers
+			if headers == nil {
+				headers = http.Header{}
+			}
+			notification, err := tt.parse(payload, headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.id != "" && notification.ID != tt.id {
+				t.Errorf("got ID %q, want %q", notification.ID, tt.id)
+			}
+			if notification.ID == "" {
+				t.Error("notification has no ID")
+			}
+			if again, _ := tt.parse(payload, headers); again.ID != notification.ID {
+				t.Errorf("ID changed between deliveries: %q, %q", notification.ID, again.ID)
+			}
+			if notification.Type != tt.typ {
+				t.Errorf("got type %q, want %q", notification.Type, tt.typ)
+			}
+
+			if tt.order == nil {
+				if len(notification.Orders) != 0 {
+					t.Errorf("got %d orders, want none", len(notification.Orders))
+				}
+			} else {
+				if len(notification.Orders) != 1 {
+					t.Fatalf("got %d orders, want 1", len(notification.Orders))
+				}
+				order := notification.Orders[0]
+				if order.ID != tt.order.id || order.Status != tt.order.status {
+					t.Errorf("got order %s %s, want %s %s", order.ID, order.Status, tt.order.id, tt.order.status)
+				}
+				if order.Status == OrderStatusShipped && len(order.Items) > 0 && order.TrackingNumber == "" {
+					t.Error("shipped order has no tracking number")
+				}
+				if len(order.Items) != len(tt.order.skus) {
+					t.Fatalf("got %d items, want %d", len(order.Items), len(tt.order.skus))
+				}
+				for i, sku := range tt.order.skus {
+					if order.Items[i].SKU != sku || order.Items[i].Quantity <= 0 {
+						t.Errorf("item %d: got %s x%d, want %s", i, order.Items[i].SKU, order.Items[i].Quantity, sku)
+					}
+				}
+			}
+
+			if len(notification.Stock) != len(tt.stock) {
+				t.Fatalf("got %d stock updates, want %d", len(notification.Stock), len(tt.stock))
+			}
+			for _, update := range notification.Stock {
+				want, ok := tt.stock[update.SKU]
+				if !ok || update.Stock == nil || *update.Stock != want {
+					t.Errorf("unexpected stock update %+v", update)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWebhookAmounts(t *testing.T) {
+	payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", "woocommerce_order.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err := ParseWooCommerceWebhook(payload, http.Header{"X-Wc-Webhook-Topic": {"order.created"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := notification.Orders[0]
+	if order.TotalAmount != 164.9 || order.ShippingAmount != 25 || order.DiscountAmount != 10 {
+		t.Errorf("got total %.2f, shipping %.2f, discount %.2f", order.TotalAmount, order.ShippingAmount, order.DiscountAmount)
+	}
+	if item := order.Items[0]; item.TotalPrice != 139.9 || item.DiscountAmount < 9.99 || item.DiscountAmount > 10.01 {
+		t.Errorf("got item %+v", item)
+	}
+	if order.CustomerName != "Elif Şahin" || order.ShippingAddress.City != "İzmir" {
+		t.Errorf("got customer %q in %q", order.CustomerName, order.ShippingAddress.City)
+	}
+
+	if _, err := ParseShopifyWebhook([]byte(`{"id": 1}`), http.Header{}); err == nil {
+		t.Error("shopify webhook without a topic was accepted")
+	}
+	ping, err := ParseWooCommerceWebhook([]byte("webhook_id=12"), http.Header{})
+	if err != nil || ping.Type != "ping" || len(ping.Orders) != 0 {
+		t.Errorf("got %+v, %v for a ping", ping, err)
+	}
+}
@@ -9,20 +9,7 @@ import (
 // PaymentProvider interface for all payment gateways
 type PaymentProvider interface {
 	integrations.IntegrationProvider
-	
-	// Payment operations
-	CreatePayment(ctx context.Context, payment *PaymentRequest) (*PaymentResponse, error)
-	CapturePayment(ctx context.Context, paymentID string, amount float64) (*PaymentResponse, error)
-	RefundPayment(ctx context.Context, paymentID string, amount float64) (*RefundResponse, error)
-	GetPaymentStatus(ctx context.Context, paymentID string) (*PaymentStatus, error)
-	
-	// 3D Secure operations
-	Initialize3DSecure(ctx context.Context, payment *PaymentRequest) (*ThreeDSecureResponse, error)
-	Verify3DSecure(ctx context.Context, paymentID string, verificationData map[string]string) (*PaymentResponse, error)
-	
-	// Tokenization
-	TokenizeCard(ctx context.Context, card *CardDetails) (*CardToken, error)
-	DeleteToken(ctx context.Context, tokenID string) error
+	Gateway
 	
 	// Subscription operations
 	CreateSubscription(ctx context.Context, subscription *SubscriptionRequest) (*SubscriptionResponse, error)
@@ -51,6 +38,9 @@ type PaymentRequest struct {
 	CallbackURL     string                 `json:"callback_url"`
 	Installment     int                    `json:"installment,omitempty"`
 	Enable3DSecure  bool                   `json:"enable_3d_secure"`
+	// AuthorizeOnly places a hold on the card that has to be captured or
+	// voided later instead of charging it immediately
+	AuthorizeOnly   bool                   `json:"authorize_only,omitempty"`
 }
 
 // PaymentResponse represents a payment response
@@ -155,6 +145,7 @@ type PaymentStatusType string
 const (
 	PaymentStatusPending    PaymentStatusType = "pending"
 	PaymentStatusProcessing PaymentStatusType = "processing"
+	PaymentStatusAuthorized PaymentStatusType = "authorized"
 	PaymentStatusSucceeded  PaymentStatusType = "succeeded"
 	PaymentStatusFailed     PaymentStatusType = "failed"
 	PaymentStatusCanceled   PaymentStatusType = "canceled"
@@ -231,6 +222,8 @@ type SubscriptionResponse struct {
 type Transaction struct {
 	ID              string            `json:"id"`
 	Type            string            `json:"type"` // payment, refund, chargeback
+	// PaymentID is the payment a refund or chargeback belongs to
+	PaymentID       string            `json:"payment_id,omitempty"`
 	Status          PaymentStatusType `json:"status"`
 	Amount          float64           `json:"amount"`
 	Currency        string            `json:"currency"`
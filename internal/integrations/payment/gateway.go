@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kolajAi/internal/integrations"
+)
+
+// Gateway is the card payment lifecycle shared by every payment gateway.
+// Checkout only talks to a Gateway, so iyzico and the local sandbox are
+// interchangeable.
+type Gateway interface {
+	// Name identifies the gateway in stored payment records
+	Name() string
+
+	// CreatePayment authorizes a payment and, unless the request is
+	// AuthorizeOnly, captures it. Declined cards come back as a failed
+	// response or a non-retryable IntegrationError. Payments that need a
+	// 3-D Secure challenge come back pending with the challenge in their
+	// metadata.
+	CreatePayment(ctx context.Context, payment *PaymentRequest) (*PaymentResponse, error)
+	CapturePayment(ctx context.Context, paymentID string, amount float64) (*PaymentResponse, error)
+	RefundPayment(ctx context.Context, paymentID string, amount float64) (*RefundResponse, error)
+	// VoidPayment releases an authorized payment that has not been captured
+	VoidPayment(ctx context.Context, paymentID string) (*PaymentResponse, error)
+	GetPaymentStatus(ctx context.Context, paymentID string) (*PaymentStatus, error)
+
+	// 3D Secure operations
+	Initialize3DSecure(ctx context.Context, payment *PaymentRequest) (*ThreeDSecureResponse, error)
+	Verify3DSecure(ctx context.Context, paymentID string, verificationData map[string]string) (*PaymentResponse, error)
+
+	// Tokenization
+	TokenizeCard(ctx context.Context, card *CardDetails) (*CardToken, error)
+	DeleteToken(ctx context.Context, tokenID string) error
+}
+
+// TransactionLister is implemented by gateways that report their
+// transactions, so they can be reconciled against local payments
+type TransactionLister interface {
+	Name() string
+	ListTransactions(ctx context.Context, filters TransactionFilters) ([]*Transaction, error)
+}
+
+// Transaction types reported by ListTransactions
+const (
+	TransactionTypePayment = "payment"
+	TransactionTypeRefund  = "refund"
+	TransactionTypeVoid    = "void"
+)
+
+// WebhookVerifier is implemented by gateways that sign their webhooks
+type WebhookVerifier interface {
+	VerifyWebhook(payload *WebhookPayload) error
+}
+
+// Webhook event types sent by gateways when a payment changes status
+// outside of a request, e.g. after a bank settles an asynchronous payment
+const (
+	WebhookPaymentSucceeded  = "payment.succeeded"
+	WebhookPaymentFailed     = "payment.failed"
+	WebhookPaymentAuthorized = "payment.authorized"
+	WebhookPaymentCanceled   = "payment.canceled"
+	WebhookPaymentRefunded   = "payment.refunded"
+)
+
+// Metadata keys of a payment response that waits for a 3-D Secure challenge
+const (
+	MetadataRequires3DSecure = "requires_3d_secure"
+	Metadata3DSecureHTML     = "3d_secure_html"
+	Metadata3DSecureURL      = "3d_secure_url"
+)
+
+// Requires3DSecure reports whether the payment waits for a 3-D Secure
+// challenge to be completed through Verify3DSecure
+func (r *PaymentResponse) Requires3DSecure() bool {
+	required, _ := r.Metadata[MetadataRequires3DSecure].(bool)
+	return required
+}
+
+// declineCodes are the error codes gateways reject a payment itself with
+var declineCodes = map[string]bool{
+	ErrorCodeInsufficientFunds: true,
+	ErrorCodeCardDeclined:      true,
+	ErrorCodeInvalidCard:       true,
+	ErrorCodeExpiredCard:       true,
+	ErrorCodeFraudDetected:     true,
+	ErrorCode3DSecureFailed:    true,
+
+	// iyzico reports the bank's answer with its own codes
+	"10005": true, // do not honour
+	"10012": true, // invalid transaction
+	"10034": true, // fraud suspected
+	"10041": true, // lost card
+	"10043": true, // stolen card
+	"10051": true, // insufficient funds
+	"10054": true, // expired card
+	"10057": true, // not permitted to the card holder
+	"10058": true, // not permitted to the terminal
+	"10084": true, // invalid CVC
+	"10093": true, // card blocked for online payments
+	"10201": true, // card not permitted
+	"10207": true, // contact the issuing bank
+	"10215": true, // invalid card number
+}
+
+// IsDecline reports whether err is a gateway rejection of the payment itself
+// (declined card, failed 3-D Secure, fraud) rather than a failure to reach
+// the gateway or an invalid request
+func IsDecline(err error) bool {
+	var integrationErr *integrations.IntegrationError
+	return errors.As(err, &integrationErr) && !integrationErr.Retryable && declineCodes[integrationErr.Code]
+}
+
+// gatewayError builds the error a gateway returns for a rejected operation
+func gatewayError(provider, code, message string) *integrations.IntegrationError {
+	return &integrations.IntegrationError{
+		Code:      code,
+		Message:   message,
+		Provider:  provider,
+		Retryable: false,
+		Timestamp: time.Now(),
+	}
+}
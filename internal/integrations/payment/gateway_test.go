@@ -0,0 +1,31 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"kolajAi/internal/integrations"
+)
+
+func TestIsDecline(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"declined card", gatewayError("sandbox", ErrorCodeCardDeclined, "declined"), true},
+		{"failed 3-D Secure", gatewayError("sandbox", ErrorCode3DSecureFailed, "rejected"), true},
+		{"wrapped iyzico decline", fmt.Errorf("charge: %w", gatewayError("iyzico", "10051", "insufficient funds")), true},
+		{"invalid state", gatewayError("sandbox", "INVALID_STATE", "cannot capture a failed payment"), false},
+		{"invalid amount", gatewayError("sandbox", ErrorCodeInvalidAmount, "payment amount must be positive"), false},
+		{"unparseable answer", gatewayError("iyzico", "PARSE_ERROR", "failed to parse response"), false},
+		{"outage", &integrations.IntegrationError{Code: ErrorCodeCardDeclined, Retryable: true}, false},
+		{"plain error", errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := IsDecline(tt.err); got != tt.want {
+			t.Errorf("%s: IsDecline = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
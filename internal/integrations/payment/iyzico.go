@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -21,6 +22,10 @@ type IyzicoProvider struct {
 	credentials integrations.Credentials
 	baseURL     string
 	rateLimit   integrations.RateLimitInfo
+	limiter     *integrations.RateLimiter
+	// randomKey generates the random key of request signatures; it
+	// defaults to the current time in nanoseconds
+	randomKey func() string
 }
 
 // NewIyzicoProvider creates a new Iyzico payment provider
@@ -34,9 +39,20 @@ func NewIyzicoProvider() *IyzicoProvider {
 			RequestsRemaining: 100,
 			ResetsAt:          time.Now().Add(time.Minute),
 		},
+		limiter: integrations.DefaultRateLimiter,
 	}
 }
 
+// SetHTTPClient replaces the client requests are sent with
+func (p *IyzicoProvider) SetHTTPClient(client *http.Client) {
+	p.httpClient = client
+}
+
+// SetRateLimiter replaces the limiter requests wait for
+func (p *IyzicoProvider) SetRateLimiter(limiter *integrations.RateLimiter) {
+	p.limiter = limiter
+}
+
 // Initialize sets up the Iyzico provider
 func (p *IyzicoProvider) Initialize(ctx context.Context, credentials integrations.Credentials, config map[string]interface{}) error {
 	p.credentials = credentials
@@ -124,6 +140,11 @@ func (p *IyzicoProvider) GetRateLimit() integrations.RateLimitInfo {
 	return p.rateLimit
 }
 
+// Name identifies iyzico in stored payment records
+func (p *IyzicoProvider) Name() string {
+	return "iyzico"
+}
+
 // Close cleans up any resources
 func (p *IyzicoProvider) Close() error {
 	// No specific cleanup needed for Iyzico
@@ -135,10 +156,16 @@ func (p *IyzicoProvider) CreatePayment(ctx context.Context, payment *PaymentRequ
 	// Build Iyzico payment request
 	iyzicoRequest := p.buildPaymentRequest(payment)
 	
-	// Determine endpoint based on 3D Secure requirement
+	// Determine endpoint based on 3D Secure and pre-authorization
 	endpoint := "/payment/auth"
+	if payment.AuthorizeOnly {
+		endpoint = "/payment/preauth"
+	}
 	if payment.Enable3DSecure {
 		endpoint = "/payment/3dsecure/initialize"
+		if payment.AuthorizeOnly {
+			endpoint = "/payment/3dsecure/initialize/preauth"
+		}
 	}
 	
 	var iyzicoResponse map[string]interface{}
@@ -151,16 +178,65 @@ func (p *IyzicoProvider) CreatePayment(ctx context.Context, payment *PaymentRequ
 	return p.parsePaymentResponse(iyzicoResponse, payment)
 }
 
-// CapturePayment captures a previously authorized payment
+// CapturePayment captures a payment pre-authorized with AuthorizeOnly
 func (p *IyzicoProvider) CapturePayment(ctx context.Context, paymentID string, amount float64) (*PaymentResponse, error) {
-	// Iyzico doesn't support separate auth/capture, payments are captured immediately
-	return nil, &integrations.IntegrationError{
-		Code:      "NOT_SUPPORTED",
-		Message:   "Iyzico does not support separate authorization and capture",
-		Provider:  "iyzico",
-		Retryable: false,
-		Timestamp: time.Now(),
+	request := map[string]interface{}{
+		"locale":         "tr",
+		"conversationId": fmt.Sprintf("capture-%s-%d", paymentID, time.Now().Unix()),
+		"paymentId":      paymentID,
+		"paidPrice":      fmt.Sprintf("%.2f", amount),
+		"ip":             "127.0.0.1",
+	}
+	
+	var response map[string]interface{}
+	err := p.makeRequest(ctx, "POST", "/payment/postauth", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	
+	return p.parsePaymentResponse(response, nil)
+}
+
+// VoidPayment cancels a payment before it is settled. Iyzico releases
+// pre-authorizations and reverses same-day payments through the same
+// cancel endpoint.
+func (p *IyzicoProvider) VoidPayment(ctx context.Context, paymentID string) (*PaymentResponse, error) {
+	request := map[string]interface{}{
+		"locale":         "tr",
+		"conversationId": fmt.Sprintf("cancel-%s-%d", paymentID, time.Now().Unix()),
+		"paymentId":      paymentID,
+		"ip":             "127.0.0.1",
+	}
+	
+	var response map[string]interface{}
+	err := p.makeRequest(ctx, "POST", "/payment/cancel", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	
+	status, _ := response["status"].(string)
+	if status != "success" {
+		errorMessage, _ := response["errorMessage"].(string)
+		return nil, &integrations.IntegrationError{
+			Code:      "VOID_FAILED",
+			Message:   errorMessage,
+			Provider:  "iyzico",
+			Retryable: false,
+			Timestamp: time.Now(),
+		}
 	}
+	
+	amount, _ := response["price"].(float64)
+	currency, _ := response["currency"].(string)
+	return &PaymentResponse{
+		ID:            paymentID,
+		Status:        PaymentStatusCanceled,
+		Amount:        amount,
+		Currency:      currency,
+		TransactionID: paymentID,
+		CreatedAt:     time.Now(),
+		ProcessedAt:   time.Now(),
+	}, nil
 }
 
 // RefundPayment refunds a payment
@@ -234,7 +310,7 @@ func (p *IyzicoProvider) GetPaymentStatus(ctx context.Context, paymentID string)
 		case "AUTH":
 			paymentStatus = string(PaymentStatusSucceeded)
 		case "PRE_AUTH":
-			paymentStatus = string(PaymentStatusPending)
+			paymentStatus = string(PaymentStatusAuthorized)
 		case "FRAUD":
 			paymentStatus = string(PaymentStatusFailed)
 		}
@@ -421,7 +497,7 @@ func (p *IyzicoProvider) GetTransaction(ctx context.Context, transactionID strin
 	
 	return &Transaction{
 		ID:        transactionID,
-		Type:      "payment",
+		Type:      TransactionTypePayment,
 		Status:    status.Status,
 		Amount:    status.Amount,
 		Currency:  "TRY",
@@ -429,18 +505,110 @@ func (p *IyzicoProvider) GetTransaction(ctx context.Context, transactionID strin
 	}, nil
 }
 
-// ListTransactions lists transactions based on filters
+// ListTransactions lists the transactions of the filter's date range
+// through iyzico's daily transaction report. Payments carry our order ID
+// as their conversation ID.
 func (p *IyzicoProvider) ListTransactions(ctx context.Context, filters TransactionFilters) ([]*Transaction, error) {
-	// Basic implementation - in production this would use Iyzico's reporting API
-	log.Printf("ListTransactions called with filters: %+v", filters)
+	endDate := filters.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now()
+	}
+	startDate := filters.StartDate
+	if startDate.IsZero() {
+		startDate = endDate
+	}
+	
+	var transactions []*Transaction
+	firstDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	for day := firstDay; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		for page := 1; ; page++ {
+			endpoint := fmt.Sprintf("/v2/reporting/payment/transactions?transactionDate=%s&page=%d", day.Format("2006-01-02"), page)
+			
+			var response map[string]interface{}
+			if err := p.makeRequest(ctx, "GET", endpoint, nil, &response); err != nil {
+				return nil, err
+			}
+			
+			status, _ := response["status"].(string)
+			if status != "success" {
+				errorMessage, _ := response["errorMessage"].(string)
+				return nil, &integrations.IntegrationError{
+					Code:      "TRANSACTION_LIST_FAILED",
+					Message:   errorMessage,
+					Provider:  "iyzico",
+					Retryable: true,
+					Timestamp: time.Now(),
+				}
+			}
+			
+			items, _ := response["transactions"].([]interface{})
+			for _, item := range items {
+				data, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				transaction := p.parseTransaction(data)
+				if transaction.CreatedAt.Before(filters.StartDate) || (!filters.EndDate.IsZero() && transaction.CreatedAt.After(filters.EndDate)) {
+					continue
+				}
+				if filters.Status != "" && transaction.Status != filters.Status {
+					continue
+				}
+				if filters.Currency != "" && transaction.Currency != filters.Currency {
+					continue
+				}
+				transactions = append(transactions, transaction)
+			}
+			
+			totalPages, _ := response["totalPageCount"].(float64)
+			if float64(page) >= totalPages {
+				break
+			}
+		}
+	}
 	
-	// For now, return empty list as Iyzico requires specific reporting API access
-	// In a real implementation, this would:
-	// 1. Build query parameters from filters
-	// 2. Make API call to Iyzico reporting endpoint
-	// 3. Parse response and convert to Transaction structs
+	return transactions, nil
+}
+
+// parseTransaction converts an iyzico report row to a generic transaction
+func (p *IyzicoProvider) parseTransaction(data map[string]interface{}) *Transaction {
+	transaction := &Transaction{
+		Type:   TransactionTypePayment,
+		Status: PaymentStatusFailed,
+	}
+	
+	paymentID := fmt.Sprint(data["paymentId"])
+	switch data["transactionType"] {
+	case "REFUND":
+		transaction.Type = TransactionTypeRefund
+		transaction.ID = fmt.Sprint(data["transactionId"])
+		transaction.PaymentID = paymentID
+	case "CANCEL":
+		transaction.Type = TransactionTypeVoid
+		transaction.ID = fmt.Sprint(data["transactionId"])
+		transaction.PaymentID = paymentID
+	default:
+		transaction.ID = paymentID
+	}
+	if status, ok := data["transactionStatus"].(float64); ok && status == 1 {
+		transaction.Status = PaymentStatusSucceeded
+	}
+	
+	transaction.Amount, _ = data["price"].(float64)
+	transaction.Currency, _ = data["transactionCurrency"].(string)
+	transaction.OrderID, _ = data["conversationId"].(string)
+	transaction.NetAmount, _ = data["merchantPayoutAmount"].(float64)
+	commission, _ := data["iyziCommissionFee"].(float64)
+	commissionRate, _ := data["iyziCommissionRateAmount"].(float64)
+	transaction.Fees = commission + commissionRate
+	if date, ok := data["transactionDate"].(string); ok {
+		if parsed, err := time.ParseInLocation("2006-01-02 15:04:05", date, time.Local); err == nil {
+			transaction.CreatedAt = parsed
+			transaction.ProcessedAt = parsed
+		}
+	}
 	
-	return []*Transaction{}, nil
+	return transaction
 }
 
 // GetBalance gets the account balance
@@ -479,9 +647,15 @@ func (p *IyzicoProvider) makeRequest(ctx context.Context, method, endpoint strin
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	
+	// Wait for the rate limiter
+	if err := p.limiter.Wait(ctx, "iyzico", integrations.EndpointDefault, p.rateLimit); err != nil {
+		return err
+	}
+	
 	// Generate authorization header
-	auth := p.generateAuthHeader(endpoint, string(requestBody))
-	req.Header.Set("Authorization", auth)
+	randomKey := p.newRandomKey()
+	req.Header.Set("Authorization", p.generateAuthHeader(endpoint, string(requestBody), randomKey))
+	req.Header.Set("x-iyzi-rnd", randomKey)
 	
 	// Execute request
 	resp, err := p.httpClient.Do(req)
@@ -498,6 +672,20 @@ func (p *IyzicoProvider) makeRequest(ctx context.Context, method, endpoint strin
 	
 	// Update rate limit info
 	p.updateRateLimit(resp.Header)
+	p.limiter.Observe("iyzico", integrations.EndpointDefault, resp, p.rateLimit)
+	
+	// Iyzico reports declines and invalid requests in the body with
+	// status failure; only outages and throttling come as HTTP errors
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return &integrations.IntegrationError{
+			Code:       "API_ERROR",
+			Message:    fmt.Sprintf("Iyzico API returned status %d", resp.StatusCode),
+			Provider:   "iyzico",
+			Retryable:  true,
+			Timestamp:  time.Now(),
+			StatusCode: resp.StatusCode,
+		}
+	}
 	
 	// Parse response
 	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
@@ -514,19 +702,30 @@ func (p *IyzicoProvider) makeRequest(ctx context.Context, method, endpoint strin
 	return nil
 }
 
-// generateAuthHeader generates Iyzico authorization header
-func (p *IyzicoProvider) generateAuthHeader(uri string, body string) string {
-	randomKey := fmt.Sprintf("%d", time.Now().UnixNano())
-	message := p.credentials.APIKey + randomKey + p.credentials.APISecret + body
+// generateAuthHeader generates the IYZWSv2 authorization header: an
+// HMAC-SHA256 of the random key, the URI path and the body, keyed with the
+// secret key
+func (p *IyzicoProvider) generateAuthHeader(uri string, body string, randomKey string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		uri = uri[:i]
+	}
 	
 	h := hmac.New(sha256.New, []byte(p.credentials.APISecret))
-	h.Write([]byte(message))
-	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	h.Write([]byte(randomKey + uri + body))
+	signature := hex.EncodeToString(h.Sum(nil))
 	
 	authString := fmt.Sprintf("apiKey:%s&randomKey:%s&signature:%s",
 		p.credentials.APIKey, randomKey, signature)
 	
-	return "IYZWS " + base64.StdEncoding.EncodeToString([]byte(authString))
+	return "IYZWSv2 " + base64.StdEncoding.EncodeToString([]byte(authString))
+}
+
+// newRandomKey returns the random key of a request's signature
+func (p *IyzicoProvider) newRandomKey() string {
+	if p.randomKey != nil {
+		return p.randomKey()
+	}
+	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
 
 // updateRateLimit updates rate limit information from response headers
@@ -629,8 +828,13 @@ func (p *IyzicoProvider) parsePaymentResponse(iyzicoResp map[string]interface{},
 	}
 	
 	paymentStatus := PaymentStatusPending
-	if phase, ok := iyzicoResp["phase"].(string); ok && phase == "AUTH" {
-		paymentStatus = PaymentStatusSucceeded
+	if phase, ok := iyzicoResp["phase"].(string); ok {
+		switch phase {
+		case "AUTH", "POST_AUTH":
+			paymentStatus = PaymentStatusSucceeded
+		case "PRE_AUTH":
+			paymentStatus = PaymentStatusAuthorized
+		}
 	}
 	
 	// Safely extract values with type assertions
@@ -660,7 +864,7 @@ func (p *IyzicoProvider) parsePaymentResponse(iyzicoResp map[string]interface{},
 		CreatedAt:       time.Now(),
 	}
 	
-	if paymentStatus == PaymentStatusSucceeded {
+	if paymentStatus == PaymentStatusSucceeded || paymentStatus == PaymentStatusAuthorized {
 		response.ProcessedAt = time.Now()
 	}
 	
@@ -668,8 +872,8 @@ func (p *IyzicoProvider) parsePaymentResponse(iyzicoResp map[string]interface{},
 	if htmlContent, ok := iyzicoResp["threeDSHtmlContent"].(string); ok && htmlContent != "" {
 		response.Status = PaymentStatusPending
 		response.Metadata = map[string]interface{}{
-			"requires_3d_secure": true,
-			"3d_secure_html":     htmlContent,
+			MetadataRequires3DSecure: true,
+			Metadata3DSecureHTML:     htmlContent,
 		}
 	}
 	
@@ -0,0 +1,156 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/httpreplay"
+)
+
+// The contract tests run the provider against recorded iyzico traffic in
+// testdata. To refresh a fixture against the sandbox, run the test with
+// HTTPREPLAY=record and IYZICO_API_KEY and IYZICO_SECRET_KEY set.
+
+var iyzicoTestCredentials = integrations.Credentials{
+	APIKey:    testValue("IYZICO_API_KEY", "sandbox-test-api-key"),
+	APISecret: testValue("IYZICO_SECRET_KEY", "sandbox-test-secret-key"),
+}
+
+// replay returns a recorder for the named fixture that fails the test on
+// requests the fixture does not answer and interactions left unused
+func replay(t *testing.T, name string) *httpreplay.Recorder {
+	t.Helper()
+	recorder, err := httpreplay.New(filepath.Join("testdata", name+".json"), httpreplay.Config{Mode: httpreplay.ModeFromEnv()})
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+		}
+		for _, request := range recorder.Unmatched() {
+			t.Errorf("unexpected request %s", request)
+		}
+		for _, interaction := range recorder.Unused() {
+			t.Errorf("request not sent: %s %s", interaction.Request.Method, interaction.Request.URL)
+		}
+	})
+	return recorder
+}
+
+// testValue returns the environment variable when recording and fallback
+// otherwise, so replays never depend on real credentials
+func testValue(env, fallback string) string {
+	if httpreplay.ModeFromEnv() == httpreplay.ModeRecord {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+	}
+	return fallback
+}
+
+func newIyzicoContract(t *testing.T, fixture string) (*IyzicoProvider, *httpreplay.Recorder) {
+	t.Helper()
+	recorder := replay(t, fixture)
+	p := NewIyzicoProvider()
+	p.SetHTTPClient(recorder.Client())
+	p.SetRateLimiter(integrations.NewRateLimiter())
+	p.randomKey = func() string { return "1788344100000000001" }
+	if err := p.Initialize(context.Background(), iyzicoTestCredentials, map[string]interface{}{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return p, recorder
+}
+
+// testPayment is a payment with iyzico's sandbox test card
+func testPayment(orderID, cardNumber string) *PaymentRequest {
+	address := Address{
+		FirstName:    "Ayşe",
+		LastName:     "Yılmaz",
+		AddressLine1: "Bağdat Cad. No: 12",
+		City:         "İstanbul",
+		PostalCode:   "34710",
+		Country:      "Turkey",
+		Phone:        "+905320000000",
+		Email:        "ayse.yilmaz@example.com",
+	}
+	return &PaymentRequest{
+		Amount:     299.8,
+		Currency:   "TRY",
+		OrderID:    orderID,
+		CustomerID: "42",
+		PaymentMethod: PaymentMethod{
+			Type: PaymentMethodTypeCard,
+			Card: &CardDetails{Number: cardNumber, ExpMonth: "12", ExpYear: "2030", CVV: "123", HolderName: "Ayşe Yılmaz"},
+		},
+		BillingAddress:  address,
+		ShippingAddress: address,
+		Items:           []PaymentItem{{ID: "TS-RED-M", Name: "Pamuklu Kadın Tişört", Quantity: 2, Price: 149.9, Category: "Giyim"}},
+		Installment:     1,
+	}
+}
+
+func TestIyzicoPaymentContract(t *testing.T) {
+	p, recorder := newIyzicoContract(t, "iyzico_payment")
+	ctx := context.Background()
+
+	response, err := p.CreatePayment(ctx, testPayment("ORD-1001", "5528790000000008"))
+	if err != nil {
+		t.Fatalf("CreatePayment: %v", err)
+	}
+	if response.Status != PaymentStatusSucceeded || response.ID != "22416035" || response.Amount != 299.8 {
+		t.Errorf("payment: got %s %s %.2f", response.ID, response.Status, response.Amount)
+	}
+
+	// A decline is answered with status failure and iyzico's error code
+	_, err = p.CreatePayment(ctx, testPayment("ORD-1002", "4111111111111129"))
+	var integrationErr *integrations.IntegrationError
+	if !errors.As(err, &integrationErr) || integrationErr.Code != "10051" || integrationErr.Retryable {
+		t.Fatalf("declined payment: got %v", err)
+	}
+	if !IsDecline(err) {
+		t.Fatalf("declined payment is not a decline: %v", err)
+	}
+
+	// IYZWSv2: base64 of the API key, the random key and the hex
+	// HMAC-SHA256 of random key, URI path and body under the secret key
+	for _, request := range recorder.Requests() {
+		body, _ := io.ReadAll(request.Body)
+		randomKey := request.Header.Get("X-Iyzi-Rnd")
+		if randomKey != "1788344100000000001" {
+			t.Errorf("x-iyzi-rnd: got %q", randomKey)
+		}
+		mac := hmac.New(sha256.New, []byte(iyzicoTestCredentials.APISecret))
+		mac.Write([]byte(randomKey + request.URL.Path + string(body)))
+		want := "IYZWSv2 " + base64.StdEncoding.EncodeToString([]byte(
+			"apiKey:"+iyzicoTestCredentials.APIKey+"&randomKey:"+randomKey+"&signature:"+hex.EncodeToString(mac.Sum(nil))))
+		if got := request.Header.Get("Authorization"); got != want {
+			t.Errorf("Authorization:\n got %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestIyzicoErrorContract(t *testing.T) {
+	p, _ := newIyzicoContract(t, "iyzico_errors")
+
+	// Outages and throttling are retryable; declines never are
+	for _, status := range []int{500, 429} {
+		_, err := p.CreatePayment(context.Background(), testPayment("ORD-1003", "5528790000000008"))
+		var integrationErr *integrations.IntegrationError
+		if !errors.As(err, &integrationErr) {
+			t.Fatalf("%d: got %v", status, err)
+		}
+		if integrationErr.StatusCode != status || !integrationErr.Retryable {
+			t.Errorf("%d: got status %d, retryable %v", status, integrationErr.StatusCode, integrationErr.Retryable)
+		}
+	}
+}
@@ -0,0 +1,667 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Magic card numbers of the sandbox gateway. Any other number that passes
+// the Luhn check is approved; numbers that fail it are rejected as invalid.
+const (
+	SandboxCardApproved          = "4242424242424242"
+	SandboxCardDeclined          = "4000000000000002"
+	SandboxCardInsufficientFunds = "4000000000009995"
+	SandboxCardExpired           = "4000000000000069"
+	SandboxCardFraud             = "4100000000000019"
+	SandboxCardProcessingError   = "4000000000000119"
+	// SandboxCard3DSecure is approved after a 3-D Secure challenge
+	SandboxCard3DSecure = "4000000000003220"
+	// SandboxCardAsync stays processing and settles as approved through a
+	// webhook
+	SandboxCardAsync = "4000000000000077"
+	// SandboxCardAsyncDeclined stays processing and is declined through a
+	// webhook
+	SandboxCardAsyncDeclined = "4000000000000341"
+)
+
+// Sandbox3DSecureFailed passed as the "result" verification field fails the
+// 3-D Secure challenge. Any other result passes it.
+const Sandbox3DSecureFailed = "failed"
+
+// sandboxOutcome is what the sandbox does with a card
+type sandboxOutcome struct {
+	declineCode string
+	challenge   bool
+	async       bool
+}
+
+var sandboxCards = map[string]sandboxOutcome{
+	SandboxCardDeclined:          {declineCode: ErrorCodeCardDeclined},
+	SandboxCardInsufficientFunds: {declineCode: ErrorCodeInsufficientFunds},
+	SandboxCardExpired:           {declineCode: ErrorCodeExpiredCard},
+	SandboxCardFraud:             {declineCode: ErrorCodeFraudDetected},
+	SandboxCardProcessingError:   {declineCode: ErrorCodeProcessingError},
+	SandboxCard3DSecure:          {challenge: true},
+	SandboxCardAsync:             {async: true},
+	SandboxCardAsyncDeclined:     {async: true, declineCode: ErrorCodeCardDeclined},
+}
+
+// SandboxConfig configures the sandbox gateway
+type SandboxConfig struct {
+	// WebhookHandler receives a webhook for every payment status change.
+	// Without it webhooks are dropped.
+	WebhookHandler func(*WebhookPayload)
+	// WebhookDelay is how long after a status change its webhook is
+	// delivered and how long asynchronous payments stay processing. It
+	// defaults to 100ms.
+	WebhookDelay time.Duration
+	// WebhookSecret signs the webhook payloads
+	WebhookSecret string
+	// Force3DSecure challenges every card payment
+	Force3DSecure bool
+	Logger        *log.Logger
+}
+
+// SandboxProvider is a local, in-memory payment gateway. It simulates
+// declines, 3-D Secure challenges and asynchronous webhooks through magic
+// card numbers so checkout can be exercised end-to-end without network
+// access.
+type SandboxProvider struct {
+	config SandboxConfig
+
+	mu       sync.Mutex
+	seq      int64
+	payments map[string]*sandboxPayment
+	tokens   map[string]CardDetails
+	webhooks sync.WaitGroup
+}
+
+// sandboxPayment is the state of one sandbox payment
+type sandboxPayment struct {
+	id          string
+	request     PaymentRequest
+	card        CardDetails
+	outcome     sandboxOutcome
+	status      PaymentStatusType
+	captured    float64
+	refunded    float64
+	authCode    string
+	declineCode string
+	awaiting3DS bool
+	refunds     []RefundResponse
+	createdAt   time.Time
+	updatedAt   time.Time
+	events      []PaymentEvent
+}
+
+// NewSandboxProvider creates a new sandbox gateway
+func NewSandboxProvider(config SandboxConfig) *SandboxProvider {
+	if config.WebhookDelay <= 0 {
+		config.WebhookDelay = 100 * time.Millisecond
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+	return &SandboxProvider{
+		config:   config,
+		payments: make(map[string]*sandboxPayment),
+		tokens:   make(map[string]CardDetails),
+	}
+}
+
+// Name identifies the sandbox in stored payment records
+func (p *SandboxProvider) Name() string {
+	return "sandbox"
+}
+
+// Close waits for the pending webhooks to be delivered
+func (p *SandboxProvider) Close() error {
+	p.webhooks.Wait()
+	return nil
+}
+
+// CreatePayment authorizes and, unless the request is AuthorizeOnly,
+// captures a payment. Declines come back as failed responses.
+func (p *SandboxProvider) CreatePayment(ctx context.Context, payment *PaymentRequest) (*PaymentResponse, error) {
+	return p.createPayment(payment, payment.Enable3DSecure || p.config.Force3DSecure)
+}
+
+// Initialize3DSecure creates a payment that waits for a 3-D Secure
+// challenge whatever the card
+func (p *SandboxProvider) Initialize3DSecure(ctx context.Context, payment *PaymentRequest) (*ThreeDSecureResponse, error) {
+	response, err := p.createPayment(payment, true)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Requires3DSecure() {
+		return nil, gatewayError(p.Name(), ErrorCode3DSecureFailed, "payment was rejected before the 3-D Secure challenge")
+	}
+	return &ThreeDSecureResponse{
+		ID:          response.ID,
+		Status:      "pending",
+		RedirectURL: response.Metadata[Metadata3DSecureURL].(string),
+		HTMLContent: response.Metadata[Metadata3DSecureHTML].(string),
+		Method:      "redirect",
+	}, nil
+}
+
+// Verify3DSecure completes the challenge of a pending payment. The
+// challenge fails when verificationData["result"] is Sandbox3DSecureFailed.
+func (p *SandboxProvider) Verify3DSecure(ctx context.Context, paymentID string, verificationData map[string]string) (*PaymentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, err := p.findPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if !pay.awaiting3DS {
+		return nil, gatewayError(p.Name(), "INVALID_STATE", "payment is not waiting for a 3-D Secure challenge")
+	}
+
+	pay.awaiting3DS = false
+	if verificationData["result"] == Sandbox3DSecureFailed {
+		p.fail(pay, ErrorCode3DSecureFailed)
+	} else {
+		p.authorize(pay)
+	}
+	return p.response(pay), nil
+}
+
+// CapturePayment captures an authorized payment. A zero amount captures
+// the whole authorization.
+func (p *SandboxProvider) CapturePayment(ctx context.Context, paymentID string, amount float64) (*PaymentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, err := p.findPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if pay.status != PaymentStatusAuthorized {
+		return nil, gatewayError(p.Name(), "INVALID_STATE", fmt.Sprintf("cannot capture a %s payment", pay.status))
+	}
+	if amount <= 0 {
+		amount = pay.request.Amount
+	}
+	if amount > pay.request.Amount+0.005 {
+		return nil, gatewayError(p.Name(), ErrorCodeInvalidAmount, "capture amount exceeds the authorized amount")
+	}
+
+	pay.captured = roundAmount(amount)
+	p.setStatus(pay, PaymentStatusSucceeded, "captured")
+	return p.response(pay), nil
+}
+
+// VoidPayment releases an authorization or abandons a payment that is
+// still waiting for its 3-D Secure challenge
+func (p *SandboxProvider) VoidPayment(ctx context.Context, paymentID string) (*PaymentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, err := p.findPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if pay.status != PaymentStatusAuthorized && !pay.awaiting3DS {
+		return nil, gatewayError(p.Name(), "INVALID_STATE", fmt.Sprintf("cannot void a %s payment", pay.status))
+	}
+
+	pay.awaiting3DS = false
+	p.setStatus(pay, PaymentStatusCanceled, "voided")
+	return p.response(pay), nil
+}
+
+// RefundPayment refunds part or all of a captured payment. A zero amount
+// refunds whatever has not been refunded yet.
+func (p *SandboxProvider) RefundPayment(ctx context.Context, paymentID string, amount float64) (*RefundResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, err := p.findPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if pay.status != PaymentStatusSucceeded && pay.status != PaymentStatusPartiallyRefunded {
+		return nil, gatewayError(p.Name(), "INVALID_STATE", fmt.Sprintf("cannot refund a %s payment", pay.status))
+	}
+	remaining := roundAmount(pay.captured - pay.refunded)
+	if amount <= 0 {
+		amount = remaining
+	}
+	if amount > remaining+0.005 {
+		return nil, gatewayError(p.Name(), ErrorCodeInvalidAmount, "refund amount exceeds the refundable amount")
+	}
+
+	pay.refunded = roundAmount(pay.refunded + amount)
+	status := PaymentStatusPartiallyRefunded
+	if pay.refunded >= pay.captured-0.005 {
+		status = PaymentStatusRefunded
+	}
+	p.setStatus(pay, status, fmt.Sprintf("refunded %.2f", amount))
+
+	now := time.Now()
+	refund := RefundResponse{
+		ID:          p.nextID("sbx_ref"),
+		PaymentID:   pay.id,
+		Amount:      roundAmount(amount),
+		Currency:    pay.request.Currency,
+		Status:      "completed",
+		CreatedAt:   now,
+		ProcessedAt: now,
+	}
+	pay.refunds = append(pay.refunds, refund)
+	return &refund, nil
+}
+
+// GetPaymentStatus returns the current status and history of a payment
+func (p *SandboxProvider) GetPaymentStatus(ctx context.Context, paymentID string) (*PaymentStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pay, err := p.findPayment(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentStatus{
+		ID:             pay.id,
+		Status:         pay.status,
+		Amount:         pay.request.Amount,
+		RefundedAmount: pay.refunded,
+		UpdatedAt:      pay.updatedAt,
+		Events:         append([]PaymentEvent(nil), pay.events...),
+	}, nil
+}
+
+// ListTransactions lists the payments and refunds of the filter's date
+// range, oldest first
+func (p *SandboxProvider) ListTransactions(ctx context.Context, filters TransactionFilters) ([]*Transaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var transactions []*Transaction
+	for _, pay := range p.payments {
+		if pay.status != PaymentStatusPending && pay.status != PaymentStatusProcessing {
+			transactions = append(transactions, &Transaction{
+				ID:                pay.id,
+				Type:              TransactionTypePayment,
+				Status:            pay.status,
+				Amount:            pay.request.Amount,
+				Currency:          pay.request.Currency,
+				PaymentMethodType: string(PaymentMethodTypeCard),
+				CustomerID:        pay.request.CustomerID,
+				OrderID:           pay.request.OrderID,
+				Description:       pay.request.Description,
+				CreatedAt:         pay.createdAt,
+				ProcessedAt:       pay.updatedAt,
+				NetAmount:         pay.captured,
+			})
+		}
+		for _, refund := range pay.refunds {
+			transactions = append(transactions, &Transaction{
+				ID:          refund.ID,
+				Type:        TransactionTypeRefund,
+				PaymentID:   pay.id,
+				Status:      PaymentStatusSucceeded,
+				Amount:      refund.Amount,
+				Currency:    refund.Currency,
+				CustomerID:  pay.request.CustomerID,
+				OrderID:     pay.request.OrderID,
+				CreatedAt:   refund.CreatedAt,
+				ProcessedAt: refund.ProcessedAt,
+			})
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].ID < transactions[j].ID
+		}
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+
+	filtered := transactions[:0]
+	for _, transaction := range transactions {
+		switch {
+		case !filters.StartDate.IsZero() && transaction.CreatedAt.Before(filters.StartDate),
+			!filters.EndDate.IsZero() && transaction.CreatedAt.After(filters.EndDate),
+			filters.Status != "" && transaction.Status != filters.Status,
+			filters.Currency != "" && transaction.Currency != filters.Currency,
+			filters.CustomerID != "" && transaction.CustomerID != filters.CustomerID:
+			continue
+		}
+		filtered = append(filtered, transaction)
+	}
+
+	if filters.Offset > 0 {
+		if filters.Offset >= len(filtered) {
+			return nil, nil
+		}
+		filtered = filtered[filters.Offset:]
+	}
+	if filters.Limit > 0 && len(filtered) > filters.Limit {
+		filtered = filtered[:filters.Limit]
+	}
+	return filtered, nil
+}
+
+// TokenizeCard stores a card so it can be charged by token
+func (p *SandboxProvider) TokenizeCard(ctx context.Context, card *CardDetails) (*CardToken, error) {
+	if card == nil || !luhnValid(card.Number) {
+		return nil, gatewayError(p.Name(), ErrorCodeInvalidCard, "card number is invalid")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID("sbx_tok")
+	p.tokens[id] = *card
+	return &CardToken{
+		ID:         id,
+		Last4:      card.Number[len(card.Number)-4:],
+		Brand:      cardBrand(card.Number),
+		ExpMonth:   card.ExpMonth,
+		ExpYear:    card.ExpYear,
+		HolderName: card.HolderName,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// DeleteToken removes a stored card
+func (p *SandboxProvider) DeleteToken(ctx context.Context, tokenID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.tokens[tokenID]; !ok {
+		return gatewayError(p.Name(), "TOKEN_NOT_FOUND", "card token not found")
+	}
+	delete(p.tokens, tokenID)
+	return nil
+}
+
+// VerifyWebhook checks the signature of a webhook sent by the sandbox
+func (p *SandboxProvider) VerifyWebhook(payload *WebhookPayload) error {
+	expected := p.sign(payload)
+	if !hmac.Equal([]byte(expected), []byte(payload.Signature)) {
+		return gatewayError(p.Name(), "INVALID_SIGNATURE", "webhook signature does not match")
+	}
+	return nil
+}
+
+// createPayment registers a payment and runs it as far as it goes without
+// outside input
+func (p *SandboxProvider) createPayment(request *PaymentRequest, challenge bool) (*PaymentResponse, error) {
+	if request.Amount <= 0 {
+		return nil, gatewayError(p.Name(), ErrorCodeInvalidAmount, "payment amount must be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	card, err := p.resolveCard(request.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pay := &sandboxPayment{
+		id:        p.nextID("sbx_pay"),
+		request:   *request,
+		card:      card,
+		outcome:   sandboxCards[card.Number],
+		status:    PaymentStatusPending,
+		createdAt: now,
+		updatedAt: now,
+	}
+	if pay.request.Currency == "" {
+		pay.request.Currency = "TRY"
+	}
+	p.payments[pay.id] = pay
+
+	switch {
+	case !luhnValid(card.Number):
+		p.fail(pay, ErrorCodeInvalidCard)
+	case challenge || pay.outcome.challenge:
+		pay.awaiting3DS = true
+		pay.events = append(pay.events, PaymentEvent{Type: "3d_secure_required", Timestamp: now})
+	default:
+		p.authorize(pay)
+	}
+	return p.response(pay), nil
+}
+
+// resolveCard returns the card a payment method charges
+func (p *SandboxProvider) resolveCard(method PaymentMethod) (CardDetails, error) {
+	if method.Token != "" {
+		card, ok := p.tokens[method.Token]
+		if !ok {
+			return CardDetails{}, gatewayError(p.Name(), "TOKEN_NOT_FOUND", "card token not found")
+		}
+		return card, nil
+	}
+	if method.Card == nil {
+		return CardDetails{}, gatewayError(p.Name(), ErrorCodeInvalidCard, "payment method has no card")
+	}
+	return *method.Card, nil
+}
+
+// authorize applies the card outcome to a payment. Asynchronous cards stay
+// processing until their webhook settles them.
+func (p *SandboxProvider) authorize(pay *sandboxPayment) {
+	switch {
+	case pay.outcome.async:
+		p.setStatus(pay, PaymentStatusProcessing, "awaiting bank confirmation")
+		p.webhooks.Add(1)
+		time.AfterFunc(p.config.WebhookDelay, func() {
+			defer p.webhooks.Done()
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if pay.status != PaymentStatusProcessing {
+				return
+			}
+			if pay.outcome.declineCode != "" {
+				p.fail(pay, pay.outcome.declineCode)
+			} else {
+				p.approve(pay)
+			}
+		})
+	case pay.outcome.declineCode != "":
+		p.fail(pay, pay.outcome.declineCode)
+	default:
+		p.approve(pay)
+	}
+}
+
+// approve authorizes or captures an approved payment
+func (p *SandboxProvider) approve(pay *sandboxPayment) {
+	pay.authCode = fmt.Sprintf("%06d", p.seq%1000000)
+	if pay.request.AuthorizeOnly {
+		p.setStatus(pay, PaymentStatusAuthorized, "authorized")
+		return
+	}
+	pay.captured = pay.request.Amount
+	p.setStatus(pay, PaymentStatusSucceeded, "captured")
+}
+
+// fail declines a payment
+func (p *SandboxProvider) fail(pay *sandboxPayment, code string) {
+	pay.declineCode = code
+	p.setStatus(pay, PaymentStatusFailed, code)
+}
+
+// setStatus records a status change and schedules its webhook
+func (p *SandboxProvider) setStatus(pay *sandboxPayment, status PaymentStatusType, message string) {
+	now := time.Now()
+	pay.status = status
+	pay.updatedAt = now
+	pay.events = append(pay.events, PaymentEvent{Type: string(status), Timestamp: now, Message: message})
+
+	eventType := webhookEventType(status)
+	if eventType == "" || p.config.WebhookHandler == nil {
+		return
+	}
+	payload := &WebhookPayload{
+		ID:      p.nextID("sbx_evt"),
+		Type:    eventType,
+		Created: now,
+		Data: map[string]interface{}{
+			"payment_id":      pay.id,
+			"order_id":        pay.request.OrderID,
+			"status":          string(status),
+			"amount":          pay.request.Amount,
+			"captured_amount": pay.captured,
+			"refunded_amount": pay.refunded,
+			"currency":        pay.request.Currency,
+			"failure_code":    pay.declineCode,
+		},
+	}
+	payload.Signature = p.sign(payload)
+
+	p.webhooks.Add(1)
+	time.AfterFunc(p.config.WebhookDelay, func() {
+		defer p.webhooks.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.config.Logger.Printf("Sandbox webhook handler panicked on %s: %v", payload.ID, r)
+			}
+		}()
+		p.config.WebhookHandler(payload)
+	})
+}
+
+// response builds the gateway response of a payment
+func (p *SandboxProvider) response(pay *sandboxPayment) *PaymentResponse {
+	response := &PaymentResponse{
+		ID:              pay.id,
+		Status:          pay.status,
+		Amount:          pay.request.Amount,
+		Currency:        pay.request.Currency,
+		PaymentMethod:   pay.request.PaymentMethod,
+		TransactionID:   pay.id,
+		AuthCode:        pay.authCode,
+		ReferenceNumber: pay.request.OrderID,
+		CreatedAt:       pay.createdAt,
+		Metadata:        map[string]interface{}{"card_last4": last4(pay.card.Number)},
+	}
+	if pay.status != PaymentStatusPending && pay.status != PaymentStatusProcessing {
+		response.ProcessedAt = pay.updatedAt
+	}
+	if pay.awaiting3DS {
+		response.Metadata[MetadataRequires3DSecure] = true
+		response.Metadata[Metadata3DSecureURL] = "/payment/sandbox/3ds/" + pay.id
+		response.Metadata[Metadata3DSecureHTML] = fmt.Sprintf(
+			`<form method="post" action="/payment/sandbox/3ds/%s"><button name="result" value="passed">Approve</button><button name="result" value="%s">Fail</button></form>`,
+			pay.id, Sandbox3DSecureFailed)
+	}
+	if pay.status == PaymentStatusFailed {
+		response.Error = &PaymentError{
+			Code:    pay.declineCode,
+			Message: "payment declined: " + pay.declineCode,
+			Type:    "card_error",
+		}
+	}
+	return response
+}
+
+// findPayment returns a payment by ID
+func (p *SandboxProvider) findPayment(paymentID string) (*sandboxPayment, error) {
+	pay, ok := p.payments[paymentID]
+	if !ok {
+		return nil, gatewayError(p.Name(), "PAYMENT_NOT_FOUND", "payment not found")
+	}
+	return pay, nil
+}
+
+// nextID generates a sandbox identifier with the given prefix
+func (p *SandboxProvider) nextID(prefix string) string {
+	p.seq++
+	return fmt.Sprintf("%s_%06d", prefix, p.seq)
+}
+
+// sign computes the signature of a webhook payload
+func (p *SandboxProvider) sign(payload *WebhookPayload) string {
+	paymentID, _ := payload.Data["payment_id"].(string)
+	status, _ := payload.Data["status"].(string)
+	mac := hmac.New(sha256.New, []byte(p.config.WebhookSecret))
+	mac.Write([]byte(payload.ID + "." + payload.Type + "." + paymentID + "." + status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEventType maps a payment status to the webhook announcing it
+func webhookEventType(status PaymentStatusType) string {
+	switch status {
+	case PaymentStatusSucceeded:
+		return WebhookPaymentSucceeded
+	case PaymentStatusFailed:
+		return WebhookPaymentFailed
+	case PaymentStatusAuthorized:
+		return WebhookPaymentAuthorized
+	case PaymentStatusCanceled:
+		return WebhookPaymentCanceled
+	case PaymentStatusRefunded, PaymentStatusPartiallyRefunded:
+		return WebhookPaymentRefunded
+	}
+	return ""
+}
+
+// luhnValid checks the Luhn checksum of a card number
+func luhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// cardBrand guesses the card scheme from the leading digits
+func cardBrand(number string) string {
+	switch {
+	case len(number) == 0:
+		return "unknown"
+	case number[0] == '4':
+		return "visa"
+	case number[0] == '5' || number[0] == '2':
+		return "mastercard"
+	case number[0] == '3':
+		return "amex"
+	case number[0] == '9':
+		return "troy"
+	}
+	return "unknown"
+}
+
+// last4 returns the last four digits of a card number
+func last4(number string) string {
+	if len(number) < 4 {
+		return number
+	}
+	return number[len(number)-4:]
+}
+
+// roundAmount rounds an amount to cents
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
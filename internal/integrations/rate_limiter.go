@@ -0,0 +1,294 @@
+package integrations
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Endpoint classes. Each class of an integration has its own token bucket,
+// so e.g. a bulk product sync does not starve order imports.
+const (
+	EndpointDefault   = "default"
+	EndpointProducts  = "products"
+	EndpointOrders    = "orders"
+	EndpointInventory = "inventory"
+)
+
+// DefaultRateLimiter is shared by all providers, so providers created for
+// the same integration draw from the same buckets
+var DefaultRateLimiter = NewRateLimiter()
+
+// RateLimiter throttles outgoing integration requests with a token bucket
+// per integration and endpoint class. Buckets start at the rate the
+// provider declares and adapt to the limits its responses report: the
+// remaining requests are spread until the limit resets, and Retry-After
+// holds all requests of the bucket back.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+// RateLimiterState is the state of one bucket of an integration
+type RateLimiterState struct {
+	Class             string    `json:"class"`
+	RequestsPerSecond float64   `json:"requests_per_second"`
+	Burst             int       `json:"burst"`
+	Tokens            float64   `json:"tokens"`
+	BlockedUntil      time.Time `json:"blocked_until,omitempty"`
+	Waiting           int       `json:"waiting"`
+	// Throttled counts the requests that had to wait
+	Throttled int64 `json:"throttled"`
+}
+
+// rateBucket is the token bucket of an integration's endpoint class
+type rateBucket struct {
+	integration string
+	class       string
+	rate        float64 // declared requests per second
+	burst       float64
+	tokens      float64
+	last        time.Time
+	// pace limits the rate to spread the remaining requests until
+	// paceUntil, when the marketplace's limit resets
+	pace         float64
+	paceUntil    time.Time
+	blockedUntil time.Time
+	waiting      int
+	throttled    int64
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// Wait blocks until a request to the endpoint class may be sent, or the
+// context is done. limit is the provider's current rate limit; it sets
+// the rate of a bucket the first time the class is used.
+func (l *RateLimiter) Wait(ctx context.Context, integration, class string, limit RateLimitInfo) error {
+	queued := false
+	defer func() {
+		if queued {
+			l.mu.Lock()
+			l.bucket(integration, class, limit).waiting--
+			l.mu.Unlock()
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		b := l.bucket(integration, class, limit)
+		delay := b.take(time.Now())
+		if delay > 0 && !queued {
+			queued = true
+			b.waiting++
+			b.throttled++
+		}
+		l.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Observe adapts the bucket of an endpoint class to a response: limit is
+// the provider's rate limit after it read the response headers. A 429 or
+// 503 holds the bucket back for its Retry-After, or empties it when the
+// response names no delay.
+func (l *RateLimiter) Observe(integration, class string, resp *http.Response, limit RateLimitInfo) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(integration, class, limit)
+	b.refill(now)
+	if rate := limitRate(limit); rate > 0 {
+		b.rate = rate
+		b.burst = limitBurst(limit, rate)
+		b.tokens = math.Min(b.tokens, b.burst)
+	}
+	if limit.ResetsAt.After(now) {
+		if limit.RequestsRemaining <= 0 {
+			b.block(limit.ResetsAt)
+		} else {
+			b.tokens = math.Min(b.tokens, float64(limit.RequestsRemaining))
+			b.pace = float64(limit.RequestsRemaining) / limit.ResetsAt.Sub(now).Seconds()
+			b.paceUntil = limit.ResetsAt
+		}
+	}
+
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return
+	}
+	if delay, ok := RetryAfter(resp.Header, now); ok {
+		b.block(now.Add(delay))
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		b.tokens = 0
+	}
+}
+
+// State returns the state of an integration's buckets, ordered by class
+func (l *RateLimiter) State(integration string) []RateLimiterState {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	states := make([]RateLimiterState, 0)
+	for _, b := range l.buckets {
+		if b.integration != integration {
+			continue
+		}
+		b.refill(now)
+		state := RateLimiterState{
+			Class:             b.class,
+			RequestsPerSecond: b.currentRate(now),
+			Burst:             int(b.burst),
+			Tokens:            math.Floor(b.tokens*100) / 100,
+			Waiting:           b.waiting,
+			Throttled:         b.throttled,
+		}
+		if b.blockedUntil.After(now) {
+			state.BlockedUntil = b.blockedUntil
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Class < states[j].Class })
+	return states
+}
+
+// Reset drops an integration's buckets, e.g. after its limits changed
+func (l *RateLimiter) Reset(integration string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.integration == integration {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RetryAfter parses the Retry-After header, given in seconds or as an
+// HTTP date
+func RetryAfter(headers http.Header, now time.Time) (time.Duration, bool) {
+	value := headers.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// bucket returns the bucket of an endpoint class, creating it full at the
+// provider's declared rate
+func (l *RateLimiter) bucket(integration, class string, limit RateLimitInfo) *rateBucket {
+	if class == "" {
+		class = EndpointDefault
+	}
+	key := integration + "/" + class
+	b, exists := l.buckets[key]
+	if !exists {
+		rate := limitRate(limit)
+		if rate <= 0 {
+			rate = 1
+		}
+		burst := limitBurst(limit, rate)
+		b = &rateBucket{
+			integration: integration,
+			class:       class,
+			rate:        rate,
+			burst:       burst,
+			tokens:      burst,
+			last:        time.Now(),
+		}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// take takes a token, or returns how long to wait for one
+func (b *rateBucket) take(now time.Time) time.Duration {
+	if b.blockedUntil.After(now) {
+		return b.blockedUntil.Sub(now)
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.currentRate(now) * float64(time.Second))
+}
+
+// refill adds the tokens earned since the last refill
+func (b *rateBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.currentRate(now))
+	}
+	b.last = now
+}
+
+// currentRate is the declared rate, lowered while the remaining requests
+// are spread until the limit resets
+func (b *rateBucket) currentRate(now time.Time) float64 {
+	if b.paceUntil.After(now) && b.pace > 0 && b.pace < b.rate {
+		return b.pace
+	}
+	return b.rate
+}
+
+// block holds all requests back until the given time
+func (b *rateBucket) block(until time.Time) {
+	if until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+	b.tokens = 0
+	b.last = until
+}
+
+// limitRate returns the requests per second a rate limit allows
+func limitRate(limit RateLimitInfo) float64 {
+	if limit.RequestsPerSecond > 0 {
+		return float64(limit.RequestsPerSecond)
+	}
+	if limit.RequestsPerMinute > 0 {
+		return float64(limit.RequestsPerMinute) / 60
+	}
+	return 0
+}
+
+// limitBurst returns the burst of a rate limit, ten seconds of requests
+// when it declares none
+func limitBurst(limit RateLimitInfo, rate float64) float64 {
+	if limit.BurstSize > 0 {
+		return float64(limit.BurstSize)
+	}
+	return math.Max(1, math.Floor(rate*10))
+}
@@ -0,0 +1,118 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterQueuesBeyondBurst(t *testing.T) {
+	limiter := NewRateLimiter()
+	limit := RateLimitInfo{RequestsPerSecond: 50, BurstSize: 2}
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, "n11", EndpointOrders, limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("third request sent after %s, want it to wait for a token", elapsed)
+	}
+
+	// Other classes have their own bucket
+	if err := limiter.Wait(ctx, "n11", EndpointProducts, limit); err != nil {
+		t.Fatal(err)
+	}
+
+	state := limiter.State("n11")
+	if len(state) != 2 || state[0].Class != EndpointOrders || state[1].Class != EndpointProducts {
+		t.Fatalf("got %+v", state)
+	}
+	if state[0].Throttled != 1 || state[0].Waiting != 0 || state[1].Throttled != 0 {
+		t.Errorf("got %+v", state)
+	}
+}
+
+func TestRateLimiterHonorsRetryAfter(t *testing.T) {
+	limiter := NewRateLimiter()
+	limit := RateLimitInfo{RequestsPerMinute: 600}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}
+	limiter.Observe("trendyol", EndpointProducts, resp, limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "trendyol", EndpointProducts, limit); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context's error", err)
+	}
+
+	state := limiter.State("trendyol")
+	if len(state) != 1 {
+		t.Fatalf("got %+v", state)
+	}
+	if wait := time.Until(state[0].BlockedUntil); wait < 29*time.Second || wait > 30*time.Second {
+		t.Errorf("blocked for %s, want 30s", wait)
+	}
+	if state[0].Waiting != 0 || state[0].Throttled != 1 {
+		t.Errorf("got %+v", state[0])
+	}
+
+	limiter.Reset("trendyol")
+	if err := limiter.Wait(context.Background(), "trendyol", EndpointProducts, limit); err != nil {
+		t.Errorf("after reset: %v", err)
+	}
+}
+
+func TestRateLimiterAdaptsToRemainingRequests(t *testing.T) {
+	limiter := NewRateLimiter()
+	ok := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+	// 5 requests left for the next 10 seconds are spread over them
+	limiter.Observe("hepsiburada", EndpointInventory, ok, RateLimitInfo{
+		RequestsPerMinute: 600,
+		RequestsRemaining: 5,
+		ResetsAt:          time.Now().Add(10 * time.Second),
+	})
+	state := limiter.State("hepsiburada")[0]
+	if state.RequestsPerSecond < 0.45 || state.RequestsPerSecond > 0.55 {
+		t.Errorf("got %.2f requests per second, want 0.5", state.RequestsPerSecond)
+	}
+	if state.Tokens > 5 {
+		t.Errorf("got %.2f tokens, want at most the 5 remaining", state.Tokens)
+	}
+
+	// None left holds requests back until the limit resets
+	resetsAt := time.Now().Add(time.Hour)
+	limiter.Observe("hepsiburada", EndpointInventory, ok, RateLimitInfo{
+		RequestsPerMinute: 600,
+		ResetsAt:          resetsAt,
+	})
+	if state := limiter.State("hepsiburada")[0]; !state.BlockedUntil.Equal(resetsAt) {
+		t.Errorf("blocked until %s, want %s", state.BlockedUntil, resetsAt)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"Fri, 16 Oct 2026 12:00:45 GMT", 45 * time.Second, true},
+		{"Fri, 16 Oct 2026 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := RetryAfter(http.Header{"Retry-After": {tt.value}}, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q: got %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/marketplace"
+)
+
+var (
+	// ErrIntegrationNotFound is returned for an unknown integration ID
+	ErrIntegrationNotFound = errors.New("integration not found")
+	// ErrIntegrationUnavailable is returned for stub definitions without a
+	// provider, which cannot be enabled
+	ErrIntegrationUnavailable = errors.New("integration has no provider")
+	// ErrMissingCredentials is returned when required credential fields
+	// are not set
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrCapabilityNotSupported is returned when an integration is called
+	// for a capability it does not declare
+	ErrCapabilityNotSupported = errors.New("capability not supported")
+	// ErrNoCredentialManager is returned when the registry has no credential
+	// manager to read or store credentials with
+	ErrNoCredentialManager = errors.New("no credential manager")
+)
+
+// Integration capabilities gated by the registry
+const (
+	CapabilityProductSync     = "product_sync"
+	CapabilityOrderSync       = "order_sync"
+	CapabilityInventorySync   = "inventory_sync"
+	CapabilityPriceSync       = "price_sync"
+	CapabilityCategoryMapping = "category_mapping"
+)
+
+// ProviderFactory creates an uninitialized provider
+type ProviderFactory func() (integrations.IntegrationProvider, error)
+
+// CredentialField describes a credential an integration takes. Fields named
+// after integrations.Credentials fields (api_key, client_id, ...) are set on
+// them; the others go to Credentials.Extra and are also passed to the
+// provider as config, e.g. the seller account IDs.
+type CredentialField struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
+	// Secret fields are never shown back once stored
+	Secret bool `json:"secret"`
+}
+
+var marketplaceCapabilities = []string{
+	CapabilityProductSync,
+	CapabilityOrderSync,
+	CapabilityInventorySync,
+	CapabilityPriceSync,
+	CapabilityCategoryMapping,
+}
+
+var (
+	trendyolCredentials = []CredentialField{
+		{Name: "api_key", Label: "API Key", Required: true},
+		{Name: "api_secret", Label: "API Secret", Required: true, Secret: true},
+		{Name: "supplier_id", Label: "Supplier ID", Required: true},
+	}
+	hepsiburadaCredentials = []CredentialField{
+		{Name: "api_key", Label: "API Key", Required: true},
+		{Name: "api_secret", Label: "API Secret", Required: true, Secret: true},
+		{Name: "merchant_id", Label: "Merchant ID", Required: true},
+	}
+	n11Credentials = []CredentialField{
+		{Name: "api_key", Label: "API Key", Required: true},
+		{Name: "api_secret", Label: "API Secret", Required: true, Secret: true},
+	}
+	amazonCredentials = []CredentialField{
+		{Name: "client_id", Label: "LWA Client ID", Required: true},
+		{Name: "client_secret", Label: "LWA Client Secret", Required: true, Secret: true},
+		{Name: "refresh_token", Label: "Refresh Token", Required: true, Secret: true},
+		{Name: "access_key_id", Label: "AWS Access Key ID", Required: true},
+		{Name: "secret_access_key", Label: "AWS Secret Access Key", Required: true, Secret: true},
+		{Name: "seller_id", Label: "Seller ID", Required: true},
+	}
+	cicekSepetiCredentials = []CredentialField{
+		{Name: "api_key", Label: "API Key", Required: true, Secret: true},
+	}
+)
+
+// marketplaceProvider returns the factory of a marketplace provider
+func marketplaceProvider(integrationID string) ProviderFactory {
+	return func() (integrations.IntegrationProvider, error) {
+		provider, err := marketplace.NewProvider(integrationID)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	}
+}
+
+// Supports reports whether an integration declares a capability
+func (r *IntegrationRegistry) Supports(integrationID, capability string) bool {
+	def, exists := r.GetIntegration(integrationID)
+	if !exists {
+		return false
+	}
+	for _, c := range def.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCredentials validates credential values against the integration's
+// schema and stores them. Values of fields the schema does not list are
+// ignored. An enabled provider keeps its old credentials until the
+// integration is enabled again.
+func (r *IntegrationRegistry) SetCredentials(integrationID string, values map[string]string) error {
+	def, err := r.providedIntegration(integrationID)
+	if err != nil {
+		return err
+	}
+	if r.credentialManager == nil {
+		return ErrNoCredentialManager
+	}
+
+	creds := integrations.Credentials{Extra: make(map[string]string)}
+	var missing []string
+	for _, field := range def.Credentials {
+		value := strings.TrimSpace(values[field.Name])
+		if value == "" {
+			if field.Required {
+				missing = append(missing, field.Name)
+			}
+			continue
+		}
+		setCredential(&creds, field.Name, value)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w for %s: %s", ErrMissingCredentials, integrationID, strings.Join(missing, ", "))
+	}
+
+	if err := r.credentialManager.SetCredentials(integrationID, &creds); err != nil {
+		return fmt.Errorf("failed to store credentials of %s: %w", integrationID, err)
+	}
+	// A failed check of the old credentials says nothing about the new ones
+	r.mutex.Lock()
+	def.HealthError = ""
+	r.mutex.Unlock()
+	r.refreshAvailability(integrationID)
+	return nil
+}
+
+// Configured reports whether an integration can be enabled, i.e. has a
+// provider and stored credentials
+func (r *IntegrationRegistry) Configured(integrationID string) bool {
+	if _, err := r.providedIntegration(integrationID); err != nil || r.credentialManager == nil {
+		return false
+	}
+	_, err := r.credentialManager.GetCredentials(integrationID)
+	return err == nil
+}
+
+// Enable instantiates an integration's provider with its stored
+// credentials and initializes it, replacing the provider it had
+func (r *IntegrationRegistry) Enable(ctx context.Context, integrationID string) (integrations.IntegrationProvider, error) {
+	def, err := r.providedIntegration(integrationID)
+	if err != nil {
+		return nil, err
+	}
+	if r.credentialManager == nil {
+		return nil, ErrNoCredentialManager
+	}
+
+	creds, err := r.credentialManager.GetCredentials(integrationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrMissingCredentials, integrationID, err)
+	}
+	var missing []string
+	for _, field := range def.Credentials {
+		if field.Required && credentialValue(creds, field.Name) == "" {
+			missing = append(missing, field.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w for %s: %s", ErrMissingCredentials, integrationID, strings.Join(missing, ", "))
+	}
+
+	config := make(map[string]interface{}, len(def.Config)+len(creds.Extra))
+	for key, value := range def.Config {
+		config[key] = value
+	}
+	for key, value := range creds.Extra {
+		config[key] = value
+	}
+
+	provider, err := def.Factory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", integrationID, err)
+	}
+	if err := provider.Initialize(ctx, *creds, config); err != nil {
+		r.RecordHealth(integrationID, err)
+		return nil, fmt.Errorf("failed to initialize %s provider: %w", integrationID, err)
+	}
+	r.RecordHealth(integrationID, nil)
+
+	r.mutex.Lock()
+	previous := r.providers[integrationID]
+	r.providers[integrationID] = provider
+	r.mutex.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	return provider, nil
+}
+
+// Disable closes an integration's provider. Its credentials are kept.
+func (r *IntegrationRegistry) Disable(integrationID string) error {
+	r.mutex.Lock()
+	provider, exists := r.providers[integrationID]
+	delete(r.providers, integrationID)
+	r.mutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+	if err := provider.Close(); err != nil {
+		return fmt.Errorf("failed to close %s provider: %w", integrationID, err)
+	}
+	return nil
+}
+
+// Provider returns the provider of an integration for calls that need the
+// given capabilities, enabling the integration if it is not enabled yet
+func (r *IntegrationRegistry) Provider(ctx context.Context, integrationID string, capabilities ...string) (integrations.IntegrationProvider, error) {
+	if _, err := r.providedIntegration(integrationID); err != nil {
+		return nil, err
+	}
+	for _, capability := range capabilities {
+		if !r.Supports(integrationID, capability) {
+			return nil, fmt.Errorf("%w: %s does not support %s", ErrCapabilityNotSupported, integrationID, capability)
+		}
+	}
+
+	if provider, exists := r.GetProvider(integrationID); exists {
+		return provider, nil
+	}
+	return r.Enable(ctx, integrationID)
+}
+
+// GetIntegrationMetrics returns the metrics of an enabled integration's
+// provider, including the state of its rate limiter buckets
+func (r *IntegrationRegistry) GetIntegrationMetrics(integrationID string) (map[string]interface{}, bool) {
+	provider, exists := r.GetProvider(integrationID)
+	if !exists {
+		return nil, false
+	}
+	if reporter, ok := provider.(interface{ GetMetrics() map[string]interface{} }); ok {
+		return reporter.GetMetrics(), true
+	}
+	return map[string]interface{}{}, true
+}
+
+// CheckHealth runs the health check of an integration's provider, enabling
+// the integration if it is not enabled yet, and records its result
+func (r *IntegrationRegistry) CheckHealth(ctx context.Context, integrationID string) error {
+	provider, err := r.Provider(ctx, integrationID)
+	if err != nil {
+		return err
+	}
+	err = provider.HealthCheck(ctx)
+	r.RecordHealth(integrationID, err)
+	return err
+}
+
+// RecordHealth records the result of a health check of an integration,
+// e.g. one run by the integration monitor. An integration whose last check
+// failed is not available until a check passes or its credentials change.
+func (r *IntegrationRegistry) RecordHealth(integrationID string, err error) {
+	def, exists := r.GetIntegration(integrationID)
+	if !exists {
+		return
+	}
+	now := time.Now()
+	r.mutex.Lock()
+	def.LastHealthCheck = &now
+	def.HealthError = ""
+	if err != nil {
+		def.HealthError = err.Error()
+	}
+	r.mutex.Unlock()
+	r.refreshAvailability(integrationID)
+}
+
+// HasProvider reports whether an integration has a provider, i.e. is not a
+// stub listed for the catalog only
+func (r *IntegrationRegistry) HasProvider(integrationID string) bool {
+	_, err := r.providedIntegration(integrationID)
+	return err == nil
+}
+
+// refreshAvailability derives whether an integration is available from its
+// stored credentials and its last health check
+func (r *IntegrationRegistry) refreshAvailability(integrationID string) {
+	def, exists := r.GetIntegration(integrationID)
+	if !exists {
+		return
+	}
+	configured := r.Configured(integrationID)
+	r.mutex.Lock()
+	def.Available = configured && def.HealthError == ""
+	r.mutex.Unlock()
+}
+
+// providedIntegration returns the definition of an integration that has a
+// provider
+func (r *IntegrationRegistry) providedIntegration(integrationID string) (*IntegrationDefinition, error) {
+	def, exists := r.GetIntegration(integrationID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrIntegrationNotFound, integrationID)
+	}
+	if def.Factory == nil {
+		return nil, fmt.Errorf("%w: %s", ErrIntegrationUnavailable, integrationID)
+	}
+	return def, nil
+}
+
+// setCredential sets a credential field by its schema name
+func setCredential(creds *integrations.Credentials, name, value string) {
+	switch name {
+	case "api_key":
+		creds.APIKey = value
+	case "api_secret":
+		creds.APISecret = value
+	case "access_token":
+		creds.AccessToken = value
+	case "refresh_token":
+		creds.RefreshToken = value
+	case "client_id":
+		creds.ClientID = value
+	case "client_secret":
+		creds.ClientSecret = value
+	case "access_key_id":
+		creds.AccessKeyID = value
+	case "secret_access_key":
+		creds.SecretAccessKey = value
+	case "seller_id":
+		creds.SellerID = value
+	default:
+		if creds.Extra == nil {
+			creds.Extra = make(map[string]string)
+		}
+		creds.Extra[name] = value
+	}
+}
+
+// credentialValue returns a credential field by its schema name
+func credentialValue(creds *integrations.Credentials, name string) string {
+	switch name {
+	case "api_key":
+		return creds.APIKey
+	case "api_secret":
+		return creds.APISecret
+	case "access_token":
+		return creds.AccessToken
+	case "refresh_token":
+		return creds.RefreshToken
+	case "client_id":
+		return creds.ClientID
+	case "client_secret":
+		return creds.ClientSecret
+	case "access_key_id":
+		return creds.AccessKeyID
+	case "secret_access_key":
+		return creds.SecretAccessKey
+	case "seller_id":
+		return creds.SellerID
+	default:
+		return creds.Extra[name]
+	}
+}
@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kolajAi/internal/integrations/credentials"
+	"kolajAi/internal/integrations/marketplace"
+)
+
+func newTestRegistry(t *testing.T) *IntegrationRegistry {
+	t.Helper()
+	key, err := credentials.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := credentials.NewManager(key, credentials.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewIntegrationRegistry(manager)
+}
+
+func TestStubIntegrationsUnavailable(t *testing.T) {
+	r := newTestRegistry(t)
+
+	provided := map[string]bool{}
+	for _, def := range r.ListIntegrations() {
+		if def.Available {
+			t.Errorf("%s is available without credentials", def.ID)
+		}
+		if r.HasProvider(def.ID) {
+			provided[def.ID] = true
+		} else if def.IsActive || def.IsProduction {
+			t.Errorf("stub %s is marked active or production-ready", def.ID)
+		}
+	}
+	for _, id := range []string{"trendyol", "hepsiburada", "n11", "amazon_tr", "ciceksepeti"} {
+		if !provided[id] {
+			t.Errorf("%s has no provider", id)
+		}
+	}
+	if len(provided) != 5 {
+		t.Errorf("got %d integrations with a provider, want 5", len(provided))
+	}
+
+	if _, err := r.Enable(context.Background(), "sahibinden"); !errors.Is(err, ErrIntegrationUnavailable) {
+		t.Errorf("enabling a stub: got %v, want ErrIntegrationUnavailable", err)
+	}
+	if err := r.SetCredentials("sahibinden", map[string]string{"api_key": "key"}); !errors.Is(err, ErrIntegrationUnavailable) {
+		t.Errorf("setting stub credentials: got %v, want ErrIntegrationUnavailable", err)
+	}
+	if _, err := r.Provider(context.Background(), "unknown"); !errors.Is(err, ErrIntegrationNotFound) {
+		t.Errorf("unknown integration: got %v, want ErrIntegrationNotFound", err)
+	}
+}
+
+func TestAvailabilityFollowsCredentialsAndHealth(t *testing.T) {
+	r := newTestRegistry(t)
+	available := func() bool {
+		def, _ := r.GetIntegration("ciceksepeti")
+		return def.Available
+	}
+
+	if err := r.SetCredentials("ciceksepeti", map[string]string{"api_key": "key"}); err != nil {
+		t.Fatal(err)
+	}
+	if !available() {
+		t.Fatal("not available with credentials")
+	}
+	r.RecordHealth("ciceksepeti", errors.New("401 unauthorized"))
+	if available() {
+		t.Fatal("available after a failed health check")
+	}
+	if def, _ := r.GetIntegration("ciceksepeti"); def.LastHealthCheck == nil || def.HealthError != "401 unauthorized" {
+		t.Fatalf("health check not recorded: %+v", def)
+	}
+	r.RecordHealth("ciceksepeti", nil)
+	if !available() {
+		t.Fatal("not available after a passing health check")
+	}
+
+	// A registry over stored credentials starts out available
+	restarted := NewIntegrationRegistry(r.credentialManager)
+	if def, _ := restarted.GetIntegration("ciceksepeti"); !def.Available {
+		t.Error("stored credentials not picked up")
+	}
+	if def, _ := restarted.GetIntegration("trendyol"); def.Available {
+		t.Error("trendyol available without credentials")
+	}
+}
+
+func TestDeclaredCapabilitiesMatchProviders(t *testing.T) {
+	r := newTestRegistry(t)
+
+	for _, def := range r.ListIntegrations() {
+		if def.Factory == nil {
+			continue
+		}
+		provider, err := def.Factory()
+		if err != nil {
+			t.Fatalf("%s: %v", def.ID, err)
+		}
+		supported := map[string]bool{}
+		for _, capability := range provider.GetCapabilities() {
+			supported[capability] = true
+		}
+		for _, capability := range def.Capabilities {
+			if !supported[capability] {
+				t.Errorf("%s declares %s, which its provider does not support", def.ID, capability)
+			}
+		}
+		if _, ok := provider.(marketplace.MarketplaceProvider); def.Category == "marketplace" && !ok {
+			t.Errorf("%s provider is not a marketplace provider", def.ID)
+		}
+	}
+}
+
+func TestEnableWithStoredCredentials(t *testing.T) {
+	r := newTestRegistry(t)
+	ctx := context.Background()
+
+	if r.Configured("ciceksepeti") {
+		t.Fatal("configured without credentials")
+	}
+	if _, err := r.Provider(ctx, "ciceksepeti"); !errors.Is(err, ErrMissingCredentials) {
+		t.Fatalf("got %v, want ErrMissingCredentials", err)
+	}
+	if err := r.SetCredentials("ciceksepeti", map[string]string{"api_key": " "}); !errors.Is(err, ErrMissingCredentials) {
+		t.Fatalf("blank required field: got %v, want ErrMissingCredentials", err)
+	}
+	if err := r.SetCredentials("ciceksepeti", map[string]string{"api_key": "key"}); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Configured("ciceksepeti") {
+		t.Fatal("not configured with credentials")
+	}
+
+	provider, err := r.Provider(ctx, "ciceksepeti", CapabilityOrderSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := provider.(*marketplace.CicekSepetiProvider); !ok {
+		t.Fatalf("got %T, want *marketplace.CicekSepetiProvider", provider)
+	}
+	if again, _ := r.Provider(ctx, "ciceksepeti"); again != provider {
+		t.Error("enabled provider was not reused")
+	}
+	if _, err := r.Provider(ctx, "ciceksepeti", "payment_processing"); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("got %v, want ErrCapabilityNotSupported", err)
+	}
+
+	if err := r.Disable("ciceksepeti"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := r.GetProvider("ciceksepeti"); exists {
+		t.Error("provider kept after disabling")
+	}
+	if !r.Configured("ciceksepeti") {
+		t.Error("credentials dropped after disabling")
+	}
+}
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+	}{
+		{"", ""},
+		{"* * * *", ""},
+		{"* * * * * *", ""},
+		{"60 * * * *", ""},
+		{"* 24 * * *", ""},
+		{"* * 0 * *", ""},
+		{"* * * 13 *", ""},
+		{"* * * * 8", ""},
+		{"*/0 * * * *", ""},
+		{"*/x * * * *", ""},
+		{"30-10 * * * *", ""},
+		{"* * * foo *", ""},
+		{"@every 5m", ""},
+		{"* * * * *", "Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseCron(tt.expr, tt.timezone); err == nil {
+			t.Errorf("ParseCron(%q, %q) succeeded, want error", tt.expr, tt.timezone)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-03-04 is a Wednesday
+	from := time.Date(2026, 3, 4, 10, 17, 42, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		timezone string
+		from     time.Time
+		want     time.Time
+	}{
+		{"every minute", "* * * * *", "", from, time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"strictly after an exact match", "* * * * *", "", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC), time.Date(2026, 3, 4, 10, 19, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", "", from, time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"step from a value", "5/20 * * * *", "", from, time.Date(2026, 3, 4, 10, 25, 0, 0, time.UTC)},
+		{"range with step", "0 9-17/4 * * *", "", from, time.Date(2026, 3, 4, 13, 0, 0, 0, time.UTC)},
+		{"list", "0,45 * * * *", "", from, time.Date(2026, 3, 4, 10, 45, 0, 0, time.UTC)},
+		{"next day", "30 2 * * *", "", from, time.Date(2026, 3, 5, 2, 30, 0, 0, time.UTC)},
+		{"day of week", "0 8 * * mon", "", from, time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", "", from, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"day of week range", "0 9 * * mon-fri", "", time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 jun *", "", from, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"end of month", "0 12 31 * *", "", from, time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{"skips short months", "0 0 31 * *", "", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", "", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 15 * fri", "", from, time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"descriptor", "@daily", "", from, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"yearly descriptor", "@yearly", "", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"time zone", "0 3 * * *", "Europe/Istanbul", from, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"never fires", "0 0 30 2 *", "", from, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr, tt.timezone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := cron.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from.Format(time.RFC3339), got.UTC().Format(time.RFC3339), tt.want.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestCronNextAcrossDaylightSaving(t *testing.T) {
+	cron, err := ParseCron("30 2 * * *", "Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 does not exist on 2026-03-29 in Berlin, so the run moves to the
+	// next day that has it
+	from := time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 30, 0, 30, 0, 0, time.UTC)
+	if got := cron.Next(from); !got.Equal(want) {
+		t.Fatalf("Next = %s, want %s", got.UTC().Format(time.RFC3339), want.Format(time.RFC3339))
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // JobStatus represents the status of a job
@@ -43,6 +46,9 @@ type Job struct {
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	RetryCount  int                    `json:"retry_count"`
 	MaxRetries  int                    `json:"max_retries"`
+
+	// leaseToken identifies the lease under which a LeaseQueue handed out the job
+	leaseToken string
 }
 
 // JobHandler is a function that processes a job
@@ -60,13 +66,30 @@ type JobManager struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	logger        *log.Logger
+
+	store             LeaseQueue
+	workerID          string
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	startOnce         sync.Once
 }
 
+// unhandledJobDelay is how long a durable job of a type this process has no
+// handler for stays back in the queue before it is claimed again
+const unhandledJobDelay = time.Minute
+
 // JobManagerConfig holds configuration for job manager
 type JobManagerConfig struct {
 	Workers      int
 	MaxQueueSize int
 	Logger       *log.Logger
+
+	// Queue makes the manager durable: jobs are persisted and claimed under
+	// a lease instead of being held in the in-memory channel
+	Queue             LeaseQueue
+	WorkerID          string
+	PollInterval      time.Duration
+	VisibilityTimeout time.Duration
 }
 
 // NewJobManager creates a new job manager
@@ -80,6 +103,15 @@ func NewJobManager(config JobManagerConfig) *JobManager {
 	if config.Logger == nil {
 		config.Logger = log.Default()
 	}
+	if config.WorkerID == "" {
+		config.WorkerID = processID()
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = 5 * time.Minute
+	}
 	
 	ctx, cancel := context.WithCancel(context.Background())
 	
@@ -92,14 +124,31 @@ func NewJobManager(config JobManagerConfig) *JobManager {
 		ctx:          ctx,
 		cancel:       cancel,
 		logger:       config.Logger,
+
+		store:             config.Queue,
+		workerID:          config.WorkerID,
+		pollInterval:      config.PollInterval,
+		visibilityTimeout: config.VisibilityTimeout,
 	}
 	
-	// Start workers
-	jm.startWorkers()
-	
 	return jm
 }
 
+// Start starts the workers. It is called once the handlers are registered,
+// so that no job is claimed before the handler of its type exists. Jobs
+// whose lease expired, such as the ones a stopped process was running, are
+// returned to the queue first.
+func (jm *JobManager) Start() {
+	jm.startOnce.Do(func() {
+		if jm.store != nil {
+			if _, err := jm.store.Recover(); err != nil {
+				jm.logger.Printf("Failed to recover in-flight jobs: %v", err)
+			}
+		}
+		jm.startWorkers()
+	})
+}
+
 // RegisterHandler registers a job handler
 func (jm *JobManager) RegisterHandler(jobType string, handler JobHandler) {
 	jm.mu.Lock()
@@ -122,6 +171,14 @@ func (jm *JobManager) SubmitJob(job *Job) error {
 		job.MaxRetries = 3
 	}
 	
+	if jm.store != nil {
+		if err := jm.store.Push(job); err != nil {
+			return err
+		}
+		jm.logger.Printf("Job %s submitted successfully", job.ID)
+		return nil
+	}
+	
 	jm.mu.Lock()
 	jm.jobs[job.ID] = job
 	jm.mu.Unlock()
@@ -137,6 +194,10 @@ func (jm *JobManager) SubmitJob(job *Job) error {
 
 // GetJob returns a job by ID
 func (jm *JobManager) GetJob(id string) (*Job, error) {
+	if jm.store != nil {
+		return jm.store.Get(id)
+	}
+	
 	jm.mu.RLock()
 	defer jm.mu.RUnlock()
 	
@@ -148,8 +209,17 @@ func (jm *JobManager) GetJob(id string) (*Job, error) {
 	return job, nil
 }
 
-// GetJobsByStatus returns jobs with a specific status
+// GetJobsByStatus returns jobs with a specific status. With a durable queue
+// they are read from the queue, at most MaxQueueSize of them.
 func (jm *JobManager) GetJobsByStatus(status JobStatus) []*Job {
+	if jm.store != nil {
+		jobs, err := jm.store.List(status, jm.maxQueueSize)
+		if err != nil {
+			jm.logger.Printf("Failed to list %s jobs: %v", status, err)
+		}
+		return jobs
+	}
+	
 	jm.mu.RLock()
 	defer jm.mu.RUnlock()
 	
@@ -165,6 +235,10 @@ func (jm *JobManager) GetJobsByStatus(status JobStatus) []*Job {
 
 // CancelJob cancels a pending or running job
 func (jm *JobManager) CancelJob(id string) error {
+	if jm.store != nil {
+		return jm.store.Cancel(id)
+	}
+	
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 	
@@ -198,6 +272,11 @@ func (jm *JobManager) worker(id int) {
 	
 	jm.logger.Printf("Worker %d started", id)
 	
+	if jm.store != nil {
+		jm.leaseWorker(id)
+		return
+	}
+	
 	for {
 		select {
 		case <-jm.ctx.Done():
@@ -210,6 +289,52 @@ func (jm *JobManager) worker(id int) {
 	}
 }
 
+// leaseWorker claims jobs from the durable queue until shutdown
+func (jm *JobManager) leaseWorker(id int) {
+	for {
+		select {
+		case <-jm.ctx.Done():
+			jm.logger.Printf("Worker %d shutting down", id)
+			return
+		default:
+		}
+		
+		job, err := jm.store.Claim(jm.workerID, jm.visibilityTimeout)
+		if err != nil {
+			if err != ErrQueueEmpty {
+				jm.logger.Printf("Worker %d failed to claim job: %v", id, err)
+			}
+			select {
+			case <-jm.ctx.Done():
+				jm.logger.Printf("Worker %d shutting down", id)
+				return
+			case <-time.After(jm.pollInterval):
+			}
+			continue
+		}
+		
+		jm.processJob(job)
+	}
+}
+
+// keepLeaseAlive extends the lease of a running job until done is closed
+func (jm *JobManager) keepLeaseAlive(job *Job, done <-chan struct{}) {
+	ticker := time.NewTicker(jm.visibilityTimeout / 2)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := jm.store.Extend(job, jm.visibilityTimeout); err != nil {
+				jm.logger.Printf("Failed to extend lease of job %s: %v", job.ID, err)
+				return
+			}
+		}
+	}
+}
+
 // processJob processes a single job
 func (jm *JobManager) processJob(job *Job) {
 	jm.logger.Printf("Processing job %s (type: %s)", job.ID, job.Type)
@@ -225,7 +350,16 @@ func (jm *JobManager) processJob(job *Job) {
 	jm.mu.RUnlock()
 	
 	if !exists {
-		jm.handleJobError(job, fmt.Errorf("no handler registered for job type: %s", job.Type))
+		err := fmt.Errorf("no handler registered for job type: %s", job.Type)
+		if jm.store != nil {
+			// Another process may run this type, so the attempt is not counted
+			jm.logger.Printf("Job %s released: %v", job.ID, err)
+			if rerr := jm.store.Release(job, unhandledJobDelay); rerr != nil {
+				jm.logger.Printf("Failed to release job %s: %v", job.ID, rerr)
+			}
+			return
+		}
+		jm.handleJobError(job, err)
 		return
 	}
 	
@@ -233,6 +367,12 @@ func (jm *JobManager) processJob(job *Job) {
 	ctx, cancel := context.WithTimeout(jm.ctx, 30*time.Minute)
 	defer cancel()
 	
+	if jm.store != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go jm.keepLeaseAlive(job, done)
+	}
+	
 	// Execute job
 	err := handler(ctx, job)
 	
@@ -252,6 +392,14 @@ func (jm *JobManager) updateJobStatus(job *Job, status JobStatus) {
 
 // handleJobError handles job execution error
 func (jm *JobManager) handleJobError(job *Job, err error) {
+	if jm.store != nil {
+		jm.logger.Printf("Job %s failed (attempt %d/%d): %v", job.ID, job.RetryCount+1, job.MaxRetries, err)
+		if ferr := jm.store.Fail(job, err); ferr != nil {
+			jm.logger.Printf("Failed to record failure of job %s: %v", job.ID, ferr)
+		}
+		return
+	}
+	
 	job.Error = err.Error()
 	job.RetryCount++
 	
@@ -283,6 +431,13 @@ func (jm *JobManager) handleJobError(job *Job, err error) {
 func (jm *JobManager) handleJobSuccess(job *Job) {
 	jm.logger.Printf("Job %s completed successfully", job.ID)
 	
+	if jm.store != nil {
+		if err := jm.store.Complete(job); err != nil {
+			jm.logger.Printf("Failed to record completion of job %s: %v", job.ID, err)
+		}
+		return
+	}
+	
 	job.Status = JobStatusCompleted
 	now := time.Now()
 	job.CompletedAt = &now
@@ -321,6 +476,21 @@ func (jm *JobManager) GetStats() JobManagerStats {
 		JobsByStatus: make(map[JobStatus]int),
 	}
 	
+	if pq, ok := jm.store.(*PersistentQueue); ok {
+		byStatus, deadLetters, err := pq.Stats()
+		if err != nil {
+			jm.logger.Printf("Failed to load job queue stats: %v", err)
+			return stats
+		}
+		stats.JobsByStatus = byStatus
+		stats.DeadLetters = deadLetters
+		stats.QueueSize = byStatus[JobStatusPending] + byStatus[JobStatusRunning]
+		for _, count := range byStatus {
+			stats.TotalJobs += count
+		}
+		return stats
+	}
+	
 	for _, job := range jm.jobs {
 		stats.JobsByStatus[job.Status]++
 	}
@@ -335,6 +505,15 @@ type JobManagerStats struct {
 	Workers      int                  `json:"workers"`
 	MaxQueueSize int                  `json:"max_queue_size"`
 	JobsByStatus map[JobStatus]int    `json:"jobs_by_status"`
+	DeadLetters  int                  `json:"dead_letters"`
+}
+
+// processID identifies this process among the workers sharing a durable
+// queue. The random part keeps it unique across processes on the same host
+// and across restarts that reuse a PID, as in containers.
+func processID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.New().String()[:8])
 }
 
 // generateJobID generates a unique job ID
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kolajAi/internal/database"
+)
+
+// LeaderLock is a named lease stored in the database. Only the instance
+// holding an unexpired lease is the leader for that name, which keeps
+// several server instances from doing the same singleton work.
+type LeaderLock struct {
+	repo  database.SimpleRepository
+	name  string
+	owner string
+	ttl   time.Duration
+}
+
+// NewLeaderLock creates a leader lock
+func NewLeaderLock(repo database.SimpleRepository, name, owner string, ttl time.Duration) *LeaderLock {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &LeaderLock{repo: repo, name: name, owner: owner, ttl: ttl}
+}
+
+// Acquire takes or renews the lease. It returns true when this instance is
+// the leader until the lease expires.
+func (l *LeaderLock) Acquire() (bool, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(l.ttl)
+
+	result, err := l.repo.Exec(`
+		UPDATE job_leader_locks SET owner = ?, expires_at = ?, updated_at = ?
+		WHERE name = ? AND (owner = ? OR expires_at <= ?)`,
+		l.owner, expiresAt, now, l.name, l.owner, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leader lock %s: %w", l.name, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return true, nil
+	}
+
+	// No row updated: either the lock has never been taken or somebody else
+	// holds it. The primary key makes the insert fail in the latter case.
+	_, err = l.repo.Exec(`
+		INSERT INTO job_leader_locks (name, owner, expires_at, updated_at) VALUES (?, ?, ?, ?)`,
+		l.name, l.owner, expiresAt, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire leader lock %s: %w", l.name, err)
+	}
+
+	return true, nil
+}
+
+// Release gives up the lease if this instance holds it
+func (l *LeaderLock) Release() error {
+	_, err := l.repo.Exec(`DELETE FROM job_leader_locks WHERE name = ? AND owner = ?`, l.name, l.owner)
+	if err != nil {
+		return fmt.Errorf("failed to release leader lock %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is the unique constraint error of
+// SQLite or MySQL
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint failed") || strings.Contains(msg, "duplicate entry")
+}
@@ -0,0 +1,610 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"kolajAi/internal/database"
+)
+
+var (
+	// ErrQueueEmpty is returned when no job is available for claiming
+	ErrQueueEmpty = errors.New("job queue is empty")
+	// ErrLeaseLost is returned when a worker reports on a job whose lease has
+	// expired and been handed to another worker
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// LeaseQueue is a JobQueue that hands jobs out under a time-limited lease.
+// A job whose lease expires before it is completed becomes visible again,
+// so work claimed by a crashed worker is picked up by another one.
+type LeaseQueue interface {
+	JobQueue
+	Claim(owner string, visibility time.Duration) (*Job, error)
+	Extend(job *Job, visibility time.Duration) error
+	Complete(job *Job) error
+	Fail(job *Job, jobErr error) error
+	Release(job *Job, delay time.Duration) error
+	Cancel(id string) error
+	Get(id string) (*Job, error)
+	List(status JobStatus, limit int) ([]*Job, error)
+	Recover() (int, error)
+}
+
+// DeadLetter represents a job that exhausted its retries
+type DeadLetter struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Priority   JobPriority            `json:"priority"`
+	Payload    map[string]interface{} `json:"payload"`
+	Error      string                 `json:"error"`
+	RetryCount int                    `json:"retry_count"`
+	MaxRetries int                    `json:"max_retries"`
+	CreatedAt  time.Time              `json:"created_at"`
+	FailedAt   time.Time              `json:"failed_at"`
+}
+
+// PersistentQueueConfig holds configuration for the persistent queue
+type PersistentQueueConfig struct {
+	VisibilityTimeout time.Duration
+	RetryBackoff      time.Duration
+	ClaimBatchSize    int
+	Logger            *log.Logger
+}
+
+// PersistentQueue stores jobs in the application database so that queued
+// and in-flight work survives restarts
+type PersistentQueue struct {
+	repo              database.SimpleRepository
+	visibilityTimeout time.Duration
+	retryBackoff      time.Duration
+	claimBatchSize    int
+	logger            *log.Logger
+}
+
+// NewPersistentQueue creates a new database-backed job queue
+func NewPersistentQueue(repo database.SimpleRepository, config PersistentQueueConfig) *PersistentQueue {
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = 5 * time.Minute
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Minute
+	}
+	if config.ClaimBatchSize <= 0 {
+		config.ClaimBatchSize = 10
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+
+	return &PersistentQueue{
+		repo:              repo,
+		visibilityTimeout: config.VisibilityTimeout,
+		retryBackoff:      config.RetryBackoff,
+		claimBatchSize:    config.ClaimBatchSize,
+		logger:            config.Logger,
+	}
+}
+
+// Push adds a job to the queue
+func (pq *PersistentQueue) Push(job *Job) error {
+	if job.ID == "" {
+		job.ID = generateJobID()
+	}
+	if job.Status == "" {
+		job.Status = JobStatusPending
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	if job.MaxRetries == 0 {
+		job.MaxRetries = 3
+	}
+
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = pq.repo.Exec(`
+		INSERT INTO job_queue (id, job_type, priority, status, payload, retry_count, max_retries,
+			available_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Type, int(job.Priority), string(JobStatusPending), string(payload),
+		job.RetryCount, job.MaxRetries, now, job.CreatedAt.UTC(), now)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// Pop claims the next available job using the default visibility timeout
+func (pq *PersistentQueue) Pop() (*Job, error) {
+	return pq.Claim("", pq.visibilityTimeout)
+}
+
+// Size returns the number of pending and in-flight jobs
+func (pq *PersistentQueue) Size() int {
+	var count int
+	err := pq.repo.QueryRow(`SELECT COUNT(*) FROM job_queue WHERE status IN (?, ?)`,
+		string(JobStatusPending), string(JobStatusRunning)).Scan(&count)
+	if err != nil {
+		pq.logger.Printf("Failed to count queued jobs: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Close is a no-op; the underlying connection is owned by the caller
+func (pq *PersistentQueue) Close() error {
+	return nil
+}
+
+// Claim leases the highest priority available job to owner. Jobs whose
+// previous lease has expired are treated as available again.
+func (pq *PersistentQueue) Claim(owner string, visibility time.Duration) (*Job, error) {
+	if visibility <= 0 {
+		visibility = pq.visibilityTimeout
+	}
+
+	now := time.Now().UTC()
+	candidates, err := pq.claimCandidates(now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range candidates {
+		token := uuid.New().String()
+		result, err := pq.repo.Exec(`
+			UPDATE job_queue
+			SET status = ?, lease_owner = ?, lease_token = ?, lease_expires_at = ?, started_at = ?, updated_at = ?
+			WHERE id = ? AND ((status = ? AND available_at <= ?) OR (status = ? AND lease_expires_at <= ?))`,
+			string(JobStatusRunning), owner, token, now.Add(visibility), now, now,
+			id, string(JobStatusPending), now, string(JobStatusRunning), now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim job %s: %w", id, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim job %s: %w", id, err)
+		}
+		if affected == 0 {
+			// Another worker won the race for this job
+			continue
+		}
+
+		job, err := pq.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		job.leaseToken = token
+		return job, nil
+	}
+
+	return nil, ErrQueueEmpty
+}
+
+// claimCandidates returns the IDs of jobs that may currently be claimed
+func (pq *PersistentQueue) claimCandidates(now time.Time) ([]string, error) {
+	rows, err := pq.repo.Query(fmt.Sprintf(`
+		SELECT id FROM job_queue
+		WHERE (status = ? AND available_at <= ?) OR (status = ? AND lease_expires_at <= ?)
+		ORDER BY priority DESC, available_at ASC
+		LIMIT %d`, pq.claimBatchSize),
+		string(JobStatusPending), now, string(JobStatusRunning), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query claimable jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan claimable job: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Extend pushes the lease of a claimed job further into the future
+func (pq *PersistentQueue) Extend(job *Job, visibility time.Duration) error {
+	if visibility <= 0 {
+		visibility = pq.visibilityTimeout
+	}
+
+	now := time.Now().UTC()
+	return pq.updateLeased(job, `
+		UPDATE job_queue SET lease_expires_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND lease_token = ?`,
+		now.Add(visibility), now, job.ID, string(JobStatusRunning), job.leaseToken)
+}
+
+// Complete marks a claimed job as completed and stores its result
+func (pq *PersistentQueue) Complete(job *Job) error {
+	result, err := json.Marshal(job.Result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job result: %w", err)
+	}
+
+	now := time.Now().UTC()
+	job.Status = JobStatusCompleted
+	job.CompletedAt = &now
+
+	return pq.updateLeased(job, `
+		UPDATE job_queue
+		SET status = ?, result = ?, error = NULL, lease_owner = NULL, lease_token = NULL,
+			lease_expires_at = NULL, completed_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND lease_token = ?`,
+		string(JobStatusCompleted), string(result), now, now,
+		job.ID, string(JobStatusRunning), job.leaseToken)
+}
+
+// Fail records a failed attempt. The job is scheduled for another attempt
+// with linear backoff, or moved to the dead-letter table once it has used
+// up MaxRetries.
+func (pq *PersistentQueue) Fail(job *Job, jobErr error) error {
+	job.Error = jobErr.Error()
+	job.RetryCount++
+
+	now := time.Now().UTC()
+	if job.RetryCount < job.MaxRetries {
+		job.Status = JobStatusPending
+		availableAt := now.Add(time.Duration(job.RetryCount) * pq.retryBackoff)
+
+		return pq.updateLeased(job, `
+			UPDATE job_queue
+			SET status = ?, error = ?, retry_count = ?, lease_owner = NULL, lease_token = NULL,
+				lease_expires_at = NULL, available_at = ?, updated_at = ?
+			WHERE id = ? AND status = ? AND lease_token = ?`,
+			string(JobStatusPending), job.Error, job.RetryCount, availableAt, now,
+			job.ID, string(JobStatusRunning), job.leaseToken)
+	}
+
+	job.Status = JobStatusFailed
+	job.CompletedAt = &now
+
+	return pq.deadLetter(job, now)
+}
+
+// Release returns a claimed job to the queue without counting an attempt.
+// It can be claimed again after delay.
+func (pq *PersistentQueue) Release(job *Job, delay time.Duration) error {
+	now := time.Now().UTC()
+	job.Status = JobStatusPending
+
+	return pq.updateLeased(job, `
+		UPDATE job_queue
+		SET status = ?, lease_owner = NULL, lease_token = NULL, lease_expires_at = NULL,
+			available_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND lease_token = ?`,
+		string(JobStatusPending), now.Add(delay), now,
+		job.ID, string(JobStatusRunning), job.leaseToken)
+}
+
+// deadLetter moves a job that exhausted its retries to job_dead_letters
+func (pq *PersistentQueue) deadLetter(job *Job, now time.Time) error {
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	tx, err := pq.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM job_queue WHERE id = ? AND status = ? AND lease_token = ?`,
+		job.ID, string(JobStatusRunning), job.leaseToken)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove job %s from queue: %w", job.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		tx.Rollback()
+		return ErrLeaseLost
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO job_dead_letters (id, job_type, priority, payload, error, retry_count, max_retries, created_at, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Type, int(job.Priority), string(payload), job.Error,
+		job.RetryCount, job.MaxRetries, job.CreatedAt.UTC(), now)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to dead-letter job %s: %w", job.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dead letter: %w", err)
+	}
+
+	pq.logger.Printf("Job %s moved to dead-letter queue after %d attempts: %s", job.ID, job.RetryCount, job.Error)
+	return nil
+}
+
+// updateLeased runs an update guarded by the job's lease token
+func (pq *PersistentQueue) updateLeased(job *Job, query string, args ...interface{}) error {
+	result, err := pq.repo.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.ID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.ID, err)
+	}
+	if affected == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Cancel cancels a job that has not been claimed yet
+func (pq *PersistentQueue) Cancel(id string) error {
+	now := time.Now().UTC()
+	result, err := pq.repo.Exec(`
+		UPDATE job_queue SET status = ?, completed_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		string(JobStatusCancelled), now, now, id, string(JobStatusPending))
+	if err != nil {
+		return fmt.Errorf("failed to cancel job %s: %w", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to cancel job %s: %w", id, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("job %s cannot be cancelled", id)
+	}
+
+	return nil
+}
+
+// jobColumns are the job_queue columns scanJob reads
+const jobColumns = `id, job_type, priority, status, payload, result, error, retry_count, max_retries,
+	created_at, started_at, completed_at`
+
+// Get returns a job by ID
+func (pq *PersistentQueue) Get(id string) (*Job, error) {
+	job, err := scanJob(pq.repo.QueryRow(`SELECT `+jobColumns+` FROM job_queue WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// List returns up to limit jobs with the given status, most recent first
+func (pq *PersistentQueue) List(status JobStatus, limit int) ([]*Job, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := pq.repo.Query(fmt.Sprintf(`
+		SELECT `+jobColumns+` FROM job_queue WHERE status = ?
+		ORDER BY created_at DESC LIMIT %d`, limit), string(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s jobs: %w", status, err)
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// scanJob reads a job selected with jobColumns
+func scanJob(row database.Row) (*Job, error) {
+	var (
+		job                               Job
+		priority                          int
+		status                            string
+		payload, result, jobErr           sql.NullString
+		startedAt, completedAt, createdAt sql.NullTime
+	)
+
+	err := row.Scan(&job.ID, &job.Type, &priority, &status, &payload, &result, &jobErr,
+		&job.RetryCount, &job.MaxRetries, &createdAt, &startedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Priority = JobPriority(priority)
+	job.Status = JobStatus(status)
+	job.Error = jobErr.String
+	job.CreatedAt = createdAt.Time
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if payload.Valid && payload.String != "" {
+		if err := json.Unmarshal([]byte(payload.String), &job.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload of job %s: %w", job.ID, err)
+		}
+	}
+	if result.Valid && result.String != "" {
+		if err := json.Unmarshal([]byte(result.String), &job.Result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal result of job %s: %w", job.ID, err)
+		}
+	}
+
+	return &job, nil
+}
+
+// Recover returns jobs whose lease has expired to the pending state. Lease
+// owners are unique per process, so a restarted process cannot recognise
+// its old leases; the jobs of a stopped or crashed process come back once
+// their leases expire, and the leases of running processes are left alone.
+// It is meant to be called at startup before workers begin claiming.
+func (pq *PersistentQueue) Recover() (int, error) {
+	now := time.Now().UTC()
+	result, err := pq.repo.Exec(`
+		UPDATE job_queue
+		SET status = ?, lease_owner = NULL, lease_token = NULL, lease_expires_at = NULL,
+			available_at = ?, updated_at = ?
+		WHERE status = ? AND lease_expires_at <= ?`,
+		string(JobStatusPending), now, now, string(JobStatusRunning), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover in-flight jobs: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover in-flight jobs: %w", err)
+	}
+	if affected > 0 {
+		pq.logger.Printf("Recovered %d in-flight jobs", affected)
+	}
+
+	return int(affected), nil
+}
+
+// ListDeadLetters returns dead-lettered jobs, most recent first
+func (pq *PersistentQueue) ListDeadLetters(limit, offset int) ([]DeadLetter, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := pq.repo.Query(fmt.Sprintf(`
+		SELECT id, job_type, priority, payload, error, retry_count, max_retries, created_at, failed_at
+		FROM job_dead_letters ORDER BY failed_at DESC LIMIT %d OFFSET %d`, limit, offset))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var (
+			letter          DeadLetter
+			priority        int
+			payload, errMsg sql.NullString
+		)
+		if err := rows.Scan(&letter.ID, &letter.Type, &priority, &payload, &errMsg,
+			&letter.RetryCount, &letter.MaxRetries, &letter.CreatedAt, &letter.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		letter.Priority = JobPriority(priority)
+		letter.Error = errMsg.String
+		if payload.Valid && payload.String != "" {
+			json.Unmarshal([]byte(payload.String), &letter.Payload)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// RequeueDeadLetter moves a dead-lettered job back into the queue with a
+// fresh retry budget
+func (pq *PersistentQueue) RequeueDeadLetter(id string) error {
+	var (
+		jobType         string
+		priority        int
+		maxRetries      int
+		payload, errMsg sql.NullString
+		createdAt       time.Time
+	)
+
+	err := pq.repo.QueryRow(`
+		SELECT job_type, priority, payload, error, max_retries, created_at
+		FROM job_dead_letters WHERE id = ?`, id).Scan(
+		&jobType, &priority, &payload, &errMsg, &maxRetries, &createdAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("dead letter not found: %s", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load dead letter %s: %w", id, err)
+	}
+
+	tx, err := pq.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.Exec(`
+		INSERT INTO job_queue (id, job_type, priority, status, payload, error, retry_count, max_retries,
+			available_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?)`,
+		id, jobType, priority, string(JobStatusPending), payload.String, errMsg.String,
+		maxRetries, now, createdAt.UTC(), now)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to requeue dead letter %s: %w", id, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM job_dead_letters WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove dead letter %s: %w", id, err)
+	}
+
+	return tx.Commit()
+}
+
+// PurgeCompleted deletes completed and cancelled jobs older than the given age
+func (pq *PersistentQueue) PurgeCompleted(olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+	result, err := pq.repo.Exec(`
+		DELETE FROM job_queue WHERE status IN (?, ?) AND updated_at < ?`,
+		string(JobStatusCompleted), string(JobStatusCancelled), cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge completed jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Stats returns the number of queued jobs per status and the dead-letter count
+func (pq *PersistentQueue) Stats() (map[JobStatus]int, int, error) {
+	rows, err := pq.repo.Query(`SELECT status, COUNT(*) FROM job_queue GROUP BY status`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query job stats: %w", err)
+	}
+
+	byStatus := make(map[JobStatus]int)
+	for rows.Next() {
+		var (
+			status string
+			count  int
+		)
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan job stats: %w", err)
+		}
+		byStatus[JobStatus(status)] = count
+	}
+	rows.Close()
+
+	var deadLetters int
+	if err := pq.repo.QueryRow(`SELECT COUNT(*) FROM job_dead_letters`).Scan(&deadLetters); err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	return byStatus, deadLetters, nil
+}
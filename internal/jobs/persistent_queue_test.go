@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/testutil"
+)
+
+func newTestQueue(t *testing.T) *PersistentQueue {
+	t.Helper()
+	return NewPersistentQueue(testutil.NewRepo(t), PersistentQueueConfig{
+		RetryBackoff: time.Nanosecond,
+		Logger:       testutil.DiscardLogger,
+	})
+}
+
+func TestPersistentQueueClaimOrder(t *testing.T) {
+	pq := newTestQueue(t)
+	for _, job := range []*Job{
+		{ID: "low", Type: "test", Priority: JobPriorityLow},
+		{ID: "urgent", Type: "test", Priority: JobPriorityUrgent},
+		{ID: "normal", Type: "test", Priority: JobPriorityNormal, Payload: map[string]interface{}{"order_id": "42"}},
+	} {
+		if err := pq.Push(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []string{"urgent", "normal", "low"} {
+		job, err := pq.Claim("worker-a", time.Minute)
+		if err != nil {
+			t.Fatalf("claim %s: %v", want, err)
+		}
+		if job.ID != want || job.Status != JobStatusRunning {
+			t.Fatalf("claimed %s (%s), want running %s", job.ID, job.Status, want)
+		}
+		if want == "normal" && job.Payload["order_id"] != "42" {
+			t.Fatalf("payload was not kept: %v", job.Payload)
+		}
+	}
+
+	if _, err := pq.Claim("worker-a", time.Minute); !errors.Is(err, ErrQueueEmpty) {
+		t.Fatalf("got %v, want ErrQueueEmpty", err)
+	}
+	if size := pq.Size(); size != 3 {
+		t.Fatalf("size = %d, want 3 running jobs", size)
+	}
+}
+
+func TestPersistentQueueLeaseExpiry(t *testing.T) {
+	pq := newTestQueue(t)
+	if err := pq.Push(&Job{ID: "j1", Type: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := pq.Claim("worker-a", 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pq.Claim("worker-b", time.Minute); !errors.Is(err, ErrQueueEmpty) {
+		t.Fatalf("leased job was handed out twice: %v", err)
+	}
+
+	// Extending keeps the job leased past its original expiry
+	if err := pq.Extend(first, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, err := pq.Claim("worker-b", time.Minute); !errors.Is(err, ErrQueueEmpty) {
+		t.Fatalf("extended job was handed out: %v", err)
+	}
+
+	// Once the lease runs out another worker takes the job over, and the
+	// first worker can no longer report on it
+	if err := pq.Extend(first, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	second, err := pq.Claim("worker-b", time.Minute)
+	if err != nil {
+		t.Fatalf("expired lease was not reclaimed: %v", err)
+	}
+	if second.ID != "j1" {
+		t.Fatalf("claimed %s, want j1", second.ID)
+	}
+	if err := pq.Complete(first); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("stale worker completed the job: %v", err)
+	}
+	if err := pq.Extend(first, time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("stale worker extended the lease: %v", err)
+	}
+
+	second.Result = "ok"
+	if err := pq.Complete(second); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := pq.Get("j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != JobStatusCompleted || stored.CompletedAt == nil {
+		t.Fatalf("job was not completed: %+v", stored)
+	}
+}
+
+func TestPersistentQueueFailAndDeadLetter(t *testing.T) {
+	pq := newTestQueue(t)
+	if err := pq.Push(&Job{ID: "j1", Type: "test", MaxRetries: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := pq.Claim("worker-a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pq.Fail(job, errors.New("gateway timeout")); err != nil {
+		t.Fatal(err)
+	}
+	retried, err := pq.Get("j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != JobStatusPending || retried.RetryCount != 1 || retried.Error != "gateway timeout" {
+		t.Fatalf("failed attempt was not scheduled for retry: %+v", retried)
+	}
+
+	time.Sleep(time.Millisecond)
+	job, err = pq.Claim("worker-a", time.Minute)
+	if err != nil {
+		t.Fatalf("retry was not claimable: %v", err)
+	}
+	if job.RetryCount != 1 {
+		t.Fatalf("retry count = %d, want 1", job.RetryCount)
+	}
+	if err := pq.Fail(job, errors.New("gateway timeout")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pq.Get("j1"); err == nil {
+		t.Fatal("dead-lettered job is still in the queue")
+	}
+	letters, err := pq.ListDeadLetters(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].ID != "j1" || letters[0].RetryCount != 2 || letters[0].Error != "gateway timeout" {
+		t.Fatalf("dead letters = %+v", letters)
+	}
+
+	// A requeued dead letter starts over with a fresh retry budget
+	if err := pq.RequeueDeadLetter("j1"); err != nil {
+		t.Fatal(err)
+	}
+	job, err = pq.Claim("worker-a", time.Minute)
+	if err != nil {
+		t.Fatalf("requeued job was not claimable: %v", err)
+	}
+	if job.ID != "j1" || job.RetryCount != 0 {
+		t.Fatalf("requeued job = %+v", job)
+	}
+	if letters, _ := pq.ListDeadLetters(10, 0); len(letters) != 0 {
+		t.Fatalf("dead letter was kept after requeue: %+v", letters)
+	}
+}
+
+func TestPersistentQueueRecover(t *testing.T) {
+	pq := newTestQueue(t)
+	for _, id := range []string{"own", "other", "expired"} {
+		if err := pq.Push(&Job{ID: id, Type: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	claim := func(owner string, visibility time.Duration) {
+		t.Helper()
+		if _, err := pq.Claim(owner, visibility); err != nil {
+			t.Fatal(err)
+		}
+	}
+	claim("host:1:aaaa", time.Minute)
+	claim("host:2:bbbb", time.Minute)
+	claim("host:3:cccc", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// Only expired leases are recovered; live ones are left to their owners
+	recovered, err := pq.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != 1 {
+		t.Fatalf("recovered %d jobs, want 1", recovered)
+	}
+
+	pending, err := pq.List(JobStatusPending, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := pq.List(JobStatusRunning, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || len(running) != 2 {
+		t.Fatalf("pending = %d, running = %d; want 1 and 2", len(pending), len(running))
+	}
+	if pending[0].ID != "expired" {
+		t.Fatalf("recovered a live lease: %s is pending", pending[0].ID)
+	}
+}
+
+func TestJobManagerDurableQueue(t *testing.T) {
+	pq := newTestQueue(t)
+	jm := NewJobManager(JobManagerConfig{
+		Workers:      1,
+		Queue:        pq,
+		Logger:       testutil.DiscardLogger,
+		PollInterval: 5 * time.Millisecond,
+	})
+
+	done := make(chan string, 1)
+	jm.RegisterHandler("echo", func(ctx context.Context, job *Job) error {
+		done <- job.Payload["message"].(string)
+		return nil
+	})
+	jm.Start()
+	if err := jm.SubmitJob(&Job{ID: "j1", Type: "echo", Payload: map[string]interface{}{"message": "merhaba"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-done:
+		if got != "merhaba" {
+			t.Fatalf("handler got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("job was not processed")
+	}
+
+	// Status queries read the queue table, so they see jobs submitted by
+	// any process
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		completed := jm.GetJobsByStatus(JobStatusCompleted)
+		if len(completed) == 1 && completed[0].ID == "j1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("completed jobs = %+v", completed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	jm.Shutdown()
+	if err := pq.Push(&Job{ID: "elsewhere", Type: "echo"}); err != nil {
+		t.Fatal(err)
+	}
+	if pending := jm.GetJobsByStatus(JobStatusPending); len(pending) != 1 || pending[0].ID != "elsewhere" {
+		t.Fatalf("job pushed by another process is not listed: %+v", pending)
+	}
+}
+
+func TestJobManagerReleasesUnhandledJobs(t *testing.T) {
+	pq := newTestQueue(t)
+	jm := NewJobManager(JobManagerConfig{
+		Workers:      1,
+		Queue:        pq,
+		Logger:       testutil.DiscardLogger,
+		PollInterval: 5 * time.Millisecond,
+	})
+	if err := jm.SubmitJob(&Job{ID: "j1", Type: "elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is claimed before the workers start
+	time.Sleep(20 * time.Millisecond)
+	if job, err := pq.Get("j1"); err != nil || job.Status != JobStatusPending || job.StartedAt != nil {
+		t.Fatalf("job before Start = %+v (err %v)", job, err)
+	}
+
+	jm.Start()
+	defer jm.Shutdown()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := pq.Get("j1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.StartedAt != nil && job.Status == JobStatusPending {
+			if job.RetryCount != 0 {
+				t.Fatalf("releasing an unhandled job used up attempt %d", job.RetryCount)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v, want it claimed and released", job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if letters, _ := pq.ListDeadLetters(10, 0); len(letters) != 0 {
+		t.Fatalf("unhandled job dead-lettered: %+v", letters)
+	}
+}
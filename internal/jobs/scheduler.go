@@ -0,0 +1,399 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"kolajAi/internal/database"
+)
+
+// Schedule represents a recurring job definition
+type Schedule struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	CronExpr   string                 `json:"cron_expr"`
+	Timezone   string                 `json:"timezone"`
+	JobType    string                 `json:"job_type"`
+	Payload    map[string]interface{} `json:"payload,omitempty"`
+	Priority   JobPriority            `json:"priority"`
+	MaxRetries int                    `json:"max_retries"`
+	Enabled    bool                   `json:"enabled"`
+	RunCount   int64                  `json:"run_count"`
+	LastRunAt  *time.Time             `json:"last_run_at,omitempty"`
+	NextRunAt  *time.Time             `json:"next_run_at,omitempty"`
+	LastJobID  string                 `json:"last_job_id,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// SchedulerConfig holds configuration for the scheduler
+type SchedulerConfig struct {
+	InstanceID   string
+	TickInterval time.Duration
+	LockTTL      time.Duration
+	Logger       *log.Logger
+}
+
+// Scheduler enqueues jobs into a JobManager according to cron schedules.
+// Schedules and their last/next run times are persisted, and a leader lock
+// ensures only one server instance fires them.
+type Scheduler struct {
+	repo         database.SimpleRepository
+	jobManager   *JobManager
+	lock         *LeaderLock
+	tickInterval time.Duration
+	logger       *log.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(repo database.SimpleRepository, jobManager *JobManager, config SchedulerConfig) *Scheduler {
+	if config.InstanceID == "" {
+		config.InstanceID = processID()
+	}
+	if config.TickInterval <= 0 {
+		config.TickInterval = 15 * time.Second
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 4 * config.TickInterval
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		repo:         repo,
+		jobManager:   jobManager,
+		lock:         NewLeaderLock(repo, "job_scheduler", config.InstanceID, config.LockTTL),
+		tickInterval: config.TickInterval,
+		logger:       config.Logger,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Register creates or updates a schedule. The run history of an existing
+// schedule is kept; its next run is recomputed when the timing changed.
+func (s *Scheduler) Register(schedule *Schedule) error {
+	if schedule.ID == "" || schedule.JobType == "" {
+		return fmt.Errorf("schedule id and job type are required")
+	}
+	if schedule.Name == "" {
+		schedule.Name = schedule.ID
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.MaxRetries == 0 {
+		schedule.MaxRetries = 3
+	}
+
+	cron, err := ParseCron(schedule.CronExpr, schedule.Timezone)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(schedule.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule payload: %w", err)
+	}
+
+	now := time.Now().UTC()
+	next := cron.Next(now).UTC()
+	schedule.NextRunAt = &next
+	schedule.UpdatedAt = now
+
+	existing, err := s.Get(schedule.ID)
+	if err != nil {
+		schedule.CreatedAt = now
+		_, err = s.repo.Exec(`
+			INSERT INTO job_schedules (id, name, cron_expr, timezone, job_type, payload, priority, max_retries,
+				enabled, next_run_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			schedule.ID, schedule.Name, schedule.CronExpr, schedule.Timezone, schedule.JobType,
+			string(payload), int(schedule.Priority), schedule.MaxRetries, schedule.Enabled,
+			next, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to create schedule %s: %w", schedule.ID, err)
+		}
+		return nil
+	}
+
+	if existing.CronExpr == schedule.CronExpr && existing.Timezone == schedule.Timezone && existing.NextRunAt != nil {
+		schedule.NextRunAt = existing.NextRunAt
+	}
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.RunCount = existing.RunCount
+	schedule.LastRunAt = existing.LastRunAt
+	schedule.LastJobID = existing.LastJobID
+
+	_, err = s.repo.Exec(`
+		UPDATE job_schedules
+		SET name = ?, cron_expr = ?, timezone = ?, job_type = ?, payload = ?, priority = ?, max_retries = ?,
+			enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?`,
+		schedule.Name, schedule.CronExpr, schedule.Timezone, schedule.JobType, string(payload),
+		int(schedule.Priority), schedule.MaxRetries, schedule.Enabled, schedule.NextRunAt.UTC(), now,
+		schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", schedule.ID, err)
+	}
+
+	return nil
+}
+
+// Remove deletes a schedule
+func (s *Scheduler) Remove(id string) error {
+	if _, err := s.repo.Exec(`DELETE FROM job_schedules WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to remove schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetEnabled enables or disables a schedule. Re-enabling recomputes the
+// next run so that missed runs are not fired in a burst.
+func (s *Scheduler) SetEnabled(id string, enabled bool) error {
+	schedule, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	next := schedule.NextRunAt
+	if enabled && !schedule.Enabled {
+		cron, err := ParseCron(schedule.CronExpr, schedule.Timezone)
+		if err != nil {
+			return err
+		}
+		n := cron.Next(now).UTC()
+		next = &n
+	}
+
+	_, err = s.repo.Exec(`UPDATE job_schedules SET enabled = ?, next_run_at = ?, updated_at = ? WHERE id = ?`,
+		enabled, next, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", id, err)
+	}
+	return nil
+}
+
+// Get returns a schedule by ID
+func (s *Scheduler) Get(id string) (*Schedule, error) {
+	rows, err := s.repo.Query(scheduleSelect+` WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedule %s: %w", id, err)
+	}
+
+	schedules, err := scanSchedules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, fmt.Errorf("schedule not found: %s", id)
+	}
+
+	return schedules[0], nil
+}
+
+// List returns all schedules ordered by next run time
+func (s *Scheduler) List() ([]*Schedule, error) {
+	rows, err := s.repo.Query(scheduleSelect + ` ORDER BY next_run_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return scanSchedules(rows)
+}
+
+// RunNow enqueues the schedule's job immediately without changing its next
+// regular run
+func (s *Scheduler) RunNow(id string) (*Job, error) {
+	schedule, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job := s.newJob(schedule, now)
+	if err := s.jobManager.SubmitJob(job); err != nil {
+		return nil, fmt.Errorf("failed to submit job for schedule %s: %w", id, err)
+	}
+
+	_, err = s.repo.Exec(`
+		UPDATE job_schedules SET last_run_at = ?, last_job_id = ?, run_count = run_count + 1, updated_at = ?
+		WHERE id = ?`, now, job.ID, now, id)
+	if err != nil {
+		s.logger.Printf("Failed to record manual run of schedule %s: %v", id, err)
+	}
+
+	return job, nil
+}
+
+// Start starts the scheduler loop
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.tickInterval)
+		defer ticker.Stop()
+
+		s.tick()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick()
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and gives up leadership
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+
+	if err := s.lock.Release(); err != nil {
+		s.logger.Printf("Failed to release scheduler lock: %v", err)
+	}
+}
+
+// tick fires all due schedules if this instance is the leader
+func (s *Scheduler) tick() {
+	leader, err := s.lock.Acquire()
+	if err != nil {
+		s.logger.Printf("Scheduler lock error: %v", err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	now := time.Now().UTC()
+	rows, err := s.repo.Query(scheduleSelect+` WHERE enabled = ? AND next_run_at <= ?`, true, now)
+	if err != nil {
+		s.logger.Printf("Failed to load due schedules: %v", err)
+		return
+	}
+
+	due, err := scanSchedules(rows)
+	if err != nil {
+		s.logger.Printf("Failed to load due schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range due {
+		s.fire(schedule, now)
+	}
+}
+
+// fire advances a due schedule and enqueues its job. The run counter guards
+// the update, so a schedule is fired at most once per activation even if
+// leadership changed hands mid-tick.
+func (s *Scheduler) fire(schedule *Schedule, now time.Time) {
+	cron, err := ParseCron(schedule.CronExpr, schedule.Timezone)
+	if err != nil {
+		s.logger.Printf("Schedule %s has an invalid cron expression: %v", schedule.ID, err)
+		return
+	}
+
+	// Runs missed while no instance was leader are collapsed into one
+	next := cron.Next(now).UTC()
+	job := s.newJob(schedule, now)
+
+	// The schedule only advances once its job is queued, so a failed submit
+	// is retried on the next tick instead of skipping the run
+	if err := s.jobManager.SubmitJob(job); err != nil {
+		s.logger.Printf("Failed to submit job for schedule %s: %v", schedule.ID, err)
+		return
+	}
+
+	result, err := s.repo.Exec(`
+		UPDATE job_schedules
+		SET last_run_at = ?, next_run_at = ?, last_job_id = ?, run_count = run_count + 1, updated_at = ?
+		WHERE id = ? AND run_count = ?`,
+		now, next, job.ID, now, schedule.ID, schedule.RunCount)
+	if err == nil {
+		var affected int64
+		if affected, err = result.RowsAffected(); err == nil && affected == 0 {
+			err = fmt.Errorf("schedule was fired by another instance")
+		}
+	}
+	if err != nil {
+		s.logger.Printf("Failed to advance schedule %s: %v", schedule.ID, err)
+		if cancelErr := s.jobManager.CancelJob(job.ID); cancelErr != nil {
+			s.logger.Printf("Failed to cancel job %s of schedule %s: %v", job.ID, schedule.ID, cancelErr)
+		}
+		return
+	}
+
+	s.logger.Printf("Schedule %s fired job %s, next run at %s", schedule.ID, job.ID, next.Format(time.RFC3339))
+}
+
+// newJob builds the job enqueued for a schedule activation
+func (s *Scheduler) newJob(schedule *Schedule, now time.Time) *Job {
+	payload := make(map[string]interface{}, len(schedule.Payload)+2)
+	for k, v := range schedule.Payload {
+		payload[k] = v
+	}
+	payload["schedule_id"] = schedule.ID
+	payload["scheduled_at"] = now.Format(time.RFC3339)
+
+	return &Job{
+		ID:         generateJobID(),
+		Type:       schedule.JobType,
+		Priority:   schedule.Priority,
+		Payload:    payload,
+		MaxRetries: schedule.MaxRetries,
+	}
+}
+
+const scheduleSelect = `SELECT id, name, cron_expr, timezone, job_type, payload, priority, max_retries, enabled,
+	run_count, last_run_at, next_run_at, last_job_id, created_at, updated_at FROM job_schedules`
+
+// scanSchedules reads schedules from rows and closes them
+func scanSchedules(rows database.Rows) ([]*Schedule, error) {
+	defer rows.Close()
+
+	var schedules []*Schedule
+	for rows.Next() {
+		var (
+			schedule             Schedule
+			priority             int
+			payload, lastJobID   sql.NullString
+			lastRunAt, nextRunAt sql.NullTime
+		)
+		if err := rows.Scan(&schedule.ID, &schedule.Name, &schedule.CronExpr, &schedule.Timezone,
+			&schedule.JobType, &payload, &priority, &schedule.MaxRetries, &schedule.Enabled,
+			&schedule.RunCount, &lastRunAt, &nextRunAt, &lastJobID, &schedule.CreatedAt, &schedule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+
+		schedule.Priority = JobPriority(priority)
+		schedule.LastJobID = lastJobID.String
+		if lastRunAt.Valid {
+			schedule.LastRunAt = &lastRunAt.Time
+		}
+		if nextRunAt.Valid {
+			schedule.NextRunAt = &nextRunAt.Time
+		}
+		if payload.Valid && payload.String != "" && payload.String != "null" {
+			if err := json.Unmarshal([]byte(payload.String), &schedule.Payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal payload of schedule %s: %w", schedule.ID, err)
+			}
+		}
+
+		schedules = append(schedules, &schedule)
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"kolajAi/internal/testutil"
+)
+
+func TestLeaderLock(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	first := NewLeaderLock(repo, "job_scheduler", "host:1:aaaa", 20*time.Millisecond)
+	second := NewLeaderLock(repo, "job_scheduler", "host:2:bbbb", time.Minute)
+
+	acquire := func(lock *LeaderLock, want bool) {
+		t.Helper()
+		leader, err := lock.Acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leader != want {
+			t.Fatalf("%s: leader = %v, want %v", lock.owner, leader, want)
+		}
+	}
+
+	acquire(first, true)
+	acquire(second, false)
+	acquire(first, true)
+
+	// An expired lease is taken over by the next instance that asks
+	time.Sleep(40 * time.Millisecond)
+	acquire(second, true)
+	acquire(first, false)
+
+	if err := second.Release(); err != nil {
+		t.Fatal(err)
+	}
+	acquire(first, true)
+}
+
+func TestLeaderLockReportsDatabaseErrors(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	if _, err := repo.Exec(`DROP TABLE job_leader_locks`); err != nil {
+		t.Fatal(err)
+	}
+
+	leader, err := NewLeaderLock(repo, "job_scheduler", "host:1:aaaa", time.Minute).Acquire()
+	if err == nil || leader {
+		t.Fatalf("got leader = %v, err = %v; want an error", leader, err)
+	}
+}
+
+// pushOnlyQueue is a queue whose jobs are never handed to workers
+type pushOnlyQueue struct {
+	*PersistentQueue
+}
+
+func (pushOnlyQueue) Claim(owner string, visibility time.Duration) (*Job, error) {
+	return nil, ErrQueueEmpty
+}
+
+// newTestScheduler returns a scheduler whose job manager stores jobs in the
+// same database and never runs them
+func newTestScheduler(t *testing.T) (*Scheduler, *PersistentQueue) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	pq := NewPersistentQueue(repo, PersistentQueueConfig{Logger: testutil.DiscardLogger})
+	jm := NewJobManager(JobManagerConfig{Workers: 1, Queue: pushOnlyQueue{pq}, Logger: testutil.DiscardLogger, PollInterval: time.Hour})
+	t.Cleanup(jm.Shutdown)
+	return NewScheduler(repo, jm, SchedulerConfig{InstanceID: "host:1:aaaa", Logger: testutil.DiscardLogger}), pq
+}
+
+// makeDue moves the next run of a schedule into the past
+func makeDue(t *testing.T, s *Scheduler, id string) {
+	t.Helper()
+	if _, err := s.repo.Exec(`UPDATE job_schedules SET next_run_at = ? WHERE id = ?`,
+		time.Now().UTC().Add(-time.Minute), id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerFiresDueSchedules(t *testing.T) {
+	s, pq := newTestScheduler(t)
+	if err := s.Register(&Schedule{ID: "report", CronExpr: "0 3 * * *", JobType: "daily_report", Enabled: true,
+		Payload: map[string]interface{}{"format": "pdf"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register(&Schedule{ID: "paused", CronExpr: "* * * * *", JobType: "noop", Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is due right after registration
+	s.tick()
+	if size := pq.Size(); size != 0 {
+		t.Fatalf("%d jobs queued before any schedule was due", size)
+	}
+
+	makeDue(t, s, "report")
+	makeDue(t, s, "paused")
+	s.tick()
+
+	jobs, err := pq.List(JobStatusPending, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Type != "daily_report" {
+		t.Fatalf("queued jobs = %+v, want one daily_report", jobs)
+	}
+	if jobs[0].Payload["format"] != "pdf" || jobs[0].Payload["schedule_id"] != "report" {
+		t.Fatalf("payload = %v", jobs[0].Payload)
+	}
+
+	schedule, err := s.Get("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.RunCount != 1 || schedule.LastJobID != jobs[0].ID || !schedule.NextRunAt.After(time.Now()) {
+		t.Fatalf("schedule was not advanced: %+v", schedule)
+	}
+
+	// The advanced schedule does not fire again on the next tick
+	s.tick()
+	if size := pq.Size(); size != 1 {
+		t.Fatalf("%d jobs queued, want 1", size)
+	}
+}
+
+func TestSchedulerKeepsRunWhenSubmitFails(t *testing.T) {
+	s, pq := newTestScheduler(t)
+	if err := s.Register(&Schedule{ID: "sweep", CronExpr: "*/5 * * * *", JobType: "sweep", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	makeDue(t, s, "sweep")
+	before, err := s.Get("sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.repo.Exec(`ALTER TABLE job_queue RENAME TO job_queue_offline`); err != nil {
+		t.Fatal(err)
+	}
+	s.tick()
+
+	after, err := s.Get("sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.RunCount != 0 || !after.NextRunAt.Equal(*before.NextRunAt) {
+		t.Fatalf("schedule advanced although its job was not queued: %+v", after)
+	}
+
+	// The run is fired once the queue is reachable again
+	if _, err := s.repo.Exec(`ALTER TABLE job_queue_offline RENAME TO job_queue`); err != nil {
+		t.Fatal(err)
+	}
+	s.tick()
+	if size := pq.Size(); size != 1 {
+		t.Fatalf("%d jobs queued after recovery, want 1", size)
+	}
+}
+
+func TestSchedulerFollowerDoesNotFire(t *testing.T) {
+	s, pq := newTestScheduler(t)
+	if err := s.Register(&Schedule{ID: "sweep", CronExpr: "* * * * *", JobType: "sweep", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	makeDue(t, s, "sweep")
+
+	leader := NewLeaderLock(s.repo, "job_scheduler", "host:2:bbbb", time.Minute)
+	if ok, err := leader.Acquire(); err != nil || !ok {
+		t.Fatalf("leader = %v, err = %v", ok, err)
+	}
+	s.tick()
+	if size := pq.Size(); size != 0 {
+		t.Fatalf("follower queued %d jobs", size)
+	}
+}
+
+func TestSchedulerRunNow(t *testing.T) {
+	s, pq := newTestScheduler(t)
+	if err := s.Register(&Schedule{ID: "sweep", CronExpr: "0 0 1 1 *", JobType: "sweep", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.Get("sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := s.RunNow("sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pq.Get(job.ID); err != nil {
+		t.Fatalf("manual run was not queued: %v", err)
+	}
+	after, err := s.Get("sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.RunCount != 1 || !after.NextRunAt.Equal(*before.NextRunAt) {
+		t.Fatalf("manual run changed the regular schedule: %+v", after)
+	}
+}
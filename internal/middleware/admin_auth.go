@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"time"
 	
-	"github.com/kolajai/internal/models"
+	"kolajAi/internal/models"
 )
 
 // AdminAuthMiddleware checks if user is authenticated as admin
@@ -21,7 +21,7 @@ func AdminAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		}
 		
 		// Check if user is logged in
-		userID, ok := session.Values["user_id"].(int64)
+		userID, ok := session["user_id"].(int64)
 		if !ok || userID == 0 {
 			log.Printf("AdminAuth: No user_id in session")
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
@@ -29,7 +29,7 @@ func AdminAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		}
 		
 		// Check if user is admin
-		isAdmin, ok := session.Values["is_admin"].(bool)
+		isAdmin, ok := session["is_admin"].(bool)
 		if !ok || !isAdmin {
 			log.Printf("AdminAuth: User %d is not admin", userID)
 			http.Error(w, "Forbidden - Admin access required", http.StatusForbidden)
@@ -55,7 +55,7 @@ func RequirePermission(resource, action string) func(http.HandlerFunc) http.Hand
 				return
 			}
 			
-			userID, ok := session.Values["user_id"].(int64)
+			userID, ok := session["user_id"].(int64)
 			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
@@ -185,6 +185,9 @@ type MarketplaceCategory struct {
 	CategoryID        uint        `json:"category_id" gorm:"index;not null"`
 	Category          Category    `json:"category" gorm:"foreignKey:CategoryID"`
 	
+	// VendorID scopes the mapping to one vendor's products. 0 is the
+	// platform-wide mapping vendors fall back to.
+	VendorID          uint        `json:"vendor_id" gorm:"index;default:0"`
 	MarketplaceName   string      `json:"marketplace_name" gorm:"size:100;not null"`
 	ExternalCategoryID string     `json:"external_category_id" gorm:"size:255;not null"`
 	ExternalCategoryName string   `json:"external_category_name" gorm:"size:500"`
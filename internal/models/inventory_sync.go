@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// InventoryAllocationRule decides how much of a product's stock a sales
+// channel is offered. A rule without a product applies to every product of
+// the channel; product rules take precedence over it.
+type InventoryAllocationRule struct {
+	ID            int64  `json:"id" db:"id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	ProductID     int64  `json:"product_id" db:"product_id"`
+	// BufferStock is the number of units held back from the channel
+	BufferStock int `json:"buffer_stock" db:"buffer_stock"`
+	// Percentage is the share, 0 to 100, of the stock left after the
+	// buffer that the channel is offered
+	Percentage float64 `json:"percentage" db:"percentage"`
+	// MaxQuantity caps the quantity offered. 0 means no cap.
+	MaxQuantity int `json:"max_quantity" db:"max_quantity"`
+	// Priority orders the channels when stock is scarce. Lower values are
+	// served first.
+	Priority  int       `json:"priority" db:"priority"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ChannelStock is the quantity of a product last pushed to a sales channel
+type ChannelStock struct {
+	IntegrationID string     `json:"integration_id" db:"integration_id"`
+	ProductID     int64      `json:"product_id" db:"product_id"`
+	SKU           string     `json:"sku" db:"sku"`
+	Quantity      int        `json:"quantity" db:"quantity"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	SyncedAt      *time.Time `json:"synced_at" db:"synced_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
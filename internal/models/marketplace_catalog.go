@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// MarketplaceCategoryNode is a category of a marketplace's category tree as
+// last downloaded. Products can only be listed in leaf categories.
+type MarketplaceCategoryNode struct {
+	IntegrationID string    `json:"integration_id" db:"integration_id"`
+	ExternalID    string    `json:"external_id" db:"external_id"`
+	Name          string    `json:"name" db:"name"`
+	ParentID      string    `json:"parent_id" db:"parent_id"`
+	Path          string    `json:"path" db:"path"`
+	Level         int       `json:"level" db:"level"`
+	IsLeaf        bool      `json:"is_leaf" db:"is_leaf"`
+	SyncedAt      time.Time `json:"synced_at" db:"synced_at"`
+}
+
+// MarketplaceAttribute is an attribute a marketplace category accepts, as
+// last downloaded
+type MarketplaceAttribute struct {
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	CategoryID    string `json:"category_id" db:"category_id"`
+	AttributeID   string `json:"attribute_id" db:"attribute_id"`
+	Name          string `json:"name" db:"name"`
+	Required      bool   `json:"required" db:"required"`
+	// AllowCustom lets attributes with fixed values take other values too
+	AllowCustom bool                        `json:"allow_custom" db:"allow_custom"`
+	Varianter   bool                        `json:"varianter" db:"varianter"`
+	Values      []MarketplaceAttributeValue `json:"values,omitempty" db:"-"`
+	SyncedAt    time.Time                   `json:"synced_at" db:"synced_at"`
+}
+
+// MarketplaceAttributeValue is a fixed value of a marketplace attribute
+type MarketplaceAttributeValue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// MarketplaceBrand is a brand a marketplace accepts, as last downloaded
+type MarketplaceBrand struct {
+	IntegrationID string    `json:"integration_id" db:"integration_id"`
+	ExternalID    string    `json:"external_id" db:"external_id"`
+	Name          string    `json:"name" db:"name"`
+	SyncedAt      time.Time `json:"synced_at" db:"synced_at"`
+}
+
+// MarketplaceAttributeMapping maps a product attribute to an attribute of a
+// marketplace category. A mapping with a Value maps only that value, to
+// ExternalValueID; one without maps the attribute itself. DefaultValue is
+// sent when the product does not have the attribute.
+type MarketplaceAttributeMapping struct {
+	ID                  int64     `json:"id" db:"id"`
+	IntegrationID       string    `json:"integration_id" db:"integration_id"`
+	VendorID            int64     `json:"vendor_id" db:"vendor_id"`
+	ExternalCategoryID  string    `json:"external_category_id" db:"external_category_id"`
+	ExternalAttributeID string    `json:"external_attribute_id" db:"external_attribute_id"`
+	AttributeName       string    `json:"attribute_name" db:"attribute_name"`
+	Value               string    `json:"value" db:"value"`
+	ExternalValueID     string    `json:"external_value_id" db:"external_value_id"`
+	DefaultValue        string    `json:"default_value" db:"default_value"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
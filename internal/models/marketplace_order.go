@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// MarketplaceOrder links an order imported from a marketplace to the local
+// order created for it
+type MarketplaceOrder struct {
+	ID                 int64  `json:"id" db:"id"`
+	IntegrationID      string `json:"integration_id" db:"integration_id"`
+	MarketplaceOrderID string `json:"marketplace_order_id" db:"marketplace_order_id"`
+	OrderNumber        string `json:"order_number" db:"order_number"`
+	OrderID            int64  `json:"order_id" db:"order_id"`
+	// MarketplaceStatus is the order status last reported by the
+	// marketplace, as a local order status
+	MarketplaceStatus string `json:"marketplace_status" db:"marketplace_status"`
+	// SyncedStatus is the local status the marketplace is known to have,
+	// either because it reported it or because it was pushed to it
+	SyncedStatus string    `json:"synced_status" db:"synced_status"`
+	LastError    string    `json:"last_error" db:"last_error"`
+	ImportedAt   time.Time `json:"imported_at" db:"imported_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MarketplaceOrderCursor is how far orders of a marketplace integration
+// have been imported
+type MarketplaceOrderCursor struct {
+	IntegrationID string     `json:"integration_id" db:"integration_id"`
+	LastOrderAt   *time.Time `json:"last_order_at" db:"last_order_at"`
+	LastRunAt     *time.Time `json:"last_run_at" db:"last_run_at"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	ImportedCount int        `json:"imported_count" db:"imported_count"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MarketplaceOrderFailure is a marketplace order an import could not
+// process. It is retried from its stored payload until it is imported.
+type MarketplaceOrderFailure struct {
+	ID                 int64     `json:"id" db:"id"`
+	IntegrationID      string    `json:"integration_id" db:"integration_id"`
+	MarketplaceOrderID string    `json:"marketplace_order_id" db:"marketplace_order_id"`
+	OrderDate          time.Time `json:"order_date" db:"order_date"`
+	Payload            string    `json:"-" db:"payload"`
+	Attempts           int       `json:"attempts" db:"attempts"`
+	LastError          string    `json:"last_error" db:"last_error"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
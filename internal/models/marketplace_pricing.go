@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Price rounding modes. Prices are always rounded up, so rounding never
+// takes a price below its margin floor.
+const (
+	PriceRoundingNone  = ""      // to the kuruş
+	PriceRoundingWhole = "whole" // 100.00
+	PriceRoundingHalf  = "half"  // 99.50, 100.00
+	PriceRounding90    = "90"    // 99.90
+	PriceRounding99    = "99"    // 99.99
+)
+
+// MarketplacePriceRule decides the price a sales channel is offered a product
+// at. Rules can apply to a whole channel or narrow it down to a vendor, a
+// category or a single product; the most specific active rule wins.
+type MarketplacePriceRule struct {
+	ID            int64  `json:"id" db:"id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	VendorID      int64  `json:"vendor_id" db:"vendor_id"`
+	CategoryID    int64  `json:"category_id" db:"category_id"`
+	ProductID     int64  `json:"product_id" db:"product_id"`
+	// MarkupPercent and MarkupFixed are added to the base price
+	MarkupPercent float64 `json:"markup_percent" db:"markup_percent"`
+	MarkupFixed   float64 `json:"markup_fixed" db:"markup_fixed"`
+	// CommissionPercent and FixedFee are what the marketplace keeps of a
+	// sale. The price is grossed up so that what is left after them is the
+	// marked up price.
+	CommissionPercent float64 `json:"commission_percent" db:"commission_percent"`
+	FixedFee          float64 `json:"fixed_fee" db:"fixed_fee"`
+	// MinMarginPercent is the margin over the cost price that must be left
+	// after commission and fees. Promotions cannot go below it either.
+	MinMarginPercent float64 `json:"min_margin_percent" db:"min_margin_percent"`
+	Rounding         string  `json:"rounding" db:"rounding"`
+	// UseRecommendedPrice bases the price on the pricing strategy's
+	// recommended price instead of the catalog price
+	UseRecommendedPrice bool      `json:"use_recommended_price" db:"use_recommended_price"`
+	IsActive            bool      `json:"is_active" db:"is_active"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MarketplacePricePromotion is a promotional price of a product for a period.
+// Without an integration it applies to every channel. The regular channel
+// price is sent as the list price while the promotion runs.
+type MarketplacePricePromotion struct {
+	ID            int64  `json:"id" db:"id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	ProductID     int64  `json:"product_id" db:"product_id"`
+	// Price is the promotional price. When it is 0, DiscountPercent is taken
+	// off the regular channel price instead.
+	Price           float64   `json:"price" db:"price"`
+	DiscountPercent float64   `json:"discount_percent" db:"discount_percent"`
+	StartsAt        time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt          time.Time `json:"ends_at" db:"ends_at"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// IsRunning reports whether the promotion applies at the given time
+func (p *MarketplacePricePromotion) IsRunning(at time.Time) bool {
+	return !at.Before(p.StartsAt) && at.Before(p.EndsAt)
+}
+
+// ChannelPrice is the price of a product last pushed to a sales channel. A
+// price the channel rejected is kept with the rejection as LastError, so it
+// is only sent again once it changes.
+type ChannelPrice struct {
+	IntegrationID string     `json:"integration_id" db:"integration_id"`
+	ProductID     int64      `json:"product_id" db:"product_id"`
+	SKU           string     `json:"sku" db:"sku"`
+	Price         float64    `json:"price" db:"price"`
+	ListPrice     float64    `json:"list_price" db:"list_price"`
+	LastError     string     `json:"last_error" db:"last_error"`
+	SyncedAt      *time.Time `json:"synced_at" db:"synced_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
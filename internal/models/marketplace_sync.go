@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Marketplace sync run statuses
+const (
+	SyncRunRunning    = "running"
+	SyncRunProcessing = "processing" // waiting for the marketplace's batches
+	SyncRunCompleted  = "completed"
+	SyncRunPartial    = "partial" // some listings rejected or not sent
+	SyncRunFailed     = "failed"
+)
+
+// Marketplace sync item statuses. Items are accepted, rejected and pending
+// as the marketplace reports them; failed items were not sent or their
+// outcome was never reported.
+const (
+	SyncItemAccepted = "accepted"
+	SyncItemRejected = "rejected"
+	SyncItemPending  = "pending"
+	SyncItemFailed   = "failed"
+)
+
+// Marketplace sync run triggers
+const (
+	SyncTriggerManual = "manual"
+	SyncTriggerRetry  = "retry"
+)
+
+// MarketplaceSyncRun is one sync of listings to a marketplace
+type MarketplaceSyncRun struct {
+	ID            int64  `json:"id" db:"id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	Trigger       string `json:"trigger" db:"trigger_type"`
+	// RetryOf is the run whose failed items a retry run sends again
+	RetryOf    int64      `json:"retry_of,omitempty" db:"retry_of"`
+	Status     string     `json:"status" db:"status"`
+	Total      int        `json:"total" db:"total"`
+	Accepted   int        `json:"accepted" db:"accepted"`
+	Rejected   int        `json:"rejected" db:"rejected"`
+	Pending    int        `json:"pending" db:"pending"`
+	Failed     int        `json:"failed" db:"failed"`
+	Error      string     `json:"error,omitempty" db:"error_message"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MarketplaceSyncItem is the outcome of one listing of a sync run
+type MarketplaceSyncItem struct {
+	ID            int64  `json:"id" db:"id"`
+	RunID         int64  `json:"run_id" db:"run_id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	ProductID     int64  `json:"product_id" db:"product_id"`
+	SKU           string `json:"sku" db:"sku"`
+	Barcode       string `json:"barcode" db:"barcode"`
+	Status        string `json:"status" db:"status"`
+	// Message is the marketplace's reason for a rejection, or why the
+	// listing was not sent
+	Message string `json:"message,omitempty" db:"message"`
+	// BatchID identifies the marketplace batch a pending listing is in
+	BatchID   string    `json:"batch_id,omitempty" db:"batch_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -11,6 +11,7 @@ type Order struct {
 	ID              int64     `json:"id" db:"id"`
 	UserID          int64     `json:"user_id" db:"user_id"`
 	VendorID        int64     `json:"vendor_id" db:"vendor_id"`
+	ParentOrderID   int64     `json:"parent_order_id" db:"parent_order_id"` // set on the per-vendor sub-orders of a multi-vendor order
 	OrderNumber     string    `json:"order_number" db:"order_number"`
 	Status          string    `json:"status" db:"status"` // pending, confirmed, processing, shipped, delivered, cancelled, refunded
 	PaymentStatus   string    `json:"payment_status" db:"payment_status"` // pending, paid, failed, refunded, partial
@@ -49,6 +50,7 @@ type Order struct {
 	InternalNotes   string    `json:"internal_notes" db:"internal_notes"`
 	CouponCode      string    `json:"coupon_code" db:"coupon_code"`
 	ReferenceID     string    `json:"reference_id" db:"reference_id"`
+	SourceChannel   string    `json:"source_channel" db:"source_channel"` // web, or the marketplace integration an imported order came from
 	
 	// Timestamps
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
@@ -60,6 +62,7 @@ type Order struct {
 	User            *User       `json:"user,omitempty"`
 	Vendor          *Vendor     `json:"vendor,omitempty"`
 	StatusHistory   []OrderStatusHistory `json:"status_history,omitempty"`
+	SubOrders       []Order     `json:"sub_orders,omitempty"`
 }
 
 // OrderAddress represents delivery address for an order
@@ -109,6 +112,7 @@ type OrderItem struct {
 	TotalPrice      float64 `json:"total_price" db:"total_price"`
 	Commission      float64 `json:"commission" db:"commission"`
 	Status          string  `json:"status" db:"status"`
+	StockTaken      int     `json:"stock_taken" db:"stock_taken"` // units taken from stock without a reservation, given back on cancellation
 	IsWholesale     bool    `json:"is_wholesale" db:"is_wholesale"`
 	ProductSnapshot string  `json:"product_snapshot" db:"product_snapshot"` // JSON snapshot of product at time of order
 	
@@ -240,6 +244,12 @@ func (o *Order) IsCancelled() bool {
 	return o.Status == "cancelled"
 }
 
+// IsSubOrder checks if order is the part of a multi-vendor order that
+// belongs to one vendor
+func (o *Order) IsSubOrder() bool {
+	return o.ParentOrderID > 0
+}
+
 // IsRefunded checks if order is refunded
 func (o *Order) IsRefunded() bool {
 	return o.Status == "refunded" || o.PaymentStatus == "refunded"
@@ -342,4 +352,8 @@ const (
 	OrderStatusRefunded   = "refunded"
 )
 
+// OrderChannelWeb is the source channel of orders placed on the storefront.
+// Orders imported from a marketplace carry its integration ID instead.
+const OrderChannelWeb = "web"
+
 // Note: PaymentStatus constants moved to payment.go model
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// PaymentReconciliation is one run comparing a payment gateway's
+// transactions with our payment and order records over a period
+type PaymentReconciliation struct {
+	ID               int64                       `json:"id" db:"id"`
+	Gateway          string                      `json:"gateway" db:"gateway"`
+	PeriodStart      time.Time                   `json:"period_start" db:"period_start"`
+	PeriodEnd        time.Time                   `json:"period_end" db:"period_end"`
+	GatewayCount     int                         `json:"gateway_count" db:"gateway_count"`
+	LocalCount       int                         `json:"local_count" db:"local_count"`
+	MatchedCount     int                         `json:"matched_count" db:"matched_count"`
+	DiscrepancyCount int                         `json:"discrepancy_count" db:"discrepancy_count"`
+	GatewayTotal     float64                     `json:"gateway_total" db:"gateway_total"`
+	LocalTotal       float64                     `json:"local_total" db:"local_total"`
+	Status           string                      `json:"status" db:"status"` // balanced, discrepancies
+	CreatedAt        time.Time                   `json:"created_at" db:"created_at"`
+	Items            []PaymentReconciliationItem `json:"items,omitempty" db:"-"`
+}
+
+// PaymentReconciliationItem is a discrepancy found by a reconciliation run
+type PaymentReconciliationItem struct {
+	ID               int64     `json:"id" db:"id"`
+	ReconciliationID int64     `json:"reconciliation_id" db:"reconciliation_id"`
+	Issue            string    `json:"issue" db:"issue"`
+	TransactionID    string    `json:"transaction_id" db:"transaction_id"`
+	OrderID          int64     `json:"order_id" db:"order_id"`
+	GatewayAmount    float64   `json:"gateway_amount" db:"gateway_amount"`
+	LocalAmount      float64   `json:"local_amount" db:"local_amount"`
+	Currency         string    `json:"currency" db:"currency"`
+	Details          string    `json:"details" db:"details"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// Constants for reconciliation statuses
+const (
+	ReconciliationStatusBalanced      = "balanced"
+	ReconciliationStatusDiscrepancies = "discrepancies"
+)
+
+// Constants for reconciliation issues
+const (
+	// ReconciliationMissingLocal is a gateway transaction we have no
+	// payment record for
+	ReconciliationMissingLocal = "missing_local"
+	// ReconciliationMissingGateway is a settled local payment the gateway
+	// does not report
+	ReconciliationMissingGateway = "missing_gateway"
+	// ReconciliationDuplicate is a transaction reported twice, or an order
+	// charged more than once
+	ReconciliationDuplicate         = "duplicate"
+	ReconciliationAmountMismatch    = "amount_mismatch"
+	ReconciliationCurrencyMismatch  = "currency_mismatch"
+	ReconciliationReferenceMismatch = "reference_mismatch"
+	ReconciliationStatusMismatch    = "status_mismatch"
+	ReconciliationRefundMismatch    = "refund_mismatch"
+)
+
+// IsBalanced checks if the run found no discrepancies
+func (r *PaymentReconciliation) IsBalanced() bool {
+	return r.Status == ReconciliationStatusBalanced
+}
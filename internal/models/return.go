@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ReturnWindowDays is the statutory right of withdrawal period for
+// distance sales in Turkey (Law No. 6502)
+const ReturnWindowDays = 14
+
+// ReturnRequest represents a customer's request to return items of an
+// order (RMA). A request only covers items of a single vendor.
+type ReturnRequest struct {
+	ID              int64      `json:"id" db:"id"`
+	RMANumber       string     `json:"rma_number" db:"rma_number"`
+	OrderID         int64      `json:"order_id" db:"order_id"`
+	UserID          int64      `json:"user_id" db:"user_id"`
+	VendorID        int64      `json:"vendor_id" db:"vendor_id"`
+	Status          string     `json:"status" db:"status"` // requested, approved, rejected, in_transit, received, refunding, refunded, cancelled
+	Reason          string     `json:"reason" db:"reason"`
+	CustomerNote    string     `json:"customer_note" db:"customer_note"`
+	VendorNote      string     `json:"vendor_note" db:"vendor_note"`
+	RefundAmount    float64    `json:"refund_amount" db:"refund_amount"`
+	RefundReference string     `json:"refund_reference" db:"refund_reference"`
+	ShipmentID      int64      `json:"shipment_id" db:"shipment_id"`
+	TrackingNumber  string     `json:"tracking_number" db:"tracking_number"`
+	ApprovedAt      *time.Time `json:"approved_at" db:"approved_at"`
+	ReceivedAt      *time.Time `json:"received_at" db:"received_at"`
+	RefundedAt      *time.Time `json:"refunded_at" db:"refunded_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Related data (loaded separately)
+	Items []ReturnItem `json:"items,omitempty"`
+}
+
+// ReturnItem is a returned quantity of an order item
+type ReturnItem struct {
+	ID           int64   `json:"id" db:"id"`
+	ReturnID     int64   `json:"return_id" db:"return_id"`
+	OrderItemID  int64   `json:"order_item_id" db:"order_item_id"`
+	ProductID    int64   `json:"product_id" db:"product_id"`
+	Quantity     int     `json:"quantity" db:"quantity"`
+	UnitPrice    float64 `json:"unit_price" db:"unit_price"`
+	RefundAmount float64 `json:"refund_amount" db:"refund_amount"`
+	Reason       string  `json:"reason" db:"reason"`
+	Condition    string  `json:"condition" db:"item_condition"` // set on receipt: resellable, damaged, missing
+	Restocked    bool    `json:"restocked" db:"restocked"`
+}
+
+// Constants for return statuses
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusInTransit = "in_transit"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunding = "refunding"
+	ReturnStatusRefunded  = "refunded"
+	ReturnStatusCancelled = "cancelled"
+)
+
+// Constants for return reasons
+const (
+	ReturnReasonDefective      = "defective"
+	ReturnReasonDamaged        = "damaged_in_shipping"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonChangedMind    = "changed_mind"
+	ReturnReasonOther          = "other"
+)
+
+// Constants for the condition of received items
+const (
+	ReturnConditionResellable = "resellable"
+	ReturnConditionDamaged    = "damaged"
+	ReturnConditionMissing    = "missing"
+)
+
+// Validate validates return item data
+func (ri *ReturnItem) Validate() error {
+	if ri.OrderItemID <= 0 {
+		return errors.New("valid order item ID is required")
+	}
+
+	if ri.Quantity <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+
+	validReasons := []string{ReturnReasonDefective, ReturnReasonDamaged, ReturnReasonWrongItem,
+		ReturnReasonNotAsDescribed, ReturnReasonChangedMind, ReturnReasonOther}
+	if !contains(validReasons, strings.TrimSpace(ri.Reason)) {
+		return errors.New("invalid return reason")
+	}
+
+	return nil
+}
+
+// IsOpen reports whether the return still awaits a final outcome
+func (r *ReturnRequest) IsOpen() bool {
+	switch r.Status {
+	case ReturnStatusRejected, ReturnStatusRefunded, ReturnStatusCancelled:
+		return false
+	}
+	return true
+}
+
+// CanBeCancelled checks if the customer can still withdraw the request
+func (r *ReturnRequest) CanBeCancelled() bool {
+	return r.Status == ReturnStatusRequested || r.Status == ReturnStatusApproved
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// VendorLedgerEntry is one side of a double-entry ledger transaction. Every
+// transaction posts entries whose debits and credits balance; a vendor's
+// balance is the credit balance of its vendor_payable account.
+type VendorLedgerEntry struct {
+	ID            int64     `json:"id" db:"id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	VendorID      int64     `json:"vendor_id" db:"vendor_id"`
+	OrderID       int64     `json:"order_id" db:"order_id"`
+	PayoutID      int64     `json:"payout_id" db:"payout_id"`
+	Account       string    `json:"account" db:"account"`
+	EntryType     string    `json:"entry_type" db:"entry_type"`
+	Debit         float64   `json:"debit" db:"debit"`
+	Credit        float64   `json:"credit" db:"credit"`
+	Description   string    `json:"description" db:"description"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// VendorPayout is a payout statement that settles a vendor's ledger entries
+// up to the end of a period
+type VendorPayout struct {
+	ID                 int64      `json:"id" db:"id"`
+	VendorID           int64      `json:"vendor_id" db:"vendor_id"`
+	PeriodStart        time.Time  `json:"period_start" db:"period_start"`
+	PeriodEnd          time.Time  `json:"period_end" db:"period_end"`
+	GrossSales         float64    `json:"gross_sales" db:"gross_sales"`
+	Commission         float64    `json:"commission" db:"commission"`
+	Refunds            float64    `json:"refunds" db:"refunds"`
+	CommissionReversal float64    `json:"commission_reversal" db:"commission_reversal"`
+	ShippingCosts      float64    `json:"shipping_costs" db:"shipping_costs"`
+	NetAmount          float64    `json:"net_amount" db:"net_amount"`
+	Currency           string     `json:"currency" db:"currency"`
+	Status             string     `json:"status" db:"status"` // pending, paid, cancelled
+	Reference          string     `json:"reference" db:"reference"`
+	PaidAt             *time.Time `json:"paid_at" db:"paid_at"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Constants for ledger accounts
+const (
+	LedgerAccountVendorPayable     = "vendor_payable"
+	LedgerAccountCustomerClearing  = "customer_clearing"
+	LedgerAccountCommissionRevenue = "commission_revenue"
+	LedgerAccountShippingPayable   = "shipping_payable"
+	LedgerAccountBank              = "bank"
+)
+
+// Constants for ledger entry types
+const (
+	LedgerEntrySale               = "sale"
+	LedgerEntryCommission         = "commission"
+	LedgerEntryRefund             = "refund"
+	LedgerEntryCommissionReversal = "commission_reversal"
+	LedgerEntryShipping           = "shipping"
+	LedgerEntryPayout             = "payout"
+)
+
+// Constants for payout statuses
+const (
+	PayoutStatusPending   = "pending"
+	PayoutStatusPaid      = "paid"
+	PayoutStatusCancelled = "cancelled"
+)
+
+// IsPaid checks if the payout has been transferred to the vendor
+func (p *VendorPayout) IsPaid() bool {
+	return p.Status == PayoutStatusPaid
+}
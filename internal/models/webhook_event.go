@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Webhook inbox event statuses. Pending events wait for their integration's
+// queue; failed events have run out of attempts and are only processed
+// again when replayed.
+const (
+	WebhookEventPending    = "pending"
+	WebhookEventProcessing = "processing"
+	WebhookEventProcessed  = "processed"
+	WebhookEventFailed     = "failed"
+)
+
+// WebhookEvent is a webhook received from an integration, kept in the inbox
+// until it has been applied
+type WebhookEvent struct {
+	ID            int64  `json:"id" db:"id"`
+	IntegrationID string `json:"integration_id" db:"integration_id"`
+	// EventID is the integration's ID of the event, which deliveries of the
+	// same event share
+	EventID   string `json:"event_id" db:"event_id"`
+	EventType string `json:"event_type" db:"event_type"`
+	Payload   string `json:"payload" db:"payload"`
+	// Headers are the delivery's headers as JSON
+	Headers       string     `json:"headers" db:"headers"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	ReceivedAt    time.Time  `json:"received_at" db:"received_at"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+}
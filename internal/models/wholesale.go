@@ -11,6 +11,7 @@ type WholesaleCustomer struct {
 	BusinessType string     `json:"business_type" db:"business_type"`
 	YearlyVolume float64    `json:"yearly_volume" db:"yearly_volume"`
 	CreditLimit  float64    `json:"credit_limit" db:"credit_limit"`
+	CreditUsed   float64    `json:"credit_used" db:"credit_used"`     // owed on unpaid orders
 	PaymentTerms int        `json:"payment_terms" db:"payment_terms"` // days
 	DiscountTier string     `json:"discount_tier" db:"discount_tier"` // bronze, silver, gold, platinum
 	Status       string     `json:"status" db:"status"`               // pending, approved, suspended, rejected
@@ -48,11 +49,17 @@ type WholesaleOrder struct {
 	TaxAmount      float64   `json:"tax_amount" db:"tax_amount"`
 	ShippingCost   float64   `json:"shipping_cost" db:"shipping_cost"`
 	TotalAmount    float64   `json:"total_amount" db:"total_amount"`
+	PaidAmount     float64   `json:"paid_amount" db:"paid_amount"`
 	Currency       string    `json:"currency" db:"currency"`
+	VendorID       int       `json:"vendor_id" db:"vendor_id"`
+	QuoteID        int       `json:"quote_id" db:"quote_id"`
 	Notes          string    `json:"notes" db:"notes"`
 	InternalNotes  string    `json:"internal_notes" db:"internal_notes"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Related data (loaded separately)
+	Items []WholesaleOrderItem `json:"items,omitempty" db:"-"`
 }
 
 // WholesaleOrderItem represents items in wholesale orders
@@ -80,9 +87,15 @@ type WholesaleQuote struct {
 	SubTotal       float64   `json:"sub_total" db:"sub_total"`
 	DiscountAmount float64   `json:"discount_amount" db:"discount_amount"`
 	TotalAmount    float64   `json:"total_amount" db:"total_amount"`
+	Revision       int       `json:"revision" db:"revision"`
+	OrderID        int       `json:"order_id" db:"order_id"`
 	Notes          string    `json:"notes" db:"notes"`
+	VendorNotes    string    `json:"vendor_notes" db:"vendor_notes"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// Related data (loaded separately)
+	Items []WholesaleQuoteItem `json:"items,omitempty" db:"-"`
 }
 
 // WholesaleQuoteItem represents items in wholesale quotes
@@ -93,7 +106,69 @@ type WholesaleQuoteItem struct {
 	ProductName  string  `json:"product_name" db:"product_name"`
 	ProductSKU   string  `json:"product_sku" db:"product_sku"`
 	Quantity     int     `json:"quantity" db:"quantity"`
-	UnitPrice    float64 `json:"unit_price" db:"unit_price"`
+	ListPrice    float64 `json:"list_price" db:"list_price"`     // price list price for the quantity
+	TargetPrice  float64 `json:"target_price" db:"target_price"` // price asked for by the buyer
+	UnitPrice    float64 `json:"unit_price" db:"unit_price"`     // price offered by the vendor
 	DiscountRate float64 `json:"discount_rate" db:"discount_rate"`
 	TotalPrice   float64 `json:"total_price" db:"total_price"`
 }
+
+// WholesaleTierAll marks price tiers that apply to every customer group
+const WholesaleTierAll = "all"
+
+// Constants for wholesale customer discount tiers
+const (
+	WholesaleTierBronze   = "bronze"
+	WholesaleTierSilver   = "silver"
+	WholesaleTierGold     = "gold"
+	WholesaleTierPlatinum = "platinum"
+)
+
+// Constants for wholesale customer statuses
+const (
+	WholesaleCustomerPending   = "pending"
+	WholesaleCustomerApproved  = "approved"
+	WholesaleCustomerSuspended = "suspended"
+	WholesaleCustomerRejected  = "rejected"
+)
+
+// Constants for wholesale quote statuses. A draft quote waits for the
+// vendor, a sent quote waits for the buyer.
+const (
+	QuoteStatusDraft    = "draft"
+	QuoteStatusSent     = "sent"
+	QuoteStatusAccepted = "accepted"
+	QuoteStatusRejected = "rejected"
+	QuoteStatusExpired  = "expired"
+)
+
+// Constants for wholesale order payment statuses
+const (
+	WholesalePaymentPending = "pending"
+	WholesalePaymentPaid    = "paid"
+	WholesalePaymentPartial = "partial"
+	WholesalePaymentOverdue = "overdue"
+)
+
+// IsApproved checks if the customer can buy wholesale
+func (c *WholesaleCustomer) IsApproved() bool {
+	return c.Status == WholesaleCustomerApproved
+}
+
+// IsOpen checks if the quote is still being negotiated
+func (q *WholesaleQuote) IsOpen() bool {
+	return q.Status == QuoteStatusDraft || q.Status == QuoteStatusSent
+}
+
+// IsExpired checks if the quote's validity has run out
+func (q *WholesaleQuote) IsExpired(now time.Time) bool {
+	return q.Status == QuoteStatusExpired || (q.IsOpen() && now.After(q.ValidUntil))
+}
+
+// Outstanding returns the unpaid amount of the order
+func (o *WholesaleOrder) Outstanding() float64 {
+	if o.Status == "cancelled" || o.TotalAmount <= o.PaidAmount {
+		return 0
+	}
+	return o.TotalAmount - o.PaidAmount
+}
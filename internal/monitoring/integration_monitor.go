@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"kolajAi/internal/integrations"
 	"kolajAi/internal/integrations/registry"
 )
 
@@ -39,7 +40,7 @@ type MonitoringConfig struct {
 // HealthChecker manages health checking for a single integration
 type HealthChecker struct {
 	IntegrationID   string
-	Provider        registry.IntegrationProvider
+	Provider        integrations.IntegrationProvider
 	Status          HealthStatus
 	LastCheck       time.Time
 	LastSuccess     time.Time
@@ -418,6 +419,9 @@ func (im *IntegrationMonitor) Stop() error {
 
 // initializeHealthChecker initializes a health checker for an integration
 func (im *IntegrationMonitor) initializeHealthChecker(integration *registry.IntegrationDefinition) {
+	if !im.registry.HasProvider(integration.ID) {
+		return
+	}
 	provider, exists := im.registry.GetProvider(integration.ID)
 	if !exists {
 		im.logger.Warn("Provider not found for integration", map[string]interface{}{
@@ -530,6 +534,13 @@ func (im *IntegrationMonitor) performSingleHealthCheck(ctx context.Context, inte
 	responseTime := time.Since(startTime)
 	checker.ResponseTime = responseTime
 
+	// The registry marks integrations whose checks fail unavailable
+	if success {
+		im.registry.RecordHealth(integrationID, nil)
+	} else {
+		im.registry.RecordHealth(integrationID, lastErr)
+	}
+
 	// Update metrics
 	checker.Metrics.TotalChecks++
 	if checker.Metrics.MinResponseTime > responseTime {
@@ -689,8 +700,7 @@ func (im *IntegrationMonitor) collectIntegrationMetrics(integration *registry.In
 	}
 
 	// Get provider metrics if available
-	if provider, exists := im.registry.GetProvider(integration.ID); exists {
-		providerMetrics := provider.GetMetrics()
+	if providerMetrics, exists := im.registry.GetIntegrationMetrics(integration.ID); exists {
 		
 		if requestCount, ok := providerMetrics["request_count"].(int64); ok {
 			metrics.RequestCount = requestCount
@@ -1196,4 +1206,4 @@ func (im *IntegrationMonitor) GetMonitoringStatus() map[string]interface{} {
 		"alert_rules_count":     alertRulesCount,
 		"config":                im.config,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,340 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats a report result can be exported in
+const (
+	FormatCSV   = "csv"
+	FormatExcel = "excel"
+	FormatPDF   = "pdf"
+)
+
+// ExportedReport is a report result rendered as a file
+type ExportedReport struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Export renders a report result as a file in format, with the visible
+// columns of the report. An empty format exports CSV.
+func Export(config *ReportConfig, result *ReportResult, format string) (*ExportedReport, error) {
+	columns := exportColumns(config, result)
+	generatedAt := result.GeneratedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	basename := fmt.Sprintf("%s_%s", config.ID, generatedAt.Format("20060102_1504"))
+
+	switch strings.ToLower(format) {
+	case "", FormatCSV:
+		content, err := exportCSV(columns, result)
+		if err != nil {
+			return nil, err
+		}
+		return &ExportedReport{Filename: basename + ".csv", ContentType: "text/csv; charset=utf-8", Content: content}, nil
+	case FormatExcel:
+		return &ExportedReport{
+			Filename:    basename + ".xls",
+			ContentType: "application/vnd.ms-excel",
+			Content:     exportSpreadsheet(config.Name, columns, result),
+		}, nil
+	case FormatPDF:
+		return &ExportedReport{
+			Filename:    basename + ".pdf",
+			ContentType: "application/pdf",
+			Content:     exportPDF(config.Name, generatedAt, columns, result),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// exportColumns returns the visible columns of the report, or a column per
+// result field when the report defines none
+func exportColumns(config *ReportConfig, result *ReportResult) []ColumnConfig {
+	var columns []ColumnConfig
+	for _, column := range config.Columns {
+		if column.Visible {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) > 0 || len(result.Data) == 0 {
+		return columns
+	}
+
+	fields := make([]string, 0, len(result.Data[0]))
+	for field := range result.Data[0] {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		columns = append(columns, ColumnConfig{ID: field, Name: field, Field: field, Visible: true})
+	}
+	return columns
+}
+
+// exportCSV renders the result as CSV with a header row
+func exportCSV(columns []ColumnConfig, result *ReportResult) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.Name
+	}
+	if err := w.Write(record); err != nil {
+		return nil, fmt.Errorf("failed to write report: %w", err)
+	}
+	for _, row := range result.Data {
+		for i, column := range columns {
+			record[i] = formatCell(row[column.Field])
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportSpreadsheet renders the result as an Excel 2003 XML workbook, which
+// spreadsheet applications open without an external library
+func exportSpreadsheet(name string, columns []ColumnConfig, result *ReportResult) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<?mso-application progid="Excel.Sheet"?>` + "\n")
+	buf.WriteString(`<Workbook xmlns="urn:schemas-microsoft-com:office:spreadsheet" xmlns:ss="urn:schemas-microsoft-com:office:spreadsheet">` + "\n")
+	fmt.Fprintf(&buf, `<Worksheet ss:Name="%s"><Table>`+"\n", xmlEscape(sheetName(name)))
+
+	buf.WriteString("<Row>")
+	for _, column := range columns {
+		fmt.Fprintf(&buf, `<Cell><Data ss:Type="String">%s</Data></Cell>`, xmlEscape(column.Name))
+	}
+	buf.WriteString("</Row>\n")
+	for _, row := range result.Data {
+		buf.WriteString("<Row>")
+		for _, column := range columns {
+			value := row[column.Field]
+			cellType := "String"
+			if isNumber(value) {
+				cellType = "Number"
+			}
+			fmt.Fprintf(&buf, `<Cell><Data ss:Type="%s">%s</Data></Cell>`, cellType, xmlEscape(formatCell(value)))
+		}
+		buf.WriteString("</Row>\n")
+	}
+
+	buf.WriteString("</Table></Worksheet></Workbook>\n")
+	return buf.Bytes()
+}
+
+// sheetName makes a report name a valid worksheet name
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Report"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// PDF page layout: A4 landscape with a monospaced font so columns line up
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 36
+	pdfFontSize   = 8
+	pdfLeading    = 10
+	// pdfLineWidth is how many Courier characters fit between the margins
+	pdfLineWidth = (pdfPageWidth - 2*pdfMargin) * 10 / (6 * pdfFontSize)
+	// pdfColumnWidth caps the width of a single column
+	pdfColumnWidth = 30
+)
+
+// exportPDF renders the result as a plain text table in a PDF document
+func exportPDF(name string, generatedAt time.Time, columns []ColumnConfig, result *ReportResult) []byte {
+	widths := make([]int, len(columns))
+	cells := make([][]string, len(result.Data))
+	for i, column := range columns {
+		widths[i] = len([]rune(column.Name))
+	}
+	for r, row := range result.Data {
+		cells[r] = make([]string, len(columns))
+		for i, column := range columns {
+			cells[r][i] = formatCell(row[column.Field])
+			if n := len([]rune(cells[r][i])); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for i := range widths {
+		if widths[i] > pdfColumnWidth {
+			widths[i] = pdfColumnWidth
+		}
+	}
+
+	line := func(values []string) string {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = padCell(value, widths[i])
+		}
+		return truncateRunes(strings.Join(parts, " | "), pdfLineWidth)
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	lines := []string{
+		name,
+		fmt.Sprintf("%s - %d rows", generatedAt.Format("2006-01-02 15:04"), len(result.Data)),
+		"",
+		line(header),
+		strings.Repeat("-", len([]rune(line(header)))),
+	}
+	for _, row := range cells {
+		lines = append(lines, line(row))
+	}
+	return renderPDF(lines)
+}
+
+// renderPDF lays lines out on as many pages as they need
+func renderPDF(lines []string) []byte {
+	perPage := (pdfPageHeight - 2*pdfMargin) / pdfLeading
+	var pages [][]string
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects 1-3 are the catalog, the page tree and the font; each page
+	// takes a page object and a content stream after them
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range pages {
+		var stream bytes.Buffer
+		fmt.Fprintf(&stream, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+		for _, text := range page {
+			fmt.Fprintf(&stream, "(%s) Tj T*\n", pdfString(text))
+		}
+		stream.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfTransliterations maps the Turkish letters WinAnsiEncoding lacks to
+// their closest Latin letters
+var pdfTransliterations = strings.NewReplacer("ğ", "g", "Ğ", "G", "ş", "s", "Ş", "S", "ı", "i", "İ", "I")
+
+// pdfString encodes text as a WinAnsi PDF string literal body
+func pdfString(text string) string {
+	var buf bytes.Buffer
+	for _, r := range pdfTransliterations.Replace(text) {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			buf.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&buf, "\\%03o", r)
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
+
+func padCell(value string, width int) string {
+	value = truncateRunes(value, width)
+	return value + strings.Repeat(" ", width-len([]rune(value)))
+}
+
+func truncateRunes(value string, width int) string {
+	runes := []rune(value)
+	if len(runes) <= width {
+		return value
+	}
+	return string(runes[:width])
+}
+
+// formatCell renders a result value as text
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testExport(t *testing.T, format string, rows int) *ExportedReport {
+	t.Helper()
+	config := &ReportConfig{
+		ID:   "sales",
+		Name: "Satışlar: Şubat",
+		Columns: []ColumnConfig{
+			{ID: "vendor", Name: "Satıcı", Field: "vendor", Visible: true},
+			{ID: "total", Name: "Toplam", Field: "total", Visible: true},
+			{ID: "internal", Name: "Internal", Field: "internal"},
+		},
+	}
+	result := &ReportResult{GeneratedAt: time.Date(2026, 2, 28, 18, 30, 0, 0, time.UTC)}
+	for i := 0; i < rows; i++ {
+		result.Data = append(result.Data, map[string]interface{}{
+			"vendor":   fmt.Sprintf("Ağaç, Işık (%d)", i),
+			"total":    12.5 + float64(i),
+			"internal": "hidden",
+		})
+	}
+	exported, err := Export(config, result, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exported
+}
+
+func TestExportCSV(t *testing.T) {
+	exported := testExport(t, FormatCSV, 2)
+	if exported.Filename != "sales_20260228_1830.csv" {
+		t.Fatalf("filename = %s", exported.Filename)
+	}
+	records, err := csv.NewReader(bytes.NewReader(exported.Content)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"Satıcı", "Toplam"}, {"Ağaç, Işık (0)", "12.5"}, {"Ağaç, Işık (1)", "13.5"}}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Fatalf("records = %v, want %v", records, want)
+	}
+}
+
+func TestExportExcel(t *testing.T) {
+	exported := testExport(t, FormatExcel, 1)
+	content := string(exported.Content)
+	if !strings.HasSuffix(exported.Filename, ".xls") {
+		t.Fatalf("filename = %s", exported.Filename)
+	}
+	for _, want := range []string{
+		`<Worksheet ss:Name="Satışlar_ Şubat">`,
+		`<Data ss:Type="String">Ağaç, Işık (0)</Data>`,
+		`<Data ss:Type="Number">12.5</Data>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("workbook has no %s:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "hidden") {
+		t.Fatal("workbook has a column that is not visible")
+	}
+}
+
+func TestExportPDF(t *testing.T) {
+	// Enough rows for a second page
+	exported := testExport(t, FormatPDF, 80)
+	content := string(exported.Content)
+	if !strings.HasPrefix(content, "%PDF-1.4") || !strings.HasSuffix(content, "%%EOF\n") {
+		t.Fatalf("not a PDF document:\n%s", content)
+	}
+	if !strings.Contains(content, "/Count 2") {
+		t.Fatal("80 rows do not span two pages")
+	}
+	// Letters WinAnsi lacks are transliterated, the others are escaped
+	if !strings.Contains(content, `(Satislar: Subat) Tj`) || !strings.Contains(content, `Aga\347, Isik \(0\)`) {
+		t.Fatalf("unexpected text encoding:\n%s", content)
+	}
+
+	// The cross-reference table points at the objects
+	var xref int
+	if _, err := fmt.Sscanf(content[strings.LastIndex(content, "startxref"):], "startxref\n%d", &xref); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content[xref:], "xref") {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	var offset int
+	if _, err := fmt.Sscanf(content[xref:], "xref\n0 %d\n0000000000 65535 f \n%d", new(int), &offset); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content[offset:], "1 0 obj") {
+		t.Fatalf("offset %d does not point at the first object", offset)
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	if _, err := Export(&ReportConfig{ID: "sales"}, &ReportResult{}, "docx"); err == nil {
+		t.Fatal("exported an unknown format")
+	}
+}
@@ -4,13 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
 	"strings"
+	"sync"
 	"time"
 )
 
+// CacheEntityReport is the cached entity of report results
+const CacheEntityReport = "report"
+
 // ReportManager handles dynamic report generation
 type ReportManager struct {
 	db *sql.DB
+	// cache, when set, keeps the results of reports reading only from
+	// cachedTables
+	cache        *cache.EntityCache
+	cachedTables map[string]bool
+	// replicas, when set, runs the reports' queries on read replicas so they
+	// do not hold connections of the primary
+	replicas *database.ReplicaSet
+
+	mu sync.Mutex
+	// listeners are called with the ID of every report created, updated or
+	// deleted through the manager
+	listeners []func(reportID string)
 }
 
 // ReportConfig represents report configuration
@@ -228,55 +246,10 @@ type UserSegment struct {
 	Attributes map[string]interface{} `json:"attributes"`
 }
 
-// NewReportManager creates a new report manager
+// NewReportManager creates a new report manager. Its tables are created by
+// the report_tables migration.
 func NewReportManager(db *sql.DB) *ReportManager {
-	rm := &ReportManager{db: db}
-	rm.createReportTables()
-	return rm
-}
-
-// createReportTables creates necessary tables for reporting
-func (rm *ReportManager) createReportTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS report_configs (
-			id VARCHAR(128) PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			description TEXT,
-			category VARCHAR(100),
-			config_json TEXT NOT NULL,
-			created_by BIGINT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			INDEX idx_category (category),
-			INDEX idx_created_by (created_by)
-		)`,
-		`CREATE TABLE IF NOT EXISTS report_executions (
-			id VARCHAR(128) PRIMARY KEY,
-			report_id VARCHAR(128),
-			executed_by BIGINT,
-			execution_time_ms INT,
-			row_count INT,
-			filters_json TEXT,
-			executed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			INDEX idx_report_id (report_id),
-			INDEX idx_executed_by (executed_by),
-			INDEX idx_executed_at (executed_at)
-		)`,
-		`CREATE TABLE IF NOT EXISTS user_behavior_cache (
-			user_id BIGINT PRIMARY KEY,
-			behavior_data TEXT NOT NULL,
-			last_updated DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			INDEX idx_last_updated (last_updated)
-		)`,
-	}
-
-	for _, query := range queries {
-		if _, err := rm.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
-	}
-
-	return nil
+	return &ReportManager{db: db}
 }
 
 // CreateReport creates a new report configuration
@@ -296,8 +269,103 @@ func (rm *ReportManager) CreateReport(config *ReportConfig) error {
 
 	_, err = rm.db.Exec(query, config.ID, config.Name, config.Description, 
 		config.Category, string(configJSON), config.CreatedBy)
-	
-	return err
+	if err != nil {
+		return err
+	}
+
+	rm.changed(config.ID)
+	return nil
+}
+
+// UpdateReport replaces the configuration of an existing report
+func (rm *ReportManager) UpdateReport(config *ReportConfig) error {
+	existing, err := rm.GetReportConfig(config.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get report config: %w", err)
+	}
+	config.CreatedAt = existing.CreatedAt
+	config.UpdatedAt = time.Now()
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	_, err = rm.db.Exec(`
+		UPDATE report_configs SET name = ?, description = ?, category = ?, config_json = ?, updated_at = ?
+		WHERE id = ?`,
+		config.Name, config.Description, config.Category, string(configJSON), config.UpdatedAt, config.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update report config: %w", err)
+	}
+
+	rm.changed(config.ID)
+	return nil
+}
+
+// DeleteReport deletes a report configuration
+func (rm *ReportManager) DeleteReport(reportID string) error {
+	if _, err := rm.db.Exec("DELETE FROM report_configs WHERE id = ?", reportID); err != nil {
+		return fmt.Errorf("failed to delete report config: %w", err)
+	}
+
+	rm.changed(reportID)
+	return nil
+}
+
+// OnChange registers fn to be called after a report is created, updated or
+// deleted through the manager, so that its schedule can be kept in step
+func (rm *ReportManager) OnChange(fn func(reportID string)) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.listeners = append(rm.listeners, fn)
+}
+
+// changed calls the change listeners for a report
+func (rm *ReportManager) changed(reportID string) {
+	rm.mu.Lock()
+	listeners := append([]func(string){}, rm.listeners...)
+	rm.mu.Unlock()
+	for _, fn := range listeners {
+		fn(reportID)
+	}
+}
+
+// SetCache makes ExecuteReport cache the results of reports that read only
+// from tables, whose write paths must invalidate the cache's table tags
+func (rm *ReportManager) SetCache(c *cache.EntityCache, tables []string) {
+	rm.cache = c
+	rm.cachedTables = make(map[string]bool, len(tables))
+	for _, table := range tables {
+		rm.cachedTables[table] = true
+	}
+}
+
+// SetReplicas runs report queries on the read replicas of replicas. Report
+// configurations and executions are still written to the primary.
+func (rm *ReportManager) SetReplicas(replicas *database.ReplicaSet) {
+	rm.replicas = replicas
+}
+
+// reader returns the database report queries run on
+func (rm *ReportManager) reader() *sql.DB {
+	if rm.replicas == nil {
+		return rm.db
+	}
+	return rm.replicas.Replica()
+}
+
+// EnsureReport creates a report configuration unless one with the same ID
+// exists already, so built-in reports can be registered on every start
+func (rm *ReportManager) EnsureReport(config *ReportConfig) error {
+	_, err := rm.GetReportConfig(config.ID)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get report config: %w", err)
+	}
+	return rm.CreateReport(config)
 }
 
 // ExecuteReport executes a report and returns results
@@ -310,10 +378,66 @@ func (rm *ReportManager) ExecuteReport(reportID string, filters map[string]inter
 		return nil, fmt.Errorf("failed to get report config: %w", err)
 	}
 
+	var result *ReportResult
+	if tables, ok := rm.cacheableTables(config); ok {
+		filtersJSON, err := json.Marshal(filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal filters: %w", err)
+		}
+		key := rm.cache.Key(CacheEntityReport, reportID, map[string]string{"filters": string(filtersJSON)})
+		tags := make([]string, 0, len(tables))
+		for _, table := range tables {
+			tags = append(tags, cache.TableTag(table))
+		}
+		result, err = cache.Load(rm.cache, CacheEntityReport, key, tags, func() (*ReportResult, error) {
+			return rm.runReport(config, filters)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if result, err = rm.runReport(config, filters); err != nil {
+		return nil, err
+	}
+	result.GeneratedBy = userID
+
+	// Log execution
+	rm.logExecution(reportID, userID, time.Since(startTime), result.TotalRows, filters)
+
+	return result, nil
+}
+
+// cacheableTables returns the tables a report reads from, and whether its
+// results can be cached because every one of them is a cached table
+func (rm *ReportManager) cacheableTables(config *ReportConfig) ([]string, bool) {
+	if rm.cache == nil || len(config.DataSources) == 0 {
+		return nil, false
+	}
+	// Only the first data source and its joins make the FROM clause
+	source := config.DataSources[0]
+	tables := []string{source.Source}
+	for _, join := range source.Joins {
+		tables = append(tables, join.Table)
+	}
+	for i, table := range tables {
+		// Drop an alias, as in "orders o"
+		if fields := strings.Fields(table); len(fields) > 0 {
+			tables[i] = fields[0]
+		}
+		if !rm.cachedTables[tables[i]] {
+			return nil, false
+		}
+	}
+	return tables, true
+}
+
+// runReport queries the data of a report and builds its charts and summary
+func (rm *ReportManager) runReport(config *ReportConfig, filters map[string]interface{}) (*ReportResult, error) {
+	startTime := time.Now()
+
 	// Build and execute query
 	query, args := rm.buildQuery(config, filters)
 	
-	rows, err := rm.db.Query(query, args...)
+	rows, err := rm.reader().Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -331,25 +455,17 @@ func (rm *ReportManager) ExecuteReport(reportID string, filters map[string]inter
 	// Generate summary
 	summary := rm.generateSummary(data, config)
 
-	executionTime := time.Since(startTime)
-
-	result := &ReportResult{
-		ID:            reportID,
+	return &ReportResult{
+		ID:            config.ID,
 		Name:          config.Name,
 		Data:          data,
 		Charts:        charts,
 		Summary:       summary,
 		Filters:       filters,
 		TotalRows:     len(data),
-		ExecutionTime: executionTime,
+		ExecutionTime: time.Since(startTime),
 		GeneratedAt:   time.Now(),
-		GeneratedBy:   userID,
-	}
-
-	// Log execution
-	rm.logExecution(reportID, userID, executionTime, len(data), filters)
-
-	return result, nil
+	}, nil
 }
 
 // GetUserBehaviorReport generates comprehensive user behavior report
@@ -517,6 +633,11 @@ func (rm *ReportManager) processRows(rows *sql.Rows, columns []ColumnConfig) ([]
 		row := make(map[string]interface{})
 		for i, col := range cols {
 			val := values[i]
+			// Drivers return text as bytes, which would be cached and
+			// served as base64
+			if b, ok := val.([]byte); ok {
+				val = string(b)
+			}
 			if val != nil {
 				// Format value based on column type
 				if len(columns) > i {
@@ -759,7 +880,7 @@ func (rm *ReportManager) getUserBasicInfo(userID int64, report *UserBehaviorRepo
 	`
 	
 	var lastActivity sql.NullTime
-	err := rm.db.QueryRow(query, userID, userID).Scan(
+	err := rm.reader().QueryRow(query, userID, userID).Scan(
 		&report.Name, &report.Email, &report.RegistrationDate, &lastActivity)
 	
 	if err != nil {
@@ -783,7 +904,7 @@ func (rm *ReportManager) getUserOrderStats(userID int64, report *UserBehaviorRep
 		WHERE user_id = ? AND status != 'cancelled'
 	`
 	
-	return rm.db.QueryRow(query, userID).Scan(
+	return rm.reader().QueryRow(query, userID).Scan(
 		&report.TotalOrders, &report.TotalSpent, &report.AverageOrderValue)
 }
 
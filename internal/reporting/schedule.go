@@ -0,0 +1,65 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CronExpression converts the schedule into a five-field cron expression
+func (sc *ScheduleConfig) CronExpression() (string, error) {
+	hour, minute := 0, 0
+	if sc.Time != "" {
+		if _, err := fmt.Sscanf(sc.Time, "%d:%d", &hour, &minute); err != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+			return "", fmt.Errorf("invalid schedule time %q, expected HH:MM", sc.Time)
+		}
+	}
+
+	switch strings.ToLower(sc.Frequency) {
+	case "daily":
+		return fmt.Sprintf("%d %d * * *", minute, hour), nil
+	case "weekly":
+		days := make([]string, 0, len(sc.Days))
+		for _, day := range sc.Days {
+			if len(day) < 3 {
+				return "", fmt.Errorf("invalid schedule day %q", day)
+			}
+			days = append(days, strings.ToLower(day[:3]))
+		}
+		if len(days) == 0 {
+			days = append(days, "mon")
+		}
+		return fmt.Sprintf("%d %d * * %s", minute, hour, strings.Join(days, ",")), nil
+	case "monthly":
+		return fmt.Sprintf("%d %d 1 * *", minute, hour), nil
+	default:
+		return "", fmt.Errorf("unsupported schedule frequency %q", sc.Frequency)
+	}
+}
+
+// GetScheduledReports returns all reports that have an enabled schedule
+func (rm *ReportManager) GetScheduledReports() ([]*ReportConfig, error) {
+	rows, err := rm.db.Query("SELECT config_json FROM report_configs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report configs: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*ReportConfig
+	for rows.Next() {
+		var configJSON string
+		if err := rows.Scan(&configJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan report config: %w", err)
+		}
+
+		var config ReportConfig
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			continue
+		}
+		if config.Schedule != nil && config.Schedule.Enabled {
+			reports = append(reports, &config)
+		}
+	}
+
+	return reports, nil
+}
@@ -286,6 +286,41 @@ func (r *AdminRepository) UpdateUserStatus(userID int64, isActive bool) error {
 	return nil
 }
 
+// GetUserByID returns a user for admin actions
+func (r *AdminRepository) GetUserByID(userID int64) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(`
+		SELECT id, name, email, phone, role, is_active, is_admin, is_seller, created_at, updated_at
+		FROM users WHERE id = ?
+	`, userID).Scan(&user.ID, &user.Name, &user.Email, &user.Phone,
+		&user.Role, &user.IsActive, &user.IsAdmin, &user.IsSeller,
+		&user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// BanUser deactivates a user. The reason is kept in the admin audit log.
+func (r *AdminRepository) BanUser(userID int64, reason string) error {
+	return r.UpdateUserStatus(userID, false)
+}
+
+// UnbanUser reactivates a banned user
+func (r *AdminRepository) UnbanUser(userID int64) error {
+	return r.UpdateUserStatus(userID, true)
+}
+
+// ActivateUser activates a user
+func (r *AdminRepository) ActivateUser(userID int64) error {
+	return r.UpdateUserStatus(userID, true)
+}
+
+// DeactivateUser deactivates a user
+func (r *AdminRepository) DeactivateUser(userID int64) error {
+	return r.UpdateUserStatus(userID, false)
+}
+
 // DeleteUser soft deletes a user
 func (r *AdminRepository) DeleteUser(userID int64) error {
 	// Instead of hard delete, we deactivate the user
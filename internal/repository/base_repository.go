@@ -42,6 +42,11 @@ func (r *BaseRepository) FindAll(table string, result interface{}, conditions ma
 	return r.db.FindAll(table, result, conditions, orderBy, limit, offset)
 }
 
+// FindAllKeyset retrieves a keyset page of records after cursor
+func (r *BaseRepository) FindAllKeyset(table string, result interface{}, conditions map[string]interface{}, keyset database.Keyset, cursor *database.Cursor, limit int) error {
+	return r.db.FindAllKeyset(table, result, conditions, keyset, cursor, limit)
+}
+
 // FindOne retrieves a single record
 func (r *BaseRepository) FindOne(table string, result interface{}, conditions map[string]interface{}) error {
 	return r.db.FindOne(table, result, conditions)
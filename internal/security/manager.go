@@ -35,6 +35,9 @@ type SecurityConfig struct {
 	PasswordRequireSymbol bool         `json:"password_require_symbol"`
 	SessionTimeout       time.Duration `json:"session_timeout"`
 	CSRFTokenLength      int           `json:"csrf_token_length"`
+	// CSRFExemptPaths are path prefixes of server-to-server callbacks, such
+	// as payment gateway webhooks, which authenticate themselves
+	CSRFExemptPaths      []string      `json:"csrf_exempt_paths"`
 	EnableIPWhitelist    bool          `json:"enable_ip_whitelist"`
 	EnableIPBlacklist    bool          `json:"enable_ip_blacklist"`
 	EnableRateLimit      bool          `json:"enable_rate_limit"`
@@ -790,10 +793,24 @@ func (sm *SecurityManager) validateCSRFToken(r *http.Request) bool {
 
 // requiresCSRFProtection checks if request requires CSRF protection
 func (sm *SecurityManager) requiresCSRFProtection(r *http.Request) bool {
+	if sm.IsCSRFExempt(r.URL.Path) {
+		return false
+	}
 	// CSRF protection for state-changing methods
 	return r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE" || r.Method == "PATCH"
 }
 
+// IsCSRFExempt reports whether the path is one of the configured
+// server-to-server callbacks that do not carry a CSRF token
+func (sm *SecurityManager) IsCSRFExempt(path string) bool {
+	for _, prefix := range sm.config.CSRFExemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // logSecurityEvent logs a security event
 func (sm *SecurityManager) logSecurityEvent(ctx context.Context, eventType SecurityEventType, severity SecuritySeverity, r *http.Request, message string, details map[string]interface{}) {
 	event := &SecurityEvent{
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -580,8 +581,11 @@ func (s *AIAdvancedService) AnalyzeProductImage(imageURL string) (map[string]int
 // GetAICreditsBalance gets the AI credits balance for a user
 func (s *AIAdvancedService) GetAICreditsBalance(userID int) (int, error) {
 	var credits int
-	row := s.repo.QueryRow("SELECT COALESCE(ai_credits, 0) FROM users WHERE id = ?", userID)
+	row := s.repo.QueryRow("SELECT credits FROM ai_credits WHERE user_id = ?", userID)
 	err := row.Scan(&credits)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to get AI credits balance: %w", err)
 	}
@@ -590,7 +594,8 @@ func (s *AIAdvancedService) GetAICreditsBalance(userID int) (int, error) {
 
 // DeductAICredits deducts AI credits from user's balance
 func (s *AIAdvancedService) DeductAICredits(userID int, credits int) error {
-	_, err := s.repo.Exec("UPDATE users SET ai_credits = GREATEST(ai_credits - ?, 0) WHERE id = ?", credits, userID)
+	_, err := s.repo.Exec(`UPDATE ai_credits SET credits = CASE WHEN credits > ? THEN credits - ? ELSE 0 END, updated_at = ?
+		WHERE user_id = ?`, credits, credits, time.Now(), userID)
 	if err != nil {
 		return fmt.Errorf("failed to deduct AI credits: %w", err)
 	}
@@ -0,0 +1,840 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Auction errors
+var (
+	ErrAuctionNotActive           = errors.New("auction is not active")
+	ErrAuctionEnded               = errors.New("auction has ended")
+	ErrBidTooLow                  = errors.New("bid is below the minimum bid")
+	ErrAlreadyLeading             = errors.New("bidder is already the highest bidder")
+	ErrSelfBidding                = errors.New("vendors cannot bid on their own auctions")
+	ErrBuyNowUnavailable          = errors.New("buy it now is not available for this auction")
+	ErrConcurrentAuctionOp        = errors.New("auction was changed concurrently")
+	ErrAuctionEngineNotConfigured = errors.New("auction engine is not configured")
+)
+
+// AuctionEngineConfig configures the auction engine
+type AuctionEngineConfig struct {
+	// SoftCloseWindow is how long before the end a bid extends the auction,
+	// for auctions without their own extend_minutes
+	SoftCloseWindow time.Duration
+	// PaymentWindow is how long the winner has to pay. The sold unit is
+	// reserved for that long; the checkout's expired reservation release
+	// cancels unpaid orders and puts the unit back. Defaults to 48 hours.
+	PaymentWindow time.Duration
+	Currency      string
+
+	WebSocketService    *WebSocketService
+	NotificationService *NotificationService
+	// Cache, when set, drops the cached reads of sold products and of the
+	// orders sales create
+	Cache  *cache.EntityCache
+	Logger *log.Logger
+}
+
+// AuctionEngine processes bids, buy-it-now purchases and auction endings.
+// Operations on the same auction are serialized in process, and every write
+// is guarded by the auction's bid count so that concurrent instances cannot
+// both win a race.
+type AuctionEngine struct {
+	repo   database.SimpleRepository
+	config AuctionEngineConfig
+	logger *log.Logger
+
+	mu    sync.Mutex
+	locks map[int]*auctionLock
+}
+
+// auctionLock is a reference counted per-auction mutex
+type auctionLock struct {
+	sync.Mutex
+	refs int
+}
+
+// BidRequest is a bid placed by a user. MaxAmount turns the bid into a proxy
+// bid: the engine bids on the user's behalf, one increment at a time, up to
+// that amount.
+type BidRequest struct {
+	AuctionID int
+	UserID    int
+	Amount    float64
+	MaxAmount float64
+	IPAddress string
+}
+
+// BidResult describes the state of the auction after a bid
+type BidResult struct {
+	Auction  *models.Auction    `json:"auction"`
+	Bid      *models.AuctionBid `json:"bid"`
+	Leading  bool               `json:"leading"`
+	Extended bool               `json:"extended"`
+}
+
+// leadingBid is the current high bid with its bidder's maximum
+type leadingBid struct {
+	id        int
+	userID    int
+	amount    float64
+	maxAmount float64
+}
+
+// auctionSale is the product sold in an auction, if any, and the vendor's
+// commission rate
+type auctionSale struct {
+	product        *models.Product
+	commissionRate float64
+}
+
+// NewAuctionEngine creates a new auction engine
+func NewAuctionEngine(repo database.SimpleRepository, config AuctionEngineConfig) (*AuctionEngine, error) {
+	if config.SoftCloseWindow <= 0 {
+		config.SoftCloseWindow = 5 * time.Minute
+	}
+	if config.PaymentWindow <= 0 {
+		config.PaymentWindow = 48 * time.Hour
+	}
+	if config.Currency == "" {
+		config.Currency = "TRY"
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &AuctionEngine{
+		repo:   repo,
+		config: config,
+		logger: logger,
+		locks:  make(map[int]*auctionLock),
+	}, nil
+}
+
+// lock serializes operations on an auction and returns the unlock function
+func (e *AuctionEngine) lock(auctionID int) func() {
+	e.mu.Lock()
+	l, ok := e.locks[auctionID]
+	if !ok {
+		l = &auctionLock{}
+		e.locks[auctionID] = l
+	}
+	l.refs++
+	e.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		e.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(e.locks, auctionID)
+		}
+		e.mu.Unlock()
+	}
+}
+
+// PlaceBid places a bid. The bidder's maximum is the larger of Amount and
+// MaxAmount. If another bidder's proxy bid is higher, it outbids the new
+// bid right away; otherwise the new bidder leads at one increment above the
+// previous maximum. Reaching the reserve price lifts the bid to the reserve,
+// and bids close to the end extend the auction (soft close).
+func (e *AuctionEngine) PlaceBid(req *BidRequest) (*BidResult, error) {
+	maxAmount := roundMoney(math.Max(req.Amount, req.MaxAmount))
+	if maxAmount <= 0 {
+		return nil, ErrBidTooLow
+	}
+
+	unlock := e.lock(req.AuctionID)
+	defer unlock()
+
+	auction, err := e.loadAuction(req.AuctionID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if err := checkBiddable(auction, now); err != nil {
+		return nil, err
+	}
+	if auction.VendorID > 0 && e.isVendorUser(auction.VendorID, req.UserID) {
+		return nil, ErrSelfBidding
+	}
+	leader, err := e.loadLeadingBid(auction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if leader != nil && leader.userID == req.UserID {
+		return e.raiseMaximum(auction, leader, maxAmount, now)
+	}
+
+	minimum := minimumBid(auction, leader != nil)
+	if maxAmount < minimum {
+		return nil, fmt.Errorf("%w: bid must be at least %.2f", ErrBidTooLow, minimum)
+	}
+
+	increment := bidIncrement(auction)
+	bid := &models.AuctionBid{
+		AuctionID: auction.ID,
+		UserID:    req.UserID,
+		MaxAmount: maxAmount,
+		IsProxy:   req.MaxAmount > req.Amount,
+		IPAddress: req.IPAddress,
+		CreatedAt: now,
+	}
+	var rows []*models.AuctionBid
+	var outbidUserID int
+
+	switch {
+	case leader == nil:
+		bid.Amount = reservePrice(auction, minimum, maxAmount)
+		bid.IsWinning = true
+		rows = append(rows, bid)
+	case maxAmount > leader.maxAmount:
+		// The previous leader's proxy bids up to its maximum before the new
+		// bidder takes the lead one increment above it
+		if leader.maxAmount > leader.amount {
+			rows = append(rows, &models.AuctionBid{
+				AuctionID: auction.ID,
+				UserID:    leader.userID,
+				Amount:    leader.maxAmount,
+				MaxAmount: leader.maxAmount,
+				IsProxy:   true,
+				CreatedAt: now,
+			})
+		}
+		bid.Amount = reservePrice(auction, math.Min(maxAmount, leader.maxAmount+increment), maxAmount)
+		bid.IsWinning = true
+		rows = append(rows, bid)
+		outbidUserID = leader.userID
+	default:
+		// The leader's proxy outbids the new bid; ties go to the earlier bid
+		bid.Amount = maxAmount
+		rows = append(rows, bid, &models.AuctionBid{
+			AuctionID: auction.ID,
+			UserID:    leader.userID,
+			Amount:    reservePrice(auction, math.Min(leader.maxAmount, maxAmount+increment), leader.maxAmount),
+			MaxAmount: leader.maxAmount,
+			IsProxy:   true,
+			IsWinning: true,
+			CreatedAt: now,
+		})
+	}
+
+	winning := rows[len(rows)-1]
+	updated := *auction
+	updated.CurrentBid = winning.Amount
+	updated.TotalBids += len(rows)
+	updated.IsReserveMet = auction.ReservePrice <= 0 || winning.Amount >= auction.ReservePrice
+	extended := false
+	if window := e.softCloseWindow(auction); auction.AutoExtend && auction.EndTime.Sub(now) < window {
+		updated.EndTime = now.Add(window)
+		extended = true
+	}
+	updated.UpdatedAt = now
+
+	err = e.inTransaction(func(tx database.Transaction) error {
+		if err := insertBids(tx, rows); err != nil {
+			return err
+		}
+		return updateAuction(tx, auction, &updated)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to place bid: %w", err)
+	}
+
+	e.publishBid(&updated, extended)
+	if outbidUserID != 0 {
+		e.notifyOutbid(&updated, outbidUserID)
+	}
+
+	return &BidResult{Auction: &updated, Bid: bid, Leading: bid.IsWinning, Extended: extended}, nil
+}
+
+// raiseMaximum raises the leading bidder's proxy maximum. The current bid
+// only moves when the new maximum reaches an unmet reserve.
+func (e *AuctionEngine) raiseMaximum(auction *models.Auction, leader *leadingBid, maxAmount float64, now time.Time) (*BidResult, error) {
+	if maxAmount <= leader.maxAmount {
+		return nil, ErrAlreadyLeading
+	}
+
+	bid := &models.AuctionBid{
+		ID:        leader.id,
+		AuctionID: auction.ID,
+		UserID:    leader.userID,
+		Amount:    leader.amount,
+		MaxAmount: maxAmount,
+		IsProxy:   true,
+		IsWinning: true,
+		CreatedAt: now,
+	}
+	updated := *auction
+	var rows []*models.AuctionBid
+	if auction.ReservePrice > 0 && !auction.IsReserveMet && maxAmount >= auction.ReservePrice {
+		bid = &models.AuctionBid{
+			AuctionID: auction.ID,
+			UserID:    leader.userID,
+			Amount:    auction.ReservePrice,
+			MaxAmount: maxAmount,
+			IsProxy:   true,
+			IsWinning: true,
+			CreatedAt: now,
+		}
+		rows = append(rows, bid)
+		updated.CurrentBid = bid.Amount
+		updated.TotalBids++
+		updated.IsReserveMet = true
+		updated.UpdatedAt = now
+	}
+
+	err := e.inTransaction(func(tx database.Transaction) error {
+		if len(rows) == 0 {
+			result, err := tx.Exec(`UPDATE auction_bids SET max_amount = ?, is_proxy = ? WHERE id = ? AND is_winning = ?`,
+				maxAmount, true, leader.id, true)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+				return ErrConcurrentAuctionOp
+			}
+			return nil
+		}
+		if err := insertBids(tx, rows); err != nil {
+			return err
+		}
+		return updateAuction(tx, auction, &updated)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to raise maximum bid: %w", err)
+	}
+
+	if len(rows) > 0 {
+		e.publishBid(&updated, false)
+	}
+	return &BidResult{Auction: &updated, Bid: bid, Leading: true}, nil
+}
+
+// BuyNow ends the auction at its buy-it-now price and creates the buyer's
+// order. Buy it now is available until the bidding reaches the reserve
+// price, or until the first bid on auctions without a reserve.
+func (e *AuctionEngine) BuyNow(auctionID, userID int) (*models.Order, error) {
+	unlock := e.lock(auctionID)
+	defer unlock()
+
+	auction, err := e.loadAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if err := checkBiddable(auction, now); err != nil {
+		return nil, err
+	}
+	if !buyNowAvailable(auction) {
+		return nil, ErrBuyNowUnavailable
+	}
+	if auction.VendorID > 0 && e.isVendorUser(auction.VendorID, userID) {
+		return nil, ErrSelfBidding
+	}
+	leader, err := e.loadLeadingBid(auction.ID)
+	if err != nil {
+		return nil, err
+	}
+	sale, err := e.loadSale(auction)
+	if err != nil {
+		return nil, err
+	}
+
+	bid := &models.AuctionBid{
+		AuctionID: auction.ID,
+		UserID:    userID,
+		Amount:    auction.BuyNowPrice,
+		MaxAmount: auction.BuyNowPrice,
+		IsWinning: true,
+		CreatedAt: now,
+	}
+	winnerID := userID
+	updated := *auction
+	updated.CurrentBid = bid.Amount
+	updated.TotalBids++
+	updated.IsReserveMet = true
+	updated.Status = "ended"
+	updated.WinnerID = &winnerID
+	updated.EndTime = now
+	updated.UpdatedAt = now
+
+	var order *models.Order
+	err = e.inTransaction(func(tx database.Transaction) error {
+		if err := insertBids(tx, []*models.AuctionBid{bid}); err != nil {
+			return err
+		}
+		if err := updateAuction(tx, auction, &updated); err != nil {
+			return err
+		}
+		var err error
+		order, err = createAuctionOrder(tx, &updated, sale, e.config.Currency, now, now.Add(e.config.PaymentWindow))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to buy auction %d: %w", auctionID, err)
+	}
+	e.saleCommitted(sale)
+
+	e.publishEnded(&updated)
+	if leader != nil && leader.userID != userID {
+		e.notifyOutbid(&updated, leader.userID)
+	}
+	e.notifyWon(&updated, order)
+
+	return order, nil
+}
+
+// EndAuction closes an auction. If the highest bid meets the reserve price,
+// the highest bidder wins and an order awaiting payment is created for
+// them; otherwise the auction ends without a winner and the returned order
+// is nil.
+func (e *AuctionEngine) EndAuction(auctionID int) (*models.Order, error) {
+	return e.endAuction(auctionID, false)
+}
+
+// EndExpiredAuction ends an auction like EndAuction, but only once its end
+// time has passed. An auction extended by a late bid is left running.
+func (e *AuctionEngine) EndExpiredAuction(auctionID int) (*models.Order, error) {
+	return e.endAuction(auctionID, true)
+}
+
+func (e *AuctionEngine) endAuction(auctionID int, onlyExpired bool) (*models.Order, error) {
+	unlock := e.lock(auctionID)
+	defer unlock()
+
+	auction, err := e.loadAuction(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != "active" {
+		return nil, ErrAuctionNotActive
+	}
+	if onlyExpired && time.Now().Before(auction.EndTime) {
+		return nil, nil
+	}
+	leader, err := e.loadLeadingBid(auction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updated := *auction
+	updated.Status = "ended"
+	updated.UpdatedAt = now
+	hasWinner := leader != nil && (auction.ReservePrice <= 0 || leader.amount >= auction.ReservePrice)
+
+	var sale *auctionSale
+	if hasWinner {
+		winnerID := leader.userID
+		updated.WinnerID = &winnerID
+		updated.CurrentBid = leader.amount
+		if sale, err = e.loadSale(auction); err != nil {
+			return nil, err
+		}
+	}
+
+	var order *models.Order
+	err = e.inTransaction(func(tx database.Transaction) error {
+		if err := updateAuction(tx, auction, &updated); err != nil {
+			return err
+		}
+		if !hasWinner {
+			return nil
+		}
+		var err error
+		order, err = createAuctionOrder(tx, &updated, sale, e.config.Currency, now, now.Add(e.config.PaymentWindow))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to end auction %d: %w", auctionID, err)
+	}
+	if order != nil {
+		e.saleCommitted(sale)
+	}
+
+	e.publishEnded(&updated)
+	if order != nil {
+		e.notifyWon(&updated, order)
+	}
+
+	return order, nil
+}
+
+// saleCommitted drops the cached reads a committed sale changed
+func (e *AuctionEngine) saleCommitted(sale *auctionSale) {
+	ordersChanged(e.config.Cache)
+	if sale.product != nil {
+		productsChanged(e.config.Cache, int64(sale.product.ID))
+	}
+}
+
+// createAuctionOrder creates the winner's order awaiting payment and
+// reserves the auctioned unit until expiresAt. The order is paid and
+// confirmed through the regular checkout payment flow; if it is not paid in
+// time, the reservation expires and the unit goes back into stock.
+func createAuctionOrder(tx database.Transaction, auction *models.Auction, sale *auctionSale, currency string, now, expiresAt time.Time) (*models.Order, error) {
+	price := roundMoney(auction.CurrentBid)
+	order := &models.Order{
+		UserID:         int64(*auction.WinnerID),
+		VendorID:       int64(auction.VendorID),
+		OrderNumber:    fmt.Sprintf("AUC-%06d", auction.ID),
+		Status:         models.OrderStatusPending,
+		PaymentStatus:  "pending",
+		SubtotalAmount: price,
+		TotalAmount:    price,
+		Currency:       currency,
+		Notes:          fmt.Sprintf("Won in auction #%d: %s", auction.ID, auction.Title),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := insertOrder(tx, order); err != nil {
+		return nil, err
+	}
+
+	item := models.OrderItem{
+		OrderID:     order.ID,
+		VendorID:    order.VendorID,
+		ProductName: auction.Title,
+		Quantity:    1,
+		UnitPrice:   price,
+		TotalPrice:  price,
+		Commission:  roundMoney(price * sale.commissionRate / 100),
+		Status:      "pending",
+	}
+	if product := sale.product; product != nil {
+		result, err := tx.Exec(`
+			UPDATE products SET
+				status = CASE WHEN stock <= 1 THEN ? ELSE status END,
+				stock = stock - 1,
+				updated_at = ?
+			WHERE id = ? AND stock >= 1`,
+			ProductStatusOutOfStock, now, product.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take stock for product %d: %w", product.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return nil, fmt.Errorf("%w for product %d", ErrInsufficientStock, product.ID)
+		}
+		item.ProductID = int64(product.ID)
+		item.ProductName = product.Name
+		item.ProductSKU = product.SKU
+
+		_, err = tx.Exec(`
+			INSERT INTO stock_reservations (id, order_id, product_id, quantity, status, expires_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), order.ID, product.ID, 1, ReservationStatusReserved, expiresAt, now, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stock reservation: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO order_items (order_id, product_id, vendor_id, product_name, product_sku,
+			quantity, unit_price, total_price, commission, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.OrderID, item.ProductID, item.VendorID, item.ProductName, item.ProductSKU,
+		item.Quantity, item.UnitPrice, item.TotalPrice, item.Commission, item.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add order item: %w", err)
+	}
+	item.ID, _ = result.LastInsertId()
+	order.Items = append(order.Items, item)
+
+	return order, nil
+}
+
+// inTransaction runs fn in a transaction and commits it if fn succeeds
+func (e *AuctionEngine) inTransaction(fn func(tx database.Transaction) error) error {
+	tx, err := e.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin auction transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit auction transaction: %w", err)
+	}
+	return nil
+}
+
+// insertBids writes bid rows in order. Only the last row may be winning;
+// writing it clears the previous winning bid.
+func insertBids(tx database.Transaction, bids []*models.AuctionBid) error {
+	for _, bid := range bids {
+		if bid.IsWinning {
+			if _, err := tx.Exec(`UPDATE auction_bids SET is_winning = ? WHERE auction_id = ? AND is_winning = ?`,
+				false, bid.AuctionID, true); err != nil {
+				return fmt.Errorf("failed to clear winning bid: %w", err)
+			}
+		}
+		result, err := tx.Exec(`
+			INSERT INTO auction_bids (auction_id, user_id, amount, is_winning, is_proxy, max_amount, ip_address, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			bid.AuctionID, bid.UserID, bid.Amount, bid.IsWinning, bid.IsProxy, bid.MaxAmount, bid.IPAddress, bid.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert bid: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		bid.ID = int(id)
+	}
+	return nil
+}
+
+// updateAuction writes the bidding state of an auction. The update only
+// applies if the auction is still active with the bid count it was loaded
+// with, which rejects writes racing with another instance.
+func updateAuction(tx database.Transaction, loaded, updated *models.Auction) error {
+	var winnerID interface{}
+	if updated.WinnerID != nil {
+		winnerID = *updated.WinnerID
+	}
+	result, err := tx.Exec(`
+		UPDATE auctions SET current_bid = ?, total_bids = ?, is_reserve_met = ?, end_time = ?,
+			status = ?, winner_id = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND total_bids = ?`,
+		updated.CurrentBid, updated.TotalBids, updated.IsReserveMet, updated.EndTime,
+		updated.Status, winnerID, updated.UpdatedAt,
+		loaded.ID, "active", loaded.TotalBids)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrConcurrentAuctionOp
+	}
+	return nil
+}
+
+// loadAuction reads the bidding state of an auction
+func (e *AuctionEngine) loadAuction(auctionID int) (*models.Auction, error) {
+	var a models.Auction
+	var winnerID sql.NullInt64
+	err := e.repo.QueryRow(`
+		SELECT id, vendor_id, COALESCE(product_id, 0), title, starting_price, COALESCE(reserve_price, 0),
+			COALESCE(current_bid, 0), bid_increment, COALESCE(buy_now_price, 0), start_time, end_time,
+			status, winner_id, COALESCE(total_bids, 0), COALESCE(is_reserve_met, FALSE),
+			COALESCE(auto_extend, FALSE), COALESCE(extend_minutes, 0)
+		FROM auctions WHERE id = ?`, auctionID).Scan(
+		&a.ID, &a.VendorID, &a.ProductID, &a.Title, &a.StartingPrice, &a.ReservePrice,
+		&a.CurrentBid, &a.BidIncrement, &a.BuyNowPrice, &a.StartTime, &a.EndTime,
+		&a.Status, &winnerID, &a.TotalBids, &a.IsReserveMet,
+		&a.AutoExtend, &a.ExtendMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction %d: %w", auctionID, err)
+	}
+	if winnerID.Valid {
+		id := int(winnerID.Int64)
+		a.WinnerID = &id
+	}
+	return &a, nil
+}
+
+// loadLeadingBid returns the current winning bid, or nil if there is none
+func (e *AuctionEngine) loadLeadingBid(auctionID int) (*leadingBid, error) {
+	var b leadingBid
+	err := e.repo.QueryRow(`
+		SELECT id, user_id, amount, COALESCE(max_amount, 0) FROM auction_bids
+		WHERE auction_id = ? AND is_winning = ?
+		ORDER BY id DESC LIMIT 1`, auctionID, true).Scan(&b.id, &b.userID, &b.amount, &b.maxAmount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leading bid: %w", err)
+	}
+	if b.maxAmount < b.amount {
+		b.maxAmount = b.amount
+	}
+	return &b, nil
+}
+
+// loadSale reads what the winner's order needs before the order
+// transaction starts
+func (e *AuctionEngine) loadSale(auction *models.Auction) (*auctionSale, error) {
+	rate, err := vendorCommissionRate(e.repo, int64(auction.VendorID))
+	if err != nil {
+		return nil, err
+	}
+	sale := &auctionSale{commissionRate: rate}
+	if auction.ProductID <= 0 {
+		return sale, nil
+	}
+
+	var p models.Product
+	err = e.repo.QueryRow(`SELECT id, name, COALESCE(sku, '') FROM products WHERE id = ?`, auction.ProductID).
+		Scan(&p.ID, &p.Name, &p.SKU)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sale, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auction product: %w", err)
+	}
+	sale.product = &p
+	return sale, nil
+}
+
+// isVendorUser reports whether the user owns the vendor
+func (e *AuctionEngine) isVendorUser(vendorID, userID int) bool {
+	var ownerID int
+	err := e.repo.QueryRow(`SELECT user_id FROM vendors WHERE id = ?`, vendorID).Scan(&ownerID)
+	return err == nil && ownerID == userID
+}
+
+// softCloseWindow returns how long before the end a bid extends an auction
+func (e *AuctionEngine) softCloseWindow(auction *models.Auction) time.Duration {
+	if auction.ExtendMinutes > 0 {
+		return time.Duration(auction.ExtendMinutes) * time.Minute
+	}
+	return e.config.SoftCloseWindow
+}
+
+// checkBiddable rejects bids on auctions that are not running
+func checkBiddable(auction *models.Auction, now time.Time) error {
+	if auction.Status != "active" || now.Before(auction.StartTime) {
+		return ErrAuctionNotActive
+	}
+	if !now.Before(auction.EndTime) {
+		return ErrAuctionEnded
+	}
+	return nil
+}
+
+// minimumBid returns the lowest acceptable bid
+func minimumBid(auction *models.Auction, hasBids bool) float64 {
+	if !hasBids {
+		return roundMoney(auction.StartingPrice)
+	}
+	return roundMoney(auction.CurrentBid + bidIncrement(auction))
+}
+
+// bidIncrement returns the auction's bid increment
+func bidIncrement(auction *models.Auction) float64 {
+	if auction.BidIncrement > 0 {
+		return auction.BidIncrement
+	}
+	return 1
+}
+
+// reservePrice lifts a bid to the unmet reserve price when the bidder's
+// maximum reaches it
+func reservePrice(auction *models.Auction, amount, maxAmount float64) float64 {
+	if auction.ReservePrice > 0 && amount < auction.ReservePrice && maxAmount >= auction.ReservePrice {
+		return roundMoney(auction.ReservePrice)
+	}
+	return roundMoney(amount)
+}
+
+// buyNowAvailable reports whether the auction can still be bought outright
+func buyNowAvailable(auction *models.Auction) bool {
+	if auction.BuyNowPrice <= 0 || auction.CurrentBid >= auction.BuyNowPrice {
+		return false
+	}
+	if auction.ReservePrice > 0 {
+		return !auction.IsReserveMet
+	}
+	return auction.TotalBids == 0
+}
+
+// publishBid pushes the new auction state to the auction's channel
+func (e *AuctionEngine) publishBid(auction *models.Auction, extended bool) {
+	e.publish(ChannelAuctionPrefix+fmt.Sprintf("%d", auction.ID), 0, MessageTypeAuctionBid, map[string]interface{}{
+		"auction_id":     auction.ID,
+		"current_bid":    auction.CurrentBid,
+		"total_bids":     auction.TotalBids,
+		"is_reserve_met": auction.IsReserveMet,
+		"end_time":       auction.EndTime,
+		"extended":       extended,
+	})
+}
+
+// publishEnded pushes the result of an auction to the auction's channel
+func (e *AuctionEngine) publishEnded(auction *models.Auction) {
+	e.publish(ChannelAuctionPrefix+fmt.Sprintf("%d", auction.ID), 0, MessageTypeAuctionEnded, map[string]interface{}{
+		"auction_id":     auction.ID,
+		"current_bid":    auction.CurrentBid,
+		"total_bids":     auction.TotalBids,
+		"is_reserve_met": auction.IsReserveMet,
+		"has_winner":     auction.WinnerID != nil,
+	})
+}
+
+// notifyOutbid tells a bidder they no longer lead the auction
+func (e *AuctionEngine) notifyOutbid(auction *models.Auction, userID int) {
+	e.publish(ChannelUserPrefix+fmt.Sprintf("%d", userID), userID, MessageTypeOutbid, map[string]interface{}{
+		"auction_id":  auction.ID,
+		"title":       auction.Title,
+		"current_bid": auction.CurrentBid,
+		"end_time":    auction.EndTime,
+	})
+	e.notify("auction_outbid", userID, map[string]interface{}{
+		"AuctionID":    auction.ID,
+		"AuctionTitle": auction.Title,
+		"CurrentBid":   fmt.Sprintf("%.2f", auction.CurrentBid),
+	})
+}
+
+// notifyWon tells the winner about their order
+func (e *AuctionEngine) notifyWon(auction *models.Auction, order *models.Order) {
+	userID := int(order.UserID)
+	e.publish(ChannelUserPrefix+fmt.Sprintf("%d", userID), userID, MessageTypeAuctionWon, map[string]interface{}{
+		"auction_id":   auction.ID,
+		"title":        auction.Title,
+		"amount":       order.TotalAmount,
+		"order_id":     order.ID,
+		"order_number": order.OrderNumber,
+	})
+	e.notify("auction_won", userID, map[string]interface{}{
+		"AuctionID":    auction.ID,
+		"AuctionTitle": auction.Title,
+		"Amount":       fmt.Sprintf("%.2f %s", order.TotalAmount, order.Currency),
+		"OrderNumber":  order.OrderNumber,
+	})
+}
+
+// publish sends an event over the WebSocket service. Delivery is best
+// effort; the auction state in the database is authoritative.
+func (e *AuctionEngine) publish(channel string, userID int, messageType string, data map[string]interface{}) {
+	ws := e.config.WebSocketService
+	if ws == nil {
+		return
+	}
+	message := &Message{
+		Type:      messageType,
+		Channel:   channel,
+		UserID:    int64(userID),
+		Data:      data,
+		Timestamp: time.Now(),
+		MessageID: ws.generateMessageID(),
+	}
+	if err := ws.SendToChannel(channel, message); err != nil {
+		e.logger.Printf("failed to publish %s to %s: %v", messageType, channel, err)
+	}
+}
+
+// notify sends a transactional notification
+func (e *AuctionEngine) notify(notificationType string, userID int, variables map[string]interface{}) {
+	if e.config.NotificationService == nil || userID <= 0 {
+		return
+	}
+	if err := e.config.NotificationService.SendTransactionalNotification(notificationType, uint(userID), variables); err != nil {
+		e.logger.Printf("failed to send %s notification to user %d: %v", notificationType, userID, err)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+func newTestEngine(t *testing.T) (*AuctionEngine, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	engine, err := NewAuctionEngine(repo, AuctionEngineConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine, repo
+}
+
+// seedAuction inserts an active auction of productID ending in endsIn
+func seedAuction(t *testing.T, repo database.SimpleRepository, vendorID, productID int64, auction models.Auction, endsIn time.Duration) int {
+	t.Helper()
+	now := time.Now().UTC()
+	return int(mustExec(t, repo, `
+		INSERT INTO auctions (vendor_id, product_id, title, starting_price, reserve_price, bid_increment, buy_now_price,
+			start_time, end_time, status, auto_extend, extend_minutes)
+		VALUES (?, ?, 'Antika saat', ?, ?, ?, ?, ?, ?, 'active', ?, ?)`,
+		vendorID, productID, auction.StartingPrice, auction.ReservePrice, auction.BidIncrement, auction.BuyNowPrice,
+		now.Add(-time.Hour), now.Add(endsIn), auction.AutoExtend, auction.ExtendMinutes))
+}
+
+func TestMinimumBid(t *testing.T) {
+	tests := []struct {
+		name    string
+		auction models.Auction
+		hasBids bool
+		want    float64
+	}{
+		{"first bid", models.Auction{StartingPrice: 100, CurrentBid: 0, BidIncrement: 5}, false, 100},
+		{"next bid", models.Auction{StartingPrice: 100, CurrentBid: 120, BidIncrement: 5}, true, 125},
+		{"default increment", models.Auction{StartingPrice: 100, CurrentBid: 120}, true, 121},
+		{"cents", models.Auction{StartingPrice: 9.99, CurrentBid: 10.005, BidIncrement: 0.5}, true, 10.51},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minimumBid(&tt.auction, tt.hasBids); got != tt.want {
+				t.Fatalf("minimumBid = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservePrice(t *testing.T) {
+	tests := []struct {
+		name      string
+		reserve   float64
+		amount    float64
+		maxAmount float64
+		want      float64
+	}{
+		{"no reserve", 0, 110, 500, 110},
+		{"maximum below reserve", 300, 110, 250, 110},
+		{"maximum reaches reserve", 300, 110, 300, 300},
+		{"amount above reserve", 300, 320, 500, 320},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auction := models.Auction{ReservePrice: tt.reserve}
+			if got := reservePrice(&auction, tt.amount, tt.maxAmount); got != tt.want {
+				t.Fatalf("reservePrice = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceBidProxyAndSoftClose(t *testing.T) {
+	engine, repo := newTestEngine(t)
+	vendorID := seedVendor(t, repo, 10)
+	productID := seedProduct(t, repo, vendorID, 100, 1)
+	auctionID := seedAuction(t, repo, vendorID, productID,
+		models.Auction{StartingPrice: 100, BidIncrement: 10, AutoExtend: true, ExtendMinutes: 10}, 2*time.Minute)
+	alice, bob := int(seedUser(t, repo)), int(seedUser(t, repo))
+
+	first, err := engine.PlaceBid(&BidRequest{AuctionID: auctionID, UserID: alice, Amount: 100, MaxAmount: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Leading || first.Auction.CurrentBid != 100 || !first.Extended {
+		t.Fatalf("first bid = %+v, auction %+v", first, first.Auction)
+	}
+
+	// Alice's proxy outbids Bob one increment above his bid
+	second, err := engine.PlaceBid(&BidRequest{AuctionID: auctionID, UserID: bob, Amount: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Leading || second.Auction.CurrentBid != 160 || second.Auction.TotalBids != 3 {
+		t.Fatalf("second bid leading %v at %.2f after %d bids", second.Leading, second.Auction.CurrentBid, second.Auction.TotalBids)
+	}
+
+	if _, err := engine.PlaceBid(&BidRequest{AuctionID: auctionID, UserID: bob, Amount: 165}); !errors.Is(err, ErrBidTooLow) {
+		t.Fatalf("bid below the minimum: got %v, want ErrBidTooLow", err)
+	}
+	third, err := engine.PlaceBid(&BidRequest{AuctionID: auctionID, UserID: bob, Amount: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !third.Leading || third.Auction.CurrentBid != 210 {
+		t.Fatalf("third bid leading %v at %.2f, want leading at 210", third.Leading, third.Auction.CurrentBid)
+	}
+
+	var vendorUserID int
+	if err := repo.QueryRow(`SELECT user_id FROM vendors WHERE id = ?`, vendorID).Scan(&vendorUserID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.PlaceBid(&BidRequest{AuctionID: auctionID, UserID: vendorUserID, Amount: 500}); !errors.Is(err, ErrSelfBidding) {
+		t.Fatalf("vendor bid: got %v, want ErrSelfBidding", err)
+	}
+}
+
+func TestUnpaidAuctionOrderReleasesStock(t *testing.T) {
+	engine, repo := newTestEngine(t)
+	vendorID := seedVendor(t, repo, 10)
+	productID := seedProduct(t, repo, vendorID, 100, 1)
+	auctionID := seedAuction(t, repo, vendorID, productID,
+		models.Auction{StartingPrice: 100, BidIncrement: 10, BuyNowPrice: 400}, time.Hour)
+	buyer := int(seedUser(t, repo))
+
+	order, err := engine.BuyNow(auctionID, buyer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.TotalAmount != 400 || order.Status != models.OrderStatusPending {
+		t.Fatalf("order = %+v", order)
+	}
+	if stock := productStock(t, repo, productID); stock != 0 {
+		t.Fatalf("stock = %d, want the unit held for the winner", stock)
+	}
+	if _, err := engine.BuyNow(auctionID, buyer); !errors.Is(err, ErrAuctionNotActive) {
+		t.Fatalf("second purchase: got %v, want ErrAuctionNotActive", err)
+	}
+
+	paymentService := NewPaymentService(repo)
+	checkout, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservations, err := checkout.GetReservations(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].ExpiresAt.Before(time.Now().Add(47*time.Hour)) {
+		t.Fatalf("reservations = %+v, want one held for the payment window", reservations)
+	}
+
+	// Nothing is released while the winner can still pay
+	if released, err := checkout.ReleaseExpiredReservations(); err != nil || released != 0 {
+		t.Fatalf("released %d orders early (err %v)", released, err)
+	}
+	mustExec(t, repo, `UPDATE stock_reservations SET expires_at = ? WHERE order_id = ?`, time.Now().UTC().Add(-time.Minute), order.ID)
+	released, err := checkout.ReleaseExpiredReservations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Fatalf("released %d orders, want 1", released)
+	}
+	if status, _ := orderStatus(t, repo, order.ID); status != models.OrderStatusCancelled {
+		t.Fatalf("unpaid order is %s, want cancelled", status)
+	}
+	if stock := productStock(t, repo, productID); stock != 1 {
+		t.Fatalf("stock = %d, want the unit back", stock)
+	}
+}
+
+func TestAuctionServiceRequiresEngine(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	s := NewAuctionService(repo)
+	bid := &models.AuctionBid{AuctionID: 1, UserID: 1, Amount: 100}
+	if err := s.PlaceBid(bid); !errors.Is(err, ErrAuctionEngineNotConfigured) {
+		t.Fatalf("got %v, want ErrAuctionEngineNotConfigured", err)
+	}
+}
@@ -4,17 +4,27 @@ import (
 	"fmt"
 	"kolajAi/internal/database"
 	"kolajAi/internal/models"
+	"sync"
 	"time"
 )
 
 type AuctionService struct {
-	repo database.SimpleRepository
+	repo   database.SimpleRepository
+	mu     sync.Mutex
+	engine *AuctionEngine
+	// cursors signs the cursors of bid pages
+	cursors *database.CursorCodec
 }
 
 func NewAuctionService(repo database.SimpleRepository) *AuctionService {
 	return &AuctionService{repo: repo}
 }
 
+// SetCursorCodec sets the codec that signs the cursors of bid pages
+func (s *AuctionService) SetCursorCodec(codec *database.CursorCodec) {
+	s.cursors = codec
+}
+
 // GetActiveAuctions retrieves active auctions
 func (s *AuctionService) GetActiveAuctions(limit int) ([]models.Auction, error) {
 	var auctions []models.Auction
@@ -116,6 +126,24 @@ func (s *AuctionService) GetAuctionBids(auctionID int, limit, offset int) ([]mod
 	return bids, nil
 }
 
+// GetAuctionBidsPage returns a keyset page of an auction's bids, highest
+// first
+func (s *AuctionService) GetAuctionBidsPage(auctionID int, params database.CursorPaginationParams) ([]models.AuctionBid, database.CursorPaginationResult, error) {
+	keyset := database.Keyset{Scope: fmt.Sprintf("auction_bids:%d", auctionID), Column: "amount", Order: database.Descending}
+	conditions := map[string]interface{}{"auction_id": auctionID}
+	bids, result, err := database.Paginate(cursorCodec(s.cursors), keyset, params,
+		func(cursor *database.Cursor, limit int) ([]models.AuctionBid, error) {
+			var bids []models.AuctionBid
+			err := s.repo.FindAllKeyset("auction_bids", &bids, conditions, keyset, cursor, limit)
+			return bids, err
+		},
+		func(bid models.AuctionBid) (interface{}, int64) { return bid.Amount, int64(bid.ID) })
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to get auction bids page: %w", err)
+	}
+	return bids, result, nil
+}
+
 // GetAuctionsByVendor retrieves auctions by vendor ID
 func (s *AuctionService) GetAuctionsByVendor(vendorID int, limit, offset int) ([]models.Auction, error) {
 	var auctions []models.Auction
@@ -161,23 +189,25 @@ func (s *AuctionService) StartAuction(auctionID int) error {
 	return s.UpdateAuction(auctionID, auction)
 }
 
-// EndAuction ends an auction
+// EndAuction ends an auction through the auction engine. The highest
+// bidder wins if the reserve price is met, and an order is created for
+// them.
 func (s *AuctionService) EndAuction(auctionID int) error {
-	auction, err := s.GetAuctionByID(auctionID)
+	engine, err := s.getEngine()
 	if err != nil {
 		return err
 	}
+	_, err = engine.EndAuction(auctionID)
+	return err
+}
 
-	// Get the winning bid
-	winningBid, err := s.GetWinningBid(auctionID)
-	if err == nil && winningBid != nil {
-		auction.WinnerID = &winningBid.UserID
+// BuyNow buys an auction at its buy-it-now price and returns the order
+func (s *AuctionService) BuyNow(auctionID, userID int) (*models.Order, error) {
+	engine, err := s.getEngine()
+	if err != nil {
+		return nil, err
 	}
-
-	auction.Status = "ended"
-	auction.UpdatedAt = time.Now()
-
-	return s.UpdateAuction(auctionID, auction)
+	return engine.BuyNow(auctionID, userID)
 }
 
 // CancelAuction cancels an auction
@@ -189,87 +219,45 @@ func (s *AuctionService) CancelAuction(auctionID int) error {
 	return s.UpdateAuction(auctionID, auction)
 }
 
-// PlaceBid places a bid on an auction
+// PlaceBid places a bid on an auction through the auction engine. A
+// MaxAmount above Amount makes it a proxy bid. On success bid is updated
+// with the stored bid.
 func (s *AuctionService) PlaceBid(bid *models.AuctionBid) error {
-	// Get auction details
-	auction, err := s.GetAuctionByID(bid.AuctionID)
+	engine, err := s.getEngine()
 	if err != nil {
 		return err
 	}
 
-	// Validate bid
-	if err := s.validateBid(auction, bid); err != nil {
-		return err
-	}
-
-	// Mark previous bids as not winning
-	err = s.markPreviousBidsAsLosing(bid.AuctionID)
+	result, err := engine.PlaceBid(&BidRequest{
+		AuctionID: bid.AuctionID,
+		UserID:    bid.UserID,
+		Amount:    bid.Amount,
+		MaxAmount: bid.MaxAmount,
+		IPAddress: bid.IPAddress,
+	})
 	if err != nil {
 		return err
 	}
-
-	// Create the bid
-	bid.CreatedAt = time.Now()
-	bid.IsWinning = true
-
-	id, err := s.repo.CreateStruct("auction_bids", bid)
-	if err != nil {
-		return fmt.Errorf("failed to place bid: %w", err)
-	}
-	bid.ID = int(id)
-
-	// Update auction
-	auction.CurrentBid = bid.Amount
-	auction.TotalBids++
-	if auction.ReservePrice > 0 && bid.Amount >= auction.ReservePrice {
-		auction.IsReserveMet = true
-	}
-
-	// Auto-extend if needed
-	if auction.AutoExtend && time.Until(auction.EndTime) < time.Duration(auction.ExtendMinutes)*time.Minute {
-		auction.EndTime = auction.EndTime.Add(time.Duration(auction.ExtendMinutes) * time.Minute)
-	}
-
-	return s.UpdateAuction(bid.AuctionID, auction)
-}
-
-// validateBid validates a bid
-func (s *AuctionService) validateBid(auction *models.Auction, bid *models.AuctionBid) error {
-	// Check if auction is active
-	if auction.Status != "active" {
-		return fmt.Errorf("auction is not active")
-	}
-
-	// Check if auction has ended
-	if time.Now().After(auction.EndTime) {
-		return fmt.Errorf("auction has ended")
-	}
-
-	// Check if bid meets minimum increment
-	minBid := auction.CurrentBid + auction.BidIncrement
-	if bid.Amount < minBid {
-		return fmt.Errorf("bid must be at least %.2f", minBid)
-	}
-
+	*bid = *result.Bid
 	return nil
 }
 
-// markPreviousBidsAsLosing marks all previous bids as not winning
-func (s *AuctionService) markPreviousBidsAsLosing(auctionID int) error {
-	// In a real implementation, this would be a single SQL UPDATE query
-	bids, err := s.GetAuctionBids(auctionID, 0, 0)
-	if err != nil {
-		return err
-	}
+// SetEngine sets the auction engine bids and auction endings go through
+func (s *AuctionService) SetEngine(engine *AuctionEngine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.engine = engine
+}
 
-	for _, bid := range bids {
-		if bid.IsWinning {
-			bid.IsWinning = false
-			s.repo.Update("auction_bids", bid.ID, &bid)
-		}
+// getEngine returns the auction engine. Bids are refused until one has
+// been set, rather than processed without bid events and notifications.
+func (s *AuctionService) getEngine() (*AuctionEngine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.engine == nil {
+		return nil, ErrAuctionEngineNotConfigured
 	}
-
-	return nil
+	return s.engine, nil
 }
 
 // GetWinningBid retrieves the winning bid for an auction
@@ -431,10 +419,17 @@ func (s *AuctionService) ProcessExpiredAuctions() error {
 		return fmt.Errorf("failed to get active auctions: %w", err)
 	}
 
+	engine, err := s.getEngine()
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	for _, auction := range auctions {
 		if auction.EndTime.Before(now) {
-			err := s.EndAuction(auction.ID)
+			// The engine checks the end time again, as a late bid may have
+			// extended the auction since it was listed
+			_, err := engine.EndExpiredAuction(auction.ID)
 			if err != nil {
 				fmt.Printf("Error ending auction %d: %v\n", auction.ID, err)
 			}
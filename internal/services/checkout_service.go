@@ -0,0 +1,1151 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Checkout errors
+var (
+	ErrEmptyCart           = errors.New("cart is empty")
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrProductUnavailable  = errors.New("product is not available for sale")
+	ErrCouponNotApplicable = errors.New("coupon is not applicable to this order")
+	ErrNoShippingRate      = errors.New("no shipping rate applies to this order")
+	ErrReservationExpired  = errors.New("stock reservation has expired")
+	ErrOrderNotPending     = errors.New("order is not awaiting payment")
+)
+
+// Stock reservation statuses
+const (
+	ReservationStatusReserved  = "reserved"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+)
+
+// CheckoutConfig holds checkout settings
+type CheckoutConfig struct {
+	ReservationTTL time.Duration
+	TaxRate        float64
+	Currency       string
+	// StateMachine confirms and cancels checked out orders. A default one
+	// without payment or notification hooks is created when nil.
+	StateMachine *OrderStateMachine
+	// Inventory, when set, pushes the stock reserved by checkouts to the
+	// marketplaces
+	Inventory *InventorySyncService
+	// PaymentCallbackURL is where the bank posts the result of a 3-D Secure
+	// challenge; the order ID is added as the order_id query parameter
+	PaymentCallbackURL string
+	// Cache, when set, drops the cached reads of the orders and products a
+	// checkout changes
+	Cache  *cache.EntityCache
+	Logger *log.Logger
+}
+
+// CheckoutService turns carts into orders. Stock is reserved in the same
+// database transaction that creates the order, so two checkouts can never
+// take the same unit.
+type CheckoutService struct {
+	repo           database.SimpleRepository
+	orderService   *OrderService
+	paymentService *PaymentService
+	stateMachine   *OrderStateMachine
+	config         CheckoutConfig
+	logger         *log.Logger
+}
+
+// CheckoutRequest holds everything needed to place an order from a cart
+type CheckoutRequest struct {
+	Cart          *models.Cart
+	UserID        int64
+	PaymentMethod PaymentMethod
+	Coupon        *models.Coupon
+	Discounts     []models.Discount
+	ShippingRates []models.ShippingRate
+
+	ShippingAddress string
+	ShippingCity    string
+	ShippingState   string
+	ShippingZip     string
+	ShippingCountry string
+	ShippingPhone   string
+
+	BillingAddress string
+	BillingCity    string
+	BillingState   string
+	BillingZip     string
+	BillingCountry string
+	BillingPhone   string
+
+	Notes string
+}
+
+// CheckoutResult is the outcome of a successful checkout. Carts with
+// products of several vendors produce a parent order whose SubOrders hold
+// the items of each vendor.
+type CheckoutResult struct {
+	Order                *models.Order `json:"order"`
+	ReservationExpiresAt time.Time     `json:"reservation_expires_at"`
+}
+
+// StockReservation holds units of a product for an unpaid order
+type StockReservation struct {
+	ID        string    `json:"id"`
+	OrderID   int64     `json:"order_id"`
+	ProductID int64     `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// checkoutLine is a cart line priced against the current product row
+type checkoutLine struct {
+	product  models.Product
+	quantity int
+	total    float64
+}
+
+// vendorLines are the checkout lines of one vendor
+type vendorLines struct {
+	vendorID       int64
+	commissionRate float64
+	lines          []checkoutLine
+	subtotal       float64
+}
+
+// NewCheckoutService creates a new checkout service
+func NewCheckoutService(repo database.SimpleRepository, orderService *OrderService, paymentService *PaymentService, config CheckoutConfig) (*CheckoutService, error) {
+	if config.ReservationTTL <= 0 {
+		config.ReservationTTL = 15 * time.Minute
+	}
+	if config.TaxRate < 0 {
+		config.TaxRate = 0
+	}
+	if config.Currency == "" {
+		config.Currency = "TRY"
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	stateMachine := config.StateMachine
+	if stateMachine == nil {
+		var err error
+		stateMachine, err = NewOrderStateMachine(repo, OrderStateMachineConfig{PaymentService: paymentService, Cache: config.Cache, Logger: logger})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &CheckoutService{
+		repo:           repo,
+		orderService:   orderService,
+		paymentService: paymentService,
+		stateMachine:   stateMachine,
+		config:         config,
+		logger:         logger,
+	}
+
+	return s, nil
+}
+
+// Checkout prices the cart, applies coupon, discounts, shipping and tax,
+// and creates the order with its items and stock reservations in a single
+// transaction. The reservations hold the stock until the payment is
+// confirmed, fails or the reservation TTL runs out. A cart with products of
+// several vendors is split into one sub-order per vendor under a parent
+// order that carries the totals and the payment.
+func (s *CheckoutService) Checkout(req *CheckoutRequest) (*CheckoutResult, error) {
+	if req.Cart == nil {
+		return nil, ErrEmptyCart
+	}
+	if req.UserID <= 0 {
+		return nil, errors.New("valid user ID is required")
+	}
+
+	cartItems := req.Cart.Items
+	if len(cartItems) == 0 && req.Cart.ID > 0 {
+		items, err := s.loadCartItems(req.Cart.ID)
+		if err != nil {
+			return nil, err
+		}
+		cartItems = items
+	}
+
+	lines, err := s.priceLines(cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtotal, weight float64
+	itemCount := 0
+	for _, line := range lines {
+		subtotal += line.total
+		weight += line.product.Weight * float64(line.quantity)
+		itemCount += line.quantity
+	}
+
+	discount, couponDiscount, freeShipping, err := s.calculateDiscount(req, lines, subtotal, itemCount)
+	if err != nil {
+		return nil, err
+	}
+	discountedSubtotal := subtotal - discount
+
+	shipping := 0.0
+	if !freeShipping {
+		shipping, err = s.calculateShipping(req, weight, discountedSubtotal, itemCount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tax := roundMoney(discountedSubtotal * s.config.TaxRate)
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.config.ReservationTTL)
+
+	order := &models.Order{
+		UserID:          req.UserID,
+		OrderNumber:     s.orderService.generateOrderNumber(),
+		Status:          models.OrderStatusPending,
+		PaymentStatus:   "pending",
+		PaymentMethod:   string(req.PaymentMethod),
+		SubtotalAmount:  roundMoney(subtotal),
+		TaxAmount:       tax,
+		ShippingAmount:  roundMoney(shipping),
+		DiscountAmount:  roundMoney(discount),
+		Currency:        s.config.Currency,
+		ShippingAddress: req.ShippingAddress,
+		ShippingCity:    req.ShippingCity,
+		ShippingState:   req.ShippingState,
+		ShippingZip:     req.ShippingZip,
+		ShippingCountry: req.ShippingCountry,
+		ShippingPhone:   req.ShippingPhone,
+		BillingAddress:  req.BillingAddress,
+		BillingCity:     req.BillingCity,
+		BillingState:    req.BillingState,
+		BillingZip:      req.BillingZip,
+		BillingCountry:  req.BillingCountry,
+		BillingPhone:    req.BillingPhone,
+		Notes:           req.Notes,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	order.TotalAmount = roundMoney(order.SubtotalAmount - order.DiscountAmount + order.ShippingAmount + order.TaxAmount)
+	if req.Coupon != nil {
+		order.CouponCode = req.Coupon.Code
+	}
+	if err := order.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groupByVendor(lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 1 {
+		order.VendorID = groups[0].vendorID
+	} else {
+		order.SubOrders = splitOrder(order, groups, req.Coupon, couponDiscount)
+	}
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin checkout transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if err := insertOrder(tx, order); err != nil {
+		return nil, err
+	}
+	if req.Coupon != nil {
+		if err := redeemCoupon(tx, req.Coupon, order, now); err != nil {
+			return nil, err
+		}
+	}
+	if len(order.SubOrders) == 0 {
+		if err := s.reserveLines(tx, order, groups[0], expiresAt, now); err != nil {
+			return nil, err
+		}
+	}
+	for i := range order.SubOrders {
+		sub := &order.SubOrders[i]
+		sub.ParentOrderID = order.ID
+		if err := insertOrder(tx, sub); err != nil {
+			return nil, err
+		}
+		if err := s.reserveLines(tx, sub, groups[i], expiresAt, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Cart.ID > 0 {
+		if _, err := tx.Exec(`DELETE FROM cart_items WHERE cart_id = ?`, req.Cart.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear cart: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit checkout: %w", err)
+	}
+	committed = true
+
+	productIDs := orderProductIDs(order.Items)
+	for _, sub := range order.SubOrders {
+		productIDs = append(productIDs, orderProductIDs(sub.Items)...)
+	}
+	ordersChanged(s.config.Cache)
+	productsChanged(s.config.Cache, productIDs...)
+	if s.config.Inventory != nil {
+		s.config.Inventory.StockChanged(StockChangeOrder, productIDs...)
+	}
+
+	return &CheckoutResult{Order: order, ReservationExpiresAt: expiresAt}, nil
+}
+
+// insertOrder writes an order and its first status history entry
+func insertOrder(tx database.Transaction, order *models.Order) error {
+	result, err := tx.Exec(`
+		INSERT INTO orders (user_id, vendor_id, parent_order_id, order_number, status, payment_status, payment_method,
+			subtotal, tax_amount, shipping_amount, discount_amount, total_amount, currency,
+			shipping_address, shipping_city, shipping_state, shipping_zip, shipping_country, shipping_phone,
+			billing_address, billing_city, billing_state, billing_zip, billing_country, billing_phone,
+			notes, coupon_code, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.UserID, order.VendorID, order.ParentOrderID, order.OrderNumber, order.Status, order.PaymentStatus, order.PaymentMethod,
+		order.SubtotalAmount, order.TaxAmount, order.ShippingAmount, order.DiscountAmount, order.TotalAmount, order.Currency,
+		order.ShippingAddress, order.ShippingCity, order.ShippingState, order.ShippingZip, order.ShippingCountry, order.ShippingPhone,
+		order.BillingAddress, order.BillingCity, order.BillingState, order.BillingZip, order.BillingCountry, order.BillingPhone,
+		order.Notes, order.CouponCode, order.CreatedAt, order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	order.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get order ID: %w", err)
+	}
+	return insertStatusHistory(tx, order.ID, "", order.Status, "order placed", order.UserID, order.CreatedAt)
+}
+
+// reserveLines takes the stock of a vendor's lines and adds them to the
+// order as items with stock reservations
+func (s *CheckoutService) reserveLines(tx database.Transaction, order *models.Order, group vendorLines, expiresAt, now time.Time) error {
+	for _, line := range group.lines {
+		// The stock check and the decrement are one statement, so concurrent
+		// checkouts cannot both take the last unit
+		result, err := tx.Exec(`
+			UPDATE products SET
+				status = CASE WHEN stock <= ? THEN ? ELSE status END,
+				stock = stock - ?,
+				updated_at = ?
+			WHERE id = ? AND stock >= ?`,
+			line.quantity, ProductStatusOutOfStock, line.quantity, now, line.product.ID, line.quantity)
+		if err != nil {
+			return fmt.Errorf("failed to reserve stock for product %d: %w", line.product.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("%w for product %d", ErrInsufficientStock, line.product.ID)
+		}
+
+		item := models.OrderItem{
+			OrderID:     order.ID,
+			ProductID:   int64(line.product.ID),
+			VendorID:    group.vendorID,
+			ProductName: line.product.Name,
+			ProductSKU:  line.product.SKU,
+			Quantity:    line.quantity,
+			UnitPrice:   line.product.Price,
+			TotalPrice:  roundMoney(line.total),
+			Commission:  roundMoney(line.total * group.commissionRate / 100),
+			Status:      "pending",
+		}
+		result, err = tx.Exec(`
+			INSERT INTO order_items (order_id, product_id, vendor_id, product_name, product_sku,
+				quantity, unit_price, total_price, commission, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.OrderID, item.ProductID, item.VendorID, item.ProductName, item.ProductSKU,
+			item.Quantity, item.UnitPrice, item.TotalPrice, item.Commission, item.Status)
+		if err != nil {
+			return fmt.Errorf("failed to add order item: %w", err)
+		}
+		item.ID, _ = result.LastInsertId()
+		order.Items = append(order.Items, item)
+
+		_, err = tx.Exec(`
+			INSERT INTO stock_reservations (id, order_id, product_id, quantity, status, expires_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), order.ID, line.product.ID, line.quantity, ReservationStatusReserved, expiresAt, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to create stock reservation: %w", err)
+		}
+	}
+	return nil
+}
+
+// groupByVendor groups the lines by vendor in cart order and looks up each
+// vendor's commission rate
+func (s *CheckoutService) groupByVendor(lines []checkoutLine) ([]vendorLines, error) {
+	var groups []vendorLines
+	index := make(map[int64]int)
+	for _, line := range lines {
+		vendorID := int64(line.product.VendorID)
+		i, ok := index[vendorID]
+		if !ok {
+			i = len(groups)
+			index[vendorID] = i
+			groups = append(groups, vendorLines{vendorID: vendorID})
+		}
+		groups[i].lines = append(groups[i].lines, line)
+		groups[i].subtotal += line.total
+	}
+
+	for i := range groups {
+		rate, err := vendorCommissionRate(s.repo, groups[i].vendorID)
+		if err != nil {
+			return nil, err
+		}
+		groups[i].commissionRate = rate
+	}
+
+	return groups, nil
+}
+
+// vendorCommissionRate returns the commission percentage charged to a
+// vendor. Unknown vendors pay no commission.
+func vendorCommissionRate(repo database.SimpleRepository, vendorID int64) (float64, error) {
+	var rate float64
+	err := repo.QueryRow(`SELECT COALESCE(commission_rate, 0) FROM vendors WHERE id = ?`, vendorID).Scan(&rate)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get commission rate of vendor %d: %w", vendorID, err)
+	}
+	return rate, nil
+}
+
+// splitOrder builds one sub-order per vendor. The coupon discount is split
+// by the vendors' share of the lines the coupon applies to, the other
+// discounts by what is left of each vendor's subtotal, and shipping and tax
+// by the vendors' share of the subtotal, so the sub-orders add up to the
+// parent order exactly.
+func splitOrder(parent *models.Order, groups []vendorLines, coupon *models.Coupon, couponDiscount float64) []models.Order {
+	weights := make([]float64, len(groups))
+	eligible := make([]float64, len(groups))
+	for i, g := range groups {
+		weights[i] = g.subtotal
+		if coupon == nil {
+			continue
+		}
+		for _, line := range g.lines {
+			if couponAllowsProduct(coupon, line.product) {
+				eligible[i] += line.total
+			}
+		}
+	}
+	couponDiscount = roundMoney(couponDiscount)
+	couponShares := allocateAmount(couponDiscount, eligible)
+	remaining := make([]float64, len(groups))
+	for i := range groups {
+		remaining[i] = weights[i] - couponShares[i]
+	}
+	otherShares := allocateAmount(roundMoney(parent.DiscountAmount-couponDiscount), remaining)
+	discounts := make([]float64, len(groups))
+	for i := range groups {
+		discounts[i] = roundMoney(couponShares[i] + otherShares[i])
+	}
+	shipping := allocateAmount(parent.ShippingAmount, weights)
+	taxes := allocateAmount(parent.TaxAmount, weights)
+
+	subs := make([]models.Order, len(groups))
+	for i, g := range groups {
+		sub := *parent
+		sub.VendorID = g.vendorID
+		sub.OrderNumber = fmt.Sprintf("%s-%d", parent.OrderNumber, i+1)
+		sub.SubtotalAmount = roundMoney(g.subtotal)
+		sub.DiscountAmount = discounts[i]
+		sub.ShippingAmount = shipping[i]
+		sub.TaxAmount = taxes[i]
+		sub.TotalAmount = roundMoney(sub.SubtotalAmount - sub.DiscountAmount + sub.ShippingAmount + sub.TaxAmount)
+		sub.Items = nil
+		sub.SubOrders = nil
+		subs[i] = sub
+	}
+	return subs
+}
+
+// allocateAmount splits amount by weight in whole cents. The rounding
+// remainder goes to the last share.
+func allocateAmount(amount float64, weights []float64) []float64 {
+	shares := make([]float64, len(weights))
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 || len(weights) == 0 {
+		return shares
+	}
+
+	allocated := 0.0
+	for i, w := range weights {
+		if i == len(weights)-1 {
+			shares[i] = roundMoney(amount - allocated)
+			break
+		}
+		shares[i] = roundMoney(amount * w / total)
+		allocated += shares[i]
+	}
+	return shares
+}
+
+// subOrderIDs returns the IDs of the per-vendor sub-orders of an order
+func (s *CheckoutService) subOrderIDs(orderID int64) ([]int64, error) {
+	rows, err := s.repo.Query(`SELECT id FROM orders WHERE parent_order_id = ? ORDER BY id ASC`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan sub-order: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// PayOrder charges a pending order through the payment service. A completed
+// payment commits the reservations, a failed one releases them. Pending
+// payments (redirects, bank transfers, 3-D Secure challenges) keep the
+// reservations until they are confirmed or expire. Card orders need the
+// card to charge.
+func (s *CheckoutService) PayOrder(order *models.Order, card *PaymentCard) (*PaymentResponse, error) {
+	if order.Status != models.OrderStatusPending || order.PaymentStatus != "pending" {
+		return nil, ErrOrderNotPending
+	}
+
+	response, err := s.paymentService.ProcessPayment(&PaymentRequest{
+		OrderID:     order.ID,
+		Amount:      order.TotalAmount,
+		Currency:    order.Currency,
+		Method:      PaymentMethod(order.PaymentMethod),
+		CustomerID:  order.UserID,
+		Description: "Order " + order.OrderNumber,
+		Card:        card,
+		CallbackURL: s.paymentCallbackURL(order.ID),
+	})
+	if err != nil {
+		if releaseErr := s.FailPayment(order.ID, err.Error()); releaseErr != nil {
+			s.logger.Printf("Failed to release reservations for order %d: %v", order.ID, releaseErr)
+		}
+		return nil, fmt.Errorf("failed to process payment: %w", err)
+	}
+
+	// Keep the transaction ID so the payment can be refunded later. Sub-orders
+	// share the payment of their parent.
+	if _, err := s.repo.Exec(`UPDATE orders SET reference_id = ?, updated_at = ? WHERE id = ? OR parent_order_id = ?`,
+		response.TransactionID, time.Now().UTC(), order.ID, order.ID); err != nil {
+		s.logger.Printf("Failed to store payment reference for order %d: %v", order.ID, err)
+	}
+	ordersChanged(s.config.Cache)
+
+	err = s.applyPayment(order.ID, response)
+	if errors.Is(err, ErrReservationExpired) {
+		// The reservations ran out while the card was being charged
+		if refundErr := s.refundReleasedOrder(order.ID, response); refundErr != nil {
+			return response, refundErr
+		}
+	}
+	return response, err
+}
+
+// Complete3DSecure finishes the 3-D Secure challenge of an order's pending
+// card payment and confirms or releases the order accordingly
+func (s *CheckoutService) Complete3DSecure(orderID int64, verificationData map[string]string) (*PaymentResponse, error) {
+	order, err := s.stateMachine.loadOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPending || order.ReferenceID == "" {
+		return nil, ErrOrderNotPending
+	}
+
+	response, err := s.paymentService.Complete3DSecure(order.ReferenceID, verificationData)
+	if err != nil {
+		return nil, err
+	}
+	err = s.applyPayment(orderID, response)
+	if errors.Is(err, ErrReservationExpired) {
+		if refundErr := s.refundReleasedOrder(orderID, response); refundErr != nil {
+			return response, refundErr
+		}
+	}
+	return response, err
+}
+
+// paymentCallbackURL returns the 3-D Secure callback URL of an order, or
+// an empty string when none is configured
+func (s *CheckoutService) paymentCallbackURL(orderID int64) string {
+	if s.config.PaymentCallbackURL == "" {
+		return ""
+	}
+	separator := "?"
+	if strings.Contains(s.config.PaymentCallbackURL, "?") {
+		separator = "&"
+	}
+	return s.config.PaymentCallbackURL + separator + "order_id=" + strconv.FormatInt(orderID, 10)
+}
+
+// HandlePaymentWebhook applies a payment gateway webhook to the order the
+// payment belongs to. A payment that completes after the order's
+// reservations were released is refunded.
+func (s *CheckoutService) HandlePaymentWebhook(payload *payment.WebhookPayload) error {
+	response, err := s.paymentService.HandleWebhook(payload)
+	if err != nil {
+		return err
+	}
+	if response.OrderID == 0 {
+		return nil
+	}
+
+	err = s.applyPayment(response.OrderID, response)
+	if errors.Is(err, ErrReservationExpired) {
+		return s.refundReleasedOrder(response.OrderID, response)
+	}
+	return err
+}
+
+// refundReleasedOrder refunds a payment that completed after its order's
+// reservations were released
+func (s *CheckoutService) refundReleasedOrder(orderID int64, response *PaymentResponse) error {
+	if _, err := s.paymentService.RefundPayment(response.TransactionID, response.Amount-response.RefundedAmount, "stock reservation expired"); err != nil {
+		return fmt.Errorf("failed to refund payment of released order %d: %w", orderID, err)
+	}
+	s.logger.Printf("Refunded payment %s of order %d whose reservations had expired", response.TransactionID, orderID)
+	return nil
+}
+
+// applyPayment confirms or releases an order according to its payment.
+// Pending payments leave the order as it is.
+func (s *CheckoutService) applyPayment(orderID int64, response *PaymentResponse) error {
+	switch response.Status {
+	case PaymentStatusCompleted, PaymentStatusAuthorized:
+		return s.ConfirmPayment(orderID)
+	case PaymentStatusFailed, PaymentStatusCancelled:
+		return s.FailPayment(orderID, response.FailureReason)
+	}
+	return nil
+}
+
+// ConfirmPayment turns the order's reservations into sales and confirms
+// the order. The sub-orders of a multi-vendor order are confirmed before
+// their parent. It fails with ErrReservationExpired if the reservations
+// have already been released; the caller must then refund the payment.
+func (s *CheckoutService) ConfirmPayment(orderID int64) error {
+	subIDs, err := s.subOrderIDs(orderID)
+	if err != nil {
+		return err
+	}
+	for _, subID := range subIDs {
+		if err := s.confirmOrder(subID); err != nil {
+			return err
+		}
+	}
+	return s.confirmOrder(orderID)
+}
+
+// confirmOrder confirms a single pending order as paid
+func (s *CheckoutService) confirmOrder(orderID int64) error {
+	order, err := s.stateMachine.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case order.Status == models.OrderStatusCancelled:
+		return ErrReservationExpired
+	case order.Status != models.OrderStatusPending && order.PaymentStatus == "paid":
+		// Already confirmed
+		return nil
+	}
+
+	_, err = s.stateMachine.Transition(&TransitionRequest{
+		OrderID:       orderID,
+		From:          models.OrderStatusPending,
+		To:            models.OrderStatusConfirmed,
+		PaymentStatus: "paid",
+		Comment:       "payment received",
+	})
+	return err
+}
+
+// FailPayment releases the order's reservations back to stock and cancels
+// the order. Calling it again for the same order is a no-op.
+func (s *CheckoutService) FailPayment(orderID int64, reason string) error {
+	if reason == "" {
+		reason = "payment failed"
+	}
+	return s.releaseOrder(orderID, "failed", reason)
+}
+
+// ReleaseExpiredReservations releases the stock of orders whose reservation
+// TTL ran out before the payment was confirmed. It returns the number of
+// orders cancelled.
+func (s *CheckoutService) ReleaseExpiredReservations() (int, error) {
+	// Reservations belong to sub-orders for multi-vendor carts; the whole
+	// order is released through its parent
+	rows, err := s.repo.Query(`
+		SELECT DISTINCT CASE WHEN o.parent_order_id > 0 THEN o.parent_order_id ELSE o.id END
+		FROM stock_reservations r JOIN orders o ON o.id = r.order_id
+		WHERE r.status = ? AND r.expires_at <= ?
+		LIMIT 100`,
+		ReservationStatusReserved, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired reservations: %w", err)
+	}
+	var orderIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired reservation: %w", err)
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+
+	released := 0
+	for _, orderID := range orderIDs {
+		if err := s.releaseOrder(orderID, "failed", "stock reservation expired"); err != nil {
+			s.logger.Printf("Failed to release expired reservations for order %d: %v", orderID, err)
+			continue
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+// GetReservations returns the stock reservations of an order, including
+// those of its sub-orders
+func (s *CheckoutService) GetReservations(orderID int64) ([]StockReservation, error) {
+	reservations, err := s.stateMachine.loadReservations(orderID)
+	if err != nil {
+		return nil, err
+	}
+	subIDs, err := s.subOrderIDs(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subID := range subIDs {
+		subReservations, err := s.stateMachine.loadReservations(subID)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, subReservations...)
+	}
+	return reservations, nil
+}
+
+// releaseOrder cancels a still pending order and its sub-orders; the state
+// machine's cancel effect puts the reserved stock back
+func (s *CheckoutService) releaseOrder(orderID int64, paymentStatus, reason string) error {
+	order, err := s.stateMachine.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if order.ParentOrderID > 0 {
+		return s.releaseOrder(order.ParentOrderID, paymentStatus, reason)
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil
+	}
+
+	subIDs, err := s.subOrderIDs(orderID)
+	if err != nil {
+		return err
+	}
+	for _, subID := range subIDs {
+		if err := s.cancelPendingOrder(subID, paymentStatus, reason); err != nil {
+			return err
+		}
+	}
+	if err := s.cancelPendingOrder(orderID, paymentStatus, reason); err != nil {
+		return err
+	}
+	return s.releaseCoupon(orderID)
+}
+
+// cancelPendingOrder cancels a single order that is still awaiting payment
+func (s *CheckoutService) cancelPendingOrder(orderID int64, paymentStatus, reason string) error {
+	_, err := s.stateMachine.Transition(&TransitionRequest{
+		OrderID:       orderID,
+		From:          models.OrderStatusPending,
+		To:            models.OrderStatusCancelled,
+		PaymentStatus: paymentStatus,
+		Comment:       reason,
+	})
+	if errors.Is(err, ErrConcurrentTransition) {
+		// Confirmed or cancelled by someone else in the meantime
+		return nil
+	}
+	return err
+}
+
+// loadCartItems reads the items of a stored cart
+func (s *CheckoutService) loadCartItems(cartID int) ([]models.CartItem, error) {
+	rows, err := s.repo.Query(`SELECT id, product_id, quantity FROM cart_items WHERE cart_id = ? ORDER BY id ASC`, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.CartItem
+	for rows.Next() {
+		item := models.CartItem{CartID: cartID}
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// priceLines merges cart items per product and prices them with the current
+// product price rather than the price stored in the cart
+func (s *CheckoutService) priceLines(items []models.CartItem) ([]checkoutLine, error) {
+	quantities := make(map[int]int)
+	var order []int
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		if _, ok := quantities[item.ProductID]; !ok {
+			order = append(order, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+	if len(order) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	lines := make([]checkoutLine, 0, len(order))
+	for _, productID := range order {
+		var p models.Product
+		err := s.repo.QueryRow(`
+			SELECT id, vendor_id, name, sku, price, stock, weight, status
+			FROM products WHERE id = ?`, productID).
+			Scan(&p.ID, &p.VendorID, &p.Name, &p.SKU, &p.Price, &p.Stock, &p.Weight, &p.Status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product %d: %w", productID, err)
+		}
+		if p.Status != ProductStatusActive && p.Status != ProductStatusOutOfStock {
+			return nil, fmt.Errorf("%w: %s", ErrProductUnavailable, p.Name)
+		}
+		quantity := quantities[productID]
+		if p.Stock < quantity {
+			return nil, fmt.Errorf("%w for product %d", ErrInsufficientStock, p.ID)
+		}
+		lines = append(lines, checkoutLine{product: p, quantity: quantity, total: p.Price * float64(quantity)})
+	}
+
+	return lines, nil
+}
+
+// calculateDiscount applies the coupon and any triggered automatic
+// discounts. It returns the total discount, the part of it the coupon
+// gave and whether shipping is free.
+func (s *CheckoutService) calculateDiscount(req *CheckoutRequest, lines []checkoutLine, subtotal float64, itemCount int) (float64, float64, bool, error) {
+	discount := 0.0
+	couponDiscount := 0.0
+	freeShipping := false
+	couponApplied := false
+
+	if c := req.Coupon; c != nil {
+		if !c.IsValid() || !couponAllowsUser(c, req.UserID) || !couponAllowsCountry(c, req.ShippingCountry) {
+			return 0, 0, false, ErrCouponNotApplicable
+		}
+		if c.MinOrderAmount != nil && subtotal < *c.MinOrderAmount {
+			return 0, 0, false, fmt.Errorf("%w: minimum order amount is %.2f", ErrCouponNotApplicable, *c.MinOrderAmount)
+		}
+		if c.MinItemCount != nil && itemCount < *c.MinItemCount {
+			return 0, 0, false, fmt.Errorf("%w: minimum item count is %d", ErrCouponNotApplicable, *c.MinItemCount)
+		}
+		if c.UsageLimit != nil {
+			used, err := s.couponUsage(c.Code, 0)
+			if err != nil {
+				return 0, 0, false, err
+			}
+			if used < c.UsedCount {
+				used = c.UsedCount
+			}
+			if used >= *c.UsageLimit {
+				return 0, 0, false, fmt.Errorf("%w: usage limit reached", ErrCouponNotApplicable)
+			}
+		}
+		if c.UsageLimitPerUser != nil {
+			used, err := s.couponUsage(c.Code, req.UserID)
+			if err != nil {
+				return 0, 0, false, err
+			}
+			if !c.CanBeUsedByCustomer(uint(req.UserID), used) {
+				return 0, 0, false, fmt.Errorf("%w: usage limit per customer reached", ErrCouponNotApplicable)
+			}
+		}
+
+		eligible := 0.0
+		for _, line := range lines {
+			if couponAllowsProduct(c, line.product) {
+				eligible += line.total
+			}
+		}
+		if eligible == 0 {
+			return 0, 0, false, ErrCouponNotApplicable
+		}
+
+		if c.Type == models.CouponTypeFreeShipping {
+			freeShipping = true
+		} else {
+			couponDiscount = c.CalculateDiscount(eligible)
+			discount += couponDiscount
+		}
+		couponApplied = true
+	}
+
+	discounts := append([]models.Discount(nil), req.Discounts...)
+	sort.SliceStable(discounts, func(i, j int) bool {
+		return discounts[i].Priority > discounts[j].Priority
+	})
+	for i := range discounts {
+		d := &discounts[i]
+		if !d.IsValid() || !d.ShouldTrigger(subtotal, itemCount, nil) {
+			continue
+		}
+		if couponApplied && (!d.CanCombineWithCoupons || !req.Coupon.CanCombineWithOthers) {
+			continue
+		}
+		discount += d.CalculateDiscount(subtotal - discount)
+	}
+
+	if discount > subtotal {
+		discount = subtotal
+	}
+	if couponDiscount > discount {
+		couponDiscount = discount
+	}
+
+	return discount, couponDiscount, freeShipping, nil
+}
+
+// calculateShipping picks the highest priority applicable rate. Without any
+// configured rates shipping is free.
+func (s *CheckoutService) calculateShipping(req *CheckoutRequest, weight, amount float64, itemCount int) (float64, error) {
+	if len(req.ShippingRates) == 0 {
+		return 0, nil
+	}
+
+	rates := append([]models.ShippingRate(nil), req.ShippingRates...)
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Priority > rates[j].Priority
+	})
+	for i := range rates {
+		if rates[i].IsApplicable(weight, amount, req.ShippingCountry, req.ShippingState, req.ShippingCity) {
+			return rates[i].CalculateRate(weight, amount, itemCount), nil
+		}
+	}
+
+	return 0, ErrNoShippingRate
+}
+
+// couponUsage returns how often a customer has redeemed a coupon, or how
+// often it was redeemed in total for customerID 0
+func (s *CheckoutService) couponUsage(code string, customerID int64) (int, error) {
+	var used int
+	err := s.repo.QueryRow(`SELECT used_count FROM coupon_usage_counts WHERE coupon_code = ? AND customer_id = ?`,
+		code, customerID).Scan(&used)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get usage of coupon %s: %w", code, err)
+	}
+	return used, nil
+}
+
+// redeemCoupon records the order's use of a coupon. The usage limits are
+// checked by the same conditional updates that count the use, so concurrent
+// checkouts cannot redeem a coupon more often than allowed.
+func redeemCoupon(tx database.Transaction, c *models.Coupon, order *models.Order, now time.Time) error {
+	// The coupon's own count covers uses from before the totals were kept
+	if err := countCouponUse(tx, c.Code, 0, c.UsageLimit, c.UsedCount); err != nil {
+		return err
+	}
+	if err := countCouponUse(tx, c.Code, order.UserID, c.UsageLimitPerUser, 0); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO coupon_usages (coupon_code, customer_id, order_id, discount_amount, order_amount, currency, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Code, order.UserID, order.ID, order.DiscountAmount, order.TotalAmount, order.Currency,
+		string(models.CouponUsageStatusUsed), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to record coupon usage: %w", err)
+	}
+	c.UsedCount++
+	return nil
+}
+
+// countCouponUse adds one use to a coupon's running total, failing with
+// ErrCouponNotApplicable when that would exceed limit
+func countCouponUse(tx database.Transaction, code string, customerID int64, limit *int, initial int) error {
+	_, err := tx.Exec(`INSERT INTO coupon_usage_counts (coupon_code, customer_id, used_count) VALUES (?, ?, ?)`,
+		code, customerID, initial)
+	if err != nil && !isUniqueViolation(err) {
+		return fmt.Errorf("failed to count coupon usage: %w", err)
+	}
+
+	query := `UPDATE coupon_usage_counts SET used_count = used_count + 1 WHERE coupon_code = ? AND customer_id = ?`
+	args := []interface{}{code, customerID}
+	if limit != nil {
+		query += ` AND used_count < ?`
+		args = append(args, *limit)
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to count coupon usage: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		if customerID > 0 {
+			return fmt.Errorf("%w: usage limit per customer reached", ErrCouponNotApplicable)
+		}
+		return fmt.Errorf("%w: usage limit reached", ErrCouponNotApplicable)
+	}
+	return nil
+}
+
+// releaseCoupon gives the coupon use of an order that was cancelled before
+// it was paid back to the coupon and the customer
+func (s *CheckoutService) releaseCoupon(orderID int64) error {
+	var code string
+	var customerID int64
+	err := s.repo.QueryRow(`SELECT coupon_code, customer_id FROM coupon_usages WHERE order_id = ? AND status = ?`,
+		orderID, string(models.CouponUsageStatusUsed)).Scan(&code, &customerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get coupon usage of order %d: %w", orderID, err)
+	}
+
+	// Only the release that moves the usage out of "used" gives it back
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(`UPDATE coupon_usages SET status = ?, updated_at = ? WHERE order_id = ? AND status = ?`,
+		string(models.CouponUsageStatusCancelled), now, orderID, string(models.CouponUsageStatusUsed))
+	if err != nil {
+		return fmt.Errorf("failed to release coupon usage of order %d: %w", orderID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`
+		UPDATE coupon_usage_counts SET used_count = used_count - 1
+		WHERE coupon_code = ? AND customer_id IN (0, ?) AND used_count > 0`, code, customerID); err != nil {
+		return fmt.Errorf("failed to release coupon usage of order %d: %w", orderID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit coupon release: %w", err)
+	}
+	return nil
+}
+
+// couponAllowsProduct applies the product and vendor restrictions of a coupon
+func couponAllowsProduct(c *models.Coupon, p models.Product) bool {
+	id := uint(p.ID)
+	vendorID := uint(p.VendorID)
+
+	if containsUint(c.ApplicableProducts.ExcludedProducts, id) {
+		return false
+	}
+	if len(c.ApplicableProducts.IncludedProducts) > 0 && !containsUint(c.ApplicableProducts.IncludedProducts, id) {
+		return false
+	}
+	if containsUint(c.ApplicableVendors.ExcludedVendors, vendorID) {
+		return false
+	}
+	if len(c.ApplicableVendors.IncludedVendors) > 0 && !containsUint(c.ApplicableVendors.IncludedVendors, vendorID) {
+		return false
+	}
+	if c.VendorID != nil && *c.VendorID != vendorID {
+		return false
+	}
+
+	return true
+}
+
+// couponAllowsUser applies the user restrictions of a coupon
+func couponAllowsUser(c *models.Coupon, userID int64) bool {
+	id := uint(userID)
+	if containsUint(c.ApplicableUsers.ExcludedUsers, id) {
+		return false
+	}
+	return len(c.ApplicableUsers.IncludedUsers) == 0 || containsUint(c.ApplicableUsers.IncludedUsers, id)
+}
+
+// couponAllowsCountry applies the geographic restrictions of a coupon
+func couponAllowsCountry(c *models.Coupon, country string) bool {
+	for _, excluded := range c.ExcludedCountries {
+		if strings.EqualFold(excluded, country) {
+			return false
+		}
+	}
+	if len(c.ApplicableCountries) == 0 {
+		return true
+	}
+	for _, allowed := range c.ApplicableCountries {
+		if strings.EqualFold(allowed, country) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint(values []uint, v uint) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// roundMoney rounds an amount to two decimals
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
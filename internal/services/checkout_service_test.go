@@ -0,0 +1,392 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// mustExec runs a fixture statement and returns the inserted row's ID
+func mustExec(t *testing.T, repo database.SimpleRepository, query string, args ...interface{}) int64 {
+	t.Helper()
+	result, err := repo.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+func seedUser(t *testing.T, repo database.SimpleRepository) int64 {
+	t.Helper()
+	var count int
+	if err := repo.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return mustExec(t, repo, `INSERT INTO users (name, email, password) VALUES (?, ?, ?)`,
+		"Müşteri", fmt.Sprintf("user%d@example.com", count+1), "x")
+}
+
+func seedVendor(t *testing.T, repo database.SimpleRepository, commissionRate float64) int64 {
+	t.Helper()
+	userID := seedUser(t, repo)
+	return mustExec(t, repo, `INSERT INTO vendors (user_id, company_name, status, commission_rate) VALUES (?, ?, 'active', ?)`,
+		userID, "Satıcı", commissionRate)
+}
+
+func seedProduct(t *testing.T, repo database.SimpleRepository, vendorID int64, price float64, stock int) int64 {
+	t.Helper()
+	var categoryID int64
+	if err := repo.QueryRow(`SELECT id FROM categories LIMIT 1`).Scan(&categoryID); err != nil {
+		categoryID = mustExec(t, repo, `INSERT INTO categories (name, slug) VALUES ('Genel', 'genel')`)
+	}
+	var count int
+	if err := repo.QueryRow(`SELECT COUNT(*) FROM products`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return mustExec(t, repo, `INSERT INTO products (vendor_id, category_id, name, sku, price, stock, status) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		vendorID, categoryID, fmt.Sprintf("Ürün %d", count+1), fmt.Sprintf("SKU-%d", count+1), price, stock, ProductStatusActive)
+}
+
+func productStock(t *testing.T, repo database.SimpleRepository, productID int64) int {
+	t.Helper()
+	var stock int
+	if err := repo.QueryRow(`SELECT stock FROM products WHERE id = ?`, productID).Scan(&stock); err != nil {
+		t.Fatal(err)
+	}
+	return stock
+}
+
+func orderStatus(t *testing.T, repo database.SimpleRepository, orderID int64) (string, string) {
+	t.Helper()
+	var status, paymentStatus string
+	if err := repo.QueryRow(`SELECT status, payment_status FROM orders WHERE id = ?`, orderID).Scan(&status, &paymentStatus); err != nil {
+		t.Fatal(err)
+	}
+	return status, paymentStatus
+}
+
+func newTestCheckout(t *testing.T) (*CheckoutService, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	paymentService := NewPaymentService(repo)
+	paymentService.SetGateway(payment.NewSandboxProvider(payment.SandboxConfig{Logger: testutil.DiscardLogger}))
+	s, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, repo
+}
+
+// checkoutRequest returns a card checkout of the given cart lines
+func checkoutRequest(userID int64, items ...models.CartItem) *CheckoutRequest {
+	return &CheckoutRequest{
+		Cart:            &models.Cart{Items: items},
+		UserID:          userID,
+		PaymentMethod:   PaymentMethodCreditCard,
+		ShippingAddress: "Bağdat Caddesi 1",
+		ShippingCity:    "İstanbul",
+		ShippingCountry: "TR",
+	}
+}
+
+func line(productID int64, quantity int) models.CartItem {
+	return models.CartItem{ProductID: int(productID), Quantity: quantity}
+}
+
+func testCard(number string) *PaymentCard {
+	return &PaymentCard{Card: &payment.CardDetails{
+		Number: number, ExpMonth: "12", ExpYear: "2099", CVV: "123", HolderName: "Test Müşteri",
+	}}
+}
+
+func TestCheckoutReservesStock(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 50, 3)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := result.Order
+	if order.SubtotalAmount != 100 || order.TotalAmount != 100 || len(order.Items) != 1 {
+		t.Fatalf("order = %+v", order)
+	}
+	if order.Items[0].Commission != 10 {
+		t.Fatalf("commission = %.2f, want 10 at the vendor's 10%% rate", order.Items[0].Commission)
+	}
+	if stock := productStock(t, repo, productID); stock != 1 {
+		t.Fatalf("stock = %d, want 1 after reserving 2", stock)
+	}
+
+	var subtotal float64
+	if err := repo.QueryRow(`SELECT subtotal FROM orders WHERE id = ?`, order.ID).Scan(&subtotal); err != nil {
+		t.Fatal(err)
+	}
+	if subtotal != 100 {
+		t.Fatalf("stored subtotal = %.2f", subtotal)
+	}
+
+	// The last unit cannot be sold twice
+	_, err = s.Checkout(checkoutRequest(userID, line(productID, 2)))
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("got %v, want ErrInsufficientStock", err)
+	}
+}
+
+func TestCheckoutSplitsMultiVendorOrders(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	first := seedProduct(t, repo, seedVendor(t, repo, 10), 30, 5)
+	second := seedProduct(t, repo, seedVendor(t, repo, 20), 70, 5)
+
+	fixed := models.Coupon{Code: "INDIRIM10", Type: models.CouponTypeFixedAmount, Value: 10, IsActive: true, ValidFrom: time.Now().Add(-time.Hour)}
+	req := checkoutRequest(userID, line(first, 1), line(second, 1))
+	req.Coupon = &fixed
+	result, err := s.Checkout(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := result.Order
+	if len(order.SubOrders) != 2 || order.TotalAmount != 90 {
+		t.Fatalf("order = %+v", order)
+	}
+	var total, discount float64
+	for _, sub := range order.SubOrders {
+		if sub.ParentOrderID != order.ID || len(sub.Items) != 1 {
+			t.Fatalf("sub-order = %+v", sub)
+		}
+		total += sub.TotalAmount
+		discount += sub.DiscountAmount
+	}
+	if total != order.TotalAmount || discount != order.DiscountAmount {
+		t.Fatalf("sub-orders add up to %.2f with %.2f discount, want %.2f and %.2f", total, discount, order.TotalAmount, order.DiscountAmount)
+	}
+
+	reservations, err := s.GetReservations(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 2 {
+		t.Fatalf("reservations = %+v", reservations)
+	}
+
+	orders := NewOrderService(repo)
+	placed, err := orders.GetCustomerOrder(int(userID), int(order.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placed.SubOrders) != 2 || len(placed.SubOrders[0].Items) != 1 || len(placed.SubOrders[1].Items) != 1 {
+		t.Fatalf("customer sees order %+v", placed)
+	}
+	if _, err := orders.GetCustomerOrder(int(seedUser(t, repo)), int(order.ID)); !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("got %v for another customer's order, want ErrOrderNotFound", err)
+	}
+}
+
+func TestCheckoutSplitsCouponToEligibleVendors(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	firstVendor := seedVendor(t, repo, 10)
+	first := seedProduct(t, repo, firstVendor, 30, 5)
+	second := seedProduct(t, repo, seedVendor(t, repo, 20), 70, 5)
+
+	vendorID := uint(firstVendor)
+	coupon := models.Coupon{Code: "SATICI10", Type: models.CouponTypeFixedAmount, Value: 10, IsActive: true,
+		ValidFrom: time.Now().Add(-time.Hour), VendorID: &vendorID}
+	req := checkoutRequest(userID, line(first, 1), line(second, 1))
+	req.Coupon = &coupon
+	result, err := s.Checkout(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs := result.Order.SubOrders
+	if len(subs) != 2 {
+		t.Fatalf("sub-orders = %+v", subs)
+	}
+	if subs[0].DiscountAmount != 10 || subs[1].DiscountAmount != 0 {
+		t.Fatalf("discounts = %.2f and %.2f, want the whole coupon on the vendor it belongs to",
+			subs[0].DiscountAmount, subs[1].DiscountAmount)
+	}
+
+	// Customers and admins see the parent order only
+	orders := NewOrderService(repo)
+	for name, list := range map[string]func() ([]models.Order, error){
+		"user": func() ([]models.Order, error) { return orders.GetOrdersByUser(int(userID), 10, 0) },
+		"all":  func() ([]models.Order, error) { return orders.GetAllOrders(10, 0) },
+	} {
+		got, err := list()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != result.Order.ID {
+			t.Fatalf("%s orders = %d, want the parent order only", name, len(got))
+		}
+	}
+	stats, err := orders.GetOrderStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["total_orders"] != int64(1) || stats["pending_orders"] != int64(1) {
+		t.Fatalf("stats = %v", stats)
+	}
+}
+
+func TestCheckoutEnforcesCouponLimits(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	alice := seedUser(t, repo)
+	bob := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 100, 10)
+
+	total, perUser := 2, 1
+	coupon := models.Coupon{Code: "YAZ", Type: models.CouponTypePercentage, Value: 10, IsActive: true,
+		ValidFrom: time.Now().Add(-time.Hour), UsageLimit: &total, UsageLimitPerUser: &perUser}
+	checkout := func(userID int64) (*CheckoutResult, error) {
+		c := coupon
+		req := checkoutRequest(userID, line(productID, 1))
+		req.Coupon = &c
+		return s.Checkout(req)
+	}
+
+	first, err := checkout(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Order.DiscountAmount != 10 {
+		t.Fatalf("discount = %.2f, want 10", first.Order.DiscountAmount)
+	}
+	if _, err := checkout(alice); !errors.Is(err, ErrCouponNotApplicable) {
+		t.Fatalf("second use by the same customer: got %v, want ErrCouponNotApplicable", err)
+	}
+	if _, err := checkout(bob); err != nil {
+		t.Fatal(err)
+	}
+	carol := seedUser(t, repo)
+	if _, err := checkout(carol); !errors.Is(err, ErrCouponNotApplicable) {
+		t.Fatalf("use past the total limit: got %v, want ErrCouponNotApplicable", err)
+	}
+
+	// A cancelled order gives its coupon use back
+	if err := s.FailPayment(first.Order.ID, "card declined"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkout(alice); err != nil {
+		t.Fatalf("coupon of a cancelled order was not released: %v", err)
+	}
+}
+
+func TestCheckoutCouponLimitInTransaction(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 100, 10)
+
+	limit := 1
+	coupon := models.Coupon{Code: "TEK", Type: models.CouponTypeFixedAmount, Value: 5, IsActive: true,
+		ValidFrom: time.Now().Add(-time.Hour), UsageLimit: &limit}
+	req := checkoutRequest(userID, line(productID, 1))
+	req.Coupon = &coupon
+	if _, err := s.Checkout(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A checkout that passed the pre-check before the last use was counted
+	// still fails when the use is counted in its transaction
+	order := &models.Order{ID: 99, UserID: userID, DiscountAmount: 5, TotalAmount: 95, Currency: "TRY"}
+	tx, err := repo.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := coupon
+	stale.UsedCount = 0
+	err = redeemCoupon(tx, &stale, order, time.Now())
+	tx.Rollback()
+	if !errors.Is(err, ErrCouponNotApplicable) {
+		t.Fatalf("got %v, want ErrCouponNotApplicable", err)
+	}
+}
+
+func TestPayOrder(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	tests := []struct {
+		name          string
+		card          string
+		wantStatus    string
+		wantPayment   string
+		wantStock     int
+		wantPaymentOK PaymentStatus
+	}{
+		{"approved", payment.SandboxCardApproved, models.OrderStatusConfirmed, "paid", 4, PaymentStatusCompleted},
+		{"declined", payment.SandboxCardDeclined, models.OrderStatusCancelled, "failed", 4, PaymentStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Checkout(checkoutRequest(userID, line(productID, 1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := s.PayOrder(result.Order, testCard(tt.card))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != tt.wantPaymentOK {
+				t.Fatalf("payment status = %s, want %s", response.Status, tt.wantPaymentOK)
+			}
+			status, paymentStatus := orderStatus(t, repo, result.Order.ID)
+			if status != tt.wantStatus || paymentStatus != tt.wantPayment {
+				t.Fatalf("order is %s/%s, want %s/%s", status, paymentStatus, tt.wantStatus, tt.wantPayment)
+			}
+			if stock := productStock(t, repo, productID); stock != tt.wantStock {
+				t.Fatalf("stock = %d, want %d", stock, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestPayOrderRefundsExpiredReservation(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The reservation runs out while the customer is still on the payment page
+	mustExec(t, repo, `UPDATE stock_reservations SET expires_at = ? WHERE order_id = ?`, time.Now().UTC().Add(-time.Minute), result.Order.ID)
+	released, err := s.ReleaseExpiredReservations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Fatalf("released %d orders, want 1", released)
+	}
+	if stock := productStock(t, repo, productID); stock != 5 {
+		t.Fatalf("stock = %d, want 5 after the release", stock)
+	}
+
+	response, err := s.PayOrder(result.Order, testCard(payment.SandboxCardApproved))
+	if !errors.Is(err, ErrReservationExpired) {
+		t.Fatalf("got %v, want ErrReservationExpired", err)
+	}
+	record, err := s.paymentService.GetPaymentStatus(response.TransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RefundedAmount != response.Amount {
+		t.Fatalf("refunded %.2f of %.2f", record.RefundedAmount, response.Amount)
+	}
+	if status, _ := orderStatus(t, repo, result.Order.ID); status != models.OrderStatusCancelled {
+		t.Fatalf("order status = %s, want cancelled", status)
+	}
+}
@@ -0,0 +1,16 @@
+package services
+
+import "kolajAi/internal/database"
+
+// defaultCursorCodec signs the cursors of services that were not given a
+// codec. Its key is random, so its cursors stop working when the process
+// restarts and are not accepted by other instances.
+var defaultCursorCodec = database.NewCursorCodec(nil)
+
+// cursorCodec returns codec, or the default codec when it is nil
+func cursorCodec(codec *database.CursorCodec) *database.CursorCodec {
+	if codec != nil {
+		return codec
+	}
+	return defaultCursorCodec
+}
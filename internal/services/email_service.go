@@ -3,14 +3,19 @@ package services
 import (
 	"bytes"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 	"time"
 
+	"kolajAi/internal/database"
 	"kolajAi/internal/models"
 	"kolajAi/internal/repository"
 )
@@ -21,6 +26,8 @@ type EmailService struct {
 	db       *sql.DB
 	config   EmailConfig
 	provider EmailProvider
+	// cursors signs the cursors of email log pages
+	cursors *database.CursorCodec
 }
 
 // EmailConfig holds email configuration
@@ -364,12 +371,42 @@ func (s *EmailService) GetEmailStatus(messageID string) (*EmailStatus, error) {
 
 // GetEmailLogs retrieves email logs with pagination
 func (s *EmailService) GetEmailLogs(limit, offset int) ([]*EmailLog, error) {
+	return s.queryEmailLogs("ORDER BY created_at DESC LIMIT ? OFFSET ?", limit, offset)
+}
+
+// SetCursorCodec sets the codec that signs the cursors of email log pages
+func (s *EmailService) SetCursorCodec(codec *database.CursorCodec) {
+	s.cursors = codec
+}
+
+// GetEmailLogsPage returns a keyset page of email logs, newest first
+func (s *EmailService) GetEmailLogsPage(params database.CursorPaginationParams) ([]*EmailLog, database.CursorPaginationResult, error) {
+	keyset := database.Keyset{Scope: "email_logs", Column: "created_at", Order: database.Descending}
+	logs, result, err := database.Paginate(cursorCodec(s.cursors), keyset, params,
+		func(cursor *database.Cursor, limit int) ([]*EmailLog, error) {
+			clause := "ORDER BY " + keyset.OrderBy(cursor) + " LIMIT ?"
+			condition, args := keyset.Condition(cursor)
+			if condition != "" {
+				clause = "WHERE " + condition + " " + clause
+			}
+			return s.queryEmailLogs(clause, append(args, limit)...)
+		},
+		func(log *EmailLog) (interface{}, int64) { return log.CreatedAt, int64(log.ID) })
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to get email logs page: %w", err)
+	}
+	return logs, result, nil
+}
+
+// queryEmailLogs reads the email logs selected by clause, the part of the
+// query after the FROM clause
+func (s *EmailService) queryEmailLogs(clause string, args ...interface{}) ([]*EmailLog, error) {
 	query := `SELECT id, message_id, to_email, from_email, subject, status, provider, 
 			  template_id, variables, error, sent_at, delivered_at, opened_at, 
 			  clicked_at, created_at, updated_at 
-			  FROM email_logs ORDER BY created_at DESC LIMIT ? OFFSET ?`
+			  FROM email_logs ` + clause
 
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query email logs: %w", err)
 	}
@@ -645,15 +682,48 @@ func (p *SMTPProvider) buildMessage(req *EmailRequest) string {
 	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(req.To, ",")))
 	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", req.Subject))
 	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-	msg.WriteString("\r\n")
-	
+
+	body := req.TextBody
 	if req.HTMLBody != "" {
-		msg.WriteString(req.HTMLBody)
-	} else {
-		msg.WriteString(req.TextBody)
+		body = req.HTMLBody
 	}
-	
+	if len(req.Attachments) == 0 {
+		msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+		msg.WriteString("\r\n")
+		msg.WriteString(body)
+		return msg.String()
+	}
+
+	// Attachments are sent as parts of a multipart/mixed message after the body
+	parts := multipart.NewWriter(&msg)
+	msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n", parts.Boundary()))
+	msg.WriteString("\r\n")
+
+	part, _ := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	part.Write([]byte(body))
+	for _, attachment := range req.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		disposition := attachment.Disposition
+		if disposition == "" {
+			disposition = "attachment"
+		}
+		part, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	parts.Close()
+
 	return msg.String()
 }
 
@@ -0,0 +1,29 @@
+package services
+
+import "kolajAi/internal/cache"
+
+// Cached entities; each has its own TTL in the cache.entities.ttl config
+const (
+	CacheEntityProduct          = "product"
+	CacheEntityFeaturedProducts = "featured_products"
+	CacheEntityCategories       = "categories"
+)
+
+// EntityCacheTables lists the tables whose write paths invalidate the
+// entity cache. Reports reading only from these tables can be cached.
+var EntityCacheTables = []string{"products", "product_images", "product_reviews", "categories", "orders", "order_items"}
+
+// productsChanged invalidates the cached reads of products once their rows
+// have been written, with the lists and reports over the products table
+func productsChanged(c *cache.EntityCache, productIDs ...int64) {
+	tags := []string{cache.TableTag("products")}
+	for _, productID := range productIDs {
+		tags = append(tags, cache.RowTag("products", productID))
+	}
+	c.Invalidate(tags...)
+}
+
+// ordersChanged invalidates the cached reads of orders and their items
+func ordersChanged(c *cache.EntityCache) {
+	c.Invalidate(cache.TableTag("orders"), cache.TableTag("order_items"))
+}
@@ -1,63 +1,125 @@
 package services
 
 import (
-	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/jobs"
+	"kolajAi/internal/models"
+)
+
+// Webhook inbox errors
+var (
+	ErrWebhookEventNotFound = errors.New("webhook event not found")
+	ErrWebhookEventPending  = errors.New("webhook event is still being processed")
+	ErrNoWebhookHandler     = errors.New("no webhook handler for integration")
+	ErrNoOrderImport        = errors.New("marketplace order import is not configured")
+	// ErrDuplicateWebhookEvent is returned by Receive for an event the
+	// inbox already holds
+	ErrDuplicateWebhookEvent = errors.New("duplicate webhook event")
 )
 
-// IntegrationWebhookService manages webhooks for marketplace integrations
+// maxWebhookBodySize bounds the payloads the webhook endpoint reads
+const maxWebhookBodySize = 5 << 20
+
+// IntegrationWebhookConfig holds webhook inbox settings and collaborators
+type IntegrationWebhookConfig struct {
+	// Integrations resolves the integration a webhook is for. Its
+	// webhook_secret credential signs its webhooks; webhooks of
+	// integrations without one are rejected.
+	Integrations *MarketplaceIntegrationsService
+	// Orders, when set, imports the orders webhooks carry and applies
+	// their status changes
+	Orders *MarketplaceOrderImportService
+	// Inventory, when set, checks the stock webhooks report against the
+	// stock last pushed to the channel
+	Inventory *InventorySyncService
+	// MaxAttempts is how often an event is tried before it is marked
+	// failed. It defaults to 5.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// every further attempt up to an hour. It defaults to one minute.
+	RetryBackoff time.Duration
+	// ProcessingTimeout is how long a claimed event may take before
+	// another worker may claim it again. It defaults to five minutes.
+	ProcessingTimeout time.Duration
+	// BatchSize is the number of events of an integration read at once. It
+	// defaults to 100.
+	BatchSize int
+	Logger    *log.Logger
+}
+
+// IntegrationWebhookService receives webhooks from marketplace integrations
+// into a persistent inbox. Each event is stored once, however often it is
+// delivered, and acknowledged; the events of an integration are then
+// applied one at a time in the order they were received, turning their
+// orders and stock into order imports and stock checks. Failed events are
+// retried with exponential backoff and can be replayed by hand.
 type IntegrationWebhookService struct {
-	marketplaceService *MarketplaceIntegrationsService
-	aiManager         *AIIntegrationManager
-	webhookHandlers   map[string]WebhookHandler
-	secretKeys        map[string]string
+	repo            database.SimpleRepository
+	config          IntegrationWebhookConfig
+	logger          *log.Logger
+	webhookHandlers map[string]WebhookHandler
+	secretKeys      map[string]string
+	jobs            *jobs.JobManager
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
 }
 
 // WebhookHandler interface for handling different webhook types
 type WebhookHandler interface {
-	Handle(ctx context.Context, payload []byte, headers map[string]string) error
-	ValidateSignature(payload []byte, signature string, secret string) bool
+	// Parse maps a payload to the updates it carries
+	Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error)
+	ValidateSignature(payload []byte, headers http.Header, secret string) bool
 	GetIntegrationType() string
 }
 
-// WebhookEvent represents a webhook event
-type WebhookEvent struct {
-	ID            string                 `json:"id"`
-	IntegrationID string                 `json:"integration_id"`
-	EventType     string                 `json:"event_type"`
-	Payload       map[string]interface{} `json:"payload"`
-	Timestamp     time.Time              `json:"timestamp"`
-	Signature     string                 `json:"signature"`
-	Headers       map[string]string      `json:"headers"`
-	Processed     bool                   `json:"processed"`
-	ProcessedAt   *time.Time             `json:"processed_at,omitempty"`
-	Error         string                 `json:"error,omitempty"`
-	RetryCount    int                    `json:"retry_count"`
-	MaxRetries    int                    `json:"max_retries"`
-}
-
 // NewIntegrationWebhookService creates a new webhook service
-func NewIntegrationWebhookService(
-	marketplaceService *MarketplaceIntegrationsService,
-	aiManager *AIIntegrationManager,
-) *IntegrationWebhookService {
+func NewIntegrationWebhookService(repo database.SimpleRepository, config IntegrationWebhookConfig) (*IntegrationWebhookService, error) {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Minute
+	}
+	if config.ProcessingTimeout <= 0 {
+		config.ProcessingTimeout = 5 * time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
 	service := &IntegrationWebhookService{
-		marketplaceService: marketplaceService,
-		aiManager:         aiManager,
-		webhookHandlers:   make(map[string]WebhookHandler),
-		secretKeys:        make(map[string]string),
+		repo:            repo,
+		config:          config,
+		logger:          logger,
+		webhookHandlers: make(map[string]WebhookHandler),
+		secretKeys:      make(map[string]string),
+		locks:           make(map[string]*sync.Mutex),
 	}
-	
+
 	service.registerDefaultHandlers()
-	return service
+	return service, nil
 }
 
 // registerDefaultHandlers registers default webhook handlers
@@ -73,7 +135,8 @@ func (ws *IntegrationWebhookService) registerDefaultHandlers() {
 	ws.RegisterHandler("google", &GoogleWebhookHandler{})
 }
 
-// RegisterHandler registers a webhook handler for an integration type
+// RegisterHandler registers a webhook handler for an integration. The
+// handler of "amazon" also serves amazon_tr and amazon_us.
 func (ws *IntegrationWebhookService) RegisterHandler(integrationType string, handler WebhookHandler) {
 	ws.webhookHandlers[integrationType] = handler
 }
@@ -83,7 +146,44 @@ func (ws *IntegrationWebhookService) SetSecretKey(integrationID, secretKey strin
 	ws.secretKeys[integrationID] = secretKey
 }
 
-// HandleWebhook processes incoming webhook requests
+// SetJobManager makes received webhooks processed through the job queue.
+// Without a job manager they are processed in the background.
+func (ws *IntegrationWebhookService) SetJobManager(jm *jobs.JobManager) {
+	ws.jobs = jm
+}
+
+// handlerFor returns the handler of an integration, matching e.g.
+// amazon_tr to the handler of amazon
+func (ws *IntegrationWebhookService) handlerFor(integrationID string) (WebhookHandler, error) {
+	if handler, exists := ws.webhookHandlers[integrationID]; exists {
+		return handler, nil
+	}
+	if name, _, found := strings.Cut(integrationID, "_"); found {
+		if handler, exists := ws.webhookHandlers[name]; exists {
+			return handler, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %s", ErrNoWebhookHandler, integrationID)
+}
+
+// secretKey returns the secret an integration signs its webhooks with
+func (ws *IntegrationWebhookService) secretKey(integrationID string) string {
+	if secret := ws.secretKeys[integrationID]; secret != "" {
+		return secret
+	}
+	if ws.config.Integrations != nil {
+		if integration, err := ws.config.Integrations.GetIntegration(integrationID); err == nil {
+			return integration.Credentials["webhook_secret"]
+		}
+	}
+	return ""
+}
+
+// HandleWebhook receives a webhook into the inbox and acknowledges it.
+// Deliveries of an event already in the inbox are acknowledged without
+// being stored again. The endpoint is public, so only webhooks signed with
+// the integration's secret are accepted; an integration without a secret
+// cannot receive webhooks.
 func (ws *IntegrationWebhookService) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Extract integration ID from URL path or headers
 	integrationID := ws.extractIntegrationID(r)
@@ -91,109 +191,408 @@ func (ws *IntegrationWebhookService) HandleWebhook(w http.ResponseWriter, r *htt
 		http.Error(w, "Missing integration ID", http.StatusBadRequest)
 		return
 	}
-	
-	// Get integration details
-	integration, err := ws.marketplaceService.GetIntegration(integrationID)
+
+	if ws.config.Integrations != nil {
+		if _, err := ws.config.Integrations.GetIntegration(integrationID); err != nil {
+			http.Error(w, "Integration not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	handler, err := ws.handlerFor(integrationID)
 	if err != nil {
-		http.Error(w, "Integration not found", http.StatusNotFound)
+		http.Error(w, "No handler for integration type", http.StatusBadRequest)
 		return
 	}
-	
-	// Read request body
-	body, err := io.ReadAll(r.Body)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
 	if err != nil {
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
-	
-	// Extract headers
-	headers := make(map[string]string)
-	for key, values := range r.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-	
-	// Get webhook handler for integration type
-	handler, exists := ws.webhookHandlers[integration.Type]
-	if !exists {
-		http.Error(w, "No handler for integration type", http.StatusBadRequest)
+
+	secret := ws.secretKey(integrationID)
+	if secret == "" {
+		ws.logger.Printf("Rejected %s webhook: no webhook secret is configured", integrationID)
+		http.Error(w, "Webhook secret not configured", http.StatusUnauthorized)
 		return
 	}
-	
-	// Validate webhook signature
-	signature := headers["X-Signature"]
-	if signature == "" {
-		signature = headers["X-Hub-Signature-256"]
-	}
-	
-	secretKey := ws.secretKeys[integrationID]
-	if secretKey != "" && !handler.ValidateSignature(body, signature, secretKey) {
+	if !handler.ValidateSignature(body, r.Header, secret) {
 		http.Error(w, "Invalid signature", http.StatusUnauthorized)
 		return
 	}
-	
-	// Create webhook event
-	event := &WebhookEvent{
-		ID:            ws.generateEventID(),
-		IntegrationID: integrationID,
-		EventType:     ws.extractEventType(headers, body),
-		Timestamp:     time.Now(),
-		Signature:     signature,
-		Headers:       headers,
-		MaxRetries:    3,
-	}
-	
-	// Parse payload
-	if err := json.Unmarshal(body, &event.Payload); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+
+	notification, err := handler.Parse(body, r.Header)
+	if err != nil {
+		ws.logger.Printf("Rejected %s webhook: %v", integrationID, err)
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
-	
-		// Process webhook asynchronously
-	go ws.processWebhookAsync(r.Context(), event, handler)
-	
+
+	if _, err := ws.Receive(integrationID, notification, body, r.Header); err != nil {
+		if errors.Is(err, ErrDuplicateWebhookEvent) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+			return
+		}
+		ws.logger.Printf("Failed to store %s webhook %s: %v", integrationID, notification.ID, err)
+		http.Error(w, "Failed to store webhook", http.StatusInternalServerError)
+		return
+	}
+	ws.schedule(integrationID)
+
 	// Return success response
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-// processWebhookAsync processes webhook events asynchronously
-func (ws *IntegrationWebhookService) processWebhookAsync(ctx context.Context, event *WebhookEvent, handler WebhookHandler) {
-	// Use provided context instead of creating new background context
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	
-	for attempt := 0; attempt <= event.MaxRetries; attempt++ {
-		event.RetryCount = attempt
-		
-		// Convert payload back to JSON for handler
-		payloadBytes, _ := json.Marshal(event.Payload)
-		
-		// Process webhook
-		if err := handler.Handle(ctx, payloadBytes, event.Headers); err != nil {
-			event.Error = err.Error()
-			
-			if attempt < event.MaxRetries {
-				// Wait before retry (exponential backoff)
-				waitTime := time.Duration(attempt*attempt) * time.Second
-				time.Sleep(waitTime)
+// Receive stores a parsed webhook in the inbox as pending. An event the
+// inbox already holds returns ErrDuplicateWebhookEvent.
+func (ws *IntegrationWebhookService) Receive(integrationID string, notification *marketplace.WebhookNotification, payload []byte, headers http.Header) (*models.WebhookEvent, error) {
+	headerData, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook headers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	event := &models.WebhookEvent{
+		IntegrationID: integrationID,
+		EventID:       notification.ID,
+		EventType:     notification.Type,
+		Payload:       string(payload),
+		Headers:       string(headerData),
+		Status:        models.WebhookEventPending,
+		NextAttemptAt: now,
+		ReceivedAt:    now,
+	}
+	result, err := ws.repo.Exec(`
+		INSERT INTO webhook_inbox (integration_id, event_id, event_type, payload, headers, status,
+			attempts, last_error, next_attempt_at, received_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, '', ?, ?)`,
+		event.IntegrationID, event.EventID, event.EventType, event.Payload, event.Headers, event.Status,
+		event.NextAttemptAt, event.ReceivedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrDuplicateWebhookEvent
+		}
+		return nil, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+	event.ID, _ = result.LastInsertId()
+	return event, nil
+}
+
+// schedule processes the events of an integration through the job queue,
+// or in the background without one
+func (ws *IntegrationWebhookService) schedule(integrationID string) {
+	if ws.jobs != nil {
+		err := ws.jobs.SubmitJob(&jobs.Job{
+			Type:     JobTypeProcessWebhooks,
+			Priority: jobs.JobPriorityHigh,
+			Payload:  map[string]interface{}{"integration_id": integrationID},
+		})
+		if err == nil {
+			return
+		}
+		ws.logger.Printf("Failed to queue %s webhooks: %v", integrationID, err)
+	}
+	go func() {
+		if _, err := ws.ProcessIntegration(integrationID); err != nil {
+			ws.logger.Printf("Failed to process %s webhooks: %v", integrationID, err)
+		}
+	}()
+}
+
+// lockFor returns the lock serializing the events of an integration
+func (ws *IntegrationWebhookService) lockFor(integrationID string) *sync.Mutex {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	lock, exists := ws.locks[integrationID]
+	if !exists {
+		lock = &sync.Mutex{}
+		ws.locks[integrationID] = lock
+	}
+	return lock
+}
+
+// ProcessDue processes the events due of every integration and returns the
+// number of events applied
+func (ws *IntegrationWebhookService) ProcessDue() (int, error) {
+	rows, err := ws.repo.Query(`
+		SELECT DISTINCT integration_id FROM webhook_inbox
+		WHERE status IN (?, ?) AND next_attempt_at <= ?`,
+		models.WebhookEventPending, models.WebhookEventProcessing, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due webhook events: %w", err)
+	}
+	var integrationIDs []string
+	for rows.Next() {
+		var integrationID string
+		if err := rows.Scan(&integrationID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		integrationIDs = append(integrationIDs, integrationID)
+	}
+	rows.Close()
+
+	processed := 0
+	var errs []error
+	for _, integrationID := range integrationIDs {
+		n, err := ws.ProcessIntegration(integrationID)
+		processed += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integrationID, err))
+		}
+	}
+	return processed, errors.Join(errs...)
+}
+
+// ProcessIntegration applies the due events of an integration in the order
+// they were received and returns the number applied. Events whose updates
+// fail are retried later without holding back the events after them;
+// every event carries the full state of its order or stock, and order
+// statuses never move an order backwards.
+func (ws *IntegrationWebhookService) ProcessIntegration(integrationID string) (int, error) {
+	lock := ws.lockFor(integrationID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	processed := 0
+	var afterID int64
+	for {
+		events, err := ws.queryEvents(`
+			WHERE integration_id = ? AND status IN (?, ?) AND next_attempt_at <= ? AND id > ?
+			ORDER BY id ASC LIMIT ?`,
+			integrationID, models.WebhookEventPending, models.WebhookEventProcessing, time.Now().UTC(), afterID, ws.config.BatchSize)
+		if err != nil {
+			return processed, err
+		}
+		for i := range events {
+			event := &events[i]
+			afterID = event.ID
+			claimed, err := ws.claim(event)
+			if err != nil {
+				return processed, err
+			}
+			if !claimed {
 				continue
 			}
+			if err := ws.process(event); err != nil {
+				return processed, err
+			}
+			if event.Status == models.WebhookEventProcessed {
+				processed++
+			}
+		}
+		if len(events) < ws.config.BatchSize {
+			return processed, nil
+		}
+	}
+}
+
+// claim marks an event as processing unless another worker claimed it
+// since it was read. The claim expires after ProcessingTimeout.
+func (ws *IntegrationWebhookService) claim(event *models.WebhookEvent) (bool, error) {
+	result, err := ws.repo.Exec(`
+		UPDATE webhook_inbox SET status = ?, attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ? AND status = ? AND attempts = ?`,
+		models.WebhookEventProcessing, time.Now().UTC().Add(ws.config.ProcessingTimeout),
+		event.ID, event.Status, event.Attempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook event %d: %w", event.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return false, nil
+	}
+	event.Status = models.WebhookEventProcessing
+	event.Attempts++
+	return true, nil
+}
+
+// process applies a claimed event and records the outcome: processed,
+// pending with the next retry, or failed once out of attempts
+func (ws *IntegrationWebhookService) process(event *models.WebhookEvent) error {
+	applyErr := ws.apply(event)
+
+	now := time.Now().UTC()
+	if applyErr == nil {
+		event.Status = models.WebhookEventProcessed
+		event.LastError = ""
+		event.ProcessedAt = &now
+	} else {
+		event.LastError = applyErr.Error()
+		if event.Attempts >= ws.config.MaxAttempts {
+			event.Status = models.WebhookEventFailed
+			ws.logger.Printf("Giving up %s webhook %s after %d attempts: %v", event.IntegrationID, event.EventID, event.Attempts, applyErr)
 		} else {
-			// Success
-			event.Processed = true
-			now := time.Now()
-			event.ProcessedAt = &now
-			event.Error = ""
-			break
+			event.Status = models.WebhookEventPending
+			event.NextAttemptAt = now.Add(ws.retryDelay(event.Attempts))
+			ws.logger.Printf("Retrying %s webhook %s at %s: %v", event.IntegrationID, event.EventID, event.NextAttemptAt.Format(time.RFC3339), applyErr)
 		}
 	}
-	
-	// Store webhook event for debugging/monitoring
-	ws.storeWebhookEvent(event)
+
+	_, err := ws.repo.Exec(`
+		UPDATE webhook_inbox SET status = ?, last_error = ?, next_attempt_at = ?, processed_at = ?
+		WHERE id = ?`,
+		event.Status, event.LastError, event.NextAttemptAt, event.ProcessedAt, event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook event %d: %w", event.ID, err)
+	}
+	return nil
+}
+
+// retryDelay is the backoff after a failed attempt, doubling from
+// RetryBackoff up to an hour
+func (ws *IntegrationWebhookService) retryDelay(attempts int) time.Duration {
+	delay := float64(ws.config.RetryBackoff) * math.Pow(2, float64(attempts-1))
+	if delay > float64(time.Hour) {
+		return time.Hour
+	}
+	return time.Duration(delay)
+}
+
+// apply parses a stored event again and applies its updates
+func (ws *IntegrationWebhookService) apply(event *models.WebhookEvent) error {
+	handler, err := ws.handlerFor(event.IntegrationID)
+	if err != nil {
+		return err
+	}
+	var headers http.Header
+	if event.Headers != "" {
+		if err := json.Unmarshal([]byte(event.Headers), &headers); err != nil {
+			return fmt.Errorf("failed to decode webhook headers: %w", err)
+		}
+	}
+	notification, err := handler.Parse([]byte(event.Payload), headers)
+	if err != nil {
+		return err
+	}
+
+	for i := range notification.Orders {
+		order := &notification.Orders[i]
+		if ws.config.Orders == nil {
+			return ErrNoOrderImport
+		}
+		if len(order.Items) == 0 {
+			// Status changes of orders not imported yet are left to the
+			// import, which brings the current status along
+			err := ws.config.Orders.ApplyOrderStatus(event.IntegrationID, order.ID, order.Status)
+			if err != nil && !errors.Is(err, ErrMarketplaceOrderNotFound) {
+				return fmt.Errorf("order %s: %w", order.ID, err)
+			}
+			continue
+		}
+		if _, err := ws.config.Orders.ImportOrder(event.IntegrationID, order); err != nil {
+			return fmt.Errorf("order %s: %w", order.ID, err)
+		}
+	}
+
+	for _, update := range notification.Stock {
+		if update.Stock == nil {
+			continue
+		}
+		if ws.config.Inventory == nil {
+			return ErrNoInventorySync
+		}
+		if err := ws.config.Inventory.ChannelStockReported(event.IntegrationID, update.SKU, *update.Stock); err != nil {
+			return fmt.Errorf("stock of %s: %w", update.SKU, err)
+		}
+	}
+	return nil
+}
+
+// Replay queues an event to be applied again, e.g. a failed event after
+// its cause was fixed
+func (ws *IntegrationWebhookService) Replay(eventID int64) (*models.WebhookEvent, error) {
+	event, err := ws.GetEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status == models.WebhookEventProcessing && event.NextAttemptAt.After(time.Now().UTC()) {
+		return nil, ErrWebhookEventPending
+	}
+
+	_, err = ws.repo.Exec(`
+		UPDATE webhook_inbox SET status = ?, attempts = 0, last_error = '', next_attempt_at = ?, processed_at = NULL
+		WHERE id = ?`,
+		models.WebhookEventPending, time.Now().UTC(), eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay webhook event: %w", err)
+	}
+	ws.schedule(event.IntegrationID)
+	return ws.GetEvent(eventID)
+}
+
+// GetEvent returns an event of the inbox
+func (ws *IntegrationWebhookService) GetEvent(eventID int64) (*models.WebhookEvent, error) {
+	events, err := ws.queryEvents(`WHERE id = ?`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrWebhookEventNotFound
+	}
+	return &events[0], nil
+}
+
+// ListEvents returns inbox events, newest first, optionally of one
+// integration and status, with the total number of matching events
+func (ws *IntegrationWebhookService) ListEvents(integrationID, status string, limit, offset int) ([]models.WebhookEvent, int, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	where := `WHERE 1 = 1`
+	var args []interface{}
+	if integrationID != "" {
+		where += ` AND integration_id = ?`
+		args = append(args, integrationID)
+	}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := ws.repo.QueryRow(`SELECT COUNT(*) FROM webhook_inbox `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook events: %w", err)
+	}
+	events, err := ws.queryEvents(where+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// queryEvents reads inbox events matching a where clause
+func (ws *IntegrationWebhookService) queryEvents(where string, args ...interface{}) ([]models.WebhookEvent, error) {
+	rows, err := ws.repo.Query(`
+		SELECT id, integration_id, event_id, event_type, payload, COALESCE(headers, ''), status, attempts,
+			COALESCE(last_error, ''), next_attempt_at, received_at, processed_at
+		FROM webhook_inbox `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.WebhookEvent, 0)
+	for rows.Next() {
+		var event models.WebhookEvent
+		var processedAt sql.NullTime
+		if err := rows.Scan(&event.ID, &event.IntegrationID, &event.EventID, &event.EventType, &event.Payload,
+			&event.Headers, &event.Status, &event.Attempts, &event.LastError, &event.NextAttemptAt,
+			&event.ReceivedAt, &processedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		if processedAt.Valid {
+			event.ProcessedAt = &processedAt.Time
+		}
+		events = append(events, event)
+	}
+	return events, nil
 }
 
 // extractIntegrationID extracts integration ID from request
@@ -202,54 +601,39 @@ func (ws *IntegrationWebhookService) extractIntegrationID(r *http.Request) strin
 	if integrationID := r.URL.Query().Get("integration_id"); integrationID != "" {
 		return integrationID
 	}
-	
+
 	// Try to get from headers
 	if integrationID := r.Header.Get("X-Integration-ID"); integrationID != "" {
 		return integrationID
 	}
-	
+
 	return ""
 }
 
-// extractEventType extracts event type from headers or payload
-func (ws *IntegrationWebhookService) extractEventType(headers map[string]string, body []byte) string {
-	// Try to get from headers
-	if eventType := headers["X-Event-Type"]; eventType != "" {
-		return eventType
-	}
-	
-	if eventType := headers["X-GitHub-Event"]; eventType != "" {
-		return eventType
-	}
-	
-	// Try to extract from payload
-	var payload map[string]interface{}
-	if err := json.Unmarshal(body, &payload); err != nil {
-		// Log the error but continue processing
-		log.Printf("WARN - Failed to unmarshal webhook payload for event type detection: %v", err)
-		return "unknown"
-	}
-	
-	if eventType, ok := payload["event_type"].(string); ok {
-		return eventType
-	}
-	if eventType, ok := payload["type"].(string); ok {
-		return eventType
-	}
-	
-	return "unknown"
+// validateHexSignature checks a hex HMAC-SHA256 signature sent as
+// "sha256=<hex>"
+func validateHexSignature(payload []byte, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expectedSignature := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
 }
 
-// generateEventID generates a unique event ID
-func (ws *IntegrationWebhookService) generateEventID() string {
-	return fmt.Sprintf("webhook_%d_%d", time.Now().UnixNano(), time.Now().Nanosecond())
+// validateBase64Signature checks a base64 HMAC-SHA256 signature
+func validateBase64Signature(payload []byte, signature, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expectedSignature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
-// storeWebhookEvent stores webhook event for monitoring
-func (ws *IntegrationWebhookService) storeWebhookEvent(event *WebhookEvent) {
-	// In a real implementation, this would store to database
-	// For now, we'll just log it
-	fmt.Printf("Webhook Event: %+v\n", event)
+// genericSignature returns the signature of integrations that sign with
+// X-Signature or X-Hub-Signature-256
+func genericSignature(headers http.Header) string {
+	if signature := headers.Get("X-Signature"); signature != "" {
+		return signature
+	}
+	return headers.Get("X-Hub-Signature-256")
 }
 
 // Specific webhook handlers
@@ -257,183 +641,140 @@ func (ws *IntegrationWebhookService) storeWebhookEvent(event *WebhookEvent) {
 // TrendyolWebhookHandler handles Trendyol webhooks
 type TrendyolWebhookHandler struct{}
 
-func (h *TrendyolWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Trendyol webhook processing
-	return nil
+func (h *TrendyolWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseTrendyolWebhook(payload, headers)
 }
 
-func (h *TrendyolWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *TrendyolWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, genericSignature(headers), secret)
 }
 
 func (h *TrendyolWebhookHandler) GetIntegrationType() string {
 	return "trendyol"
 }
 
-func (h *TrendyolWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
 // HepsiburadaWebhookHandler handles Hepsiburada webhooks
 type HepsiburadaWebhookHandler struct{}
 
-func (h *HepsiburadaWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Hepsiburada webhook processing
-	return nil
+func (h *HepsiburadaWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseHepsiburadaWebhook(payload, headers)
 }
 
-func (h *HepsiburadaWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *HepsiburadaWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	if signature := headers.Get("X-Hepsiburada-Signature"); signature != "" {
+		return validateBase64Signature(payload, signature, secret)
+	}
+	return validateHexSignature(payload, genericSignature(headers), secret)
 }
 
 func (h *HepsiburadaWebhookHandler) GetIntegrationType() string {
 	return "hepsiburada"
 }
 
-func (h *HepsiburadaWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
 // N11WebhookHandler handles N11 webhooks
 type N11WebhookHandler struct{}
 
-func (h *N11WebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement N11 webhook processing
-	return nil
+func (h *N11WebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseN11Webhook(payload, headers)
 }
 
-func (h *N11WebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *N11WebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, genericSignature(headers), secret)
 }
 
 func (h *N11WebhookHandler) GetIntegrationType() string {
 	return "n11"
 }
 
-func (h *N11WebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
-// AmazonWebhookHandler handles Amazon webhooks
+// AmazonWebhookHandler handles Amazon notifications relayed to the webhook
+// endpoint
 type AmazonWebhookHandler struct{}
 
-func (h *AmazonWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Amazon webhook processing
-	return nil
+func (h *AmazonWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseAmazonNotification(payload, headers)
 }
 
-func (h *AmazonWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *AmazonWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, genericSignature(headers), secret)
 }
 
 func (h *AmazonWebhookHandler) GetIntegrationType() string {
 	return "amazon"
 }
 
-func (h *AmazonWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
 // ShopifyWebhookHandler handles Shopify webhooks
 type ShopifyWebhookHandler struct{}
 
-func (h *ShopifyWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Shopify webhook processing
-	return nil
+func (h *ShopifyWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseShopifyWebhook(payload, headers)
 }
 
-func (h *ShopifyWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *ShopifyWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateBase64Signature(payload, headers.Get("X-Shopify-Hmac-Sha256"), secret)
 }
 
 func (h *ShopifyWebhookHandler) GetIntegrationType() string {
 	return "shopify"
 }
 
-func (h *ShopifyWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
 // WooCommerceWebhookHandler handles WooCommerce webhooks
 type WooCommerceWebhookHandler struct{}
 
-func (h *WooCommerceWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement WooCommerce webhook processing
-	return nil
+func (h *WooCommerceWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return marketplace.ParseWooCommerceWebhook(payload, headers)
 }
 
-func (h *WooCommerceWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *WooCommerceWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateBase64Signature(payload, headers.Get("X-WC-Webhook-Signature"), secret)
 }
 
 func (h *WooCommerceWebhookHandler) GetIntegrationType() string {
 	return "woocommerce"
 }
 
-func (h *WooCommerceWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
-// FacebookWebhookHandler handles Facebook webhooks
+// FacebookWebhookHandler handles Facebook webhooks. They carry no order or
+// stock updates and are only recorded.
 type FacebookWebhookHandler struct{}
 
-func (h *FacebookWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Facebook webhook processing
-	return nil
+func (h *FacebookWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return recordedWebhook(payload, "object")
 }
 
-func (h *FacebookWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *FacebookWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, headers.Get("X-Hub-Signature-256"), secret)
 }
 
 func (h *FacebookWebhookHandler) GetIntegrationType() string {
 	return "facebook"
 }
 
-func (h *FacebookWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
-
-// GoogleWebhookHandler handles Google webhooks
+// GoogleWebhookHandler handles Google webhooks. They carry no order or
+// stock updates and are only recorded.
 type GoogleWebhookHandler struct{}
 
-func (h *GoogleWebhookHandler) Handle(ctx context.Context, payload []byte, headers map[string]string) error {
-	// Implement Google webhook processing
-	return nil
+func (h *GoogleWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return recordedWebhook(payload, "type")
 }
 
-func (h *GoogleWebhookHandler) ValidateSignature(payload []byte, signature string, secret string) bool {
-	return h.validateHMACSHA256(payload, signature, secret)
+func (h *GoogleWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, genericSignature(headers), secret)
 }
 
 func (h *GoogleWebhookHandler) GetIntegrationType() string {
 	return "google"
 }
 
-func (h *GoogleWebhookHandler) validateHMACSHA256(payload []byte, signature string, secret string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte("sha256="+expectedSignature))
-}
\ No newline at end of file
+// recordedWebhook maps a webhook without updates, identified by its
+// payload's hash and typed by the payload field typeField
+func recordedWebhook(payload []byte, typeField string) (*marketplace.WebhookNotification, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	notification := &marketplace.WebhookNotification{ID: hex.EncodeToString(sum[:16]), Type: "unknown"}
+	if eventType, ok := fields[typeField].(string); ok && eventType != "" {
+		notification.Type = eventType
+	}
+	return notification, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// testWebhookHandler accepts any payload signed like Trendyol's webhooks
+type testWebhookHandler struct{}
+
+func (h *testWebhookHandler) Parse(payload []byte, headers http.Header) (*marketplace.WebhookNotification, error) {
+	return &marketplace.WebhookNotification{ID: string(payload), Type: "ping"}, nil
+}
+
+func (h *testWebhookHandler) ValidateSignature(payload []byte, headers http.Header, secret string) bool {
+	return validateHexSignature(payload, genericSignature(headers), secret)
+}
+
+func (h *testWebhookHandler) GetIntegrationType() string {
+	return "test"
+}
+
+func postWebhook(ws *IntegrationWebhookService, payload, secret string) int {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/integration?integration_id=test", strings.NewReader(payload))
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(payload))
+		r.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	w := httptest.NewRecorder()
+	ws.HandleWebhook(w, r)
+	return w.Code
+}
+
+func TestHandleWebhookRequiresSignature(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ws, err := NewIntegrationWebhookService(repo, IntegrationWebhookConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.RegisterHandler("test", &testWebhookHandler{})
+
+	if code := postWebhook(ws, "event-1", "anything"); code != http.StatusUnauthorized {
+		t.Fatalf("webhook of an integration without a secret: status %d, want 401", code)
+	}
+	ws.SetSecretKey("test", "s3cret")
+	if code := postWebhook(ws, "event-1", ""); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned webhook: status %d, want 401", code)
+	}
+	if code := postWebhook(ws, "event-1", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("webhook with a wrong signature: status %d, want 401", code)
+	}
+	if _, total, err := ws.ListEvents("test", "", 10, 0); err != nil || total != 0 {
+		t.Fatalf("%d rejected webhooks stored (err %v)", total, err)
+	}
+
+	if code := postWebhook(ws, "event-1", "s3cret"); code != http.StatusOK {
+		t.Fatalf("signed webhook: status %d, want 200", code)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, _, err := ws.ListEvents("test", "", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) == 1 && events[0].Status == models.WebhookEventProcessed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("events = %+v, want the signed webhook processed", events)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,940 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+)
+
+// Inventory sync errors
+var (
+	ErrAllocationRuleNotFound = errors.New("allocation rule not found")
+	ErrInvalidAllocationRule  = errors.New("invalid allocation rule")
+	ErrNoInventorySync        = errors.New("inventory sync is not configured")
+	ErrProductNotFound        = errors.New("product not found")
+)
+
+// Stock change reasons recorded with queued products
+const (
+	StockChangeOrder            = "order"
+	StockChangeCancellation     = "cancellation"
+	StockChangeReturn           = "return"
+	StockChangeMarketplaceOrder = "marketplace_order"
+	StockChangeManual           = "manual"
+	StockChangeRule             = "allocation_rule"
+	StockChangeSweep            = "sweep"
+	StockChangeChannelReport    = "channel_report"
+)
+
+// InventorySyncConfig holds inventory sync settings and collaborators
+type InventorySyncConfig struct {
+	// Integrations supplies the credentials of the marketplaces. Its
+	// Provider method is the default ProviderFactory.
+	Integrations *MarketplaceIntegrationsService
+	// ProviderFactory overrides how providers are created
+	ProviderFactory MarketplaceProviderFactory
+	// IntegrationIDs lists the channels stock is pushed to. It defaults to
+	// MarketplaceOrderIntegrations; integrations without credentials are
+	// skipped. Channels without a rule are ranked in this order.
+	IntegrationIDs []string
+	// StateMachine, when set, queues the products of cancelled orders
+	StateMachine *OrderStateMachine
+	// Cache, when set, drops the cached reads of products whose stock is
+	// set by hand
+	Cache *cache.EntityCache
+	// Debounce is how long changes are collected before they are pushed.
+	// It defaults to two seconds.
+	Debounce time.Duration
+	// MaxDelay bounds how long a steady stream of changes can postpone a
+	// push. It defaults to 30 seconds.
+	MaxDelay time.Duration
+	// BatchSize is the number of products sent per marketplace call. It
+	// defaults to 100.
+	BatchSize int
+	// ScarceStock is the stock at or below which channels stop overlapping
+	// and are served from one pool in priority order. It defaults to 5; a
+	// negative value lets channels always overlap.
+	ScarceStock int
+	// DefaultBufferStock is held back from channels without a rule
+	DefaultBufferStock int
+	// MaxAttempts is how often a product is retried after marketplace
+	// errors before it is dropped from the queue. It defaults to 5.
+	MaxAttempts int
+	// Timeout bounds the marketplace calls of one channel. It defaults to
+	// two minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// InventorySyncService keeps the stock offered on every connected
+// marketplace in line with the catalog. Stock changes queue their products;
+// queued products are pushed in debounced batches with the quantity each
+// channel's allocation rule gives it. A product leaves the queue only once
+// every channel has taken its new quantity, so a marketplace that is down
+// is caught up on the next flush.
+type InventorySyncService struct {
+	repo      database.SimpleRepository
+	config    InventorySyncConfig
+	providers MarketplaceProviderFactory
+	logger    *log.Logger
+
+	mu           sync.Mutex
+	timer        *time.Timer
+	pendingSince time.Time
+	flushing     sync.Mutex
+}
+
+// InventorySyncResult summarizes a flush of the sync queue
+type InventorySyncResult struct {
+	Products int            `json:"products"`
+	Pushed   map[string]int `json:"pushed"`
+	Rejected int            `json:"rejected"`
+	Failed   int            `json:"failed"`
+	Errors   []string       `json:"errors,omitempty"`
+}
+
+// queuedProduct is a product waiting in the sync queue with its stock
+type queuedProduct struct {
+	queueID  int64
+	version  int
+	attempts int
+
+	productID int64
+	sku       string
+	stock     int
+	channels  map[string]models.ChannelStock
+	failed    []string
+}
+
+// NewInventorySyncService creates a new inventory sync service and
+// registers its hooks with the state machine
+func NewInventorySyncService(repo database.SimpleRepository, config InventorySyncConfig) (*InventorySyncService, error) {
+	providers := config.ProviderFactory
+	if providers == nil {
+		if config.Integrations == nil {
+			return nil, ErrNoMarketplaceProvider
+		}
+		providers = config.Integrations.Provider
+	}
+	if len(config.IntegrationIDs) == 0 {
+		config.IntegrationIDs = MarketplaceOrderIntegrations
+	}
+	if config.Debounce <= 0 {
+		config.Debounce = 2 * time.Second
+	}
+	if config.MaxDelay < config.Debounce {
+		config.MaxDelay = 30 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.ScarceStock == 0 {
+		config.ScarceStock = 5
+	}
+	if config.DefaultBufferStock < 0 {
+		config.DefaultBufferStock = 0
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	s := &InventorySyncService{repo: repo, config: config, providers: providers, logger: logger}
+	if config.StateMachine != nil {
+		config.StateMachine.AddHook(models.OrderStatusCancelled, s.orderRestocked)
+	}
+	if config.Integrations != nil {
+		config.Integrations.inventory = s
+	}
+	return s, nil
+}
+
+// orderRestocked queues the products a cancelled order gave back
+func (s *InventorySyncService) orderRestocked(tc *TransitionContext) error {
+	productIDs := orderProductIDs(tc.Items)
+	for _, r := range tc.Reservations {
+		productIDs = append(productIDs, r.ProductID)
+	}
+	s.StockChanged(StockChangeCancellation, productIDs...)
+	return nil
+}
+
+// orderProductIDs returns the products of order items
+func orderProductIDs(items []models.OrderItem) []int64 {
+	productIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	return productIDs
+}
+
+// StockChanged queues products whose stock has changed and schedules a
+// push. It is called after the change is committed; failures are logged
+// and left to the sweep.
+func (s *InventorySyncService) StockChanged(reason string, productIDs ...int64) {
+	queued := 0
+	for _, productID := range productIDs {
+		if productID <= 0 {
+			continue
+		}
+		if err := s.enqueue(productID, reason); err != nil {
+			s.logger.Printf("Failed to queue inventory sync for product %d: %v", productID, err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		s.schedule()
+	}
+}
+
+// enqueue adds a product to the sync queue. A product already queued gets
+// a new version, so a flush that read the old one keeps it queued.
+func (s *InventorySyncService) enqueue(productID int64, reason string) error {
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		UPDATE inventory_sync_queue
+		SET reason = ?, version = version + 1, attempts = 0, last_error = '', queued_at = ?
+		WHERE product_id = ?`,
+		reason, now, productID)
+	if err != nil {
+		return fmt.Errorf("failed to queue product: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	_, err = s.repo.Exec(`
+		INSERT INTO inventory_sync_queue (product_id, reason, version, attempts, last_error, queued_at)
+		VALUES (?, ?, 1, 0, '', ?)`,
+		productID, reason, now)
+	if err != nil && !isUniqueViolation(err) {
+		return fmt.Errorf("failed to queue product: %w", err)
+	}
+	return nil
+}
+
+// schedule flushes the queue once no change has come in for the debounce
+// period, or after MaxDelay at the latest
+func (s *InventorySyncService) schedule() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.timer == nil {
+		s.pendingSince = now
+		s.timer = time.AfterFunc(s.config.Debounce, s.flushPending)
+		return
+	}
+	delay := s.config.Debounce
+	if remaining := s.pendingSince.Add(s.config.MaxDelay).Sub(now); remaining < delay {
+		delay = remaining
+	}
+	if delay > 0 {
+		s.timer.Reset(delay)
+	}
+}
+
+// flushPending runs the debounced flush
+func (s *InventorySyncService) flushPending() {
+	s.mu.Lock()
+	s.timer = nil
+	s.mu.Unlock()
+
+	if _, err := s.Flush(); err != nil {
+		s.logger.Printf("Inventory sync failed: %v", err)
+	}
+}
+
+// Stop cancels a pending debounced flush. Queued products stay queued for
+// the next flush.
+func (s *InventorySyncService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// SetStock sets the stock of a product by hand and queues it
+func (s *InventorySyncService) SetStock(productID int64, quantity int) error {
+	if quantity < 0 {
+		return fmt.Errorf("stock cannot be negative")
+	}
+	result, err := s.repo.Exec(`
+		UPDATE products SET
+			status = CASE
+				WHEN ? <= 0 AND status = ? THEN ?
+				WHEN ? > 0 AND status = ? THEN ?
+				ELSE status END,
+			stock = ?,
+			updated_at = ?
+		WHERE id = ?`,
+		quantity, ProductStatusActive, ProductStatusOutOfStock,
+		quantity, ProductStatusOutOfStock, ProductStatusActive,
+		quantity, time.Now().UTC(), productID)
+	if err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+
+	productsChanged(s.config.Cache, productID)
+	s.StockChanged(StockChangeManual, productID)
+	return nil
+}
+
+// ChannelStockReported records the stock a marketplace reports for a SKU,
+// e.g. through a webhook. The catalog stays the source of truth: a quantity
+// other than the one last pushed queues the product, so the next flush
+// pushes its allocation to the channel again. SKUs that are not in the
+// catalog and integrations stock is not pushed to are ignored.
+func (s *InventorySyncService) ChannelStockReported(integrationID, sku string, quantity int) error {
+	synced := false
+	for _, channel := range s.config.IntegrationIDs {
+		if channel == integrationID {
+			synced = true
+		}
+	}
+	if !synced || sku == "" {
+		return nil
+	}
+
+	var productID int64
+	err := s.repo.QueryRow(`SELECT id FROM products WHERE sku = ?`, sku).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get product %s: %w", sku, err)
+	}
+
+	var current int
+	err = s.repo.QueryRow(`SELECT quantity FROM inventory_channel_stock WHERE integration_id = ? AND product_id = ?`,
+		integrationID, productID).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Never pushed; the sweep queues it
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get channel stock: %w", err)
+	case current == quantity:
+		return nil
+	}
+
+	if _, err := s.repo.Exec(`UPDATE inventory_channel_stock SET quantity = ? WHERE integration_id = ? AND product_id = ?`,
+		quantity, integrationID, productID); err != nil {
+		return fmt.Errorf("failed to save channel stock: %w", err)
+	}
+	s.StockChanged(StockChangeChannelReport, productID)
+	return nil
+}
+
+// SweepChanges queues products changed since they were last pushed, and
+// products never pushed at all. It catches stock written by code that does
+// not report its changes.
+func (s *InventorySyncService) SweepChanges() (int, error) {
+	rows, err := s.repo.Query(`
+		SELECT p.id FROM products p
+		WHERE p.sku <> ''
+		AND NOT EXISTS (SELECT 1 FROM inventory_sync_queue q WHERE q.product_id = p.id)
+		AND (
+			NOT EXISTS (SELECT 1 FROM inventory_channel_stock c WHERE c.product_id = p.id)
+			OR p.updated_at > (SELECT MIN(c.updated_at) FROM inventory_channel_stock c WHERE c.product_id = p.id)
+		)
+		ORDER BY p.id ASC
+		LIMIT 1000`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find changed products: %w", err)
+	}
+	var productIDs []int64
+	for rows.Next() {
+		var productID int64
+		if err := rows.Scan(&productID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan changed product: %w", err)
+		}
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+
+	for _, productID := range productIDs {
+		if err := s.enqueue(productID, StockChangeSweep); err != nil {
+			return 0, err
+		}
+	}
+	return len(productIDs), nil
+}
+
+// Flush pushes the queued products to every configured channel. Channels
+// that fail keep their products queued; products a marketplace rejects as
+// invalid are recorded on the channel and not retried until they change
+// again.
+func (s *InventorySyncService) Flush() (*InventorySyncResult, error) {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+
+	result := &InventorySyncResult{Pushed: make(map[string]int)}
+	channels := s.channels()
+	if len(channels) == 0 {
+		return result, nil
+	}
+	rules, err := s.loadRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	lastID := int64(0)
+	for {
+		batch, err := s.loadQueue(lastID, s.config.BatchSize)
+		if err != nil {
+			return result, errors.Join(append(errs, err)...)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].queueID
+		if err := s.flushBatch(batch, channels, rules, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// flushBatch pushes one batch of queued products to every channel
+func (s *InventorySyncService) flushBatch(batch []*queuedProduct, channels []string, rules map[string]map[int64]models.InventoryAllocationRule, result *InventorySyncResult) error {
+	readAt := time.Now().UTC()
+	products := make([]*queuedProduct, 0, len(batch))
+	for _, p := range batch {
+		found, err := s.loadProduct(p)
+		if err != nil {
+			return err
+		}
+		if !found || p.sku == "" {
+			// Deleted products and products without a SKU cannot be
+			// matched on any marketplace
+			if err := s.dequeue(p); err != nil {
+				return err
+			}
+			continue
+		}
+		products = append(products, p)
+	}
+	if len(products) == 0 {
+		return nil
+	}
+	result.Products += len(products)
+
+	allocations := make(map[int64]map[string]int, len(products))
+	for _, p := range products {
+		allocations[p.productID] = s.allocation(p, channels, rules)
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		updates := make([]marketplace.StockPriceUpdate, 0, len(products))
+		pending := make(map[string]*queuedProduct, len(products))
+		for _, p := range products {
+			quantity := allocations[p.productID][channel]
+			if current, ok := p.channels[channel]; ok && current.Quantity == quantity && current.LastError == "" {
+				continue
+			}
+			stock := quantity
+			updates = append(updates, marketplace.StockPriceUpdate{SKU: p.sku, Stock: &stock})
+			pending[p.sku] = p
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		rejected, err := s.push(channel, updates)
+		for _, update := range updates {
+			p := pending[update.SKU]
+			switch {
+			case rejected[update.SKU] != "":
+				result.Rejected++
+				if err := s.saveChannelStock(channel, p, p.channels[channel].Quantity, rejected[update.SKU], nil, readAt); err != nil {
+					errs = append(errs, err)
+				}
+			case err != nil:
+				p.failed = append(p.failed, fmt.Sprintf("%s: %v", channel, err))
+				if err := s.saveChannelStock(channel, p, p.channels[channel].Quantity, err.Error(), nil, readAt); err != nil {
+					errs = append(errs, err)
+				}
+			default:
+				result.Pushed[channel]++
+				if err := s.saveChannelStock(channel, p, allocations[p.productID][channel], "", &readAt, readAt); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", channel, err))
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+
+	for _, p := range products {
+		if len(p.failed) == 0 {
+			if err := s.dequeue(p); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		result.Failed++
+		if err := s.retryLater(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// push sends stock updates to a channel
+func (s *InventorySyncService) push(integrationID string, updates []marketplace.StockPriceUpdate) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	provider, err := s.providers(ctx, integrationID)
+	if err != nil {
+		return nil, err
+	}
+	return pushStockPrice(ctx, provider, updates)
+}
+
+// pushStockPrice sends stock and price updates to a marketplace. Updates
+// the marketplace rejects as invalid are returned by SKU with their error,
+// and the others are sent again without them.
+func pushStockPrice(ctx context.Context, provider marketplace.MarketplaceProvider, updates []marketplace.StockPriceUpdate) (map[string]string, error) {
+	rejected := make(map[string]string)
+	for len(updates) > 0 {
+		err := provider.UpdateStockAndPrice(ctx, updates)
+		var validation *marketplace.ValidationError
+		if !errors.As(err, &validation) {
+			return rejected, err
+		}
+
+		remaining := updates[:0:0]
+		for _, e := range validation.Errors {
+			rejected[e.Key] = e.Error()
+		}
+		for _, update := range updates {
+			if _, ok := rejected[update.SKU]; !ok {
+				remaining = append(remaining, update)
+			}
+		}
+		if len(remaining) == len(updates) {
+			// The errors name no SKU of the batch, so none can be told apart
+			for _, update := range updates {
+				rejected[update.SKU] = validation.Error()
+			}
+			return rejected, nil
+		}
+		updates = remaining
+	}
+	return rejected, nil
+}
+
+// allocation returns the quantity each channel is offered of a product
+func (s *InventorySyncService) allocation(p *queuedProduct, channels []string, rules map[string]map[int64]models.InventoryAllocationRule) map[string]int {
+	channelRules := make([]models.InventoryAllocationRule, len(channels))
+	for i, channel := range channels {
+		channelRules[i] = s.ruleFor(channel, p.productID, rules)
+	}
+	return allocateStock(p.stock, channelRules, s.config.ScarceStock)
+}
+
+// ruleFor returns the rule of a channel for a product: the product's own
+// rule, the channel's rule, or the default of a full share less the default
+// buffer
+func (s *InventorySyncService) ruleFor(integrationID string, productID int64, rules map[string]map[int64]models.InventoryAllocationRule) models.InventoryAllocationRule {
+	if rule, ok := rules[integrationID][productID]; ok {
+		return rule
+	}
+	if rule, ok := rules[integrationID][0]; ok {
+		return rule
+	}
+	return models.InventoryAllocationRule{
+		IntegrationID: integrationID,
+		BufferStock:   s.config.DefaultBufferStock,
+		Percentage:    100,
+	}
+}
+
+// allocateStock splits a product's stock between channels. Each channel is
+// offered its percentage of the stock left after its buffer, capped at its
+// maximum. When the stock is at or below scarce the channels draw from one
+// pool in priority order, so the quantities offered never add up to more
+// than the stock. Ties keep the order of rules.
+func allocateStock(stock int, rules []models.InventoryAllocationRule, scarce int) map[string]int {
+	if stock < 0 {
+		stock = 0
+	}
+	ordered := make([]models.InventoryAllocationRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority < ordered[j].Priority
+	})
+
+	quantities := make(map[string]int, len(ordered))
+	pool := stock
+	for _, rule := range ordered {
+		quantity := 0
+		if stock > rule.BufferStock {
+			// The epsilon keeps shares like 30% of 10 from rounding down
+			quantity = int(math.Floor(float64(stock-rule.BufferStock)*rule.Percentage/100 + 1e-9))
+		}
+		if rule.MaxQuantity > 0 && quantity > rule.MaxQuantity {
+			quantity = rule.MaxQuantity
+		}
+		if stock <= scarce {
+			if quantity > pool {
+				quantity = pool
+			}
+			pool -= quantity
+		}
+		quantities[rule.IntegrationID] = quantity
+	}
+	return quantities
+}
+
+// Allocate returns the quantity each configured channel is offered of a
+// product with its current stock
+func (s *InventorySyncService) Allocate(productID int64) (map[string]int, error) {
+	rules, err := s.loadRules()
+	if err != nil {
+		return nil, err
+	}
+	p := &queuedProduct{productID: productID}
+	found, err := s.loadProduct(p)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrProductNotFound
+	}
+	return s.allocation(p, s.channels(), rules), nil
+}
+
+// channels returns the integrations stock is pushed to
+func (s *InventorySyncService) channels() []string {
+	channels := make([]string, 0, len(s.config.IntegrationIDs))
+	for _, integrationID := range s.config.IntegrationIDs {
+		if marketplaceConfigured(s.config.Integrations, s.config.ProviderFactory, integrationID) {
+			channels = append(channels, integrationID)
+		}
+	}
+	return channels
+}
+
+// loadQueue reads a batch of queued products
+func (s *InventorySyncService) loadQueue(afterID int64, limit int) ([]*queuedProduct, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, product_id, version, attempts FROM inventory_sync_queue
+		WHERE id > ? ORDER BY id ASC LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory sync queue: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []*queuedProduct
+	for rows.Next() {
+		p := &queuedProduct{}
+		if err := rows.Scan(&p.queueID, &p.productID, &p.version, &p.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory sync queue: %w", err)
+		}
+		batch = append(batch, p)
+	}
+	return batch, nil
+}
+
+// loadProduct reads the SKU and sellable stock of a queued product and the
+// quantities last pushed for it. Products that are not for sale have no
+// sellable stock.
+func (s *InventorySyncService) loadProduct(p *queuedProduct) (bool, error) {
+	var sku sql.NullString
+	var status string
+	err := s.repo.QueryRow(`SELECT sku, stock, status FROM products WHERE id = ?`, p.productID).
+		Scan(&sku, &p.stock, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get product %d: %w", p.productID, err)
+	}
+	p.sku = sku.String
+	if status != ProductStatusActive && status != ProductStatusOutOfStock {
+		p.stock = 0
+	}
+
+	channels, err := s.GetChannelStock(p.productID)
+	if err != nil {
+		return false, err
+	}
+	p.channels = make(map[string]models.ChannelStock, len(channels))
+	for _, c := range channels {
+		p.channels[c.IntegrationID] = c
+	}
+	return true, nil
+}
+
+// dequeue removes a product from the queue unless it was queued again
+// since it was read
+func (s *InventorySyncService) dequeue(p *queuedProduct) error {
+	if _, err := s.repo.Exec(`DELETE FROM inventory_sync_queue WHERE id = ? AND version = ?`, p.queueID, p.version); err != nil {
+		return fmt.Errorf("failed to dequeue product %d: %w", p.productID, err)
+	}
+	return nil
+}
+
+// retryLater keeps a product that failed on some channel queued, or drops
+// it once it has run out of attempts
+func (s *InventorySyncService) retryLater(p *queuedProduct) error {
+	if p.attempts+1 >= s.config.MaxAttempts {
+		s.logger.Printf("Giving up inventory sync of product %d after %d attempts: %v", p.productID, p.attempts+1, p.failed)
+		return s.dequeue(p)
+	}
+	lastError := p.failed[0]
+	if _, err := s.repo.Exec(`
+		UPDATE inventory_sync_queue SET attempts = attempts + 1, last_error = ?
+		WHERE id = ? AND version = ?`,
+		lastError, p.queueID, p.version); err != nil {
+		return fmt.Errorf("failed to update inventory sync queue: %w", err)
+	}
+	return nil
+}
+
+// saveChannelStock records the outcome of a push. updatedAt is when the
+// product was read, so changes made during the push are swept again.
+func (s *InventorySyncService) saveChannelStock(integrationID string, p *queuedProduct, quantity int, lastError string, syncedAt *time.Time, updatedAt time.Time) error {
+	if syncedAt == nil {
+		if current, ok := p.channels[integrationID]; ok {
+			syncedAt = current.SyncedAt
+		}
+	}
+	result, err := s.repo.Exec(`
+		UPDATE inventory_channel_stock
+		SET sku = ?, quantity = ?, last_error = ?, synced_at = ?, updated_at = ?
+		WHERE integration_id = ? AND product_id = ?`,
+		p.sku, quantity, lastError, syncedAt, updatedAt, integrationID, p.productID)
+	if err != nil {
+		return fmt.Errorf("failed to save channel stock: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	_, err = s.repo.Exec(`
+		INSERT INTO inventory_channel_stock (integration_id, product_id, sku, quantity, last_error, synced_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		integrationID, p.productID, p.sku, quantity, lastError, syncedAt, updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save channel stock: %w", err)
+	}
+	return nil
+}
+
+// GetChannelStock returns the quantities last pushed for a product
+func (s *InventorySyncService) GetChannelStock(productID int64) ([]models.ChannelStock, error) {
+	rows, err := s.repo.Query(`
+		SELECT integration_id, product_id, sku, quantity, COALESCE(last_error, ''), synced_at, updated_at
+		FROM inventory_channel_stock WHERE product_id = ? ORDER BY integration_id ASC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel stock: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []models.ChannelStock
+	for rows.Next() {
+		var c models.ChannelStock
+		var syncedAt sql.NullTime
+		if err := rows.Scan(&c.IntegrationID, &c.ProductID, &c.SKU, &c.Quantity, &c.LastError, &syncedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan channel stock: %w", err)
+		}
+		if syncedAt.Valid {
+			c.SyncedAt = &syncedAt.Time
+		}
+		channels = append(channels, c)
+	}
+	return channels, nil
+}
+
+// SaveRule creates or updates an allocation rule and queues the products
+// it applies to
+func (s *InventorySyncService) SaveRule(rule *models.InventoryAllocationRule) error {
+	if rule.IntegrationID == "" {
+		return fmt.Errorf("%w: integration is required", ErrInvalidAllocationRule)
+	}
+	if rule.ProductID < 0 || rule.BufferStock < 0 || rule.MaxQuantity < 0 {
+		return fmt.Errorf("%w: product, buffer and maximum cannot be negative", ErrInvalidAllocationRule)
+	}
+	if rule.Percentage < 0 || rule.Percentage > 100 {
+		return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidAllocationRule)
+	}
+
+	now := time.Now().UTC()
+	rule.UpdatedAt = now
+	if rule.ID == 0 {
+		rule.CreatedAt = now
+		result, err := s.repo.Exec(`
+			INSERT INTO inventory_allocation_rules
+				(integration_id, product_id, buffer_stock, percentage, max_quantity, priority, is_active, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			rule.IntegrationID, rule.ProductID, rule.BufferStock, rule.Percentage, rule.MaxQuantity,
+			rule.Priority, rule.IsActive, rule.CreatedAt, rule.UpdatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: %s already has a rule for product %d", ErrInvalidAllocationRule, rule.IntegrationID, rule.ProductID)
+			}
+			return fmt.Errorf("failed to create allocation rule: %w", err)
+		}
+		if rule.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get allocation rule ID: %w", err)
+		}
+	} else {
+		result, err := s.repo.Exec(`
+			UPDATE inventory_allocation_rules
+			SET buffer_stock = ?, percentage = ?, max_quantity = ?, priority = ?, is_active = ?, updated_at = ?
+			WHERE id = ?`,
+			rule.BufferStock, rule.Percentage, rule.MaxQuantity, rule.Priority, rule.IsActive, rule.UpdatedAt, rule.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update allocation rule: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrAllocationRuleNotFound
+		}
+	}
+
+	return s.requeueRule(rule.ProductID)
+}
+
+// DeleteRule removes an allocation rule and queues the products it
+// applied to
+func (s *InventorySyncService) DeleteRule(ruleID int64) error {
+	var productID int64
+	err := s.repo.QueryRow(`SELECT product_id FROM inventory_allocation_rules WHERE id = ?`, ruleID).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAllocationRuleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get allocation rule: %w", err)
+	}
+	if _, err := s.repo.Exec(`DELETE FROM inventory_allocation_rules WHERE id = ?`, ruleID); err != nil {
+		return fmt.Errorf("failed to delete allocation rule: %w", err)
+	}
+	return s.requeueRule(productID)
+}
+
+// requeueRule queues the products whose allocation a rule change affects:
+// the rule's product, or every product for a channel rule
+func (s *InventorySyncService) requeueRule(productID int64) error {
+	if productID > 0 {
+		s.StockChanged(StockChangeRule, productID)
+		return nil
+	}
+
+	rows, err := s.repo.Query(`SELECT id FROM products WHERE sku <> '' ORDER BY id ASC`)
+	if err != nil {
+		return fmt.Errorf("failed to get products: %w", err)
+	}
+	var productIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan product: %w", err)
+		}
+		productIDs = append(productIDs, id)
+	}
+	rows.Close()
+
+	s.StockChanged(StockChangeRule, productIDs...)
+	return nil
+}
+
+// GetRules returns the allocation rules of a channel, or of every channel
+// when integrationID is empty
+func (s *InventorySyncService) GetRules(integrationID string) ([]models.InventoryAllocationRule, error) {
+	query := `SELECT id, integration_id, product_id, buffer_stock, percentage, max_quantity, priority, is_active, created_at, updated_at
+		FROM inventory_allocation_rules`
+	args := []interface{}{}
+	if integrationID != "" {
+		query += ` WHERE integration_id = ?`
+		args = append(args, integrationID)
+	}
+	query += ` ORDER BY integration_id ASC, product_id ASC`
+
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allocation rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.InventoryAllocationRule
+	for rows.Next() {
+		var r models.InventoryAllocationRule
+		if err := rows.Scan(&r.ID, &r.IntegrationID, &r.ProductID, &r.BufferStock, &r.Percentage, &r.MaxQuantity,
+			&r.Priority, &r.IsActive, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan allocation rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// GetRule returns an allocation rule
+func (s *InventorySyncService) GetRule(ruleID int64) (*models.InventoryAllocationRule, error) {
+	var r models.InventoryAllocationRule
+	err := s.repo.QueryRow(`
+		SELECT id, integration_id, product_id, buffer_stock, percentage, max_quantity, priority, is_active, created_at, updated_at
+		FROM inventory_allocation_rules WHERE id = ?`, ruleID).
+		Scan(&r.ID, &r.IntegrationID, &r.ProductID, &r.BufferStock, &r.Percentage, &r.MaxQuantity,
+			&r.Priority, &r.IsActive, &r.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAllocationRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allocation rule: %w", err)
+	}
+	return &r, nil
+}
+
+// loadRules returns the active rules by channel and product
+func (s *InventorySyncService) loadRules() (map[string]map[int64]models.InventoryAllocationRule, error) {
+	rules, err := s.GetRules("")
+	if err != nil {
+		return nil, err
+	}
+	byChannel := make(map[string]map[int64]models.InventoryAllocationRule)
+	for _, rule := range rules {
+		if !rule.IsActive {
+			continue
+		}
+		if byChannel[rule.IntegrationID] == nil {
+			byChannel[rule.IntegrationID] = make(map[int64]models.InventoryAllocationRule)
+		}
+		byChannel[rule.IntegrationID][rule.ProductID] = rule
+	}
+	return byChannel, nil
+}
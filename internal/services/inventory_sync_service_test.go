@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+func TestAllocateStock(t *testing.T) {
+	full := func(channel string) models.InventoryAllocationRule {
+		return models.InventoryAllocationRule{IntegrationID: channel, Percentage: 100}
+	}
+	tests := []struct {
+		name   string
+		stock  int
+		rules  []models.InventoryAllocationRule
+		scarce int
+		want   map[string]int
+	}{
+		{"full shares overlap", 20, []models.InventoryAllocationRule{full("trendyol"), full("n11")}, 5,
+			map[string]int{"trendyol": 20, "n11": 20}},
+		{"buffer held back", 20, []models.InventoryAllocationRule{{IntegrationID: "trendyol", BufferStock: 5, Percentage: 100}}, 5,
+			map[string]int{"trendyol": 15}},
+		{"percentage rounds down", 10, []models.InventoryAllocationRule{{IntegrationID: "trendyol", Percentage: 30}, {IntegrationID: "n11", Percentage: 25}}, 5,
+			map[string]int{"trendyol": 3, "n11": 2}},
+		{"maximum caps", 20, []models.InventoryAllocationRule{{IntegrationID: "trendyol", Percentage: 100, MaxQuantity: 8}}, 5,
+			map[string]int{"trendyol": 8}},
+		{"stock within buffer", 3, []models.InventoryAllocationRule{{IntegrationID: "trendyol", BufferStock: 3, Percentage: 100}}, 5,
+			map[string]int{"trendyol": 0}},
+		{"scarce stock by priority", 4, []models.InventoryAllocationRule{
+			{IntegrationID: "n11", Percentage: 100, Priority: 2},
+			{IntegrationID: "trendyol", Percentage: 100, MaxQuantity: 3, Priority: 1},
+		}, 5, map[string]int{"trendyol": 3, "n11": 1}},
+		{"overlap when scarce is negative", 4, []models.InventoryAllocationRule{full("trendyol"), full("n11")}, -1,
+			map[string]int{"trendyol": 4, "n11": 4}},
+		{"negative stock", -2, []models.InventoryAllocationRule{full("trendyol")}, 5,
+			map[string]int{"trendyol": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocateStock(tt.stock, tt.rules, tt.scarce)
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocateStock = %v, want %v", got, tt.want)
+			}
+			for channel, quantity := range tt.want {
+				if got[channel] != quantity {
+					t.Fatalf("allocateStock = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func newTestInventorySync(t *testing.T, providers map[string]*fakeMarketplace) (*InventorySyncService, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	var channels []string
+	for channel := range providers {
+		channels = append(channels, channel)
+	}
+	s, err := NewInventorySyncService(repo, InventorySyncConfig{
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			return providers[integrationID], nil
+		},
+		IntegrationIDs: channels,
+		// Flushes are run by the tests
+		Debounce: time.Hour,
+		Logger:   testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	return s, repo
+}
+
+func TestInventoryFlushRetriesFailedChannel(t *testing.T) {
+	trendyol, n11 := &fakeMarketplace{}, &fakeMarketplace{err: errors.New("servis kullanılamıyor")}
+	s, repo := newTestInventorySync(t, map[string]*fakeMarketplace{"trendyol": trendyol, "n11": n11})
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 40, 12)
+	if err := s.SaveRule(&models.InventoryAllocationRule{IntegrationID: "n11", BufferStock: 2, Percentage: 50, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.StockChanged(StockChangeManual, productID)
+	result, err := s.Flush()
+	if err == nil {
+		t.Fatalf("flush with a failing channel succeeded")
+	}
+	if result.Pushed["trendyol"] != 1 || result.Failed != 1 || trendyol.stock["SKU-1"] != 12 {
+		t.Fatalf("result = %+v, trendyol stock %v", result, trendyol.stock)
+	}
+
+	// The product stays queued until the failed channel takes its quantity,
+	// and the channel that has it is not sent it again
+	trendyol.stock = nil
+	n11.err = nil
+	result, err = s.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pushed["n11"] != 1 || result.Pushed["trendyol"] != 0 || n11.stock["SKU-1"] != 5 {
+		t.Fatalf("result = %+v, n11 stock %v", result, n11.stock)
+	}
+	if result, err := s.Flush(); err != nil || result.Products != 0 {
+		t.Fatalf("queue not empty after the retry: %+v (err %v)", result, err)
+	}
+
+	stocks, err := s.GetChannelStock(productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stock := range stocks {
+		if stock.LastError != "" {
+			t.Fatalf("channel stock %+v keeps its error", stock)
+		}
+	}
+}
@@ -0,0 +1,1015 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+)
+
+// Marketplace catalog errors
+var (
+	ErrMarketplaceCategoryNotFound = errors.New("marketplace category not found")
+	ErrCategoryMappingNotFound     = errors.New("category mapping not found")
+	ErrAttributeMappingNotFound    = errors.New("attribute mapping not found")
+	ErrInvalidCatalogMapping       = errors.New("invalid catalog mapping")
+	ErrNoCategorySuggestions       = errors.New("category suggestions require the AI service")
+)
+
+// MarketplaceCatalogConfig holds catalog mapping settings and collaborators
+type MarketplaceCatalogConfig struct {
+	// Integrations supplies the credentials of the marketplaces. Its
+	// Provider method is the default ProviderFactory.
+	Integrations *MarketplaceIntegrationsService
+	// ProviderFactory overrides how providers are created
+	ProviderFactory MarketplaceProviderFactory
+	// AIService, when set, suggests marketplace categories
+	AIService *AIService
+	// IntegrationIDs lists the marketplaces whose catalogs are refreshed.
+	// It defaults to MarketplaceOrderIntegrations; integrations without
+	// credentials are skipped.
+	IntegrationIDs []string
+	// AttributeTTL is how long a downloaded attribute schema is used before
+	// it is downloaded again. It defaults to seven days.
+	AttributeTTL time.Duration
+	// Timeout bounds each marketplace call. It defaults to two minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// MarketplaceCatalogService caches the category trees, attribute schemas
+// and brands of the marketplaces and keeps the mappings of catalog
+// categories and product attributes to them. Listings are completed from
+// the mappings and checked against the target category's schema before
+// they are sent, so missing attributes are caught before the marketplace
+// rejects the listing.
+type MarketplaceCatalogService struct {
+	repo      database.SimpleRepository
+	config    MarketplaceCatalogConfig
+	providers MarketplaceProviderFactory
+	logger    *log.Logger
+}
+
+// CategorySuggestion is a marketplace category proposed for a product or
+// catalog category
+type CategorySuggestion struct {
+	ExternalCategoryID string  `json:"external_category_id"`
+	Path               string  `json:"path"`
+	Confidence         float64 `json:"confidence"`
+	// Source is "mapping" for categories mapped from a predicted catalog
+	// category and "keyword" for matches on the marketplace's tree
+	Source string `json:"source"`
+}
+
+// catalogProduct is what listing preparation needs of a product
+type catalogProduct struct {
+	id          int64
+	vendorID    int64
+	categoryID  int64
+	sku         string
+	name        string
+	description string
+	attributes  map[string]string
+}
+
+// attribute returns the first of the named product attributes that is set
+func (p *catalogProduct) attribute(names ...string) string {
+	for _, name := range names {
+		if value := p.attributes[strings.ToLower(name)]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// NewMarketplaceCatalogService creates a new marketplace catalog service
+func NewMarketplaceCatalogService(repo database.SimpleRepository, config MarketplaceCatalogConfig) (*MarketplaceCatalogService, error) {
+	providers := config.ProviderFactory
+	if providers == nil {
+		if config.Integrations == nil {
+			return nil, ErrNoMarketplaceProvider
+		}
+		providers = config.Integrations.Provider
+	}
+	if len(config.IntegrationIDs) == 0 {
+		config.IntegrationIDs = MarketplaceOrderIntegrations
+	}
+	if config.AttributeTTL <= 0 {
+		config.AttributeTTL = 7 * 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	s := &MarketplaceCatalogService{repo: repo, config: config, providers: providers, logger: logger}
+	if config.Integrations != nil {
+		config.Integrations.catalog = s
+	}
+	return s, nil
+}
+
+// provider returns the provider of an integration and a context bounded by
+// the call timeout
+func (s *MarketplaceCatalogService) provider(integrationID string) (marketplace.MarketplaceProvider, context.Context, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	provider, err := s.providers(ctx, integrationID)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return provider, ctx, cancel, nil
+}
+
+// SyncCategories downloads the category tree of a marketplace and replaces
+// the cached one. Paths and levels are rebuilt from the parent links, so
+// every marketplace's tree reads the same way.
+func (s *MarketplaceCatalogService) SyncCategories(integrationID string) (int, error) {
+	provider, ctx, cancel, err := s.provider(integrationID)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	categories, err := provider.GetCategories(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s categories: %w", integrationID, err)
+	}
+
+	byID := make(map[string]marketplace.Category, len(categories))
+	parents := make(map[string]bool)
+	for _, category := range categories {
+		byID[category.ID] = category
+		if category.ParentID != "" {
+			parents[category.ParentID] = true
+		}
+	}
+	paths := make(map[string]string, len(categories))
+	levels := make(map[string]int, len(categories))
+	var resolve func(id string, depth int) (string, int)
+	resolve = func(id string, depth int) (string, int) {
+		if path, ok := paths[id]; ok {
+			return path, levels[id]
+		}
+		category := byID[id]
+		path, level := category.Name, 0
+		// The depth bound guards against cycles in a broken tree
+		if parent, ok := byID[category.ParentID]; ok && category.ParentID != id && depth < 32 {
+			parentPath, parentLevel := resolve(parent.ID, depth+1)
+			path, level = parentPath+" > "+category.Name, parentLevel+1
+		}
+		paths[id], levels[id] = path, level
+		return path, level
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin category sync: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM marketplace_category_tree WHERE integration_id = ?`, integrationID); err != nil {
+		return 0, fmt.Errorf("failed to clear category tree: %w", err)
+	}
+	for _, category := range byID {
+		path, level := resolve(category.ID, 0)
+		if _, err := tx.Exec(`
+			INSERT INTO marketplace_category_tree (integration_id, external_id, name, parent_id, path, level, is_leaf, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			integrationID, category.ID, category.Name, category.ParentID, path, level, !parents[category.ID], now); err != nil {
+			return 0, fmt.Errorf("failed to cache category %s: %w", category.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit category sync: %w", err)
+	}
+	committed = true
+	return len(byID), nil
+}
+
+// SyncBrands downloads the brands of a marketplace and replaces the cached
+// ones
+func (s *MarketplaceCatalogService) SyncBrands(integrationID string) (int, error) {
+	provider, ctx, cancel, err := s.provider(integrationID)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	brands, err := provider.GetBrands(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s brands: %w", integrationID, err)
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin brand sync: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM marketplace_brands WHERE integration_id = ?`, integrationID); err != nil {
+		return 0, fmt.Errorf("failed to clear brands: %w", err)
+	}
+	seen := make(map[string]bool, len(brands))
+	for _, brand := range brands {
+		if seen[brand.ID] {
+			continue
+		}
+		seen[brand.ID] = true
+		if _, err := tx.Exec(`
+			INSERT INTO marketplace_brands (integration_id, external_id, name, synced_at) VALUES (?, ?, ?, ?)`,
+			integrationID, brand.ID, brand.Name, now); err != nil {
+			return 0, fmt.Errorf("failed to cache brand %s: %w", brand.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit brand sync: %w", err)
+	}
+	committed = true
+	return len(seen), nil
+}
+
+// RefreshAll downloads the category trees and brands of every configured
+// integration. An integration that fails does not stop the others; their
+// errors are joined.
+func (s *MarketplaceCatalogService) RefreshAll() (int, error) {
+	refreshed := 0
+	var errs []error
+	for _, integrationID := range s.config.IntegrationIDs {
+		if !marketplaceConfigured(s.config.Integrations, s.config.ProviderFactory, integrationID) {
+			continue
+		}
+		if _, err := s.SyncCategories(integrationID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integrationID, err))
+			continue
+		}
+		if _, err := s.SyncBrands(integrationID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integrationID, err))
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, errors.Join(errs...)
+}
+
+// GetCategory returns a cached marketplace category
+func (s *MarketplaceCatalogService) GetCategory(integrationID, externalID string) (*models.MarketplaceCategoryNode, error) {
+	var node models.MarketplaceCategoryNode
+	err := s.repo.QueryRow(`
+		SELECT integration_id, external_id, name, parent_id, path, level, is_leaf, synced_at
+		FROM marketplace_category_tree WHERE integration_id = ? AND external_id = ?`, integrationID, externalID).
+		Scan(&node.IntegrationID, &node.ExternalID, &node.Name, &node.ParentID, &node.Path, &node.Level, &node.IsLeaf, &node.SyncedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMarketplaceCategoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marketplace category: %w", err)
+	}
+	return &node, nil
+}
+
+// SearchCategories returns the cached leaf categories of a marketplace
+// whose path contains the query
+func (s *MarketplaceCatalogService) SearchCategories(integrationID, query string, limit int) ([]models.MarketplaceCategoryNode, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.queryCategories(`
+		SELECT integration_id, external_id, name, parent_id, path, level, is_leaf, synced_at
+		FROM marketplace_category_tree
+		WHERE integration_id = ? AND is_leaf = ? AND LOWER(path) LIKE ?
+		ORDER BY path ASC LIMIT ?`,
+		integrationID, true, "%"+strings.ToLower(query)+"%", limit)
+}
+
+// queryCategories runs a query returning cached categories
+func (s *MarketplaceCatalogService) queryCategories(query string, args ...interface{}) ([]models.MarketplaceCategoryNode, error) {
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marketplace categories: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []models.MarketplaceCategoryNode
+	for rows.Next() {
+		var node models.MarketplaceCategoryNode
+		if err := rows.Scan(&node.IntegrationID, &node.ExternalID, &node.Name, &node.ParentID, &node.Path,
+			&node.Level, &node.IsLeaf, &node.SyncedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan marketplace category: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// GetCategoryAttributes returns the attribute schema of a marketplace
+// category. The cached schema is used while it is fresh; a stale one is
+// still used when the marketplace cannot be reached.
+func (s *MarketplaceCatalogService) GetCategoryAttributes(integrationID, categoryID string) ([]models.MarketplaceAttribute, error) {
+	cached, err := s.cachedAttributes(integrationID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cached) > 0 && time.Since(cached[0].SyncedAt) < s.config.AttributeTTL {
+		return cached, nil
+	}
+
+	attributes, err := s.syncAttributes(integrationID, categoryID)
+	if err != nil {
+		if len(cached) > 0 {
+			s.logger.Printf("Using stale %s attributes of category %s: %v", integrationID, categoryID, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// syncAttributes downloads the attribute schema of a category and replaces
+// the cached one
+func (s *MarketplaceCatalogService) syncAttributes(integrationID, categoryID string) ([]models.MarketplaceAttribute, error) {
+	provider, ctx, cancel, err := s.provider(integrationID)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	schema, err := provider.GetCategoryAttributes(ctx, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s attributes of category %s: %w", integrationID, categoryID, err)
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin attribute sync: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM marketplace_category_attributes WHERE integration_id = ? AND category_id = ?`,
+		integrationID, categoryID); err != nil {
+		return nil, fmt.Errorf("failed to clear category attributes: %w", err)
+	}
+	attributes := make([]models.MarketplaceAttribute, 0, len(schema))
+	for _, a := range schema {
+		attribute := models.MarketplaceAttribute{
+			IntegrationID: integrationID,
+			CategoryID:    categoryID,
+			AttributeID:   a.ID,
+			Name:          a.Name,
+			Required:      a.Required,
+			AllowCustom:   a.AllowCustom,
+			Varianter:     a.Varianter,
+			SyncedAt:      now,
+		}
+		for _, value := range a.Values {
+			attribute.Values = append(attribute.Values, models.MarketplaceAttributeValue{ID: value.ID, Name: value.Name})
+		}
+		values, err := json.Marshal(attribute.Values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode attribute values: %w", err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO marketplace_category_attributes
+				(integration_id, category_id, attribute_id, name, required, allow_custom, varianter, attribute_values, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			integrationID, categoryID, attribute.AttributeID, attribute.Name, attribute.Required, attribute.AllowCustom,
+			attribute.Varianter, string(values), now); err != nil {
+			return nil, fmt.Errorf("failed to cache attribute %s: %w", attribute.AttributeID, err)
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit attribute sync: %w", err)
+	}
+	committed = true
+	return attributes, nil
+}
+
+// cachedAttributes reads the cached attribute schema of a category
+func (s *MarketplaceCatalogService) cachedAttributes(integrationID, categoryID string) ([]models.MarketplaceAttribute, error) {
+	rows, err := s.repo.Query(`
+		SELECT integration_id, category_id, attribute_id, name, required, allow_custom, varianter,
+			COALESCE(attribute_values, ''), synced_at
+		FROM marketplace_category_attributes
+		WHERE integration_id = ? AND category_id = ?
+		ORDER BY id ASC`, integrationID, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attributes []models.MarketplaceAttribute
+	for rows.Next() {
+		var a models.MarketplaceAttribute
+		var values string
+		if err := rows.Scan(&a.IntegrationID, &a.CategoryID, &a.AttributeID, &a.Name, &a.Required, &a.AllowCustom,
+			&a.Varianter, &values, &a.SyncedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan category attribute: %w", err)
+		}
+		if values != "" {
+			if err := json.Unmarshal([]byte(values), &a.Values); err != nil {
+				return nil, fmt.Errorf("failed to decode attribute values: %w", err)
+			}
+		}
+		attributes = append(attributes, a)
+	}
+	return attributes, nil
+}
+
+// FindBrand returns the cached marketplace brand with the given name
+func (s *MarketplaceCatalogService) FindBrand(integrationID, name string) (*models.MarketplaceBrand, error) {
+	var brand models.MarketplaceBrand
+	err := s.repo.QueryRow(`
+		SELECT integration_id, external_id, name, synced_at FROM marketplace_brands
+		WHERE integration_id = ? AND LOWER(name) = ?`, integrationID, strings.ToLower(strings.TrimSpace(name))).
+		Scan(&brand.IntegrationID, &brand.ExternalID, &brand.Name, &brand.SyncedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+// MapCategory maps a catalog category to a marketplace category, for one
+// vendor or, with VendorID 0, for the whole platform. Once the
+// marketplace's tree is cached the target must be a leaf of it.
+func (s *MarketplaceCatalogService) MapCategory(mapping *models.MarketplaceCategory) error {
+	if mapping.MarketplaceName == "" || mapping.CategoryID == 0 || mapping.ExternalCategoryID == "" {
+		return fmt.Errorf("%w: marketplace, category and marketplace category are required", ErrInvalidCatalogMapping)
+	}
+
+	node, err := s.GetCategory(mapping.MarketplaceName, mapping.ExternalCategoryID)
+	switch {
+	case err == nil:
+		if !node.IsLeaf {
+			return fmt.Errorf("%w: %s is not a leaf category", ErrInvalidCatalogMapping, node.Path)
+		}
+		mapping.ExternalCategoryName = node.Name
+		mapping.ExternalPath = node.Path
+	case errors.Is(err, ErrMarketplaceCategoryNotFound):
+		var cached int
+		if err := s.repo.QueryRow(`SELECT COUNT(*) FROM marketplace_category_tree WHERE integration_id = ?`,
+			mapping.MarketplaceName).Scan(&cached); err != nil {
+			return fmt.Errorf("failed to count cached categories: %w", err)
+		}
+		if cached > 0 {
+			return fmt.Errorf("%w: %s has no category %s", ErrInvalidCatalogMapping, mapping.MarketplaceName, mapping.ExternalCategoryID)
+		}
+	default:
+		return err
+	}
+
+	now := time.Now().UTC()
+	mapping.IsActive = true
+	mapping.UpdatedAt = now
+	if mapping.SyncStatus == "" {
+		mapping.SyncStatus = models.SyncStatusPending
+	}
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_categories
+		SET external_category_id = ?, external_category_name = ?, external_path = ?, is_active = ?, updated_at = ?
+		WHERE marketplace_name = ? AND vendor_id = ? AND category_id = ?`,
+		mapping.ExternalCategoryID, mapping.ExternalCategoryName, mapping.ExternalPath, mapping.IsActive, now,
+		mapping.MarketplaceName, mapping.VendorID, mapping.CategoryID)
+	if err != nil {
+		return fmt.Errorf("failed to update category mapping: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	mapping.CreatedAt = now
+	result, err = s.repo.Exec(`
+		INSERT INTO marketplace_categories (category_id, vendor_id, marketplace_name, external_category_id,
+			external_category_name, external_path, is_active, commission_rate, sync_status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		mapping.CategoryID, mapping.VendorID, mapping.MarketplaceName, mapping.ExternalCategoryID,
+		mapping.ExternalCategoryName, mapping.ExternalPath, mapping.IsActive, mapping.CommissionRate,
+		mapping.SyncStatus, mapping.CreatedAt, mapping.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create category mapping: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	mapping.ID = uint(id)
+	return nil
+}
+
+// GetCategoryMapping returns the marketplace category a vendor's products
+// of a catalog category are listed in: the vendor's own mapping, or the
+// platform's
+func (s *MarketplaceCatalogService) GetCategoryMapping(integrationID string, vendorID, categoryID int64) (*models.MarketplaceCategory, error) {
+	mappings, err := s.queryCategoryMappings(`
+		WHERE marketplace_name = ? AND category_id = ? AND vendor_id IN (?, 0) AND is_active = ?
+		ORDER BY vendor_id DESC LIMIT 1`, integrationID, categoryID, vendorID, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) == 0 {
+		return nil, ErrCategoryMappingNotFound
+	}
+	return &mappings[0], nil
+}
+
+// GetCategoryMappings returns the category mappings a vendor sees: its own
+// and the platform's
+func (s *MarketplaceCatalogService) GetCategoryMappings(integrationID string, vendorID int64) ([]models.MarketplaceCategory, error) {
+	return s.queryCategoryMappings(`
+		WHERE marketplace_name = ? AND vendor_id IN (?, 0)
+		ORDER BY category_id ASC, vendor_id DESC`, integrationID, vendorID)
+}
+
+// DeleteCategoryMapping removes a category mapping of a vendor. Vendor 0
+// removes platform mappings.
+func (s *MarketplaceCatalogService) DeleteCategoryMapping(id uint, vendorID int64) error {
+	result, err := s.repo.Exec(`DELETE FROM marketplace_categories WHERE id = ? AND vendor_id = ?`, id, vendorID)
+	if err != nil {
+		return fmt.Errorf("failed to delete category mapping: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrCategoryMappingNotFound
+	}
+	return nil
+}
+
+// queryCategoryMappings reads category mappings matching a where clause
+func (s *MarketplaceCatalogService) queryCategoryMappings(where string, args ...interface{}) ([]models.MarketplaceCategory, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, category_id, vendor_id, marketplace_name, external_category_id, external_category_name,
+			external_path, is_active, commission_rate, last_sync_at, sync_status, COALESCE(sync_error, ''),
+			created_at, updated_at
+		FROM marketplace_categories `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.MarketplaceCategory
+	for rows.Next() {
+		var m models.MarketplaceCategory
+		var lastSyncAt sql.NullTime
+		var status string
+		if err := rows.Scan(&m.ID, &m.CategoryID, &m.VendorID, &m.MarketplaceName, &m.ExternalCategoryID,
+			&m.ExternalCategoryName, &m.ExternalPath, &m.IsActive, &m.CommissionRate, &lastSyncAt, &status,
+			&m.SyncError, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan category mapping: %w", err)
+		}
+		if lastSyncAt.Valid {
+			m.LastSyncAt = &lastSyncAt.Time
+		}
+		m.SyncStatus = models.SyncStatus(status)
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// SaveAttributeMapping creates or updates an attribute mapping. The target
+// attribute must be in the category's schema, and a mapped value must be
+// one of its values.
+func (s *MarketplaceCatalogService) SaveAttributeMapping(mapping *models.MarketplaceAttributeMapping) error {
+	if mapping.IntegrationID == "" || mapping.ExternalCategoryID == "" || mapping.ExternalAttributeID == "" {
+		return fmt.Errorf("%w: marketplace, category and attribute are required", ErrInvalidCatalogMapping)
+	}
+	if mapping.Value != "" && mapping.ExternalValueID == "" {
+		return fmt.Errorf("%w: a mapped value needs the marketplace value", ErrInvalidCatalogMapping)
+	}
+	if mapping.Value == "" && mapping.AttributeName == "" && mapping.DefaultValue == "" {
+		return fmt.Errorf("%w: an attribute mapping needs a product attribute or a default value", ErrInvalidCatalogMapping)
+	}
+
+	schema, err := s.GetCategoryAttributes(mapping.IntegrationID, mapping.ExternalCategoryID)
+	if err != nil {
+		return err
+	}
+	attribute := findMarketplaceAttribute(schema, mapping.ExternalAttributeID)
+	if attribute == nil {
+		return fmt.Errorf("%w: category %s has no attribute %s", ErrInvalidCatalogMapping, mapping.ExternalCategoryID, mapping.ExternalAttributeID)
+	}
+	if mapping.ExternalValueID != "" && len(attribute.Values) > 0 && findAttributeValue(attribute.Values, mapping.ExternalValueID, "") == nil {
+		return fmt.Errorf("%w: %s has no value %s", ErrInvalidCatalogMapping, attribute.Name, mapping.ExternalValueID)
+	}
+
+	now := time.Now().UTC()
+	mapping.UpdatedAt = now
+	if mapping.ID > 0 {
+		result, err := s.repo.Exec(`
+			UPDATE marketplace_attribute_mappings
+			SET attribute_name = ?, value = ?, external_value_id = ?, default_value = ?, updated_at = ?
+			WHERE id = ? AND vendor_id = ?`,
+			mapping.AttributeName, mapping.Value, mapping.ExternalValueID, mapping.DefaultValue, now, mapping.ID, mapping.VendorID)
+		if err != nil {
+			return fmt.Errorf("failed to update attribute mapping: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrAttributeMappingNotFound
+		}
+		return nil
+	}
+
+	mapping.CreatedAt = now
+	result, err := s.repo.Exec(`
+		INSERT INTO marketplace_attribute_mappings (integration_id, vendor_id, external_category_id, external_attribute_id,
+			attribute_name, value, external_value_id, default_value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		mapping.IntegrationID, mapping.VendorID, mapping.ExternalCategoryID, mapping.ExternalAttributeID,
+		mapping.AttributeName, mapping.Value, mapping.ExternalValueID, mapping.DefaultValue, mapping.CreatedAt, mapping.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s is already mapped", ErrInvalidCatalogMapping, attribute.Name)
+		}
+		return fmt.Errorf("failed to create attribute mapping: %w", err)
+	}
+	mapping.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// DeleteAttributeMapping removes an attribute mapping of a vendor. Vendor 0
+// removes platform mappings.
+func (s *MarketplaceCatalogService) DeleteAttributeMapping(id, vendorID int64) error {
+	result, err := s.repo.Exec(`DELETE FROM marketplace_attribute_mappings WHERE id = ? AND vendor_id = ?`, id, vendorID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute mapping: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrAttributeMappingNotFound
+	}
+	return nil
+}
+
+// GetAttributeMappings returns the attribute mappings of a marketplace
+// category a vendor sees. Its own mappings come before the platform's.
+func (s *MarketplaceCatalogService) GetAttributeMappings(integrationID string, vendorID int64, categoryID string) ([]models.MarketplaceAttributeMapping, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, integration_id, vendor_id, external_category_id, external_attribute_id, attribute_name, value,
+			external_value_id, default_value, created_at, updated_at
+		FROM marketplace_attribute_mappings
+		WHERE integration_id = ? AND external_category_id = ? AND vendor_id IN (?, 0)
+		ORDER BY vendor_id DESC, external_attribute_id ASC, value ASC`, integrationID, categoryID, vendorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attribute mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.MarketplaceAttributeMapping
+	for rows.Next() {
+		var m models.MarketplaceAttributeMapping
+		if err := rows.Scan(&m.ID, &m.IntegrationID, &m.VendorID, &m.ExternalCategoryID, &m.ExternalAttributeID,
+			&m.AttributeName, &m.Value, &m.ExternalValueID, &m.DefaultValue, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// PrepareListing completes a listing of a catalog product for a
+// marketplace. The category comes from the category mapping and every
+// attribute of the category's schema is filled from the product's
+// attributes through the attribute mappings. Everything the schema
+// requires but the product cannot supply is returned as a
+// *marketplace.ValidationError. Listings without a product are returned as
+// they are.
+func (s *MarketplaceCatalogService) PrepareListing(integrationID string, listing marketplace.Listing) (marketplace.Listing, error) {
+	if listing.ProductID <= 0 {
+		return listing, nil
+	}
+	product, err := s.loadProduct(int64(listing.ProductID))
+	if err != nil {
+		return listing, err
+	}
+	if listing.SKU == "" {
+		listing.SKU = product.sku
+	}
+	invalid := &marketplace.ValidationError{Provider: integrationID}
+	fail := func(field, format string, args ...interface{}) {
+		invalid.Errors = append(invalid.Errors, &marketplace.MappingError{
+			Provider: integrationID,
+			Entity:   marketplace.EntityListing,
+			Key:      listing.SKU,
+			Field:    field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if listing.CategoryID == "" {
+		mapping, err := s.GetCategoryMapping(integrationID, product.vendorID, product.categoryID)
+		if errors.Is(err, ErrCategoryMappingNotFound) {
+			fail("category_id", "category %d is not mapped to a %s category", product.categoryID, integrationID)
+			return listing, invalid
+		}
+		if err != nil {
+			return listing, err
+		}
+		listing.CategoryID = mapping.ExternalCategoryID
+		listing.Category = mapping.ExternalPath
+	}
+
+	if listing.Brand == "" {
+		listing.Brand = product.attribute("brand", "marka")
+	}
+	if listing.BrandID == "" && listing.Brand != "" {
+		if brand, err := s.FindBrand(integrationID, listing.Brand); err == nil {
+			listing.BrandID = brand.ExternalID
+		}
+	}
+
+	schema, err := s.GetCategoryAttributes(integrationID, listing.CategoryID)
+	if err != nil {
+		return listing, err
+	}
+	mappings, err := s.GetAttributeMappings(integrationID, product.vendorID, listing.CategoryID)
+	if err != nil {
+		return listing, err
+	}
+
+	given := make(map[string]bool, len(listing.Attributes))
+	for _, attribute := range listing.Attributes {
+		if attribute.ID != "" {
+			given[attribute.ID] = true
+		}
+	}
+	for i := range schema {
+		attribute := &schema[i]
+		if given[attribute.AttributeID] || marketplace.FilledFromListing(integrationID, attribute.AttributeID) {
+			continue
+		}
+		field := "attributes." + attribute.Name
+
+		value, defaultValue := product.attribute(attribute.Name), ""
+		if mapping := attributeMapping(mappings, attribute.AttributeID, ""); mapping != nil {
+			if mapping.AttributeName != "" {
+				value = product.attribute(mapping.AttributeName)
+			}
+			defaultValue = mapping.DefaultValue
+		}
+		if value == "" {
+			value = defaultValue
+		}
+		if value == "" {
+			if attribute.Required {
+				fail(field, "is required; give the product the attribute or map one to it")
+			}
+			continue
+		}
+
+		valueID := ""
+		if mapping := attributeMapping(mappings, attribute.AttributeID, value); mapping != nil {
+			valueID = mapping.ExternalValueID
+		} else if match := findAttributeValue(attribute.Values, "", value); match != nil {
+			valueID = match.ID
+		}
+		if valueID == "" && len(attribute.Values) > 0 && !attribute.AllowCustom {
+			fail(field, "%q is not one of the %d values %s accepts; map it to one", value, len(attribute.Values), integrationID)
+			continue
+		}
+
+		listing.Attributes = append(listing.Attributes, marketplace.Attribute{
+			ID:      attribute.AttributeID,
+			Name:    attribute.Name,
+			Value:   value,
+			ValueID: valueID,
+		})
+	}
+
+	if len(invalid.Errors) > 0 {
+		return listing, invalid
+	}
+	return listing, nil
+}
+
+// ValidateProduct checks a product against the schema of the marketplace
+// category it maps to. It returns a *marketplace.ValidationError listing
+// every problem, or nil when the product can be listed.
+func (s *MarketplaceCatalogService) ValidateProduct(integrationID string, productID int64) error {
+	listing := marketplace.Listing{ProductID: int(productID)}
+	_, err := s.PrepareListing(integrationID, listing)
+	return err
+}
+
+// attributeMapping returns the first mapping of a marketplace attribute,
+// for a value or, with an empty value, for the attribute itself
+func attributeMapping(mappings []models.MarketplaceAttributeMapping, attributeID, value string) *models.MarketplaceAttributeMapping {
+	for i := range mappings {
+		m := &mappings[i]
+		if m.ExternalAttributeID != attributeID {
+			continue
+		}
+		if value == "" && m.Value == "" || value != "" && strings.EqualFold(m.Value, value) {
+			return m
+		}
+	}
+	return nil
+}
+
+// findMarketplaceAttribute returns an attribute of a schema by ID
+func findMarketplaceAttribute(schema []models.MarketplaceAttribute, attributeID string) *models.MarketplaceAttribute {
+	for i := range schema {
+		if schema[i].AttributeID == attributeID {
+			return &schema[i]
+		}
+	}
+	return nil
+}
+
+// findAttributeValue returns a fixed value by ID or, case-insensitively,
+// by name
+func findAttributeValue(values []models.MarketplaceAttributeValue, id, name string) *models.MarketplaceAttributeValue {
+	name = strings.TrimSpace(name)
+	for i := range values {
+		if id != "" && values[i].ID == id || name != "" && strings.EqualFold(values[i].Name, name) {
+			return &values[i]
+		}
+	}
+	return nil
+}
+
+// loadProduct reads a product with its attributes
+func (s *MarketplaceCatalogService) loadProduct(productID int64) (*catalogProduct, error) {
+	product := &catalogProduct{id: productID, attributes: make(map[string]string)}
+	var description sql.NullString
+	err := s.repo.QueryRow(`SELECT vendor_id, category_id, sku, name, description FROM products WHERE id = ?`, productID).
+		Scan(&product.vendorID, &product.categoryID, &product.sku, &product.name, &description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	product.description = description.String
+
+	rows, err := s.repo.Query(`SELECT name, value FROM product_attributes WHERE product_id = ? ORDER BY id ASC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product attributes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan product attribute: %w", err)
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := product.attributes[key]; !ok {
+			product.attributes[key] = strings.TrimSpace(value)
+		}
+	}
+	return product, nil
+}
+
+// SuggestCategories proposes marketplace categories for a product. The AI
+// service predicts the product's catalog categories, whose mappings are
+// proposed with the prediction's confidence; the product's text is also
+// matched against the marketplace's leaf categories.
+func (s *MarketplaceCatalogService) SuggestCategories(integrationID string, productID int64, limit int) ([]CategorySuggestion, error) {
+	if s.config.AIService == nil {
+		return nil, ErrNoCategorySuggestions
+	}
+	product, err := s.loadProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make(map[string]*CategorySuggestion)
+	predictions, err := s.config.AIService.PredictProductCategory(product.name, product.description)
+	if err != nil {
+		return nil, err
+	}
+	for _, prediction := range predictions {
+		mapping, err := s.GetCategoryMapping(integrationID, product.vendorID, int64(prediction.CategoryID))
+		if errors.Is(err, ErrCategoryMappingNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		suggest(suggestions, CategorySuggestion{
+			ExternalCategoryID: mapping.ExternalCategoryID,
+			Path:               mapping.ExternalPath,
+			Confidence:         prediction.Confidence,
+			Source:             "mapping",
+		})
+	}
+
+	keywords, err := s.matchCategories(integrationID, product.name+" "+product.description)
+	if err != nil {
+		return nil, err
+	}
+	for _, suggestion := range keywords {
+		suggest(suggestions, suggestion)
+	}
+
+	return rankSuggestions(suggestions, limit), nil
+}
+
+// SuggestCategoryMapping proposes marketplace categories for a catalog
+// category by matching its name against the marketplace's leaf categories
+func (s *MarketplaceCatalogService) SuggestCategoryMapping(integrationID string, categoryID int64, limit int) ([]CategorySuggestion, error) {
+	if s.config.AIService == nil {
+		return nil, ErrNoCategorySuggestions
+	}
+	var name string
+	var description sql.NullString
+	err := s.repo.QueryRow(`SELECT name, description FROM categories WHERE id = ?`, categoryID).Scan(&name, &description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("category %d not found", categoryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	matches, err := s.matchCategories(integrationID, name+" "+description.String)
+	if err != nil {
+		return nil, err
+	}
+	suggestions := make(map[string]*CategorySuggestion, len(matches))
+	for _, suggestion := range matches {
+		suggest(suggestions, suggestion)
+	}
+	return rankSuggestions(suggestions, limit), nil
+}
+
+// matchCategories scores the marketplace's leaf categories against a text
+// with the AI service's keyword matching
+func (s *MarketplaceCatalogService) matchCategories(integrationID, text string) ([]CategorySuggestion, error) {
+	leaves, err := s.queryCategories(`
+		SELECT integration_id, external_id, name, parent_id, path, level, is_leaf, synced_at
+		FROM marketplace_category_tree WHERE integration_id = ? AND is_leaf = ?`, integrationID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	text = strings.ToLower(text)
+	var matches []CategorySuggestion
+	for _, leaf := range leaves {
+		confidence := s.config.AIService.calculateCategoryConfidence(text, strings.ToLower(leaf.Name))
+		if confidence <= 0.1 {
+			continue
+		}
+		matches = append(matches, CategorySuggestion{
+			ExternalCategoryID: leaf.ExternalID,
+			Path:               leaf.Path,
+			Confidence:         confidence,
+			Source:             "keyword",
+		})
+	}
+	return matches, nil
+}
+
+// suggest adds a suggestion, keeping the more confident one of a category
+func suggest(suggestions map[string]*CategorySuggestion, suggestion CategorySuggestion) {
+	if existing, ok := suggestions[suggestion.ExternalCategoryID]; ok && existing.Confidence >= suggestion.Confidence {
+		return
+	}
+	suggestions[suggestion.ExternalCategoryID] = &suggestion
+}
+
+// rankSuggestions orders suggestions by confidence, most confident first
+func rankSuggestions(suggestions map[string]*CategorySuggestion, limit int) []CategorySuggestion {
+	if limit <= 0 {
+		limit = 5
+	}
+	ranked := make([]CategorySuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		ranked = append(ranked, *suggestion)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Confidence != ranked[j].Confidence {
+			return ranked[i].Confidence > ranked[j].Confidence
+		}
+		return ranked[i].Path < ranked[j].Path
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
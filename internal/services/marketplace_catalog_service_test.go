@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// fakeCatalogMarketplace serves a clothing category tree whose t-shirt
+// leaf requires a colour and a size, and accepts every listing it is sent
+// unless err is set
+type fakeCatalogMarketplace struct {
+	marketplace.MarketplaceProvider
+	mu       sync.Mutex
+	listings []marketplace.Listing
+	err      error
+}
+
+func (f *fakeCatalogMarketplace) GetCategories(ctx context.Context) ([]marketplace.Category, error) {
+	return []marketplace.Category{
+		{ID: "1", Name: "Giyim"},
+		{ID: "2", Name: "Tişört", ParentID: "1"},
+	}, nil
+}
+
+func (f *fakeCatalogMarketplace) GetCategoryAttributes(ctx context.Context, categoryID string) ([]marketplace.CategoryAttribute, error) {
+	return []marketplace.CategoryAttribute{
+		{ID: "47", Name: "Renk", Required: true, Values: []marketplace.AttributeValue{{ID: "10", Name: "Kırmızı"}, {ID: "11", Name: "Mavi"}}},
+		{ID: "338", Name: "Beden", Required: true, Values: []marketplace.AttributeValue{{ID: "20", Name: "S"}, {ID: "21", Name: "M"}}},
+		{ID: "14", Name: "Materyal", AllowCustom: true},
+	}, nil
+}
+
+func (f *fakeCatalogMarketplace) GetBrands(ctx context.Context) ([]marketplace.Brand, error) {
+	return nil, nil
+}
+
+func (f *fakeCatalogMarketplace) SyncProducts(ctx context.Context, listings []marketplace.Listing) (*marketplace.SyncReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	report := &marketplace.SyncReport{}
+	for _, listing := range listings {
+		f.listings = append(f.listings, listing)
+		report.Results = append(report.Results, marketplace.ListingResult{SKU: listing.SKU, Status: models.SyncItemAccepted})
+	}
+	return report, nil
+}
+
+func (f *fakeCatalogMarketplace) sent() []marketplace.Listing {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]marketplace.Listing(nil), f.listings...)
+}
+
+// newTestCatalog returns a marketplace integrations service whose trendyol
+// listings are completed by a catalog service and sent as sync runs to a
+// fake marketplace with a cached category tree
+func newTestCatalog(t *testing.T) (*MarketplaceIntegrationsService, *MarketplaceCatalogService, *MarketplaceSyncService, *fakeCatalogMarketplace, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	provider := &fakeCatalogMarketplace{}
+	providers := func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+		return provider, nil
+	}
+	integrations := NewMarketplaceIntegrationsService()
+	catalog, err := NewMarketplaceCatalogService(repo, MarketplaceCatalogConfig{
+		Integrations:    integrations,
+		ProviderFactory: providers,
+		IntegrationIDs:  []string{"trendyol"},
+		Logger:          testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncRuns, err := NewMarketplaceSyncService(repo, MarketplaceSyncConfig{
+		Integrations:    integrations,
+		ProviderFactory: providers,
+		Logger:          testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := catalog.SyncCategories("trendyol"); err != nil {
+		t.Fatal(err)
+	}
+	return integrations, catalog, syncRuns, provider, repo
+}
+
+// seedCatalogProduct seeds a product of a vendor with the given attributes
+func seedCatalogProduct(t *testing.T, repo database.SimpleRepository, vendorID int64, attributes map[string]string) int64 {
+	t.Helper()
+	productID := seedProduct(t, repo, vendorID, 100, 5)
+	for name, value := range attributes {
+		mustExec(t, repo, `INSERT INTO product_attributes (product_id, name, value) VALUES (?, ?, ?)`, productID, name, value)
+	}
+	return productID
+}
+
+func productCategory(t *testing.T, repo database.SimpleRepository, productID int64) int64 {
+	t.Helper()
+	var categoryID int64
+	if err := repo.QueryRow(`SELECT category_id FROM products WHERE id = ?`, productID).Scan(&categoryID); err != nil {
+		t.Fatal(err)
+	}
+	return categoryID
+}
+
+func TestCatalogMappings(t *testing.T) {
+	_, catalog, _, _, repo := newTestCatalog(t)
+	vendorID := seedVendor(t, repo, 0)
+	productID := seedCatalogProduct(t, repo, vendorID, map[string]string{"Renk": "kırmızı"})
+	categoryID := uint(productCategory(t, repo, productID))
+
+	err := catalog.MapCategory(&models.MarketplaceCategory{MarketplaceName: "trendyol", CategoryID: categoryID, ExternalCategoryID: "1"})
+	if !errors.Is(err, ErrInvalidCatalogMapping) {
+		t.Fatalf("mapping to a parent category: got %v, want ErrInvalidCatalogMapping", err)
+	}
+	mapping := &models.MarketplaceCategory{MarketplaceName: "trendyol", CategoryID: categoryID, ExternalCategoryID: "2"}
+	if err := catalog.MapCategory(mapping); err != nil {
+		t.Fatal(err)
+	}
+	if mapping.ExternalPath != "Giyim > Tişört" {
+		t.Fatalf("mapped path = %q", mapping.ExternalPath)
+	}
+
+	var invalid *marketplace.ValidationError
+	if err := catalog.ValidateProduct("trendyol", productID); !errors.As(err, &invalid) {
+		t.Fatalf("got %v, want a validation error for the missing size", err)
+	}
+	if len(invalid.Errors) != 1 || invalid.Errors[0].Field != "attributes.Beden" {
+		t.Fatalf("validation errors = %v", invalid)
+	}
+
+	err = catalog.SaveAttributeMapping(&models.MarketplaceAttributeMapping{
+		IntegrationID: "trendyol", VendorID: vendorID, ExternalCategoryID: "2", ExternalAttributeID: "338",
+		Value: "small", ExternalValueID: "99",
+	})
+	if !errors.Is(err, ErrInvalidCatalogMapping) {
+		t.Fatalf("mapping to an unknown value: got %v, want ErrInvalidCatalogMapping", err)
+	}
+	if err := catalog.SaveAttributeMapping(&models.MarketplaceAttributeMapping{
+		IntegrationID: "trendyol", VendorID: vendorID, ExternalCategoryID: "2", ExternalAttributeID: "338", DefaultValue: "S",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	listing, err := catalog.PrepareListing("trendyol", marketplace.Listing{ProductID: int(productID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, attribute := range listing.Attributes {
+		got[attribute.ID] = attribute.ValueID
+	}
+	if listing.CategoryID != "2" || got["47"] != "10" || got["338"] != "20" || len(got) != 2 {
+		t.Fatalf("listing in category %s with attributes %v", listing.CategoryID, got)
+	}
+}
+
+func TestExportRejectsUnmappedListings(t *testing.T) {
+	integrations, catalog, syncRuns, provider, repo := newTestCatalog(t)
+	mappedVendor := seedVendor(t, repo, 0)
+	otherVendor := seedVendor(t, repo, 0)
+	mapped := seedCatalogProduct(t, repo, mappedVendor, map[string]string{"Renk": "Mavi"})
+	unmapped := seedCatalogProduct(t, repo, otherVendor, map[string]string{"Renk": "Mavi"})
+	// The platform maps the category for every vendor; only one vendor
+	// maps the size its products are sold in
+	if err := catalog.MapCategory(&models.MarketplaceCategory{
+		MarketplaceName: "trendyol", CategoryID: uint(productCategory(t, repo, mapped)), ExternalCategoryID: "2",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.SaveAttributeMapping(&models.MarketplaceAttributeMapping{
+		IntegrationID: "trendyol", VendorID: mappedVendor, ExternalCategoryID: "2", ExternalAttributeID: "338", DefaultValue: "M",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := integrations.SyncProductsRun("trendyol", []marketplace.Listing{
+		{ProductID: int(mapped), Title: "Tişört"},
+		{ProductID: int(unmapped), Title: "Tişört"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.SyncRunPartial || run.Accepted != 1 || run.Rejected != 1 {
+		t.Fatalf("run = %+v", run)
+	}
+
+	sent := provider.sent()
+	if len(sent) != 1 || sent[0].ProductID != int(mapped) || sent[0].CategoryID != "2" || len(sent[0].Attributes) != 2 {
+		t.Fatalf("sent listings = %+v", sent)
+	}
+	rejected, err := syncRuns.GetRunItems(run.ID, models.SyncItemRejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 || rejected[0].ProductID != unmapped || !strings.Contains(rejected[0].Message, "Beden") {
+		t.Fatalf("rejected items = %+v", rejected)
+	}
+}
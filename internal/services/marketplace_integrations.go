@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 	
 	"kolajAi/internal/integrations"
 	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/integrations/registry"
+	"kolajAi/internal/models"
 )
 
 // MarketplaceIntegration represents a marketplace integration
@@ -24,6 +28,18 @@ type MarketplaceIntegration struct {
 // MarketplaceIntegrationsService manages all marketplace integrations
 type MarketplaceIntegrationsService struct {
 	integrations map[string]*MarketplaceIntegration
+	// inventory pushes stock changes to the marketplaces. It is set by
+	// NewInventorySyncService.
+	inventory *InventorySyncService
+	// catalog completes listings from the category and attribute mappings.
+	// It is set by NewMarketplaceCatalogService.
+	catalog *MarketplaceCatalogService
+	// syncRuns records product syncs to the marketplaces as runs. It is set
+	// by NewMarketplaceSyncService.
+	syncRuns *MarketplaceSyncService
+	// registry creates the providers and holds their credentials when set
+	// with SetRegistry
+	registry *registry.IntegrationRegistry
 }
 
 // NewMarketplaceIntegrationsService creates a new marketplace integrations service
@@ -43,6 +59,39 @@ func NewMarketplaceIntegrationsService() *MarketplaceIntegrationsService {
 	return service
 }
 
+// SetRegistry routes the integrations the registry has providers for
+// through it: their credentials are stored in its credential manager and
+// their providers are the ones it enables
+func (s *MarketplaceIntegrationsService) SetRegistry(integrationRegistry *registry.IntegrationRegistry) {
+	s.registry = integrationRegistry
+}
+
+// registryIntegration reports whether an integration is routed through the
+// registry
+func (s *MarketplaceIntegrationsService) registryIntegration(integrationID string) bool {
+	if s.registry == nil {
+		return false
+	}
+	return s.registry.HasProvider(integrationID)
+}
+
+// configured reports whether an integration has credentials
+func (s *MarketplaceIntegrationsService) configured(integrationID string) bool {
+	if s.registryIntegration(integrationID) {
+		return s.registry.Configured(integrationID)
+	}
+	integration, err := s.GetIntegration(integrationID)
+	if err != nil {
+		return false
+	}
+	for _, value := range integration.Credentials {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // initializeTurkishMarketplaces initializes Turkish marketplace integrations
 func (s *MarketplaceIntegrationsService) initializeTurkishMarketplaces() {
 	// Only include marketplaces with real implementations
@@ -91,6 +140,12 @@ func (s *MarketplaceIntegrationsService) initializeTurkishMarketplaces() {
 			"development", 
 			[]string{"basic_sync", "inventory_management"},
 		},
+		{
+			"ciceksepeti",
+			"ÇiçekSepeti",
+			"development",
+			[]string{"basic_sync", "order_sync", "inventory_management"},
+		},
 	}
 	
 	for _, mp := range turkishMarketplaces {
@@ -549,6 +604,18 @@ func (s *MarketplaceIntegrationsService) ConfigureIntegration(id string, credent
 		return err
 	}
 	
+	// Registry integrations keep their credentials in the credential
+	// manager; enabling the provider tests the connection
+	if s.registryIntegration(id) {
+		if err := s.registry.SetCredentials(id, credentials); err != nil {
+			return err
+		}
+		if _, err := s.registry.Enable(context.Background(), id); err != nil {
+			return fmt.Errorf("connection test failed: %w", err)
+		}
+		return nil
+	}
+	
 	// Update credentials
 	for key, value := range credentials {
 		integration.Credentials[key] = value
@@ -564,6 +631,15 @@ func (s *MarketplaceIntegrationsService) ConfigureIntegration(id string, credent
 
 // testIntegrationConnection tests if the integration can connect successfully
 func (s *MarketplaceIntegrationsService) testIntegrationConnection(integration *MarketplaceIntegration) error {
+	if s.registryIntegration(integration.ID) {
+		ctx := context.Background()
+		provider, err := s.registry.Provider(ctx, integration.ID)
+		if err != nil {
+			return err
+		}
+		return provider.HealthCheck(ctx)
+	}
+	
 	// Test connection based on integration type
 	switch integration.Type {
 	case "turkish":
@@ -836,7 +912,44 @@ func (s *MarketplaceIntegrationsService) testGoogleMerchantConnection(accessToke
 }
 
 // SyncProducts syncs products with a marketplace
-func (s *MarketplaceIntegrationsService) SyncProducts(integrationID string, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) SyncProducts(integrationID string, products []marketplace.Listing) error {
+	_, err := s.SyncProductsRun(integrationID, products)
+	return err
+}
+
+// SyncProductsRun syncs products to a marketplace and returns the recorded
+// run when sync runs are enabled for it. Listings the marketplace rejects
+// are reported on the run; an error is returned when the run failed as a
+// whole.
+func (s *MarketplaceIntegrationsService) SyncProductsRun(integrationID string, products []marketplace.Listing) (*models.MarketplaceSyncRun, error) {
+	if s.syncRuns != nil && isMarketplaceProvider(integrationID) {
+		if _, err := s.GetIntegration(integrationID); err != nil {
+			return nil, err
+		}
+		run, err := s.syncRuns.Sync(integrationID, products, models.SyncTriggerManual, 0)
+		if err != nil {
+			return run, err
+		}
+		if run.Status == models.SyncRunFailed {
+			return run, fmt.Errorf("sync run %d failed: %s", run.ID, run.Error)
+		}
+		return run, nil
+	}
+	return nil, s.syncProducts(integrationID, products)
+}
+
+// isMarketplaceProvider reports whether an integration has a typed provider
+func isMarketplaceProvider(integrationID string) bool {
+	for _, id := range MarketplaceOrderIntegrations {
+		if id == integrationID {
+			return true
+		}
+	}
+	return false
+}
+
+// syncProducts syncs products without recording a run
+func (s *MarketplaceIntegrationsService) syncProducts(integrationID string, products []marketplace.Listing) error {
 	integration, err := s.GetIntegration(integrationID)
 	if err != nil {
 		return err
@@ -858,7 +971,7 @@ func (s *MarketplaceIntegrationsService) SyncProducts(integrationID string, prod
 }
 
 // syncToTurkishMarketplace syncs products to Turkish marketplaces
-func (s *MarketplaceIntegrationsService) syncToTurkishMarketplace(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToTurkishMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Validate integration credentials
 	if err := s.testIntegrationConnection(integration); err != nil {
 		return fmt.Errorf("connection test failed: %w", err)
@@ -890,7 +1003,7 @@ func (s *MarketplaceIntegrationsService) syncToTurkishMarketplace(integration *M
 }
 
 // syncToInternationalMarketplace syncs products to international marketplaces
-func (s *MarketplaceIntegrationsService) syncToInternationalMarketplace(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToInternationalMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Validate integration credentials
 	if err := s.testIntegrationConnection(integration); err != nil {
 		return fmt.Errorf("connection test failed: %w", err)
@@ -920,7 +1033,7 @@ func (s *MarketplaceIntegrationsService) syncToInternationalMarketplace(integrat
 }
 
 // syncToEcommercePlatform syncs products to e-commerce platforms
-func (s *MarketplaceIntegrationsService) syncToEcommercePlatform(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToEcommercePlatform(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Validate integration credentials
 	if err := s.testIntegrationConnection(integration); err != nil {
 		return fmt.Errorf("connection test failed: %w", err)
@@ -950,7 +1063,7 @@ func (s *MarketplaceIntegrationsService) syncToEcommercePlatform(integration *Ma
 }
 
 // syncToSocialMedia syncs products to social media platforms
-func (s *MarketplaceIntegrationsService) syncToSocialMedia(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToSocialMedia(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Validate integration credentials
 	if err := s.testIntegrationConnection(integration); err != nil {
 		return fmt.Errorf("connection test failed: %w", err)
@@ -980,23 +1093,33 @@ func (s *MarketplaceIntegrationsService) syncToSocialMedia(integration *Marketpl
 }
 
 // Product transformation methods
-func (s *MarketplaceIntegrationsService) transformProductsForTurkishMarketplace(integration *MarketplaceIntegration, products []interface{}) ([]interface{}, error) {
-	transformedProducts := make([]interface{}, 0, len(products))
+func (s *MarketplaceIntegrationsService) transformProductsForTurkishMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) ([]marketplace.Listing, error) {
+	transformedProducts := make([]marketplace.Listing, 0, len(products))
+	invalid := &marketplace.ValidationError{Provider: integration.ID}
 	
 	for _, product := range products {
 		// Transform each product according to Turkish marketplace requirements
 		transformed, err := s.transformSingleProductForTurkish(integration, product)
 		if err != nil {
+			// Listings the marketplace's schema rejects fail the whole sync,
+			// so they are fixed instead of silently left out
+			var validationErr *marketplace.ValidationError
+			if errors.As(err, &validationErr) {
+				invalid.Errors = append(invalid.Errors, validationErr.Errors...)
+			}
 			continue // Skip invalid products, log error in production
 		}
 		transformedProducts = append(transformedProducts, transformed)
 	}
 	
+	if len(invalid.Errors) > 0 {
+		return nil, invalid
+	}
 	return transformedProducts, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformProductsForInternationalMarketplace(integration *MarketplaceIntegration, products []interface{}) ([]interface{}, error) {
-	transformedProducts := make([]interface{}, 0, len(products))
+func (s *MarketplaceIntegrationsService) transformProductsForInternationalMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) ([]marketplace.Listing, error) {
+	transformedProducts := make([]marketplace.Listing, 0, len(products))
 	
 	for _, product := range products {
 		// Transform each product for international marketplace (currency, language, regulations)
@@ -1010,8 +1133,8 @@ func (s *MarketplaceIntegrationsService) transformProductsForInternationalMarket
 	return transformedProducts, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformProductsForEcommercePlatform(integration *MarketplaceIntegration, products []interface{}) ([]interface{}, error) {
-	transformedProducts := make([]interface{}, 0, len(products))
+func (s *MarketplaceIntegrationsService) transformProductsForEcommercePlatform(integration *MarketplaceIntegration, products []marketplace.Listing) ([]marketplace.Listing, error) {
+	transformedProducts := make([]marketplace.Listing, 0, len(products))
 	
 	for _, product := range products {
 		// Transform each product for e-commerce platform
@@ -1025,8 +1148,8 @@ func (s *MarketplaceIntegrationsService) transformProductsForEcommercePlatform(i
 	return transformedProducts, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformProductsForSocialMedia(integration *MarketplaceIntegration, products []interface{}) ([]interface{}, error) {
-	transformedProducts := make([]interface{}, 0, len(products))
+func (s *MarketplaceIntegrationsService) transformProductsForSocialMedia(integration *MarketplaceIntegration, products []marketplace.Listing) ([]marketplace.Listing, error) {
+	transformedProducts := make([]marketplace.Listing, 0, len(products))
 	
 	for _, product := range products {
 		// Transform each product for social media platform
@@ -1041,25 +1164,28 @@ func (s *MarketplaceIntegrationsService) transformProductsForSocialMedia(integra
 }
 
 // Single product transformation methods
-func (s *MarketplaceIntegrationsService) transformSingleProductForTurkish(integration *MarketplaceIntegration, product interface{}) (interface{}, error) {
-	// Implement product transformation logic for Turkish marketplaces
-	// This would include category mapping, price formatting, description localization, etc.
+func (s *MarketplaceIntegrationsService) transformSingleProductForTurkish(integration *MarketplaceIntegration, product marketplace.Listing) (marketplace.Listing, error) {
+	// Category and attributes come from the vendor's mappings and are
+	// checked against the marketplace category's schema
+	if s.catalog != nil {
+		return s.catalog.PrepareListing(integration.ID, product)
+	}
 	return product, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformSingleProductForInternational(integration *MarketplaceIntegration, product interface{}) (interface{}, error) {
+func (s *MarketplaceIntegrationsService) transformSingleProductForInternational(integration *MarketplaceIntegration, product marketplace.Listing) (marketplace.Listing, error) {
 	// Implement product transformation logic for international marketplaces
 	// This would include currency conversion, language translation, compliance checks, etc.
 	return product, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformSingleProductForEcommerce(integration *MarketplaceIntegration, product interface{}) (interface{}, error) {
+func (s *MarketplaceIntegrationsService) transformSingleProductForEcommerce(integration *MarketplaceIntegration, product marketplace.Listing) (marketplace.Listing, error) {
 	// Implement product transformation logic for e-commerce platforms
 	// This would include format conversion, field mapping, etc.
 	return product, nil
 }
 
-func (s *MarketplaceIntegrationsService) transformSingleProductForSocial(integration *MarketplaceIntegration, product interface{}) (interface{}, error) {
+func (s *MarketplaceIntegrationsService) transformSingleProductForSocial(integration *MarketplaceIntegration, product marketplace.Listing) (marketplace.Listing, error) {
 	// Implement product transformation logic for social media platforms
 	// This would include image optimization, catalog format, etc.
 	return product, nil
@@ -1078,29 +1204,125 @@ func (s *MarketplaceIntegrationsService) ProcessOrder(integrationID string, orde
 	return nil
 }
 
-// UpdateInventory updates inventory across integrated marketplaces
+// UpdateInventory sets the stock of a product and pushes it to every
+// connected marketplace through the inventory sync
 func (s *MarketplaceIntegrationsService) UpdateInventory(productID string, quantity int) error {
-	// Update inventory across all active integrations
-	for _, integration := range s.integrations {
-		if integration.IsActive {
-			// Send inventory update to each marketplace
-			// This would be done asynchronously in production
+	if s.inventory == nil {
+		return ErrNoInventorySync
+	}
+	id, err := strconv.ParseInt(productID, 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid product ID %q", productID)
+	}
+	return s.inventory.SetStock(id, quantity)
+}
+
+// GetMarketplaceOrders retrieves the orders a marketplace has received
+// since the given time
+func (s *MarketplaceIntegrationsService) GetMarketplaceOrders(integrationID string, since time.Time) ([]marketplace.Order, error) {
+	ctx := context.Background()
+	provider, err := s.providerFor(ctx, integrationID, registry.CapabilityOrderSync)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]marketplace.Order, 0)
+	query := marketplace.OrderQuery{Since: since}
+	for {
+		page, err := provider.GetOrders(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get orders from %s: %w", integrationID, err)
+		}
+		orders = append(orders, page.Orders...)
+		if page.NextPageToken == "" {
+			return orders, nil
 		}
+		query.PageToken = page.NextPageToken
 	}
-	return nil
 }
 
-// GetMarketplaceOrders retrieves orders from a specific marketplace
-func (s *MarketplaceIntegrationsService) GetMarketplaceOrders(integrationID string, since time.Time) ([]interface{}, error) {
-	_, err := s.GetIntegration(integrationID)
+// Provider returns the marketplace provider of an integration, initialized
+// with the integration's credentials
+func (s *MarketplaceIntegrationsService) Provider(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+	return s.providerFor(ctx, integrationID)
+}
+
+// GetIntegrationMetrics returns the provider metrics of a registry
+// integration, among them the state of its rate limiter. Integrations that
+// are not enabled yet report only that.
+func (s *MarketplaceIntegrationsService) GetIntegrationMetrics(integrationID string) (map[string]interface{}, error) {
+	if !s.registryIntegration(integrationID) {
+		return nil, fmt.Errorf("integration %s has no provider", integrationID)
+	}
+	metrics, enabled := s.registry.GetIntegrationMetrics(integrationID)
+	if !enabled {
+		metrics = map[string]interface{}{}
+	}
+	metrics["enabled"] = enabled
+	return metrics, nil
+}
+
+// providerFor returns the marketplace provider of an integration for calls
+// that need the given capabilities. Registry integrations get the provider
+// the registry enabled.
+func (s *MarketplaceIntegrationsService) providerFor(ctx context.Context, integrationID string, capabilities ...string) (marketplace.MarketplaceProvider, error) {
+	if s.registryIntegration(integrationID) {
+		provider, err := s.registry.Provider(ctx, integrationID, capabilities...)
+		if err != nil {
+			return nil, err
+		}
+		marketplaceProvider, ok := provider.(marketplace.MarketplaceProvider)
+		if !ok {
+			return nil, fmt.Errorf("integration %s is not a marketplace", integrationID)
+		}
+		return marketplaceProvider, nil
+	}
+
+	integration, err := s.GetIntegration(integrationID)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Fetch orders from marketplace API
-	// This would implement actual API calls
-	
-	return []interface{}{}, nil
+
+	provider, err := marketplace.NewProvider(integration.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := integrations.Credentials{
+		APIKey:    integration.Credentials["api_key"],
+		APISecret: integration.Credentials["api_secret"],
+	}
+	environment, _ := integration.Config["environment"].(string)
+	if environment == "" {
+		environment = "production"
+	}
+	config := map[string]interface{}{
+		"environment": environment,
+	}
+
+	switch integration.ID {
+	case "trendyol":
+		config["supplier_id"] = integration.Credentials["supplier_id"]
+	case "hepsiburada":
+		config["merchant_id"] = integration.Credentials["merchant_id"]
+	case "amazon_tr":
+		credentials = integrations.Credentials{
+			ClientID:        integration.Credentials["client_id"],
+			ClientSecret:    integration.Credentials["client_secret"],
+			RefreshToken:    integration.Credentials["refresh_token"],
+			AccessKeyID:     integration.Credentials["access_key_id"],
+			SecretAccessKey: integration.Credentials["secret_access_key"],
+			SellerID:        integration.Credentials["seller_id"],
+		}
+		config["region"] = "eu-west-1"
+		config["marketplace_id"] = "A1UNQM1SR2CHM" // Turkey marketplace
+	}
+
+	if err := provider.Initialize(ctx, credentials, config); err != nil {
+		return nil, fmt.Errorf("failed to initialize %s provider: %w", integration.Name, err)
+	}
+
+	return provider, nil
 }
 
 // CreateShipment creates a shipment with a cargo integration
@@ -1137,214 +1359,165 @@ func (s *MarketplaceIntegrationsService) GenerateInvoice(efaturaID string, invoi
 	return "INV2024001", nil
 }
 
-// Specific marketplace sync methods for Turkish marketplaces
-func (s *MarketplaceIntegrationsService) syncToTrendyol(integration *MarketplaceIntegration, products []interface{}) error {
-	// Create Trendyol provider
-	provider := marketplace.NewTrendyolProvider()
-	
-	credentials := integrations.Credentials{
-		APIKey:    integration.Credentials["api_key"],
-		APISecret: integration.Credentials["api_secret"],
+// syncReportError returns the listings a marketplace rejected as a
+// *marketplace.ValidationError, or the error that stopped the sync
+func syncReportError(provider string, report *marketplace.SyncReport, err error) error {
+	if err != nil || report == nil {
+		return err
 	}
-	
-	config := map[string]interface{}{
-		"environment": "production", // Use production for real sync
-		"supplier_id": integration.Credentials["supplier_id"],
+	invalid := &marketplace.ValidationError{Provider: provider}
+	for _, result := range report.Results {
+		if result.Status == marketplace.ListingRejected {
+			invalid.Errors = append(invalid.Errors, &marketplace.MappingError{
+				Provider: provider,
+				Entity:   marketplace.EntityListing,
+				Key:      result.SKU,
+				Message:  result.Message,
+			})
+		}
 	}
-	
-	ctx := context.Background()
-	if err := provider.Initialize(ctx, credentials, config); err != nil {
-		return fmt.Errorf("failed to initialize Trendyol provider: %w", err)
+	if len(invalid.Errors) > 0 {
+		return invalid
 	}
-	
-	// Sync products to Trendyol
-	return provider.SyncProducts(ctx, products)
+	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToHepsiburada(integration *MarketplaceIntegration, products []interface{}) error {
-	// Create Hepsiburada provider
-	provider := marketplace.NewHepsiburadaProvider()
-	
-	credentials := integrations.Credentials{
-		APIKey:    integration.Credentials["api_key"],
-		APISecret: integration.Credentials["api_secret"],
-	}
-	
-	config := map[string]interface{}{
-		"environment": "production", // Use production for real sync
-		"merchant_id": integration.Credentials["merchant_id"],
-	}
-	
-	ctx := context.Background()
-	if err := provider.Initialize(ctx, credentials, config); err != nil {
-		return fmt.Errorf("failed to initialize Hepsiburada provider: %w", err)
-	}
-	
-	// Sync products to Hepsiburada
-	return provider.SyncProducts(ctx, products)
+// Specific marketplace sync methods for Turkish marketplaces
+func (s *MarketplaceIntegrationsService) syncToTrendyol(integration *MarketplaceIntegration, products []marketplace.Listing) error {
+	return s.syncToProvider(integration, products, "Trendyol")
 }
 
-func (s *MarketplaceIntegrationsService) syncToN11(integration *MarketplaceIntegration, products []interface{}) error {
-	// Create N11 provider
-	provider := marketplace.NewN11Provider()
-	
-	credentials := integrations.Credentials{
-		APIKey:    integration.Credentials["api_key"],
-		APISecret: integration.Credentials["api_secret"],
-	}
-	
-	config := map[string]interface{}{
-		"environment": "production", // Use production for real sync
-	}
-	
-	ctx := context.Background()
-	if err := provider.Initialize(ctx, credentials, config); err != nil {
-		return fmt.Errorf("failed to initialize N11 provider: %v", err)
-	}
-	
-	// Sync products
-	if err := provider.SyncProducts(ctx, products); err != nil {
-		return fmt.Errorf("failed to sync products to N11: %v", err)
-	}
-	
-	return nil
+func (s *MarketplaceIntegrationsService) syncToHepsiburada(integration *MarketplaceIntegration, products []marketplace.Listing) error {
+	return s.syncToProvider(integration, products, "Hepsiburada")
 }
 
-func (s *MarketplaceIntegrationsService) syncToAmazonTR(integration *MarketplaceIntegration, products []interface{}) error {
-	// Create Amazon provider
-	provider := marketplace.NewAmazonProvider()
-	
-	credentials := integrations.Credentials{
-		ClientID:        integration.Credentials["client_id"],
-		ClientSecret:    integration.Credentials["client_secret"],
-		RefreshToken:    integration.Credentials["refresh_token"],
-		AccessKeyID:     integration.Credentials["access_key_id"],
-		SecretAccessKey: integration.Credentials["secret_access_key"],
-		SellerID:        integration.Credentials["seller_id"],
-	}
-	
-	config := map[string]interface{}{
-		"region":         "eu-west-1",
-		"marketplace_id": "A1UNQM1SR2CHM", // Turkey marketplace
-	}
-	
+func (s *MarketplaceIntegrationsService) syncToN11(integration *MarketplaceIntegration, products []marketplace.Listing) error {
+	return s.syncToProvider(integration, products, "N11")
+}
+
+func (s *MarketplaceIntegrationsService) syncToAmazonTR(integration *MarketplaceIntegration, products []marketplace.Listing) error {
+	return s.syncToProvider(integration, products, "Amazon Turkey")
+}
+
+func (s *MarketplaceIntegrationsService) syncToCicekSepeti(integration *MarketplaceIntegration, products []marketplace.Listing) error {
+	return s.syncToProvider(integration, products, "ÇiçekSepeti")
+}
+
+// syncToProvider syncs products with the integration's marketplace provider
+func (s *MarketplaceIntegrationsService) syncToProvider(integration *MarketplaceIntegration, products []marketplace.Listing, name string) error {
 	ctx := context.Background()
-	if err := provider.Initialize(ctx, credentials, config); err != nil {
-		return fmt.Errorf("failed to initialize Amazon Turkey provider: %v", err)
+	provider, err := s.providerFor(ctx, integration.ID, registry.CapabilityProductSync)
+	if err != nil {
+		return err
 	}
-	
-	// Sync products
-	if err := provider.SyncProducts(ctx, products); err != nil {
-		return fmt.Errorf("failed to sync products to Amazon Turkey: %v", err)
+
+	report, err := provider.SyncProducts(ctx, products)
+	if err := syncReportError(integration.ID, report, err); err != nil {
+		return fmt.Errorf("failed to sync products to %s: %w", name, err)
 	}
-	
-	return nil
-}
 
-func (s *MarketplaceIntegrationsService) syncToCicekSepeti(integration *MarketplaceIntegration, products []interface{}) error {
-	// Implement ÇiçekSepeti API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToPttAvm(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToPttAvm(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement PttAvm API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToGenericTurkishMarketplace(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToGenericTurkishMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Generic sync logic for other Turkish marketplaces
 	return nil
 }
 
 // Specific marketplace sync methods for international marketplaces
-func (s *MarketplaceIntegrationsService) syncToAmazonInternational(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToAmazonInternational(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Amazon MWS/SP-API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToEbay(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToEbay(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement eBay API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToEtsy(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToEtsy(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Etsy API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToAliExpress(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToAliExpress(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement AliExpress API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToWalmart(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToWalmart(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Walmart API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToGenericInternationalMarketplace(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToGenericInternationalMarketplace(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Generic sync logic for other international marketplaces
 	return nil
 }
 
 // Specific e-commerce platform sync methods
-func (s *MarketplaceIntegrationsService) syncToWooCommerce(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToWooCommerce(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement WooCommerce REST API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToMagento(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToMagento(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Magento REST API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToShopify(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToShopify(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Shopify Admin API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToOpenCart(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToOpenCart(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement OpenCart API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToPrestaShop(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToPrestaShop(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement PrestaShop API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToGenericEcommercePlatform(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToGenericEcommercePlatform(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Generic sync logic for other e-commerce platforms
 	return nil
 }
 
 // Specific social media platform sync methods
-func (s *MarketplaceIntegrationsService) syncToFacebookShop(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToFacebookShop(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Facebook Catalog API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToInstagramShop(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToInstagramShop(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Instagram Shopping API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToGoogleMerchant(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToGoogleMerchant(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Google Merchant Center API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToPinterestBusiness(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToPinterestBusiness(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement Pinterest Business API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToTikTokShop(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToTikTokShop(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Implement TikTok Shop API integration
 	return nil
 }
 
-func (s *MarketplaceIntegrationsService) syncToGenericSocialMedia(integration *MarketplaceIntegration, products []interface{}) error {
+func (s *MarketplaceIntegrationsService) syncToGenericSocialMedia(integration *MarketplaceIntegration, products []marketplace.Listing) error {
 	// Generic sync logic for other social media platforms
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,1006 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+)
+
+// Marketplace order import errors
+var (
+	ErrMarketplaceOrderNotFound = errors.New("marketplace order not found")
+	ErrNoMarketplaceProvider    = errors.New("no marketplace provider configured")
+	ErrUnmatchedMarketplaceSKU  = errors.New("marketplace order has items not in the catalog")
+)
+
+// marketplaceUserDomain is the email domain of the users marketplace orders
+// are placed under. It is reserved, so nothing is ever mailed there.
+const marketplaceUserDomain = "marketplace.invalid"
+
+// MarketplaceOrderIntegrations are the marketplaces orders are imported
+// from by default
+var MarketplaceOrderIntegrations = []string{"trendyol", "hepsiburada", "n11", "amazon_tr", "ciceksepeti"}
+
+// MarketplaceProviderFactory returns the initialized provider of a
+// marketplace integration
+type MarketplaceProviderFactory func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error)
+
+// MarketplaceOrderImportConfig holds import settings and collaborators
+type MarketplaceOrderImportConfig struct {
+	// Integrations supplies the credentials of the marketplaces. Its
+	// Provider method is the default ProviderFactory.
+	Integrations *MarketplaceIntegrationsService
+	// StateMachine moves imported orders to the marketplace's status and
+	// reports local status changes back to the marketplace
+	StateMachine *OrderStateMachine
+	// ProviderFactory overrides how providers are created
+	ProviderFactory MarketplaceProviderFactory
+	// Inventory, when set, pushes the stock taken by imported orders to
+	// the other channels
+	Inventory *InventorySyncService
+	// Cache, when set, drops the cached reads of imported orders and of
+	// the products they take stock from
+	Cache *cache.EntityCache
+	// IntegrationIDs lists the marketplaces to import from. It defaults to
+	// MarketplaceOrderIntegrations; integrations without credentials are
+	// skipped.
+	IntegrationIDs []string
+	// PageSize is the number of orders requested per page. It defaults to
+	// 100.
+	PageSize int
+	// InitialLookback is how far back the first import of an integration
+	// goes. It defaults to seven days.
+	InitialLookback time.Duration
+	// Overlap is subtracted from the cursor so orders that reach the
+	// marketplace API late are still picked up. It defaults to 15 minutes.
+	Overlap time.Duration
+	// Timeout bounds the marketplace calls of one integration's import. It
+	// defaults to five minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// MarketplaceOrderImportService imports marketplace orders into the native
+// order system. Each integration is polled from its own cursor, orders are
+// deduplicated by their marketplace order ID, and the local orders take
+// stock like storefront orders do. Status changes made locally are pushed
+// back to the marketplace through the state machine's hooks.
+type MarketplaceOrderImportService struct {
+	repo      database.SimpleRepository
+	config    MarketplaceOrderImportConfig
+	providers MarketplaceProviderFactory
+	logger    *log.Logger
+	// following holds the orders being walked to their marketplace's
+	// status, whose transitions are not pushed back
+	following sync.Map
+}
+
+// OrderImportResult summarizes the import of one integration
+type OrderImportResult struct {
+	IntegrationID string    `json:"integration_id"`
+	Since         time.Time `json:"since"`
+	Fetched       int       `json:"fetched"`
+	Imported      int       `json:"imported"`
+	Updated       int       `json:"updated"`
+	Failed        int       `json:"failed"`
+	Errors        []string  `json:"errors,omitempty"`
+}
+
+// importedLine is an order item matched to a catalog product
+type importedLine struct {
+	productID int64
+	vendorID  int64
+	name      string
+}
+
+// NewMarketplaceOrderImportService creates a new marketplace order import
+// service and registers its hooks with the state machine
+func NewMarketplaceOrderImportService(repo database.SimpleRepository, config MarketplaceOrderImportConfig) (*MarketplaceOrderImportService, error) {
+	if config.StateMachine == nil {
+		return nil, fmt.Errorf("marketplace order import requires an order state machine")
+	}
+	providers := config.ProviderFactory
+	if providers == nil {
+		if config.Integrations == nil {
+			return nil, ErrNoMarketplaceProvider
+		}
+		providers = config.Integrations.Provider
+	}
+	if len(config.IntegrationIDs) == 0 {
+		config.IntegrationIDs = MarketplaceOrderIntegrations
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = 100
+	}
+	if config.InitialLookback <= 0 {
+		config.InitialLookback = 7 * 24 * time.Hour
+	}
+	if config.Overlap <= 0 {
+		config.Overlap = 15 * time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	s := &MarketplaceOrderImportService{repo: repo, config: config, providers: providers, logger: logger}
+	s.register(config.StateMachine)
+	return s, nil
+}
+
+// register pushes local status changes of imported orders back to their
+// marketplace
+func (s *MarketplaceOrderImportService) register(sm *OrderStateMachine) {
+	for _, status := range []string{
+		models.OrderStatusConfirmed,
+		models.OrderStatusProcessing,
+		models.OrderStatusShipped,
+		models.OrderStatusDelivered,
+		models.OrderStatusCancelled,
+		models.OrderStatusRefunded,
+	} {
+		sm.AddHook(status, s.pushStatusChange)
+	}
+}
+
+// ImportAll retries the parked orders, imports the new orders of every
+// configured integration and retries the status updates and pushes that
+// failed. An integration that fails does not stop the others; their
+// errors are joined.
+func (s *MarketplaceOrderImportService) ImportAll() ([]*OrderImportResult, error) {
+	var results []*OrderImportResult
+	var errs []error
+	// Parked orders are retried first, so an order parked by this run is
+	// not retried straight away
+	if _, err := s.RetryParkedOrders(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, integrationID := range s.config.IntegrationIDs {
+		if !s.configured(integrationID) {
+			continue
+		}
+		result, err := s.ImportIntegration(integrationID)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integrationID, err))
+		}
+	}
+
+	if _, err := s.RetryStatusUpdates(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := s.PushPendingUpdates(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// configured reports whether an integration has credentials to import with
+func (s *MarketplaceOrderImportService) configured(integrationID string) bool {
+	return marketplaceConfigured(s.config.Integrations, s.config.ProviderFactory, integrationID)
+}
+
+// marketplaceConfigured reports whether a marketplace integration has
+// credentials. Integrations are always used when a custom provider factory
+// is set.
+func marketplaceConfigured(integrations *MarketplaceIntegrationsService, factory MarketplaceProviderFactory, integrationID string) bool {
+	if factory != nil || integrations == nil {
+		return true
+	}
+	return integrations.configured(integrationID)
+}
+
+// ImportIntegration imports the orders an integration has received since
+// its cursor. Orders seen before only have their status brought up to
+// date. Orders that fail are parked in marketplace_order_failures and the
+// cursor moves past them, so one bad order does not make every run fetch
+// the same window again.
+func (s *MarketplaceOrderImportService) ImportIntegration(integrationID string) (*OrderImportResult, error) {
+	cursor, err := s.GetCursor(integrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-s.config.InitialLookback)
+	if cursor.LastOrderAt != nil {
+		since = cursor.LastOrderAt.Add(-s.config.Overlap)
+	}
+	result := &OrderImportResult{IntegrationID: integrationID, Since: since}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	lastOrderAt := cursor.LastOrderAt
+	var firstFailure *time.Time
+	runErr := func() error {
+		provider, err := s.providers(ctx, integrationID)
+		if err != nil {
+			return err
+		}
+
+		query := marketplace.OrderQuery{Since: since, PageSize: s.config.PageSize}
+		for {
+			page, err := provider.GetOrders(ctx, query)
+			if err != nil {
+				return fmt.Errorf("failed to get orders: %w", err)
+			}
+			for i := range page.Orders {
+				order := &page.Orders[i]
+				result.Fetched++
+				created, err := s.ImportOrder(integrationID, order)
+				switch {
+				case err != nil:
+					result.Failed++
+					result.Errors = append(result.Errors, fmt.Sprintf("order %s: %v", order.ID, err))
+					if parkErr := s.parkOrder(integrationID, order, err); parkErr != nil {
+						s.logger.Printf("Failed to park %s order %s: %v", integrationID, order.ID, parkErr)
+						if firstFailure == nil || order.OrderDate.Before(*firstFailure) {
+							orderDate := order.OrderDate.UTC()
+							firstFailure = &orderDate
+						}
+					}
+				case created:
+					result.Imported++
+				default:
+					result.Updated++
+				}
+				if err == nil {
+					s.unparkOrder(integrationID, order.ID)
+				}
+				if lastOrderAt == nil || order.OrderDate.After(*lastOrderAt) {
+					orderDate := order.OrderDate.UTC()
+					lastOrderAt = &orderDate
+				}
+			}
+			if page.NextPageToken == "" {
+				return nil
+			}
+			query.PageToken = page.NextPageToken
+		}
+	}()
+
+	// Failed orders are parked and retried by RetryParkedOrders, so they do
+	// not hold the cursor back; one that could not be parked does, so the
+	// next run fetches it again. When the run itself failed, pages may have
+	// been missed and the cursor stays where it was.
+	if firstFailure != nil && (lastOrderAt == nil || firstFailure.Before(*lastOrderAt)) {
+		lastOrderAt = firstFailure
+	}
+	if runErr != nil {
+		lastOrderAt = cursor.LastOrderAt
+	}
+
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	} else if len(result.Errors) > 0 {
+		lastError = strings.Join(result.Errors, "; ")
+	}
+	if err := s.saveCursor(integrationID, lastOrderAt, now, lastError, result.Imported); err != nil {
+		return result, err
+	}
+
+	if runErr != nil {
+		return result, runErr
+	}
+	if result.Failed > 0 {
+		s.logger.Printf("Marketplace order import from %s: %d of %d orders failed", integrationID, result.Failed, result.Fetched)
+	}
+	return result, nil
+}
+
+// ImportOrder imports a single marketplace order, for example one delivered
+// by a webhook. It reports whether a local order was created; orders that
+// were imported before are moved to the marketplace's status instead.
+// Orders are placed under their channel's user. An order with items whose
+// SKU is not in the catalog is rejected with ErrUnmatchedMarketplaceSKU and
+// retried once the catalog has the products.
+func (s *MarketplaceOrderImportService) ImportOrder(integrationID string, order *marketplace.Order) (bool, error) {
+	if order.ID == "" {
+		return false, fmt.Errorf("marketplace order has no ID")
+	}
+
+	existing, err := s.getLink(`integration_id = ? AND marketplace_order_id = ?`, integrationID, order.ID)
+	if err == nil {
+		return false, s.applyMarketplaceStatus(existing, marketplace.ModelOrderStatus(order.Status))
+	}
+	if !errors.Is(err, ErrMarketplaceOrderNotFound) {
+		return false, err
+	}
+
+	local := order.ToModel(integrationID)
+	if len(local.Items) == 0 {
+		return false, fmt.Errorf("marketplace order %s has no items", order.ID)
+	}
+	if local.CreatedAt.IsZero() {
+		local.CreatedAt = time.Now().UTC()
+	}
+	local.UpdatedAt = time.Now().UTC()
+
+	// Products are matched by SKU before the transaction, which can only
+	// execute statements
+	lines := make([]*importedLine, len(local.Items))
+	vendors := make(map[int64]bool)
+	var unmatched []string
+	for i, item := range local.Items {
+		line, err := s.matchProduct(item.ProductSKU)
+		if err != nil {
+			return false, err
+		}
+		if line == nil {
+			unmatched = append(unmatched, fmt.Sprintf("%q", item.ProductSKU))
+			continue
+		}
+		lines[i] = line
+		vendors[line.vendorID] = true
+	}
+	if len(unmatched) > 0 {
+		return false, fmt.Errorf("%w: %s", ErrUnmatchedMarketplaceSKU, strings.Join(unmatched, ", "))
+	}
+	if local.UserID, err = s.channelUser(integrationID); err != nil {
+		return false, err
+	}
+	if len(vendors) == 1 {
+		for vendorID := range vendors {
+			local.VendorID = vendorID
+		}
+	}
+	commissionRates := make(map[int64]float64)
+	for vendorID := range vendors {
+		if commissionRates[vendorID], err = vendorCommissionRate(s.repo, vendorID); err != nil {
+			return false, err
+		}
+	}
+
+	// The order is created as pending and then walked to the marketplace's
+	// status, so the transitions' effects run as they would for a
+	// storefront order
+	target := local.Status
+	local.Status = models.OrderStatusPending
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin marketplace order import: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if err := insertOrder(tx, local); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE orders SET source_channel = ?, tracking_number = ?, carrier_name = ? WHERE id = ?`,
+		local.SourceChannel, local.TrackingNumber, local.CarrierName, local.ID); err != nil {
+		return false, fmt.Errorf("failed to set order channel: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range local.Items {
+		item := &local.Items[i]
+		line := lines[i]
+		item.OrderID = local.ID
+		item.ProductID = line.productID
+		item.VendorID = line.vendorID
+		if item.ProductName == "" {
+			item.ProductName = line.name
+		}
+		item.Commission = roundMoney(item.TotalPrice * commissionRates[line.vendorID] / 100)
+
+		result, err := tx.Exec(`
+			INSERT INTO order_items (order_id, product_id, vendor_id, product_name, product_sku,
+				quantity, unit_price, total_price, commission, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.OrderID, item.ProductID, item.VendorID, item.ProductName, item.ProductSKU,
+			item.Quantity, item.UnitPrice, item.TotalPrice, item.Commission, item.Status)
+		if err != nil {
+			return false, fmt.Errorf("failed to add order item: %w", err)
+		}
+		item.ID, _ = result.LastInsertId()
+
+		// The marketplace has sold the units already, so stock is taken even
+		// if it goes short; it never drops below zero. The item records the
+		// units actually taken, which a cancellation gives back.
+		if _, err := tx.Exec(`
+			UPDATE order_items SET stock_taken = (
+				SELECT CASE WHEN stock >= ? THEN ? WHEN stock > 0 THEN stock ELSE 0 END FROM products WHERE id = ?)
+			WHERE id = ?`,
+			item.Quantity, item.Quantity, line.productID, item.ID); err != nil {
+			return false, fmt.Errorf("failed to record stock of order item %d: %w", item.ID, err)
+		}
+		if _, err := tx.Exec(`
+			UPDATE products SET
+				status = CASE WHEN stock <= ? THEN ? ELSE status END,
+				stock = CASE WHEN stock >= ? THEN stock - ? ELSE 0 END,
+				updated_at = ?
+			WHERE id = ?`,
+			item.Quantity, ProductStatusOutOfStock, item.Quantity, item.Quantity, now, line.productID); err != nil {
+			return false, fmt.Errorf("failed to take stock for product %d: %w", line.productID, err)
+		}
+	}
+
+	// The order is synced once it has been walked to the marketplace's
+	// status below
+	result, err := tx.Exec(`
+		INSERT INTO marketplace_orders (integration_id, marketplace_order_id, order_number, order_id,
+			marketplace_status, synced_status, last_error, imported_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		integrationID, order.ID, order.OrderNumber, local.ID, target, models.OrderStatusPending, "", now, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			// Imported concurrently, e.g. by a webhook during a poll
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to link marketplace order: %w", err)
+	}
+	linkID, _ := result.LastInsertId()
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit marketplace order import: %w", err)
+	}
+	committed = true
+
+	ordersChanged(s.config.Cache)
+	productsChanged(s.config.Cache, orderProductIDs(local.Items)...)
+	if s.config.Inventory != nil {
+		s.config.Inventory.StockChanged(StockChangeMarketplaceOrder, orderProductIDs(local.Items)...)
+	}
+
+	link := &models.MarketplaceOrder{
+		ID:                 linkID,
+		IntegrationID:      integrationID,
+		MarketplaceOrderID: order.ID,
+		OrderNumber:        order.OrderNumber,
+		OrderID:            local.ID,
+		MarketplaceStatus:  target,
+		SyncedStatus:       models.OrderStatusPending,
+	}
+	if err := s.follow(link, models.OrderStatusPending, order.TrackingNumber, order.ShippingMethod); err != nil {
+		// The order is imported; RetryStatusUpdates moves it on
+		s.logger.Printf("Imported %s order %s could not be moved to %s: %v", integrationID, order.ID, target, err)
+	}
+
+	return true, nil
+}
+
+// ApplyOrderStatus moves an imported order to the status the marketplace
+// reports for it, for notifications that carry only the status. Orders
+// that were not imported yet return ErrMarketplaceOrderNotFound.
+func (s *MarketplaceOrderImportService) ApplyOrderStatus(integrationID, marketplaceOrderID, status string) error {
+	link, err := s.getLink(`integration_id = ? AND marketplace_order_id = ?`, integrationID, marketplaceOrderID)
+	if err != nil {
+		return err
+	}
+	return s.applyMarketplaceStatus(link, marketplace.ModelOrderStatus(status))
+}
+
+// matchProduct finds the catalog product sold under a SKU. It returns nil
+// when the SKU is not in the catalog.
+func (s *MarketplaceOrderImportService) matchProduct(sku string) (*importedLine, error) {
+	if sku == "" {
+		return nil, nil
+	}
+	var line importedLine
+	err := s.repo.QueryRow(`SELECT id, COALESCE(vendor_id, 0), name FROM products WHERE sku = ?`, sku).
+		Scan(&line.productID, &line.vendorID, &line.name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to match product %s: %w", sku, err)
+	}
+	return &line, nil
+}
+
+// channelUser returns the user the orders of an integration are placed
+// under, creating it on first use. Marketplace customers have no local
+// account, and the channel's user cannot sign in.
+func (s *MarketplaceOrderImportService) channelUser(integrationID string) (int64, error) {
+	email := integrationID + "@" + marketplaceUserDomain
+	var userID int64
+	err := s.repo.QueryRow(`SELECT id FROM users WHERE email = ?`, email).Scan(&userID)
+	if err == nil {
+		return userID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get user of %s: %w", integrationID, err)
+	}
+
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		INSERT INTO users (name, email, password, is_active, is_admin, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		integrationID+" siparişleri", email, "!", false, false, now, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			// Created concurrently by another import
+			return s.channelUser(integrationID)
+		}
+		return 0, fmt.Errorf("failed to create user of %s: %w", integrationID, err)
+	}
+	return result.LastInsertId()
+}
+
+// applyMarketplaceStatus moves the local order of an imported order forward
+// to the status the marketplace reports. Statuses the local order is
+// already past are ignored. A status that was applied before is applied
+// again when its last attempt failed.
+func (s *MarketplaceOrderImportService) applyMarketplaceStatus(link *models.MarketplaceOrder, status string) error {
+	if status == link.MarketplaceStatus && link.LastError == "" {
+		return nil
+	}
+
+	var current, trackingNumber, carrierName string
+	err := s.repo.QueryRow(`SELECT status, COALESCE(tracking_number, ''), COALESCE(carrier_name, '') FROM orders WHERE id = ?`, link.OrderID).
+		Scan(&current, &trackingNumber, &carrierName)
+	if err != nil {
+		return fmt.Errorf("failed to get order %d: %w", link.OrderID, err)
+	}
+
+	if status != link.MarketplaceStatus {
+		_, err = s.repo.Exec(`UPDATE marketplace_orders SET marketplace_status = ?, updated_at = ? WHERE id = ?`,
+			status, time.Now().UTC(), link.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update marketplace order: %w", err)
+		}
+		link.MarketplaceStatus = status
+	}
+	return s.follow(link, current, trackingNumber, carrierName)
+}
+
+// follow walks the local order of an imported order from its current status
+// to the marketplace's, then records the marketplace's status as synced. A
+// failed walk is kept on the link and retried by RetryStatusUpdates; the
+// synced status stays where it was.
+func (s *MarketplaceOrderImportService) follow(link *models.MarketplaceOrder, current, trackingNumber, carrierName string) error {
+	status := link.MarketplaceStatus
+	if err := s.advance(link, current, status, trackingNumber, carrierName); err != nil {
+		s.recordError(link.ID, err)
+		return err
+	}
+
+	// A local order already past the marketplace's status keeps what was
+	// synced before
+	synced := link.SyncedStatus
+	if current == status || transitionPath(current, status) != nil {
+		synced = status
+	}
+	_, err := s.repo.Exec(`UPDATE marketplace_orders SET synced_status = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		synced, "", time.Now().UTC(), link.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update marketplace order: %w", err)
+	}
+	link.SyncedStatus = synced
+	link.LastError = ""
+	return nil
+}
+
+// advance walks a local order through the transition table to a status.
+// The transitions follow the marketplace, so they are not pushed back to it.
+func (s *MarketplaceOrderImportService) advance(link *models.MarketplaceOrder, from, to, trackingNumber, carrierName string) error {
+	s.following.Store(link.OrderID, true)
+	defer s.following.Delete(link.OrderID)
+
+	for _, status := range transitionPath(from, to) {
+		_, err := s.config.StateMachine.Transition(&TransitionRequest{
+			OrderID:        link.OrderID,
+			To:             status,
+			From:           from,
+			TrackingNumber: trackingNumber,
+			CarrierName:    carrierName,
+			Comment:        fmt.Sprintf("%s order %s is %s", link.IntegrationID, link.MarketplaceOrderID, to),
+		})
+		if err != nil {
+			return err
+		}
+		from = status
+	}
+	return nil
+}
+
+// pushStatusChange reports a local status change of an imported order to
+// its marketplace. Changes that came from the marketplace, and statuses a
+// marketplace cannot be moved to, are not pushed. A failed push is kept on
+// the link and retried by PushPendingUpdates.
+func (s *MarketplaceOrderImportService) pushStatusChange(tc *TransitionContext) error {
+	if _, ok := s.following.Load(tc.Order.ID); ok {
+		return nil
+	}
+	link, err := s.getLink(`order_id = ?`, tc.Order.ID)
+	if errors.Is(err, ErrMarketplaceOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	trackingNumber := tc.Request.TrackingNumber
+	if trackingNumber == "" {
+		trackingNumber = tc.Order.TrackingNumber
+	}
+	return s.push(link, tc.To, trackingNumber, tc.Request.CarrierName)
+}
+
+// push sends a local status to the marketplace of an imported order
+func (s *MarketplaceOrderImportService) push(link *models.MarketplaceOrder, status, trackingNumber, carrierName string) error {
+	// The marketplace is already at or past this status
+	for _, reached := range []string{link.SyncedStatus, link.MarketplaceStatus} {
+		if reached == status || transitionPath(status, reached) != nil {
+			return nil
+		}
+	}
+
+	var shipment *marketplace.Shipment
+	if status == models.OrderStatusShipped {
+		shipment = &marketplace.Shipment{
+			Carrier:        carrierName,
+			TrackingNumber: trackingNumber,
+			ShippedAt:      time.Now().UTC(),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	err := func() error {
+		provider, err := s.providers(ctx, link.IntegrationID)
+		if err != nil {
+			return err
+		}
+		return provider.UpdateOrderStatus(ctx, link.MarketplaceOrderID, marketplace.OrderStatusFromModel(status), shipment)
+	}()
+
+	var validationErr *marketplace.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors[0].Entity == marketplace.EntityOrderStatus {
+		// The marketplace moves the order to this status itself
+		err = nil
+	}
+	if err != nil {
+		s.recordError(link.ID, err)
+		return fmt.Errorf("failed to push status %s to %s order %s: %w", status, link.IntegrationID, link.MarketplaceOrderID, err)
+	}
+
+	_, err = s.repo.Exec(`UPDATE marketplace_orders SET synced_status = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		status, "", time.Now().UTC(), link.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update marketplace order: %w", err)
+	}
+	link.SyncedStatus = status
+	return nil
+}
+
+// PushPendingUpdates pushes the status of imported orders whose last push
+// failed. It returns the number of orders brought up to date.
+func (s *MarketplaceOrderImportService) PushPendingUpdates() (int, error) {
+	updates, err := s.failedLinks(`mo.synced_status <> o.status`)
+	if err != nil {
+		return 0, err
+	}
+
+	pushed := 0
+	var errs []error
+	for i := range updates {
+		p := &updates[i]
+		if transitionPath(p.status, p.link.MarketplaceStatus) != nil {
+			// Still following the marketplace; RetryStatusUpdates moves it
+			continue
+		}
+		if err := s.push(&p.link, p.status, p.trackingNumber, p.carrierName); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pushed++
+	}
+	return pushed, errors.Join(errs...)
+}
+
+// RetryStatusUpdates walks imported orders whose move to their
+// marketplace's status failed to that status again. It returns the number
+// of orders brought up to date.
+func (s *MarketplaceOrderImportService) RetryStatusUpdates() (int, error) {
+	updates, err := s.failedLinks(`mo.synced_status <> mo.marketplace_status`)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	var errs []error
+	for i := range updates {
+		p := &updates[i]
+		if p.status != p.link.MarketplaceStatus && transitionPath(p.status, p.link.MarketplaceStatus) == nil {
+			// The local order has moved past the marketplace
+			continue
+		}
+		if err := s.follow(&p.link, p.status, p.trackingNumber, p.carrierName); err != nil {
+			errs = append(errs, fmt.Errorf("failed to move %s order %s to %s: %w",
+				p.link.IntegrationID, p.link.MarketplaceOrderID, p.link.MarketplaceStatus, err))
+			continue
+		}
+		updated++
+	}
+	return updated, errors.Join(errs...)
+}
+
+// failedLink is an imported order whose last status update or push failed,
+// with the status and shipment of its local order
+type failedLink struct {
+	link           models.MarketplaceOrder
+	status         string
+	trackingNumber string
+	carrierName    string
+}
+
+// failedLinks returns the imported orders with an error that match a
+// condition on the link (mo) and its local order (o)
+func (s *MarketplaceOrderImportService) failedLinks(where string) ([]failedLink, error) {
+	rows, err := s.repo.Query(`
+		SELECT mo.id, mo.integration_id, mo.marketplace_order_id, COALESCE(mo.order_number, ''), mo.order_id,
+			mo.marketplace_status, mo.synced_status, mo.last_error, o.status, COALESCE(o.tracking_number, ''), COALESCE(o.carrier_name, '')
+		FROM marketplace_orders mo
+		JOIN orders o ON o.id = mo.order_id
+		WHERE COALESCE(mo.last_error, '') <> '' AND ` + where + `
+		ORDER BY mo.id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending marketplace status updates: %w", err)
+	}
+	defer rows.Close()
+
+	var links []failedLink
+	for rows.Next() {
+		var p failedLink
+		if err := rows.Scan(&p.link.ID, &p.link.IntegrationID, &p.link.MarketplaceOrderID, &p.link.OrderNumber, &p.link.OrderID,
+			&p.link.MarketplaceStatus, &p.link.SyncedStatus, &p.link.LastError, &p.status, &p.trackingNumber, &p.carrierName); err != nil {
+			return nil, fmt.Errorf("failed to scan pending marketplace status update: %w", err)
+		}
+		links = append(links, p)
+	}
+	return links, nil
+}
+
+// parkOrder stores a marketplace order that failed to import so it can be
+// retried without holding the cursor back. An order parked before has its
+// payload, error and attempts brought up to date.
+func (s *MarketplaceOrderImportService) parkOrder(integrationID string, order *marketplace.Order, cause error) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to encode marketplace order: %w", err)
+	}
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_order_failures
+		SET order_date = ?, payload = ?, attempts = attempts + 1, last_error = ?, updated_at = ?
+		WHERE integration_id = ? AND marketplace_order_id = ?`,
+		order.OrderDate.UTC(), string(payload), cause.Error(), now, integrationID, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to park marketplace order: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	_, err = s.repo.Exec(`
+		INSERT INTO marketplace_order_failures (integration_id, marketplace_order_id, order_date, payload, attempts, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?)`,
+		integrationID, order.ID, order.OrderDate.UTC(), string(payload), cause.Error(), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to park marketplace order: %w", err)
+	}
+	return nil
+}
+
+// unparkOrder removes a marketplace order that has been imported from the
+// parked orders
+func (s *MarketplaceOrderImportService) unparkOrder(integrationID, marketplaceOrderID string) {
+	_, err := s.repo.Exec(`DELETE FROM marketplace_order_failures WHERE integration_id = ? AND marketplace_order_id = ?`,
+		integrationID, marketplaceOrderID)
+	if err != nil {
+		s.logger.Printf("Failed to unpark %s order %s: %v", integrationID, marketplaceOrderID, err)
+	}
+}
+
+// RetryParkedOrders imports the parked marketplace orders again, oldest
+// first. Orders that are imported leave the parked orders; the others keep
+// their latest error. It returns the number of orders imported.
+func (s *MarketplaceOrderImportService) RetryParkedOrders() (int, error) {
+	parked, err := s.GetParkedOrders("", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	var errs []error
+	for i := range parked {
+		p := &parked[i]
+		var order marketplace.Order
+		if err := json.Unmarshal([]byte(p.Payload), &order); err != nil {
+			errs = append(errs, fmt.Errorf("failed to decode parked %s order %s: %w", p.IntegrationID, p.MarketplaceOrderID, err))
+			continue
+		}
+		if _, err := s.ImportOrder(p.IntegrationID, &order); err != nil {
+			if parkErr := s.parkOrder(p.IntegrationID, &order, err); parkErr != nil {
+				errs = append(errs, parkErr)
+			}
+			continue
+		}
+		s.unparkOrder(p.IntegrationID, p.MarketplaceOrderID)
+		imported++
+	}
+	return imported, errors.Join(errs...)
+}
+
+// GetParkedOrders returns the marketplace orders of an integration that
+// failed to import, oldest first. An empty integration ID returns all; a
+// limit of zero or less returns every parked order.
+func (s *MarketplaceOrderImportService) GetParkedOrders(integrationID string, limit int) ([]models.MarketplaceOrderFailure, error) {
+	query := `
+		SELECT id, integration_id, marketplace_order_id, order_date, payload, attempts, COALESCE(last_error, ''), created_at, updated_at
+		FROM marketplace_order_failures`
+	args := []interface{}{}
+	if integrationID != "" {
+		query += ` WHERE integration_id = ?`
+		args = append(args, integrationID)
+	}
+	query += ` ORDER BY order_date ASC, id ASC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parked marketplace orders: %w", err)
+	}
+	defer rows.Close()
+
+	var parked []models.MarketplaceOrderFailure
+	for rows.Next() {
+		var p models.MarketplaceOrderFailure
+		if err := rows.Scan(&p.ID, &p.IntegrationID, &p.MarketplaceOrderID, &p.OrderDate, &p.Payload,
+			&p.Attempts, &p.LastError, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan parked marketplace order: %w", err)
+		}
+		parked = append(parked, p)
+	}
+	return parked, nil
+}
+
+// recordError keeps the last import or push error of an imported order
+func (s *MarketplaceOrderImportService) recordError(linkID int64, cause error) {
+	_, err := s.repo.Exec(`UPDATE marketplace_orders SET last_error = ?, updated_at = ? WHERE id = ?`,
+		cause.Error(), time.Now().UTC(), linkID)
+	if err != nil {
+		s.logger.Printf("Failed to record error of marketplace order %d: %v", linkID, err)
+	}
+}
+
+// GetMarketplaceOrder returns the marketplace link of a local order
+func (s *MarketplaceOrderImportService) GetMarketplaceOrder(orderID int64) (*models.MarketplaceOrder, error) {
+	return s.getLink(`order_id = ?`, orderID)
+}
+
+// GetMarketplaceOrders returns the most recently imported orders of an
+// integration, newest first. An empty integration ID returns all.
+func (s *MarketplaceOrderImportService) GetMarketplaceOrders(integrationID string, limit int) ([]models.MarketplaceOrder, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `SELECT ` + marketplaceOrderColumns + ` FROM marketplace_orders`
+	args := []interface{}{}
+	if integrationID != "" {
+		query += ` WHERE integration_id = ?`
+		args = append(args, integrationID)
+	}
+	query += ` ORDER BY imported_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marketplace orders: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.MarketplaceOrder
+	for rows.Next() {
+		var link models.MarketplaceOrder
+		if err := scanMarketplaceOrder(rows, &link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// marketplaceOrderColumns are the columns scanMarketplaceOrder reads
+const marketplaceOrderColumns = `id, integration_id, marketplace_order_id, order_number, order_id,
+	marketplace_status, synced_status, COALESCE(last_error, ''), imported_at, updated_at`
+
+// scanMarketplaceOrder scans the marketplaceOrderColumns of a row
+func scanMarketplaceOrder(row interface{ Scan(...interface{}) error }, link *models.MarketplaceOrder) error {
+	err := row.Scan(&link.ID, &link.IntegrationID, &link.MarketplaceOrderID, &link.OrderNumber, &link.OrderID,
+		&link.MarketplaceStatus, &link.SyncedStatus, &link.LastError, &link.ImportedAt, &link.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrMarketplaceOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to scan marketplace order: %w", err)
+	}
+	return nil
+}
+
+// getLink returns the marketplace order matching a condition
+func (s *MarketplaceOrderImportService) getLink(where string, args ...interface{}) (*models.MarketplaceOrder, error) {
+	var link models.MarketplaceOrder
+	row := s.repo.QueryRow(`SELECT `+marketplaceOrderColumns+` FROM marketplace_orders WHERE `+where, args...)
+	if err := scanMarketplaceOrder(row, &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetCursor returns how far the orders of an integration have been
+// imported. Integrations that were never imported have an empty cursor.
+func (s *MarketplaceOrderImportService) GetCursor(integrationID string) (*models.MarketplaceOrderCursor, error) {
+	cursor := &models.MarketplaceOrderCursor{IntegrationID: integrationID}
+	var lastOrderAt, lastRunAt sql.NullTime
+	err := s.repo.QueryRow(`
+		SELECT last_order_at, last_run_at, COALESCE(last_error, ''), imported_count, updated_at
+		FROM marketplace_order_cursors WHERE integration_id = ?`, integrationID).
+		Scan(&lastOrderAt, &lastRunAt, &cursor.LastError, &cursor.ImportedCount, &cursor.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return cursor, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marketplace order cursor: %w", err)
+	}
+	if lastOrderAt.Valid {
+		cursor.LastOrderAt = &lastOrderAt.Time
+	}
+	if lastRunAt.Valid {
+		cursor.LastRunAt = &lastRunAt.Time
+	}
+	return cursor, nil
+}
+
+// saveCursor writes the cursor of an integration after an import run
+func (s *MarketplaceOrderImportService) saveCursor(integrationID string, lastOrderAt *time.Time, runAt time.Time, lastError string, imported int) error {
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_order_cursors
+		SET last_order_at = ?, last_run_at = ?, last_error = ?, imported_count = imported_count + ?, updated_at = ?
+		WHERE integration_id = ?`,
+		lastOrderAt, runAt, lastError, imported, runAt, integrationID)
+	if err != nil {
+		return fmt.Errorf("failed to save marketplace order cursor: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	_, err = s.repo.Exec(`
+		INSERT INTO marketplace_order_cursors (integration_id, last_order_at, last_run_at, last_error, imported_count, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		integrationID, lastOrderAt, runAt, lastError, imported, runAt)
+	if err != nil {
+		return fmt.Errorf("failed to save marketplace order cursor: %w", err)
+	}
+	return nil
+}
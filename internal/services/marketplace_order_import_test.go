@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// fakeMarketplace lists orders and records the status, stock and price
+// updates pushed to a marketplace. Stock and price updates fail with err
+// while it is set.
+type fakeMarketplace struct {
+	marketplace.MarketplaceProvider
+	mu      sync.Mutex
+	orders  []marketplace.Order
+	updates []string
+	stock   map[string]int
+	prices  map[string][2]float64
+	err     error
+}
+
+func (f *fakeMarketplace) GetOrders(ctx context.Context, query marketplace.OrderQuery) (*marketplace.OrderPage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	page := &marketplace.OrderPage{}
+	for _, order := range f.orders {
+		if !order.OrderDate.Before(query.Since) {
+			page.Orders = append(page.Orders, order)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeMarketplace) UpdateStockAndPrice(ctx context.Context, updates []marketplace.StockPriceUpdate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	if f.stock == nil {
+		f.stock = make(map[string]int)
+		f.prices = make(map[string][2]float64)
+	}
+	for _, update := range updates {
+		if update.Stock != nil {
+			f.stock[update.SKU] = *update.Stock
+		}
+		if update.Price != nil {
+			f.prices[update.SKU] = [2]float64{*update.Price, update.ListPrice}
+		}
+	}
+	return nil
+}
+
+func (f *fakeMarketplace) UpdateOrderStatus(ctx context.Context, orderID string, status string, shipment *marketplace.Shipment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, orderID+":"+status)
+	return nil
+}
+
+func (f *fakeMarketplace) pushed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.updates...)
+}
+
+func newTestOrderImport(t *testing.T) (*MarketplaceOrderImportService, *fakeMarketplace, *OrderStateMachine, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	sm := newTestStateMachine(t, repo, nil)
+	provider := &fakeMarketplace{}
+	s, err := NewMarketplaceOrderImportService(repo, MarketplaceOrderImportConfig{
+		StateMachine: sm,
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			return provider, nil
+		},
+		IntegrationIDs: []string{"trendyol"},
+		Logger:         testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, provider, sm, repo
+}
+
+// marketplaceOrder is a paid trendyol order of quantity units of a SKU
+func marketplaceOrder(id, status, sku string, quantity int) *marketplace.Order {
+	return &marketplace.Order{
+		ID:            id,
+		OrderNumber:   id,
+		Status:        status,
+		PaymentStatus: "paid",
+		Items:         []marketplace.OrderItem{{SKU: sku, Name: "Ürün", Quantity: quantity, Price: 40}},
+		OrderDate:     time.Now().UTC().Add(-time.Hour),
+	}
+}
+
+func importedOrder(t *testing.T, s *MarketplaceOrderImportService, marketplaceOrderID string) (*models.MarketplaceOrder, *models.Order) {
+	t.Helper()
+	link, err := s.getLink(`integration_id = ? AND marketplace_order_id = ?`, "trendyol", marketplaceOrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order models.Order
+	if err := s.repo.QueryRow(`SELECT user_id, status FROM orders WHERE id = ?`, link.OrderID).Scan(&order.UserID, &order.Status); err != nil {
+		t.Fatal(err)
+	}
+	return link, &order
+}
+
+func TestImportOrderUnderChannelUser(t *testing.T) {
+	s, provider, _, repo := newTestOrderImport(t)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 40, 5)
+
+	order := marketplaceOrder("TY-1", marketplace.OrderStatusShipped, "SKU-1", 2)
+	order.TrackingNumber = "TR123"
+	created, err := s.ImportOrder("trendyol", order)
+	if err != nil || !created {
+		t.Fatalf("import created %v (err %v)", created, err)
+	}
+	link, local := importedOrder(t, s, "TY-1")
+	if local.Status != models.OrderStatusShipped || link.SyncedStatus != models.OrderStatusShipped || link.LastError != "" {
+		t.Fatalf("order is %s, link %+v", local.Status, link)
+	}
+	if stock := productStock(t, repo, productID); stock != 3 {
+		t.Fatalf("stock = %d, want 3", stock)
+	}
+	if pushed := provider.pushed(); len(pushed) != 0 {
+		t.Fatalf("statuses from the marketplace were pushed back: %v", pushed)
+	}
+
+	var email string
+	var active bool
+	if err := repo.QueryRow(`SELECT email, is_active FROM users WHERE id = ?`, local.UserID).Scan(&email, &active); err != nil {
+		t.Fatal(err)
+	}
+	if email != "trendyol@"+marketplaceUserDomain || active {
+		t.Fatalf("order placed under %s (active %v), want the inactive channel user", email, active)
+	}
+
+	if created, err := s.ImportOrder("trendyol", order); err != nil || created {
+		t.Fatalf("second import created %v (err %v)", created, err)
+	}
+	if _, err := s.ImportOrder("trendyol", marketplaceOrder("TY-2", marketplace.OrderStatusConfirmed, "SKU-1", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, second := importedOrder(t, s, "TY-2"); second.UserID != local.UserID {
+		t.Fatalf("orders of one channel placed under users %d and %d", local.UserID, second.UserID)
+	}
+}
+
+func TestImportOrderRejectsUnmatchedSKU(t *testing.T) {
+	s, _, _, repo := newTestOrderImport(t)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 40, 5)
+
+	order := marketplaceOrder("TY-1", marketplace.OrderStatusConfirmed, "SKU-1", 1)
+	order.Items = append(order.Items, marketplace.OrderItem{SKU: "UNKNOWN", Quantity: 1, Price: 10})
+	if _, err := s.ImportOrder("trendyol", order); !errors.Is(err, ErrUnmatchedMarketplaceSKU) {
+		t.Fatalf("got %v, want ErrUnmatchedMarketplaceSKU", err)
+	}
+	var orders int
+	if err := repo.QueryRow(`SELECT COUNT(*) FROM orders`).Scan(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if orders != 0 {
+		t.Fatalf("%d orders created for a rejected import", orders)
+	}
+	if stock := productStock(t, repo, productID); stock != 5 {
+		t.Fatalf("stock = %d after a rejected import", stock)
+	}
+}
+
+func TestImportOrderRetriesFailedStatus(t *testing.T) {
+	s, provider, sm, repo := newTestOrderImport(t)
+	seedProduct(t, repo, seedVendor(t, repo, 10), 40, 5)
+
+	// Shipping needs a tracking number the marketplace did not send
+	order := marketplaceOrder("TY-1", marketplace.OrderStatusShipped, "SKU-1", 1)
+	if created, err := s.ImportOrder("trendyol", order); err != nil || !created {
+		t.Fatalf("import created %v (err %v)", created, err)
+	}
+	link, local := importedOrder(t, s, "TY-1")
+	if local.Status != models.OrderStatusConfirmed || link.SyncedStatus != models.OrderStatusPending || link.LastError == "" {
+		t.Fatalf("order is %s, link %+v, want confirmed and unsynced with an error", local.Status, link)
+	}
+
+	// The same status is applied again while the last attempt failed
+	if _, err := s.ImportOrder("trendyol", order); err == nil {
+		t.Fatalf("re-import succeeded without a tracking number")
+	}
+	if pushed, err := s.PushPendingUpdates(); err != nil || pushed != 0 {
+		t.Fatalf("pushed %d orders still following the marketplace (err %v)", pushed, err)
+	}
+
+	mustExec(t, repo, `UPDATE orders SET tracking_number = 'TR123' WHERE id = ?`, link.OrderID)
+	updated, err := s.RetryStatusUpdates()
+	if err != nil || updated != 1 {
+		t.Fatalf("retried %d orders (err %v), want 1", updated, err)
+	}
+	link, local = importedOrder(t, s, "TY-1")
+	if local.Status != models.OrderStatusShipped || link.SyncedStatus != models.OrderStatusShipped || link.LastError != "" {
+		t.Fatalf("order is %s, link %+v, want shipped and synced", local.Status, link)
+	}
+	if pushed := provider.pushed(); len(pushed) != 0 {
+		t.Fatalf("statuses from the marketplace were pushed back: %v", pushed)
+	}
+
+	// Local changes are still pushed
+	if _, err := sm.Transition(&TransitionRequest{OrderID: link.OrderID, To: models.OrderStatusDelivered}); err != nil {
+		t.Fatal(err)
+	}
+	if pushed := provider.pushed(); len(pushed) != 1 || pushed[0] != "TY-1:"+marketplace.OrderStatusDelivered {
+		t.Fatalf("pushed %v, want the delivery", pushed)
+	}
+}
+
+func TestImportParksFailedOrders(t *testing.T) {
+	s, provider, _, repo := newTestOrderImport(t)
+	seedProduct(t, repo, seedVendor(t, repo, 10), 40, 5)
+
+	// The first order sells a product the catalog does not have yet
+	failing := marketplaceOrder("TY-1", marketplace.OrderStatusConfirmed, "SKU-2", 1)
+	failing.OrderDate = time.Now().UTC().Add(-2 * time.Hour)
+	provider.orders = []marketplace.Order{*failing, *marketplaceOrder("TY-2", marketplace.OrderStatusConfirmed, "SKU-1", 1)}
+
+	result, err := s.ImportIntegration("trendyol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || result.Failed != 1 {
+		t.Fatalf("result = %+v, want one imported and one failed order", result)
+	}
+	cursor, err := s.GetCursor("trendyol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.LastOrderAt == nil || !cursor.LastOrderAt.Equal(provider.orders[1].OrderDate) {
+		t.Fatalf("cursor at %v, want past the failed order at %v", cursor.LastOrderAt, provider.orders[1].OrderDate)
+	}
+	parked, err := s.GetParkedOrders("trendyol", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parked) != 1 || parked[0].MarketplaceOrderID != "TY-1" || parked[0].Attempts != 1 || parked[0].LastError == "" {
+		t.Fatalf("parked orders = %+v", parked)
+	}
+
+	// The order stays parked while it fails and is imported once the
+	// product is in the catalog
+	if imported, err := s.RetryParkedOrders(); err != nil || imported != 0 {
+		t.Fatalf("retry imported %d orders (err %v), want none", imported, err)
+	}
+	if parked, _ := s.GetParkedOrders("trendyol", 0); len(parked) != 1 || parked[0].Attempts != 2 {
+		t.Fatalf("parked orders after a failed retry = %+v", parked)
+	}
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 40, 5) // SKU-2
+	if imported, err := s.RetryParkedOrders(); err != nil || imported != 1 {
+		t.Fatalf("retry imported %d orders (err %v), want 1", imported, err)
+	}
+	if parked, _ := s.GetParkedOrders("", 0); len(parked) != 0 {
+		t.Fatalf("imported order still parked: %+v", parked)
+	}
+	if _, local := importedOrder(t, s, "TY-1"); local.Status != models.OrderStatusConfirmed {
+		t.Fatalf("retried order is %s", local.Status)
+	}
+	if stock := productStock(t, repo, productID); stock != 4 {
+		t.Fatalf("stock = %d, want 4", stock)
+	}
+}
@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+)
+
+// Marketplace sync errors
+var (
+	ErrSyncRunNotFound = errors.New("sync run not found")
+	ErrNothingToRetry  = errors.New("sync run has no failed listings to retry")
+)
+
+// MarketplaceSyncConfig holds sync run settings and collaborators
+type MarketplaceSyncConfig struct {
+	// Integrations supplies the credentials of the marketplaces. Its
+	// Provider method is the default ProviderFactory.
+	Integrations *MarketplaceIntegrationsService
+	// ProviderFactory overrides how providers are created
+	ProviderFactory MarketplaceProviderFactory
+	// Catalog, when set, completes listings from the category and attribute
+	// mappings before they are sent. It defaults to the catalog service
+	// registered with Integrations.
+	Catalog *MarketplaceCatalogService
+	// PendingTimeout is how long a listing can wait for its batch result
+	// before it is marked failed. It defaults to 24 hours.
+	PendingTimeout time.Duration
+	// Timeout bounds the marketplace calls of a run. It defaults to five
+	// minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// MarketplaceSyncService records every listing sync as a run with the
+// outcome of each listing: accepted, rejected with the marketplace's
+// reason, or pending until the marketplace's batch result is polled.
+// Failed listings of a run can be sent again on their own.
+type MarketplaceSyncService struct {
+	repo      database.SimpleRepository
+	config    MarketplaceSyncConfig
+	providers MarketplaceProviderFactory
+	logger    *log.Logger
+}
+
+// syncEntry is a listing of a run while it is being sent
+type syncEntry struct {
+	item    models.MarketplaceSyncItem
+	listing marketplace.Listing
+}
+
+// NewMarketplaceSyncService creates a new marketplace sync service
+func NewMarketplaceSyncService(repo database.SimpleRepository, config MarketplaceSyncConfig) (*MarketplaceSyncService, error) {
+	providers := config.ProviderFactory
+	if providers == nil {
+		if config.Integrations == nil {
+			return nil, ErrNoMarketplaceProvider
+		}
+		providers = config.Integrations.Provider
+	}
+	if config.PendingTimeout <= 0 {
+		config.PendingTimeout = 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	s := &MarketplaceSyncService{repo: repo, config: config, providers: providers, logger: logger}
+	if config.Integrations != nil {
+		config.Integrations.syncRuns = s
+	}
+	return s, nil
+}
+
+// catalog returns the catalog service listings are completed with
+func (s *MarketplaceSyncService) catalog() *MarketplaceCatalogService {
+	if s.config.Catalog != nil {
+		return s.config.Catalog
+	}
+	if s.config.Integrations != nil {
+		return s.config.Integrations.catalog
+	}
+	return nil
+}
+
+// Sync sends listings to a marketplace and records the run. Rejected
+// listings do not fail the sync; they are recorded on the run. An error is
+// returned only when the run could not be recorded, with the run as far as
+// it got.
+func (s *MarketplaceSyncService) Sync(integrationID string, listings []marketplace.Listing, trigger string, retryOf int64) (*models.MarketplaceSyncRun, error) {
+	now := time.Now().UTC()
+	run := &models.MarketplaceSyncRun{
+		IntegrationID: integrationID,
+		Trigger:       trigger,
+		RetryOf:       retryOf,
+		Status:        models.SyncRunRunning,
+		Total:         len(listings),
+		StartedAt:     now,
+		UpdatedAt:     now,
+	}
+	result, err := s.repo.Exec(`
+		INSERT INTO marketplace_sync_runs (integration_id, trigger_type, retry_of, status, total, error_message, started_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, '', ?, ?)`,
+		run.IntegrationID, run.Trigger, run.RetryOf, run.Status, run.Total, run.StartedAt, run.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync run: %w", err)
+	}
+	run.ID, _ = result.LastInsertId()
+
+	entries := make([]*syncEntry, len(listings))
+	for i, listing := range listings {
+		entries[i] = &syncEntry{
+			listing: listing,
+			item: models.MarketplaceSyncItem{
+				RunID:         run.ID,
+				IntegrationID: integrationID,
+				ProductID:     int64(listing.ProductID),
+				SKU:           listing.SKU,
+				Barcode:       listing.Barcode,
+			},
+		}
+	}
+
+	runErr := s.send(integrationID, entries)
+	if runErr != nil {
+		s.logger.Printf("Marketplace sync run %d to %s failed: %v", run.ID, integrationID, runErr)
+	}
+
+	for _, entry := range entries {
+		if err := s.saveItem(entry); err != nil {
+			return run, err
+		}
+	}
+	errorMessage := ""
+	if runErr != nil {
+		errorMessage = runErr.Error()
+	}
+	if err := s.refreshRun(run.ID, errorMessage); err != nil {
+		return run, err
+	}
+	return s.GetRun(run.ID)
+}
+
+// send completes and sends the listings of a run and sets the status of
+// each entry. The error is what stopped the sync, if anything did.
+func (s *MarketplaceSyncService) send(integrationID string, entries []*syncEntry) error {
+	catalog := s.catalog()
+	sent := make([]*syncEntry, 0, len(entries))
+	listings := make([]marketplace.Listing, 0, len(entries))
+	for _, entry := range entries {
+		listing := entry.listing
+		if catalog != nil {
+			prepared, err := catalog.PrepareListing(integrationID, listing)
+			var invalid *marketplace.ValidationError
+			if errors.As(err, &invalid) {
+				entry.reject(validationMessage(invalid))
+				continue
+			}
+			if err != nil {
+				entry.fail(err.Error())
+				continue
+			}
+			listing = prepared
+		}
+		if entry.item.SKU == "" {
+			entry.item.SKU = listing.SKU
+		}
+		sent = append(sent, entry)
+		listings = append(listings, listing)
+	}
+	if len(listings) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	provider, err := s.providers(ctx, integrationID)
+	if err != nil {
+		for _, entry := range sent {
+			entry.fail(err.Error())
+		}
+		return err
+	}
+
+	report, err := provider.SyncProducts(ctx, listings)
+	var invalid *marketplace.ValidationError
+	if errors.As(err, &invalid) {
+		// Nothing was sent; the listings that could be mapped are sent
+		// again on retry
+		reasons := make(map[string][]string)
+		for _, e := range invalid.Errors {
+			reasons[e.Key] = append(reasons[e.Key], e.Field+" "+e.Message)
+		}
+		for _, entry := range sent {
+			if messages, ok := reasons[entry.item.SKU]; ok {
+				entry.reject(strings.Join(messages, "; "))
+			} else if messages, ok := reasons[entry.item.Barcode]; ok && entry.item.Barcode != "" {
+				entry.reject(strings.Join(messages, "; "))
+			} else {
+				entry.fail("not sent: other listings of the sync could not be mapped")
+			}
+		}
+		return nil
+	}
+
+	results := make(map[string]marketplace.ListingResult)
+	if report != nil {
+		for _, r := range report.Results {
+			if r.SKU != "" {
+				results["sku:"+r.SKU] = r
+			}
+			if r.Barcode != "" {
+				results["barcode:"+r.Barcode] = r
+			}
+		}
+	}
+	for _, entry := range sent {
+		r, ok := results["sku:"+entry.item.SKU]
+		if !ok && entry.item.Barcode != "" {
+			r, ok = results["barcode:"+entry.item.Barcode]
+		}
+		switch {
+		case ok:
+			entry.item.Status = r.Status
+			entry.item.Message = r.Message
+			entry.item.BatchID = r.BatchID
+		case err != nil:
+			entry.fail(err.Error())
+		default:
+			entry.fail("the marketplace did not report the listing")
+		}
+	}
+	return err
+}
+
+func (e *syncEntry) reject(message string) {
+	e.item.Status = models.SyncItemRejected
+	e.item.Message = message
+}
+
+func (e *syncEntry) fail(message string) {
+	e.item.Status = models.SyncItemFailed
+	e.item.Message = message
+}
+
+// validationMessage joins the field errors of a validation error
+func validationMessage(err *marketplace.ValidationError) string {
+	messages := make([]string, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		messages = append(messages, strings.TrimSpace(e.Field+" "+e.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// saveItem records the outcome of a listing with the listing itself, which
+// a retry sends again
+func (s *MarketplaceSyncService) saveItem(entry *syncEntry) error {
+	listing, err := json.Marshal(entry.listing)
+	if err != nil {
+		return fmt.Errorf("failed to encode listing: %w", err)
+	}
+	now := time.Now().UTC()
+	entry.item.CreatedAt, entry.item.UpdatedAt = now, now
+	result, err := s.repo.Exec(`
+		INSERT INTO marketplace_sync_items (run_id, integration_id, product_id, sku, barcode, status, message, batch_id, listing, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.item.RunID, entry.item.IntegrationID, entry.item.ProductID, entry.item.SKU, entry.item.Barcode,
+		entry.item.Status, entry.item.Message, entry.item.BatchID, string(listing), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save sync item: %w", err)
+	}
+	entry.item.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// refreshRun recounts the items of a run and updates its status. A run is
+// processing while listings are pending, and finished once none are.
+func (s *MarketplaceSyncService) refreshRun(runID int64, errorMessage string) error {
+	rows, err := s.repo.Query(`SELECT status, COUNT(*) FROM marketplace_sync_items WHERE run_id = ? GROUP BY status`, runID)
+	if err != nil {
+		return fmt.Errorf("failed to count sync items: %w", err)
+	}
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan sync item count: %w", err)
+		}
+		counts[status] = count
+	}
+	rows.Close()
+
+	accepted, rejected := counts[models.SyncItemAccepted], counts[models.SyncItemRejected]
+	pending, failed := counts[models.SyncItemPending], counts[models.SyncItemFailed]
+	now := time.Now().UTC()
+	var finishedAt *time.Time
+	status := models.SyncRunProcessing
+	if pending == 0 {
+		finishedAt = &now
+		switch {
+		case accepted == 0 && rejected == 0 && failed > 0:
+			status = models.SyncRunFailed
+		case rejected > 0 || failed > 0:
+			status = models.SyncRunPartial
+		default:
+			status = models.SyncRunCompleted
+		}
+	}
+
+	_, err = s.repo.Exec(`
+		UPDATE marketplace_sync_runs
+		SET status = ?, accepted = ?, rejected = ?, pending = ?, failed = ?,
+			error_message = CASE WHEN ? <> '' THEN ? ELSE error_message END,
+			finished_at = ?, updated_at = ?
+		WHERE id = ?`,
+		status, accepted, rejected, pending, failed, errorMessage, errorMessage, finishedAt, now, runID)
+	if err != nil {
+		return fmt.Errorf("failed to update sync run: %w", err)
+	}
+	return nil
+}
+
+// PollPending asks the marketplaces for the results of the batches with
+// pending listings and records them. Listings still pending after the
+// pending timeout are marked failed. It returns the number of listings
+// whose outcome was recorded.
+func (s *MarketplaceSyncService) PollPending() (int, error) {
+	rows, err := s.repo.Query(`
+		SELECT DISTINCT integration_id, batch_id FROM marketplace_sync_items
+		WHERE status = ? AND batch_id <> ''`, models.SyncItemPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find pending batches: %w", err)
+	}
+	type batch struct{ integrationID, batchID string }
+	var batches []batch
+	for rows.Next() {
+		var b batch
+		if err := rows.Scan(&b.integrationID, &b.batchID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending batch: %w", err)
+		}
+		batches = append(batches, b)
+	}
+	rows.Close()
+
+	updated := 0
+	var errs []error
+	for _, b := range batches {
+		n, err := s.pollBatch(b.integrationID, b.batchID)
+		updated += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s batch %s: %w", b.integrationID, b.batchID, err))
+		}
+	}
+
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_sync_items SET status = ?, message = ?, updated_at = ?
+		WHERE status = ? AND created_at < ?`,
+		models.SyncItemFailed, "the marketplace did not report a result in time", now,
+		models.SyncItemPending, now.Add(-s.config.PendingTimeout))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to expire pending sync items: %w", err))
+	} else if affected, err := result.RowsAffected(); err == nil {
+		updated += int(affected)
+	}
+
+	if err := s.refreshProcessingRuns(); err != nil {
+		errs = append(errs, err)
+	}
+	return updated, errors.Join(errs...)
+}
+
+// pollBatch records the results of one marketplace batch
+func (s *MarketplaceSyncService) pollBatch(integrationID, batchID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	provider, err := s.providers(ctx, integrationID)
+	if err != nil {
+		return 0, err
+	}
+	status, err := provider.GetBatchStatus(ctx, batchID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	updated := 0
+	for _, r := range status.Results {
+		if r.Status == marketplace.ListingPending {
+			continue
+		}
+		column, key := "sku", r.SKU
+		if key == "" {
+			column, key = "barcode", r.Barcode
+		}
+		if key == "" {
+			continue
+		}
+		result, err := s.repo.Exec(`
+			UPDATE marketplace_sync_items SET status = ?, message = ?, updated_at = ?
+			WHERE integration_id = ? AND batch_id = ? AND status = ? AND `+column+` = ?`,
+			r.Status, r.Message, now, integrationID, batchID, models.SyncItemPending, key)
+		if err != nil {
+			return updated, fmt.Errorf("failed to update sync item: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			updated += int(affected)
+		}
+	}
+
+	if status.Done {
+		result, err := s.repo.Exec(`
+			UPDATE marketplace_sync_items SET status = ?, message = ?, updated_at = ?
+			WHERE integration_id = ? AND batch_id = ? AND status = ?`,
+			models.SyncItemFailed, "the marketplace finished the batch without reporting the listing", now,
+			integrationID, batchID, models.SyncItemPending)
+		if err != nil {
+			return updated, fmt.Errorf("failed to close sync batch: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			updated += int(affected)
+		}
+	}
+	return updated, nil
+}
+
+// refreshProcessingRuns updates the runs waiting for batch results
+func (s *MarketplaceSyncService) refreshProcessingRuns() error {
+	rows, err := s.repo.Query(`SELECT id FROM marketplace_sync_runs WHERE status = ?`, models.SyncRunProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to find processing sync runs: %w", err)
+	}
+	var runIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		runIDs = append(runIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range runIDs {
+		if err := s.refreshRun(id, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retry sends the rejected and failed listings of a run again as a new
+// run. The listings are completed from the current mappings, so mapping
+// fixes take effect.
+func (s *MarketplaceSyncService) Retry(runID int64) (*models.MarketplaceSyncRun, error) {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repo.Query(`
+		SELECT COALESCE(listing, '') FROM marketplace_sync_items
+		WHERE run_id = ? AND status IN (?, ?) ORDER BY id ASC`,
+		runID, models.SyncItemRejected, models.SyncItemFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed sync items: %w", err)
+	}
+	var listings []marketplace.Listing
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan sync item: %w", err)
+		}
+		var listing marketplace.Listing
+		if err := json.Unmarshal([]byte(data), &listing); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to decode listing: %w", err)
+		}
+		listings = append(listings, listing)
+	}
+	rows.Close()
+
+	if len(listings) == 0 {
+		return nil, ErrNothingToRetry
+	}
+	return s.Sync(run.IntegrationID, listings, models.SyncTriggerRetry, run.ID)
+}
+
+// GetRun returns a sync run
+func (s *MarketplaceSyncService) GetRun(runID int64) (*models.MarketplaceSyncRun, error) {
+	runs, err := s.queryRuns(`WHERE id = ?`, runID)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrSyncRunNotFound
+	}
+	return &runs[0], nil
+}
+
+// ListRuns returns sync runs, newest first, optionally of one integration
+// and status, with the total number of matching runs
+func (s *MarketplaceSyncService) ListRuns(integrationID, status string, limit, offset int) ([]models.MarketplaceSyncRun, int, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	where := `WHERE 1 = 1`
+	var args []interface{}
+	if integrationID != "" {
+		where += ` AND integration_id = ?`
+		args = append(args, integrationID)
+	}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := s.repo.QueryRow(`SELECT COUNT(*) FROM marketplace_sync_runs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count sync runs: %w", err)
+	}
+	runs, err := s.queryRuns(where+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// queryRuns reads sync runs matching a where clause
+func (s *MarketplaceSyncService) queryRuns(where string, args ...interface{}) ([]models.MarketplaceSyncRun, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, integration_id, trigger_type, retry_of, status, total, accepted, rejected, pending, failed,
+			COALESCE(error_message, ''), started_at, finished_at, updated_at
+		FROM marketplace_sync_runs `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.MarketplaceSyncRun
+	for rows.Next() {
+		var run models.MarketplaceSyncRun
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.IntegrationID, &run.Trigger, &run.RetryOf, &run.Status, &run.Total,
+			&run.Accepted, &run.Rejected, &run.Pending, &run.Failed, &run.Error, &run.StartedAt, &finishedAt,
+			&run.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// GetRunItems returns the listings of a run, optionally of one status
+func (s *MarketplaceSyncService) GetRunItems(runID int64, status string) ([]models.MarketplaceSyncItem, error) {
+	where := `WHERE run_id = ?`
+	args := []interface{}{runID}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+	return s.queryItems(where+` ORDER BY id ASC`, args...)
+}
+
+// GetProductSyncStatus returns the latest outcome of a product on every
+// marketplace it was synced to. It answers why a product is not showing on
+// a marketplace.
+func (s *MarketplaceSyncService) GetProductSyncStatus(productID int64) ([]models.MarketplaceSyncItem, error) {
+	return s.queryItems(`
+		WHERE i.product_id = ? AND i.id = (
+			SELECT MAX(latest.id) FROM marketplace_sync_items latest
+			WHERE latest.product_id = i.product_id AND latest.integration_id = i.integration_id
+		)
+		ORDER BY i.integration_id ASC`, productID)
+}
+
+// queryItems reads sync items matching a where clause on the alias i
+func (s *MarketplaceSyncService) queryItems(where string, args ...interface{}) ([]models.MarketplaceSyncItem, error) {
+	rows, err := s.repo.Query(`
+		SELECT i.id, i.run_id, i.integration_id, i.product_id, i.sku, i.barcode, i.status, COALESCE(i.message, ''),
+			i.batch_id, i.created_at, i.updated_at
+		FROM marketplace_sync_items i `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.MarketplaceSyncItem
+	for rows.Next() {
+		var item models.MarketplaceSyncItem
+		if err := rows.Scan(&item.ID, &item.RunID, &item.IntegrationID, &item.ProductID, &item.SKU, &item.Barcode,
+			&item.Status, &item.Message, &item.BatchID, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sync item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
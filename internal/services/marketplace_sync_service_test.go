@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// fakeSyncMarketplace reports the outcome set in outcomes for each listing
+// it is sent, accepting the others. When err is set the sync stops with it
+// after the first reported listings; a validation error stops it before
+// anything is sent.
+type fakeSyncMarketplace struct {
+	marketplace.MarketplaceProvider
+	mu       sync.Mutex
+	outcomes map[string]marketplace.ListingResult
+	batches  map[string]*marketplace.BatchStatus
+	err      error
+	reported int
+	calls    [][]string
+}
+
+func (f *fakeSyncMarketplace) SyncProducts(ctx context.Context, listings []marketplace.Listing) (*marketplace.SyncReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var skus []string
+	for _, listing := range listings {
+		skus = append(skus, listing.SKU)
+	}
+	f.calls = append(f.calls, skus)
+
+	var invalid *marketplace.ValidationError
+	if errors.As(f.err, &invalid) {
+		return nil, f.err
+	}
+	report := &marketplace.SyncReport{}
+	for i, listing := range listings {
+		if f.err != nil && i >= f.reported {
+			break
+		}
+		result, ok := f.outcomes[listing.SKU]
+		if !ok {
+			result = marketplace.ListingResult{Status: marketplace.ListingAccepted}
+		}
+		result.SKU = listing.SKU
+		report.Results = append(report.Results, result)
+	}
+	return report, f.err
+}
+
+func (f *fakeSyncMarketplace) GetBatchStatus(ctx context.Context, batchID string) (*marketplace.BatchStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.batches[batchID]
+	if !ok {
+		return nil, errors.New("unknown batch")
+	}
+	return status, nil
+}
+
+func (f *fakeSyncMarketplace) sentSKUs() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.calls...)
+}
+
+func newTestSyncRuns(t *testing.T, provider *fakeSyncMarketplace, pendingTimeout time.Duration) *MarketplaceSyncService {
+	t.Helper()
+	s, err := NewMarketplaceSyncService(testutil.NewRepo(t), MarketplaceSyncConfig{
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			if provider == nil {
+				return nil, errors.New("trendyol is not configured")
+			}
+			return provider, nil
+		},
+		PendingTimeout: pendingTimeout,
+		Logger:         testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func syncListings(skus ...string) []marketplace.Listing {
+	listings := make([]marketplace.Listing, len(skus))
+	for i, sku := range skus {
+		listings[i] = marketplace.Listing{ProductID: i + 1, SKU: sku, Title: "Ürün " + sku}
+	}
+	return listings
+}
+
+// itemStatuses returns the status and message of each listing of a run by
+// SKU
+func itemStatuses(t *testing.T, s *MarketplaceSyncService, runID int64) map[string]string {
+	t.Helper()
+	items, err := s.GetRunItems(runID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	for _, item := range items {
+		statuses[item.SKU] = item.Status
+		if item.Message != "" {
+			statuses[item.SKU] += ": " + item.Message
+		}
+	}
+	return statuses
+}
+
+func TestSyncRunRecordsListingOutcomes(t *testing.T) {
+	provider := &fakeSyncMarketplace{
+		outcomes: map[string]marketplace.ListingResult{
+			"B": {Status: marketplace.ListingRejected, Message: "barkod başka bir ürüne ait"},
+			"C": {Status: marketplace.ListingPending, BatchID: "batch-1"},
+			"D": {Status: marketplace.ListingPending, BatchID: "batch-1"},
+		},
+		batches: map[string]*marketplace.BatchStatus{
+			"batch-1": {BatchID: "batch-1", Results: []marketplace.ListingResult{{SKU: "C", Status: marketplace.ListingPending}}},
+		},
+	}
+	s := newTestSyncRuns(t, provider, time.Hour)
+
+	run, err := s.Sync("trendyol", syncListings("A", "B", "C", "D"), models.SyncTriggerManual, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.SyncRunProcessing || run.Total != 4 || run.Accepted != 1 || run.Rejected != 1 || run.Pending != 2 || run.FinishedAt != nil {
+		t.Fatalf("run = %+v", run)
+	}
+	if got := itemStatuses(t, s, run.ID)["B"]; got != "rejected: barkod başka bir ürüne ait" {
+		t.Fatalf("rejected listing = %q", got)
+	}
+
+	// A batch still in progress leaves the run processing
+	if updated, err := s.PollPending(); err != nil || updated != 0 {
+		t.Fatalf("poll = %d (err %v)", updated, err)
+	}
+	if run, _ = s.GetRun(run.ID); run.Status != models.SyncRunProcessing {
+		t.Fatalf("run after an unfinished batch = %+v", run)
+	}
+
+	// Once the batch is done, listings it did not report are failed
+	provider.mu.Lock()
+	provider.batches["batch-1"] = &marketplace.BatchStatus{BatchID: "batch-1", Done: true,
+		Results: []marketplace.ListingResult{{SKU: "C", Status: marketplace.ListingAccepted}}}
+	provider.mu.Unlock()
+	if updated, err := s.PollPending(); err != nil || updated != 2 {
+		t.Fatalf("poll = %d (err %v)", updated, err)
+	}
+	run, err = s.GetRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.SyncRunPartial || run.Accepted != 2 || run.Rejected != 1 || run.Failed != 1 || run.Pending != 0 || run.FinishedAt == nil {
+		t.Fatalf("run after the batch = %+v", run)
+	}
+	if got := itemStatuses(t, s, run.ID)["D"]; !strings.HasPrefix(got, models.SyncItemFailed) {
+		t.Fatalf("unreported listing = %q", got)
+	}
+}
+
+func TestSyncRunFailures(t *testing.T) {
+	t.Run("marketplace stops the sync", func(t *testing.T) {
+		provider := &fakeSyncMarketplace{err: errors.New("bağlantı koptu"), reported: 1}
+		s := newTestSyncRuns(t, provider, time.Hour)
+
+		run, err := s.Sync("trendyol", syncListings("A", "B"), models.SyncTriggerManual, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != models.SyncRunPartial || run.Accepted != 1 || run.Failed != 1 || run.Error != "bağlantı koptu" {
+			t.Fatalf("run = %+v", run)
+		}
+		if got := itemStatuses(t, s, run.ID)["B"]; got != "failed: bağlantı koptu" {
+			t.Fatalf("unsent listing = %q", got)
+		}
+	})
+
+	t.Run("marketplace not configured", func(t *testing.T) {
+		s := newTestSyncRuns(t, nil, time.Hour)
+
+		run, err := s.Sync("trendyol", syncListings("A", "B"), models.SyncTriggerManual, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != models.SyncRunFailed || run.Failed != 2 || run.Error == "" {
+			t.Fatalf("run = %+v", run)
+		}
+	})
+
+	t.Run("listing cannot be mapped", func(t *testing.T) {
+		provider := &fakeSyncMarketplace{err: &marketplace.ValidationError{Provider: "trendyol", Errors: []*marketplace.MappingError{
+			{Provider: "trendyol", Entity: marketplace.EntityListing, Key: "B", Field: "barcode", Message: "is required"},
+		}}}
+		s := newTestSyncRuns(t, provider, time.Hour)
+
+		run, err := s.Sync("trendyol", syncListings("A", "B"), models.SyncTriggerManual, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses := itemStatuses(t, s, run.ID)
+		if run.Status != models.SyncRunPartial || run.Rejected != 1 || run.Failed != 1 ||
+			statuses["B"] != "rejected: barcode is required" || !strings.HasPrefix(statuses["A"], models.SyncItemFailed) {
+			t.Fatalf("run = %+v, items %v", run, statuses)
+		}
+	})
+
+	t.Run("batch result never reported", func(t *testing.T) {
+		provider := &fakeSyncMarketplace{
+			outcomes: map[string]marketplace.ListingResult{"A": {Status: marketplace.ListingPending, BatchID: "lost"}},
+		}
+		s := newTestSyncRuns(t, provider, time.Nanosecond)
+
+		run, err := s.Sync("trendyol", syncListings("A"), models.SyncTriggerManual, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		// Polling the batch fails, and the listing is failed once it has
+		// waited longer than the pending timeout
+		updated, err := s.PollPending()
+		if err == nil || updated != 1 {
+			t.Fatalf("poll = %d (err %v), want the batch error and one expired listing", updated, err)
+		}
+		if run, _ = s.GetRun(run.ID); run.Status != models.SyncRunFailed || run.Failed != 1 {
+			t.Fatalf("run = %+v", run)
+		}
+	})
+}
+
+func TestSyncRunRetry(t *testing.T) {
+	provider := &fakeSyncMarketplace{
+		outcomes: map[string]marketplace.ListingResult{"B": {Status: marketplace.ListingRejected, Message: "kategori kapalı"}},
+		err:      errors.New("zaman aşımı"),
+		reported: 2,
+	}
+	s := newTestSyncRuns(t, provider, time.Hour)
+
+	first, err := s.Sync("trendyol", syncListings("A", "B", "C"), models.SyncTriggerManual, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Accepted != 1 || first.Rejected != 1 || first.Failed != 1 {
+		t.Fatalf("first run = %+v", first)
+	}
+
+	// The retry sends the rejected and failed listings only, as a new run
+	// that points back at the first
+	provider.mu.Lock()
+	provider.outcomes, provider.err = nil, nil
+	provider.mu.Unlock()
+	retry, err := s.Retry(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.ID == first.ID || retry.Trigger != models.SyncTriggerRetry || retry.RetryOf != first.ID ||
+		retry.Status != models.SyncRunCompleted || retry.Total != 2 || retry.Accepted != 2 {
+		t.Fatalf("retry run = %+v", retry)
+	}
+	calls := provider.sentSKUs()
+	if last := calls[len(calls)-1]; len(last) != 2 || last[0] != "B" || last[1] != "C" {
+		t.Fatalf("retry sent %v, want B and C", last)
+	}
+	if run, _ := s.GetRun(first.ID); run.Status != models.SyncRunPartial {
+		t.Fatalf("first run changed by the retry: %+v", run)
+	}
+
+	if _, err := s.Retry(retry.ID); !errors.Is(err, ErrNothingToRetry) {
+		t.Fatalf("retry of a completed run: got %v, want ErrNothingToRetry", err)
+	}
+	if _, err := s.Retry(999); !errors.Is(err, ErrSyncRunNotFound) {
+		t.Fatalf("retry of a missing run: got %v, want ErrSyncRunNotFound", err)
+	}
+}
@@ -128,11 +128,9 @@ func (s *NotificationService) SendNotification(req *NotificationRequest) (*model
 	}
 
 	// Save to database
-	id, err := s.repo.Create("notifications", notification)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create notification: %w", err)
+	if err := s.saveNotification(notification); err != nil {
+		return nil, err
 	}
-	notification.ID = uint(id)
 
 	// Send immediately or schedule
 	if req.ScheduledAt == nil || req.ScheduledAt.Before(time.Now()) {
@@ -197,13 +195,13 @@ func (s *NotificationService) SendTransactionalNotification(notificationType str
 	
 	for _, channel := range enabledChannels {
 		req := &NotificationRequest{
-			UserID:    userID,
-			Type:      template.Type,
-			Channel:   channel,
-			Title:     template.Title,
-			Message:   template.Message,
-			Variables: variables,
-			Priority:  template.Priority,
+			UserID:     userID,
+			Type:       template.Type,
+			Channel:    channel,
+			Title:      template.Title,
+			Message:    template.Message,
+			TemplateID: template.ID,
+			Variables:  variables,
 		}
 
 		_, err := s.SendNotification(req)
@@ -260,7 +258,7 @@ func (s *NotificationService) GetUserNotifications(userID uint, limit, offset in
 		return nil, errors.New("user ID is required")
 	}
 
-	query := `SELECT id, recipient_id, type, title, message, priority, status, 
+	query := `SELECT id, recipient_id, type, channel, title, message, priority, status, 
 			  scheduled_at, sent_at, delivered_at, read_at, expires_at, created_at, updated_at 
 			  FROM notifications WHERE recipient_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`
 
@@ -288,7 +286,7 @@ func (s *NotificationService) GetUnreadNotifications(userID uint) ([]*models.Not
 		return nil, errors.New("user ID is required")
 	}
 
-	query := `SELECT id, recipient_id, type, title, message, priority, status, 
+	query := `SELECT id, recipient_id, type, channel, title, message, priority, status, 
 			  scheduled_at, sent_at, delivered_at, read_at, expires_at, created_at, updated_at 
 			  FROM notifications WHERE recipient_id = ? AND read_at IS NULL 
 			  ORDER BY created_at DESC`
@@ -381,25 +379,30 @@ func (s *NotificationService) GetNotificationStats(userID uint, days int) (*Noti
 
 // ProcessScheduledNotifications processes scheduled notifications
 func (s *NotificationService) ProcessScheduledNotifications() error {
-	query := `SELECT id, recipient_id, type, title, message, priority, status, 
+	query := `SELECT id, recipient_id, type, channel, title, message, priority, status, 
 			  scheduled_at, sent_at, delivered_at, read_at, expires_at, created_at, updated_at 
 			  FROM notifications 
-			  WHERE status = 'pending' AND scheduled_at IS NOT NULL AND scheduled_at <= CURRENT_TIMESTAMP`
+			  WHERE status = 'pending' AND scheduled_at IS NOT NULL AND scheduled_at <= ?`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to query scheduled notifications: %w", err)
 	}
-	defer rows.Close()
 
-	var processed int
+	// Due notifications are read before any is sent, as sending updates them
+	var due []*models.Notification
 	for rows.Next() {
 		notification, err := s.scanNotification(rows)
 		if err != nil {
 			fmt.Printf("Warning: Failed to scan scheduled notification: %v\n", err)
 			continue
 		}
+		due = append(due, notification)
+	}
+	rows.Close()
 
+	var processed int
+	for _, notification := range due {
 		if err := s.sendNotificationNow(notification); err != nil {
 			s.updateNotificationStatus(notification.ID, models.NotificationStatusFailed, err.Error())
 			fmt.Printf("Warning: Failed to send scheduled notification %d: %v\n", notification.ID, err)
@@ -436,17 +439,17 @@ func (s *NotificationService) validateNotificationRequest(req *NotificationReque
 
 func (s *NotificationService) canSendToUser(userID uint, notificationType models.NotificationType, channel models.NotificationChannel) bool {
 	// Check user notification preferences
-	query := `SELECT COUNT(*) FROM user_notification_preferences 
-			  WHERE user_id = ? AND notification_type = ? AND channel = ? AND enabled = 1`
+	query := `SELECT enabled FROM user_notification_preferences 
+			  WHERE user_id = ? AND notification_type = ? AND channel = ?`
 
-	var count int
-	err := s.db.QueryRow(query, userID, notificationType, channel).Scan(&count)
+	var enabled bool
+	err := s.db.QueryRow(query, userID, notificationType, channel).Scan(&enabled)
 	if err != nil {
 		// If no preferences found, allow by default
 		return true
 	}
 
-	return count > 0
+	return enabled
 }
 
 func (s *NotificationService) processTemplate(req *NotificationRequest) error {
@@ -517,11 +520,41 @@ func (s *NotificationService) updateSentAt(id uint) {
 	s.db.Exec(query, id)
 }
 
+// saveNotification stores a new notification for its first channel
+func (s *NotificationService) saveNotification(notification *models.Notification) error {
+	var channel models.NotificationChannel
+	if len(notification.Channels) > 0 {
+		channel = notification.Channels[0]
+	}
+	utc := func(t *time.Time) interface{} {
+		if t == nil {
+			return nil
+		}
+		return t.UTC()
+	}
+	result, err := s.db.Exec(`INSERT INTO notifications (recipient_id, recipient_type, type, channel, title, message,
+			  priority, status, scheduled_at, expires_at, created_at, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		notification.RecipientID, notification.RecipientType, notification.Type, channel, notification.Title,
+		notification.Message, notification.Priority, notification.Status, utc(notification.ScheduledAt),
+		utc(notification.ExpiresAt), notification.CreatedAt.UTC(), notification.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get notification ID: %w", err)
+	}
+	notification.ID = uint(id)
+	return nil
+}
+
 func (s *NotificationService) scanNotification(rows *sql.Rows) (*models.Notification, error) {
 	var notification models.Notification
+	var channel models.NotificationChannel
 
 	err := rows.Scan(
-		&notification.ID, &notification.RecipientID, &notification.Type,
+		&notification.ID, &notification.RecipientID, &notification.Type, &channel,
 		&notification.Title, &notification.Message, &notification.Priority,
 		&notification.Status, &notification.ScheduledAt, &notification.SentAt,
 		&notification.DeliveredAt, &notification.ReadAt, &notification.ExpiresAt,
@@ -530,6 +563,7 @@ func (s *NotificationService) scanNotification(rows *sql.Rows) (*models.Notifica
 	if err != nil {
 		return nil, err
 	}
+	notification.Channels = []models.NotificationChannel{channel}
 
 	return &notification, nil
 }
@@ -597,6 +631,20 @@ func (s *NotificationService) getTransactionalTemplates() map[string]*SimpleTemp
 			Message:  "You have received a new message from {{.SenderName}}",
 			Priority: models.NotificationPriorityNormal,
 		},
+		"auction_outbid": {
+			ID:       "auction_outbid",
+			Type:     models.NotificationTypeInfo,
+			Title:    "You Have Been Outbid",
+			Message:  "Someone outbid you on {{.AuctionTitle}}. The current bid is {{.CurrentBid}}",
+			Priority: models.NotificationPriorityHigh,
+		},
+		"auction_won": {
+			ID:       "auction_won",
+			Type:     models.NotificationTypeTransactional,
+			Title:    "Auction Won",
+			Message:  "You won {{.AuctionTitle}} for {{.Amount}}. Please pay order #{{.OrderNumber}}",
+			Priority: models.NotificationPriorityHigh,
+		},
 		"system_maintenance": {
 			ID:       "system_maintenance",
 			Type:     models.NotificationTypeSystem,
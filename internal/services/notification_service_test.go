@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+func TestScheduledNotificationsAreSentWhenDue(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := testutil.NewRepo(t)
+	userID := uint(seedUser(t, repo))
+	s := NewNotificationService(nil, db, nil)
+
+	later := time.Now().Add(time.Hour)
+	scheduled, err := s.SendNotification(&NotificationRequest{
+		UserID:      userID,
+		Type:        models.NotificationTypeInfo,
+		Channel:     models.NotificationChannelInApp,
+		Title:       "Kampanya",
+		Message:     "Yarın başlıyor",
+		ScheduledAt: &later,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := func() string {
+		t.Helper()
+		var status string
+		if err := db.QueryRow(`SELECT status FROM notifications WHERE id = ?`, scheduled.ID).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	if err := s.ProcessScheduledNotifications(); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != string(models.NotificationStatusPending) {
+		t.Fatalf("status before it is due = %s, want pending", got)
+	}
+
+	mustExec(t, repo, `UPDATE notifications SET scheduled_at = ? WHERE id = ?`, time.Now().UTC().Add(-time.Minute), scheduled.ID)
+	if err := s.ProcessScheduledNotifications(); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got != string(models.NotificationStatusSent) {
+		t.Fatalf("status once due = %s, want sent", got)
+	}
+
+	unread, err := s.GetUnreadNotifications(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].Channels[0] != models.NotificationChannelInApp {
+		t.Fatalf("unread = %+v, want the in-app notification", unread)
+	}
+}
+
+func TestNotificationPreferencesDisableChannels(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := testutil.NewRepo(t)
+	userID := uint(seedUser(t, repo))
+	s := NewNotificationService(nil, db, nil)
+	mustExec(t, repo, `INSERT INTO user_notification_preferences (user_id, notification_type, channel, enabled) VALUES (?, ?, ?, 0)`,
+		userID, models.NotificationTypeMarketing, models.NotificationChannelInApp)
+
+	request := func(notificationType models.NotificationType) *NotificationRequest {
+		return &NotificationRequest{UserID: userID, Type: notificationType, Channel: models.NotificationChannelInApp, Title: "Merhaba"}
+	}
+	if _, err := s.SendNotification(request(models.NotificationTypeMarketing)); err == nil {
+		t.Fatal("sent a notification the user switched off")
+	}
+	if _, err := s.SendNotification(request(models.NotificationTypeInfo)); err != nil {
+		t.Fatalf("notification without a preference: %v", err)
+	}
+}
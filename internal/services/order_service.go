@@ -1,18 +1,46 @@
 package services
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"kolajAi/internal/cache"
 	"kolajAi/internal/database"
 	"kolajAi/internal/models"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// ErrOrderNotFound is returned for an order that does not exist or belongs
+// to another customer
+var ErrOrderNotFound = errors.New("order not found")
+
 type OrderService struct {
-	repo database.SimpleRepository
+	repo         database.SimpleRepository
+	mu           *sync.Mutex
+	stateMachine *OrderStateMachine
+	// cache, when set, drops the cached reads of orders the service writes
+	cache *cache.EntityCache
+	// cursors signs the cursors of order pages
+	cursors *database.CursorCodec
 }
 
 func NewOrderService(repo database.SimpleRepository) *OrderService {
-	return &OrderService{repo: repo}
+	return &OrderService{repo: repo, mu: &sync.Mutex{}}
+}
+
+// WithContext returns a copy of the service whose repository is bound to
+// ctx, so a request reads the orders it wrote itself
+func (s *OrderService) WithContext(ctx context.Context) *OrderService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bound := *s
+	bound.repo = database.BindContext(s.repo, ctx)
+	return &bound
 }
 
 // CreateOrder creates a new order
@@ -39,6 +67,7 @@ func (s *OrderService) CreateOrder(order *models.Order) error {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
 	order.ID = id
+	ordersChanged(s.cache)
 	return nil
 }
 
@@ -52,6 +81,34 @@ func (s *OrderService) GetOrderByID(id int) (*models.Order, error) {
 	return &order, nil
 }
 
+// GetCustomerOrder returns an order of a customer with its items. A
+// multi-vendor order comes with its per-vendor sub-orders, which hold the
+// items, shipments and statuses of each vendor.
+func (s *OrderService) GetCustomerOrder(userID, id int) (*models.Order, error) {
+	order := &models.Order{}
+	err := s.repo.FindOne("orders", order, map[string]interface{}{"id": id, "user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.Items, err = s.GetOrderItems(id); err != nil {
+		return nil, err
+	}
+
+	conditions := map[string]interface{}{"parent_order_id": id}
+	if err := s.repo.FindAll("orders", &order.SubOrders, conditions, "id ASC", 0, 0); err != nil {
+		return nil, fmt.Errorf("failed to get sub-orders: %w", err)
+	}
+	for i := range order.SubOrders {
+		if order.SubOrders[i].Items, err = s.GetOrderItems(int(order.SubOrders[i].ID)); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
 // GetOrderByNumber retrieves an order by order number
 func (s *OrderService) GetOrderByNumber(orderNumber string) (*models.Order, error) {
 	var order models.Order
@@ -63,20 +120,60 @@ func (s *OrderService) GetOrderByNumber(orderNumber string) (*models.Order, erro
 	return &order, nil
 }
 
-// UpdateOrder updates an order
+// UpdateOrder updates the addresses, notes and shipment details of an
+// order. A changed status or payment status goes through the state
+// machine, so it is validated and recorded like any other transition; the
+// amounts of a placed order are never rewritten.
 func (s *OrderService) UpdateOrder(id int, order *models.Order) error {
+	sm, err := s.getStateMachine()
+	if err != nil {
+		return err
+	}
+	current, err := sm.loadOrder(int64(id))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case order.Status != "" && order.Status != current.Status:
+		if _, err := sm.Transition(&TransitionRequest{
+			OrderID:        int64(id),
+			From:           current.Status,
+			To:             order.Status,
+			PaymentStatus:  order.PaymentStatus,
+			TrackingNumber: order.TrackingNumber,
+			CarrierName:    order.CarrierName,
+		}); err != nil {
+			return err
+		}
+	case order.PaymentStatus != "" && order.PaymentStatus != current.PaymentStatus:
+		if _, err := sm.UpdatePaymentStatus(int64(id), order.PaymentStatus, ""); err != nil {
+			return err
+		}
+	}
+
 	order.UpdatedAt = time.Now()
-	err := s.repo.Update("orders", id, order)
+	_, err = s.repo.Exec(`
+		UPDATE orders SET
+			shipping_address = ?, shipping_city = ?, shipping_state = ?, shipping_zip = ?, shipping_country = ?, shipping_phone = ?,
+			billing_address = ?, billing_city = ?, billing_state = ?, billing_zip = ?, billing_country = ?, billing_phone = ?,
+			tracking_number = ?, carrier_name = ?, notes = ?, internal_notes = ?, updated_at = ?
+		WHERE id = ?`,
+		order.ShippingAddress, order.ShippingCity, order.ShippingState, order.ShippingZip, order.ShippingCountry, order.ShippingPhone,
+		order.BillingAddress, order.BillingCity, order.BillingState, order.BillingZip, order.BillingCountry, order.BillingPhone,
+		order.TrackingNumber, order.CarrierName, order.Notes, order.InternalNotes, order.UpdatedAt, id)
 	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
+	ordersChanged(s.cache)
 	return nil
 }
 
-// GetOrdersByUser retrieves orders by user ID
+// GetOrdersByUser retrieves orders by user ID. The per-vendor sub-orders
+// of multi-vendor orders are left out; customers see the parent order.
 func (s *OrderService) GetOrdersByUser(userID int, limit, offset int) ([]models.Order, error) {
 	var orders []models.Order
-	conditions := map[string]interface{}{"user_id": userID}
+	conditions := map[string]interface{}{"user_id": userID, "parent_order_id": 0}
 
 	err := s.repo.FindAll("orders", &orders, conditions, "created_at DESC", limit, offset)
 	if err != nil {
@@ -143,6 +240,7 @@ func (s *OrderService) AddOrderItem(item *models.OrderItem) error {
 		return fmt.Errorf("failed to add order item: %w", err)
 	}
 	item.ID = id
+	ordersChanged(s.cache)
 	return nil
 }
 
@@ -182,84 +280,140 @@ func (s *OrderService) GetOrderAddresses(orderID int) ([]models.OrderAddress, er
 
 // ConfirmOrder confirms an order
 func (s *OrderService) ConfirmOrder(orderID int) error {
-	order := &models.Order{
-		Status:    "confirmed",
-		UpdatedAt: time.Now(),
-	}
-	return s.UpdateOrder(orderID, order)
+	return s.transitionOrder(&TransitionRequest{
+		OrderID: int64(orderID),
+		To:      models.OrderStatusConfirmed,
+	})
 }
 
 // ShipOrder marks an order as shipped
 func (s *OrderService) ShipOrder(orderID int, trackingNumber string) error {
-	now := time.Now()
-	order := &models.Order{
-		Status:         "shipped",
+	return s.transitionOrder(&TransitionRequest{
+		OrderID:        int64(orderID),
+		To:             models.OrderStatusShipped,
 		TrackingNumber: trackingNumber,
-		ShippedAt:      &now,
-		UpdatedAt:      now,
-	}
-	return s.UpdateOrder(orderID, order)
+	})
 }
 
 // DeliverOrder marks an order as delivered
 func (s *OrderService) DeliverOrder(orderID int) error {
-	now := time.Now()
-	order := &models.Order{
-		Status:      "delivered",
-		DeliveredAt: &now,
-		UpdatedAt:   now,
-	}
-	return s.UpdateOrder(orderID, order)
+	return s.transitionOrder(&TransitionRequest{
+		OrderID: int64(orderID),
+		To:      models.OrderStatusDelivered,
+	})
 }
 
 // CancelOrder cancels an order
 func (s *OrderService) CancelOrder(orderID int) error {
-	order := &models.Order{
-		Status:    "cancelled",
-		UpdatedAt: time.Now(),
+	return s.transitionOrder(&TransitionRequest{
+		OrderID: int64(orderID),
+		To:      models.OrderStatusCancelled,
+	})
+}
+
+// SetCache sets the cache whose order reads the service's writes
+// invalidate. A default state machine created afterwards invalidates it
+// too.
+func (s *OrderService) SetCache(c *cache.EntityCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = c
+}
+
+// SetCursorCodec sets the codec that signs the cursors of order pages
+func (s *OrderService) SetCursorCodec(codec *database.CursorCodec) {
+	s.cursors = codec
+}
+
+// SetStateMachine sets the state machine order status changes go through
+func (s *OrderService) SetStateMachine(sm *OrderStateMachine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateMachine = sm
+}
+
+// TransitionOrder moves an order to another status through the state
+// machine. Rejected transitions return a *TransitionError.
+func (s *OrderService) TransitionOrder(req *TransitionRequest) (*models.Order, error) {
+	sm, err := s.getStateMachine()
+	if err != nil {
+		return nil, err
+	}
+	return sm.Transition(req)
+}
+
+// GetOrderStatusHistory returns the status changes of an order, oldest first
+func (s *OrderService) GetOrderStatusHistory(orderID int) ([]models.OrderStatusHistory, error) {
+	sm, err := s.getStateMachine()
+	if err != nil {
+		return nil, err
 	}
-	return s.UpdateOrder(orderID, order)
+	return sm.History(int64(orderID))
 }
 
-// UpdatePaymentStatus updates the payment status of an order
+func (s *OrderService) transitionOrder(req *TransitionRequest) error {
+	_, err := s.TransitionOrder(req)
+	return err
+}
+
+// getStateMachine returns the configured state machine, creating a default
+// one on first use
+func (s *OrderService) getStateMachine() (*OrderStateMachine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stateMachine == nil {
+		sm, err := NewOrderStateMachine(s.repo, OrderStateMachineConfig{Cache: s.cache})
+		if err != nil {
+			return nil, err
+		}
+		s.stateMachine = sm
+	}
+	return s.stateMachine, nil
+}
+
+// UpdatePaymentStatus updates the payment status of an order through the
+// state machine. A paid pending order is confirmed and a failed one is
+// cancelled.
 func (s *OrderService) UpdatePaymentStatus(orderID int, paymentStatus string) error {
-	order := &models.Order{
-		PaymentStatus: paymentStatus,
-		UpdatedAt:     time.Now(),
+	sm, err := s.getStateMachine()
+	if err != nil {
+		return err
 	}
-	return s.UpdateOrder(orderID, order)
+	_, err = sm.UpdatePaymentStatus(int64(orderID), paymentStatus, "")
+	return err
 }
 
-// GetOrderStats returns order statistics
+// GetOrderStats returns order statistics. Sub-orders are not counted
+// separately from their parent order.
 func (s *OrderService) GetOrderStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
 	// Total orders
-	totalOrders, err := s.repo.Count("orders", nil)
+	totalOrders, err := s.repo.Count("orders", map[string]interface{}{"parent_order_id": 0})
 	if err == nil {
 		stats["total_orders"] = totalOrders
 	}
 
 	// Pending orders
-	pendingOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "pending"})
+	pendingOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "pending", "parent_order_id": 0})
 	if err == nil {
 		stats["pending_orders"] = pendingOrders
 	}
 
 	// Confirmed orders
-	confirmedOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "confirmed"})
+	confirmedOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "confirmed", "parent_order_id": 0})
 	if err == nil {
 		stats["confirmed_orders"] = confirmedOrders
 	}
 
 	// Shipped orders
-	shippedOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "shipped"})
+	shippedOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "shipped", "parent_order_id": 0})
 	if err == nil {
 		stats["shipped_orders"] = shippedOrders
 	}
 
 	// Delivered orders
-	deliveredOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "delivered"})
+	deliveredOrders, err := s.repo.Count("orders", map[string]interface{}{"status": "delivered", "parent_order_id": 0})
 	if err == nil {
 		stats["delivered_orders"] = deliveredOrders
 	}
@@ -279,10 +433,11 @@ func (s *OrderService) GetVendorOrders(vendorID int, limit, offset int) ([]model
 	return items, nil
 }
 
-// generateOrderNumber generates a unique order number
+// generateOrderNumber generates a unique order number. The random suffix
+// keeps orders placed within the same second apart.
 func (s *OrderService) generateOrderNumber() string {
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("ORD-%d", timestamp)
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return fmt.Sprintf("ORD-%d-%s", time.Now().Unix(), suffix)
 }
 
 // Cart Management
@@ -409,10 +564,11 @@ func (s *OrderService) ClearCart(cartID int) error {
 	return nil
 }
 
-// GetAllOrders returns all orders with pagination
+// GetAllOrders returns all orders with pagination, without the sub-orders
+// of multi-vendor orders
 func (s *OrderService) GetAllOrders(limit, offset int) ([]models.Order, error) {
 	var orders []models.Order
-	conditions := map[string]interface{}{}
+	conditions := map[string]interface{}{"parent_order_id": 0}
 	err := s.repo.FindAll("orders", &orders, conditions, "created_at DESC", limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all orders: %w", err)
@@ -420,8 +576,24 @@ func (s *OrderService) GetAllOrders(limit, offset int) ([]models.Order, error) {
 	return orders, nil
 }
 
+// GetOrdersPage returns a keyset page of all orders, newest first
+func (s *OrderService) GetOrdersPage(params database.CursorPaginationParams) ([]models.Order, database.CursorPaginationResult, error) {
+	keyset := database.Keyset{Scope: "orders", Column: "created_at", Order: database.Descending}
+	orders, result, err := database.Paginate(cursorCodec(s.cursors), keyset, params,
+		func(cursor *database.Cursor, limit int) ([]models.Order, error) {
+			var orders []models.Order
+			err := s.repo.FindAllKeyset("orders", &orders, map[string]interface{}{"parent_order_id": 0}, keyset, cursor, limit)
+			return orders, err
+		},
+		func(order models.Order) (interface{}, int64) { return order.CreatedAt, order.ID })
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to get orders page: %w", err)
+	}
+	return orders, result, nil
+}
+
 // GetOrderCount returns the total number of orders
 func (s *OrderService) GetOrderCount() (int64, error) {
-	conditions := map[string]interface{}{}
+	conditions := map[string]interface{}{"parent_order_id": 0}
 	return s.repo.Count("orders", conditions)
 }
@@ -0,0 +1,1060 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+)
+
+// Order state machine errors
+var (
+	ErrInvalidTransition    = errors.New("invalid order status transition")
+	ErrTransitionRejected   = errors.New("order status transition rejected")
+	ErrConcurrentTransition = errors.New("order status was changed concurrently")
+)
+
+// TransitionError describes a rejected order status change. It wraps one of
+// ErrInvalidTransition, ErrTransitionRejected or ErrConcurrentTransition,
+// and the guard or effect error that caused the rejection if there is one.
+type TransitionError struct {
+	OrderID int64
+	From    string
+	To      string
+	Reason  string
+	Err     error
+	Cause   error
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("order %d: %s -> %s: %v", e.OrderID, e.From, e.To, e.Err)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *TransitionError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Err, e.Cause}
+	}
+	return []error{e.Err}
+}
+
+// orderTransitions lists the statuses each order status may move to
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:    {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed:  {models.OrderStatusProcessing, models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusProcessing: {models.OrderStatusShipped},
+	models.OrderStatusShipped:    {models.OrderStatusDelivered, models.OrderStatusRefunded},
+	models.OrderStatusDelivered:  {models.OrderStatusRefunded},
+	models.OrderStatusCancelled:  {},
+	models.OrderStatusRefunded:   {},
+}
+
+// TransitionRequest asks for an order to be moved to another status
+type TransitionRequest struct {
+	OrderID int64
+	To      string
+	// From, when set, must match the current status
+	From           string
+	PaymentStatus  string
+	TrackingNumber string
+	CarrierName    string
+	Comment        string
+	ChangedBy      int64
+}
+
+// TransitionContext is passed to guards, effects and hooks. Order holds the
+// order as it was before the transition.
+type TransitionContext struct {
+	Order        *models.Order
+	From         string
+	To           string
+	Request      *TransitionRequest
+	Items        []models.OrderItem
+	Reservations []StockReservation
+	// ReturnRefunds holds the amounts already refunded through returns per
+	// vendor
+	ReturnRefunds map[int64]float64
+	// Refundable is how much of the payment the order shares with its
+	// parent and sibling orders has not been refunded yet
+	Refundable float64
+	// SubOrders holds the per-vendor sub-orders a multi-vendor order
+	// cancelled with them
+	SubOrders []*models.Order
+}
+
+// TransitionGuard may reject a transition before anything is written
+type TransitionGuard func(tc *TransitionContext) error
+
+// TransitionEffect runs inside the transition's database transaction. An
+// error rolls the status change back.
+type TransitionEffect func(tx database.Transaction, tc *TransitionContext) error
+
+// TransitionHook runs after the status change has been committed. Errors
+// are logged and recorded in the order's status history.
+type TransitionHook func(tc *TransitionContext) error
+
+// OrderStateMachineConfig holds the optional collaborators of the state
+// machine's built-in hooks
+type OrderStateMachineConfig struct {
+	PaymentService      *PaymentService
+	NotificationService *NotificationService
+	// Ledger, when set, posts vendor sales and reversals with the status
+	// changes
+	Ledger *VendorLedgerService
+	// Cache, when set, drops the cached reads of changed orders and of the
+	// products they restock or sell
+	Cache  *cache.EntityCache
+	Logger *log.Logger
+}
+
+// OrderStateMachine is the single place order statuses are changed. Every
+// change is validated against the transition table and the registered
+// guards, and written to order_status_history with the status update.
+type OrderStateMachine struct {
+	repo    database.SimpleRepository
+	config  OrderStateMachineConfig
+	logger  *log.Logger
+	mu      sync.RWMutex
+	guards  map[string][]TransitionGuard
+	effects map[string][]TransitionEffect
+	hooks   map[string][]TransitionHook
+}
+
+// NewOrderStateMachine creates an order state machine with the built-in
+// guards, effects and hooks registered
+func NewOrderStateMachine(repo database.SimpleRepository, config OrderStateMachineConfig) (*OrderStateMachine, error) {
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	sm := &OrderStateMachine{
+		repo:    repo,
+		config:  config,
+		logger:  logger,
+		guards:  make(map[string][]TransitionGuard),
+		effects: make(map[string][]TransitionEffect),
+		hooks:   make(map[string][]TransitionHook),
+	}
+
+	sm.AddGuard(models.OrderStatusShipped, requireTrackingNumber)
+	sm.AddGuard(models.OrderStatusRefunded, requirePaidOrder)
+	sm.AddEffect(models.OrderStatusConfirmed, commitReservations)
+	sm.AddEffect(models.OrderStatusCancelled, restockOrder)
+	sm.AddEffect(models.OrderStatusCancelled, sm.queueRefund)
+	sm.AddEffect(models.OrderStatusRefunded, sm.queueRefund)
+	sm.AddHook(models.OrderStatusCancelled, sm.refundPayment)
+	sm.AddHook(models.OrderStatusRefunded, sm.refundPayment)
+	for status := range orderTransitions {
+		sm.AddHook(status, sm.notifyCustomer)
+	}
+	if config.Cache != nil {
+		for status := range orderTransitions {
+			sm.AddHook(status, sm.invalidateCache)
+		}
+	}
+	if config.Ledger != nil {
+		config.Ledger.Register(sm)
+	}
+
+	return sm, nil
+}
+
+// AddGuard registers a guard for transitions into the given status
+func (sm *OrderStateMachine) AddGuard(to string, guard TransitionGuard) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.guards[to] = append(sm.guards[to], guard)
+}
+
+// AddEffect registers an effect for transitions into the given status
+func (sm *OrderStateMachine) AddEffect(to string, effect TransitionEffect) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.effects[to] = append(sm.effects[to], effect)
+}
+
+// AddHook registers a hook for transitions into the given status
+func (sm *OrderStateMachine) AddHook(to string, hook TransitionHook) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.hooks[to] = append(sm.hooks[to], hook)
+}
+
+// CanTransition reports whether the transition table allows from -> to
+func (sm *OrderStateMachine) CanTransition(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTransitions returns the statuses an order in the given status may
+// move to
+func (sm *OrderStateMachine) AllowedTransitions(from string) []string {
+	return append([]string(nil), orderTransitions[from]...)
+}
+
+// transitionPath returns the shortest series of statuses that takes an
+// order from one status to another, excluding from, or nil if the
+// transition table has no such path
+func transitionPath(from, to string) []string {
+	if from == to {
+		return nil
+	}
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		status := queue[0]
+		queue = queue[1:]
+		for _, next := range orderTransitions[status] {
+			if _, seen := previous[next]; seen {
+				continue
+			}
+			previous[next] = status
+			if next == to {
+				var path []string
+				for s := to; s != from; s = previous[s] {
+					path = append([]string{s}, path...)
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// Transition moves an order to req.To. The status update, the history
+// entry and the effects are written in one transaction; hooks run after
+// it has been committed.
+func (sm *OrderStateMachine) Transition(req *TransitionRequest) (*models.Order, error) {
+	order, err := sm.loadOrder(req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &TransitionContext{Order: order, From: order.Status, To: req.To, Request: req}
+	reject := func(cause error, reason string) *TransitionError {
+		return &TransitionError{OrderID: order.ID, From: tc.From, To: tc.To, Reason: reason, Err: cause}
+	}
+
+	if req.From != "" && req.From != order.Status {
+		return nil, reject(ErrConcurrentTransition, fmt.Sprintf("expected status %s", req.From))
+	}
+	if _, ok := orderTransitions[req.To]; !ok {
+		return nil, reject(ErrInvalidTransition, "unknown status")
+	}
+	if !sm.CanTransition(order.Status, req.To) {
+		return nil, reject(ErrInvalidTransition, "")
+	}
+
+	sm.mu.RLock()
+	guards := append([]TransitionGuard(nil), sm.guards[req.To]...)
+	effects := append([]TransitionEffect(nil), sm.effects[req.To]...)
+	hooks := append([]TransitionHook(nil), sm.hooks[req.To]...)
+	sm.mu.RUnlock()
+
+	for _, guard := range guards {
+		if err := guard(tc); err != nil {
+			transitionErr := reject(ErrTransitionRejected, err.Error())
+			transitionErr.Cause = err
+			return nil, transitionErr
+		}
+	}
+
+	// A multi-vendor order is cancelled by cancelling its sub-orders first.
+	// Each of them puts its own stock back and refunds its own share of the
+	// payment.
+	if req.To == models.OrderStatusCancelled && order.ParentOrderID == 0 {
+		if tc.SubOrders, err = sm.cancelSubOrders(order, req); err != nil {
+			return nil, err
+		}
+	}
+
+	// Effects run inside the transaction, which can only execute statements,
+	// so everything they need to read is loaded up front
+	if len(effects) > 0 {
+		if tc.Items, err = sm.loadOrderItems(order.ID); err != nil {
+			return nil, err
+		}
+		if tc.Reservations, err = sm.loadReservations(order.ID); err != nil {
+			return nil, err
+		}
+		if tc.ReturnRefunds, err = sm.loadReturnRefunds(order.ID); err != nil {
+			return nil, err
+		}
+		if tc.Refundable, err = sm.loadRefundable(order); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	sets := []string{"status = ?", "updated_at = ?"}
+	args := []interface{}{req.To, now}
+	if req.PaymentStatus != "" {
+		sets = append(sets, "payment_status = ?")
+		args = append(args, req.PaymentStatus)
+	}
+	switch req.To {
+	case models.OrderStatusShipped:
+		sets = append(sets, "shipped_at = ?")
+		args = append(args, now)
+		if req.TrackingNumber != "" {
+			sets = append(sets, "tracking_number = ?")
+			args = append(args, req.TrackingNumber)
+		}
+		if req.CarrierName != "" {
+			sets = append(sets, "carrier_name = ?")
+			args = append(args, req.CarrierName)
+		}
+	case models.OrderStatusDelivered:
+		sets = append(sets, "delivered_at = ?")
+		args = append(args, now)
+	case models.OrderStatusCancelled:
+		sets = append(sets, "cancelled_at = ?")
+		args = append(args, now)
+	}
+	args = append(args, order.ID, order.Status)
+
+	tx, err := sm.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin order transition: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`UPDATE orders SET `+strings.Join(sets, ", ")+` WHERE id = ? AND status = ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, reject(ErrConcurrentTransition, "")
+	}
+
+	if err := insertStatusHistory(tx, order.ID, order.Status, req.To, req.Comment, req.ChangedBy, now); err != nil {
+		return nil, err
+	}
+
+	for _, effect := range effects {
+		if err := effect(tx, tc); err != nil {
+			transitionErr := reject(ErrTransitionRejected, err.Error())
+			transitionErr.Cause = err
+			return nil, transitionErr
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit order transition: %w", err)
+	}
+	committed = true
+
+	for _, hook := range hooks {
+		if err := hook(tc); err != nil {
+			sm.logger.Printf("Order %d %s hook failed: %v", order.ID, req.To, err)
+			sm.recordNote(order.ID, req.To, "hook failed: "+err.Error())
+		}
+	}
+
+	updated := *order
+	updated.Status = req.To
+	updated.UpdatedAt = now
+	if req.PaymentStatus != "" {
+		updated.PaymentStatus = req.PaymentStatus
+	}
+	if req.TrackingNumber != "" {
+		updated.TrackingNumber = req.TrackingNumber
+	}
+	return &updated, nil
+}
+
+// UpdatePaymentStatus records a payment status reported for an order. A
+// payment received for a pending order confirms it and a failed payment
+// cancels it, through the regular transitions. Other payment status
+// changes leave the order status alone and are noted in its history.
+func (sm *OrderStateMachine) UpdatePaymentStatus(orderID int64, paymentStatus, comment string) (*models.Order, error) {
+	switch paymentStatus {
+	case "pending", "paid", "failed", "refunded", "partial":
+	default:
+		return nil, fmt.Errorf("invalid payment status: %s", paymentStatus)
+	}
+
+	order, err := sm.loadOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentStatus == paymentStatus {
+		return order, nil
+	}
+	if order.Status == models.OrderStatusPending {
+		switch paymentStatus {
+		case "paid":
+			return sm.Transition(&TransitionRequest{OrderID: orderID, From: order.Status, To: models.OrderStatusConfirmed,
+				PaymentStatus: paymentStatus, Comment: comment})
+		case "failed":
+			return sm.Transition(&TransitionRequest{OrderID: orderID, From: order.Status, To: models.OrderStatusCancelled,
+				PaymentStatus: paymentStatus, Comment: comment})
+		}
+	}
+
+	now := time.Now().UTC()
+	tx, err := sm.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin payment status update: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE orders SET payment_status = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND payment_status = ?`,
+		paymentStatus, now, orderID, order.Status, order.PaymentStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payment status: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, &TransitionError{OrderID: orderID, From: order.Status, To: order.Status,
+			Reason: "payment status changed", Err: ErrConcurrentTransition}
+	}
+	note := fmt.Sprintf("payment status %s -> %s", order.PaymentStatus, paymentStatus)
+	if comment != "" {
+		note += ": " + comment
+	}
+	if err := insertStatusHistory(tx, orderID, order.Status, order.Status, note, 0, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payment status update: %w", err)
+	}
+	ordersChanged(sm.config.Cache)
+
+	updated := *order
+	updated.PaymentStatus = paymentStatus
+	updated.UpdatedAt = now
+	return &updated, nil
+}
+
+// History returns the status history of an order, oldest first
+func (sm *OrderStateMachine) History(orderID int64) ([]models.OrderStatusHistory, error) {
+	rows, err := sm.repo.Query(`
+		SELECT id, order_id, status, previous_status, COALESCE(comment, ''), changed_by, created_at
+		FROM order_status_history WHERE order_id = ? ORDER BY id ASC`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusHistory
+	for rows.Next() {
+		var h models.OrderStatusHistory
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.Status, &h.PreviousStatus, &h.Comment, &h.ChangedBy, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order status history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// recordNote adds a history entry that does not change the status
+func (sm *OrderStateMachine) recordNote(orderID int64, status, comment string) {
+	_, err := sm.repo.Exec(`
+		INSERT INTO order_status_history (order_id, status, previous_status, comment, changed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		orderID, status, status, comment, 0, time.Now().UTC())
+	if err != nil {
+		sm.logger.Printf("Failed to record history note for order %d: %v", orderID, err)
+	}
+}
+
+// insertStatusHistory writes a status change to the audit table
+func insertStatusHistory(tx database.Transaction, orderID int64, from, to, comment string, changedBy int64, at time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO order_status_history (order_id, status, previous_status, comment, changed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		orderID, to, from, comment, changedBy, at)
+	if err != nil {
+		return fmt.Errorf("failed to record order status history: %w", err)
+	}
+	return nil
+}
+
+// loadOrder reads the columns of an order the state machine works with
+func (sm *OrderStateMachine) loadOrder(orderID int64) (*models.Order, error) {
+	var o models.Order
+	err := sm.repo.QueryRow(`
+		SELECT id, user_id, COALESCE(vendor_id, 0), parent_order_id, order_number, status, payment_status,
+			COALESCE(payment_method, ''), COALESCE(discount_amount, 0), total_amount, COALESCE(currency, ''),
+			COALESCE(tracking_number, ''), COALESCE(reference_id, ''), source_channel
+		FROM orders WHERE id = ?`, orderID).
+		Scan(&o.ID, &o.UserID, &o.VendorID, &o.ParentOrderID, &o.OrderNumber, &o.Status, &o.PaymentStatus,
+			&o.PaymentMethod, &o.DiscountAmount, &o.TotalAmount, &o.Currency, &o.TrackingNumber, &o.ReferenceID,
+			&o.SourceChannel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %d: %w", orderID, err)
+	}
+	return &o, nil
+}
+
+// loadOrderItems reads the product quantities and vendor amounts of an order
+func (sm *OrderStateMachine) loadOrderItems(orderID int64) ([]models.OrderItem, error) {
+	rows, err := sm.repo.Query(`
+		SELECT id, product_id, vendor_id, quantity, total_price, COALESCE(commission, 0), stock_taken
+		FROM order_items WHERE order_id = ? ORDER BY id ASC`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.OrderItem
+	for rows.Next() {
+		item := models.OrderItem{OrderID: orderID}
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.VendorID, &item.Quantity, &item.TotalPrice, &item.Commission, &item.StockTaken); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// loadReservations reads the stock reservations of an order. Orders that
+// were not placed through the checkout service have none.
+func (sm *OrderStateMachine) loadReservations(orderID int64) ([]StockReservation, error) {
+	rows, err := sm.repo.Query(`
+		SELECT id, order_id, product_id, quantity, status, expires_at, created_at, updated_at
+		FROM stock_reservations WHERE order_id = ? ORDER BY created_at ASC`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var reservations []StockReservation
+	for rows.Next() {
+		var r StockReservation
+		if err := rows.Scan(&r.ID, &r.OrderID, &r.ProductID, &r.Quantity, &r.Status, &r.ExpiresAt, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock reservation: %w", err)
+		}
+		reservations = append(reservations, r)
+	}
+
+	return reservations, nil
+}
+
+// loadReturnRefunds sums the refunds issued through returns of an order per
+// vendor
+func (sm *OrderStateMachine) loadReturnRefunds(orderID int64) (map[int64]float64, error) {
+	rows, err := sm.repo.Query(`
+		SELECT vendor_id, COALESCE(SUM(refund_amount), 0) FROM return_requests
+		WHERE order_id = ? AND status = ? GROUP BY vendor_id`,
+		orderID, models.ReturnStatusRefunded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make(map[int64]float64)
+	for rows.Next() {
+		var vendorID int64
+		var amount float64
+		if err := rows.Scan(&vendorID, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan return refund: %w", err)
+		}
+		refunds[vendorID] = amount
+	}
+
+	return refunds, nil
+}
+
+// loadRefundable returns how much of an order's payment may still be
+// refunded. Sub-orders share the payment of their parent, so the refunds
+// queued or completed and those issued through returns for the parent and
+// any of its sub-orders all count against the parent's total.
+func (sm *OrderStateMachine) loadRefundable(order *models.Order) (float64, error) {
+	paymentOrderID, paid := order.ID, order.TotalAmount
+	if order.ParentOrderID > 0 {
+		paymentOrderID = order.ParentOrderID
+		if err := sm.repo.QueryRow(`SELECT total_amount FROM orders WHERE id = ?`, paymentOrderID).Scan(&paid); err != nil {
+			return 0, fmt.Errorf("failed to get parent order %d: %w", paymentOrderID, err)
+		}
+	}
+
+	var refunded, returned float64
+	err := sm.repo.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM order_refunds
+		WHERE status <> ? AND order_id IN (SELECT id FROM orders WHERE id = ? OR parent_order_id = ?)`,
+		RefundStatusFailed, paymentOrderID, paymentOrderID).Scan(&refunded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queued refunds: %w", err)
+	}
+	err = sm.repo.QueryRow(`
+		SELECT COALESCE(SUM(refund_amount), 0) FROM return_requests
+		WHERE status = ? AND order_id IN (SELECT id FROM orders WHERE id = ? OR parent_order_id = ?)`,
+		models.ReturnStatusRefunded, paymentOrderID, paymentOrderID).Scan(&returned)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get return refunds: %w", err)
+	}
+
+	return roundMoney(paid - refunded - returned), nil
+}
+
+// cancelSubOrders cancels the sub-orders of a multi-vendor order that are
+// not cancelled yet. Nothing is cancelled unless all of them can be.
+func (sm *OrderStateMachine) cancelSubOrders(order *models.Order, req *TransitionRequest) ([]*models.Order, error) {
+	rows, err := sm.repo.Query(`SELECT id FROM orders WHERE parent_order_id = ? ORDER BY id ASC`, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-orders: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan sub-order: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var subOrders []*models.Order
+	for _, id := range ids {
+		sub, err := sm.loadOrder(id)
+		if err != nil {
+			return nil, err
+		}
+		if sub.Status != models.OrderStatusCancelled && !sm.CanTransition(sub.Status, models.OrderStatusCancelled) {
+			return nil, &TransitionError{
+				OrderID: order.ID,
+				From:    order.Status,
+				To:      req.To,
+				Reason:  fmt.Sprintf("sub-order %d is %s", sub.ID, sub.Status),
+				Err:     ErrTransitionRejected,
+			}
+		}
+		subOrders = append(subOrders, sub)
+	}
+
+	for _, sub := range subOrders {
+		if sub.Status == models.OrderStatusCancelled {
+			continue
+		}
+		if _, err := sm.Transition(&TransitionRequest{
+			OrderID:       sub.ID,
+			From:          sub.Status,
+			To:            models.OrderStatusCancelled,
+			PaymentStatus: req.PaymentStatus,
+			Comment:       req.Comment,
+			ChangedBy:     req.ChangedBy,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return subOrders, nil
+}
+
+// requireTrackingNumber rejects shipping an order without a tracking number
+func requireTrackingNumber(tc *TransitionContext) error {
+	if tc.Request.TrackingNumber == "" && tc.Order.TrackingNumber == "" {
+		return errors.New("tracking number is required")
+	}
+	return nil
+}
+
+// requirePaidOrder rejects refunding an order that was never paid. Orders
+// partially refunded through returns can still be refunded.
+func requirePaidOrder(tc *TransitionContext) error {
+	if tc.Order.PaymentStatus != "paid" && tc.Order.PaymentStatus != "partial" {
+		return fmt.Errorf("payment status is %s", tc.Order.PaymentStatus)
+	}
+	return nil
+}
+
+// commitReservations turns the order's stock reservations into sales. A
+// reservation that has been released in the meantime fails the transition
+// with ErrReservationExpired.
+func commitReservations(tx database.Transaction, tc *TransitionContext) error {
+	now := time.Now().UTC()
+	for _, r := range tc.Reservations {
+		if r.Status == ReservationStatusCommitted {
+			continue
+		}
+		if r.Status != ReservationStatusReserved {
+			return ErrReservationExpired
+		}
+		result, err := tx.Exec(`
+			UPDATE stock_reservations SET status = ?, updated_at = ?
+			WHERE id = ? AND status = ?`,
+			ReservationStatusCommitted, now, r.ID, ReservationStatusReserved)
+		if err != nil {
+			return fmt.Errorf("failed to commit stock reservation: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return ErrReservationExpired
+		}
+		if _, err := tx.Exec(`UPDATE products SET sales_count = sales_count + ?, updated_at = ? WHERE id = ?`,
+			r.Quantity, now, r.ProductID); err != nil {
+			return fmt.Errorf("failed to update product sales: %w", err)
+		}
+	}
+	return nil
+}
+
+// restockOrder puts the stock of a cancelled order back. Only units the
+// order is known to hold are returned: those of its reservations and those
+// its items record as taken from stock. Orders that never took stock, such
+// as orders created by hand, leave the stock alone.
+func restockOrder(tx database.Transaction, tc *TransitionContext) error {
+	now := time.Now().UTC()
+
+	for _, item := range tc.Items {
+		if item.StockTaken <= 0 {
+			continue
+		}
+		result, err := tx.Exec(`UPDATE order_items SET stock_taken = 0 WHERE id = ? AND stock_taken = ?`,
+			item.ID, item.StockTaken)
+		if err != nil {
+			return fmt.Errorf("failed to release stock of order item %d: %w", item.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		if err := restockProduct(tx, item.ProductID, item.StockTaken, 0, now); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range tc.Reservations {
+		if r.Status == ReservationStatusReleased {
+			continue
+		}
+		// Only the caller that flips the reservation gives the stock back,
+		// so a concurrent release cannot double count it
+		result, err := tx.Exec(`
+			UPDATE stock_reservations SET status = ?, updated_at = ?
+			WHERE id = ? AND status = ?`,
+			ReservationStatusReleased, now, r.ID, r.Status)
+		if err != nil {
+			return fmt.Errorf("failed to release stock reservation: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		sold := 0
+		if r.Status == ReservationStatusCommitted {
+			sold = r.Quantity
+		}
+		if err := restockProduct(tx, r.ProductID, r.Quantity, sold, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restockProduct adds units back to a product and takes back sold units
+func restockProduct(tx database.Transaction, productID int64, quantity, sold int, now time.Time) error {
+	_, err := tx.Exec(`
+		UPDATE products SET
+			status = CASE WHEN status = ? THEN ? ELSE status END,
+			stock = stock + ?,
+			sales_count = CASE WHEN sales_count >= ? THEN sales_count - ? ELSE 0 END,
+			updated_at = ?
+		WHERE id = ?`,
+		ProductStatusOutOfStock, ProductStatusActive, quantity, sold, sold, now, productID)
+	if err != nil {
+		return fmt.Errorf("failed to restock product %d: %w", productID, err)
+	}
+	return nil
+}
+
+// queueRefund records the refund of a paid order that is cancelled or
+// refunded in the transition's transaction, so the refund survives a
+// gateway outage or a crash before the refundPayment hook runs. Amounts
+// already refunded through returns are not refunded again, and no order
+// refunds more of its payment than its parent and siblings left. A
+// cancelled multi-vendor order leaves the refunds to its sub-orders.
+func (sm *OrderStateMachine) queueRefund(tx database.Transaction, tc *TransitionContext) error {
+	if sm.config.PaymentService == nil {
+		return nil
+	}
+	// Marketplaces collect and refund the payments of their orders
+	if tc.Order.SourceChannel != models.OrderChannelWeb {
+		return nil
+	}
+	if tc.Order.PaymentStatus != "paid" && tc.Order.PaymentStatus != "partial" {
+		return nil
+	}
+	if len(tc.SubOrders) > 0 {
+		return nil
+	}
+
+	returned := 0.0
+	for _, amount := range tc.ReturnRefunds {
+		returned += amount
+	}
+	amount := roundMoney(math.Min(tc.Order.TotalAmount-returned, tc.Refundable))
+	now := time.Now().UTC()
+
+	if amount <= 0 {
+		if _, err := tx.Exec(`UPDATE orders SET payment_status = ? WHERE id = ?`, "refunded", tc.Order.ID); err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+		return nil
+	}
+
+	reason := tc.Request.Comment
+	if reason == "" {
+		reason = "order " + tc.To
+	}
+	_, err := tx.Exec(`
+		INSERT INTO order_refunds (order_id, transaction_id, amount, currency, reason, status, attempts,
+			next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tc.Order.ID, tc.Order.ReferenceID, amount, tc.Order.Currency, reason, RefundStatusPending, 0, now, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to queue refund: %w", err)
+	}
+	return nil
+}
+
+// refundPayment makes the first attempt at the refund queueRefund recorded.
+// A failed attempt stays queued for RetryRefunds.
+func (sm *OrderStateMachine) refundPayment(tc *TransitionContext) error {
+	refund, err := sm.loadRefund(`WHERE order_id = ? AND status = ?`, tc.Order.ID, RefundStatusPending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return sm.attemptRefund(refund)
+}
+
+// RetryRefunds retries the queued refunds whose next attempt is due,
+// including those whose previous attempt never reported back. It returns
+// the number of refunds completed.
+func (sm *OrderStateMachine) RetryRefunds() (int, error) {
+	rows, err := sm.repo.Query(`
+		SELECT id FROM order_refunds
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY id ASC LIMIT 100`,
+		RefundStatusPending, RefundStatusProcessing, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due refunds: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan due refund: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	completed := 0
+	for _, id := range ids {
+		refund, err := sm.loadRefund(`WHERE id = ?`, id)
+		if err != nil {
+			return completed, err
+		}
+		if err := sm.attemptRefund(refund); err != nil {
+			continue
+		}
+		if refund.status == RefundStatusCompleted {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// orderRefund is a queued payment refund of an order
+type orderRefund struct {
+	id            int64
+	orderID       int64
+	transactionID string
+	amount        float64
+	currency      string
+	reason        string
+	status        string
+	attempts      int
+}
+
+// Order refund statuses
+const (
+	RefundStatusPending    = "pending"
+	RefundStatusProcessing = "processing"
+	RefundStatusCompleted  = "completed"
+	RefundStatusFailed     = "failed"
+)
+
+const (
+	// refundMaxAttempts is how often a refund is tried before it is left
+	// failed for a person to settle
+	refundMaxAttempts = 8
+	// refundRetryBackoff is the delay after the first failed attempt; it
+	// doubles with every further attempt, up to an hour
+	refundRetryBackoff = time.Minute
+	// refundProcessingTimeout is how long an attempt may take before the
+	// refund is tried again
+	refundProcessingTimeout = 5 * time.Minute
+)
+
+// loadRefund reads one queued refund
+func (sm *OrderStateMachine) loadRefund(where string, args ...interface{}) (*orderRefund, error) {
+	var r orderRefund
+	err := sm.repo.QueryRow(`
+		SELECT id, order_id, transaction_id, amount, currency, COALESCE(reason, ''), status, attempts
+		FROM order_refunds `+where, args...).
+		Scan(&r.id, &r.orderID, &r.transactionID, &r.amount, &r.currency, &r.reason, &r.status, &r.attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return &r, nil
+}
+
+// attemptRefund claims a queued refund and sends it to the payment service.
+// The claim is conditional on the attempt count, so two workers never send
+// the same refund at once.
+func (sm *OrderStateMachine) attemptRefund(r *orderRefund) error {
+	now := time.Now().UTC()
+	result, err := sm.repo.Exec(`
+		UPDATE order_refunds SET status = ?, attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND attempts = ?`,
+		RefundStatusProcessing, now.Add(refundProcessingTimeout), now, r.id, r.status, r.attempts)
+	if err != nil {
+		return fmt.Errorf("failed to claim refund %d: %w", r.id, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil
+	}
+	r.attempts++
+
+	if sm.config.PaymentService == nil {
+		return sm.refundFailed(r, errors.New("no payment service configured"))
+	}
+	if _, err := sm.config.PaymentService.RefundPayment(r.transactionID, r.amount, r.reason); err != nil {
+		return sm.refundFailed(r, err)
+	}
+
+	now = time.Now().UTC()
+	r.status = RefundStatusCompleted
+	if _, err := sm.repo.Exec(`
+		UPDATE order_refunds SET status = ?, last_error = ?, updated_at = ?, completed_at = ? WHERE id = ?`,
+		r.status, "", now, now, r.id); err != nil {
+		return fmt.Errorf("failed to complete refund %d: %w", r.id, err)
+	}
+	if _, err := sm.repo.Exec(`UPDATE orders SET payment_status = ?, updated_at = ? WHERE id = ?`,
+		"refunded", now, r.orderID); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	if err := sm.markParentRefunded(r.orderID, now); err != nil {
+		return err
+	}
+
+	var status string
+	if err := sm.repo.QueryRow(`SELECT status FROM orders WHERE id = ?`, r.orderID).Scan(&status); err == nil {
+		sm.recordNote(r.orderID, status, fmt.Sprintf("payment of %.2f %s refunded", r.amount, r.currency))
+	}
+	ordersChanged(sm.config.Cache)
+	return nil
+}
+
+// markParentRefunded marks the payment of a multi-vendor order refunded
+// once the refunds of all its sub-orders went through
+func (sm *OrderStateMachine) markParentRefunded(orderID int64, now time.Time) error {
+	var parentID int64
+	if err := sm.repo.QueryRow(`SELECT parent_order_id FROM orders WHERE id = ?`, orderID).Scan(&parentID); err != nil {
+		return fmt.Errorf("failed to get order %d: %w", orderID, err)
+	}
+	if parentID == 0 {
+		return nil
+	}
+	var open int
+	if err := sm.repo.QueryRow(`SELECT COUNT(*) FROM orders WHERE parent_order_id = ? AND payment_status <> ?`,
+		parentID, "refunded").Scan(&open); err != nil {
+		return fmt.Errorf("failed to get sub-order payments: %w", err)
+	}
+	if open > 0 {
+		return nil
+	}
+	if _, err := sm.repo.Exec(`UPDATE orders SET payment_status = ?, updated_at = ? WHERE id = ?`,
+		"refunded", now, parentID); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	return nil
+}
+
+// refundFailed records a failed refund attempt and schedules the next one,
+// or gives up once the attempts are used up
+func (sm *OrderStateMachine) refundFailed(r *orderRefund, cause error) error {
+	now := time.Now().UTC()
+	r.status = RefundStatusPending
+	next := now.Add(refundRetryDelay(r.attempts))
+	if r.attempts >= refundMaxAttempts {
+		r.status = RefundStatusFailed
+		sm.logger.Printf("Giving up refund of order %d after %d attempts: %v", r.orderID, r.attempts, cause)
+	}
+	if _, err := sm.repo.Exec(`
+		UPDATE order_refunds SET status = ?, last_error = ?, next_attempt_at = ?, updated_at = ? WHERE id = ?`,
+		r.status, cause.Error(), next, now, r.id); err != nil {
+		return fmt.Errorf("failed to record refund attempt %d: %w", r.id, err)
+	}
+	return fmt.Errorf("failed to refund payment: %w", cause)
+}
+
+// refundRetryDelay is the backoff after a failed refund attempt
+func refundRetryDelay(attempts int) time.Duration {
+	delay := float64(refundRetryBackoff) * math.Pow(2, float64(attempts-1))
+	if delay > float64(time.Hour) {
+		return time.Hour
+	}
+	return time.Duration(delay)
+}
+
+// invalidateCache drops the cached reads of the order and, for transitions
+// whose effects moved stock, of its products
+func (sm *OrderStateMachine) invalidateCache(tc *TransitionContext) error {
+	ordersChanged(sm.config.Cache)
+	if len(tc.Items) > 0 || len(tc.Reservations) > 0 {
+		productIDs := orderProductIDs(tc.Items)
+		for _, r := range tc.Reservations {
+			productIDs = append(productIDs, r.ProductID)
+		}
+		productsChanged(sm.config.Cache, productIDs...)
+	}
+	return nil
+}
+
+// notifyCustomer tells the customer about the new order status
+func (sm *OrderStateMachine) notifyCustomer(tc *TransitionContext) error {
+	if sm.config.NotificationService == nil {
+		return nil
+	}
+	// Marketplace orders are placed under their channel's user, not the
+	// customer's; the marketplace notifies the customer itself
+	if tc.Order.SourceChannel != "" && tc.Order.SourceChannel != models.OrderChannelWeb {
+		return nil
+	}
+	return sm.config.NotificationService.SendOrderStatusNotification(uint(tc.Order.ID), uint(tc.Order.UserID), tc.To)
+}
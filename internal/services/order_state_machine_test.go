@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// seedOrder inserts a web order in the given status with one item per
+// product; stockTaken is recorded on every item
+func seedOrder(t *testing.T, repo database.SimpleRepository, userID int64, status, paymentStatus string, stockTaken int, productIDs ...int64) int64 {
+	t.Helper()
+	now := time.Now().UTC()
+	var count int
+	if err := repo.QueryRow(`SELECT COUNT(*) FROM orders`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	orderID := mustExec(t, repo, `
+		INSERT INTO orders (user_id, order_number, status, payment_status, subtotal, total_amount, currency, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'TRY', ?, ?)`,
+		userID, "ORD-TEST-"+string(rune('A'+count)), status, paymentStatus, 100, 100, now, now)
+	for _, productID := range productIDs {
+		var vendorID int64
+		if err := repo.QueryRow(`SELECT vendor_id FROM products WHERE id = ?`, productID).Scan(&vendorID); err != nil {
+			t.Fatal(err)
+		}
+		mustExec(t, repo, `
+			INSERT INTO order_items (order_id, product_id, vendor_id, product_name, product_sku, quantity, unit_price, total_price, stock_taken)
+			VALUES (?, ?, ?, 'Ürün', 'SKU', 2, 50, 100, ?)`,
+			orderID, productID, vendorID, stockTaken)
+	}
+	return orderID
+}
+
+func newTestStateMachine(t *testing.T, repo database.SimpleRepository, paymentService *PaymentService) *OrderStateMachine {
+	t.Helper()
+	sm, err := NewOrderStateMachine(repo, OrderStateMachineConfig{PaymentService: paymentService, Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sm
+}
+
+func TestOrderStateMachineTransitions(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	sm := newTestStateMachine(t, repo, nil)
+	userID := seedUser(t, repo)
+
+	tests := []struct {
+		name    string
+		from    string
+		req     TransitionRequest
+		wantErr error
+	}{
+		{"confirm", models.OrderStatusPending, TransitionRequest{To: models.OrderStatusConfirmed}, nil},
+		{"skip ahead", models.OrderStatusPending, TransitionRequest{To: models.OrderStatusDelivered}, ErrInvalidTransition},
+		{"unknown status", models.OrderStatusPending, TransitionRequest{To: "lost"}, ErrInvalidTransition},
+		{"ship without tracking", models.OrderStatusConfirmed, TransitionRequest{To: models.OrderStatusShipped}, ErrTransitionRejected},
+		{"ship", models.OrderStatusConfirmed, TransitionRequest{To: models.OrderStatusShipped, TrackingNumber: "TR123"}, nil},
+		{"stale from", models.OrderStatusConfirmed, TransitionRequest{From: models.OrderStatusPending, To: models.OrderStatusCancelled}, ErrConcurrentTransition},
+		{"refund unpaid", models.OrderStatusDelivered, TransitionRequest{To: models.OrderStatusRefunded}, ErrTransitionRejected},
+		{"leave cancelled", models.OrderStatusCancelled, TransitionRequest{To: models.OrderStatusPending}, ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderID := seedOrder(t, repo, userID, tt.from, "pending", 0)
+			req := tt.req
+			req.OrderID = orderID
+			_, err := sm.Transition(&req)
+			if tt.wantErr != nil {
+				var transitionErr *TransitionError
+				if !errors.Is(err, tt.wantErr) || !errors.As(err, &transitionErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				if status, _ := orderStatus(t, repo, orderID); status != tt.from {
+					t.Fatalf("rejected transition changed the status to %s", status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			history, err := sm.History(orderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 1 || history[0].PreviousStatus != tt.from || history[0].Status != tt.req.To {
+				t.Fatalf("history = %+v", history)
+			}
+		})
+	}
+}
+
+func TestCancelRestocksOnlyTakenStock(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	sm := newTestStateMachine(t, repo, nil)
+	userID := seedUser(t, repo)
+	vendorID := seedVendor(t, repo, 0)
+	taken := seedProduct(t, repo, vendorID, 50, 3)
+	untouched := seedProduct(t, repo, vendorID, 50, 3)
+
+	// An imported order that took two units and an order created by hand
+	// that never took any
+	importedID := seedOrder(t, repo, userID, models.OrderStatusConfirmed, "paid", 2, taken)
+	manualID := seedOrder(t, repo, userID, models.OrderStatusConfirmed, "paid", 0, untouched)
+
+	for _, orderID := range []int64{importedID, manualID} {
+		if _, err := sm.Transition(&TransitionRequest{OrderID: orderID, To: models.OrderStatusCancelled}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stock := productStock(t, repo, taken); stock != 5 {
+		t.Fatalf("stock = %d, want the 2 taken units back on top of 3", stock)
+	}
+	if stock := productStock(t, repo, untouched); stock != 3 {
+		t.Fatalf("stock = %d, want 3: the order never took any", stock)
+	}
+
+	var remaining int
+	if err := repo.QueryRow(`SELECT stock_taken FROM order_items WHERE order_id = ?`, importedID).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Fatalf("stock_taken = %d after the restock, want 0", remaining)
+	}
+}
+
+func TestUpdatePaymentStatus(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	orders := NewOrderService(repo)
+	sm := newTestStateMachine(t, repo, nil)
+	orders.SetStateMachine(sm)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 50, 10)
+
+	paidID := seedOrder(t, repo, userID, models.OrderStatusPending, "pending", 0, productID)
+	if err := orders.UpdatePaymentStatus(int(paidID), "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if status, paymentStatus := orderStatus(t, repo, paidID); status != models.OrderStatusConfirmed || paymentStatus != "paid" {
+		t.Fatalf("paid order is %s/%s, want confirmed/paid", status, paymentStatus)
+	}
+
+	failedID := seedOrder(t, repo, userID, models.OrderStatusPending, "pending", 2, productID)
+	if err := orders.UpdatePaymentStatus(int(failedID), "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if status, paymentStatus := orderStatus(t, repo, failedID); status != models.OrderStatusCancelled || paymentStatus != "failed" {
+		t.Fatalf("failed order is %s/%s, want cancelled/failed", status, paymentStatus)
+	}
+	if stock := productStock(t, repo, productID); stock != 12 {
+		t.Fatalf("stock = %d, want the failed order's 2 units back", stock)
+	}
+
+	// A payment status change without a status change is noted in the
+	// history
+	if err := orders.UpdatePaymentStatus(int(paidID), "partial"); err != nil {
+		t.Fatal(err)
+	}
+	history, err := sm.History(paidID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := history[len(history)-1]
+	if last.Status != models.OrderStatusConfirmed || !strings.Contains(last.Comment, "paid -> partial") {
+		t.Fatalf("last history entry = %+v", last)
+	}
+
+	if err := orders.UpdatePaymentStatus(int(paidID), "settled"); err == nil {
+		t.Fatal("unknown payment status was accepted")
+	}
+}
+
+func TestUpdateOrderGoesThroughStateMachine(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	orders := NewOrderService(repo)
+	orders.SetStateMachine(newTestStateMachine(t, repo, nil))
+	userID := seedUser(t, repo)
+	orderID := seedOrder(t, repo, userID, models.OrderStatusPending, "pending", 0)
+
+	err := orders.UpdateOrder(int(orderID), &models.Order{Status: models.OrderStatusDelivered, Notes: "kapıya bırakıldı"})
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("got %v, want ErrInvalidTransition", err)
+	}
+
+	if err := orders.UpdateOrder(int(orderID), &models.Order{Status: models.OrderStatusConfirmed, Notes: "hediye paketi"}); err != nil {
+		t.Fatal(err)
+	}
+	var status, notes string
+	var subtotal float64
+	if err := repo.QueryRow(`SELECT status, notes, subtotal FROM orders WHERE id = ?`, orderID).Scan(&status, &notes, &subtotal); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.OrderStatusConfirmed || notes != "hediye paketi" || subtotal != 100 {
+		t.Fatalf("order is %s with notes %q and subtotal %.2f", status, notes, subtotal)
+	}
+}
+
+func TestTransitionNotifiesCustomer(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	notifications := NewNotificationService(nil, testutil.NewDB(t), nil)
+	sm, err := NewOrderStateMachine(repo, OrderStateMachineConfig{NotificationService: notifications, Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := seedUser(t, repo)
+	// Only in-app, as there is no email service
+	mustExec(t, repo, `INSERT INTO user_notification_preferences (user_id, notification_type, channel, enabled) VALUES (?, ?, ?, 1)`,
+		userID, models.NotificationTypeTransactional, models.NotificationChannelInApp)
+	orderID := seedOrder(t, repo, userID, models.OrderStatusPending, "pending", 0)
+
+	if _, err := sm.Transition(&TransitionRequest{OrderID: orderID, To: models.OrderStatusConfirmed}); err != nil {
+		t.Fatal(err)
+	}
+	unread, err := notifications.GetUnreadNotifications(uint(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || !strings.Contains(unread[0].Message, models.OrderStatusConfirmed) {
+		t.Fatalf("unread = %+v, want the status update", unread)
+	}
+}
+
+func TestRefundIsRetriedAfterGatewayFailure(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := s.PayOrder(result.Order, testCard(payment.SandboxCardApproved))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The payment cannot be refunded while its gateway is unavailable
+	mustExec(t, repo, `UPDATE payments SET provider = 'offline' WHERE transaction_id = ?`, response.TransactionID)
+	if _, err := s.stateMachine.Transition(&TransitionRequest{OrderID: result.Order.ID, To: models.OrderStatusCancelled, Comment: "customer request"}); err != nil {
+		t.Fatal(err)
+	}
+	var status, lastError string
+	var attempts int
+	if err := repo.QueryRow(`SELECT status, attempts, COALESCE(last_error, '') FROM order_refunds WHERE order_id = ?`, result.Order.ID).
+		Scan(&status, &attempts, &lastError); err != nil {
+		t.Fatalf("failed refund was not kept: %v", err)
+	}
+	if status != RefundStatusPending || attempts != 1 || lastError == "" {
+		t.Fatalf("refund is %s after %d attempts (%q)", status, attempts, lastError)
+	}
+	if _, paymentStatus := orderStatus(t, repo, result.Order.ID); paymentStatus != "paid" {
+		t.Fatalf("payment status = %s before the refund went through", paymentStatus)
+	}
+
+	// Nothing is retried before the backoff runs out
+	if refunded, err := s.stateMachine.RetryRefunds(); err != nil || refunded != 0 {
+		t.Fatalf("refunded %d early (err %v)", refunded, err)
+	}
+
+	mustExec(t, repo, `UPDATE payments SET provider = ? WHERE transaction_id = ?`, response.Gateway, response.TransactionID)
+	mustExec(t, repo, `UPDATE order_refunds SET next_attempt_at = ? WHERE order_id = ?`, time.Now().UTC().Add(-time.Second), result.Order.ID)
+	refunded, err := s.stateMachine.RetryRefunds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded != 1 {
+		t.Fatalf("refunded %d orders, want 1", refunded)
+	}
+	if _, paymentStatus := orderStatus(t, repo, result.Order.ID); paymentStatus != "refunded" {
+		t.Fatalf("payment status = %s, want refunded", paymentStatus)
+	}
+	record, err := s.paymentService.GetPaymentStatus(response.TransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RefundedAmount != response.Amount {
+		t.Fatalf("refunded %.2f of %.2f", record.RefundedAmount, response.Amount)
+	}
+
+	// A completed refund is not sent again
+	mustExec(t, repo, `UPDATE order_refunds SET next_attempt_at = ? WHERE order_id = ?`, time.Now().UTC().Add(-time.Second), result.Order.ID)
+	if refunded, err := s.stateMachine.RetryRefunds(); err != nil || refunded != 0 {
+		t.Fatalf("completed refund was retried: %d (err %v)", refunded, err)
+	}
+}
+
+func TestCancelMultiVendorOrderRefundsSubOrders(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	first := seedProduct(t, repo, seedVendor(t, repo, 10), 30, 5)
+	second := seedProduct(t, repo, seedVendor(t, repo, 20), 70, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(first, 1), line(second, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := result.Order
+	response, err := s.PayOrder(order, testCard(payment.SandboxCardApproved))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.stateMachine.Transition(&TransitionRequest{OrderID: order.ID, To: models.OrderStatusCancelled, Comment: "customer request"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range order.SubOrders {
+		if status, paymentStatus := orderStatus(t, repo, sub.ID); status != models.OrderStatusCancelled || paymentStatus != "refunded" {
+			t.Fatalf("sub-order %d is %s/%s, want cancelled and refunded", sub.ID, status, paymentStatus)
+		}
+	}
+	if _, paymentStatus := orderStatus(t, repo, order.ID); paymentStatus != "refunded" {
+		t.Fatalf("parent payment status = %s, want refunded", paymentStatus)
+	}
+	if stock := productStock(t, repo, first); stock != 5 {
+		t.Fatalf("stock = %d, want 5 once the cancelled sub-order put its unit back", stock)
+	}
+	if stock := productStock(t, repo, second); stock != 5 {
+		t.Fatalf("stock = %d, want 5 once the cancelled sub-order put its units back", stock)
+	}
+
+	// The payment is refunded once, in the sub-orders' shares
+	var refunds int
+	var refunded float64
+	if err := repo.QueryRow(`SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM order_refunds`).Scan(&refunds, &refunded); err != nil {
+		t.Fatal(err)
+	}
+	if refunds != 2 || refunded != order.TotalAmount {
+		t.Fatalf("%d refunds of %.2f, want 2 adding up to %.2f", refunds, refunded, order.TotalAmount)
+	}
+	record, err := s.paymentService.GetPaymentStatus(response.TransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RefundedAmount != response.Amount {
+		t.Fatalf("refunded %.2f of %.2f", record.RefundedAmount, response.Amount)
+	}
+}
+
+func TestCancelMultiVendorOrderNeedsCancellableSubOrders(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	first := seedProduct(t, repo, seedVendor(t, repo, 10), 30, 5)
+	second := seedProduct(t, repo, seedVendor(t, repo, 20), 70, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(first, 1), line(second, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := result.Order
+	if _, err := s.PayOrder(order, testCard(payment.SandboxCardApproved)); err != nil {
+		t.Fatal(err)
+	}
+	shipped := order.SubOrders[0].ID
+	if _, err := s.stateMachine.Transition(&TransitionRequest{OrderID: shipped, To: models.OrderStatusShipped, TrackingNumber: "TRK1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.stateMachine.Transition(&TransitionRequest{OrderID: order.ID, To: models.OrderStatusCancelled})
+	if !errors.Is(err, ErrTransitionRejected) {
+		t.Fatalf("err = %v, want ErrTransitionRejected", err)
+	}
+	if status, _ := orderStatus(t, repo, order.SubOrders[1].ID); status != models.OrderStatusConfirmed {
+		t.Fatalf("other sub-order is %s, want it left confirmed", status)
+	}
+	var refunds int
+	if err := repo.QueryRow(`SELECT COUNT(*) FROM order_refunds`).Scan(&refunds); err != nil {
+		t.Fatal(err)
+	}
+	if refunds != 0 {
+		t.Fatalf("%d refunds queued for a rejected cancel", refunds)
+	}
+}
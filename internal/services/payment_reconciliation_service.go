@@ -0,0 +1,639 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+	"kolajAi/internal/reporting"
+)
+
+// Reconciliation errors
+var (
+	ErrReconciliationNotFound = errors.New("payment reconciliation not found")
+	ErrNoTransactionSource    = errors.New("no gateway to list transactions from")
+	ErrInvalidPeriod          = errors.New("reconciliation period end must be after its start")
+)
+
+// PaymentReconciliationReportID is the ID of the report listing the
+// discrepancies of a reconciliation run
+const PaymentReconciliationReportID = "payment_reconciliation"
+
+// PaymentReconciliationConfig holds reconciliation settings and collaborators
+type PaymentReconciliationConfig struct {
+	// Gateway lists the transactions our payments are reconciled against
+	Gateway payment.TransactionLister
+	// ReportManager, when set, gets the reconciliation report registered
+	ReportManager *reporting.ReportManager
+	// AmountTolerance is the largest amount difference that still counts as
+	// a match. It defaults to 0.01.
+	AmountTolerance float64
+	// Timeout bounds the gateway call. It defaults to two minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// PaymentReconciliationService compares the transactions a payment gateway
+// reports with our payment and order records and keeps the discrepancies
+// it finds for finance to review
+type PaymentReconciliationService struct {
+	repo   database.SimpleRepository
+	config PaymentReconciliationConfig
+	logger *log.Logger
+}
+
+// localPayment is a payment record together with its order
+type localPayment struct {
+	transactionID  string
+	orderID        int64
+	status         PaymentStatus
+	amount         float64
+	refundedAmount float64
+	currency       string
+	orderFound     bool
+	orderTotal     float64
+	orderCurrency  string
+	orderReference string
+	// inPeriod is set for payments made in the reconciled period. Others
+	// are loaded only because the period holds one of their transactions.
+	inPeriod bool
+}
+
+// NewPaymentReconciliationService creates a new reconciliation service
+func NewPaymentReconciliationService(repo database.SimpleRepository, config PaymentReconciliationConfig) (*PaymentReconciliationService, error) {
+	if config.AmountTolerance <= 0 {
+		config.AmountTolerance = 0.01
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	s := &PaymentReconciliationService{repo: repo, config: config, logger: logger}
+	if config.ReportManager != nil {
+		if err := config.ReportManager.EnsureReport(reconciliationReportConfig()); err != nil {
+			return nil, fmt.Errorf("failed to register reconciliation report: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// reconciliationReportConfig is the report finance reads the discrepancies
+// of a run from
+func reconciliationReportConfig() *reporting.ReportConfig {
+	return &reporting.ReportConfig{
+		ID:          PaymentReconciliationReportID,
+		Name:        "Payment reconciliation",
+		Description: "Gateway transactions that do not match our payment and order records",
+		Category:    "finance",
+		DataSources: []reporting.DataSource{{
+			Name:   "discrepancies",
+			Type:   "table",
+			Source: "payment_reconciliation_items",
+		}},
+		Filters: []reporting.FilterConfig{
+			{ID: "reconciliation_id", Name: "Reconciliation", Type: "number", Field: "reconciliation_id", Operator: "=", Required: true},
+			{ID: "issue", Name: "Issue", Type: "select", Field: "issue", Operator: "=", Options: []reporting.Option{
+				{Value: models.ReconciliationMissingLocal, Label: "Missing locally"},
+				{Value: models.ReconciliationMissingGateway, Label: "Missing at gateway"},
+				{Value: models.ReconciliationDuplicate, Label: "Duplicate"},
+				{Value: models.ReconciliationAmountMismatch, Label: "Amount mismatch"},
+				{Value: models.ReconciliationCurrencyMismatch, Label: "Currency mismatch"},
+				{Value: models.ReconciliationReferenceMismatch, Label: "Reference mismatch"},
+				{Value: models.ReconciliationStatusMismatch, Label: "Status mismatch"},
+				{Value: models.ReconciliationRefundMismatch, Label: "Refund mismatch"},
+			}},
+		},
+		Sorting: []reporting.SortConfig{{Field: "issue", Order: "ASC"}, {Field: "id", Order: "ASC"}},
+		Columns: []reporting.ColumnConfig{
+			{ID: "issue", Name: "Issue", Field: "issue", Type: "text", Sortable: true, Filterable: true, Visible: true},
+			{ID: "transaction_id", Name: "Transaction", Field: "transaction_id", Type: "text", Visible: true},
+			{ID: "order_id", Name: "Order", Field: "order_id", Type: "number", Visible: true},
+			{ID: "gateway_amount", Name: "Gateway amount", Field: "gateway_amount", Type: "number", Sortable: true, Visible: true},
+			{ID: "local_amount", Name: "Local amount", Field: "local_amount", Type: "number", Sortable: true, Visible: true},
+			{ID: "currency", Name: "Currency", Field: "currency", Type: "text", Visible: true},
+			{ID: "details", Name: "Details", Field: "details", Type: "text", Visible: true},
+		},
+		Permissions: []string{"admin", "finance"},
+	}
+}
+
+// Reconcile pulls the gateway's transactions for the period and matches
+// them against our payment records and their orders by reference, amount
+// and currency. The run and every discrepancy it finds are stored.
+func (s *PaymentReconciliationService) Reconcile(periodStart, periodEnd time.Time) (*models.PaymentReconciliation, error) {
+	if s.config.Gateway == nil {
+		return nil, ErrNoTransactionSource
+	}
+	if !periodEnd.After(periodStart) {
+		return nil, ErrInvalidPeriod
+	}
+	gatewayName := s.config.Gateway.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	transactions, err := s.config.Gateway.ListTransactions(ctx, payment.TransactionFilters{
+		StartDate: periodStart,
+		EndDate:   periodEnd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gateway transactions: %w", err)
+	}
+
+	locals, err := s.loadLocalPayments(gatewayName, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.PaymentReconciliation{
+		Gateway:     gatewayName,
+		PeriodStart: periodStart.UTC(),
+		PeriodEnd:   periodEnd.UTC(),
+		LocalCount:  len(locals),
+		CreatedAt:   time.Now().UTC(),
+	}
+	for _, local := range locals {
+		if settledPaymentStatus(local.status) {
+			run.LocalTotal += local.amount
+		}
+	}
+
+	// Transactions of payments made just outside the period are looked up
+	// on their own so they are not reported as missing
+	for _, transaction := range transactions {
+		id := transaction.ID
+		if transaction.Type != payment.TransactionTypePayment {
+			id = transaction.PaymentID
+		}
+		if _, ok := locals[id]; ok || id == "" {
+			continue
+		}
+		local, err := s.loadLocalPayment(id)
+		if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+			return nil, err
+		}
+		if local != nil {
+			locals[id] = local
+		}
+	}
+
+	refunds, err := s.loadPeriodRefunds(gatewayName, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	for id := range refunds {
+		if _, ok := locals[id]; ok {
+			continue
+		}
+		local, err := s.loadLocalPayment(id)
+		if err != nil {
+			return nil, err
+		}
+		locals[id] = local
+	}
+
+	s.match(run, transactions, locals, refunds)
+	run.LocalTotal = roundMoney(run.LocalTotal)
+	run.GatewayTotal = roundMoney(run.GatewayTotal)
+	run.DiscrepancyCount = len(run.Items)
+	run.Status = models.ReconciliationStatusBalanced
+	if run.DiscrepancyCount > 0 {
+		run.Status = models.ReconciliationStatusDiscrepancies
+	}
+
+	if err := s.saveRun(run); err != nil {
+		return nil, err
+	}
+	if run.DiscrepancyCount > 0 {
+		s.logger.Printf("Payment reconciliation %d for %s found %d discrepancies", run.ID, gatewayName, run.DiscrepancyCount)
+	}
+	return run, nil
+}
+
+// match compares the gateway transactions with the local payments and the
+// local refunds of the period, and adds a discrepancy item to the run for
+// everything that does not line up
+func (s *PaymentReconciliationService) match(run *models.PaymentReconciliation, transactions []*payment.Transaction, locals map[string]*localPayment, localRefunds map[string]float64) {
+	seen := make(map[string]bool)
+	reported := make(map[string]bool)
+	refunds := make(map[string]float64)
+	refundCurrency := make(map[string]string)
+	chargesByOrder := make(map[int64][]string)
+
+	for _, transaction := range transactions {
+		if seen[transaction.ID] {
+			run.Items = append(run.Items, reconciliationItem(models.ReconciliationDuplicate, transaction.ID, 0,
+				transaction.Amount, 0, transaction.Currency, "transaction is reported more than once by the gateway"))
+			continue
+		}
+		seen[transaction.ID] = true
+
+		switch transaction.Type {
+		case payment.TransactionTypeRefund:
+			if transaction.Status == payment.PaymentStatusSucceeded {
+				refunds[transaction.PaymentID] += transaction.Amount
+				refundCurrency[transaction.PaymentID] = transaction.Currency
+			}
+			continue
+		case payment.TransactionTypePayment:
+		default:
+			continue
+		}
+
+		run.GatewayCount++
+		gatewaySettled := settledGatewayStatus(transaction.Status)
+		if gatewaySettled {
+			run.GatewayTotal += transaction.Amount
+		}
+
+		local, ok := locals[transaction.ID]
+		if !ok {
+			if gatewaySettled {
+				run.Items = append(run.Items, reconciliationItem(models.ReconciliationMissingLocal, transaction.ID, parseOrderReference(transaction.OrderID),
+					transaction.Amount, 0, transaction.Currency, "gateway payment has no local payment record"))
+			}
+			continue
+		}
+		reported[transaction.ID] = true
+
+		issues := s.compare(transaction, local)
+		if gatewaySettled && settledPaymentStatus(local.status) {
+			chargesByOrder[local.orderID] = append(chargesByOrder[local.orderID], transaction.ID)
+		}
+		if len(issues) == 0 {
+			run.MatchedCount++
+		}
+		run.Items = append(run.Items, issues...)
+	}
+
+	// Refunds are compared per payment
+	paymentIDs := make([]string, 0, len(refunds))
+	for paymentID := range refunds {
+		paymentIDs = append(paymentIDs, paymentID)
+	}
+	sort.Strings(paymentIDs)
+	for _, paymentID := range paymentIDs {
+		amount := roundMoney(refunds[paymentID])
+		local, ok := locals[paymentID]
+		if !ok {
+			run.Items = append(run.Items, reconciliationItem(models.ReconciliationMissingLocal, paymentID, 0,
+				amount, 0, refundCurrency[paymentID], "gateway refunded a payment we have no record of"))
+			continue
+		}
+		if localAmount := roundMoney(localRefunds[paymentID]); math.Abs(amount-localAmount) > s.config.AmountTolerance {
+			run.Items = append(run.Items, reconciliationItem(models.ReconciliationRefundMismatch, paymentID, local.orderID,
+				amount, localAmount, local.currency, "gateway refunds do not add up to the local refunds of the period"))
+		}
+	}
+	refundedIDs := make([]string, 0, len(localRefunds))
+	for paymentID := range localRefunds {
+		if refunds[paymentID] == 0 {
+			refundedIDs = append(refundedIDs, paymentID)
+		}
+	}
+	sort.Strings(refundedIDs)
+	for _, paymentID := range refundedIDs {
+		local := locals[paymentID]
+		run.Items = append(run.Items, reconciliationItem(models.ReconciliationRefundMismatch, paymentID, local.orderID,
+			0, localRefunds[paymentID], local.currency, "payment is refunded locally but the gateway reports no refund"))
+	}
+
+	// An order should be charged once
+	orderIDs := make([]int64, 0, len(chargesByOrder))
+	for orderID, charges := range chargesByOrder {
+		if orderID > 0 && len(charges) > 1 {
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+	sort.Slice(orderIDs, func(i, j int) bool { return orderIDs[i] < orderIDs[j] })
+	for _, orderID := range orderIDs {
+		charges := chargesByOrder[orderID]
+		local := locals[charges[0]]
+		run.Items = append(run.Items, reconciliationItem(models.ReconciliationDuplicate, strings.Join(charges, ","), orderID,
+			0, local.orderTotal, local.currency, fmt.Sprintf("order is charged %d times", len(charges))))
+	}
+
+	// Settled payments the gateway does not know about
+	missing := make([]*localPayment, 0)
+	for id, local := range locals {
+		if !reported[id] && local.inPeriod && settledPaymentStatus(local.status) {
+			missing = append(missing, local)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].transactionID < missing[j].transactionID })
+	for _, local := range missing {
+		run.Items = append(run.Items, reconciliationItem(models.ReconciliationMissingGateway, local.transactionID, local.orderID,
+			0, local.amount, local.currency, "local payment is not reported by the gateway"))
+	}
+}
+
+// compare returns the discrepancies between a gateway payment and its local
+// record and order
+func (s *PaymentReconciliationService) compare(transaction *payment.Transaction, local *localPayment) []models.PaymentReconciliationItem {
+	var issues []models.PaymentReconciliationItem
+	add := func(issue string, localAmount float64, details string) {
+		issues = append(issues, reconciliationItem(issue, transaction.ID, local.orderID,
+			transaction.Amount, localAmount, transaction.Currency, details))
+	}
+
+	if settledGatewayStatus(transaction.Status) != settledPaymentStatus(local.status) {
+		add(models.ReconciliationStatusMismatch, local.amount,
+			fmt.Sprintf("gateway status %s, local status %s", transaction.Status, local.status))
+	}
+	if math.Abs(transaction.Amount-local.amount) > s.config.AmountTolerance {
+		add(models.ReconciliationAmountMismatch, local.amount, "payment amount differs")
+	}
+	if !strings.EqualFold(transaction.Currency, local.currency) {
+		add(models.ReconciliationCurrencyMismatch, local.amount,
+			fmt.Sprintf("gateway currency %s, local currency %s", transaction.Currency, local.currency))
+	}
+	if transaction.OrderID != "" && parseOrderReference(transaction.OrderID) != local.orderID {
+		add(models.ReconciliationReferenceMismatch, local.amount,
+			fmt.Sprintf("gateway order %s, local order %d", transaction.OrderID, local.orderID))
+	}
+
+	if local.orderID == 0 {
+		return issues
+	}
+	switch {
+	case !local.orderFound:
+		add(models.ReconciliationReferenceMismatch, 0, fmt.Sprintf("order %d does not exist", local.orderID))
+	case local.orderReference != transaction.ID && settledPaymentStatus(local.status):
+		add(models.ReconciliationReferenceMismatch, local.orderTotal,
+			fmt.Sprintf("order references payment %q", local.orderReference))
+	case math.Abs(local.orderTotal-transaction.Amount) > s.config.AmountTolerance:
+		add(models.ReconciliationAmountMismatch, local.orderTotal, "order total differs from the charged amount")
+	case local.orderCurrency != "" && !strings.EqualFold(local.orderCurrency, transaction.Currency):
+		add(models.ReconciliationCurrencyMismatch, local.orderTotal,
+			fmt.Sprintf("order currency %s, gateway currency %s", local.orderCurrency, transaction.Currency))
+	}
+	return issues
+}
+
+// GetReconciliation returns a reconciliation run with its discrepancies
+func (s *PaymentReconciliationService) GetReconciliation(id int64) (*models.PaymentReconciliation, error) {
+	var run models.PaymentReconciliation
+	err := s.repo.QueryRow(`
+		SELECT id, gateway, period_start, period_end, gateway_count, local_count, matched_count,
+			discrepancy_count, gateway_total, local_total, status, created_at
+		FROM payment_reconciliations WHERE id = ?`, id).Scan(
+		&run.ID, &run.Gateway, &run.PeriodStart, &run.PeriodEnd, &run.GatewayCount, &run.LocalCount,
+		&run.MatchedCount, &run.DiscrepancyCount, &run.GatewayTotal, &run.LocalTotal, &run.Status, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrReconciliationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment reconciliation: %w", err)
+	}
+
+	rows, err := s.repo.Query(`
+		SELECT id, reconciliation_id, issue, transaction_id, order_id, gateway_amount, local_amount,
+			currency, details, created_at
+		FROM payment_reconciliation_items WHERE reconciliation_id = ? ORDER BY id ASC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reconciliation items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.PaymentReconciliationItem
+		var details sql.NullString
+		if err := rows.Scan(&item.ID, &item.ReconciliationID, &item.Issue, &item.TransactionID, &item.OrderID,
+			&item.GatewayAmount, &item.LocalAmount, &item.Currency, &details, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation item: %w", err)
+		}
+		item.Details = details.String
+		run.Items = append(run.Items, item)
+	}
+	return &run, nil
+}
+
+// GetLatestReconciliations returns the most recent reconciliation runs
+// without their items
+func (s *PaymentReconciliationService) GetLatestReconciliations(limit int) ([]models.PaymentReconciliation, error) {
+	if limit <= 0 {
+		limit = 30
+	}
+	rows, err := s.repo.Query(`
+		SELECT id, gateway, period_start, period_end, gateway_count, local_count, matched_count,
+			discrepancy_count, gateway_total, local_total, status, created_at
+		FROM payment_reconciliations ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment reconciliations: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.PaymentReconciliation
+	for rows.Next() {
+		var run models.PaymentReconciliation
+		if err := rows.Scan(&run.ID, &run.Gateway, &run.PeriodStart, &run.PeriodEnd, &run.GatewayCount, &run.LocalCount,
+			&run.MatchedCount, &run.DiscrepancyCount, &run.GatewayTotal, &run.LocalTotal, &run.Status, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment reconciliation: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// GetReport runs the reconciliation report for a run through the report
+// manager. An empty issue lists every discrepancy.
+func (s *PaymentReconciliationService) GetReport(reconciliationID int64, issue string, userID int64) (*reporting.ReportResult, error) {
+	if s.config.ReportManager == nil {
+		return nil, errors.New("no report manager configured")
+	}
+	filters := map[string]interface{}{"reconciliation_id": reconciliationID}
+	if issue != "" {
+		filters["issue"] = issue
+	}
+	return s.config.ReportManager.ExecuteReport(PaymentReconciliationReportID, filters, userID)
+}
+
+// loadLocalPayments loads the gateway's payment records of a period, keyed
+// by transaction ID
+func (s *PaymentReconciliationService) loadLocalPayments(gateway string, periodStart, periodEnd time.Time) (map[string]*localPayment, error) {
+	rows, err := s.repo.Query(localPaymentQuery+` WHERE p.provider = ? AND p.created_at >= ? AND p.created_at <= ?`,
+		gateway, periodStart.UTC(), periodEnd.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local payments: %w", err)
+	}
+	defer rows.Close()
+
+	locals := make(map[string]*localPayment)
+	for rows.Next() {
+		local, err := scanLocalPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		local.inPeriod = true
+		locals[local.transactionID] = local
+	}
+	return locals, nil
+}
+
+// loadPeriodRefunds sums the refunds of the gateway's payments recorded in
+// a period, keyed by transaction ID
+func (s *PaymentReconciliationService) loadPeriodRefunds(gateway string, periodStart, periodEnd time.Time) (map[string]float64, error) {
+	rows, err := s.repo.Query(`
+		SELECT r.transaction_id, SUM(r.amount)
+		FROM payment_refunds r JOIN payments p ON p.transaction_id = r.transaction_id
+		WHERE p.provider = ? AND r.created_at >= ? AND r.created_at <= ?
+		GROUP BY r.transaction_id`,
+		gateway, periodStart.UTC(), periodEnd.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make(map[string]float64)
+	for rows.Next() {
+		var transactionID string
+		var amount float64
+		if err := rows.Scan(&transactionID, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan local refund: %w", err)
+		}
+		refunds[transactionID] = amount
+	}
+	return refunds, nil
+}
+
+// loadLocalPayment loads a single payment record by transaction ID
+func (s *PaymentReconciliationService) loadLocalPayment(transactionID string) (*localPayment, error) {
+	rows, err := s.repo.Query(localPaymentQuery+` WHERE p.transaction_id = ?`, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local payment: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, ErrPaymentNotFound
+	}
+	return scanLocalPayment(rows)
+}
+
+// localPaymentQuery selects payment records with their orders
+const localPaymentQuery = `
+	SELECT p.transaction_id, p.order_id, p.status, p.amount, p.refunded_amount, p.currency,
+		o.id, o.total_amount, o.currency, o.reference_id
+	FROM payments p LEFT JOIN orders o ON o.id = p.order_id`
+
+// scanLocalPayment scans a row of localPaymentQuery
+func scanLocalPayment(rows database.Rows) (*localPayment, error) {
+	var local localPayment
+	var status string
+	var orderID sql.NullInt64
+	var orderTotal sql.NullFloat64
+	var orderCurrency, orderReference sql.NullString
+	if err := rows.Scan(&local.transactionID, &local.orderID, &status, &local.amount, &local.refundedAmount,
+		&local.currency, &orderID, &orderTotal, &orderCurrency, &orderReference); err != nil {
+		return nil, fmt.Errorf("failed to scan local payment: %w", err)
+	}
+	local.status = PaymentStatus(status)
+	local.orderFound = orderID.Valid
+	local.orderTotal = orderTotal.Float64
+	local.orderCurrency = orderCurrency.String
+	local.orderReference = orderReference.String
+	return &local, nil
+}
+
+// saveRun stores a reconciliation run and its discrepancies
+func (s *PaymentReconciliationService) saveRun(run *models.PaymentReconciliation) error {
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		INSERT INTO payment_reconciliations (gateway, period_start, period_end, gateway_count, local_count,
+			matched_count, discrepancy_count, gateway_total, local_total, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Gateway, run.PeriodStart, run.PeriodEnd, run.GatewayCount, run.LocalCount, run.MatchedCount,
+		run.DiscrepancyCount, run.GatewayTotal, run.LocalTotal, run.Status, run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save payment reconciliation: %w", err)
+	}
+	if run.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get payment reconciliation ID: %w", err)
+	}
+
+	for i := range run.Items {
+		item := &run.Items[i]
+		item.ReconciliationID = run.ID
+		item.CreatedAt = run.CreatedAt
+		result, err := tx.Exec(`
+			INSERT INTO payment_reconciliation_items (reconciliation_id, issue, transaction_id, order_id,
+				gateway_amount, local_amount, currency, details, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.ReconciliationID, item.Issue, item.TransactionID, item.OrderID, item.GatewayAmount,
+			item.LocalAmount, item.Currency, item.Details, item.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save reconciliation item: %w", err)
+		}
+		if item.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get reconciliation item ID: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment reconciliation: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// reconciliationItem builds a discrepancy item
+func reconciliationItem(issue, transactionID string, orderID int64, gatewayAmount, localAmount float64, currency, details string) models.PaymentReconciliationItem {
+	return models.PaymentReconciliationItem{
+		Issue:         issue,
+		TransactionID: transactionID,
+		OrderID:       orderID,
+		GatewayAmount: roundMoney(gatewayAmount),
+		LocalAmount:   roundMoney(localAmount),
+		Currency:      currency,
+		Details:       details,
+	}
+}
+
+// settledPaymentStatus reports whether a local payment has taken the
+// customer's money
+func settledPaymentStatus(status PaymentStatus) bool {
+	switch status {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// settledGatewayStatus reports whether a gateway payment has been captured
+func settledGatewayStatus(status payment.PaymentStatusType) bool {
+	switch status {
+	case payment.PaymentStatusSucceeded, payment.PaymentStatusPartiallyRefunded, payment.PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// parseOrderReference reads our order ID back from a gateway order
+// reference. Unparseable references yield 0.
+func parseOrderReference(reference string) int64 {
+	id, _ := strconv.ParseInt(strings.TrimSpace(reference), 10, 64)
+	return id
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+	"kolajAi/internal/reporting"
+	"kolajAi/internal/testutil"
+)
+
+// newTestReconciliation returns a checkout and a reconciliation service
+// sharing a sandbox gateway
+func newTestReconciliation(t *testing.T) (*CheckoutService, *PaymentReconciliationService, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	gateway := payment.NewSandboxProvider(payment.SandboxConfig{Logger: testutil.DiscardLogger})
+	paymentService := NewPaymentService(repo)
+	paymentService.SetGateway(gateway)
+	checkout, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconciliation, err := NewPaymentReconciliationService(repo, PaymentReconciliationConfig{Gateway: gateway, Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkout, reconciliation, repo
+}
+
+// paidOrder checks out one unit of productID and pays it with an approved
+// sandbox card
+func paidOrder(t *testing.T, s *CheckoutService, userID, productID int64) *PaymentResponse {
+	t.Helper()
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := s.PayOrder(result.Order, testCard(payment.SandboxCardApproved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != PaymentStatusCompleted {
+		t.Fatalf("payment status = %s", response.Status)
+	}
+	return response
+}
+
+func TestReconcileBalancedPeriod(t *testing.T) {
+	checkout, reconciliation, repo := newTestReconciliation(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 10)
+	paidOrder(t, checkout, userID, productID)
+	refunded := paidOrder(t, checkout, userID, productID)
+	if _, err := checkout.paymentService.RefundPayment(refunded.TransactionID, 15, "kısmi iade"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	run, err := reconciliation.Reconcile(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.ReconciliationStatusBalanced || run.MatchedCount != 2 || len(run.Items) != 0 {
+		t.Fatalf("run = %+v, items %+v", run, run.Items)
+	}
+	if run.GatewayTotal != 80 || run.LocalTotal != 80 {
+		t.Fatalf("totals = %.2f gateway, %.2f local, want 80", run.GatewayTotal, run.LocalTotal)
+	}
+}
+
+func TestReconcileFlagsDiscrepancies(t *testing.T) {
+	checkout, reconciliation, repo := newTestReconciliation(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 10)
+	paidOrder(t, checkout, userID, productID)
+	mismatched := paidOrder(t, checkout, userID, productID)
+	unrecorded := paidOrder(t, checkout, userID, productID)
+
+	mustExec(t, repo, `UPDATE payments SET amount = 35 WHERE transaction_id = ?`, mismatched.TransactionID)
+	mustExec(t, repo, `DELETE FROM payments WHERE transaction_id = ?`, unrecorded.TransactionID)
+	now := time.Now().UTC()
+	mustExec(t, repo, `
+		INSERT INTO payments (transaction_id, order_id, customer_id, provider, method, status, amount, currency, created_at, updated_at)
+		VALUES ('sbx_pay_unknown', 0, ?, 'sandbox', 'credit_card', 'completed', 25, 'TRY', ?, ?)`, userID, now, now)
+
+	run, err := reconciliation.Reconcile(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.ReconciliationStatusDiscrepancies || run.MatchedCount != 1 {
+		t.Fatalf("run = %+v", run)
+	}
+	want := map[string]string{
+		mismatched.TransactionID: models.ReconciliationAmountMismatch,
+		unrecorded.TransactionID: models.ReconciliationMissingLocal,
+		"sbx_pay_unknown":        models.ReconciliationMissingGateway,
+	}
+	if len(run.Items) != len(want) {
+		t.Fatalf("items = %+v, want %d", run.Items, len(want))
+	}
+	for _, item := range run.Items {
+		if want[item.TransactionID] != item.Issue {
+			t.Fatalf("%s flagged as %s, want %s", item.TransactionID, item.Issue, want[item.TransactionID])
+		}
+	}
+
+	stored, err := reconciliation.GetReconciliation(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.DiscrepancyCount != 3 || len(stored.Items) != 3 {
+		t.Fatalf("stored run = %+v", stored)
+	}
+}
+
+func TestReconcileRequiresGatewayAndPeriod(t *testing.T) {
+	_, reconciliation, repo := newTestReconciliation(t)
+	now := time.Now().UTC()
+	s, err := NewPaymentReconciliationService(repo, PaymentReconciliationConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reconcile(now.Add(-time.Hour), now); !errors.Is(err, ErrNoTransactionSource) {
+		t.Fatalf("got %v, want ErrNoTransactionSource", err)
+	}
+	if _, err := reconciliation.Reconcile(now, now.Add(-time.Hour)); !errors.Is(err, ErrInvalidPeriod) {
+		t.Fatalf("got %v, want ErrInvalidPeriod", err)
+	}
+}
+
+// fixedTransactions is a gateway that reports a fixed transaction list
+type fixedTransactions []*payment.Transaction
+
+func (f fixedTransactions) Name() string { return "sandbox" }
+
+func (f fixedTransactions) ListTransactions(ctx context.Context, filters payment.TransactionFilters) ([]*payment.Transaction, error) {
+	return f, nil
+}
+
+// seedPayment stores a settled sandbox payment made at createdAt
+func seedPayment(t *testing.T, repo database.SimpleRepository, transactionID string, amount, refunded float64, createdAt time.Time) {
+	t.Helper()
+	status := PaymentStatusCompleted
+	if refunded > 0 {
+		status = PaymentStatusPartiallyRefunded
+	}
+	mustExec(t, repo, `
+		INSERT INTO payments (transaction_id, order_id, customer_id, provider, method, status, amount, refunded_amount, currency, created_at, updated_at)
+		VALUES (?, 0, 1, 'sandbox', 'credit_card', ?, ?, ?, 'TRY', ?, ?)`,
+		transactionID, string(status), amount, refunded, createdAt, createdAt)
+}
+
+// seedRefund records a local refund of a payment made at createdAt
+func seedRefund(t *testing.T, repo database.SimpleRepository, transactionID string, amount float64, createdAt time.Time) {
+	t.Helper()
+	mustExec(t, repo, `
+		INSERT INTO payment_refunds (transaction_id, refund_id, amount, currency, created_at)
+		VALUES (?, ?, ?, 'TRY', ?)`, transactionID, transactionID+"_ref", amount, createdAt)
+}
+
+func refundTransaction(id, paymentID string, amount float64) *payment.Transaction {
+	return &payment.Transaction{ID: id, Type: payment.TransactionTypeRefund, PaymentID: paymentID,
+		Status: payment.PaymentStatusSucceeded, Amount: amount, Currency: "TRY"}
+}
+
+func TestReconcileRefundOfEarlierPayment(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	now := time.Now().UTC()
+	// Paid two days ago, refunded 10 yesterday and 5 in the period
+	seedPayment(t, repo, "sbx_pay_old", 50, 15, now.Add(-48*time.Hour))
+	seedRefund(t, repo, "sbx_pay_old", 10, now.Add(-24*time.Hour))
+	seedRefund(t, repo, "sbx_pay_old", 5, now)
+
+	s, err := NewPaymentReconciliationService(repo, PaymentReconciliationConfig{
+		Gateway: fixedTransactions{refundTransaction("sbx_ref_1", "sbx_pay_old", 5)},
+		Logger:  testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.Reconcile(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The payment is outside the period, so it is neither missing at the
+	// gateway nor counted, and only the period's refund is compared
+	if run.Status != models.ReconciliationStatusBalanced || len(run.Items) != 0 {
+		t.Fatalf("run = %+v, items %+v", run, run.Items)
+	}
+	if run.LocalCount != 0 || run.LocalTotal != 0 {
+		t.Fatalf("local count %d, total %.2f, want none", run.LocalCount, run.LocalTotal)
+	}
+}
+
+func TestReconcileComparesPeriodRefunds(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	now := time.Now().UTC()
+	seedPayment(t, repo, "sbx_pay_short", 50, 20, now.Add(-48*time.Hour))
+	seedRefund(t, repo, "sbx_pay_short", 20, now)
+	seedPayment(t, repo, "sbx_pay_unreported", 30, 10, now.Add(-48*time.Hour))
+	seedRefund(t, repo, "sbx_pay_unreported", 10, now)
+
+	s, err := NewPaymentReconciliationService(repo, PaymentReconciliationConfig{
+		Gateway: fixedTransactions{refundTransaction("sbx_ref_1", "sbx_pay_short", 12)},
+		Logger:  testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.Reconcile(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Items) != 2 {
+		t.Fatalf("items = %+v, want 2", run.Items)
+	}
+	want := map[string][2]float64{
+		"sbx_pay_short":      {12, 20},
+		"sbx_pay_unreported": {0, 10},
+	}
+	for _, item := range run.Items {
+		amounts, ok := want[item.TransactionID]
+		if !ok || item.Issue != models.ReconciliationRefundMismatch {
+			t.Fatalf("unexpected item %+v", item)
+		}
+		if item.GatewayAmount != amounts[0] || item.LocalAmount != amounts[1] {
+			t.Fatalf("%s amounts = %.2f gateway, %.2f local, want %v", item.TransactionID, item.GatewayAmount, item.LocalAmount, amounts)
+		}
+	}
+}
+
+func TestReconciliationReport(t *testing.T) {
+	checkout, _, repo := newTestReconciliation(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 10)
+	paidOrder(t, checkout, userID, productID)
+	mismatched := paidOrder(t, checkout, userID, productID)
+	mustExec(t, repo, `UPDATE payments SET amount = 35 WHERE transaction_id = ?`, mismatched.TransactionID)
+
+	s, err := NewPaymentReconciliationService(repo, PaymentReconciliationConfig{
+		Gateway:       checkout.paymentService.gateway.(payment.TransactionLister),
+		ReportManager: reporting.NewReportManager(testutil.NewDB(t)),
+		Logger:        testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	run, err := s.Reconcile(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := s.GetReport(run.ID, "", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Data) != 1 {
+		t.Fatalf("report rows = %+v, want 1", report.Data)
+	}
+	if issue := report.Data[0]["issue"]; issue != models.ReconciliationAmountMismatch {
+		t.Fatalf("issue = %v, want %s", issue, models.ReconciliationAmountMismatch)
+	}
+
+	report, err = s.GetReport(run.ID, models.ReconciliationMissingLocal, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Data) != 0 {
+		t.Fatalf("filtered report rows = %+v, want none", report.Data)
+	}
+}
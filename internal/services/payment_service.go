@@ -1,63 +1,111 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"kolajAi/internal/database"
+	"log"
+	"strconv"
+	"sync"
 	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations"
+	"kolajAi/internal/integrations/payment"
+)
+
+// Payment errors
+var (
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrCardRequired         = errors.New("card payments need a card or a card token")
+	ErrInvalidPaymentStatus = errors.New("payment is not in a valid status for this action")
+	ErrGatewayMismatch      = errors.New("payment was made through another payment gateway")
+	ErrInvalidWebhook       = errors.New("payment webhook is invalid")
+	// ErrGatewayNotConfigured is returned for card payments when no payment
+	// gateway is set; cards are never charged against a default gateway
+	ErrGatewayNotConfigured = errors.New("no payment gateway is configured")
 )
 
+// paymentGatewayTimeout bounds every call to the payment gateway
+const paymentGatewayTimeout = 30 * time.Second
+
 // PaymentMethod represents payment methods
 type PaymentMethod string
 
 const (
-	PaymentMethodCreditCard PaymentMethod = "credit_card"
-	PaymentMethodDebitCard  PaymentMethod = "debit_card"
-	PaymentMethodPayPal     PaymentMethod = "paypal"
+	PaymentMethodCreditCard   PaymentMethod = "credit_card"
+	PaymentMethodDebitCard    PaymentMethod = "debit_card"
+	PaymentMethodPayPal       PaymentMethod = "paypal"
 	PaymentMethodBankTransfer PaymentMethod = "bank_transfer"
-	PaymentMethodCash       PaymentMethod = "cash"
+	PaymentMethodCash         PaymentMethod = "cash"
 )
 
 // PaymentStatus represents payment status
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusCompleted PaymentStatus = "completed"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusRefunded  PaymentStatus = "refunded"
-	PaymentStatusCancelled PaymentStatus = "cancelled"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusAuthorized        PaymentStatus = "authorized"
+	PaymentStatusCompleted         PaymentStatus = "completed"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusCancelled         PaymentStatus = "cancelled"
 )
 
+// PaymentCard selects the card a card payment charges: either the card
+// itself or a token saved through the gateway
+type PaymentCard struct {
+	Card           *payment.CardDetails `json:"card,omitempty"`
+	Token          string               `json:"token,omitempty"`
+	Enable3DSecure bool                 `json:"enable_3d_secure"`
+}
+
 // PaymentRequest represents a payment request
 type PaymentRequest struct {
-	OrderID       int64         `json:"order_id"`
-	Amount        float64       `json:"amount"`
-	Currency      string        `json:"currency"`
-	Method        PaymentMethod `json:"method"`
-	CustomerID    int64         `json:"customer_id"`
-	Description   string        `json:"description"`
-	CallbackURL   string        `json:"callback_url"`
-	Metadata      map[string]interface{} `json:"metadata"`
+	OrderID     int64                  `json:"order_id"`
+	Amount      float64                `json:"amount"`
+	Currency    string                 `json:"currency"`
+	Method      PaymentMethod          `json:"method"`
+	CustomerID  int64                  `json:"customer_id"`
+	Description string                 `json:"description"`
+	CallbackURL string                 `json:"callback_url"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	// Card is required for credit and debit card payments
+	Card *PaymentCard `json:"card,omitempty"`
+	// AuthorizeOnly holds the amount on the card until CapturePayment
+	AuthorizeOnly bool `json:"authorize_only"`
 }
 
 // PaymentResponse represents a payment response
 type PaymentResponse struct {
-	ID            string        `json:"id"`
-	Status        PaymentStatus `json:"status"`
-	Amount        float64       `json:"amount"`
-	Currency      string        `json:"currency"`
-	Method        PaymentMethod `json:"method"`
-	TransactionID string        `json:"transaction_id"`
-	CreatedAt     time.Time     `json:"created_at"`
-	CompletedAt   *time.Time    `json:"completed_at,omitempty"`
-	FailureReason string        `json:"failure_reason,omitempty"`
-	PaymentURL    string        `json:"payment_url,omitempty"`
-}
-
-// PaymentService handles payment operations
+	ID             string        `json:"id"`
+	OrderID        int64         `json:"order_id"`
+	Status         PaymentStatus `json:"status"`
+	Amount         float64       `json:"amount"`
+	RefundedAmount float64       `json:"refunded_amount,omitempty"`
+	Currency       string        `json:"currency"`
+	Method         PaymentMethod `json:"method"`
+	Gateway        string        `json:"gateway,omitempty"`
+	TransactionID  string        `json:"transaction_id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	CompletedAt    *time.Time    `json:"completed_at,omitempty"`
+	FailureCode    string        `json:"failure_code,omitempty"`
+	FailureReason  string        `json:"failure_reason,omitempty"`
+	PaymentURL     string        `json:"payment_url,omitempty"`
+	// ThreeDSecureHTML is the challenge page of a pending card payment
+	// that has to pass 3-D Secure through Complete3DSecure
+	ThreeDSecureHTML string `json:"three_d_secure_html,omitempty"`
+}
+
+// PaymentService handles payment operations. Card payments go through a
+// payment.Gateway; without one set they are refused.
 type PaymentService struct {
 	repo database.SimpleRepository
+
+	mu      sync.Mutex
+	gateway payment.Gateway
 }
 
 // NewPaymentService creates a new payment service
@@ -65,7 +113,27 @@ func NewPaymentService(repo database.SimpleRepository) *PaymentService {
 	return &PaymentService{repo: repo}
 }
 
-// ProcessPayment processes a payment request
+// SetGateway sets the gateway card payments go through
+func (s *PaymentService) SetGateway(gateway payment.Gateway) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gateway = gateway
+}
+
+// getGateway returns the card payment gateway, or ErrGatewayNotConfigured
+// if none has been set
+func (s *PaymentService) getGateway() (payment.Gateway, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gateway == nil {
+		return nil, ErrGatewayNotConfigured
+	}
+	return s.gateway, nil
+}
+
+// ProcessPayment processes a payment request. Card payments are charged
+// through the gateway; a declined card is returned as a failed payment,
+// not an error.
 func (s *PaymentService) ProcessPayment(request *PaymentRequest) (*PaymentResponse, error) {
 	if request.Amount <= 0 {
 		return nil, errors.New("invalid payment amount")
@@ -81,6 +149,7 @@ func (s *PaymentService) ProcessPayment(request *PaymentRequest) (*PaymentRespon
 	// Create payment response
 	response := &PaymentResponse{
 		ID:            transactionID,
+		OrderID:       request.OrderID,
 		Status:        PaymentStatusPending,
 		Amount:        request.Amount,
 		Currency:      request.Currency,
@@ -89,17 +158,10 @@ func (s *PaymentService) ProcessPayment(request *PaymentRequest) (*PaymentRespon
 		CreatedAt:     time.Now(),
 	}
 
-	// Mock different payment methods
 	switch request.Method {
 	case PaymentMethodCreditCard, PaymentMethodDebitCard:
-		// Simulate card payment processing
-		if s.simulateCardPayment(request) {
-			response.Status = PaymentStatusCompleted
-			now := time.Now()
-			response.CompletedAt = &now
-		} else {
-			response.Status = PaymentStatusFailed
-			response.FailureReason = "Card payment failed"
+		if err := s.chargeCard(request, response); err != nil {
+			return nil, err
 		}
 
 	case PaymentMethodPayPal:
@@ -120,29 +182,222 @@ func (s *PaymentService) ProcessPayment(request *PaymentRequest) (*PaymentRespon
 		return nil, errors.New("unsupported payment method")
 	}
 
-	// Save payment record (mock)
-	s.savePaymentRecord(response)
+	// A charged card must not be reported as failed because its record
+	// could not be written; the gateway still has the payment
+	if err := s.savePaymentRecord(request.CustomerID, response); err != nil {
+		log.Printf("Failed to record payment %s of order %d: %v", response.TransactionID, request.OrderID, err)
+	}
 
 	return response, nil
 }
 
-// GetPaymentStatus gets payment status by transaction ID
-func (s *PaymentService) GetPaymentStatus(transactionID string) (*PaymentResponse, error) {
-	var payment PaymentResponse
-	err := s.repo.FindOne("payments", &payment, map[string]interface{}{
-		"transaction_id": transactionID,
+// chargeCard charges a card payment through the gateway and fills in the
+// response from the gateway's answer
+func (s *PaymentService) chargeCard(request *PaymentRequest, response *PaymentResponse) error {
+	if request.Card == nil || (request.Card.Card == nil && request.Card.Token == "") {
+		return ErrCardRequired
+	}
+
+	gateway, err := s.getGateway()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	result, err := gateway.CreatePayment(ctx, &payment.PaymentRequest{
+		Amount:      request.Amount,
+		Currency:    request.Currency,
+		Description: request.Description,
+		OrderID:     strconv.FormatInt(request.OrderID, 10),
+		CustomerID:  strconv.FormatInt(request.CustomerID, 10),
+		PaymentMethod: payment.PaymentMethod{
+			Type:  payment.PaymentMethodTypeCard,
+			Card:  request.Card.Card,
+			Token: request.Card.Token,
+		},
+		Metadata:       request.Metadata,
+		ReturnURL:      request.CallbackURL,
+		CallbackURL:    request.CallbackURL,
+		Enable3DSecure: request.Card.Enable3DSecure,
+		AuthorizeOnly:  request.AuthorizeOnly,
 	})
+	response.Gateway = gateway.Name()
 	if err != nil {
-		return nil, fmt.Errorf("payment not found: %w", err)
+		if !payment.IsDecline(err) {
+			return fmt.Errorf("failed to charge card: %w", err)
+		}
+		response.Status = PaymentStatusFailed
+		response.FailureCode, response.FailureReason = declineDetails(err)
+		return nil
 	}
-	return &payment, nil
+
+	response.ID = result.ID
+	response.TransactionID = result.ID
+	applyGatewayResponse(response, result)
+	return nil
 }
 
-// RefundPayment refunds a payment
+// Complete3DSecure finishes the 3-D Secure challenge of a pending card
+// payment with the data the bank posted back
+func (s *PaymentService) Complete3DSecure(transactionID string, verificationData map[string]string) (*PaymentResponse, error) {
+	record, gateway, err := s.loadGatewayPayment(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != PaymentStatusPending {
+		return nil, ErrInvalidPaymentStatus
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	result, err := gateway.Verify3DSecure(ctx, transactionID, verificationData)
+	if err != nil {
+		if !payment.IsDecline(err) {
+			return nil, fmt.Errorf("failed to verify 3-D Secure: %w", err)
+		}
+		record.Status = PaymentStatusFailed
+		record.FailureCode, record.FailureReason = declineDetails(err)
+	} else {
+		applyGatewayResponse(record, result)
+	}
+
+	if err := s.updatePaymentRecord(record, false); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// CapturePayment captures an authorized card payment. A zero amount
+// captures the whole authorization.
+func (s *PaymentService) CapturePayment(transactionID string, amount float64) (*PaymentResponse, error) {
+	record, gateway, err := s.loadGatewayPayment(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != PaymentStatusAuthorized {
+		return nil, ErrInvalidPaymentStatus
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	result, err := gateway.CapturePayment(ctx, transactionID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture payment: %w", err)
+	}
+	applyGatewayResponse(record, result)
+	if amount > 0 {
+		record.Amount = amount
+	}
+
+	if err := s.updatePaymentRecord(record, false); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// VoidPayment releases an authorized card payment, or abandons one that is
+// still waiting for its 3-D Secure challenge
+func (s *PaymentService) VoidPayment(transactionID string) (*PaymentResponse, error) {
+	record, gateway, err := s.loadGatewayPayment(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != PaymentStatusAuthorized && record.Status != PaymentStatusPending {
+		return nil, ErrInvalidPaymentStatus
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	if _, err := gateway.VoidPayment(ctx, transactionID); err != nil {
+		return nil, fmt.Errorf("failed to void payment: %w", err)
+	}
+	record.Status = PaymentStatusCancelled
+
+	if err := s.updatePaymentRecord(record, false); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// HandleWebhook applies a gateway webhook to the payment it is about. The
+// payment status is read back from the gateway rather than taken from the
+// payload, so late or repeated webhooks cannot roll a payment back.
+func (s *PaymentService) HandleWebhook(payload *payment.WebhookPayload) (*PaymentResponse, error) {
+	gateway, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	if verifier, ok := gateway.(payment.WebhookVerifier); ok {
+		if err := verifier.VerifyWebhook(payload); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+	}
+
+	transactionID, _ := payload.Data["payment_id"].(string)
+	if transactionID == "" {
+		return nil, fmt.Errorf("%w: webhook %s has no payment ID", ErrInvalidWebhook, payload.ID)
+	}
+
+	record, gateway, err := s.loadGatewayPayment(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	status, err := gateway.GetPaymentStatus(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment status: %w", err)
+	}
+	refundedBefore := record.RefundedAmount
+	record.Status = paymentStatusFromGateway(status.Status, record.Status)
+	record.RefundedAmount = status.RefundedAmount
+	if record.Status == PaymentStatusFailed && record.FailureCode == "" {
+		record.FailureCode, _ = payload.Data["failure_code"].(string)
+		record.FailureReason = "payment declined: " + record.FailureCode
+	}
+	if record.CompletedAt == nil && (record.Status == PaymentStatusCompleted || record.Status == PaymentStatusAuthorized) {
+		completedAt := status.UpdatedAt
+		record.CompletedAt = &completedAt
+	}
+
+	if err := s.updatePaymentRecord(record, true); err != nil {
+		return nil, err
+	}
+	// Refunds made on the gateway's side are recorded as they are reported
+	if refunded := roundMoney(record.RefundedAmount - refundedBefore); refunded > 0 {
+		refund := &payment.RefundResponse{ID: payload.ID, Amount: refunded, ProcessedAt: status.UpdatedAt}
+		if err := s.saveRefundRecord(record, refund); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// GetPaymentStatus gets payment status by transaction ID
+func (s *PaymentService) GetPaymentStatus(transactionID string) (*PaymentResponse, error) {
+	return s.loadPayment(transactionID)
+}
+
+// RefundPayment refunds a payment. Card payments are refunded through the
+// gateway they were made with; other methods are settled by hand and the
+// refund is only acknowledged.
 func (s *PaymentService) RefundPayment(transactionID string, amount float64, reason string) (*PaymentResponse, error) {
-	// Mock refund processing
+	record, err := s.loadPayment(transactionID)
+	if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+		return nil, err
+	}
+	if err == nil && record.Gateway != "" {
+		return s.refundThroughGateway(record, amount)
+	}
+
 	refundID := fmt.Sprintf("REF_%s_%d", transactionID, time.Now().Unix())
-	
+
 	return &PaymentResponse{
 		ID:            refundID,
 		Status:        PaymentStatusRefunded,
@@ -153,6 +408,54 @@ func (s *PaymentService) RefundPayment(transactionID string, amount float64, rea
 	}, nil
 }
 
+// refundThroughGateway refunds a captured card payment
+func (s *PaymentService) refundThroughGateway(record *PaymentResponse, amount float64) (*PaymentResponse, error) {
+	if record.Status != PaymentStatusCompleted && record.Status != PaymentStatusPartiallyRefunded {
+		return nil, ErrInvalidPaymentStatus
+	}
+	gateway, err := s.getGateway()
+	if err != nil {
+		return nil, err
+	}
+	if gateway.Name() != record.Gateway {
+		return nil, ErrGatewayMismatch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentGatewayTimeout)
+	defer cancel()
+
+	refund, err := gateway.RefundPayment(ctx, record.TransactionID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	record.RefundedAmount = roundMoney(record.RefundedAmount + refund.Amount)
+	record.Status = PaymentStatusPartiallyRefunded
+	if record.RefundedAmount >= record.Amount-0.005 {
+		record.Status = PaymentStatusRefunded
+	}
+	if err := s.updatePaymentRecord(record, false); err != nil {
+		return nil, err
+	}
+	if err := s.saveRefundRecord(record, refund); err != nil {
+		return nil, err
+	}
+
+	completedAt := refund.ProcessedAt
+	return &PaymentResponse{
+		ID:            refund.ID,
+		OrderID:       record.OrderID,
+		Status:        PaymentStatusRefunded,
+		Amount:        refund.Amount,
+		Currency:      record.Currency,
+		Method:        record.Method,
+		Gateway:       record.Gateway,
+		TransactionID: refund.ID,
+		CreatedAt:     refund.CreatedAt,
+		CompletedAt:   &completedAt,
+	}, nil
+}
+
 // GetSupportedPaymentMethods returns supported payment methods
 func (s *PaymentService) GetSupportedPaymentMethods() []PaymentMethod {
 	return []PaymentMethod{
@@ -175,21 +478,159 @@ func (s *PaymentService) ValidatePaymentMethod(method PaymentMethod) bool {
 	return false
 }
 
-// simulateCardPayment simulates card payment processing
-func (s *PaymentService) simulateCardPayment(request *PaymentRequest) bool {
-	// Mock success rate: 90%
-	return time.Now().UnixNano()%10 < 9
+// loadGatewayPayment loads a card payment together with the gateway it was
+// made through
+func (s *PaymentService) loadGatewayPayment(transactionID string) (*PaymentResponse, payment.Gateway, error) {
+	record, err := s.loadPayment(transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	gateway, err := s.getGateway()
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Gateway != gateway.Name() {
+		return nil, nil, ErrGatewayMismatch
+	}
+	return record, gateway, nil
+}
+
+// loadPayment loads a payment record by transaction ID
+func (s *PaymentService) loadPayment(transactionID string) (*PaymentResponse, error) {
+	var record PaymentResponse
+	var method, status string
+	var errorCode, errorMessage, paymentURL sql.NullString
+	var completedAt sql.NullTime
+	err := s.repo.QueryRow(`
+		SELECT transaction_id, order_id, provider, method, status, amount, refunded_amount, currency,
+			error_code, error_message, payment_url, completed_at, created_at
+		FROM payments WHERE transaction_id = ?`, transactionID).Scan(
+		&record.TransactionID, &record.OrderID, &record.Gateway, &method, &status, &record.Amount,
+		&record.RefundedAmount, &record.Currency, &errorCode, &errorMessage, &paymentURL,
+		&completedAt, &record.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	record.ID = record.TransactionID
+	record.Method = PaymentMethod(method)
+	record.Status = PaymentStatus(status)
+	record.FailureCode = errorCode.String
+	record.FailureReason = errorMessage.String
+	record.PaymentURL = paymentURL.String
+	if completedAt.Valid {
+		record.CompletedAt = &completedAt.Time
+	}
+	return &record, nil
 }
 
 // savePaymentRecord saves payment record to database
-func (s *PaymentService) savePaymentRecord(payment *PaymentResponse) error {
-	_, err := s.repo.CreateStruct("payments", payment)
+func (s *PaymentService) savePaymentRecord(customerID int64, record *PaymentResponse) error {
+	now := time.Now().UTC()
+	_, err := s.repo.Exec(`
+		INSERT INTO payments (transaction_id, order_id, customer_id, provider, method, status, amount,
+			currency, error_code, error_message, payment_url, completed_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.TransactionID, record.OrderID, customerID, record.Gateway, string(record.Method),
+		string(record.Status), record.Amount, record.Currency, record.FailureCode,
+		record.FailureReason, record.PaymentURL, record.CompletedAt, record.CreatedAt.UTC(), now)
 	if err != nil {
 		return fmt.Errorf("failed to save payment record: %w", err)
 	}
 	return nil
 }
 
+// saveRefundRecord records a refund the gateway accepted for a payment
+func (s *PaymentService) saveRefundRecord(record *PaymentResponse, refund *payment.RefundResponse) error {
+	createdAt := refund.ProcessedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.repo.Exec(`
+		INSERT INTO payment_refunds (transaction_id, refund_id, amount, currency, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		record.TransactionID, refund.ID, refund.Amount, record.Currency, createdAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save refund record: %w", err)
+	}
+	return nil
+}
+
+// updatePaymentRecord writes the status of a payment back to its record
+func (s *PaymentService) updatePaymentRecord(record *PaymentResponse, fromWebhook bool) error {
+	now := time.Now().UTC()
+	query := `UPDATE payments SET status = ?, amount = ?, refunded_amount = ?, error_code = ?,
+		error_message = ?, completed_at = ?, updated_at = ? WHERE transaction_id = ?`
+	args := []interface{}{string(record.Status), record.Amount, record.RefundedAmount,
+		record.FailureCode, record.FailureReason, record.CompletedAt, now, record.TransactionID}
+	if fromWebhook {
+		query = `UPDATE payments SET status = ?, amount = ?, refunded_amount = ?, error_code = ?,
+			error_message = ?, completed_at = ?, updated_at = ?, webhook_received = ?, webhook_at = ?
+			WHERE transaction_id = ?`
+		args = []interface{}{string(record.Status), record.Amount, record.RefundedAmount,
+			record.FailureCode, record.FailureReason, record.CompletedAt, now, true, now,
+			record.TransactionID}
+	}
+	if _, err := s.repo.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update payment record: %w", err)
+	}
+	return nil
+}
+
+// applyGatewayResponse copies the outcome of a gateway call into a payment
+func applyGatewayResponse(response *PaymentResponse, result *payment.PaymentResponse) {
+	response.Status = paymentStatusFromGateway(result.Status, response.Status)
+	if result.Error != nil {
+		response.FailureCode = result.Error.Code
+		response.FailureReason = result.Error.Message
+	}
+	if result.Requires3DSecure() {
+		response.PaymentURL, _ = result.Metadata[payment.Metadata3DSecureURL].(string)
+		response.ThreeDSecureHTML, _ = result.Metadata[payment.Metadata3DSecureHTML].(string)
+	}
+	if response.CompletedAt == nil && (response.Status == PaymentStatusCompleted || response.Status == PaymentStatusAuthorized) {
+		completedAt := result.ProcessedAt
+		if completedAt.IsZero() {
+			completedAt = time.Now()
+		}
+		response.CompletedAt = &completedAt
+	}
+}
+
+// paymentStatusFromGateway maps a gateway status to a payment status,
+// keeping current for statuses the gateway does not know
+func paymentStatusFromGateway(status payment.PaymentStatusType, current PaymentStatus) PaymentStatus {
+	switch status {
+	case payment.PaymentStatusPending, payment.PaymentStatusProcessing:
+		return PaymentStatusPending
+	case payment.PaymentStatusAuthorized:
+		return PaymentStatusAuthorized
+	case payment.PaymentStatusSucceeded:
+		return PaymentStatusCompleted
+	case payment.PaymentStatusFailed:
+		return PaymentStatusFailed
+	case payment.PaymentStatusCanceled:
+		return PaymentStatusCancelled
+	case payment.PaymentStatusRefunded:
+		return PaymentStatusRefunded
+	case payment.PaymentStatusPartiallyRefunded:
+		return PaymentStatusPartiallyRefunded
+	}
+	return current
+}
+
+// declineDetails returns the code and message of a gateway decline
+func declineDetails(err error) (string, string) {
+	var integrationErr *integrations.IntegrationError
+	if errors.As(err, &integrationErr) {
+		return integrationErr.Code, integrationErr.Message
+	}
+	return payment.ErrorCodeCardDeclined, err.Error()
+}
+
 // CalculatePaymentFee calculates payment processing fee
 func (s *PaymentService) CalculatePaymentFee(amount float64, method PaymentMethod) float64 {
 	switch method {
@@ -211,7 +652,7 @@ func (s *PaymentService) CalculatePaymentFee(amount float64, method PaymentMetho
 // CreatePaymentIntent creates a payment intent for frontend
 func (s *PaymentService) CreatePaymentIntent(orderID int64, amount float64) (map[string]interface{}, error) {
 	intentID := fmt.Sprintf("PI_%d_%d", orderID, time.Now().Unix())
-	
+
 	return map[string]interface{}{
 		"id":            intentID,
 		"amount":        amount,
@@ -220,4 +661,4 @@ func (s *PaymentService) CreatePaymentIntent(orderID int64, amount float64) (map
 		"client_secret": fmt.Sprintf("%s_secret", intentID),
 		"created":       time.Now().Unix(),
 	}, nil
-}
\ No newline at end of file
+}
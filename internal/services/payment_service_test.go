@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+func TestCardPaymentsFailClosedWithoutGateway(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	paymentService := NewPaymentService(repo)
+	s, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PayOrder(result.Order, testCard(payment.SandboxCardApproved)); !errors.Is(err, ErrGatewayNotConfigured) {
+		t.Fatalf("got %v, want ErrGatewayNotConfigured", err)
+	}
+	if status, paymentStatus := orderStatus(t, repo, result.Order.ID); status != models.OrderStatusCancelled || paymentStatus != "failed" {
+		t.Fatalf("order is %s/%s, want cancelled/failed", status, paymentStatus)
+	}
+	if stock := productStock(t, repo, productID); stock != 5 {
+		t.Fatalf("stock = %d, want the reservation released", stock)
+	}
+
+	// Payments settled by hand do not need a gateway
+	response, err := paymentService.ProcessPayment(&PaymentRequest{OrderID: result.Order.ID, Amount: 80, Currency: "TRY", Method: PaymentMethodBankTransfer})
+	if err != nil || response.Status != PaymentStatusPending {
+		t.Fatalf("bank transfer = %+v (err %v)", response, err)
+	}
+}
+
+func TestCheckout3DSecureWithSandbox(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      string
+		wantStatus  string
+		wantPayment string
+		wantStock   int
+	}{
+		{"challenge passed", "success", models.OrderStatusConfirmed, "paid", 3},
+		{"challenge failed", payment.Sandbox3DSecureFailed, models.OrderStatusCancelled, "failed", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newTestCheckout(t)
+			userID := seedUser(t, repo)
+			productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+			result, err := s.Checkout(checkoutRequest(userID, line(productID, 2)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := s.PayOrder(result.Order, testCard(payment.SandboxCard3DSecure))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != PaymentStatusPending || response.ThreeDSecureHTML == "" {
+				t.Fatalf("payment = %+v, want a pending 3-D Secure challenge", response)
+			}
+			if status, _ := orderStatus(t, repo, result.Order.ID); status != models.OrderStatusPending {
+				t.Fatalf("order is %s during the challenge", status)
+			}
+
+			if _, err := s.Complete3DSecure(result.Order.ID, map[string]string{"result": tt.result}); err != nil {
+				t.Fatal(err)
+			}
+			if status, paymentStatus := orderStatus(t, repo, result.Order.ID); status != tt.wantStatus || paymentStatus != tt.wantPayment {
+				t.Fatalf("order is %s/%s, want %s/%s", status, paymentStatus, tt.wantStatus, tt.wantPayment)
+			}
+			if stock := productStock(t, repo, productID); stock != tt.wantStock {
+				t.Fatalf("stock = %d, want %d", stock, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestCheckoutSettlesAsyncPaymentByWebhook(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	paymentService := NewPaymentService(repo)
+	var s *CheckoutService
+	webhookErrs := make(chan error, 10)
+	gateway := payment.NewSandboxProvider(payment.SandboxConfig{
+		WebhookHandler: func(payload *payment.WebhookPayload) { webhookErrs <- s.HandlePaymentWebhook(payload) },
+		WebhookDelay:   10 * time.Millisecond,
+		Logger:         testutil.DiscardLogger,
+	})
+	paymentService.SetGateway(gateway)
+	s, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := s.PayOrder(result.Order, testCard(payment.SandboxCardAsync))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status == PaymentStatusCompleted {
+		t.Fatalf("asynchronous payment completed immediately")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, paymentStatus := orderStatus(t, repo, result.Order.ID)
+		if status == models.OrderStatusConfirmed && paymentStatus == "paid" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("order is still %s/%s after the webhook", status, paymentStatus)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	gateway.Close()
+	close(webhookErrs)
+	for err := range webhookErrs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stock := productStock(t, repo, productID); stock != 4 {
+		t.Fatalf("stock = %d, want 4", stock)
+	}
+}
+
+func TestComplete3DSecureRefundsReleasedReservation(t *testing.T) {
+	s, repo := newTestCheckout(t)
+	userID := seedUser(t, repo)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 40, 5)
+
+	result, err := s.Checkout(checkoutRequest(userID, line(productID, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PayOrder(result.Order, testCard(payment.SandboxCard3DSecure)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reservation is released while the customer is at the bank
+	mustExec(t, repo, `UPDATE stock_reservations SET status = ? WHERE order_id = ?`, ReservationStatusReleased, result.Order.ID)
+	response, err := s.Complete3DSecure(result.Order.ID, map[string]string{"result": "success"})
+	if !errors.Is(err, ErrReservationExpired) {
+		t.Fatalf("got %v, want ErrReservationExpired", err)
+	}
+	record, err := s.paymentService.GetPaymentStatus(response.TransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RefundedAmount != response.Amount {
+		t.Fatalf("refunded %.2f of %.2f", record.RefundedAmount, response.Amount)
+	}
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"kolajAi/internal/cache"
 	"kolajAi/internal/database"
 	"kolajAi/internal/models"
 	"strconv"
@@ -11,12 +13,35 @@ import (
 
 type ProductService struct {
 	repo database.SimpleRepository
+	// cache, when set, serves product, featured product and category
+	// reads. Writes through the service invalidate what they change.
+	cache *cache.EntityCache
+	// cursors signs the cursors of product pages
+	cursors *database.CursorCodec
 }
 
 func NewProductService(repo database.SimpleRepository) *ProductService {
 	return &ProductService{repo: repo}
 }
 
+// WithContext returns a copy of the service whose repository is bound to
+// ctx, so a request reads the products it wrote itself
+func (s *ProductService) WithContext(ctx context.Context) *ProductService {
+	bound := *s
+	bound.repo = database.BindContext(s.repo, ctx)
+	return &bound
+}
+
+// SetCache sets the cache product and category reads go through
+func (s *ProductService) SetCache(c *cache.EntityCache) {
+	s.cache = c
+}
+
+// SetCursorCodec sets the codec that signs the cursors of product pages
+func (s *ProductService) SetCursorCodec(codec *database.CursorCodec) {
+	s.cursors = codec
+}
+
 // CreateProduct creates a new product
 func (s *ProductService) CreateProduct(product *models.Product) error {
 	product.CreatedAt = time.Now()
@@ -30,11 +55,22 @@ func (s *ProductService) CreateProduct(product *models.Product) error {
 		return fmt.Errorf("failed to create product: %w", err)
 	}
 	product.ID = int(id)
+	productsChanged(s.cache)
 	return nil
 }
 
 // GetProductByID retrieves a product by ID
 func (s *ProductService) GetProductByID(id int) (*models.Product, error) {
+	key := s.cache.Key(CacheEntityProduct, strconv.Itoa(id), nil)
+	tags := []string{cache.RowTag("products", int64(id))}
+	return cache.Load(s.cache, CacheEntityProduct, key, tags, func() (*models.Product, error) {
+		return s.getProductByID(id)
+	})
+}
+
+// getProductByID reads a product and its images from the database. Writes
+// that change a product they read use it rather than the cache.
+func (s *ProductService) getProductByID(id int) (*models.Product, error) {
 	var product models.Product
 	err := s.repo.FindByID("products", id, &product)
 	if err != nil {
@@ -67,6 +103,7 @@ func (s *ProductService) UpdateProduct(id int, product *models.Product) error {
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
+	productsChanged(s.cache, int64(id))
 	return nil
 }
 
@@ -76,6 +113,7 @@ func (s *ProductService) DeleteProduct(id int) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
+	productsChanged(s.cache, int64(id))
 	return nil
 }
 
@@ -120,6 +158,19 @@ func (s *ProductService) SearchProducts(term string, limit, offset int) ([]model
 
 // GetFeaturedProducts retrieves featured products
 func (s *ProductService) GetFeaturedProducts(limit, offset int) ([]models.Product, error) {
+	key := s.cache.Key(CacheEntityFeaturedProducts, "", map[string]string{
+		"limit":  strconv.Itoa(limit),
+		"offset": strconv.Itoa(offset),
+	})
+	tags := []string{cache.TableTag("products"), cache.TableTag("product_images")}
+	return cache.Load(s.cache, CacheEntityFeaturedProducts, key, tags, func() ([]models.Product, error) {
+		return s.getFeaturedProducts(limit, offset)
+	})
+}
+
+// getFeaturedProducts reads featured products and their images from the
+// database
+func (s *ProductService) getFeaturedProducts(limit, offset int) ([]models.Product, error) {
 	var products []models.Product
 	conditions := map[string]interface{}{
 		"is_featured": true,
@@ -154,7 +205,7 @@ func (s *ProductService) GetFeaturedProducts(limit, offset int) ([]models.Produc
 
 // UpdateProductStock updates product stock
 func (s *ProductService) UpdateProductStock(productID int, quantity int) error {
-	product, err := s.GetProductByID(productID)
+	product, err := s.getProductByID(productID)
 	if err != nil {
 		return err
 	}
@@ -174,7 +225,7 @@ func (s *ProductService) UpdateProductStock(productID int, quantity int) error {
 
 // IncrementProductViews increments product view count
 func (s *ProductService) IncrementProductViews(productID int) error {
-	product, err := s.GetProductByID(productID)
+	product, err := s.getProductByID(productID)
 	if err != nil {
 		return err
 	}
@@ -187,7 +238,7 @@ func (s *ProductService) IncrementProductViews(productID int) error {
 
 // IncrementProductSales increments product sales count
 func (s *ProductService) IncrementProductSales(productID int, quantity int) error {
-	product, err := s.GetProductByID(productID)
+	product, err := s.getProductByID(productID)
 	if err != nil {
 		return err
 	}
@@ -214,11 +265,19 @@ func (s *ProductService) CreateCategory(category *models.Category) error {
 		return fmt.Errorf("failed to create category: %w", err)
 	}
 	category.ID = uint(id)
+	s.cache.Invalidate(cache.TableTag("categories"))
 	return nil
 }
 
 // GetAllCategories retrieves all categories
 func (s *ProductService) GetAllCategories() ([]models.Category, error) {
+	key := s.cache.Key(CacheEntityCategories, "active", nil)
+	tags := []string{cache.TableTag("categories")}
+	return cache.Load(s.cache, CacheEntityCategories, key, tags, s.getAllCategories)
+}
+
+// getAllCategories reads the active categories from the database
+func (s *ProductService) getAllCategories() ([]models.Category, error) {
 	var categories []models.Category
 	conditions := map[string]interface{}{"is_active": true}
 
@@ -314,6 +373,7 @@ func (s *ProductService) AddProductImage(image *models.ProductImage) error {
 		return fmt.Errorf("failed to add product image: %w", err)
 	}
 	image.ID = int(id)
+	s.cache.Invalidate(cache.TableTag("product_images"), cache.RowTag("products", int64(image.ProductID)))
 	return nil
 }
 
@@ -342,6 +402,7 @@ func (s *ProductService) AddProductReview(review *models.ProductReview) error {
 		return fmt.Errorf("failed to add product review: %w", err)
 	}
 	review.ID = int(id)
+	s.cache.Invalidate(cache.TableTag("product_reviews"))
 
 	// Update product rating synchronously to avoid race conditions
 	// In production, this should be moved to a job queue
@@ -378,7 +439,7 @@ func (s *ProductService) updateProductRating(productID int) error {
 
 	if len(reviews) == 0 {
 		// If no reviews, set rating to 0
-		product, err := s.GetProductByID(productID)
+		product, err := s.getProductByID(productID)
 		if err != nil {
 			return fmt.Errorf("failed to get product: %w", err)
 		}
@@ -397,7 +458,7 @@ func (s *ProductService) updateProductRating(productID int) error {
 
 	avgRating := totalRating / float64(len(reviews))
 
-	product, err := s.GetProductByID(productID)
+	product, err := s.getProductByID(productID)
 	if err != nil {
 		return fmt.Errorf("failed to get product: %w", err)
 	}
@@ -484,46 +545,7 @@ func (s *ProductService) GetRecentProducts(limit int) ([]models.Product, error)
 // GetProductsWithFilters gets products with various filters
 func (s *ProductService) GetProductsWithFilters(filters map[string]interface{}, sortBy, sortOrder string, limit, offset int) ([]models.Product, error) {
 	var products []models.Product
-	
-	// Build conditions from filters with proper type checking
-	conditions := make(map[string]interface{})
-	
-	if category, ok := filters["category"]; ok && category != "" {
-		if categoryStr, ok := category.(string); ok && categoryStr != "" {
-			conditions["category_id"] = categoryStr
-		}
-	}
-	
-	if minPrice, ok := filters["min_price"]; ok {
-		if price, ok := minPrice.(float64); ok && price > 0 {
-			// Use proper field name without operators to avoid SQL injection
-			conditions["price_min"] = price // Repository should handle this properly
-		}
-	}
-	
-	if maxPrice, ok := filters["max_price"]; ok {
-		if price, ok := maxPrice.(float64); ok && price > 0 {
-			conditions["price_max"] = price // Repository should handle this properly
-		}
-	}
-	
-	if status, ok := filters["status"]; ok && status != "" {
-		if statusStr, ok := status.(string); ok {
-			// Validate status to prevent injection
-			for _, validStatus := range ValidProductStatuses {
-				if statusStr == validStatus {
-					conditions["status"] = statusStr
-					break
-				}
-			}
-		}
-	}
-	
-	if vendorID, ok := filters["vendor_id"]; ok {
-		if id, ok := vendorID.(int64); ok && id > 0 {
-			conditions["vendor_id"] = id
-		}
-	}
+	conditions := productFilterConditions(filters)
 	
 	// Validate and sanitize sort parameters to prevent SQL injection
 	orderBy := "created_at DESC"
@@ -566,6 +588,105 @@ func (s *ProductService) GetProductsWithFilters(filters map[string]interface{},
 	return products, nil
 }
 
+// GetProductsPage returns a keyset page of the products matching filters,
+// ordered by params.OrderBy, one of AllowedProductSortColumns, and then by
+// ID. Unlike GetProductsWithFilters, deep pages cost as much as the first.
+func (s *ProductService) GetProductsPage(filters map[string]interface{}, params database.CursorPaginationParams) ([]models.Product, database.CursorPaginationResult, error) {
+	params.Validate()
+	keyset := database.Keyset{Scope: "products", Column: "created_at", Order: database.SortDirection(params.Order)}
+	for _, column := range AllowedProductSortColumns {
+		if params.OrderBy == column {
+			keyset.Column = column
+			break
+		}
+	}
+	conditions := productFilterConditions(filters)
+
+	products, result, err := database.Paginate(cursorCodec(s.cursors), keyset, params,
+		func(cursor *database.Cursor, limit int) ([]models.Product, error) {
+			var products []models.Product
+			err := s.repo.FindAllKeyset("products", &products, conditions, keyset, cursor, limit)
+			return products, err
+		},
+		func(product models.Product) (interface{}, int64) {
+			return productSortValue(product, keyset.Column), int64(product.ID)
+		})
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to get products page: %w", err)
+	}
+
+	for i := range products {
+		products[i].CalculateDiscountPrice()
+	}
+	return products, result, nil
+}
+
+// productFilterConditions builds repository conditions from product list
+// filters with proper type checking
+func productFilterConditions(filters map[string]interface{}) map[string]interface{} {
+	conditions := make(map[string]interface{})
+	
+	if category, ok := filters["category"]; ok && category != "" {
+		if categoryStr, ok := category.(string); ok && categoryStr != "" {
+			conditions["category_id"] = categoryStr
+		}
+	}
+	
+	if minPrice, ok := filters["min_price"]; ok {
+		if price, ok := minPrice.(float64); ok && price > 0 {
+			// Use proper field name without operators to avoid SQL injection
+			conditions["price_min"] = price // Repository should handle this properly
+		}
+	}
+	
+	if maxPrice, ok := filters["max_price"]; ok {
+		if price, ok := maxPrice.(float64); ok && price > 0 {
+			conditions["price_max"] = price // Repository should handle this properly
+		}
+	}
+	
+	if status, ok := filters["status"]; ok && status != "" {
+		if statusStr, ok := status.(string); ok {
+			// Validate status to prevent injection
+			for _, validStatus := range ValidProductStatuses {
+				if statusStr == validStatus {
+					conditions["status"] = statusStr
+					break
+				}
+			}
+		}
+	}
+	
+	if vendorID, ok := filters["vendor_id"]; ok {
+		if id, ok := vendorID.(int64); ok && id > 0 {
+			conditions["vendor_id"] = id
+		}
+	}
+	return conditions
+}
+
+// productSortValue returns the value of one of AllowedProductSortColumns
+func productSortValue(product models.Product, column string) interface{} {
+	switch column {
+	case "name":
+		return product.Name
+	case "price":
+		return product.Price
+	case "created_at":
+		return product.CreatedAt
+	case "updated_at":
+		return product.UpdatedAt
+	case "rating":
+		return product.Rating
+	case "sales_count":
+		return product.SalesCount
+	case "view_count":
+		return product.ViewCount
+	default:
+		return product.ID
+	}
+}
+
 // IncrementViewCount increments the view count for a product
 func (s *ProductService) IncrementViewCount(productID int) error {
 	// Basic implementation - for now just log the action
@@ -0,0 +1,782 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+)
+
+// Repricing errors
+var (
+	ErrPriceRuleNotFound      = errors.New("price rule not found")
+	ErrInvalidPriceRule       = errors.New("invalid price rule")
+	ErrPricePromotionNotFound = errors.New("price promotion not found")
+	ErrInvalidPricePromotion  = errors.New("invalid price promotion")
+)
+
+// RepricingConfig holds repricing settings and collaborators
+type RepricingConfig struct {
+	// Integrations supplies the credentials of the marketplaces. Its
+	// Provider method is the default ProviderFactory.
+	Integrations *MarketplaceIntegrationsService
+	// ProviderFactory overrides how providers are created
+	ProviderFactory MarketplaceProviderFactory
+	// IntegrationIDs lists the channels prices are pushed to. It defaults
+	// to MarketplaceOrderIntegrations; integrations without credentials
+	// are skipped.
+	IntegrationIDs []string
+	// Analytics, when set, supplies the recommended price of rules that
+	// use it
+	Analytics *AIAnalyticsService
+	// BatchSize is the number of products priced and sent per marketplace
+	// call. It defaults to 100.
+	BatchSize int
+	// Timeout bounds each marketplace call. It defaults to two minutes.
+	Timeout time.Duration
+	Logger  *log.Logger
+}
+
+// RepricingService computes the price each sales channel is offered a
+// product at from its catalog price, the channel's price rules and running
+// promotions, and pushes the prices that changed. Every computation can be
+// previewed without sending anything.
+type RepricingService struct {
+	repo      database.SimpleRepository
+	config    RepricingConfig
+	providers MarketplaceProviderFactory
+	logger    *log.Logger
+
+	repricing sync.Mutex
+}
+
+// PriceQuote is the price computed for a product on a channel
+type PriceQuote struct {
+	IntegrationID string  `json:"integration_id"`
+	ProductID     int64   `json:"product_id"`
+	SKU           string  `json:"sku"`
+	BasePrice     float64 `json:"base_price"`
+	CostPrice     float64 `json:"cost_price"`
+	Price         float64 `json:"price"`
+	// ListPrice is the regular price shown crossed out during a promotion
+	ListPrice   float64 `json:"list_price,omitempty"`
+	RuleID      int64   `json:"rule_id,omitempty"`
+	PromotionID int64   `json:"promotion_id,omitempty"`
+	// Floored is set when the minimum margin raised the price
+	Floored bool `json:"floored"`
+	// MarginPercent is what is left over the cost price after commission
+	// and fees, when the cost price is known
+	MarginPercent float64 `json:"margin_percent"`
+}
+
+// PriceChange is a quote that differs from the price last pushed
+type PriceChange struct {
+	PriceQuote
+	PreviousPrice     float64 `json:"previous_price"`
+	PreviousListPrice float64 `json:"previous_list_price"`
+}
+
+// RepricingResult summarizes a repricing run of a channel
+type RepricingResult struct {
+	IntegrationID string        `json:"integration_id"`
+	DryRun        bool          `json:"dry_run"`
+	Products      int           `json:"products"`
+	Changes       []PriceChange `json:"changes"`
+	Pushed        int           `json:"pushed"`
+	Rejected      int           `json:"rejected"`
+	Failed        int           `json:"failed"`
+	Errors        []string      `json:"errors,omitempty"`
+}
+
+// pricedProduct is what pricing needs of a product
+type pricedProduct struct {
+	id         int64
+	vendorID   int64
+	categoryID int64
+	sku        string
+	price      float64
+	costPrice  float64
+}
+
+// NewRepricingService creates a new repricing service
+func NewRepricingService(repo database.SimpleRepository, config RepricingConfig) (*RepricingService, error) {
+	providers := config.ProviderFactory
+	if providers == nil {
+		if config.Integrations == nil {
+			return nil, ErrNoMarketplaceProvider
+		}
+		providers = config.Integrations.Provider
+	}
+	if len(config.IntegrationIDs) == 0 {
+		config.IntegrationIDs = MarketplaceOrderIntegrations
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &RepricingService{repo: repo, config: config, providers: providers, logger: logger}, nil
+}
+
+// SaveRule creates or updates a price rule. A rule for the same scope as an
+// existing one replaces it.
+func (s *RepricingService) SaveRule(rule *models.MarketplacePriceRule) error {
+	if err := validatePriceRule(rule); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	rule.UpdatedAt = now
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_price_rules
+		SET markup_percent = ?, markup_fixed = ?, commission_percent = ?, fixed_fee = ?, min_margin_percent = ?,
+			rounding = ?, use_recommended_price = ?, is_active = ?, updated_at = ?
+		WHERE integration_id = ? AND vendor_id = ? AND category_id = ? AND product_id = ?`,
+		rule.MarkupPercent, rule.MarkupFixed, rule.CommissionPercent, rule.FixedFee, rule.MinMarginPercent,
+		rule.Rounding, rule.UseRecommendedPrice, rule.IsActive, now,
+		rule.IntegrationID, rule.VendorID, rule.CategoryID, rule.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to update price rule: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return s.repo.QueryRow(`
+			SELECT id, created_at FROM marketplace_price_rules
+			WHERE integration_id = ? AND vendor_id = ? AND category_id = ? AND product_id = ?`,
+			rule.IntegrationID, rule.VendorID, rule.CategoryID, rule.ProductID).Scan(&rule.ID, &rule.CreatedAt)
+	}
+
+	rule.CreatedAt = now
+	result, err = s.repo.Exec(`
+		INSERT INTO marketplace_price_rules (integration_id, vendor_id, category_id, product_id, markup_percent,
+			markup_fixed, commission_percent, fixed_fee, min_margin_percent, rounding, use_recommended_price,
+			is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.IntegrationID, rule.VendorID, rule.CategoryID, rule.ProductID, rule.MarkupPercent,
+		rule.MarkupFixed, rule.CommissionPercent, rule.FixedFee, rule.MinMarginPercent, rule.Rounding,
+		rule.UseRecommendedPrice, rule.IsActive, rule.CreatedAt, rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create price rule: %w", err)
+	}
+	rule.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// validatePriceRule checks that a rule can produce a price
+func validatePriceRule(rule *models.MarketplacePriceRule) error {
+	switch {
+	case rule.IntegrationID == "":
+		return fmt.Errorf("%w: integration is required", ErrInvalidPriceRule)
+	case rule.MarkupPercent <= -100:
+		return fmt.Errorf("%w: markup must be above -100%%", ErrInvalidPriceRule)
+	case rule.CommissionPercent < 0 || rule.CommissionPercent >= 100:
+		return fmt.Errorf("%w: commission must be between 0 and 100%%", ErrInvalidPriceRule)
+	case rule.FixedFee < 0 || rule.MinMarginPercent < 0:
+		return fmt.Errorf("%w: fee and minimum margin cannot be negative", ErrInvalidPriceRule)
+	}
+	switch rule.Rounding {
+	case models.PriceRoundingNone, models.PriceRoundingWhole, models.PriceRoundingHalf,
+		models.PriceRounding90, models.PriceRounding99:
+	default:
+		return fmt.Errorf("%w: unknown rounding %q", ErrInvalidPriceRule, rule.Rounding)
+	}
+	return nil
+}
+
+// DeleteRule removes a price rule
+func (s *RepricingService) DeleteRule(id int64) error {
+	result, err := s.repo.Exec(`DELETE FROM marketplace_price_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete price rule: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrPriceRuleNotFound
+	}
+	return nil
+}
+
+// GetRule returns a price rule
+func (s *RepricingService) GetRule(id int64) (*models.MarketplacePriceRule, error) {
+	var r models.MarketplacePriceRule
+	err := s.repo.QueryRow(`
+		SELECT id, integration_id, vendor_id, category_id, product_id, markup_percent, markup_fixed,
+			commission_percent, fixed_fee, min_margin_percent, rounding, use_recommended_price, is_active,
+			created_at, updated_at
+		FROM marketplace_price_rules WHERE id = ?`, id).
+		Scan(&r.ID, &r.IntegrationID, &r.VendorID, &r.CategoryID, &r.ProductID, &r.MarkupPercent,
+			&r.MarkupFixed, &r.CommissionPercent, &r.FixedFee, &r.MinMarginPercent, &r.Rounding,
+			&r.UseRecommendedPrice, &r.IsActive, &r.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPriceRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price rule: %w", err)
+	}
+	return &r, nil
+}
+
+// GetRules returns the price rules of a channel, or of every channel when
+// integrationID is empty
+func (s *RepricingService) GetRules(integrationID string) ([]models.MarketplacePriceRule, error) {
+	query := `
+		SELECT id, integration_id, vendor_id, category_id, product_id, markup_percent, markup_fixed,
+			commission_percent, fixed_fee, min_margin_percent, rounding, use_recommended_price, is_active,
+			created_at, updated_at
+		FROM marketplace_price_rules`
+	var args []interface{}
+	if integrationID != "" {
+		query += ` WHERE integration_id = ?`
+		args = append(args, integrationID)
+	}
+	query += ` ORDER BY integration_id ASC, product_id ASC, category_id ASC, vendor_id ASC`
+
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.MarketplacePriceRule
+	for rows.Next() {
+		var r models.MarketplacePriceRule
+		if err := rows.Scan(&r.ID, &r.IntegrationID, &r.VendorID, &r.CategoryID, &r.ProductID, &r.MarkupPercent,
+			&r.MarkupFixed, &r.CommissionPercent, &r.FixedFee, &r.MinMarginPercent, &r.Rounding,
+			&r.UseRecommendedPrice, &r.IsActive, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// SavePromotion schedules a promotional price
+func (s *RepricingService) SavePromotion(promotion *models.MarketplacePricePromotion) error {
+	switch {
+	case promotion.ProductID <= 0:
+		return fmt.Errorf("%w: product is required", ErrInvalidPricePromotion)
+	case promotion.Price < 0 || promotion.DiscountPercent < 0 || promotion.DiscountPercent >= 100:
+		return fmt.Errorf("%w: price or discount out of range", ErrInvalidPricePromotion)
+	case promotion.Price == 0 && promotion.DiscountPercent == 0:
+		return fmt.Errorf("%w: a price or a discount is required", ErrInvalidPricePromotion)
+	case !promotion.EndsAt.After(promotion.StartsAt):
+		return fmt.Errorf("%w: promotion must end after it starts", ErrInvalidPricePromotion)
+	}
+
+	promotion.CreatedAt = time.Now().UTC()
+	result, err := s.repo.Exec(`
+		INSERT INTO marketplace_price_promotions (integration_id, product_id, price, discount_percent, starts_at, ends_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		promotion.IntegrationID, promotion.ProductID, promotion.Price, promotion.DiscountPercent,
+		promotion.StartsAt.UTC(), promotion.EndsAt.UTC(), promotion.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create price promotion: %w", err)
+	}
+	promotion.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// DeletePromotion removes a promotion. The regular price returns on the
+// next repricing run.
+func (s *RepricingService) DeletePromotion(id int64) error {
+	result, err := s.repo.Exec(`DELETE FROM marketplace_price_promotions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete price promotion: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrPricePromotionNotFound
+	}
+	return nil
+}
+
+// GetPromotions returns the promotions of a product that have not ended
+func (s *RepricingService) GetPromotions(productID int64) ([]models.MarketplacePricePromotion, error) {
+	return s.queryPromotions(`WHERE product_id = ? AND ends_at > ? ORDER BY starts_at ASC`, productID, time.Now().UTC())
+}
+
+// queryPromotions reads promotions matching a where clause
+func (s *RepricingService) queryPromotions(where string, args ...interface{}) ([]models.MarketplacePricePromotion, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, integration_id, product_id, price, discount_percent, starts_at, ends_at, created_at
+		FROM marketplace_price_promotions `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.MarketplacePricePromotion
+	for rows.Next() {
+		var p models.MarketplacePricePromotion
+		if err := rows.Scan(&p.ID, &p.IntegrationID, &p.ProductID, &p.Price, &p.DiscountPercent,
+			&p.StartsAt, &p.EndsAt, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price promotion: %w", err)
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, nil
+}
+
+// Preview computes the prices of a channel and returns those that differ
+// from what was last pushed, without sending anything. Without product IDs
+// every product is priced.
+func (s *RepricingService) Preview(integrationID string, productIDs ...int64) (*RepricingResult, error) {
+	return s.run(integrationID, true, productIDs)
+}
+
+// Reprice computes the prices of a channel and pushes those that differ
+// from what was last pushed. Without product IDs every product is priced.
+func (s *RepricingService) Reprice(integrationID string, productIDs ...int64) (*RepricingResult, error) {
+	s.repricing.Lock()
+	defer s.repricing.Unlock()
+	return s.run(integrationID, false, productIDs)
+}
+
+// RepriceAll reprices every configured channel. It runs on a schedule, so
+// promotions start and end on time and catalog price changes reach the
+// channels. A channel that fails does not stop the others; their errors are
+// joined.
+func (s *RepricingService) RepriceAll() ([]*RepricingResult, error) {
+	var results []*RepricingResult
+	var errs []error
+	for _, integrationID := range s.config.IntegrationIDs {
+		if !marketplaceConfigured(s.config.Integrations, s.config.ProviderFactory, integrationID) {
+			continue
+		}
+		result, err := s.Reprice(integrationID)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", integrationID, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Quote computes the price of one product on a channel
+func (s *RepricingService) Quote(integrationID string, productID int64) (*PriceQuote, error) {
+	products, err := s.loadProducts([]int64{productID}, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, ErrProductNotFound
+	}
+	rules, err := s.GetRules(integrationID)
+	if err != nil {
+		return nil, err
+	}
+	promotions, err := s.runningPromotions(integrationID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	quote := s.quote(integrationID, products[0], rules, promotions)
+	return &quote, nil
+}
+
+// run prices the products of a channel in batches and, unless it is a dry
+// run, pushes the changes
+func (s *RepricingService) run(integrationID string, dryRun bool, productIDs []int64) (*RepricingResult, error) {
+	result := &RepricingResult{IntegrationID: integrationID, DryRun: dryRun, Changes: []PriceChange{}}
+	rules, err := s.GetRules(integrationID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	promotions, err := s.runningPromotions(integrationID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for offset := 0; ; offset += s.config.BatchSize {
+		ids := productIDs
+		if len(productIDs) > 0 {
+			if offset >= len(productIDs) {
+				break
+			}
+			end := offset + s.config.BatchSize
+			if end > len(productIDs) {
+				end = len(productIDs)
+			}
+			ids = productIDs[offset:end]
+		}
+		products, err := s.loadProducts(ids, offset, s.config.BatchSize)
+		if err != nil {
+			return result, errors.Join(append(errs, err)...)
+		}
+		if len(products) == 0 && len(productIDs) == 0 {
+			break
+		}
+		result.Products += len(products)
+
+		changes, err := s.changes(integrationID, products, rules, promotions)
+		if err != nil {
+			return result, errors.Join(append(errs, err)...)
+		}
+		result.Changes = append(result.Changes, changes...)
+		if dryRun || len(changes) == 0 {
+			continue
+		}
+		if err := s.pushChanges(integrationID, changes, now, result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// changes prices a batch of products and returns the prices that differ
+// from what was last pushed or rejected, and those that failed to push
+func (s *RepricingService) changes(integrationID string, products []*pricedProduct, rules []models.MarketplacePriceRule, promotions map[int64]*models.MarketplacePricePromotion) ([]PriceChange, error) {
+	current, err := s.channelPrices(integrationID, products)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []PriceChange
+	for _, p := range products {
+		quote := s.quote(integrationID, p, rules, promotions)
+		if quote.Price <= 0 {
+			continue
+		}
+		previous, ok := current[p.id]
+		if ok && previous.Price == quote.Price && previous.ListPrice == quote.ListPrice {
+			continue
+		}
+		changes = append(changes, PriceChange{
+			PriceQuote:        quote,
+			PreviousPrice:     previous.Price,
+			PreviousListPrice: previous.ListPrice,
+		})
+	}
+	return changes, nil
+}
+
+// pushChanges sends price changes to a channel and records the outcome of
+// every product
+func (s *RepricingService) pushChanges(integrationID string, changes []PriceChange, at time.Time, result *RepricingResult) error {
+	updates := make([]marketplace.StockPriceUpdate, 0, len(changes))
+	for i := range changes {
+		price := changes[i].Price
+		updates = append(updates, marketplace.StockPriceUpdate{SKU: changes[i].SKU, Price: &price, ListPrice: changes[i].ListPrice})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+	provider, err := s.providers(ctx, integrationID)
+	if err != nil {
+		return err
+	}
+	rejected, pushErr := pushStockPrice(ctx, provider, updates)
+
+	var errs []error
+	for _, change := range changes {
+		var saveErr error
+		switch {
+		case rejected[change.SKU] != "":
+			// The rejected price is kept, so it is not sent again until
+			// the computed price changes
+			result.Rejected++
+			saveErr = s.saveChannelPrice(integrationID, change.ProductID, change.SKU, change.Price, change.ListPrice, rejected[change.SKU], nil, at)
+		case pushErr != nil:
+			result.Failed++
+			saveErr = s.saveChannelPrice(integrationID, change.ProductID, change.SKU, change.PreviousPrice, change.PreviousListPrice, pushErr.Error(), nil, at)
+		default:
+			result.Pushed++
+			saveErr = s.saveChannelPrice(integrationID, change.ProductID, change.SKU, change.Price, change.ListPrice, "", &at, at)
+		}
+		if saveErr != nil {
+			errs = append(errs, saveErr)
+		}
+	}
+	if pushErr != nil {
+		result.Errors = append(result.Errors, pushErr.Error())
+		errs = append(errs, pushErr)
+	}
+	return errors.Join(errs...)
+}
+
+// quote computes the price of a product on a channel from its rule and
+// running promotion
+func (s *RepricingService) quote(integrationID string, p *pricedProduct, rules []models.MarketplacePriceRule, promotions map[int64]*models.MarketplacePricePromotion) PriceQuote {
+	rule := priceRuleFor(rules, p)
+	quote := PriceQuote{
+		IntegrationID: integrationID,
+		ProductID:     p.id,
+		SKU:           p.sku,
+		BasePrice:     p.price,
+		CostPrice:     p.costPrice,
+		RuleID:        rule.ID,
+	}
+	if rule.UseRecommendedPrice && s.config.Analytics != nil {
+		strategy, err := s.config.Analytics.GeneratePricingStrategy(int(p.id))
+		if err != nil {
+			s.logger.Printf("Using the catalog price of product %d: %v", p.id, err)
+		} else if strategy.RecommendedPrice > 0 {
+			quote.BasePrice = strategy.RecommendedPrice
+		}
+	}
+
+	promotion := promotions[p.id]
+	if promotion != nil {
+		quote.PromotionID = promotion.ID
+	}
+	quote.Price, quote.ListPrice, quote.Floored = channelPrice(quote.BasePrice, p.costPrice, rule, promotion)
+	if p.costPrice > 0 && quote.Price > 0 {
+		net := quote.Price*(1-rule.CommissionPercent/100) - rule.FixedFee
+		quote.MarginPercent = math.Round((net-p.costPrice)/p.costPrice*10000) / 100
+	}
+	return quote
+}
+
+// channelPrice applies a rule and promotion to a base price. The regular
+// price is the marked up price grossed up for commission and fees; a
+// promotion lowers it and sends the regular price as the list price. No
+// price goes below the one leaving the rule's minimum margin over the cost
+// price, and rounding only ever rounds up.
+func channelPrice(base, cost float64, rule models.MarketplacePriceRule, promotion *models.MarketplacePricePromotion) (price, listPrice float64, floored bool) {
+	if base <= 0 {
+		return 0, 0, false
+	}
+	keep := 1 - rule.CommissionPercent/100
+	regular := (base*(1+rule.MarkupPercent/100) + rule.MarkupFixed + rule.FixedFee) / keep
+
+	floor := 0.0
+	if cost > 0 && rule.MinMarginPercent > 0 {
+		floor = (cost*(1+rule.MinMarginPercent/100) + rule.FixedFee) / keep
+	}
+	if regular < floor {
+		regular, floored = floor, true
+	}
+	regular = roundPrice(regular, rule.Rounding)
+
+	price = regular
+	if promotion != nil {
+		if promotion.Price > 0 {
+			price = promotion.Price
+		} else {
+			price = regular * (1 - promotion.DiscountPercent/100)
+		}
+		if price < floor {
+			price, floored = floor, true
+		}
+		price = roundPrice(price, rule.Rounding)
+		if price < regular {
+			listPrice = regular
+		} else {
+			price = regular
+		}
+	}
+	return price, listPrice, floored
+}
+
+// roundPrice rounds a price up to the rounding mode's next step
+func roundPrice(price float64, mode string) float64 {
+	// Float noise such as 100.0000001 must not round up a whole step
+	cents := math.Ceil(math.Round(price*1e6)/1e4) / 100
+	switch mode {
+	case models.PriceRoundingWhole:
+		return math.Ceil(cents)
+	case models.PriceRoundingHalf:
+		return math.Ceil(cents*2) / 2
+	case models.PriceRounding90, models.PriceRounding99:
+		ending := 0.90
+		if mode == models.PriceRounding99 {
+			ending = 0.99
+		}
+		whole := math.Floor(cents)
+		if whole+ending < cents-1e-9 {
+			whole++
+		}
+		return math.Round((whole+ending)*100) / 100
+	default:
+		return cents
+	}
+}
+
+// priceRuleFor returns the most specific active rule matching a product:
+// the product's own, its category's, its vendor's, then the channel's. A
+// product without any rule gets the zero rule, which offers it at its base
+// price.
+func priceRuleFor(rules []models.MarketplacePriceRule, p *pricedProduct) models.MarketplacePriceRule {
+	best, bestScore := models.MarketplacePriceRule{}, -1
+	for _, rule := range rules {
+		if !rule.IsActive ||
+			rule.ProductID != 0 && rule.ProductID != p.id ||
+			rule.CategoryID != 0 && rule.CategoryID != p.categoryID ||
+			rule.VendorID != 0 && rule.VendorID != p.vendorID {
+			continue
+		}
+		score := 0
+		if rule.ProductID != 0 {
+			score += 4
+		}
+		if rule.CategoryID != 0 {
+			score += 2
+		}
+		if rule.VendorID != 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best
+}
+
+// runningPromotions returns the promotion that applies to each product on a
+// channel: the channel's own before promotions of every channel, and the
+// most recently started of those
+func (s *RepricingService) runningPromotions(integrationID string, at time.Time) (map[int64]*models.MarketplacePricePromotion, error) {
+	promotions, err := s.queryPromotions(`
+		WHERE integration_id IN (?, '') AND starts_at <= ? AND ends_at > ?
+		ORDER BY starts_at ASC, id ASC`, integrationID, at, at)
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[int64]*models.MarketplacePricePromotion, len(promotions))
+	for i := range promotions {
+		p := &promotions[i]
+		if current, ok := running[p.ProductID]; ok && current.IntegrationID != "" && p.IntegrationID == "" {
+			continue
+		}
+		running[p.ProductID] = p
+	}
+	return running, nil
+}
+
+// loadProducts reads the products with the given IDs, or a page of every
+// product with a SKU ordered by ID
+func (s *RepricingService) loadProducts(productIDs []int64, offset, limit int) ([]*pricedProduct, error) {
+	query := `SELECT id, vendor_id, COALESCE(category_id, 0), sku, price, COALESCE(cost_price, 0) FROM products WHERE sku <> ''`
+	var args []interface{}
+	if len(productIDs) > 0 {
+		query += ` AND id IN (` + placeholders(len(productIDs)) + `) ORDER BY id ASC`
+		for _, id := range productIDs {
+			args = append(args, id)
+		}
+	} else {
+		query += ` ORDER BY id ASC LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+
+	rows, err := s.repo.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []*pricedProduct
+	for rows.Next() {
+		p := &pricedProduct{}
+		if err := rows.Scan(&p.id, &p.vendorID, &p.categoryID, &p.sku, &p.price, &p.costPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+// placeholders returns n comma separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// channelPrices returns the prices last pushed to a channel for products
+func (s *RepricingService) channelPrices(integrationID string, products []*pricedProduct) (map[int64]models.ChannelPrice, error) {
+	prices := make(map[int64]models.ChannelPrice, len(products))
+	if len(products) == 0 {
+		return prices, nil
+	}
+	args := []interface{}{integrationID}
+	for _, p := range products {
+		args = append(args, p.id)
+	}
+	rows, err := s.repo.Query(`
+		SELECT integration_id, product_id, sku, price, list_price, COALESCE(last_error, ''), synced_at, updated_at
+		FROM marketplace_channel_prices
+		WHERE integration_id = ? AND product_id IN (`+placeholders(len(products))+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel prices: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var price models.ChannelPrice
+		var syncedAt sql.NullTime
+		if err := rows.Scan(&price.IntegrationID, &price.ProductID, &price.SKU, &price.Price, &price.ListPrice,
+			&price.LastError, &syncedAt, &price.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan channel price: %w", err)
+		}
+		if syncedAt.Valid {
+			price.SyncedAt = &syncedAt.Time
+		}
+		prices[price.ProductID] = price
+	}
+	return prices, nil
+}
+
+// GetChannelPrices returns the prices last pushed for a product on every
+// channel
+func (s *RepricingService) GetChannelPrices(productID int64) ([]models.ChannelPrice, error) {
+	rows, err := s.repo.Query(`
+		SELECT integration_id, product_id, sku, price, list_price, COALESCE(last_error, ''), synced_at, updated_at
+		FROM marketplace_channel_prices WHERE product_id = ? ORDER BY integration_id ASC`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []models.ChannelPrice
+	for rows.Next() {
+		var price models.ChannelPrice
+		var syncedAt sql.NullTime
+		if err := rows.Scan(&price.IntegrationID, &price.ProductID, &price.SKU, &price.Price, &price.ListPrice,
+			&price.LastError, &syncedAt, &price.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan channel price: %w", err)
+		}
+		if syncedAt.Valid {
+			price.SyncedAt = &syncedAt.Time
+		}
+		prices = append(prices, price)
+	}
+	return prices, nil
+}
+
+// saveChannelPrice records the price of a product on a channel
+func (s *RepricingService) saveChannelPrice(integrationID string, productID int64, sku string, price, listPrice float64, lastError string, syncedAt *time.Time, now time.Time) error {
+	result, err := s.repo.Exec(`
+		UPDATE marketplace_channel_prices
+		SET sku = ?, price = ?, list_price = ?, last_error = ?, synced_at = COALESCE(?, synced_at), updated_at = ?
+		WHERE integration_id = ? AND product_id = ?`,
+		sku, price, listPrice, lastError, syncedAt, now, integrationID, productID)
+	if err != nil {
+		return fmt.Errorf("failed to save channel price: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+
+	_, err = s.repo.Exec(`
+		INSERT INTO marketplace_channel_prices (integration_id, product_id, sku, price, list_price, last_error, synced_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		integrationID, productID, sku, price, listPrice, lastError, syncedAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to save channel price: %w", err)
+	}
+	return nil
+}
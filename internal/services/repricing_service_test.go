@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kolajAi/internal/integrations/marketplace"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+func TestRoundPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		price float64
+		mode  string
+		want  float64
+	}{
+		{"kuruş up", 10.001, models.PriceRoundingNone, 10.01},
+		{"float noise", 100.0000001, models.PriceRoundingNone, 100},
+		{"whole", 99.01, models.PriceRoundingWhole, 100},
+		{"half below", 99.2, models.PriceRoundingHalf, 99.5},
+		{"half above", 99.6, models.PriceRoundingHalf, 100},
+		{"90 same lira", 99.5, models.PriceRounding90, 99.9},
+		{"90 next lira", 99.95, models.PriceRounding90, 100.9},
+		{"99", 10, models.PriceRounding99, 10.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roundPrice(tt.price, tt.mode); got != tt.want {
+				t.Fatalf("roundPrice(%v, %q) = %v, want %v", tt.price, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelPrice(t *testing.T) {
+	tests := []struct {
+		name        string
+		base, cost  float64
+		rule        models.MarketplacePriceRule
+		promotion   *models.MarketplacePricePromotion
+		wantPrice   float64
+		wantList    float64
+		wantFloored bool
+	}{
+		{"no rule", 100, 0, models.MarketplacePriceRule{}, nil, 100, 0, false},
+		{"markup grossed up for commission", 100, 0, models.MarketplacePriceRule{MarkupPercent: 20, CommissionPercent: 20}, nil, 150, 0, false},
+		{"fixed markup and fee", 100, 0, models.MarketplacePriceRule{MarkupFixed: 4, FixedFee: 5, CommissionPercent: 10}, nil, 121.12, 0, false},
+		{"minimum margin", 100, 90, models.MarketplacePriceRule{CommissionPercent: 10, MinMarginPercent: 20}, nil, 120, 0, true},
+		{"promotion price", 100, 0, models.MarketplacePriceRule{}, &models.MarketplacePricePromotion{Price: 80}, 80, 100, false},
+		{"promotion discount rounded", 100, 0, models.MarketplacePriceRule{Rounding: models.PriceRounding90},
+			&models.MarketplacePricePromotion{DiscountPercent: 10}, 90.9, 100.9, false},
+		{"promotion below margin", 100, 80, models.MarketplacePriceRule{MinMarginPercent: 10},
+			&models.MarketplacePricePromotion{Price: 70}, 88, 100, true},
+		{"promotion above regular", 100, 0, models.MarketplacePriceRule{}, &models.MarketplacePricePromotion{Price: 120}, 100, 0, false},
+		{"no base price", 0, 50, models.MarketplacePriceRule{MinMarginPercent: 10}, nil, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, listPrice, floored := channelPrice(tt.base, tt.cost, tt.rule, tt.promotion)
+			if price != tt.wantPrice || listPrice != tt.wantList || floored != tt.wantFloored {
+				t.Fatalf("channelPrice = %v, %v, %v; want %v, %v, %v",
+					price, listPrice, floored, tt.wantPrice, tt.wantList, tt.wantFloored)
+			}
+		})
+	}
+}
+
+func TestRepricePushesOnlyChanges(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	provider := &fakeMarketplace{}
+	s, err := NewRepricingService(repo, RepricingConfig{
+		ProviderFactory: func(ctx context.Context, integrationID string) (marketplace.MarketplaceProvider, error) {
+			return provider, nil
+		},
+		IntegrationIDs: []string{"trendyol"},
+		Logger:         testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	productID := seedProduct(t, repo, seedVendor(t, repo, 10), 100, 5)
+	if err := s.SaveRule(&models.MarketplacePriceRule{IntegrationID: "trendyol", MarkupPercent: 10, Rounding: models.PriceRoundingWhole, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.RepriceAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Pushed != 1 || provider.prices["SKU-1"] != [2]float64{110, 0} {
+		t.Fatalf("results = %+v, prices %v", results, provider.prices)
+	}
+	if result, err := s.Reprice("trendyol"); err != nil || result.Pushed != 0 || len(result.Changes) != 0 {
+		t.Fatalf("unchanged prices pushed again: %+v (err %v)", result, err)
+	}
+
+	// A preview shows the promotion without sending it
+	now := time.Now().UTC()
+	if err := s.SavePromotion(&models.MarketplacePricePromotion{ProductID: productID, DiscountPercent: 20,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	preview, err := s.Preview("trendyol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Changes) != 1 || preview.Changes[0].Price != 88 || preview.Changes[0].PreviousPrice != 110 || preview.Pushed != 0 {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if provider.prices["SKU-1"] != [2]float64{110, 0} {
+		t.Fatalf("preview sent prices: %v", provider.prices)
+	}
+
+	result, err := s.Reprice("trendyol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pushed != 1 || provider.prices["SKU-1"] != [2]float64{88, 110} {
+		t.Fatalf("result = %+v, prices %v", result, provider.prices)
+	}
+}
@@ -0,0 +1,921 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// DefaultDamagedRefundRate is the share of the price refunded for items
+// the customer damaged
+const DefaultDamagedRefundRate = 0.5
+
+// Return errors
+var (
+	ErrReturnNotFound          = errors.New("return request not found")
+	ErrReturnWindowClosed      = errors.New("return window has closed")
+	ErrOrderNotReturnable      = errors.New("order is not eligible for return")
+	ErrReturnQuantityExceeded  = errors.New("return quantity exceeds the returnable quantity")
+	ErrInvalidReturnStatus     = errors.New("return request is not in a valid status for this action")
+	ErrReturnForbidden         = errors.New("return request belongs to another user or vendor")
+	ErrNoPaymentReference      = errors.New("order has no payment reference to refund")
+	ErrReturnConditionRequired = errors.New("condition of the received item is required")
+	ErrInvalidReturn           = errors.New("invalid return request")
+)
+
+// RefundFunc refunds amount of the payment with the given transaction ID
+// and returns the refund reference
+type RefundFunc func(transactionID string, amount float64, reason string) (string, error)
+
+// PaymentServiceRefund refunds through PaymentService.RefundPayment
+func PaymentServiceRefund(ps *PaymentService) RefundFunc {
+	return func(transactionID string, amount float64, reason string) (string, error) {
+		response, err := ps.RefundPayment(transactionID, amount, reason)
+		if err != nil {
+			return "", err
+		}
+		return response.ID, nil
+	}
+}
+
+// GatewayRefund refunds through a payment gateway such as iyzico
+func GatewayRefund(provider payment.Gateway, timeout time.Duration) RefundFunc {
+	return func(transactionID string, amount float64, reason string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		response, err := provider.RefundPayment(ctx, transactionID, amount)
+		if err != nil {
+			return "", err
+		}
+		return response.ID, nil
+	}
+}
+
+// ReturnServiceConfig holds return settings and collaborators
+type ReturnServiceConfig struct {
+	// WindowDays is the number of days after delivery a return can be
+	// requested in. It defaults to models.ReturnWindowDays.
+	WindowDays int
+	// Refund issues the refunds of received returns. Without it refunds go
+	// through PaymentServiceRefund.
+	Refund RefundFunc
+	// AutoRefund refunds a return as soon as its goods are received
+	AutoRefund bool
+	// DamagedRefundRate is the share of the price refunded for items that
+	// arrive damaged although the customer returned them for a reason that
+	// is not the vendor's fault. Values outside (0, 1] fall back to
+	// DefaultDamagedRefundRate.
+	DamagedRefundRate float64
+	// Ledger, when set, debits the vendor with each refund
+	Ledger *VendorLedgerService
+	// Inventory, when set, pushes restocked items to the marketplaces
+	Inventory *InventorySyncService
+	// Cache, when set, drops the cached reads of restocked products and
+	// refunded orders
+	Cache               *cache.EntityCache
+	StateMachine        *OrderStateMachine
+	NotificationService *NotificationService
+	Logger              *log.Logger
+}
+
+// ReturnItemRequest asks for a quantity of an order item to be returned
+type ReturnItemRequest struct {
+	OrderItemID int64  `json:"order_item_id"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason"`
+}
+
+// CreateReturnRequest holds a customer's return request for an order
+type CreateReturnRequest struct {
+	OrderID int64               `json:"order_id"`
+	UserID  int64               `json:"user_id"`
+	Items   []ReturnItemRequest `json:"items"`
+	Note    string              `json:"note"`
+}
+
+// ReceivedItem records the condition of a received return item
+type ReceivedItem struct {
+	ReturnItemID int64  `json:"return_item_id"`
+	Condition    string `json:"condition"`
+}
+
+// ReturnService manages return requests (RMAs). Customers request returns
+// per order item, the vendor approves or rejects them, and received goods
+// are restocked and refunded.
+type ReturnService struct {
+	repo         database.SimpleRepository
+	stateMachine *OrderStateMachine
+	config       ReturnServiceConfig
+	logger       *log.Logger
+}
+
+// NewReturnService creates a new return service
+func NewReturnService(repo database.SimpleRepository, paymentService *PaymentService, config ReturnServiceConfig) (*ReturnService, error) {
+	if config.WindowDays <= 0 {
+		config.WindowDays = models.ReturnWindowDays
+	}
+	if config.DamagedRefundRate <= 0 || config.DamagedRefundRate > 1 {
+		config.DamagedRefundRate = DefaultDamagedRefundRate
+	}
+	if config.Refund == nil && paymentService != nil {
+		config.Refund = PaymentServiceRefund(paymentService)
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	stateMachine := config.StateMachine
+	if stateMachine == nil {
+		var err error
+		stateMachine, err = NewOrderStateMachine(repo, OrderStateMachineConfig{
+			PaymentService:      paymentService,
+			NotificationService: config.NotificationService,
+			Cache:               config.Cache,
+			Logger:              logger,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ReturnService{
+		repo:         repo,
+		stateMachine: stateMachine,
+		config:       config,
+		logger:       logger,
+	}, nil
+}
+
+// RequestReturn creates a return request for items of a delivered order.
+// All items must belong to the same vendor; the request is rejected once
+// the return window after delivery has closed.
+func (s *ReturnService) RequestReturn(req *CreateReturnRequest) (*models.ReturnRequest, error) {
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrInvalidReturn)
+	}
+
+	orderID, err := s.returnOrderID(req.OrderID, req.Items[0].OrderItemID)
+	if err != nil {
+		return nil, err
+	}
+	order, deliveredAt, err := s.loadReturnableOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != req.UserID {
+		return nil, ErrReturnForbidden
+	}
+	if time.Since(deliveredAt) > time.Duration(s.config.WindowDays)*24*time.Hour {
+		return nil, ErrReturnWindowClosed
+	}
+
+	orderItems, err := s.loadOrderItems(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	returned, err := s.returnedQuantities(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Items are refunded at what the customer paid for them: their price
+	// with the order's discount and tax applied pro rata. Shipping is not
+	// refunded.
+	goodsRatio := 1.0
+	if order.SubtotalAmount > 0 {
+		goodsRatio = (order.SubtotalAmount - order.DiscountAmount + order.TaxAmount) / order.SubtotalAmount
+	}
+
+	now := time.Now().UTC()
+	ret := &models.ReturnRequest{
+		RMANumber:    generateRMANumber(now),
+		OrderID:      order.ID,
+		UserID:       req.UserID,
+		Status:       models.ReturnStatusRequested,
+		CustomerNote: req.Note,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	requested := make(map[int64]int)
+	for _, itemReq := range req.Items {
+		item := models.ReturnItem{
+			OrderItemID: itemReq.OrderItemID,
+			Quantity:    itemReq.Quantity,
+			Reason:      strings.TrimSpace(itemReq.Reason),
+		}
+		if err := item.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidReturn, err)
+		}
+
+		orderItem, ok := orderItems[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: order item %d does not belong to order %d", ErrInvalidReturn, item.OrderItemID, order.ID)
+		}
+		if ret.VendorID == 0 {
+			ret.VendorID = orderItem.VendorID
+		} else if ret.VendorID != orderItem.VendorID {
+			return nil, fmt.Errorf("%w: a return request can only contain items of one vendor", ErrInvalidReturn)
+		}
+
+		requested[item.OrderItemID] += item.Quantity
+		if requested[item.OrderItemID]+returned[item.OrderItemID] > orderItem.Quantity {
+			return nil, fmt.Errorf("%w for order item %d", ErrReturnQuantityExceeded, item.OrderItemID)
+		}
+
+		item.ProductID = orderItem.ProductID
+		item.UnitPrice = orderItem.UnitPrice
+		item.RefundAmount = roundMoney(orderItem.UnitPrice * float64(item.Quantity) * goodsRatio)
+		ret.RefundAmount += item.RefundAmount
+		if ret.Reason == "" {
+			ret.Reason = item.Reason
+		}
+		ret.Items = append(ret.Items, item)
+	}
+	ret.RefundAmount = roundMoney(ret.RefundAmount)
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin return request: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		INSERT INTO return_requests (rma_number, order_id, user_id, vendor_id, status, reason,
+			customer_note, refund_amount, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ret.RMANumber, ret.OrderID, ret.UserID, ret.VendorID, ret.Status, ret.Reason,
+		ret.CustomerNote, ret.RefundAmount, ret.CreatedAt, ret.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create return request: %w", err)
+	}
+	if ret.ID, err = result.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to get return request ID: %w", err)
+	}
+
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.ReturnID = ret.ID
+		result, err := tx.Exec(`
+			INSERT INTO return_items (return_id, order_item_id, product_id, quantity, unit_price, refund_amount, reason, restocked)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.ReturnID, item.OrderItemID, item.ProductID, item.Quantity, item.UnitPrice, item.RefundAmount, item.Reason, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add return item: %w", err)
+		}
+		item.ID, _ = result.LastInsertId()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit return request: %w", err)
+	}
+	committed = true
+
+	s.stateMachine.recordNote(order.ID, order.Status, fmt.Sprintf("return %s requested", ret.RMANumber))
+	return ret, nil
+}
+
+// ApproveReturn accepts a return request and creates the return shipment
+// the customer sends the goods back with
+func (s *ReturnService) ApproveReturn(returnID, vendorID int64, note string) (*models.ReturnRequest, error) {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.VendorID != vendorID {
+		return nil, ErrReturnForbidden
+	}
+	if ret.Status != models.ReturnStatusRequested {
+		return nil, ErrInvalidReturnStatus
+	}
+
+	shipment, err := s.buildReturnShipment(ret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin return approval: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if err := updateReturnStatus(tx, ret.ID, models.ReturnStatusRequested, models.ReturnStatusApproved,
+		"vendor_note = ?, approved_at = ?", note, now); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO shipments (order_id, customer_id, from_address, to_address, status, package_count,
+			shipping_cost, total_cost, currency, special_instructions, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		shipment.OrderID, shipment.CustomerID, shipment.FromAddress, shipment.ToAddress, shipment.Status,
+		shipment.PackageCount, shipment.ShippingCost, shipment.TotalCost, shipment.Currency,
+		shipment.SpecialInstructions, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create return shipment: %w", err)
+	}
+	shipmentID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return shipment ID: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE return_requests SET shipment_id = ? WHERE id = ?`, shipmentID, ret.ID); err != nil {
+		return nil, fmt.Errorf("failed to link return shipment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit return approval: %w", err)
+	}
+	committed = true
+
+	ret.Status = models.ReturnStatusApproved
+	ret.VendorNote = note
+	ret.ShipmentID = shipmentID
+	ret.ApprovedAt = &now
+	ret.UpdatedAt = now
+	s.notify(ret)
+	return ret, nil
+}
+
+// RejectReturn declines a return request
+func (s *ReturnService) RejectReturn(returnID, vendorID int64, note string) (*models.ReturnRequest, error) {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.VendorID != vendorID {
+		return nil, ErrReturnForbidden
+	}
+	if strings.TrimSpace(note) == "" {
+		return nil, fmt.Errorf("%w: a reason is required to reject a return", ErrInvalidReturn)
+	}
+
+	if err := updateReturnStatus(s.repo, ret.ID, models.ReturnStatusRequested, models.ReturnStatusRejected,
+		"vendor_note = ?", note); err != nil {
+		return nil, err
+	}
+
+	ret.Status = models.ReturnStatusRejected
+	ret.VendorNote = note
+	ret.UpdatedAt = time.Now().UTC()
+	s.notify(ret)
+	return ret, nil
+}
+
+// CancelReturn withdraws a return request before the goods are shipped
+func (s *ReturnService) CancelReturn(returnID, userID int64) error {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return err
+	}
+	if ret.UserID != userID {
+		return ErrReturnForbidden
+	}
+	if !ret.CanBeCancelled() {
+		return ErrInvalidReturnStatus
+	}
+
+	now := time.Now().UTC()
+	if err := updateReturnStatus(s.repo, ret.ID, ret.Status, models.ReturnStatusCancelled, ""); err != nil {
+		return err
+	}
+	if ret.ShipmentID > 0 {
+		if _, err := s.repo.Exec(`UPDATE shipments SET status = ?, cancelled_at = ?, updated_at = ? WHERE id = ?`,
+			models.ShipmentStatusCancelled, now, now, ret.ShipmentID); err != nil {
+			s.logger.Printf("Failed to cancel return shipment %d: %v", ret.ShipmentID, err)
+		}
+	}
+
+	return nil
+}
+
+// ShipReturn records that the customer handed the goods to the carrier
+func (s *ReturnService) ShipReturn(returnID, userID int64, trackingNumber string) error {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return err
+	}
+	if ret.UserID != userID {
+		return ErrReturnForbidden
+	}
+	if strings.TrimSpace(trackingNumber) == "" {
+		return fmt.Errorf("%w: tracking number is required", ErrInvalidReturn)
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin return shipment: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateReturnStatus(tx, ret.ID, models.ReturnStatusApproved, models.ReturnStatusInTransit,
+		"tracking_number = ?", trackingNumber); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE shipments SET status = ?, tracking_number = ?, shipped_at = ?, updated_at = ? WHERE id = ?`,
+		models.ShipmentStatusInTransit, trackingNumber, now, now, ret.ShipmentID); err != nil {
+		return fmt.Errorf("failed to update return shipment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit return shipment: %w", err)
+	}
+	return nil
+}
+
+// ReceiveReturn records the arrival of the returned goods. The vendor must
+// report the condition of every item. Items received in resellable
+// condition are put back into stock, missing items are not refunded and
+// damaged items are refunded according to damagedRefund. With AutoRefund
+// set the refund is issued right away.
+func (s *ReturnService) ReceiveReturn(returnID, vendorID int64, received []ReceivedItem) (*models.ReturnRequest, error) {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.VendorID != vendorID {
+		return nil, ErrReturnForbidden
+	}
+	if ret.Status != models.ReturnStatusApproved && ret.Status != models.ReturnStatusInTransit {
+		return nil, ErrInvalidReturnStatus
+	}
+
+	conditions := make(map[int64]string, len(received))
+	for _, r := range received {
+		switch r.Condition {
+		case models.ReturnConditionResellable, models.ReturnConditionDamaged, models.ReturnConditionMissing:
+			conditions[r.ReturnItemID] = r.Condition
+		default:
+			return nil, fmt.Errorf("%w: invalid condition %q for return item %d", ErrInvalidReturn, r.Condition, r.ReturnItemID)
+		}
+	}
+	for _, item := range ret.Items {
+		if _, ok := conditions[item.ID]; !ok {
+			return nil, fmt.Errorf("%w: return item %d", ErrReturnConditionRequired, item.ID)
+		}
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin return receipt: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if err := updateReturnStatus(tx, ret.ID, ret.Status, models.ReturnStatusReceived, "received_at = ?", now); err != nil {
+		return nil, err
+	}
+
+	refund := 0.0
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.Condition = conditions[item.ID]
+		switch item.Condition {
+		case models.ReturnConditionMissing:
+			item.RefundAmount = 0
+		case models.ReturnConditionDamaged:
+			item.RefundAmount = s.damagedRefund(item)
+		}
+		item.Restocked = item.Condition == models.ReturnConditionResellable
+		refund += item.RefundAmount
+
+		if _, err := tx.Exec(`UPDATE return_items SET item_condition = ?, refund_amount = ?, restocked = ? WHERE id = ?`,
+			item.Condition, item.RefundAmount, item.Restocked, item.ID); err != nil {
+			return nil, fmt.Errorf("failed to update return item: %w", err)
+		}
+		if item.Restocked {
+			if err := restockProduct(tx, item.ProductID, item.Quantity, item.Quantity, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ret.RefundAmount = roundMoney(refund)
+
+	if _, err := tx.Exec(`UPDATE return_requests SET refund_amount = ? WHERE id = ?`, ret.RefundAmount, ret.ID); err != nil {
+		return nil, fmt.Errorf("failed to update return refund amount: %w", err)
+	}
+	if ret.ShipmentID > 0 {
+		if _, err := tx.Exec(`UPDATE shipments SET status = ?, delivered_at = ?, updated_at = ? WHERE id = ?`,
+			models.ShipmentStatusDelivered, now, now, ret.ShipmentID); err != nil {
+			return nil, fmt.Errorf("failed to update return shipment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit return receipt: %w", err)
+	}
+	committed = true
+
+	ret.Status = models.ReturnStatusReceived
+	ret.ReceivedAt = &now
+	ret.UpdatedAt = now
+
+	var productIDs []int64
+	for _, item := range ret.Items {
+		if item.Restocked {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	if len(productIDs) > 0 {
+		productsChanged(s.config.Cache, productIDs...)
+	}
+	if s.config.Inventory != nil {
+		s.config.Inventory.StockChanged(StockChangeReturn, productIDs...)
+	}
+
+	if s.config.AutoRefund {
+		return s.RefundReturn(ret.ID)
+	}
+	return ret, nil
+}
+
+// RefundReturn refunds a received return. The order's payment status
+// becomes partial, or refunded once the returns cover the whole order.
+func (s *ReturnService) RefundReturn(returnID int64) (*models.ReturnRequest, error) {
+	ret, err := s.GetReturn(returnID)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.Refund == nil {
+		return nil, errors.New("no refund method configured")
+	}
+
+	order, err := s.stateMachine.loadOrder(ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.RefundAmount > 0 && order.ReferenceID == "" {
+		return nil, ErrNoPaymentReference
+	}
+
+	// Claim the return first so a concurrent call cannot refund it twice
+	if err := updateReturnStatus(s.repo, ret.ID, models.ReturnStatusReceived, models.ReturnStatusRefunding, ""); err != nil {
+		return nil, err
+	}
+
+	reference := ""
+	if ret.RefundAmount > 0 {
+		reference, err = s.config.Refund(order.ReferenceID, ret.RefundAmount, "return "+ret.RMANumber)
+		if err != nil {
+			if resetErr := updateReturnStatus(s.repo, ret.ID, models.ReturnStatusRefunding, models.ReturnStatusReceived, ""); resetErr != nil {
+				s.logger.Printf("Failed to reset return %d after refund failure: %v", ret.ID, resetErr)
+			}
+			return nil, fmt.Errorf("failed to refund return %s: %w", ret.RMANumber, err)
+		}
+	}
+
+	now := time.Now().UTC()
+	if err := updateReturnStatus(s.repo, ret.ID, models.ReturnStatusRefunding, models.ReturnStatusRefunded,
+		"refund_reference = ?, refunded_at = ?", reference, now); err != nil {
+		return nil, err
+	}
+
+	refunded, err := returnRefundedAmount(s.repo, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	paymentStatus := "partial"
+	if refunded >= order.TotalAmount-0.005 {
+		paymentStatus = "refunded"
+	}
+	if _, err := s.repo.Exec(`UPDATE orders SET payment_status = ?, updated_at = ? WHERE id = ?`,
+		paymentStatus, now, order.ID); err != nil {
+		return nil, fmt.Errorf("failed to update payment status: %w", err)
+	}
+	ordersChanged(s.config.Cache)
+	s.stateMachine.recordNote(order.ID, order.Status,
+		fmt.Sprintf("return %s refunded: %.2f %s", ret.RMANumber, ret.RefundAmount, order.Currency))
+	if s.config.Ledger != nil && ret.RefundAmount > 0 {
+		if err := s.config.Ledger.RecordRefund(ret.VendorID, order.ID, ret.RefundAmount, "return "+ret.RMANumber); err != nil {
+			s.logger.Printf("Failed to record refund of return %s in vendor ledger: %v", ret.RMANumber, err)
+		}
+	}
+
+	ret.Status = models.ReturnStatusRefunded
+	ret.RefundReference = reference
+	ret.RefundedAt = &now
+	ret.UpdatedAt = now
+	s.notify(ret)
+	return ret, nil
+}
+
+// GetReturn returns a return request with its items
+func (s *ReturnService) GetReturn(returnID int64) (*models.ReturnRequest, error) {
+	rets, err := s.queryReturns(`WHERE id = ?`, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rets) == 0 {
+		return nil, ErrReturnNotFound
+	}
+
+	ret := &rets[0]
+	if ret.Items, err = s.loadReturnItems(ret.ID); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetOrderReturns returns the return requests of an order
+func (s *ReturnService) GetOrderReturns(orderID int64) ([]models.ReturnRequest, error) {
+	return s.queryReturns(`WHERE order_id = ? ORDER BY id ASC`, orderID)
+}
+
+// GetUserReturns returns the return requests of a customer, newest first
+func (s *ReturnService) GetUserReturns(userID int64, limit, offset int) ([]models.ReturnRequest, error) {
+	return s.queryReturns(`WHERE user_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, userID, limit, offset)
+}
+
+// GetVendorReturns returns the return requests of a vendor, optionally
+// filtered by status, newest first
+func (s *ReturnService) GetVendorReturns(vendorID int64, status string, limit, offset int) ([]models.ReturnRequest, error) {
+	if status == "" {
+		return s.queryReturns(`WHERE vendor_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, vendorID, limit, offset)
+	}
+	return s.queryReturns(`WHERE vendor_id = ? AND status = ? ORDER BY id DESC LIMIT ? OFFSET ?`, vendorID, status, limit, offset)
+}
+
+// queryReturns reads return requests without their items
+func (s *ReturnService) queryReturns(where string, args ...interface{}) ([]models.ReturnRequest, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, rma_number, order_id, user_id, vendor_id, status, reason,
+			COALESCE(customer_note, ''), COALESCE(vendor_note, ''), refund_amount, COALESCE(refund_reference, ''),
+			shipment_id, COALESCE(tracking_number, ''), approved_at, received_at, refunded_at, created_at, updated_at
+		FROM return_requests `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return requests: %w", err)
+	}
+	defer rows.Close()
+
+	var rets []models.ReturnRequest
+	for rows.Next() {
+		var r models.ReturnRequest
+		var approvedAt, receivedAt, refundedAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.RMANumber, &r.OrderID, &r.UserID, &r.VendorID, &r.Status, &r.Reason,
+			&r.CustomerNote, &r.VendorNote, &r.RefundAmount, &r.RefundReference,
+			&r.ShipmentID, &r.TrackingNumber, &approvedAt, &receivedAt, &refundedAt, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan return request: %w", err)
+		}
+		r.ApprovedAt = nullTimePtr(approvedAt)
+		r.ReceivedAt = nullTimePtr(receivedAt)
+		r.RefundedAt = nullTimePtr(refundedAt)
+		rets = append(rets, r)
+	}
+
+	return rets, nil
+}
+
+// loadReturnItems reads the items of a return request
+func (s *ReturnService) loadReturnItems(returnID int64) ([]models.ReturnItem, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, return_id, order_item_id, product_id, quantity, unit_price, refund_amount, reason,
+			COALESCE(item_condition, ''), restocked
+		FROM return_items WHERE return_id = ? ORDER BY id ASC`, returnID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.ReturnItem
+	for rows.Next() {
+		var item models.ReturnItem
+		if err := rows.Scan(&item.ID, &item.ReturnID, &item.OrderItemID, &item.ProductID, &item.Quantity,
+			&item.UnitPrice, &item.RefundAmount, &item.Reason, &item.Condition, &item.Restocked); err != nil {
+			return nil, fmt.Errorf("failed to scan return item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// damagedRefund returns the refund for an item that arrived damaged. Items
+// returned as defective, damaged in shipping, wrong or not as described
+// were damaged before the customer had them and are refunded in full;
+// otherwise only DamagedRefundRate of the price is refunded.
+func (s *ReturnService) damagedRefund(item *models.ReturnItem) float64 {
+	switch item.Reason {
+	case models.ReturnReasonDefective, models.ReturnReasonDamaged,
+		models.ReturnReasonWrongItem, models.ReturnReasonNotAsDescribed:
+		return item.RefundAmount
+	}
+	return roundMoney(item.RefundAmount * s.config.DamagedRefundRate)
+}
+
+// returnOrderID resolves the order a return is booked against. The items
+// of a multi-vendor order are held by its per-vendor sub-orders, so a
+// return requested on the parent order goes to the sub-order of the item.
+func (s *ReturnService) returnOrderID(orderID, orderItemID int64) (int64, error) {
+	var itemOrderID int64
+	err := s.repo.QueryRow(`
+		SELECT oi.order_id FROM order_items oi JOIN orders o ON o.id = oi.order_id
+		WHERE oi.id = ? AND (o.id = ? OR o.parent_order_id = ?)`,
+		orderItemID, orderID, orderID).Scan(&itemOrderID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: order item %d does not belong to order %d", ErrInvalidReturn, orderItemID, orderID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order of item %d: %w", orderItemID, err)
+	}
+	return itemOrderID, nil
+}
+
+// loadReturnableOrder reads a delivered order and its delivery time
+func (s *ReturnService) loadReturnableOrder(orderID int64) (*models.Order, time.Time, error) {
+	var o models.Order
+	var deliveredAt sql.NullTime
+	err := s.repo.QueryRow(`
+		SELECT id, user_id, status, payment_status, COALESCE(subtotal, 0), COALESCE(discount_amount, 0),
+			COALESCE(tax_amount, 0), total_amount, delivered_at
+		FROM orders WHERE id = ?`, orderID).
+		Scan(&o.ID, &o.UserID, &o.Status, &o.PaymentStatus, &o.SubtotalAmount, &o.DiscountAmount,
+			&o.TaxAmount, &o.TotalAmount, &deliveredAt)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get order %d: %w", orderID, err)
+	}
+	if o.Status != models.OrderStatusDelivered || !deliveredAt.Valid {
+		return nil, time.Time{}, ErrOrderNotReturnable
+	}
+	if o.PaymentStatus != "paid" && o.PaymentStatus != "partial" {
+		return nil, time.Time{}, ErrOrderNotReturnable
+	}
+	return &o, deliveredAt.Time, nil
+}
+
+// loadOrderItems reads the items of an order keyed by item ID
+func (s *ReturnService) loadOrderItems(orderID int64) (map[int64]models.OrderItem, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, product_id, vendor_id, quantity, unit_price
+		FROM order_items WHERE order_id = ?`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[int64]models.OrderItem)
+	for rows.Next() {
+		item := models.OrderItem{OrderID: orderID}
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.VendorID, &item.Quantity, &item.UnitPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		items[item.ID] = item
+	}
+
+	return items, nil
+}
+
+// returnedQuantities sums the quantities per order item that are already
+// covered by open or refunded returns
+func (s *ReturnService) returnedQuantities(orderID int64) (map[int64]int, error) {
+	rows, err := s.repo.Query(`
+		SELECT ri.order_item_id, SUM(ri.quantity)
+		FROM return_items ri JOIN return_requests rr ON rr.id = ri.return_id
+		WHERE rr.order_id = ? AND rr.status NOT IN (?, ?)
+		GROUP BY ri.order_item_id`,
+		orderID, models.ReturnStatusRejected, models.ReturnStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get returned quantities: %w", err)
+	}
+	defer rows.Close()
+
+	quantities := make(map[int64]int)
+	for rows.Next() {
+		var itemID int64
+		var quantity int
+		if err := rows.Scan(&itemID, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan returned quantity: %w", err)
+		}
+		quantities[itemID] = quantity
+	}
+
+	return quantities, nil
+}
+
+// buildReturnShipment prepares the shipment from the customer's shipping
+// address back to the vendor
+func (s *ReturnService) buildReturnShipment(ret *models.ReturnRequest) (*models.Shipment, error) {
+	var from, to models.ShippingAddress
+	err := s.repo.QueryRow(`
+		SELECT COALESCE(shipping_address, ''), COALESCE(shipping_city, ''), COALESCE(shipping_state, ''),
+			COALESCE(shipping_zip, ''), COALESCE(shipping_country, ''), COALESCE(shipping_phone, '')
+		FROM orders WHERE id = ?`, ret.OrderID).
+		Scan(&from.AddressLine1, &from.City, &from.State, &from.PostalCode, &from.Country, &from.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order address: %w", err)
+	}
+
+	err = s.repo.QueryRow(`
+		SELECT COALESCE(company_name, ''), COALESCE(address, ''), COALESCE(city, ''),
+			COALESCE(country, ''), COALESCE(phone, '')
+		FROM vendors WHERE id = ?`, ret.VendorID).
+		Scan(&to.CompanyName, &to.AddressLine1, &to.City, &to.Country, &to.Phone)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get vendor address: %w", err)
+	}
+
+	return &models.Shipment{
+		OrderID:             uint(ret.OrderID),
+		CustomerID:          uint(ret.UserID),
+		FromAddress:         from,
+		ToAddress:           to,
+		Status:              models.ShipmentStatusPending,
+		PackageCount:        1,
+		Currency:            "TRY",
+		SpecialInstructions: "Return " + ret.RMANumber,
+	}, nil
+}
+
+// notify tells the customer about the new status of their return
+func (s *ReturnService) notify(ret *models.ReturnRequest) {
+	if s.config.NotificationService == nil {
+		return
+	}
+	err := s.config.NotificationService.SendTransactionalNotification("return_"+ret.Status, uint(ret.UserID), map[string]interface{}{
+		"rma_number":    ret.RMANumber,
+		"order_id":      ret.OrderID,
+		"refund_amount": ret.RefundAmount,
+		"vendor_note":   ret.VendorNote,
+	})
+	if err != nil {
+		s.logger.Printf("Failed to send return notification for %s: %v", ret.RMANumber, err)
+	}
+}
+
+// returnExecer is implemented by both the repository and transactions
+type returnExecer interface {
+	Exec(query string, args ...interface{}) (database.Result, error)
+}
+
+// updateReturnStatus moves a return from one status to another, setting
+// extra columns given as a SET fragment. It fails with
+// ErrInvalidReturnStatus if the return is no longer in the from status.
+func updateReturnStatus(db returnExecer, returnID int64, from, to, sets string, args ...interface{}) error {
+	query := `UPDATE return_requests SET status = ?, updated_at = ?`
+	if sets != "" {
+		query += ", " + sets
+	}
+	query += ` WHERE id = ? AND status = ?`
+
+	params := append([]interface{}{to, time.Now().UTC()}, args...)
+	params = append(params, returnID, from)
+	result, err := db.Exec(query, params...)
+	if err != nil {
+		return fmt.Errorf("failed to update return status: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrInvalidReturnStatus
+	}
+	return nil
+}
+
+// returnRefundedAmount sums the refunds issued for returns of an order
+func returnRefundedAmount(repo database.SimpleRepository, orderID int64) (float64, error) {
+	var amount float64
+	err := repo.QueryRow(`SELECT COALESCE(SUM(refund_amount), 0) FROM return_requests WHERE order_id = ? AND status = ?`,
+		orderID, models.ReturnStatusRefunded).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get refunded return amount: %w", err)
+	}
+	return amount, nil
+}
+
+// generateRMANumber creates a unique return merchandise authorization number
+func generateRMANumber(now time.Time) string {
+	return fmt.Sprintf("RMA-%s-%s", now.Format("20060102"), strings.ToUpper(uuid.New().String()[:8]))
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// newTestReturns returns a return service that records its refunds in
+// refunds instead of sending them to a gateway
+func newTestReturns(t *testing.T, refunds *[]float64) (*ReturnService, database.SimpleRepository) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	s, err := NewReturnService(repo, nil, ReturnServiceConfig{
+		Refund: func(transactionID string, amount float64, reason string) (string, error) {
+			*refunds = append(*refunds, amount)
+			return "RF-" + transactionID, nil
+		},
+		Logger: testutil.DiscardLogger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, repo
+}
+
+// deliveredOrder seeds a paid order delivered yesterday with two units of
+// each product at 50 and returns its ID and its item IDs
+func deliveredOrder(t *testing.T, repo database.SimpleRepository, userID int64, productIDs ...int64) (int64, []int64) {
+	t.Helper()
+	orderID := seedOrder(t, repo, userID, models.OrderStatusDelivered, "paid", 0, productIDs...)
+	subtotal := 100 * float64(len(productIDs))
+	mustExec(t, repo, `UPDATE orders SET subtotal = ?, total_amount = ?, reference_id = 'TXN-1', delivered_at = ? WHERE id = ?`,
+		subtotal, subtotal, time.Now().UTC().Add(-24*time.Hour), orderID)
+
+	rows, err := repo.Query(`SELECT id FROM order_items WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var itemIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		itemIDs = append(itemIDs, id)
+	}
+	return orderID, itemIDs
+}
+
+func TestReceiveReturnRequiresEveryCondition(t *testing.T) {
+	var refunds []float64
+	s, repo := newTestReturns(t, &refunds)
+	userID := seedUser(t, repo)
+	vendorID := seedVendor(t, repo, 0)
+	first := seedProduct(t, repo, vendorID, 50, 0)
+	second := seedProduct(t, repo, vendorID, 50, 0)
+	orderID, itemIDs := deliveredOrder(t, repo, userID, first, second)
+
+	ret, err := s.RequestReturn(&CreateReturnRequest{OrderID: orderID, UserID: userID, Items: []ReturnItemRequest{
+		{OrderItemID: itemIDs[0], Quantity: 2, Reason: models.ReturnReasonChangedMind},
+		{OrderItemID: itemIDs[1], Quantity: 2, Reason: models.ReturnReasonChangedMind},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ApproveReturn(ret.ID, vendorID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.ReceiveReturn(ret.ID, vendorID, []ReceivedItem{
+		{ReturnItemID: ret.Items[0].ID, Condition: models.ReturnConditionResellable},
+	})
+	if !errors.Is(err, ErrReturnConditionRequired) {
+		t.Fatalf("got %v, want ErrReturnConditionRequired", err)
+	}
+	got, err := s.GetReturn(ret.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.ReturnStatusApproved || productStock(t, repo, first) != 0 {
+		t.Fatalf("incomplete receipt was applied: status %s, stock %d", got.Status, productStock(t, repo, first))
+	}
+}
+
+func TestReceiveReturnRefundPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		reason     string
+		condition  string
+		wantRefund float64
+		wantStock  int
+	}{
+		{"resellable", models.ReturnReasonChangedMind, models.ReturnConditionResellable, 100, 2},
+		{"damaged by the customer", models.ReturnReasonChangedMind, models.ReturnConditionDamaged, 50, 0},
+		{"arrived defective", models.ReturnReasonDefective, models.ReturnConditionDamaged, 100, 0},
+		{"damaged in shipping", models.ReturnReasonDamaged, models.ReturnConditionDamaged, 100, 0},
+		{"missing", models.ReturnReasonWrongItem, models.ReturnConditionMissing, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refunds []float64
+			s, repo := newTestReturns(t, &refunds)
+			userID := seedUser(t, repo)
+			vendorID := seedVendor(t, repo, 0)
+			productID := seedProduct(t, repo, vendorID, 50, 0)
+			orderID, itemIDs := deliveredOrder(t, repo, userID, productID)
+
+			ret, err := s.RequestReturn(&CreateReturnRequest{OrderID: orderID, UserID: userID, Items: []ReturnItemRequest{
+				{OrderItemID: itemIDs[0], Quantity: 2, Reason: tt.reason},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ret.RefundAmount != 100 {
+				t.Fatalf("requested refund = %.2f, want 100", ret.RefundAmount)
+			}
+			if _, err := s.ApproveReturn(ret.ID, vendorID, ""); err != nil {
+				t.Fatal(err)
+			}
+			received, err := s.ReceiveReturn(ret.ID, vendorID, []ReceivedItem{
+				{ReturnItemID: ret.Items[0].ID, Condition: tt.condition},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if received.RefundAmount != tt.wantRefund {
+				t.Fatalf("refund = %.2f, want %.2f", received.RefundAmount, tt.wantRefund)
+			}
+			if stock := productStock(t, repo, productID); stock != tt.wantStock {
+				t.Fatalf("stock = %d, want %d", stock, tt.wantStock)
+			}
+
+			if _, err := s.RefundReturn(ret.ID); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRefund > 0 && (len(refunds) != 1 || refunds[0] != tt.wantRefund) {
+				t.Fatalf("refunds = %v, want [%.2f]", refunds, tt.wantRefund)
+			}
+			if tt.wantRefund == 0 && len(refunds) != 0 {
+				t.Fatalf("refunds = %v for a missing item", refunds)
+			}
+		})
+	}
+}
+
+func TestRequestReturnOnMultiVendorOrder(t *testing.T) {
+	var refunds []float64
+	s, repo := newTestReturns(t, &refunds)
+	userID := seedUser(t, repo)
+	firstVendor := seedVendor(t, repo, 0)
+	secondVendor := seedVendor(t, repo, 0)
+	firstOrder, firstItems := deliveredOrder(t, repo, userID, seedProduct(t, repo, firstVendor, 50, 0))
+	secondOrder, secondItems := deliveredOrder(t, repo, userID, seedProduct(t, repo, secondVendor, 50, 0))
+	parentID := seedOrder(t, repo, userID, models.OrderStatusDelivered, "paid", 0)
+	mustExec(t, repo, `UPDATE orders SET parent_order_id = ? WHERE id IN (?, ?)`, parentID, firstOrder, secondOrder)
+
+	ret, err := s.RequestReturn(&CreateReturnRequest{OrderID: parentID, UserID: userID, Items: []ReturnItemRequest{
+		{OrderItemID: secondItems[0], Quantity: 1, Reason: models.ReturnReasonChangedMind},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.OrderID != secondOrder || ret.VendorID != secondVendor || ret.RefundAmount != 50 {
+		t.Fatalf("return booked on order %d of vendor %d for %.2f, want order %d of vendor %d for 50",
+			ret.OrderID, ret.VendorID, ret.RefundAmount, secondOrder, secondVendor)
+	}
+
+	_, err = s.RequestReturn(&CreateReturnRequest{OrderID: parentID, UserID: userID, Items: []ReturnItemRequest{
+		{OrderItemID: firstItems[0], Quantity: 1, Reason: models.ReturnReasonChangedMind},
+		{OrderItemID: secondItems[0], Quantity: 1, Reason: models.ReturnReasonChangedMind},
+	}})
+	if err == nil {
+		t.Fatal("return across two sub-orders was accepted")
+	}
+	if _, err := s.RequestReturn(&CreateReturnRequest{OrderID: secondOrder, UserID: userID, Items: []ReturnItemRequest{
+		{OrderItemID: firstItems[0], Quantity: 1, Reason: models.ReturnReasonChangedMind},
+	}}); err == nil {
+		t.Fatal("return of an item of a sibling sub-order was accepted")
+	}
+}
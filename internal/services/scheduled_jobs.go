@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"kolajAi/internal/jobs"
+	"kolajAi/internal/reporting"
+	"kolajAi/internal/session"
+)
+
+// Job types fired by the built-in recurring schedules
+const (
+	JobTypeProcessExpiredAuctions        = "auctions.process_expired"
+	JobTypeProcessScheduledNotifications = "notifications.process_scheduled"
+	JobTypeExecuteScheduledReport        = "reports.execute_scheduled"
+	JobTypeSyncReportSchedules           = "reports.sync_schedules"
+	JobTypeCleanupSessions               = "sessions.cleanup"
+	JobTypeReleaseExpiredReservations    = "checkout.release_expired_reservations"
+	JobTypeRetryOrderRefunds             = "orders.retry_refunds"
+	JobTypeGenerateVendorPayouts         = "vendors.generate_payouts"
+	JobTypeExpireWholesaleQuotes         = "wholesale.expire_quotes"
+	JobTypeMarkOverdueWholesaleOrders    = "wholesale.mark_overdue"
+	JobTypeReconcilePayments             = "payments.reconcile"
+	JobTypeImportMarketplaceOrders       = "marketplace.import_orders"
+	JobTypeSyncInventory                 = "inventory.sync"
+	JobTypeRefreshMarketplaceCatalogs    = "marketplace.refresh_catalogs"
+	JobTypeRepriceMarketplaces           = "marketplace.reprice"
+	JobTypePollMarketplaceSync           = "marketplace.poll_sync"
+	// JobTypeProcessWebhooks is also submitted for each received webhook,
+	// with the integration_id whose events to process
+	JobTypeProcessWebhooks = "webhooks.process"
+)
+
+// ScheduledJobsConfig holds the services whose periodic work is driven by
+// the job scheduler. Nil services are skipped.
+type ScheduledJobsConfig struct {
+	AuctionService      *AuctionService
+	NotificationService *NotificationService
+	ReportManager       *reporting.ReportManager
+	EmailService        *EmailService
+	SessionManager      *session.SessionManager
+	CheckoutService     *CheckoutService
+	OrderStateMachine   *OrderStateMachine
+	VendorLedger        *VendorLedgerService
+	WholesaleService    *WholesaleService
+	Reconciliation      *PaymentReconciliationService
+	MarketplaceOrders   *MarketplaceOrderImportService
+	InventorySync       *InventorySyncService
+	MarketplaceCatalog  *MarketplaceCatalogService
+	Repricing           *RepricingService
+	MarketplaceSync     *MarketplaceSyncService
+	Webhooks            *IntegrationWebhookService
+	Timezone            string
+	Logger              *log.Logger
+}
+
+// RegisterScheduledJobs registers the job handlers for recurring work and
+// creates or updates their schedules
+func RegisterScheduledJobs(jm *jobs.JobManager, scheduler *jobs.Scheduler, config ScheduledJobsConfig) error {
+	if config.Timezone == "" {
+		config.Timezone = "Europe/Istanbul"
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+
+	var schedules []*jobs.Schedule
+
+	if config.AuctionService != nil {
+		jm.RegisterHandler(JobTypeProcessExpiredAuctions, func(ctx context.Context, job *jobs.Job) error {
+			return config.AuctionService.ProcessExpiredAuctions()
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "auctions_process_expired",
+			Name:     "Process expired auctions",
+			CronExpr: "* * * * *",
+			JobType:  JobTypeProcessExpiredAuctions,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.NotificationService != nil {
+		jm.RegisterHandler(JobTypeProcessScheduledNotifications, func(ctx context.Context, job *jobs.Job) error {
+			return config.NotificationService.ProcessScheduledNotifications()
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "notifications_process_scheduled",
+			Name:     "Send scheduled notifications",
+			CronExpr: "* * * * *",
+			JobType:  JobTypeProcessScheduledNotifications,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.CheckoutService != nil {
+		jm.RegisterHandler(JobTypeReleaseExpiredReservations, func(ctx context.Context, job *jobs.Job) error {
+			released, err := config.CheckoutService.ReleaseExpiredReservations()
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{"released_orders": released}
+			return nil
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "checkout_release_expired_reservations",
+			Name:     "Release expired stock reservations",
+			CronExpr: "* * * * *",
+			JobType:  JobTypeReleaseExpiredReservations,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.OrderStateMachine != nil {
+		jm.RegisterHandler(JobTypeRetryOrderRefunds, func(ctx context.Context, job *jobs.Job) error {
+			refunded, err := config.OrderStateMachine.RetryRefunds()
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{"refunded_orders": refunded}
+			return nil
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "orders_retry_refunds",
+			Name:     "Retry failed order refunds",
+			CronExpr: "*/5 * * * *",
+			JobType:  JobTypeRetryOrderRefunds,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.VendorLedger != nil {
+		jm.RegisterHandler(JobTypeGenerateVendorPayouts, func(ctx context.Context, job *jobs.Job) error {
+			// Statements cover everything up to the start of the day the job
+			// runs on, in the schedule's time zone
+			loc, err := time.LoadLocation(config.Timezone)
+			if err != nil {
+				return err
+			}
+			now := time.Now().In(loc)
+			periodEnd := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+			created, err := config.VendorLedger.GeneratePayoutStatements(periodEnd)
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{"payouts_created": created, "period_end": periodEnd}
+			return nil
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "vendors_generate_payouts",
+			Name:     "Generate weekly vendor payout statements",
+			CronExpr: "0 3 * * 1",
+			JobType:  JobTypeGenerateVendorPayouts,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.WholesaleService != nil {
+		jm.RegisterHandler(JobTypeExpireWholesaleQuotes, func(ctx context.Context, job *jobs.Job) error {
+			expired, err := config.WholesaleService.ExpireQuotes()
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{"expired_quotes": expired}
+			return nil
+		})
+		jm.RegisterHandler(JobTypeMarkOverdueWholesaleOrders, func(ctx context.Context, job *jobs.Job) error {
+			overdue, err := config.WholesaleService.MarkOverdueOrders()
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{"overdue_orders": overdue}
+			return nil
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "wholesale_expire_quotes",
+			Name:     "Expire wholesale quotes",
+			CronExpr: "*/15 * * * *",
+			JobType:  JobTypeExpireWholesaleQuotes,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		}, &jobs.Schedule{
+			ID:       "wholesale_mark_overdue",
+			Name:     "Flag overdue wholesale orders",
+			CronExpr: "0 1 * * *",
+			JobType:  JobTypeMarkOverdueWholesaleOrders,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.Reconciliation != nil {
+		jm.RegisterHandler(JobTypeReconcilePayments, func(ctx context.Context, job *jobs.Job) error {
+			// Each run reconciles the previous day in the schedule's time zone
+			loc, err := time.LoadLocation(config.Timezone)
+			if err != nil {
+				return err
+			}
+			now := time.Now().In(loc)
+			periodEnd := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+			run, err := config.Reconciliation.Reconcile(periodEnd.AddDate(0, 0, -1), periodEnd.Add(-time.Nanosecond))
+			if err != nil {
+				return err
+			}
+			job.Result = map[string]interface{}{
+				"reconciliation_id": run.ID,
+				"matched":           run.MatchedCount,
+				"discrepancies":     run.DiscrepancyCount,
+			}
+			return nil
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "payments_reconcile",
+			Name:     "Reconcile payments with the gateway",
+			CronExpr: "0 4 * * *",
+			JobType:  JobTypeReconcilePayments,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.MarketplaceOrders != nil {
+		jm.RegisterHandler(JobTypeImportMarketplaceOrders, func(ctx context.Context, job *jobs.Job) error {
+			results, err := config.MarketplaceOrders.ImportAll()
+			imported, failed := 0, 0
+			for _, result := range results {
+				imported += result.Imported
+				failed += result.Failed
+			}
+			job.Result = map[string]interface{}{
+				"integrations": len(results),
+				"imported":     imported,
+				"failed":       failed,
+			}
+			return err
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "marketplace_import_orders",
+			Name:     "Import marketplace orders",
+			CronExpr: "*/5 * * * *",
+			JobType:  JobTypeImportMarketplaceOrders,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.InventorySync != nil {
+		// The debounced pushes handle reported changes; this catches the
+		// rest and retries channels that failed
+		jm.RegisterHandler(JobTypeSyncInventory, func(ctx context.Context, job *jobs.Job) error {
+			swept, err := config.InventorySync.SweepChanges()
+			if err != nil {
+				return err
+			}
+			result, err := config.InventorySync.Flush()
+			if result != nil {
+				job.Result = map[string]interface{}{
+					"swept":    swept,
+					"products": result.Products,
+					"rejected": result.Rejected,
+					"failed":   result.Failed,
+				}
+			}
+			return err
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "inventory_sync",
+			Name:     "Sync marketplace inventory",
+			CronExpr: "* * * * *",
+			JobType:  JobTypeSyncInventory,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.Repricing != nil {
+		// Runs often enough for promotions to start and end on time
+		jm.RegisterHandler(JobTypeRepriceMarketplaces, func(ctx context.Context, job *jobs.Job) error {
+			results, err := config.Repricing.RepriceAll()
+			pushed, rejected, failed := 0, 0, 0
+			for _, result := range results {
+				pushed += result.Pushed
+				rejected += result.Rejected
+				failed += result.Failed
+			}
+			job.Result = map[string]interface{}{
+				"integrations": len(results),
+				"pushed":       pushed,
+				"rejected":     rejected,
+				"failed":       failed,
+			}
+			return err
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "marketplace_reprice",
+			Name:     "Reprice marketplace listings",
+			CronExpr: "*/15 * * * *",
+			JobType:  JobTypeRepriceMarketplaces,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.MarketplaceSync != nil {
+		jm.RegisterHandler(JobTypePollMarketplaceSync, func(ctx context.Context, job *jobs.Job) error {
+			updated, err := config.MarketplaceSync.PollPending()
+			job.Result = map[string]interface{}{"updated": updated}
+			return err
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "marketplace_poll_sync",
+			Name:     "Poll marketplace listing batch results",
+			CronExpr: "*/5 * * * *",
+			JobType:  JobTypePollMarketplaceSync,
+			Priority: jobs.JobPriorityNormal,
+			Enabled:  true,
+		})
+	}
+
+	if config.Webhooks != nil {
+		jm.RegisterHandler(JobTypeProcessWebhooks, func(ctx context.Context, job *jobs.Job) error {
+			var processed int
+			var err error
+			if integrationID, _ := job.Payload["integration_id"].(string); integrationID != "" {
+				processed, err = config.Webhooks.ProcessIntegration(integrationID)
+			} else {
+				processed, err = config.Webhooks.ProcessDue()
+			}
+			job.Result = map[string]interface{}{"processed": processed}
+			return err
+		})
+		config.Webhooks.SetJobManager(jm)
+		// Picks up retries and events whose job was lost
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "webhooks_process",
+			Name:     "Process due webhook events",
+			CronExpr: "* * * * *",
+			JobType:  JobTypeProcessWebhooks,
+			Priority: jobs.JobPriorityHigh,
+			Enabled:  true,
+		})
+	}
+
+	if config.MarketplaceCatalog != nil {
+		jm.RegisterHandler(JobTypeRefreshMarketplaceCatalogs, func(ctx context.Context, job *jobs.Job) error {
+			refreshed, err := config.MarketplaceCatalog.RefreshAll()
+			job.Result = map[string]interface{}{"integrations": refreshed}
+			return err
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "marketplace_refresh_catalogs",
+			Name:     "Refresh marketplace categories and brands",
+			CronExpr: "30 3 * * *",
+			JobType:  JobTypeRefreshMarketplaceCatalogs,
+			Priority: jobs.JobPriorityLow,
+			Enabled:  true,
+		})
+	}
+
+	if config.SessionManager != nil {
+		jm.RegisterHandler(JobTypeCleanupSessions, func(ctx context.Context, job *jobs.Job) error {
+			return config.SessionManager.CleanupExpiredSessions()
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "sessions_cleanup",
+			Name:     "Clean up expired sessions",
+			CronExpr: "@hourly",
+			JobType:  JobTypeCleanupSessions,
+			Priority: jobs.JobPriorityLow,
+			Enabled:  true,
+		})
+	}
+
+	if config.ReportManager != nil {
+		jm.RegisterHandler(JobTypeExecuteScheduledReport, func(ctx context.Context, job *jobs.Job) error {
+			reportID, _ := job.Payload["report_id"].(string)
+			if reportID == "" {
+				return fmt.Errorf("report_id is required")
+			}
+			result, err := deliverScheduledReport(config.ReportManager, config.EmailService, reportID)
+			if err != nil {
+				return err
+			}
+			job.Result = result
+			return nil
+		})
+		jm.RegisterHandler(JobTypeSyncReportSchedules, func(ctx context.Context, job *jobs.Job) error {
+			return syncReportSchedules(scheduler, config.ReportManager, config.Timezone)
+		})
+		schedules = append(schedules, &jobs.Schedule{
+			ID:       "reports_sync_schedules",
+			Name:     "Sync report schedules",
+			CronExpr: "*/5 * * * *",
+			JobType:  JobTypeSyncReportSchedules,
+			Priority: jobs.JobPriorityLow,
+			Enabled:  true,
+		})
+
+		if err := syncReportSchedules(scheduler, config.ReportManager, config.Timezone); err != nil {
+			return err
+		}
+		// Reports changed through this process are rescheduled right away;
+		// the sync job picks up changes made elsewhere
+		config.ReportManager.OnChange(func(reportID string) {
+			if err := syncReportSchedules(scheduler, config.ReportManager, config.Timezone); err != nil {
+				config.Logger.Printf("Failed to sync report schedules after report %s changed: %v", reportID, err)
+			}
+		})
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Timezone == "" {
+			schedule.Timezone = config.Timezone
+		}
+		// Keep schedules an admin has switched off disabled across restarts
+		if existing, err := scheduler.Get(schedule.ID); err == nil {
+			schedule.Enabled = existing.Enabled
+		}
+		if err := scheduler.Register(schedule); err != nil {
+			return fmt.Errorf("failed to register schedule %s: %w", schedule.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// reportSchedulePrefix starts the IDs of report schedules
+const reportSchedulePrefix = "report_"
+
+// syncReportSchedules registers a schedule for every report with an enabled
+// schedule and removes the schedules of reports that no longer have one
+func syncReportSchedules(scheduler *jobs.Scheduler, rm *reporting.ReportManager, timezone string) error {
+	reports, err := rm.GetScheduledReports()
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled reports: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(reports))
+	for _, report := range reports {
+		cronExpr, err := report.Schedule.CronExpression()
+		if err != nil {
+			return fmt.Errorf("report %s: %w", report.ID, err)
+		}
+		schedule := &jobs.Schedule{
+			ID:       reportSchedulePrefix + report.ID,
+			Name:     "Report: " + report.Name,
+			CronExpr: cronExpr,
+			Timezone: timezone,
+			JobType:  JobTypeExecuteScheduledReport,
+			Payload:  map[string]interface{}{"report_id": report.ID},
+			Priority: jobs.JobPriorityLow,
+			Enabled:  true,
+		}
+		wanted[schedule.ID] = true
+		// Keep schedules an admin has switched off disabled
+		if existing, err := scheduler.Get(schedule.ID); err == nil {
+			schedule.Enabled = existing.Enabled
+		}
+		if err := scheduler.Register(schedule); err != nil {
+			return fmt.Errorf("failed to register schedule %s: %w", schedule.ID, err)
+		}
+	}
+
+	existing, err := scheduler.List()
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+	for _, schedule := range existing {
+		if schedule.JobType == JobTypeExecuteScheduledReport && !wanted[schedule.ID] {
+			if err := scheduler.Remove(schedule.ID); err != nil {
+				return fmt.Errorf("failed to remove schedule %s: %w", schedule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deliverScheduledReport runs a scheduled report and mails it to the
+// schedule's recipients in the schedule's format. The report and its
+// schedule are read when the job runs, so edits apply to the next run.
+func deliverScheduledReport(rm *reporting.ReportManager, emailService *EmailService, reportID string) (map[string]interface{}, error) {
+	report, err := rm.GetReportConfig(reportID)
+	if err == sql.ErrNoRows {
+		// The report was deleted after the job was queued
+		return map[string]interface{}{"report_id": reportID, "skipped": "report no longer exists"}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report %s: %w", reportID, err)
+	}
+
+	result, err := rm.ExecuteReport(reportID, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	summary := map[string]interface{}{
+		"report_id":  reportID,
+		"total_rows": result.TotalRows,
+	}
+	if report.Schedule == nil || len(report.Schedule.Recipients) == 0 {
+		return summary, nil
+	}
+	if emailService == nil {
+		return nil, fmt.Errorf("report %s has recipients but no email service is configured", reportID)
+	}
+
+	exported, err := reporting.Export(report, result, report.Schedule.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export report %s: %w", reportID, err)
+	}
+	generatedAt := result.GeneratedAt.Format("2006-01-02 15:04")
+	_, err = emailService.SendEmail(&EmailRequest{
+		To:       report.Schedule.Recipients,
+		Subject:  fmt.Sprintf("Scheduled report: %s", report.Name),
+		HTMLBody: fmt.Sprintf("<p>The %s report generated at %s is attached (%d rows).</p>", html.EscapeString(report.Name), generatedAt, result.TotalRows),
+		TextBody: fmt.Sprintf("The %s report generated at %s is attached (%d rows).", report.Name, generatedAt, result.TotalRows),
+		Attachments: []EmailAttachment{{
+			Filename:    exported.Filename,
+			Content:     exported.Content,
+			ContentType: exported.ContentType,
+			Disposition: "attachment",
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mail report %s: %w", reportID, err)
+	}
+	summary["recipients"] = len(report.Schedule.Recipients)
+	summary["format"] = exported.ContentType
+	return summary, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"kolajAi/internal/jobs"
+	"kolajAi/internal/reporting"
+	"kolajAi/internal/testutil"
+)
+
+// recordingMailer is an email provider that keeps the emails it is given
+type recordingMailer struct {
+	sent []*EmailRequest
+}
+
+func (m *recordingMailer) SendEmail(req *EmailRequest) error {
+	m.sent = append(m.sent, req)
+	return nil
+}
+
+func (m *recordingMailer) SendBulkEmail(req *BulkEmailRequest) error { return nil }
+
+func (m *recordingMailer) GetDeliveryStatus(messageID string) (*EmailStatus, error) {
+	return &EmailStatus{MessageID: messageID, Status: "sent"}, nil
+}
+
+// usersReport is a report listing users on a daily schedule
+func usersReport(id string, schedule *reporting.ScheduleConfig) *reporting.ReportConfig {
+	return &reporting.ReportConfig{
+		ID:          id,
+		Name:        "Kullanıcılar",
+		DataSources: []reporting.DataSource{{Name: "users", Type: "table", Source: "users"}},
+		Sorting:     []reporting.SortConfig{{Field: "id", Order: "ASC"}},
+		Columns: []reporting.ColumnConfig{
+			{ID: "id", Name: "ID", Field: "id", Type: "number", Visible: true},
+			{ID: "email", Name: "E-posta", Field: "email", Type: "text", Visible: true},
+		},
+		Schedule: schedule,
+	}
+}
+
+// newTestScheduler returns a scheduler whose job manager never runs jobs
+func newTestScheduler(t *testing.T) (*jobs.JobManager, *jobs.Scheduler) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	jm := jobs.NewJobManager(jobs.JobManagerConfig{Workers: 1, Logger: testutil.DiscardLogger, PollInterval: time.Hour})
+	t.Cleanup(jm.Shutdown)
+	return jm, jobs.NewScheduler(repo, jm, jobs.SchedulerConfig{InstanceID: "host:1:aaaa", Logger: testutil.DiscardLogger})
+}
+
+func TestReportSchedulesFollowReportChanges(t *testing.T) {
+	jm, scheduler := newTestScheduler(t)
+	rm := reporting.NewReportManager(testutil.NewDB(t))
+	daily := &reporting.ScheduleConfig{Enabled: true, Frequency: "daily", Time: "08:00", Format: reporting.FormatCSV}
+	if err := rm.CreateReport(usersReport("users_daily", daily)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterScheduledJobs(jm, scheduler, ScheduledJobsConfig{ReportManager: rm, Logger: testutil.DiscardLogger}); err != nil {
+		t.Fatal(err)
+	}
+
+	cronOf := func(id string) string {
+		t.Helper()
+		schedule, err := scheduler.Get(id)
+		if err != nil {
+			t.Fatalf("schedule %s: %v", id, err)
+		}
+		return schedule.CronExpr
+	}
+	if got := cronOf("report_users_daily"); got != "0 8 * * *" {
+		t.Fatalf("cron = %q, want 0 8 * * *", got)
+	}
+
+	// Edits and new reports are scheduled without a restart
+	daily.Time = "09:30"
+	if err := rm.UpdateReport(usersReport("users_daily", daily)); err != nil {
+		t.Fatal(err)
+	}
+	if got := cronOf("report_users_daily"); got != "30 9 * * *" {
+		t.Fatalf("cron after update = %q, want 30 9 * * *", got)
+	}
+	weekly := &reporting.ScheduleConfig{Enabled: true, Frequency: "weekly", Days: []string{"friday"}, Time: "17:00"}
+	if err := rm.CreateReport(usersReport("users_weekly", weekly)); err != nil {
+		t.Fatal(err)
+	}
+	if got := cronOf("report_users_weekly"); got != "0 17 * * fri" {
+		t.Fatalf("cron of new report = %q, want 0 17 * * fri", got)
+	}
+
+	// Unscheduled and deleted reports lose their schedules
+	daily.Enabled = false
+	if err := rm.UpdateReport(usersReport("users_daily", daily)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.DeleteReport("users_weekly"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"report_users_daily", "report_users_weekly"} {
+		if _, err := scheduler.Get(id); err == nil {
+			t.Fatalf("schedule %s still exists", id)
+		}
+	}
+}
+
+func TestDeliverScheduledReport(t *testing.T) {
+	db := testutil.NewDB(t)
+	repo := testutil.NewRepo(t)
+	seedUser(t, repo)
+	rm := reporting.NewReportManager(db)
+	schedule := &reporting.ScheduleConfig{
+		Enabled:    true,
+		Frequency:  "daily",
+		Recipients: []string{"finans@kolaj.ai", "ops@kolaj.ai"},
+		Format:     reporting.FormatExcel,
+	}
+	if err := rm.CreateReport(usersReport("users_daily", schedule)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := deliverScheduledReport(rm, nil, "users_daily"); err == nil {
+		t.Fatal("delivered a report with recipients without an email service")
+	}
+
+	mailer := &recordingMailer{}
+	emailService := &EmailService{db: db, provider: mailer, config: EmailConfig{Provider: "test", FromEmail: "rapor@kolaj.ai"}}
+	if _, err := deliverScheduledReport(rm, emailService, "users_daily"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mailer.sent))
+	}
+	sent := mailer.sent[0]
+	if strings.Join(sent.To, ",") != "finans@kolaj.ai,ops@kolaj.ai" {
+		t.Fatalf("recipients = %v", sent.To)
+	}
+	if len(sent.Attachments) != 1 {
+		t.Fatalf("attachments = %d, want 1", len(sent.Attachments))
+	}
+	attachment := sent.Attachments[0]
+	if !strings.HasSuffix(attachment.Filename, ".xls") || attachment.ContentType != "application/vnd.ms-excel" {
+		t.Fatalf("attachment = %s (%s), want an Excel workbook", attachment.Filename, attachment.ContentType)
+	}
+	if !strings.Contains(string(attachment.Content), "E-posta") {
+		t.Fatalf("workbook has no header row: %s", attachment.Content)
+	}
+
+	// A report deleted after its job was queued is skipped
+	if err := rm.DeleteReport("users_daily"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := deliverScheduledReport(rm, emailService, "users_daily")
+	if err != nil || result["skipped"] == nil {
+		t.Fatalf("got %v, %v; want the run skipped", result, err)
+	}
+}
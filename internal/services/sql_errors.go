@@ -0,0 +1,10 @@
+package services
+
+import "strings"
+
+// isUniqueViolation reports whether err is the unique constraint error of
+// SQLite or MySQL
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint failed") || strings.Contains(msg, "duplicate entry")
+}
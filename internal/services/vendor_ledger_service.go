@@ -0,0 +1,547 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Vendor ledger errors
+var (
+	ErrPayoutNotFound      = errors.New("payout not found")
+	ErrPayoutNotPending    = errors.New("payout is not pending")
+	ErrNothingToPayOut     = errors.New("vendor has no unsettled ledger entries")
+	ErrUnbalancedPosting   = errors.New("ledger posting does not balance")
+	ErrInvalidLedgerAmount = errors.New("ledger amount must be positive")
+)
+
+// ledgerLine is one line of a posting before it is written
+type ledgerLine struct {
+	account string
+	debit   float64
+	credit  float64
+}
+
+// VendorLedgerService keeps a double-entry ledger per vendor of sale
+// revenue, marketplace commission, refunds and shipping costs, and settles
+// it with periodic payout statements
+type VendorLedgerService struct {
+	repo     database.SimpleRepository
+	currency string
+	logger   *log.Logger
+}
+
+// NewVendorLedgerService creates a new vendor ledger service
+func NewVendorLedgerService(repo database.SimpleRepository, currency string, logger *log.Logger) (*VendorLedgerService, error) {
+	if currency == "" {
+		currency = "TRY"
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &VendorLedgerService{repo: repo, currency: currency, logger: logger}, nil
+}
+
+// Register adds the ledger effects to the order state machine: confirmed
+// orders post their sale and commission, and cancelled or refunded orders
+// reverse what has not been refunded through returns yet
+func (s *VendorLedgerService) Register(sm *OrderStateMachine) {
+	sm.AddEffect(models.OrderStatusConfirmed, s.postOrderSale)
+	sm.AddEffect(models.OrderStatusCancelled, s.postOrderReversal)
+	sm.AddEffect(models.OrderStatusRefunded, s.postOrderReversal)
+}
+
+// postOrderSale posts the sale and commission of each vendor in the order.
+// Vendors are credited with their items less their share of the discount;
+// shipping and tax stay with the marketplace. Parent orders of multi-vendor
+// checkouts have no items and post nothing.
+func (s *VendorLedgerService) postOrderSale(tx database.Transaction, tc *TransitionContext) error {
+	for _, share := range vendorShares(tc.Order, tc.Items, nil) {
+		if err := s.postSale(tx, share.vendorID, tc.Order.ID, share.amount, share.commission, tc.Order.OrderNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postOrderReversal refunds the vendors of a cancelled or refunded order.
+// Orders cancelled before they were confirmed never posted a sale.
+func (s *VendorLedgerService) postOrderReversal(tx database.Transaction, tc *TransitionContext) error {
+	if tc.From == models.OrderStatusPending {
+		return nil
+	}
+	for _, share := range vendorShares(tc.Order, tc.Items, tc.ReturnRefunds) {
+		amount := roundMoney(share.amount - share.refunded)
+		if amount <= 0 {
+			continue
+		}
+		commission := 0.0
+		if share.amount > 0 {
+			commission = roundMoney(share.commission * amount / share.amount)
+		}
+		if err := s.postRefund(tx, share.vendorID, tc.Order.ID, amount, commission, "order "+tc.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postSale credits the vendor with sale revenue and debits the marketplace
+// commission. The vendor's total sales figure is kept in step.
+func (s *VendorLedgerService) postSale(tx database.Transaction, vendorID, orderID int64, amount, commission float64, orderNumber string) error {
+	if amount <= 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+
+	err := s.post(tx, vendorID, orderID, models.LedgerEntrySale, "sale "+orderNumber, now,
+		ledgerLine{account: models.LedgerAccountCustomerClearing, debit: amount},
+		ledgerLine{account: models.LedgerAccountVendorPayable, credit: amount})
+	if err != nil {
+		return err
+	}
+	if commission > 0 {
+		err = s.post(tx, vendorID, orderID, models.LedgerEntryCommission, "commission "+orderNumber, now,
+			ledgerLine{account: models.LedgerAccountVendorPayable, debit: commission},
+			ledgerLine{account: models.LedgerAccountCommissionRevenue, credit: commission})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE vendors SET total_sales = total_sales + ?, updated_at = ? WHERE id = ?`,
+		amount, now, vendorID); err != nil {
+		return fmt.Errorf("failed to update vendor sales: %w", err)
+	}
+	return nil
+}
+
+// postRefund debits the vendor with a refund and gives back the commission
+// charged on the refunded amount
+func (s *VendorLedgerService) postRefund(tx database.Transaction, vendorID, orderID int64, amount, commission float64, description string) error {
+	now := time.Now().UTC()
+
+	err := s.post(tx, vendorID, orderID, models.LedgerEntryRefund, description, now,
+		ledgerLine{account: models.LedgerAccountVendorPayable, debit: amount},
+		ledgerLine{account: models.LedgerAccountCustomerClearing, credit: amount})
+	if err != nil {
+		return err
+	}
+	if commission > 0 {
+		err = s.post(tx, vendorID, orderID, models.LedgerEntryCommissionReversal, description, now,
+			ledgerLine{account: models.LedgerAccountCommissionRevenue, debit: commission},
+			ledgerLine{account: models.LedgerAccountVendorPayable, credit: commission})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE vendors SET total_sales = CASE WHEN total_sales >= ? THEN total_sales - ? ELSE 0 END, updated_at = ?
+		WHERE id = ?`, amount, amount, now, vendorID); err != nil {
+		return fmt.Errorf("failed to update vendor sales: %w", err)
+	}
+	return nil
+}
+
+// post writes a balanced ledger transaction
+func (s *VendorLedgerService) post(tx database.Transaction, vendorID, orderID int64, entryType, description string, at time.Time, lines ...ledgerLine) error {
+	var debit, credit float64
+	for _, line := range lines {
+		debit += line.debit
+		credit += line.credit
+	}
+	if roundMoney(debit) != roundMoney(credit) {
+		return ErrUnbalancedPosting
+	}
+
+	transactionID := uuid.New().String()
+	for _, line := range lines {
+		_, err := tx.Exec(`
+			INSERT INTO vendor_ledger_entries (transaction_id, vendor_id, order_id, payout_id, account, entry_type,
+				debit, credit, description, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			transactionID, vendorID, orderID, 0, line.account, entryType,
+			roundMoney(line.debit), roundMoney(line.credit), description, at)
+		if err != nil {
+			return fmt.Errorf("failed to write ledger entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordRefund debits a vendor with a partial refund of an order, such as
+// a return. The commission given back is proportional to the commission
+// charged on the vendor's sale in that order.
+func (s *VendorLedgerService) RecordRefund(vendorID, orderID int64, amount float64, description string) error {
+	if amount <= 0 {
+		return ErrInvalidLedgerAmount
+	}
+
+	var sales, commission float64
+	err := s.repo.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN entry_type = ? THEN credit ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN entry_type = ? THEN debit ELSE 0 END), 0)
+		FROM vendor_ledger_entries
+		WHERE vendor_id = ? AND order_id = ? AND account = ?`,
+		models.LedgerEntrySale, models.LedgerEntryCommission, vendorID, orderID, models.LedgerAccountVendorPayable).
+		Scan(&sales, &commission)
+	if err != nil {
+		return fmt.Errorf("failed to get vendor order sales: %w", err)
+	}
+	reversal := 0.0
+	if sales > 0 {
+		reversal = roundMoney(commission * amount / sales)
+	}
+
+	return s.inTransaction(func(tx database.Transaction) error {
+		return s.postRefund(tx, vendorID, orderID, amount, reversal, description)
+	})
+}
+
+// RecordShippingCost debits a vendor with a shipping cost the marketplace
+// paid on its behalf, such as a return shipment label
+func (s *VendorLedgerService) RecordShippingCost(vendorID, orderID int64, amount float64, description string) error {
+	if amount <= 0 {
+		return ErrInvalidLedgerAmount
+	}
+	return s.inTransaction(func(tx database.Transaction) error {
+		return s.post(tx, vendorID, orderID, models.LedgerEntryShipping, description, time.Now().UTC(),
+			ledgerLine{account: models.LedgerAccountVendorPayable, debit: amount},
+			ledgerLine{account: models.LedgerAccountShippingPayable, credit: amount})
+	})
+}
+
+// GetBalance returns what the marketplace owes a vendor: the credit balance
+// of its payable account, including amounts on unpaid payout statements
+func (s *VendorLedgerService) GetBalance(vendorID int64) (float64, error) {
+	var balance float64
+	err := s.repo.QueryRow(`
+		SELECT COALESCE(SUM(credit) - SUM(debit), 0) FROM vendor_ledger_entries
+		WHERE vendor_id = ? AND account = ?`,
+		vendorID, models.LedgerAccountVendorPayable).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get vendor balance: %w", err)
+	}
+	return roundMoney(balance), nil
+}
+
+// GetEntries returns a vendor's payable account entries, newest first
+func (s *VendorLedgerService) GetEntries(vendorID int64, limit, offset int) ([]models.VendorLedgerEntry, error) {
+	return s.queryEntries(`WHERE vendor_id = ? AND account = ? ORDER BY id DESC LIMIT ? OFFSET ?`,
+		vendorID, models.LedgerAccountVendorPayable, limit, offset)
+}
+
+// GetOrderEntries returns every ledger entry posted for an order
+func (s *VendorLedgerService) GetOrderEntries(orderID int64) ([]models.VendorLedgerEntry, error) {
+	return s.queryEntries(`WHERE order_id = ? ORDER BY id ASC`, orderID)
+}
+
+// GeneratePayoutStatement settles a vendor's unsettled entries created
+// before periodEnd into a pending payout. The entries are claimed and the
+// statement totalled from them in one transaction, so concurrent runs
+// cannot settle an entry twice or total entries they did not claim.
+func (s *VendorLedgerService) GeneratePayoutStatement(vendorID int64, periodEnd time.Time) (*models.VendorPayout, error) {
+	periodEnd = periodEnd.UTC()
+	now := time.Now().UTC()
+
+	var payoutID int64
+	err := s.inTransaction(func(tx database.Transaction) error {
+		result, err := tx.Exec(`
+			INSERT INTO vendor_payouts (vendor_id, period_start, period_end, currency, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			vendorID, periodEnd, periodEnd, s.currency, models.PayoutStatusPending, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to create payout: %w", err)
+		}
+		if payoutID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get payout ID: %w", err)
+		}
+
+		// Both sides of each transaction are settled, so the ledger stays
+		// balanced per payout. Entries another statement claimed first no
+		// longer match payout_id = 0.
+		result, err = tx.Exec(`
+			UPDATE vendor_ledger_entries SET payout_id = ?
+			WHERE vendor_id = ? AND payout_id = 0 AND created_at < ?`,
+			payoutID, vendorID, periodEnd)
+		if err != nil {
+			return fmt.Errorf("failed to settle ledger entries: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return ErrNothingToPayOut
+		}
+
+		// The period starts with the oldest settled entry
+		_, err = tx.Exec(`
+			UPDATE vendor_payouts SET
+				period_start = COALESCE((SELECT MIN(created_at) FROM vendor_ledger_entries
+					WHERE payout_id = ? AND account = ?), period_start),
+				gross_sales = `+payoutSum("credit")+`,
+				commission = `+payoutSum("debit")+`,
+				refunds = `+payoutSum("debit")+`,
+				commission_reversal = `+payoutSum("credit")+`,
+				shipping_costs = `+payoutSum("debit")+`
+			WHERE id = ?`,
+			payoutID, models.LedgerAccountVendorPayable,
+			payoutID, models.LedgerAccountVendorPayable, models.LedgerEntrySale,
+			payoutID, models.LedgerAccountVendorPayable, models.LedgerEntryCommission,
+			payoutID, models.LedgerAccountVendorPayable, models.LedgerEntryRefund,
+			payoutID, models.LedgerAccountVendorPayable, models.LedgerEntryCommissionReversal,
+			payoutID, models.LedgerAccountVendorPayable, models.LedgerEntryShipping,
+			payoutID)
+		if err != nil {
+			return fmt.Errorf("failed to summarize vendor ledger: %w", err)
+		}
+		_, err = tx.Exec(`
+			UPDATE vendor_payouts
+			SET net_amount = ROUND(gross_sales - commission - refunds + commission_reversal - shipping_costs, 2)
+			WHERE id = ?`, payoutID)
+		if err != nil {
+			return fmt.Errorf("failed to total payout: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetPayout(payoutID)
+}
+
+// payoutSum is the subquery totalling one side of the payable entries of a
+// type settled by a payout. It takes the payout ID, account and entry type.
+func payoutSum(side string) string {
+	return `(SELECT ROUND(COALESCE(SUM(` + side + `), 0), 2) FROM vendor_ledger_entries
+					WHERE payout_id = ? AND account = ? AND entry_type = ?)`
+}
+
+// GeneratePayoutStatements creates payout statements for every vendor with
+// unsettled entries before periodEnd and returns the number created
+func (s *VendorLedgerService) GeneratePayoutStatements(periodEnd time.Time) (int, error) {
+	rows, err := s.repo.Query(`
+		SELECT DISTINCT vendor_id FROM vendor_ledger_entries
+		WHERE payout_id = 0 AND created_at < ?`, periodEnd.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query unsettled vendors: %w", err)
+	}
+	var vendorIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan vendor ID: %w", err)
+		}
+		vendorIDs = append(vendorIDs, id)
+	}
+	rows.Close()
+
+	created := 0
+	for _, vendorID := range vendorIDs {
+		if _, err := s.GeneratePayoutStatement(vendorID, periodEnd); err != nil {
+			if !errors.Is(err, ErrNothingToPayOut) {
+				s.logger.Printf("Failed to generate payout statement for vendor %d: %v", vendorID, err)
+			}
+			continue
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// MarkPayoutPaid records the bank transfer of a pending payout. A payout
+// with a negative net amount cannot be paid; it is carried by the vendor
+// balance instead.
+func (s *VendorLedgerService) MarkPayoutPaid(payoutID int64, reference string) (*models.VendorPayout, error) {
+	payout, err := s.GetPayout(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if payout.Status != models.PayoutStatusPending {
+		return nil, ErrPayoutNotPending
+	}
+	if payout.NetAmount <= 0 {
+		return nil, fmt.Errorf("%w: net amount is %.2f", ErrInvalidLedgerAmount, payout.NetAmount)
+	}
+
+	now := time.Now().UTC()
+	err = s.inTransaction(func(tx database.Transaction) error {
+		result, err := tx.Exec(`
+			UPDATE vendor_payouts SET status = ?, reference = ?, paid_at = ?, updated_at = ?
+			WHERE id = ? AND status = ?`,
+			models.PayoutStatusPaid, reference, now, now, payout.ID, models.PayoutStatusPending)
+		if err != nil {
+			return fmt.Errorf("failed to update payout: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return ErrPayoutNotPending
+		}
+
+		if err := s.post(tx, payout.VendorID, 0, models.LedgerEntryPayout, "payout "+reference, now,
+			ledgerLine{account: models.LedgerAccountVendorPayable, debit: payout.NetAmount},
+			ledgerLine{account: models.LedgerAccountBank, credit: payout.NetAmount}); err != nil {
+			return err
+		}
+		// The payout transaction belongs to the statement it pays
+		_, err = tx.Exec(`
+			UPDATE vendor_ledger_entries SET payout_id = ?
+			WHERE vendor_id = ? AND entry_type = ? AND payout_id = 0`,
+			payout.ID, payout.VendorID, models.LedgerEntryPayout)
+		if err != nil {
+			return fmt.Errorf("failed to settle payout entries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payout.Status = models.PayoutStatusPaid
+	payout.Reference = reference
+	payout.PaidAt = &now
+	payout.UpdatedAt = now
+	return payout, nil
+}
+
+// GetPayout returns a payout statement
+func (s *VendorLedgerService) GetPayout(payoutID int64) (*models.VendorPayout, error) {
+	payouts, err := s.queryPayouts(`WHERE id = ?`, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if len(payouts) == 0 {
+		return nil, ErrPayoutNotFound
+	}
+	return &payouts[0], nil
+}
+
+// GetPayouts returns a vendor's payout statements, newest first
+func (s *VendorLedgerService) GetPayouts(vendorID int64, limit, offset int) ([]models.VendorPayout, error) {
+	return s.queryPayouts(`WHERE vendor_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, vendorID, limit, offset)
+}
+
+// GetPayoutEntries returns the payable account entries settled by a payout
+func (s *VendorLedgerService) GetPayoutEntries(payoutID int64) ([]models.VendorLedgerEntry, error) {
+	return s.queryEntries(`WHERE payout_id = ? AND account = ? ORDER BY id ASC`,
+		payoutID, models.LedgerAccountVendorPayable)
+}
+
+func (s *VendorLedgerService) queryEntries(where string, args ...interface{}) ([]models.VendorLedgerEntry, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, transaction_id, vendor_id, order_id, payout_id, account, entry_type, debit, credit,
+			COALESCE(description, ''), created_at
+		FROM vendor_ledger_entries `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.VendorLedgerEntry
+	for rows.Next() {
+		var e models.VendorLedgerEntry
+		if err := rows.Scan(&e.ID, &e.TransactionID, &e.VendorID, &e.OrderID, &e.PayoutID, &e.Account, &e.EntryType,
+			&e.Debit, &e.Credit, &e.Description, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (s *VendorLedgerService) queryPayouts(where string, args ...interface{}) ([]models.VendorPayout, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, vendor_id, period_start, period_end, gross_sales, commission, refunds, commission_reversal,
+			shipping_costs, net_amount, currency, status, COALESCE(reference, ''), paid_at, created_at, updated_at
+		FROM vendor_payouts `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payouts: %w", err)
+	}
+	defer rows.Close()
+
+	var payouts []models.VendorPayout
+	for rows.Next() {
+		var p models.VendorPayout
+		if err := rows.Scan(&p.ID, &p.VendorID, &p.PeriodStart, &p.PeriodEnd, &p.GrossSales, &p.Commission, &p.Refunds,
+			&p.CommissionReversal, &p.ShippingCosts, &p.NetAmount, &p.Currency, &p.Status, &p.Reference,
+			&p.PaidAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts = append(payouts, p)
+	}
+
+	return payouts, nil
+}
+
+// inTransaction runs fn in a transaction and commits it if fn succeeds
+func (s *VendorLedgerService) inTransaction(fn func(tx database.Transaction) error) error {
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin ledger transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ledger transaction: %w", err)
+	}
+	return nil
+}
+
+// vendorShare is one vendor's part of an order
+type vendorShare struct {
+	vendorID   int64
+	amount     float64
+	commission float64
+	refunded   float64
+}
+
+// vendorShares returns the item net of each vendor of an order: the value
+// of its items less the order discount split by item value. Refunds already
+// issued through returns are attributed to the vendor they were issued for.
+func vendorShares(order *models.Order, items []models.OrderItem, refunds map[int64]float64) []vendorShare {
+	var itemsTotal float64
+	index := make(map[int64]int)
+	var shares []vendorShare
+	for _, item := range items {
+		if item.VendorID <= 0 {
+			continue
+		}
+		i, ok := index[item.VendorID]
+		if !ok {
+			i = len(shares)
+			index[item.VendorID] = i
+			shares = append(shares, vendorShare{vendorID: item.VendorID})
+		}
+		shares[i].amount += item.TotalPrice
+		shares[i].commission += item.Commission
+		itemsTotal += item.TotalPrice
+	}
+	if itemsTotal <= 0 {
+		return nil
+	}
+
+	values := make([]float64, len(shares))
+	for i := range shares {
+		values[i] = shares[i].amount
+	}
+	discounts := allocateAmount(math.Min(order.DiscountAmount, itemsTotal), values)
+	for i := range shares {
+		shares[i].amount = roundMoney(values[i] - discounts[i])
+		shares[i].commission = roundMoney(shares[i].commission)
+		shares[i].refunded = refunds[shares[i].vendorID]
+	}
+
+	return shares
+}
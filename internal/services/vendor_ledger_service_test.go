@@ -0,0 +1,252 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/integrations/payment"
+	"kolajAi/internal/jobs"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// newTestLedger returns a ledger registered on a state machine over repo
+func newTestLedger(t *testing.T, repo database.SimpleRepository) (*VendorLedgerService, *OrderStateMachine) {
+	t.Helper()
+	ledger, err := NewVendorLedgerService(repo, "TRY", testutil.DiscardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := newTestStateMachine(t, repo, nil)
+	ledger.Register(sm)
+	return ledger, sm
+}
+
+// confirmedOrder seeds an order of one product with a 10 discount, 15
+// shipping and 18 tax on 100 of goods with 9 commission, and confirms it
+func confirmedOrder(t *testing.T, repo database.SimpleRepository, sm *OrderStateMachine, userID, productID int64) int64 {
+	t.Helper()
+	orderID := seedOrder(t, repo, userID, models.OrderStatusPending, "paid", 0, productID)
+	mustExec(t, repo, `UPDATE orders SET discount_amount = 10, shipping_amount = 15, tax_amount = 18, total_amount = 123 WHERE id = ?`, orderID)
+	mustExec(t, repo, `UPDATE order_items SET commission = 9 WHERE order_id = ?`, orderID)
+	if _, err := sm.Transition(&TransitionRequest{OrderID: orderID, To: models.OrderStatusConfirmed}); err != nil {
+		t.Fatal(err)
+	}
+	return orderID
+}
+
+func TestLedgerCreditsItemNet(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ledger, sm := newTestLedger(t, repo)
+	vendorID := seedVendor(t, repo, 9)
+	productID := seedProduct(t, repo, vendorID, 50, 10)
+	orderID := confirmedOrder(t, repo, sm, seedUser(t, repo), productID)
+
+	// Shipping and tax are not the vendor's
+	if balance, err := ledger.GetBalance(vendorID); err != nil || balance != 81 {
+		t.Fatalf("balance = %.2f (err %v), want 90 sales less 9 commission", balance, err)
+	}
+	entries, err := ledger.GetOrderEntries(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var debit, credit float64
+	for _, e := range entries {
+		debit += e.Debit
+		credit += e.Credit
+	}
+	if roundMoney(debit) != roundMoney(credit) {
+		t.Fatalf("order postings are unbalanced: %.2f debit, %.2f credit", debit, credit)
+	}
+
+	if _, err := sm.Transition(&TransitionRequest{OrderID: orderID, To: models.OrderStatusCancelled}); err != nil {
+		t.Fatal(err)
+	}
+	if balance, err := ledger.GetBalance(vendorID); err != nil || balance != 0 {
+		t.Fatalf("balance after cancellation = %.2f (err %v), want 0", balance, err)
+	}
+}
+
+func TestGeneratePayoutStatement(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ledger, sm := newTestLedger(t, repo)
+	vendorID := seedVendor(t, repo, 9)
+	productID := seedProduct(t, repo, vendorID, 50, 10)
+	orderID := confirmedOrder(t, repo, sm, seedUser(t, repo), productID)
+	if err := ledger.RecordShippingCost(vendorID, orderID, 6, "return label"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.RecordRefund(vendorID, orderID, 45, "return RMA-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	periodEnd := time.Now().UTC().Add(time.Second)
+	payout, err := ledger.GeneratePayoutStatement(vendorID, periodEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.VendorPayout{GrossSales: 90, Commission: 9, Refunds: 45, CommissionReversal: 4.5, ShippingCosts: 6, NetAmount: 34.5}
+	if payout.GrossSales != want.GrossSales || payout.Commission != want.Commission || payout.Refunds != want.Refunds ||
+		payout.CommissionReversal != want.CommissionReversal || payout.ShippingCosts != want.ShippingCosts ||
+		payout.NetAmount != want.NetAmount {
+		t.Fatalf("payout = %+v", payout)
+	}
+	if payout.PeriodStart.After(payout.PeriodEnd) || payout.Status != models.PayoutStatusPending {
+		t.Fatalf("payout period %v - %v, status %s", payout.PeriodStart, payout.PeriodEnd, payout.Status)
+	}
+
+	if _, err := ledger.GeneratePayoutStatement(vendorID, periodEnd); !errors.Is(err, ErrNothingToPayOut) {
+		t.Fatalf("second statement: got %v, want ErrNothingToPayOut", err)
+	}
+	payouts, err := ledger.GetPayouts(vendorID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payouts) != 1 {
+		t.Fatalf("payouts = %d, want the empty statement rolled back", len(payouts))
+	}
+
+	paid, err := ledger.MarkPayoutPaid(payout.ID, "EFT-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid.Status != models.PayoutStatusPaid {
+		t.Fatalf("payout status = %s", paid.Status)
+	}
+	if balance, err := ledger.GetBalance(vendorID); err != nil || balance != 0 {
+		t.Fatalf("balance after payout = %.2f (err %v), want 0", balance, err)
+	}
+}
+
+func TestConcurrentPayoutStatementsSettleOnce(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ledger, sm := newTestLedger(t, repo)
+	vendorID := seedVendor(t, repo, 9)
+	productID := seedProduct(t, repo, vendorID, 50, 10)
+	userID := seedUser(t, repo)
+	for i := 0; i < 3; i++ {
+		confirmedOrder(t, repo, sm, userID, productID)
+	}
+
+	periodEnd := time.Now().UTC().Add(time.Second)
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ledger.GeneratePayoutStatement(vendorID, periodEnd)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrNothingToPayOut):
+			t.Fatal(err)
+		}
+	}
+	payouts, err := ledger.GetPayouts(vendorID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var net float64
+	for _, p := range payouts {
+		net += p.NetAmount
+	}
+	if created != len(payouts) || roundMoney(net) != 243 {
+		t.Fatalf("%d statements paying %.2f, want 243 in total", len(payouts), net)
+	}
+}
+
+func TestCheckoutIsPaidOutToVendors(t *testing.T) {
+	repo := testutil.NewRepo(t)
+	ledger, err := NewVendorLedgerService(repo, "TRY", testutil.DiscardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paymentService := NewPaymentService(repo)
+	paymentService.SetGateway(payment.NewSandboxProvider(payment.SandboxConfig{Logger: testutil.DiscardLogger}))
+	sm, err := NewOrderStateMachine(repo, OrderStateMachineConfig{PaymentService: paymentService, Ledger: ledger, Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkout, err := NewCheckoutService(repo, NewOrderService(repo), paymentService, CheckoutConfig{StateMachine: sm, Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vendors := []int64{seedVendor(t, repo, 10), seedVendor(t, repo, 20)}
+	userID := seedUser(t, repo)
+	result, err := checkout.Checkout(checkoutRequest(userID,
+		line(seedProduct(t, repo, vendors[0], 30, 5), 1),
+		line(seedProduct(t, repo, vendors[1], 70, 5), 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkout.PayOrder(result.Order, testCard(payment.SandboxCardApproved)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every vendor is credited its items less commission once the payment
+	// confirms the order
+	net := make(map[int64]float64)
+	for _, vendorID := range vendors {
+		var amount float64
+		if err := repo.QueryRow(`SELECT COALESCE(SUM(total_price - commission), 0) FROM order_items WHERE vendor_id = ?`, vendorID).
+			Scan(&amount); err != nil {
+			t.Fatal(err)
+		}
+		net[vendorID] = amount
+		if balance, err := ledger.GetBalance(vendorID); err != nil || balance != roundMoney(net[vendorID]) || balance <= 0 {
+			t.Fatalf("vendor %d balance = %.2f (err %v), want %.2f", vendorID, balance, err, net[vendorID])
+		}
+	}
+
+	// The weekly job settles everything posted before the day it runs on
+	mustExec(t, repo, `UPDATE vendor_ledger_entries SET created_at = ?`, time.Now().UTC().AddDate(0, 0, -2))
+	jm, scheduler := newTestScheduler(t)
+	if err := RegisterScheduledJobs(jm, scheduler, ScheduledJobsConfig{VendorLedger: ledger, Logger: testutil.DiscardLogger}); err != nil {
+		t.Fatal(err)
+	}
+	jm.Start()
+	job := &jobs.Job{Type: JobTypeGenerateVendorPayouts}
+	if err := jm.SubmitJob(job); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := jm.GetJob(job.ID)
+		if err == nil && got.Status == jobs.JobStatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("payout job did not complete: %+v (err %v)", got, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, vendorID := range vendors {
+		payouts, err := ledger.GetPayouts(vendorID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(payouts) != 1 || payouts[0].NetAmount != roundMoney(net[vendorID]) {
+			t.Fatalf("vendor %d payouts = %+v, want one of %.2f", vendorID, payouts, net[vendorID])
+		}
+		if _, err := ledger.MarkPayoutPaid(payouts[0].ID, "EFT"); err != nil {
+			t.Fatal(err)
+		}
+		if balance, err := ledger.GetBalance(vendorID); err != nil || balance != 0 {
+			t.Fatalf("vendor %d balance after payout = %.2f (err %v), want 0", vendorID, balance, err)
+		}
+	}
+}
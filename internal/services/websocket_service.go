@@ -59,6 +59,10 @@ const (
 	MessageTypeUnsubscribe  = "unsubscribe"
 	MessageTypeError        = "error"
 	MessageTypeSuccess      = "success"
+	MessageTypeAuctionBid   = "auction_bid"
+	MessageTypeAuctionEnded = "auction_ended"
+	MessageTypeOutbid       = "auction_outbid"
+	MessageTypeAuctionWon   = "auction_won"
 )
 
 // Channel constants
@@ -69,6 +73,7 @@ const (
 	ChannelOrderPrefix   = "order_"
 	ChannelProductPrefix = "product_"
 	ChannelAdminPrefix   = "admin_"
+	ChannelAuctionPrefix = "auction_"
 )
 
 // ConnectionStats represents WebSocket connection statistics
@@ -0,0 +1,1345 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"kolajAi/internal/cache"
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Wholesale errors
+var (
+	ErrWholesaleCustomerNotFound = errors.New("wholesale customer not found")
+	ErrWholesaleNotApproved      = errors.New("wholesale customer is not approved")
+	ErrBelowWholesaleMinimum     = errors.New("quantity is below the wholesale minimum")
+	ErrCreditLimitExceeded       = errors.New("order exceeds the customer's available credit")
+	ErrPaymentOverdue            = errors.New("customer has overdue wholesale payments")
+	ErrQuoteNotFound             = errors.New("wholesale quote not found")
+	ErrQuoteNotOpen              = errors.New("wholesale quote is not in a valid status for this action")
+	ErrQuoteExpired              = errors.New("wholesale quote has expired")
+	ErrQuoteForbidden            = errors.New("wholesale quote belongs to another customer or vendor")
+	ErrWholesaleOrderNotFound    = errors.New("wholesale order not found")
+	ErrMixedVendors              = errors.New("all products must belong to the same vendor")
+	ErrInvalidPriceTiers         = errors.New("invalid wholesale price tiers")
+)
+
+// WholesaleConfig holds wholesale settings
+type WholesaleConfig struct {
+	// QuoteValidity is how long a quote request or offer stays open
+	QuoteValidity time.Duration
+	TaxRate       float64
+	Currency      string
+	// Cache, when set, drops the cached reads of the products orders take
+	// stock from or give it back to
+	Cache  *cache.EntityCache
+	Logger *log.Logger
+}
+
+// WholesaleService handles B2B buyers: tiered price lists per customer
+// group, negotiated quotes and orders on account. Every order is bought on
+// credit, so it must fit in the customer's credit limit and is due after
+// the customer's payment terms.
+type WholesaleService struct {
+	repo   database.SimpleRepository
+	config WholesaleConfig
+	logger *log.Logger
+}
+
+// WholesaleLine is a product and quantity asked for by a buyer
+type WholesaleLine struct {
+	ProductID   int     `json:"product_id"`
+	Quantity    int     `json:"quantity"`
+	TargetPrice float64 `json:"target_price"`
+}
+
+// QuoteRequest is a buyer's request for a quote. All products must belong
+// to the same vendor.
+type QuoteRequest struct {
+	CustomerID int
+	Items      []WholesaleLine
+	Notes      string
+}
+
+// QuoteOffer is a vendor's answer to a quote request. Products without a
+// price are offered at the buyer's target price, or else at the list price.
+type QuoteOffer struct {
+	Prices     map[int]float64
+	ValidUntil time.Time
+	Notes      string
+}
+
+// WholesaleOrderRequest is an order at price list prices
+type WholesaleOrderRequest struct {
+	CustomerID int
+	Items      []WholesaleLine
+	Notes      string
+}
+
+// CreditStatus describes a wholesale customer's credit line
+type CreditStatus struct {
+	CreditLimit  float64 `json:"credit_limit"`
+	Outstanding  float64 `json:"outstanding"`
+	Available    float64 `json:"available"`
+	PaymentTerms int     `json:"payment_terms"`
+	HasOverdue   bool    `json:"has_overdue"`
+}
+
+// wholesaleProduct is the product data wholesale pricing needs
+type wholesaleProduct struct {
+	id              int
+	vendorID        int
+	name            string
+	sku             string
+	price           float64
+	wholesalePrice  float64
+	minWholesaleQty int
+	status          string
+}
+
+// NewWholesaleService creates a new wholesale service
+func NewWholesaleService(repo database.SimpleRepository, config WholesaleConfig) (*WholesaleService, error) {
+	if config.QuoteValidity <= 0 {
+		config.QuoteValidity = 14 * 24 * time.Hour
+	}
+	if config.TaxRate < 0 {
+		config.TaxRate = 0
+	}
+	if config.Currency == "" {
+		config.Currency = "TRY"
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &WholesaleService{
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+// Customers
+
+// RegisterCustomer registers a business as a wholesale customer awaiting
+// approval
+func (s *WholesaleService) RegisterCustomer(customer *models.WholesaleCustomer) error {
+	if customer.UserID <= 0 || strings.TrimSpace(customer.CompanyName) == "" {
+		return errors.New("user and company name are required")
+	}
+	if customer.DiscountTier == "" {
+		customer.DiscountTier = models.WholesaleTierBronze
+	}
+	if !isWholesaleTier(customer.DiscountTier) {
+		return fmt.Errorf("invalid discount tier: %s", customer.DiscountTier)
+	}
+	now := time.Now().UTC()
+	customer.Status = models.WholesaleCustomerPending
+	customer.CreditLimit = 0
+	customer.ApprovedBy = nil
+	customer.ApprovedAt = nil
+	customer.CreatedAt = now
+	customer.UpdatedAt = now
+
+	result, err := s.repo.Exec(`
+		INSERT INTO wholesale_customers (user_id, company_name, tax_id, business_type, yearly_volume,
+			credit_limit, payment_terms, discount_tier, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		customer.UserID, customer.CompanyName, customer.TaxID, customer.BusinessType, customer.YearlyVolume,
+		customer.CreditLimit, customer.PaymentTerms, customer.DiscountTier, customer.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to register wholesale customer: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get wholesale customer ID: %w", err)
+	}
+	customer.ID = int(id)
+	return nil
+}
+
+// ApproveCustomer approves a wholesale customer and sets its customer
+// group, credit limit and payment terms in days
+func (s *WholesaleService) ApproveCustomer(customerID, approvedBy int, tier string, creditLimit float64, paymentTerms int) error {
+	if !isWholesaleTier(tier) {
+		return fmt.Errorf("invalid discount tier: %s", tier)
+	}
+	if creditLimit < 0 || paymentTerms < 0 {
+		return errors.New("credit limit and payment terms cannot be negative")
+	}
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		UPDATE wholesale_customers SET status = ?, discount_tier = ?, credit_limit = ?, payment_terms = ?,
+			approved_by = ?, approved_at = ?, updated_at = ?
+		WHERE id = ?`,
+		models.WholesaleCustomerApproved, tier, roundMoney(creditLimit), paymentTerms, approvedBy, now, now, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to approve wholesale customer: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrWholesaleCustomerNotFound
+	}
+	return nil
+}
+
+// SetCustomerStatus suspends, rejects or reinstates a wholesale customer
+func (s *WholesaleService) SetCustomerStatus(customerID int, status string) error {
+	switch status {
+	case models.WholesaleCustomerApproved, models.WholesaleCustomerSuspended, models.WholesaleCustomerRejected:
+	default:
+		return fmt.Errorf("invalid wholesale customer status: %s", status)
+	}
+	result, err := s.repo.Exec(`UPDATE wholesale_customers SET status = ?, updated_at = ? WHERE id = ?`,
+		status, time.Now().UTC(), customerID)
+	if err != nil {
+		return fmt.Errorf("failed to update wholesale customer: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrWholesaleCustomerNotFound
+	}
+	return nil
+}
+
+// GetCustomer returns a wholesale customer
+func (s *WholesaleService) GetCustomer(customerID int) (*models.WholesaleCustomer, error) {
+	return s.queryCustomer(`WHERE id = ?`, customerID)
+}
+
+// GetCustomerByUser returns the wholesale customer of a user
+func (s *WholesaleService) GetCustomerByUser(userID int) (*models.WholesaleCustomer, error) {
+	return s.queryCustomer(`WHERE user_id = ? ORDER BY id DESC LIMIT 1`, userID)
+}
+
+// queryCustomer reads a single wholesale customer
+func (s *WholesaleService) queryCustomer(where string, args ...interface{}) (*models.WholesaleCustomer, error) {
+	var c models.WholesaleCustomer
+	var approvedBy sql.NullInt64
+	var approvedAt sql.NullTime
+	err := s.repo.QueryRow(`
+		SELECT id, user_id, company_name, COALESCE(tax_id, ''), COALESCE(business_type, ''),
+			COALESCE(yearly_volume, 0), COALESCE(credit_limit, 0), credit_used, COALESCE(payment_terms, 0),
+			COALESCE(discount_tier, 'bronze'), status, approved_by, approved_at, created_at, updated_at
+		FROM wholesale_customers `+where, args...).Scan(
+		&c.ID, &c.UserID, &c.CompanyName, &c.TaxID, &c.BusinessType,
+		&c.YearlyVolume, &c.CreditLimit, &c.CreditUsed, &c.PaymentTerms,
+		&c.DiscountTier, &c.Status, &approvedBy, &approvedAt, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWholesaleCustomerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesale customer: %w", err)
+	}
+	if approvedBy.Valid {
+		id := int(approvedBy.Int64)
+		c.ApprovedBy = &id
+	}
+	c.ApprovedAt = nullTimePtr(approvedAt)
+	return &c, nil
+}
+
+// approvedCustomer returns the customer if it may buy wholesale
+func (s *WholesaleService) approvedCustomer(customerID int) (*models.WholesaleCustomer, error) {
+	customer, err := s.GetCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	if !customer.IsApproved() {
+		return nil, ErrWholesaleNotApproved
+	}
+	return customer, nil
+}
+
+// Price lists
+
+// SetPriceTiers replaces the quantity price tiers of a product. A tier
+// either has a fixed price or a percentage discount off the retail price,
+// and applies to one customer group or, with tier "all", to every group.
+// The quantity ranges of a customer group must not overlap.
+func (s *WholesaleService) SetPriceTiers(productID int, tiers []models.WholesalePrice) error {
+	byGroup := make(map[string][]models.WholesalePrice)
+	for _, t := range tiers {
+		if t.Tier == "" {
+			t.Tier = models.WholesaleTierAll
+		}
+		if t.Tier != models.WholesaleTierAll && !isWholesaleTier(t.Tier) {
+			return fmt.Errorf("%w: unknown customer group %s", ErrInvalidPriceTiers, t.Tier)
+		}
+		if t.MinQty < 1 || (t.MaxQty != nil && *t.MaxQty < t.MinQty) {
+			return fmt.Errorf("%w: invalid quantity range from %d", ErrInvalidPriceTiers, t.MinQty)
+		}
+		if t.Price < 0 || t.Discount < 0 || t.Discount >= 100 || (t.Price == 0) == (t.Discount == 0) {
+			return fmt.Errorf("%w: a tier needs either a price or a discount", ErrInvalidPriceTiers)
+		}
+		byGroup[t.Tier] = append(byGroup[t.Tier], t)
+	}
+	for group, list := range byGroup {
+		sort.Slice(list, func(i, j int) bool { return list[i].MinQty < list[j].MinQty })
+		for i := 1; i < len(list); i++ {
+			if prev := list[i-1]; prev.MaxQty == nil || *prev.MaxQty >= list[i].MinQty {
+				return fmt.Errorf("%w: overlapping %s tiers at quantity %d", ErrInvalidPriceTiers, group, list[i].MinQty)
+			}
+		}
+	}
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin price tier transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.Exec(`DELETE FROM wholesale_prices WHERE product_id = ?`, productID); err != nil {
+		return fmt.Errorf("failed to clear price tiers: %w", err)
+	}
+	now := time.Now().UTC()
+	for _, list := range byGroup {
+		for _, t := range list {
+			var maxQty interface{}
+			if t.MaxQty != nil {
+				maxQty = *t.MaxQty
+			}
+			_, err := tx.Exec(`
+				INSERT INTO wholesale_prices (product_id, min_qty, max_qty, price, discount, tier, is_active, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				productID, t.MinQty, maxQty, roundMoney(t.Price), t.Discount, t.Tier, true, now, now)
+			if err != nil {
+				return fmt.Errorf("failed to add price tier: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit price tiers: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// GetPriceTiers returns the active price tiers of a product
+func (s *WholesaleService) GetPriceTiers(productID int) ([]models.WholesalePrice, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, product_id, min_qty, max_qty, COALESCE(price, 0), COALESCE(discount, 0), tier, is_active, created_at, updated_at
+		FROM wholesale_prices WHERE product_id = ? AND is_active = ?
+		ORDER BY tier, min_qty`, productID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price tiers: %w", err)
+	}
+	defer rows.Close()
+
+	var tiers []models.WholesalePrice
+	for rows.Next() {
+		var t models.WholesalePrice
+		var maxQty sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.ProductID, &t.MinQty, &maxQty, &t.Price, &t.Discount, &t.Tier, &t.IsActive,
+			&t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price tier: %w", err)
+		}
+		if maxQty.Valid {
+			max := int(maxQty.Int64)
+			t.MaxQty = &max
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, nil
+}
+
+// GetPrice returns a customer's unit price for a quantity of a product
+func (s *WholesaleService) GetPrice(customerID, productID, quantity int) (float64, error) {
+	customer, err := s.approvedCustomer(customerID)
+	if err != nil {
+		return 0, err
+	}
+	product, err := s.loadProduct(productID)
+	if err != nil {
+		return 0, err
+	}
+	return s.listPrice(product, customer.DiscountTier, quantity)
+}
+
+// listPrice returns the price list price of a quantity of a product for a
+// customer group. A tier of the group itself wins over an "all" tier, and
+// the tier with the highest minimum quantity wins within a group. Without a
+// matching tier the product's wholesale price applies, then its retail
+// price.
+func (s *WholesaleService) listPrice(product *wholesaleProduct, group string, quantity int) (float64, error) {
+	if quantity < 1 || quantity < product.minWholesaleQty {
+		return 0, fmt.Errorf("%w of %d for %s", ErrBelowWholesaleMinimum, product.minWholesaleQty, product.name)
+	}
+	tiers, err := s.GetPriceTiers(product.id)
+	if err != nil {
+		return 0, err
+	}
+
+	var best *models.WholesalePrice
+	for i := range tiers {
+		t := &tiers[i]
+		if t.Tier != group && t.Tier != models.WholesaleTierAll {
+			continue
+		}
+		if quantity < t.MinQty || (t.MaxQty != nil && quantity > *t.MaxQty) {
+			continue
+		}
+		if best == nil || betterPriceTier(t, best, group) {
+			best = t
+		}
+	}
+
+	switch {
+	case best != nil && best.Price > 0:
+		return roundMoney(best.Price), nil
+	case best != nil:
+		return roundMoney(product.price * (1 - best.Discount/100)), nil
+	case product.wholesalePrice > 0:
+		return roundMoney(product.wholesalePrice), nil
+	}
+	return roundMoney(product.price), nil
+}
+
+// betterPriceTier reports whether tier t wins over best for a customer
+// group
+func betterPriceTier(t, best *models.WholesalePrice, group string) bool {
+	if (t.Tier == group) != (best.Tier == group) {
+		return t.Tier == group
+	}
+	return t.MinQty > best.MinQty
+}
+
+// loadProduct reads a product for wholesale pricing
+func (s *WholesaleService) loadProduct(productID int) (*wholesaleProduct, error) {
+	var p wholesaleProduct
+	err := s.repo.QueryRow(`
+		SELECT id, vendor_id, name, COALESCE(sku, ''), price, COALESCE(wholesale_price, 0),
+			COALESCE(min_wholesale_qty, 1), COALESCE(status, '')
+		FROM products WHERE id = ?`, productID).Scan(
+		&p.id, &p.vendorID, &p.name, &p.sku, &p.price, &p.wholesalePrice, &p.minWholesaleQty, &p.status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: product %d", ErrProductUnavailable, productID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product %d: %w", productID, err)
+	}
+	if p.status != "" && p.status != ProductStatusActive && p.status != ProductStatusOutOfStock {
+		return nil, fmt.Errorf("%w: product %d", ErrProductUnavailable, productID)
+	}
+	return &p, nil
+}
+
+// priceLines prices the lines for a customer group at price list prices.
+// All products must belong to the same vendor, which is returned.
+func (s *WholesaleService) priceLines(lines []WholesaleLine, group string) ([]models.WholesaleQuoteItem, int, error) {
+	if len(lines) == 0 {
+		return nil, 0, errors.New("at least one item is required")
+	}
+	var items []models.WholesaleQuoteItem
+	vendorID := 0
+	seen := make(map[int]bool)
+	for _, line := range lines {
+		if seen[line.ProductID] {
+			return nil, 0, fmt.Errorf("product %d is listed more than once", line.ProductID)
+		}
+		seen[line.ProductID] = true
+		if line.TargetPrice < 0 {
+			return nil, 0, errors.New("target price cannot be negative")
+		}
+
+		product, err := s.loadProduct(line.ProductID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if vendorID == 0 {
+			vendorID = product.vendorID
+		} else if product.vendorID != vendorID {
+			return nil, 0, ErrMixedVendors
+		}
+		price, err := s.listPrice(product, group, line.Quantity)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, models.WholesaleQuoteItem{
+			ProductID:   product.id,
+			ProductName: product.name,
+			ProductSKU:  product.sku,
+			Quantity:    line.Quantity,
+			ListPrice:   price,
+			TargetPrice: roundMoney(line.TargetPrice),
+			UnitPrice:   price,
+			TotalPrice:  roundMoney(price * float64(line.Quantity)),
+		})
+	}
+	return items, vendorID, nil
+}
+
+// Quotes
+
+// RequestQuote creates a draft quote for the vendor to answer. The items
+// start at the customer's price list prices; target prices tell the vendor
+// what the buyer wants to pay.
+func (s *WholesaleService) RequestQuote(req *QuoteRequest) (*models.WholesaleQuote, error) {
+	customer, err := s.approvedCustomer(req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	items, vendorID, err := s.priceLines(req.Items, customer.DiscountTier)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	quote := &models.WholesaleQuote{
+		CustomerID:  customer.ID,
+		VendorID:    vendorID,
+		QuoteNumber: wholesaleNumber("WQ"),
+		Status:      models.QuoteStatusDraft,
+		ValidUntil:  now.Add(s.config.QuoteValidity),
+		Notes:       req.Notes,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Items:       items,
+	}
+	setQuoteTotals(quote)
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin quote transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		INSERT INTO wholesale_quotes (customer_id, vendor_id, quote_number, status, valid_until,
+			sub_total, discount_amount, total_amount, revision, order_id, notes, vendor_notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		quote.CustomerID, quote.VendorID, quote.QuoteNumber, quote.Status, quote.ValidUntil,
+		quote.SubTotal, quote.DiscountAmount, quote.TotalAmount, quote.Revision, quote.OrderID, quote.Notes, "", now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote ID: %w", err)
+	}
+	quote.ID = int(id)
+	if err := insertQuoteItems(tx, quote); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit quote: %w", err)
+	}
+	committed = true
+	return quote, nil
+}
+
+// CounterQuote sends the vendor's offer for a draft quote to the buyer
+func (s *WholesaleService) CounterQuote(vendorID, quoteID int, offer *QuoteOffer) (*models.WholesaleQuote, error) {
+	quote, err := s.openQuote(quoteID, models.QuoteStatusDraft)
+	if err != nil {
+		return nil, err
+	}
+	if quote.VendorID != vendorID {
+		return nil, ErrQuoteForbidden
+	}
+
+	now := time.Now().UTC()
+	validUntil := offer.ValidUntil
+	if validUntil.IsZero() {
+		validUntil = now.Add(s.config.QuoteValidity)
+	}
+	if !validUntil.After(now) {
+		return nil, errors.New("offer must be valid until a future time")
+	}
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		price, ok := offer.Prices[item.ProductID]
+		switch {
+		case ok:
+		case item.TargetPrice > 0:
+			price = item.TargetPrice
+		default:
+			price = item.UnitPrice
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("offer price for product %d must be positive", item.ProductID)
+		}
+		item.UnitPrice = roundMoney(price)
+	}
+
+	updated := *quote
+	updated.Status = models.QuoteStatusSent
+	updated.ValidUntil = validUntil
+	updated.VendorNotes = offer.Notes
+	return s.reviseQuote(quote, &updated, now)
+}
+
+// ReviseQuote answers a vendor's offer with new target prices, sending the
+// quote back to the vendor
+func (s *WholesaleService) ReviseQuote(customerID, quoteID int, targets map[int]float64, notes string) (*models.WholesaleQuote, error) {
+	quote, err := s.openQuote(quoteID, models.QuoteStatusSent)
+	if err != nil {
+		return nil, err
+	}
+	if quote.CustomerID != customerID {
+		return nil, ErrQuoteForbidden
+	}
+
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		if target, ok := targets[item.ProductID]; ok {
+			if target < 0 {
+				return nil, errors.New("target price cannot be negative")
+			}
+			item.TargetPrice = roundMoney(target)
+		}
+	}
+
+	now := time.Now().UTC()
+	updated := *quote
+	updated.Status = models.QuoteStatusDraft
+	updated.ValidUntil = now.Add(s.config.QuoteValidity)
+	if notes != "" {
+		updated.Notes = notes
+	}
+	return s.reviseQuote(quote, &updated, now)
+}
+
+// reviseQuote writes a new revision of a quote and its item prices. The
+// write only applies to the revision that was loaded.
+func (s *WholesaleService) reviseQuote(loaded, updated *models.WholesaleQuote, now time.Time) (*models.WholesaleQuote, error) {
+	updated.Revision = loaded.Revision + 1
+	updated.UpdatedAt = now
+	setQuoteTotals(updated)
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin quote transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		UPDATE wholesale_quotes SET status = ?, valid_until = ?, sub_total = ?, discount_amount = ?, total_amount = ?,
+			revision = ?, notes = ?, vendor_notes = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND revision = ?`,
+		updated.Status, updated.ValidUntil, updated.SubTotal, updated.DiscountAmount, updated.TotalAmount,
+		updated.Revision, updated.Notes, updated.VendorNotes, now,
+		loaded.ID, loaded.Status, loaded.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update quote: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, ErrQuoteNotOpen
+	}
+	for _, item := range updated.Items {
+		_, err := tx.Exec(`
+			UPDATE wholesale_quote_items SET target_price = ?, unit_price = ?, discount_rate = ?, total_price = ?
+			WHERE id = ?`,
+			item.TargetPrice, item.UnitPrice, item.DiscountRate, item.TotalPrice, item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update quote item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit quote: %w", err)
+	}
+	committed = true
+	return updated, nil
+}
+
+// RejectQuote closes an open quote. The buyer rejects offers, the vendor
+// rejects requests; pass the rejecting party's customer or vendor ID and
+// zero for the other.
+func (s *WholesaleService) RejectQuote(quoteID, customerID, vendorID int) error {
+	quote, err := s.GetQuote(quoteID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case customerID != 0 && quote.CustomerID != customerID, vendorID != 0 && quote.VendorID != vendorID:
+		return ErrQuoteForbidden
+	case customerID == 0 && vendorID == 0:
+		return ErrQuoteForbidden
+	case !quote.IsOpen():
+		return ErrQuoteNotOpen
+	}
+
+	result, err := s.repo.Exec(`UPDATE wholesale_quotes SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND revision = ?`,
+		models.QuoteStatusRejected, time.Now().UTC(), quote.ID, quote.Status, quote.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to reject quote: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrQuoteNotOpen
+	}
+	return nil
+}
+
+// AcceptQuote accepts the vendor's offer and converts the quote into a
+// wholesale order at the offered prices
+func (s *WholesaleService) AcceptQuote(customerID, quoteID int) (*models.WholesaleOrder, error) {
+	quote, err := s.openQuote(quoteID, models.QuoteStatusSent)
+	if err != nil {
+		return nil, err
+	}
+	if quote.CustomerID != customerID {
+		return nil, ErrQuoteForbidden
+	}
+
+	items := make([]models.WholesaleOrderItem, len(quote.Items))
+	for i, qi := range quote.Items {
+		items[i] = models.WholesaleOrderItem{
+			ProductID:    qi.ProductID,
+			ProductName:  qi.ProductName,
+			ProductSKU:   qi.ProductSKU,
+			Quantity:     qi.Quantity,
+			UnitPrice:    qi.UnitPrice,
+			DiscountRate: qi.DiscountRate,
+			TotalPrice:   qi.TotalPrice,
+		}
+	}
+	order := &models.WholesaleOrder{
+		CustomerID:     customerID,
+		VendorID:       quote.VendorID,
+		QuoteID:        quote.ID,
+		SubTotal:       quote.SubTotal,
+		DiscountAmount: quote.DiscountAmount,
+		Notes:          quote.Notes,
+		Items:          items,
+	}
+
+	err = s.placeOrder(order, func(tx database.Transaction) error {
+		result, err := tx.Exec(`
+			UPDATE wholesale_quotes SET status = ?, order_id = ?, updated_at = ?
+			WHERE id = ? AND status = ? AND revision = ?`,
+			models.QuoteStatusAccepted, order.ID, order.CreatedAt, quote.ID, quote.Status, quote.Revision)
+		if err != nil {
+			return fmt.Errorf("failed to accept quote: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return ErrQuoteNotOpen
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// openQuote loads a quote in the given status. A quote past its validity
+// is marked expired and rejected with ErrQuoteExpired.
+func (s *WholesaleService) openQuote(quoteID int, status string) (*models.WholesaleQuote, error) {
+	quote, err := s.GetQuote(quoteID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if quote.IsExpired(now) {
+		if quote.IsOpen() {
+			if _, err := s.repo.Exec(`UPDATE wholesale_quotes SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+				models.QuoteStatusExpired, now, quote.ID, quote.Status); err != nil {
+				s.logger.Printf("failed to expire wholesale quote %d: %v", quote.ID, err)
+			}
+		}
+		return nil, ErrQuoteExpired
+	}
+	if quote.Status != status {
+		return nil, ErrQuoteNotOpen
+	}
+	return quote, nil
+}
+
+// ExpireQuotes expires open quotes past their validity. It returns the
+// number of quotes expired.
+func (s *WholesaleService) ExpireQuotes() (int, error) {
+	result, err := s.repo.Exec(`
+		UPDATE wholesale_quotes SET status = ?, updated_at = ?
+		WHERE status IN (?, ?) AND valid_until < ?`,
+		models.QuoteStatusExpired, time.Now().UTC(), models.QuoteStatusDraft, models.QuoteStatusSent, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire quotes: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// GetQuote returns a quote with its items
+func (s *WholesaleService) GetQuote(quoteID int) (*models.WholesaleQuote, error) {
+	quotes, err := s.queryQuotes(`WHERE id = ?`, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 {
+		return nil, ErrQuoteNotFound
+	}
+
+	quote := &quotes[0]
+	if quote.Items, err = s.loadQuoteItems(quote.ID); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// GetCustomerQuotes returns the quotes of a customer, newest first
+func (s *WholesaleService) GetCustomerQuotes(customerID, limit, offset int) ([]models.WholesaleQuote, error) {
+	return s.queryQuotes(`WHERE customer_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, customerID, limit, offset)
+}
+
+// GetVendorQuotes returns the quotes of a vendor, optionally filtered by
+// status, newest first
+func (s *WholesaleService) GetVendorQuotes(vendorID int, status string, limit, offset int) ([]models.WholesaleQuote, error) {
+	if status == "" {
+		return s.queryQuotes(`WHERE vendor_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, vendorID, limit, offset)
+	}
+	return s.queryQuotes(`WHERE vendor_id = ? AND status = ? ORDER BY id DESC LIMIT ? OFFSET ?`, vendorID, status, limit, offset)
+}
+
+// queryQuotes reads quotes without their items
+func (s *WholesaleService) queryQuotes(where string, args ...interface{}) ([]models.WholesaleQuote, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, customer_id, vendor_id, quote_number, status, valid_until, COALESCE(sub_total, 0),
+			COALESCE(discount_amount, 0), total_amount, COALESCE(revision, 0), COALESCE(order_id, 0),
+			COALESCE(notes, ''), COALESCE(vendor_notes, ''), created_at, updated_at
+		FROM wholesale_quotes `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var quotes []models.WholesaleQuote
+	for rows.Next() {
+		var q models.WholesaleQuote
+		if err := rows.Scan(&q.ID, &q.CustomerID, &q.VendorID, &q.QuoteNumber, &q.Status, &q.ValidUntil, &q.SubTotal,
+			&q.DiscountAmount, &q.TotalAmount, &q.Revision, &q.OrderID,
+			&q.Notes, &q.VendorNotes, &q.CreatedAt, &q.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quote: %w", err)
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, nil
+}
+
+// loadQuoteItems reads the items of a quote
+func (s *WholesaleService) loadQuoteItems(quoteID int) ([]models.WholesaleQuoteItem, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, quote_id, product_id, COALESCE(product_name, ''), COALESCE(product_sku, ''), quantity,
+			COALESCE(list_price, 0), COALESCE(target_price, 0), unit_price, COALESCE(discount_rate, 0), total_price
+		FROM wholesale_quote_items WHERE quote_id = ? ORDER BY id`, quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WholesaleQuoteItem
+	for rows.Next() {
+		var i models.WholesaleQuoteItem
+		if err := rows.Scan(&i.ID, &i.QuoteID, &i.ProductID, &i.ProductName, &i.ProductSKU, &i.Quantity,
+			&i.ListPrice, &i.TargetPrice, &i.UnitPrice, &i.DiscountRate, &i.TotalPrice); err != nil {
+			return nil, fmt.Errorf("failed to scan quote item: %w", err)
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+// insertQuoteItems writes the items of a new quote
+func insertQuoteItems(tx database.Transaction, quote *models.WholesaleQuote) error {
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		item.QuoteID = quote.ID
+		result, err := tx.Exec(`
+			INSERT INTO wholesale_quote_items (quote_id, product_id, product_name, product_sku, quantity,
+				list_price, target_price, unit_price, discount_rate, total_price)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.QuoteID, item.ProductID, item.ProductName, item.ProductSKU, item.Quantity,
+			item.ListPrice, item.TargetPrice, item.UnitPrice, item.DiscountRate, item.TotalPrice)
+		if err != nil {
+			return fmt.Errorf("failed to add quote item: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		item.ID = int(id)
+	}
+	return nil
+}
+
+// setQuoteTotals computes item totals and discount rates against the list
+// prices, and the quote totals. Offers above the list price carry no
+// discount.
+func setQuoteTotals(quote *models.WholesaleQuote) {
+	var listTotal, total float64
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		item.TotalPrice = roundMoney(item.UnitPrice * float64(item.Quantity))
+		item.DiscountRate = 0
+		if item.ListPrice > item.UnitPrice {
+			item.DiscountRate = roundMoney((item.ListPrice - item.UnitPrice) / item.ListPrice * 100)
+		}
+		listTotal += item.ListPrice * float64(item.Quantity)
+		total += item.TotalPrice
+	}
+	quote.TotalAmount = roundMoney(total)
+	quote.SubTotal = roundMoney(listTotal)
+	if quote.SubTotal < quote.TotalAmount {
+		quote.SubTotal = quote.TotalAmount
+	}
+	quote.DiscountAmount = roundMoney(quote.SubTotal - quote.TotalAmount)
+}
+
+// Orders
+
+// CreateOrder places a wholesale order at the customer's price list prices
+func (s *WholesaleService) CreateOrder(req *WholesaleOrderRequest) (*models.WholesaleOrder, error) {
+	customer, err := s.approvedCustomer(req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	lines, vendorID, err := s.priceLines(req.Items, customer.DiscountTier)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.WholesaleOrder{
+		CustomerID: customer.ID,
+		VendorID:   vendorID,
+		Notes:      req.Notes,
+	}
+	for _, line := range lines {
+		order.SubTotal += line.TotalPrice
+		order.Items = append(order.Items, models.WholesaleOrderItem{
+			ProductID:   line.ProductID,
+			ProductName: line.ProductName,
+			ProductSKU:  line.ProductSKU,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			TotalPrice:  line.TotalPrice,
+		})
+	}
+	order.SubTotal = roundMoney(order.SubTotal)
+
+	if err := s.placeOrder(order, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// placeOrder checks the customer's credit and payment terms, takes the
+// stock and writes the order in one transaction. extra runs inside the
+// transaction after the order is written.
+func (s *WholesaleService) placeOrder(order *models.WholesaleOrder, extra func(tx database.Transaction) error) error {
+	customer, err := s.approvedCustomer(order.CustomerID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	order.OrderNumber = wholesaleNumber("WHO")
+	order.Status = "pending"
+	order.PaymentStatus = models.WholesalePaymentPending
+	order.PaymentTerms = customer.PaymentTerms
+	order.DueDate = now.AddDate(0, 0, customer.PaymentTerms)
+	order.TaxAmount = roundMoney((order.SubTotal - order.DiscountAmount) * s.config.TaxRate)
+	order.TotalAmount = roundMoney(order.SubTotal - order.DiscountAmount + order.TaxAmount + order.ShippingCost)
+	order.Currency = s.config.Currency
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	credit, err := s.GetCreditStatus(customer.ID)
+	if err != nil {
+		return err
+	}
+	if credit.HasOverdue {
+		return ErrPaymentOverdue
+	}
+	if order.TotalAmount > credit.Available {
+		return fmt.Errorf("%w: %.2f available, order total %.2f", ErrCreditLimitExceeded, credit.Available, order.TotalAmount)
+	}
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin wholesale order transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		INSERT INTO wholesale_orders (customer_id, vendor_id, quote_id, order_number, status, payment_status,
+			payment_terms, due_date, sub_total, discount_amount, tax_amount, shipping_cost, total_amount, paid_amount,
+			currency, notes, internal_notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.CustomerID, order.VendorID, order.QuoteID, order.OrderNumber, order.Status, order.PaymentStatus,
+		order.PaymentTerms, order.DueDate, order.SubTotal, order.DiscountAmount, order.TaxAmount, order.ShippingCost,
+		order.TotalAmount, order.PaidAmount, order.Currency, order.Notes, order.InternalNotes, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create wholesale order: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get wholesale order ID: %w", err)
+	}
+	order.ID = int(id)
+
+	// The credit check above only gives a precise error early. The order
+	// is booked against the limit here with one conditional update, so
+	// concurrent orders, on this or another instance, cannot together
+	// exceed it.
+	if err := useCredit(tx, customer.ID, order.TotalAmount, now); err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		item.Status = "pending"
+
+		// The stock check and the decrement are one statement, like in
+		// checkout
+		result, err := tx.Exec(`
+			UPDATE products SET
+				status = CASE WHEN stock <= ? THEN ? ELSE status END,
+				stock = stock - ?,
+				sales_count = sales_count + ?,
+				updated_at = ?
+			WHERE id = ? AND stock >= ?`,
+			item.Quantity, ProductStatusOutOfStock, item.Quantity, item.Quantity, now, item.ProductID, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("failed to take stock for product %d: %w", item.ProductID, err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("%w for product %d", ErrInsufficientStock, item.ProductID)
+		}
+
+		result, err = tx.Exec(`
+			INSERT INTO wholesale_order_items (order_id, product_id, product_name, product_sku, quantity,
+				unit_price, discount_rate, total_price, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			item.OrderID, item.ProductID, item.ProductName, item.ProductSKU, item.Quantity,
+			item.UnitPrice, item.DiscountRate, item.TotalPrice, item.Status)
+		if err != nil {
+			return fmt.Errorf("failed to add wholesale order item: %w", err)
+		}
+		itemID, _ := result.LastInsertId()
+		item.ID = int(itemID)
+	}
+
+	if extra != nil {
+		if err := extra(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit wholesale order: %w", err)
+	}
+	committed = true
+	s.stockChanged(order)
+	return nil
+}
+
+// useCredit books amount against a customer's credit limit. It fails if the
+// customer is no longer approved, has an overdue order or would exceed the
+// limit.
+func useCredit(tx database.Transaction, customerID int, amount float64, now time.Time) error {
+	result, err := tx.Exec(`
+		UPDATE wholesale_customers SET credit_used = credit_used + ?, updated_at = ?
+		WHERE id = ? AND status = ? AND credit_used + ? <= credit_limit + 0.005
+			AND NOT EXISTS (SELECT 1 FROM wholesale_orders
+				WHERE customer_id = ? AND payment_status = ? AND status <> ?)`,
+		amount, now, customerID, models.WholesaleCustomerApproved, amount,
+		customerID, models.WholesalePaymentOverdue, "cancelled")
+	if err != nil {
+		return fmt.Errorf("failed to book wholesale credit: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("%w: order total %.2f", ErrCreditLimitExceeded, amount)
+	}
+	return nil
+}
+
+// releaseCredit gives back credit booked by useCredit
+func releaseCredit(tx database.Transaction, customerID int, amount float64, now time.Time) error {
+	if amount <= 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE wholesale_customers
+		SET credit_used = CASE WHEN credit_used > ? THEN credit_used - ? ELSE 0 END, updated_at = ?
+		WHERE id = ?`,
+		amount, amount, now, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to release wholesale credit: %w", err)
+	}
+	return nil
+}
+
+// stockChanged drops the cached reads of the products of an order whose
+// stock was taken or given back
+func (s *WholesaleService) stockChanged(order *models.WholesaleOrder) {
+	productIDs := make([]int64, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, int64(item.ProductID))
+	}
+	productsChanged(s.config.Cache, productIDs...)
+}
+
+// RecordPayment records a payment against a wholesale order. Overdue
+// orders stay overdue until they are paid in full.
+func (s *WholesaleService) RecordPayment(orderID int, amount float64) (*models.WholesaleOrder, error) {
+	if amount <= 0 {
+		return nil, errors.New("payment amount must be positive")
+	}
+	order, err := s.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == "cancelled" {
+		return nil, errors.New("wholesale order is cancelled")
+	}
+	if order.PaymentStatus == models.WholesalePaymentPaid {
+		return nil, errors.New("wholesale order is already paid")
+	}
+
+	paid := roundMoney(order.PaidAmount + amount)
+	status := models.WholesalePaymentPartial
+	switch {
+	case paid >= order.TotalAmount:
+		status = models.WholesalePaymentPaid
+	case order.PaymentStatus == models.WholesalePaymentOverdue:
+		status = models.WholesalePaymentOverdue
+	}
+
+	now := time.Now().UTC()
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin wholesale payment transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`
+		UPDATE wholesale_orders SET paid_amount = ?, payment_status = ?, updated_at = ?
+		WHERE id = ? AND paid_amount = ?`,
+		paid, status, now, order.ID, order.PaidAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record wholesale payment: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return nil, errors.New("wholesale order was changed concurrently")
+	}
+	// Overpayments do not free more credit than the order used
+	if err := releaseCredit(tx, order.CustomerID, roundMoney(math.Min(amount, order.TotalAmount-order.PaidAmount)), now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit wholesale payment: %w", err)
+	}
+	committed = true
+	order.PaidAmount = paid
+	order.PaymentStatus = status
+	return order, nil
+}
+
+// CancelOrder cancels an unpaid wholesale order that has not shipped and
+// puts its stock back
+func (s *WholesaleService) CancelOrder(orderID int, reason string) error {
+	order, err := s.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != "pending" && order.Status != "confirmed" {
+		return fmt.Errorf("wholesale order cannot be cancelled in status %s", order.Status)
+	}
+	if order.PaidAmount > 0 {
+		return errors.New("paid wholesale orders must be refunded before they are cancelled")
+	}
+
+	tx, err := s.repo.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin wholesale order transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	now := time.Now().UTC()
+	internalNotes := strings.TrimSpace(order.InternalNotes + "\ncancelled: " + reason)
+	result, err := tx.Exec(`
+		UPDATE wholesale_orders SET status = ?, internal_notes = ?, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		"cancelled", internalNotes, now, order.ID, order.Status)
+	if err != nil {
+		return fmt.Errorf("failed to cancel wholesale order: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return errors.New("wholesale order was changed concurrently")
+	}
+	if err := releaseCredit(tx, order.CustomerID, order.TotalAmount, now); err != nil {
+		return err
+	}
+	for _, item := range order.Items {
+		if err := restockProduct(tx, int64(item.ProductID), item.Quantity, item.Quantity, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit wholesale order cancellation: %w", err)
+	}
+	committed = true
+	s.stockChanged(order)
+	return nil
+}
+
+// MarkOverdueOrders flags unpaid orders past their due date as overdue. It
+// returns the number of orders flagged.
+func (s *WholesaleService) MarkOverdueOrders() (int, error) {
+	now := time.Now().UTC()
+	result, err := s.repo.Exec(`
+		UPDATE wholesale_orders SET payment_status = ?, updated_at = ?
+		WHERE payment_status IN (?, ?) AND status <> ? AND due_date < ?`,
+		models.WholesalePaymentOverdue, now, models.WholesalePaymentPending, models.WholesalePaymentPartial, "cancelled", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark overdue wholesale orders: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// GetCreditStatus returns a customer's credit limit, the amount owed on
+// unpaid orders and the credit still available
+func (s *WholesaleService) GetCreditStatus(customerID int) (*CreditStatus, error) {
+	customer, err := s.GetCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var overdue int
+	err = s.repo.QueryRow(`
+		SELECT COUNT(*) FROM wholesale_orders
+		WHERE customer_id = ? AND payment_status = ? AND status <> ?`,
+		customerID, models.WholesalePaymentOverdue, "cancelled").Scan(&overdue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue wholesale orders: %w", err)
+	}
+
+	status := &CreditStatus{
+		CreditLimit:  customer.CreditLimit,
+		Outstanding:  roundMoney(customer.CreditUsed),
+		PaymentTerms: customer.PaymentTerms,
+		HasOverdue:   overdue > 0,
+	}
+	if available := customer.CreditLimit - status.Outstanding; available > 0 {
+		status.Available = roundMoney(available)
+	}
+	return status, nil
+}
+
+// GetOrder returns a wholesale order with its items
+func (s *WholesaleService) GetOrder(orderID int) (*models.WholesaleOrder, error) {
+	orders, err := s.queryOrders(`WHERE id = ?`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrWholesaleOrderNotFound
+	}
+
+	order := &orders[0]
+	rows, err := s.repo.Query(`
+		SELECT id, order_id, product_id, COALESCE(product_name, ''), COALESCE(product_sku, ''), quantity,
+			unit_price, COALESCE(discount_rate, 0), total_price, status
+		FROM wholesale_order_items WHERE order_id = ? ORDER BY id`, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesale order items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i models.WholesaleOrderItem
+		if err := rows.Scan(&i.ID, &i.OrderID, &i.ProductID, &i.ProductName, &i.ProductSKU, &i.Quantity,
+			&i.UnitPrice, &i.DiscountRate, &i.TotalPrice, &i.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan wholesale order item: %w", err)
+		}
+		order.Items = append(order.Items, i)
+	}
+	return order, nil
+}
+
+// GetCustomerOrders returns the wholesale orders of a customer, newest first
+func (s *WholesaleService) GetCustomerOrders(customerID, limit, offset int) ([]models.WholesaleOrder, error) {
+	return s.queryOrders(`WHERE customer_id = ? ORDER BY id DESC LIMIT ? OFFSET ?`, customerID, limit, offset)
+}
+
+// queryOrders reads wholesale orders without their items
+func (s *WholesaleService) queryOrders(where string, args ...interface{}) ([]models.WholesaleOrder, error) {
+	rows, err := s.repo.Query(`
+		SELECT id, customer_id, COALESCE(vendor_id, 0), COALESCE(quote_id, 0), order_number, status, payment_status,
+			COALESCE(payment_terms, 0), due_date, sub_total, COALESCE(discount_amount, 0), COALESCE(tax_amount, 0),
+			COALESCE(shipping_cost, 0), total_amount, COALESCE(paid_amount, 0), COALESCE(currency, ''),
+			COALESCE(notes, ''), COALESCE(internal_notes, ''), created_at, updated_at
+		FROM wholesale_orders `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wholesale orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.WholesaleOrder
+	for rows.Next() {
+		var o models.WholesaleOrder
+		var dueDate sql.NullTime
+		if err := rows.Scan(&o.ID, &o.CustomerID, &o.VendorID, &o.QuoteID, &o.OrderNumber, &o.Status, &o.PaymentStatus,
+			&o.PaymentTerms, &dueDate, &o.SubTotal, &o.DiscountAmount, &o.TaxAmount,
+			&o.ShippingCost, &o.TotalAmount, &o.PaidAmount, &o.Currency,
+			&o.Notes, &o.InternalNotes, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wholesale order: %w", err)
+		}
+		o.DueDate = dueDate.Time
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+// isWholesaleTier reports whether tier is a customer group
+func isWholesaleTier(tier string) bool {
+	switch tier {
+	case models.WholesaleTierBronze, models.WholesaleTierSilver, models.WholesaleTierGold, models.WholesaleTierPlatinum:
+		return true
+	}
+	return false
+}
+
+// wholesaleNumber generates a unique quote or order number
+func wholesaleNumber(prefix string) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return fmt.Sprintf("%s-%d-%s", prefix, time.Now().Unix(), suffix)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"kolajAi/internal/database"
+	"kolajAi/internal/models"
+	"kolajAi/internal/testutil"
+)
+
+// newTestWholesale returns a wholesale service and an approved customer
+// with creditLimit of credit
+func newTestWholesale(t *testing.T, creditLimit float64) (*WholesaleService, database.SimpleRepository, int) {
+	t.Helper()
+	repo := testutil.NewRepo(t)
+	s, err := NewWholesaleService(repo, WholesaleConfig{Logger: testutil.DiscardLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := &models.WholesaleCustomer{UserID: int(seedUser(t, repo)), CompanyName: "Toptancı A.Ş."}
+	if err := s.RegisterCustomer(customer); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ApproveCustomer(customer.ID, 1, models.WholesaleTierBronze, creditLimit, 30); err != nil {
+		t.Fatal(err)
+	}
+	return s, repo, customer.ID
+}
+
+func wholesaleOrder(s *WholesaleService, customerID int, productID int64, quantity int) (*models.WholesaleOrder, error) {
+	return s.CreateOrder(&WholesaleOrderRequest{
+		CustomerID: customerID,
+		Items:      []WholesaleLine{{ProductID: int(productID), Quantity: quantity}},
+	})
+}
+
+func TestWholesaleOrderRespectsCreditLimit(t *testing.T) {
+	s, repo, customerID := newTestWholesale(t, 500)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 100, 50)
+
+	if _, err := wholesaleOrder(s, customerID, productID, 6); !errors.Is(err, ErrCreditLimitExceeded) {
+		t.Fatalf("order over the limit: got %v, want ErrCreditLimitExceeded", err)
+	}
+	if stock := productStock(t, repo, productID); stock != 50 {
+		t.Fatalf("stock = %d after a rejected order", stock)
+	}
+	if _, err := wholesaleOrder(s, customerID, productID, 3); err != nil {
+		t.Fatal(err)
+	}
+	credit, err := s.GetCreditStatus(customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credit.Outstanding != 300 || credit.Available != 200 {
+		t.Fatalf("credit = %+v, want 300 used and 200 available", credit)
+	}
+
+	// An overdue order blocks new orders until it is paid
+	mustExec(t, repo, `UPDATE wholesale_orders SET payment_status = ? WHERE customer_id = ?`, models.WholesalePaymentOverdue, customerID)
+	if _, err := wholesaleOrder(s, customerID, productID, 1); !errors.Is(err, ErrPaymentOverdue) {
+		t.Fatalf("order with an overdue payment: got %v, want ErrPaymentOverdue", err)
+	}
+}
+
+func TestConcurrentWholesaleOrdersStayWithinLimit(t *testing.T) {
+	s, repo, customerID := newTestWholesale(t, 500)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 100, 50)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := wholesaleOrder(s, customerID, productID, 2)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	placed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			placed++
+		case !errors.Is(err, ErrCreditLimitExceeded):
+			t.Fatal(err)
+		}
+	}
+	customer, err := s.GetCustomer(customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed != 2 || customer.CreditUsed != 400 {
+		t.Fatalf("%d orders placed using %.2f, want 2 using 400", placed, customer.CreditUsed)
+	}
+	if stock := productStock(t, repo, productID); stock != 46 {
+		t.Fatalf("stock = %d, want 46", stock)
+	}
+}
+
+func TestWholesalePaymentAndCancellationReleaseCredit(t *testing.T) {
+	s, repo, customerID := newTestWholesale(t, 500)
+	productID := seedProduct(t, repo, seedVendor(t, repo, 0), 100, 50)
+
+	paid, err := wholesaleOrder(s, customerID, productID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := wholesaleOrder(s, customerID, productID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RecordPayment(paid.ID, 50); err != nil {
+		t.Fatal(err)
+	}
+	// Paying more than is owed frees only what the order used
+	order, err := s.RecordPayment(paid.ID, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.PaymentStatus != models.WholesalePaymentPaid {
+		t.Fatalf("payment status = %s", order.PaymentStatus)
+	}
+	if credit, err := s.GetCreditStatus(customerID); err != nil || credit.Outstanding != 300 {
+		t.Fatalf("credit after payment = %+v (err %v), want 300 outstanding", credit, err)
+	}
+
+	if err := s.CancelOrder(cancelled.ID, "müşteri vazgeçti"); err != nil {
+		t.Fatal(err)
+	}
+	if credit, err := s.GetCreditStatus(customerID); err != nil || credit.Outstanding != 0 || credit.Available != 500 {
+		t.Fatalf("credit after cancellation = %+v (err %v), want all 500 available", credit, err)
+	}
+	if stock := productStock(t, repo, productID); stock != 48 {
+		t.Fatalf("stock = %d, want the cancelled units back", stock)
+	}
+}
@@ -93,8 +93,11 @@ func NewSessionManager(db *sql.DB, config SessionConfig) (*SessionManager, error
 		return nil, fmt.Errorf("failed to create sessions table: %w", err)
 	}
 
-	// Start cleanup routine
-	sm.startCleanupRoutine(config.CleanupInterval)
+	// Start cleanup routine unless cleanup is driven externally (e.g. by the
+	// job scheduler), which is signalled by a negative interval
+	if config.CleanupInterval >= 0 {
+		sm.startCleanupRoutine(config.CleanupInterval)
+	}
 
 	return sm, nil
 }
@@ -512,14 +515,19 @@ func (sm *SessionManager) startCleanupRoutine(interval time.Duration) {
 
 // cleanupExpiredSessions removes expired sessions
 func (sm *SessionManager) cleanupExpiredSessions() {
-	query := "DELETE FROM sessions WHERE expires_at < NOW() OR is_active = FALSE"
-	_, err := sm.db.Exec(query)
-	if err != nil {
+	if err := sm.CleanupExpiredSessions(); err != nil {
 		// Log error but don't stop the cleanup routine
 		fmt.Printf("Session cleanup error: %v\n", err)
 	}
 }
 
+// CleanupExpiredSessions removes expired and inactive sessions
+func (sm *SessionManager) CleanupExpiredSessions() error {
+	query := "DELETE FROM sessions WHERE expires_at < NOW() OR is_active = FALSE"
+	_, err := sm.db.Exec(query)
+	return err
+}
+
 // GetSessionStats returns session statistics
 func (sm *SessionManager) GetSessionStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -0,0 +1,39 @@
+// Package testutil holds the fixtures shared by the tests of several
+// packages: migrated in-memory SQLite databases and a silent logger
+package testutil
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"testing"
+
+	"kolajAi/internal/database"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DiscardLogger is a logger for services whose output tests ignore
+var DiscardLogger = log.New(io.Discard, "", 0)
+
+// NewRepo returns a repository on a migrated in-memory SQLite database
+func NewRepo(t testing.TB) database.SimpleRepository {
+	t.Helper()
+	return database.NewRepositoryWrapper(database.NewMySQLRepository(NewDB(t)))
+}
+
+// NewDB returns the migrated in-memory database of the test. Repeated
+// calls in one test return handles to the same database.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if err := database.NewMigrationRunner(db, database.SQLite).RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}